@@ -0,0 +1,298 @@
+package docx
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+)
+
+const (
+	// StylesXml is the relative path of the style definitions inside the docx-archive.
+	StylesXml = "word/styles.xml"
+	// SettingsXml is the relative path of the document settings inside the docx-archive.
+	SettingsXml = "word/settings.xml"
+)
+
+var (
+	// docPrRegex matches the non-visual properties (<wp:docPr>) of a drawing, which hold its name and alt-text.
+	docPrRegex = regexp.MustCompile(`<wp:docPr\b[^>]*>`)
+	// docDefaultsRegex matches the document defaults inside the styles part.
+	docDefaultsRegex = regexp.MustCompile(`(?s)<w:docDefaults\b[^>]*?(?:/>|>.*?</w:docDefaults>)`)
+	// rPrDefaultRegex matches the default run properties inside the document defaults.
+	rPrDefaultRegex = regexp.MustCompile(`(?s)<w:rPrDefault\b[^>]*?(?:/>|>.*?</w:rPrDefault>)`)
+	// defaultRunPropertiesRegex matches the run properties inside the default run properties.
+	defaultRunPropertiesRegex = regexp.MustCompile(`(?s)<w:rPr\b[^>]*?(?:/>|>.*?</w:rPr>)`)
+	// langTagRegex matches a <w:lang> element.
+	langTagRegex = regexp.MustCompile(`<w:lang\b[^>]*/>`)
+	// themeFontLangTagRegex matches the <w:themeFontLang> element of the settings part.
+	themeFontLangTagRegex = regexp.MustCompile(`<w:themeFontLang\b[^>]*/>`)
+	// stylesOpenTagRegex matches the root element of the styles part.
+	stylesOpenTagRegex = regexp.MustCompile(`<w:styles\b[^>]*>`)
+)
+
+// Drawing describes an image or other drawing object inside the document.
+type Drawing struct {
+	FileName string // the part which contains the drawing, e.g. word/document.xml
+	ID       string // the unique id of the drawing
+	Name     string // the name of the drawing as shown in Word's selection pane
+	AltText  string // the alternative text (description) of the drawing
+	Title    string // the title of the drawing
+	Image    string // the image part shown by pictures, e.g. word/media/image1.png, empty for other drawings
+
+	relID string // the id of the relationship to the image part
+}
+
+// Drawings returns all drawings of the document body, headers and footers in document order.
+func (d *Document) Drawings() []Drawing {
+	var drawings []Drawing
+	for _, fileName := range d.xmlParts() {
+		data := d.GetFile(fileName)
+		images := d.imageRelationships(fileName)
+		for _, loc := range docPrRegex.FindAllIndex(data, -1) {
+			tag := data[loc[0]:loc[1]]
+			drawing := Drawing{FileName: fileName, relID: drawingImageID(data, loc[1])}
+			drawing.Image = images[drawing.relID]
+			drawing.ID, _ = getTagAttr(tag, "id")
+			drawing.Name, _ = getTagAttr(tag, "name")
+			drawing.AltText, _ = getTagAttr(tag, "descr")
+			drawing.Title, _ = getTagAttr(tag, "title")
+			drawings = append(drawings, drawing)
+		}
+	}
+	return drawings
+}
+
+// MissingAltText returns all drawings which do not have an alternative text.
+// Generated documents should not have any, otherwise they fail accessibility audits.
+func (d *Document) MissingAltText() []Drawing {
+	var missing []Drawing
+	for _, drawing := range d.Drawings() {
+		if drawing.AltText == "" {
+			missing = append(missing, drawing)
+		}
+	}
+	return missing
+}
+
+// SetImageAltText sets the alternative text of all drawings with the given name (see Drawing.Name).
+// An error is returned if no such drawing exists.
+func (d *Document) SetImageAltText(name, altText string) error {
+	found := false
+	for _, fileName := range d.xmlParts() {
+		data := docPrRegex.ReplaceAllFunc(d.GetFile(fileName), func(tag []byte) []byte {
+			if tagName, _ := getTagAttr(tag, "name"); tagName != name {
+				return tag
+			}
+			found = true
+			return setTagAttr(tag, "descr", altText)
+		})
+		if err := d.SetFile(fileName, data); err != nil {
+			return err
+		}
+	}
+	if !found {
+		return fmt.Errorf("drawing %s not found", name)
+	}
+	return nil
+}
+
+// SetTableHeaderRows marks the first rows of a table as header rows (<w:tblHeader/>).
+// Header rows are announced by screen readers and repeated on every page.
+// Tables are numbered in document order, starting at 0. Nested tables are included in the numbering.
+func (d *Document) SetTableHeaderRows(table, rows int) error {
+	data := d.GetFile(DocumentXml)
+	rowPositions, err := findTableRows(data, table)
+	if err != nil {
+		return err
+	}
+	if rows > len(rowPositions) {
+		return fmt.Errorf("table %d has only %d rows", table, len(rowPositions))
+	}
+
+	// insert from the back, so the earlier positions stay valid
+	for i := rows - 1; i >= 0; i-- {
+		data = markTableHeaderRow(data, int(rowPositions[i]))
+	}
+	return d.SetFile(DocumentXml, data)
+}
+
+// findTableRows returns the end positions of the <w:tr> open tags of all rows of the given table.
+// Rows of tables nested inside the table are not included.
+func findTableRows(data []byte, table int) ([]int64, error) {
+	docReader := NewReader(string(data))
+	decoder := xml.NewDecoder(docReader)
+
+	tableCount := -1
+	var tableStack []int
+	var rows []int64
+	for {
+		tok, err := decoder.Token()
+		if tok == nil || err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error getting token: %s", err)
+		}
+
+		switch elem := tok.(type) {
+		case xml.StartElement:
+			switch elem.Name.Local {
+			case TableElementName:
+				tableCount++
+				tableStack = append(tableStack, tableCount)
+			case TableRowElementName:
+				if len(tableStack) > 0 && tableStack[len(tableStack)-1] == table {
+					rows = append(rows, docReader.Pos())
+				}
+			}
+		case xml.EndElement:
+			if elem.Name.Local == TableElementName && len(tableStack) > 0 {
+				tableStack = tableStack[:len(tableStack)-1]
+			}
+		}
+	}
+
+	if table < 0 || table > tableCount {
+		return nil, fmt.Errorf("table %d not found, the document has %d tables", table, tableCount+1)
+	}
+	return rows, nil
+}
+
+// markTableHeaderRow adds <w:tblHeader/> to the row properties of the row whose open tag ends at rowStart.
+// According to the schema, the row properties follow the optional table property exceptions (<w:tblPrEx>).
+func markTableHeaderRow(data []byte, rowStart int) []byte {
+	pos := rowStart
+	skipSpace := func() {
+		for pos < len(data) && (data[pos] == ' ' || data[pos] == '\n' || data[pos] == '\r' || data[pos] == '\t') {
+			pos++
+		}
+	}
+
+	skipSpace()
+	if bytes.HasPrefix(data[pos:], []byte("<w:tblPrEx")) {
+		if end := bytes.Index(data[pos:], []byte("</w:tblPrEx>")); end >= 0 {
+			pos += end + len("</w:tblPrEx>")
+		}
+		skipSpace()
+	}
+
+	switch {
+	case bytes.HasPrefix(data[pos:], []byte("<w:trPr/>")):
+		replaced := append([]byte{}, data[:pos]...)
+		replaced = append(replaced, []byte("<w:trPr><w:tblHeader/></w:trPr>")...)
+		return append(replaced, data[pos+len("<w:trPr/>"):]...)
+	case bytes.HasPrefix(data[pos:], []byte("<w:trPr>")):
+		end := bytes.Index(data[pos:], []byte("</w:trPr>"))
+		if end >= 0 && bytes.Contains(data[pos:pos+end], []byte("<w:tblHeader")) {
+			return data
+		}
+		return insertAt(data, pos+len("<w:trPr>"), []byte("<w:tblHeader/>"))
+	default:
+		return insertAt(data, pos, []byte("<w:trPr><w:tblHeader/></w:trPr>"))
+	}
+}
+
+// Language returns the default language of the document (e.g. en-US) as defined in the document defaults.
+func (d *Document) Language() string {
+	styles, exists := d.loadPackageFile(StylesXml)
+	if !exists {
+		return ""
+	}
+	lang := langTagRegex.Find(docDefaultsRegex.Find(styles))
+	if lang == nil {
+		return ""
+	}
+	value, _ := getTagAttr(lang, "w:val")
+	return value
+}
+
+// SetLanguage sets the default language of the document, e.g. en-US or de-DE.
+// The language is used by screen readers as well as spell checking and is stored in the document defaults
+// of the styles part and, if present, as theme font language in the settings. Templates are rendered in the
+// language: their localized variants of the parts and clauses are used, see language.go.
+func (d *Document) SetLanguage(lang string) error {
+	d.templateReplacer.language = lang
+	styles, exists := d.loadPackageFile(StylesXml)
+	if !exists {
+		return fmt.Errorf("invalid docx archive, %s is missing", StylesXml)
+	}
+
+	langTag := []byte(`<w:lang w:val="` + xmlEscape(lang) + `"/>`)
+	if defaults := docDefaultsRegex.FindIndex(styles); defaults != nil {
+		section := setDefaultLanguage(styles[defaults[0]:defaults[1]], lang, langTag)
+		styles = append(append(append([]byte{}, styles[:defaults[0]]...), section...), styles[defaults[1]:]...)
+	} else if root := stylesOpenTagRegex.FindIndex(styles); root != nil {
+		styles = insertAt(styles, root[1], []byte("<w:docDefaults><w:rPrDefault><w:rPr>"+string(langTag)+"</w:rPr></w:rPrDefault></w:docDefaults>"))
+	} else {
+		return fmt.Errorf("invalid styles part %s", StylesXml)
+	}
+	d.packageFiles[StylesXml] = styles
+
+	if settings, exists := d.loadPackageFile(SettingsXml); exists {
+		d.packageFiles[SettingsXml] = themeFontLangTagRegex.ReplaceAllFunc(settings, func(tag []byte) []byte {
+			return setTagAttr(tag, "w:val", lang)
+		})
+	}
+	return nil
+}
+
+// setDefaultLanguage sets the language in the document defaults. An existing <w:lang> is updated, otherwise
+// the language is added to the existing default run properties, which are only created if they are missing.
+func setDefaultLanguage(defaults []byte, lang string, langTag []byte) []byte {
+	if langTagRegex.Match(defaults) {
+		return langTagRegex.ReplaceAllFunc(defaults, func(tag []byte) []byte {
+			return setTagAttr(tag, "w:val", lang)
+		})
+	}
+	if bytes.HasSuffix(defaults, []byte("/>")) {
+		return []byte("<w:docDefaults><w:rPrDefault><w:rPr>" + string(langTag) + "</w:rPr></w:rPrDefault></w:docDefaults>")
+	}
+
+	rPrDefault := rPrDefaultRegex.FindIndex(defaults)
+	if rPrDefault == nil {
+		// the default run properties precede the default paragraph properties
+		open := bytes.IndexByte(defaults, '>') + 1
+		return insertAt(defaults, open, []byte("<w:rPrDefault><w:rPr>"+string(langTag)+"</w:rPr></w:rPrDefault>"))
+	}
+	element := defaults[rPrDefault[0]:rPrDefault[1]]
+	var replacement []byte
+	if properties := defaultRunPropertiesRegex.FindIndex(element); properties != nil {
+		rPr := element[properties[0]:properties[1]]
+		if bytes.HasSuffix(rPr, []byte("/>")) {
+			rPr = []byte("<w:rPr>" + string(langTag) + "</w:rPr>")
+		} else {
+			open := bytes.IndexByte(rPr, '>') + 1
+			closing := len(rPr) - len("</w:rPr>")
+			content := insertChild(rPr[open:closing], langTag, runPropertyFollowers("w:lang"))
+			rPr = append(append(append([]byte{}, rPr[:open]...), content...), rPr[closing:]...)
+		}
+		replacement = append(append(append([]byte{}, element[:properties[0]]...), rPr...), element[properties[1]:]...)
+	} else if bytes.HasSuffix(element, []byte("/>")) {
+		replacement = []byte("<w:rPrDefault><w:rPr>" + string(langTag) + "</w:rPr></w:rPrDefault>")
+	} else {
+		open := bytes.IndexByte(element, '>') + 1
+		replacement = insertAt(element, open, []byte("<w:rPr>"+string(langTag)+"</w:rPr>"))
+	}
+	return append(append(append([]byte{}, defaults[:rPrDefault[0]]...), replacement...), defaults[rPrDefault[1]:]...)
+}
+
+// xmlParts returns the paths of all XML parts which contain document content: the main document,
+// all headers and footers, the footnotes, the endnotes and the comments.
+func (d *Document) xmlParts() []string {
+	parts := []string{DocumentXml}
+	parts = append(parts, d.headerFiles...)
+	parts = append(parts, d.footerFiles...)
+	return append(parts, d.noteFiles...)
+}
+
+// isXmlPart returns true if the file is one of the XML parts processed by templates, see xmlParts.
+func (d *Document) isXmlPart(fileName string) bool {
+	for _, part := range d.xmlParts() {
+		if part == fileName {
+			return true
+		}
+	}
+	return false
+}
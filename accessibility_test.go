@@ -0,0 +1,139 @@
+package docx
+
+import (
+	"strings"
+	"testing"
+)
+
+// drawingXML returns a paragraph with an inline drawing with the given non-visual properties.
+func drawingXML(properties string) string {
+	return `<w:p><w:r><w:drawing><wp:inline><wp:extent cx="952500" cy="952500"/><wp:docPr ` + properties + `/></wp:inline></w:drawing></w:r></w:p>`
+}
+
+func TestDocument_MissingAltText(t *testing.T) {
+	doc := openBody(t, drawingXML(`id="1" name="Logo" descr="Company logo"`)+
+		drawingXML(`id="2" name="Photo"`)+
+		drawingXML(`id="3" name="Chart" descr=""`)+
+		drawingXML(`id="4" name="Signature" title="Signature" descr="Signature of the CEO"`))
+	defer doc.Close()
+
+	if drawings := doc.Drawings(); len(drawings) != 4 || drawings[0].AltText != "Company logo" || drawings[3].Title != "Signature" {
+		t.Fatalf("unexpected drawings %+v", drawings)
+	}
+	missing := doc.MissingAltText()
+	if len(missing) != 2 || missing[0].Name != "Photo" || missing[1].Name != "Chart" {
+		t.Fatalf("expected the drawings Photo and Chart without alt-text, got %+v", missing)
+	}
+	if missing[0].ID != "2" || missing[0].FileName != DocumentXml {
+		t.Errorf("unexpected drawing %+v", missing[0])
+	}
+}
+
+func TestDocument_SetImageAltText(t *testing.T) {
+	tests := []struct {
+		name     string
+		altText  string
+		expected string
+		err      bool
+	}{
+		{"Photo", "Team photo", `descr="Team photo"`, false},
+		{"Logo", "New logo", `descr="New logo"`, false},
+		{"Chart", `Revenue <2024> & "profit"`, `descr="Revenue &lt;2024&gt; &amp; &#34;profit&#34;"`, false},
+		{"Missing", "Text", "", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			doc := openBody(t, drawingXML(`id="1" name="Logo" descr="Company logo"`)+
+				drawingXML(`id="2" name="Photo"`)+
+				drawingXML(`id="3" name="Chart"`))
+			defer doc.Close()
+
+			err := doc.SetImageAltText(test.name, test.altText)
+			if test.err {
+				if err == nil {
+					t.Fatalf("expected an error for the drawing %s", test.name)
+				}
+				if len(doc.MissingAltText()) != 2 {
+					t.Errorf("expected the document to be unchanged")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if data := string(doc.GetFile(DocumentXml)); !strings.Contains(data, test.expected) {
+				t.Errorf("expected %s in %s", test.expected, data)
+			}
+			for _, drawing := range doc.Drawings() {
+				if drawing.Name == test.name && drawing.AltText != test.altText {
+					t.Errorf("expected the alt-text %q, got %q", test.altText, drawing.AltText)
+				}
+			}
+		})
+	}
+}
+
+func TestDocument_SetTableHeaderRows(t *testing.T) {
+	row := func(properties, text string) string {
+		return `<w:tr>` + properties + `<w:tc><w:p><w:r><w:t>` + text + `</w:t></w:r></w:p></w:tc></w:tr>`
+	}
+	table := func(rows ...string) string {
+		return `<w:tbl><w:tblPr><w:tblW w:w="0" w:type="auto"/></w:tblPr>` + strings.Join(rows, "") + `</w:tbl>`
+	}
+	header := `<w:trPr><w:tblHeader/></w:trPr>`
+	exceptions := `<w:tblPrEx><w:tblBorders><w:top w:val="single"/></w:tblBorders></w:tblPrEx>`
+	nested := `<w:tr><w:tc>` + table(row("", "Inner 1"), row("", "Inner 2")) + `<w:p/></w:tc></w:tr>`
+
+	tests := []struct {
+		name     string
+		body     string
+		table    int
+		rows     int
+		expected string
+		err      bool
+	}{
+		{"missing properties", table(row("", "A"), row("", "B")), 0, 1,
+			table(row(header, "A"), row("", "B")), false},
+		{"empty properties", table(row(`<w:trPr/>`, "A")), 0, 1,
+			table(row(header, "A")), false},
+		{"existing properties", table(row(`<w:trPr><w:cantSplit/></w:trPr>`, "A")), 0, 1,
+			table(row(`<w:trPr><w:tblHeader/><w:cantSplit/></w:trPr>`, "A")), false},
+		{"already marked", table(row(header, "A"), row("", "B")), 0, 2,
+			table(row(header, "A"), row(header, "B")), false},
+		{"table property exceptions", table(row(exceptions, "A"), row(exceptions+`<w:trPr><w:cantSplit/></w:trPr>`, "B")), 0, 2,
+			table(row(exceptions+header, "A"), row(exceptions+`<w:trPr><w:tblHeader/><w:cantSplit/></w:trPr>`, "B")), false},
+		{"outer table", table(row("", "Header"), nested, row("", "Last")), 0, 2,
+			table(row(header, "Header"), `<w:tr>`+header+`<w:tc>`+table(row("", "Inner 1"), row("", "Inner 2"))+`<w:p/></w:tc></w:tr>`, row("", "Last")), false},
+		{"nested table", table(row("", "Header"), nested), 1, 1,
+			table(row("", "Header"), `<w:tr><w:tc>`+table(row(header, "Inner 1"), row("", "Inner 2"))+`<w:p/></w:tc></w:tr>`), false},
+		{"second table", table(row("", "A")) + `<w:p/>` + table(row("", "B")), 1, 1,
+			table(row("", "A")) + `<w:p/>` + table(row(header, "B")), false},
+		{"too many rows", table(row("", "A"), row("", "B")), 0, 3, "", true},
+		{"missing table", table(row("", "A")), 1, 1, "", true},
+		{"negative table", table(row("", "A")), -1, 1, "", true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			doc := openBody(t, test.body)
+			defer doc.Close()
+
+			err := doc.SetTableHeaderRows(test.table, test.rows)
+			data := string(doc.GetFile(DocumentXml))
+			if test.err {
+				if err == nil {
+					t.Fatalf("expected an error for %d rows of table %d", test.rows, test.table)
+				}
+				if !strings.Contains(data, test.body) {
+					t.Errorf("expected the document to be unchanged, got %s", data)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(data, "<w:body>"+test.expected+"<w:sectPr") {
+				t.Errorf("expected %s in %s", test.expected, data)
+			}
+		})
+	}
+}
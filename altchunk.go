@@ -0,0 +1,65 @@
+package docx
+
+import (
+	"fmt"
+	"sort"
+)
+
+const (
+	// AltChunkRelationshipType is the relationship type of the content imported by <w:altChunk>.
+	AltChunkRelationshipType = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/aFChunk"
+
+	// AltChunkHTML is the content type of HTML content, see AddAltChunk.
+	AltChunkHTML = "text/html"
+	// AltChunkXHTML is the content type of XHTML content.
+	AltChunkXHTML = "application/xhtml+xml"
+	// AltChunkText is the content type of plain text.
+	AltChunkText = "text/plain"
+	// AltChunkRTF is the content type of RTF content.
+	AltChunkRTF = "application/rtf"
+	// AltChunkMHTML is the content type of a web archive (MHTML).
+	AltChunkMHTML = "message/rfc822"
+	// AltChunkDocx is the content type of a .docx document.
+	AltChunkDocx = "application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"
+)
+
+// altChunkExtensions maps the supported content types to the extension of the part.
+var altChunkExtensions = map[string]string{
+	AltChunkHTML:  "htm",
+	AltChunkXHTML: "xhtml",
+	AltChunkText:  "txt",
+	AltChunkRTF:   "rtf",
+	AltChunkMHTML: "mht",
+	AltChunkDocx:  "docx",
+}
+
+// AddAltChunk replaces the paragraph whose text is the anchor by an <w:altChunk> which references the payload.
+// The payload is added to the package as is, Word converts and merges it when the document is opened. This is
+// much faster than InsertClause, but the content is not visible to other applications before Word saved the
+// document. The content type is one of AltChunkHTML, AltChunkXHTML, AltChunkText, AltChunkRTF, AltChunkMHTML
+// and AltChunkDocx.
+func (d *Document) AddAltChunk(anchor, contentType string, payload []byte) error {
+	extension, supported := altChunkExtensions[contentType]
+	if !supported {
+		types := make([]string, 0, len(altChunkExtensions))
+		for t := range altChunkExtensions {
+			types = append(types, t)
+		}
+		sort.Strings(types)
+		return fmt.Errorf("unsupported content type %s for an altChunk, expected one of %v", contentType, types)
+	}
+	paragraph, err := d.anchorParagraph(anchor)
+	if err != nil {
+		return err
+	}
+
+	partName := nextPartName("word/afchunk", "."+extension, d.partNames())
+	if err := d.addPart(partName, payload, contentType); err != nil {
+		return err
+	}
+	id, err := d.addRelationship(DocumentXml, AltChunkRelationshipType, partName)
+	if err != nil {
+		return err
+	}
+	return d.replaceParagraphXml(paragraph, []byte(`<w:altChunk r:id="`+id+`"/>`), 0)
+}
@@ -0,0 +1,67 @@
+package docx
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDocument_AddAltChunk(t *testing.T) {
+	doc, err := OpenBytes(rewriteArchive(t, readFile(t, "./test/template.docx"), func(name string, data []byte) []byte {
+		if name == DocumentXml {
+			return []byte(strings.Replace(string(data), "<w:body>", "<w:body><w:p><w:r><w:t>[HTML]</w:t></w:r></w:p>"+
+				"<w:tbl><w:tr><w:tc><w:p><w:r><w:t>[DOCX]</w:t></w:r></w:p></w:tc></w:tr></w:tbl>", 1))
+		}
+		return data
+	}, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+
+	html := []byte("<html><body><p>Hello <b>World</b></p></body></html>")
+	if err := doc.AddAltChunk("[HTML]", AltChunkHTML, html); err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.AddAltChunk("[DOCX]", AltChunkDocx, readFile(t, "./test/template.docx")); err != nil {
+		t.Fatal(err)
+	}
+
+	targets := doc.relationshipTargets(DocumentXml, AltChunkRelationshipType)
+	if len(targets) != 2 || targets[0] != "word/afchunk1.htm" || targets[1] != "word/afchunk1.docx" {
+		t.Fatalf("unexpected altChunk parts %v", targets)
+	}
+	if data, _ := doc.partData(targets[0]); !bytes.Equal(data, html) {
+		t.Errorf("the payload was not added")
+	}
+	documentXml := string(doc.GetFile(DocumentXml))
+	if strings.Contains(documentXml, "[HTML]") || strings.Count(documentXml, "<w:altChunk r:id=") != 2 {
+		t.Errorf("the anchors were not replaced")
+	}
+	if !strings.Contains(documentXml, `"/><w:p/></w:tc>`) {
+		t.Errorf("the table cell must end with a paragraph")
+	}
+
+	var buf bytes.Buffer
+	if err := doc.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	written, err := OpenBytes(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	types, err := written.packageContentTypes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if types.lookup("/word/afchunk1.htm") != AltChunkHTML || types.lookup("/word/afchunk1.docx") != AltChunkDocx {
+		t.Errorf("the content types were not registered")
+	}
+
+	if err := doc.AddAltChunk("[MISSING]", AltChunkHTML, html); err == nil {
+		t.Errorf("expected an error for a missing anchor")
+	}
+	if err := doc.AddAltChunk("[DOCX]", "application/pdf", nil); err == nil {
+		t.Errorf("expected an error for an unsupported content type")
+	}
+}
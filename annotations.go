@@ -0,0 +1,58 @@
+package docx
+
+import (
+	"regexp"
+	"strings"
+)
+
+// annotations.go implements type annotations of placeholders, e.g. {{.amount /*type:money*/}} or
+// {{.start /*type:date*/}}. The annotations are removed before the actions are executed, ListTemplatePlaceholders
+// returns them, so data-entry forms can offer suitable inputs such as date pickers or currency fields. Types of
+// fields without annotation can be declared with SetFieldTypes, e.g. from a sidecar file of the template.
+
+// annotationRegex matches a type annotation inside an action, including the white space before it.
+var annotationRegex = regexp.MustCompile(`\s*/\*\s*type:\s*([\w.-]+)\s*\*/`)
+
+// SetFieldTypes declares the types of fields whose placeholders have no annotation, by the path of the field,
+// e.g. {"amount": "money", "customer.birthday": "date"}. ListTemplatePlaceholders and the hooks added with
+// OnReplace get the types.
+func (d *Document) SetFieldTypes(types map[string]string) {
+	d.templateReplacer.fieldTypes = types
+}
+
+// ListTemplatePlaceholders returns the placeholders of all parts in document order with the fields they reference
+// and their types. Placeholders inside loops reference the fields of the items, e.g. name for {{.name}} inside
+// {{range .customers}}.
+func (d *Document) ListTemplatePlaceholders() ([]PlaceholderInfo, error) {
+	var infos []PlaceholderInfo
+	for _, part := range d.xmlParts() {
+		placeholders, err := ParseTemplatePlaceholders(d.runParsers[part].Runs(), d.GetFile(part), part)
+		if err != nil {
+			return nil, err
+		}
+		for _, placeholder := range placeholders {
+			infos = append(infos, d.templateReplacer.placeholderInfo(part, placeholder.TemplateContent))
+		}
+	}
+	return infos, nil
+}
+
+// placeholderInfo returns the field and the type of the placeholder.
+func (tr *TemplateReplacer) placeholderInfo(part, placeholder string) PlaceholderInfo {
+	info := PlaceholderInfo{Part: part, Placeholder: placeholder}
+	info.Field = strings.Join(actionField(stripAnnotations(placeholder)), ".")
+	if match := annotationRegex.FindStringSubmatch(placeholder); match != nil {
+		info.Type = match[1]
+	} else if info.Field != "" {
+		info.Type = tr.fieldTypes[info.Field]
+	}
+	return info
+}
+
+// stripAnnotations removes the type annotations from the actions, so they can be parsed by text/template.
+func stripAnnotations(actions string) string {
+	if !strings.Contains(actions, "/*") {
+		return actions
+	}
+	return annotationRegex.ReplaceAllString(actions, "")
+}
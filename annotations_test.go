@@ -0,0 +1,56 @@
+package docx
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDocument_ListTemplatePlaceholders(t *testing.T) {
+	doc, err := NewDocument().
+		Paragraph(`Total: {{.amount /*type:money*/ | printf "%.2f"}} due on {{.due /* type: date */}}`).
+		Paragraph(`{{range .items /*type:list*/}}`).Paragraph(`{{.name}}: {{.count}}`).Paragraph(`{{end}}`).
+		Paragraph(`Signed by {{.customer.name}}`).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+	doc.SetFieldTypes(map[string]string{"customer.name": "text", "count": "number"})
+
+	placeholders, err := doc.ListTemplatePlaceholders()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields []string
+	for _, placeholder := range placeholders {
+		fields = append(fields, placeholder.Field+":"+placeholder.Type)
+	}
+	expected := "amount:money due:date items:list name: count:number : customer.name:text"
+	if text := strings.Join(fields, " "); text != expected {
+		t.Errorf("expected %q, got %q", expected, text)
+	}
+	if placeholders[1].Placeholder != "{{.due /* type: date */}}" || placeholders[1].Part != DocumentXml {
+		t.Errorf("unexpected placeholder %+v", placeholders[1])
+	}
+
+	var types []string
+	doc.OnReplace(func(p PlaceholderInfo, value string) string {
+		types = append(types, p.Type)
+		return value
+	})
+	data := map[string]interface{}{"amount": 12.5, "due": "2024-07-01", "items": []map[string]interface{}{{"name": "Desk", "count": 2}},
+		"customer": map[string]interface{}{"name": "Jane"}}
+	if err := doc.ExecuteTemplate(data); err != nil {
+		t.Fatal(err)
+	}
+	texts, err := paragraphTexts(doc.GetFile(DocumentXml))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := strings.Join(texts, "|"); text != "Total: 12.50 due on 2024-07-01|Desk: 2|Signed by Jane" {
+		t.Errorf("unexpected text %q", text)
+	}
+	if strings.Join(types, " ") != " number text date money" {
+		t.Errorf("unexpected types of the hooks %q", types)
+	}
+}
@@ -0,0 +1,178 @@
+package docx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"regexp"
+	"strings"
+)
+
+const (
+	// OLEObjectRelationshipType is the relationship type of embedded OLE objects.
+	OLEObjectRelationshipType = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/oleObject"
+
+	// oleObjectContentType is the content type of the compound files of embedded OLE objects.
+	oleObjectContentType = "application/vnd.openxmlformats-officedocument.oleObject"
+)
+
+var (
+	// paragraphPropertiesRegex matches the properties of a paragraph.
+	paragraphPropertiesRegex = regexp.MustCompile(`(?s)<w:pPr>.*?</w:pPr>`)
+	// packageCLSID is the class id of OLE packages (the "Package" ProgID), which wrap arbitrary files.
+	packageCLSID = [16]byte{0x0C, 0x00, 0x03, 0x00, 0x00, 0x00, 0x00, 0x00, 0xC0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x46}
+)
+
+// AttachFile replaces the paragraph whose text is the anchor by an icon of the file, which is embedded as OLE
+// package (word/embeddings/oleObjectN.bin). Double-clicking the icon in Word opens the file, e.g. the source data
+// of a generated report. The icon is PNG, JPEG, GIF, EMF, WMF or TIFF data, nil uses a paperclip icon. The
+// paragraph properties of the anchor paragraph are kept.
+func (d *Document) AttachFile(anchor, filename string, data, icon []byte) error {
+	if filename == "" {
+		return fmt.Errorf("the file name of the attachment is empty")
+	}
+	paragraph, err := d.anchorParagraph(anchor)
+	if err != nil {
+		return err
+	}
+	if icon == nil {
+		icon = paperclipIcon()
+	}
+	icon, format, err := d.prepareImage(icon)
+	if err != nil {
+		return fmt.Errorf("invalid icon: %w", err)
+	}
+	if format.Width == 0 || format.Height == 0 {
+		return fmt.Errorf("the size of the %s icon is unknown", format.Name)
+	}
+
+	partName := nextPartName("word/embeddings/oleObject", ".bin", d.partNames())
+	if err := d.addPart(partName, olepackage(filename, data), oleObjectContentType); err != nil {
+		return err
+	}
+	objectID, err := d.addRelationship(DocumentXml, OLEObjectRelationshipType, partName)
+	if err != nil {
+		return err
+	}
+	iconName, err := d.addMedia("image", icon, format.Extension, format.ContentType)
+	if err != nil {
+		return err
+	}
+	iconID, err := d.addRelationship(DocumentXml, ImageRelationshipType, iconName)
+	if err != nil {
+		return err
+	}
+
+	documentXml := d.GetFile(DocumentXml)
+	var properties []byte
+	if match := paragraphPropertiesRegex.Find(documentXml[paragraph.Start:paragraph.End]); match != nil {
+		properties = match
+	}
+	// the shape type of pictures is declared once per part, Word writes it in front of the first shape using it
+	var shapeType string
+	if !bytes.Contains(documentXml, []byte(`id="_x0000_t75"`)) {
+		shapeType = `<v:shapetype id="_x0000_t75" coordsize="21600,21600" o:spt="75" o:preferrelative="t" ` +
+			`path="m@4@5l@4@11@9@11@9@5xe" filled="f" stroked="f"><v:stroke joinstyle="miter"/>` +
+			`<o:lock v:ext="edit" aspectratio="t"/></v:shapetype>`
+	}
+
+	// the size of VML shapes is given in points, the original size of the object in twips
+	id := d.nextDrawingID()
+	width, height := float64(format.Width)*0.75, float64(format.Height)*0.75
+	object := fmt.Sprintf(`<w:p>%s<w:r>`+
+		`<w:object w:dxaOrig="%d" w:dyaOrig="%d" xmlns:v="urn:schemas-microsoft-com:vml" xmlns:o="urn:schemas-microsoft-com:office:office">%s`+
+		`<v:shape id="_x0000_i%d" type="#_x0000_t75" alt="%s" style="width:%gpt;height:%gpt" o:ole="">`+
+		`<v:imagedata r:id="%s" o:title=""/></v:shape>`+
+		`<o:OLEObject Type="Embed" ProgID="Package" ShapeID="_x0000_i%d" DrawAspect="Icon" ObjectID="_%d" r:id="%s"/>`+
+		`</w:object></w:r></w:p>`,
+		properties, int(width*20), int(height*20), shapeType, 1024+id, xmlEscape(filename), width, height, iconID,
+		1024+id, 1000000000+id, objectID)
+	return d.replaceParagraphXml(paragraph, []byte(object), 0)
+}
+
+// olepackage returns the compound file of an OLE package which contains the file. Word shows the name of the
+// file below the icon and saves the data to a temporary file of that name when the package is opened.
+func olepackage(filename string, data []byte) []byte {
+	name := filename[strings.LastIndexAny(filename, `/\`)+1:]
+
+	// \x01Ole: version, flags (embedded object) and no moniker
+	ole := []byte{0x01, 0x00, 0x00, 0x02}
+	ole = append(ole, make([]byte, 16)...)
+
+	// \x01CompObj: version, byte order, OS version, the class id, the user type and the clipboard format
+	compObj := []byte{0x01, 0x00, 0xFE, 0xFF, 0x03, 0x0A, 0x00, 0x00, 0xFF, 0xFF, 0xFF, 0xFF}
+	compObj = append(compObj, packageCLSID[:]...)
+	compObj = appendLengthPrefixedString(compObj, "OLE Package")
+	compObj = binary.LittleEndian.AppendUint32(compObj, 0) // no clipboard format
+	compObj = appendLengthPrefixedString(compObj, "Package")
+	compObj = binary.LittleEndian.AppendUint32(compObj, 0x71B239F4) // no Unicode strings follow
+	compObj = append(compObj, make([]byte, 12)...)
+
+	// \x01Ole10Native: the label, the original and the temporary path of the file and its data
+	var native []byte
+	native = binary.LittleEndian.AppendUint16(native, 2)
+	native = append(append(native, name...), 0)
+	native = append(append(native, filename...), 0)
+	native = binary.LittleEndian.AppendUint16(native, 0)
+	native = binary.LittleEndian.AppendUint16(native, 3)
+	native = appendLengthPrefixedString(native, filename)
+	native = binary.LittleEndian.AppendUint32(native, uint32(len(data)))
+	native = append(native, data...)
+	native = append(binary.LittleEndian.AppendUint32(nil, uint32(len(native))), native...)
+
+	return writeCompoundFile(packageCLSID, []cfbStream{
+		{Name: "\x01Ole", Data: ole},
+		{Name: "\x01CompObj", Data: compObj},
+		{Name: "\x01Ole10Native", Data: native},
+	})
+}
+
+// appendLengthPrefixedString appends the null terminated string preceded by its length (including the null).
+func appendLengthPrefixedString(out []byte, s string) []byte {
+	out = binary.LittleEndian.AppendUint32(out, uint32(len(s)+1))
+	return append(append(out, s...), 0)
+}
+
+// paperclipIcon returns the default icon of attachments, a paperclip of 32x32 pixels.
+func paperclipIcon() []byte {
+	const size = 32
+	img := image.NewNRGBA(image.Rect(0, 0, size, size))
+	clip := color.NRGBA{R: 0x59, G: 0x59, B: 0x59, A: 0xFF}
+
+	// the wire of the paperclip consists of straight lines and half circles, which are open at the bottom (top
+	// false) or at the top; the distance of a pixel to the wire gives its coverage, which smooths the edges
+	lines := [][3]float64{{23, 9, 23}, {9, 9, 26}, {18, 12, 26}, {13, 12, 22}} // x, top and bottom
+	arcs := []struct {
+		x, y, radius float64
+		top          bool
+	}{{16, 9, 7, true}, {13.5, 26, 4.5, false}, {15.5, 12, 2.5, true}}
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			px, py := float64(x)+0.5, float64(y)+0.5
+			distance := math.Inf(1)
+			for _, line := range lines {
+				distance = math.Min(distance, math.Hypot(px-line[0], py-math.Max(line[1], math.Min(line[2], py))))
+			}
+			for _, arc := range arcs {
+				if (py <= arc.y) == arc.top {
+					distance = math.Min(distance, math.Abs(math.Hypot(px-arc.x, py-arc.y)-arc.radius))
+				}
+			}
+			if coverage := 1.3 - distance; coverage > 0 {
+				c := clip
+				c.A = uint8(math.Min(coverage, 1) * 0xFF)
+				img.SetNRGBA(x, y, c)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		panic(err) // encoding an in-memory image does not fail
+	}
+	return buf.Bytes()
+}
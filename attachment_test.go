@@ -0,0 +1,91 @@
+package docx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+func TestDocument_AttachFile(t *testing.T) {
+	doc, err := OpenBytes(rewriteArchive(t, readFile(t, "./test/template.docx"), func(name string, data []byte) []byte {
+		if name == DocumentXml {
+			return []byte(strings.Replace(string(data), "<w:body>", `<w:body><w:p><w:pPr><w:jc w:val="center"/></w:pPr>`+
+				`<w:r><w:t>[DATA]</w:t></w:r></w:p><w:p><w:r><w:t>[SOURCE]</w:t></w:r></w:p>`, 1))
+		}
+		return data
+	}, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+
+	csv := []byte("name,amount\nAlice,10\nBob,20\n")
+	if err := doc.AttachFile("[DATA]", "data.csv", csv, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.AttachFile("[SOURCE]", `C:\reports\source.xml`, bytes.Repeat([]byte("<x/>"), 2000), testMetafiles()["emf"]); err != nil {
+		t.Fatal(err)
+	}
+
+	objects := doc.relationshipTargets(DocumentXml, OLEObjectRelationshipType)
+	if len(objects) != 2 || objects[0] != "word/embeddings/oleObject1.bin" || objects[1] != "word/embeddings/oleObject2.bin" {
+		t.Fatalf("unexpected embedded objects %v", objects)
+	}
+	data, _ := doc.partData(objects[0])
+	clsid, streams := readCompoundFile(t, data)
+	if clsid != packageCLSID || len(streams) != 3 || !bytes.Contains(streams["\x01CompObj"], []byte("Package\x00")) {
+		t.Fatalf("unexpected OLE package %x %q", clsid, streams)
+	}
+	native := streams["\x01Ole10Native"]
+	if int(binary.LittleEndian.Uint32(native)) != len(native)-4 || !bytes.HasPrefix(native[6:], []byte("data.csv\x00data.csv\x00")) ||
+		!bytes.HasSuffix(native, csv) {
+		t.Errorf("unexpected native data %q", native)
+	}
+	data, _ = doc.partData(objects[1])
+	if _, streams = readCompoundFile(t, data); !bytes.HasPrefix(streams["\x01Ole10Native"][6:], []byte("source.xml\x00C:\\reports\\source.xml\x00")) {
+		t.Errorf("the label must be the base name of the file")
+	}
+
+	documentXml := string(doc.GetFile(DocumentXml))
+	if strings.Contains(documentXml, "[DATA]") || strings.Count(documentXml, "<w:object ") != 2 ||
+		strings.Count(documentXml, `<v:shapetype id="_x0000_t75"`) != 1 {
+		t.Errorf("the anchors were not replaced by objects")
+	}
+	if !strings.Contains(documentXml, `<w:p><w:pPr><w:jc w:val="center"/></w:pPr><w:r><w:object w:dxaOrig="480" w:dyaOrig="480"`) {
+		t.Errorf("the paragraph properties or the size of the default icon were not kept")
+	}
+	if !strings.Contains(documentXml, `style="width:72pt;height:36pt"`) {
+		t.Errorf("the size of the EMF icon was not used")
+	}
+	icons := doc.relationshipTargets(DocumentXml, ImageRelationshipType)
+	if icon := icons[len(icons)-1]; !strings.HasSuffix(icon, ".emf") {
+		t.Errorf("the icon was not added, got %s", icon)
+	}
+
+	var buf bytes.Buffer
+	if err := doc.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	written, err := OpenBytes(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	types, err := written.packageContentTypes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if contentType := types.lookup("/word/embeddings/oleObject1.bin"); contentType != oleObjectContentType {
+		t.Errorf("unexpected content type %s", contentType)
+	}
+	if data, _ := written.partData("word/embeddings/oleObject2.bin"); len(data) == 0 {
+		t.Errorf("the embedded object was not written")
+	}
+
+	if err := doc.AttachFile("[MISSING]", "data.csv", csv, nil); err == nil {
+		t.Errorf("expected an error for a missing anchor")
+	}
+	if err := doc.AttachFile("[DATA]", "", csv, nil); err == nil {
+		t.Errorf("expected an error for an empty file name")
+	}
+}
@@ -0,0 +1,222 @@
+package docx
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"runtime/debug"
+	"strconv"
+	"time"
+)
+
+// audit.go implements the audit record of a rendered document, which records how the document was generated and is
+// read back from the document later, e.g. to prove which template and data a contract was generated from.
+
+const (
+	// AuditNamespace is the namespace of the custom XML part holding the audit record.
+	AuditNamespace = "https://github.com/izetmolla/docx/audit"
+	// CustomPropertiesContentType is the content type of the custom properties (docProps/custom.xml).
+	CustomPropertiesContentType = "application/vnd.openxmlformats-officedocument.custom-properties+xml"
+	// customPropertiesFormatID is the format id of user-defined custom properties.
+	customPropertiesFormatID = "{D5CDD505-2E9C-101B-9397-08002B2CF9AE}"
+	// modulePath is the module path of this package, its version is the renderer version of the audit records.
+	modulePath = "github.com/izetmolla/docx"
+)
+
+var (
+	// customPropertyRegex matches a custom property, the first group is its name.
+	customPropertyRegex = regexp.MustCompile(`(?s)<property\b[^>]*\sname="([^"]*)"[^>]*>.*?</property>`)
+	// propertyIDRegex matches the property id of a custom property.
+	propertyIDRegex = regexp.MustCompile(`\spid="([0-9]+)"`)
+)
+
+// AuditStorage selects where SetAuditRecord stores the audit record.
+type AuditStorage int
+
+const (
+	// AuditCustomXML stores the record in a custom XML part, which is not shown to readers.
+	AuditCustomXML AuditStorage = iota
+	// AuditProperties stores the record as custom document properties, which Word shows in the properties dialog.
+	AuditProperties
+)
+
+// auditPropertyPrefix is the prefix of the names of the custom properties of the audit record.
+const auditPropertyPrefix = "Audit"
+
+// AuditRecord records how a document was rendered, see SetAuditRecord.
+type AuditRecord struct {
+	TemplateHash    string    `xml:"templateHash"`    // The SHA-256 hash of the template file
+	DataHash        string    `xml:"dataHash"`        // The SHA-256 hash of the data encoded as JSON
+	RendererVersion string    `xml:"rendererVersion"` // The version of this package, e.g. v1.4.0
+	Rendered        time.Time `xml:"rendered"`        // When the document was rendered
+	UserID          string    `xml:"userId"`          // The user who rendered the document
+}
+
+// auditRecordXml is the custom XML part of an audit record.
+type auditRecordXml struct {
+	XMLName xml.Name `xml:"https://github.com/izetmolla/docx/audit audit"`
+	AuditRecord
+}
+
+// NewAuditRecord returns the audit record of rendering the template with the data by the user, rendered now.
+// The data is hashed in its JSON encoding, maps are encoded with sorted keys, so equal data has equal hashes.
+func NewAuditRecord(tpl *Template, data interface{}, userID string) (AuditRecord, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return AuditRecord{}, fmt.Errorf("unable to hash the data: %w", err)
+	}
+	return AuditRecord{
+		TemplateHash:    contentHash(tpl.data),
+		DataHash:        contentHash(encoded),
+		RendererVersion: rendererVersion(),
+		Rendered:        time.Now().UTC().Truncate(time.Second),
+		UserID:          userID,
+	}, nil
+}
+
+// rendererVersion returns the version of this package from the build information of the binary, (devel) if it
+// is unknown.
+func rendererVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "(devel)"
+	}
+	if info.Main.Path == modulePath && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	for _, dep := range info.Deps {
+		if dep.Path == modulePath {
+			return dep.Version
+		}
+	}
+	return "(devel)"
+}
+
+// SetAuditRecord embeds the audit record into the document, replacing an existing record stored the same way.
+func (d *Document) SetAuditRecord(record AuditRecord, storage AuditStorage) error {
+	if storage == AuditProperties {
+		return d.setAuditProperties(record)
+	}
+	data, err := xml.Marshal(auditRecordXml{AuditRecord: record})
+	if err != nil {
+		return err
+	}
+	data = append([]byte(xmlDeclaration), data...)
+	customXML := d.CustomXML()
+	if part, exists := d.auditPart(); exists {
+		return customXML.Replace(part.ID, data)
+	}
+	_, err = customXML.Add(data)
+	return err
+}
+
+// AuditRecord returns the audit record embedded into the document by SetAuditRecord. A record of a custom XML
+// part takes precedence over a record of the custom properties.
+func (d *Document) AuditRecord() (AuditRecord, bool) {
+	if part, exists := d.auditPart(); exists {
+		var record auditRecordXml
+		if err := xml.Unmarshal(part.Data, &record); err == nil {
+			return record.AuditRecord, true
+		}
+	}
+	data, exists := d.loadPackageFile(d.customPropertiesPart())
+	if !exists {
+		return AuditRecord{}, false
+	}
+	properties := customProperties(data)
+	if _, exists := properties[auditPropertyPrefix+"TemplateHash"]; !exists {
+		return AuditRecord{}, false
+	}
+	rendered, _ := time.Parse(time.RFC3339, properties[auditPropertyPrefix+"Rendered"])
+	return AuditRecord{
+		TemplateHash:    properties[auditPropertyPrefix+"TemplateHash"],
+		DataHash:        properties[auditPropertyPrefix+"DataHash"],
+		RendererVersion: properties[auditPropertyPrefix+"RendererVersion"],
+		Rendered:        rendered,
+		UserID:          properties[auditPropertyPrefix+"UserID"],
+	}, true
+}
+
+// ReadAuditRecord returns the audit record of the document at the path, see Document.AuditRecord.
+func ReadAuditRecord(path string) (AuditRecord, bool, error) {
+	doc, err := Open(path)
+	if err != nil {
+		return AuditRecord{}, false, err
+	}
+	defer doc.Close()
+	record, exists := doc.AuditRecord()
+	return record, exists, nil
+}
+
+// auditPart returns the custom XML part holding the audit record.
+func (d *Document) auditPart() (CustomXMLPart, bool) {
+	for _, part := range d.CustomXML().Parts() {
+		if bytes.Contains(part.Data, []byte(AuditNamespace)) {
+			return part, true
+		}
+	}
+	return CustomXMLPart{}, false
+}
+
+// customPropertiesPart returns the name of the custom properties part.
+func (d *Document) customPropertiesPart() string {
+	if targets := d.relationshipTargets("", CustomPropertiesRelationshipType); len(targets) > 0 {
+		return targets[0]
+	}
+	return "docProps/custom.xml"
+}
+
+// setAuditProperties stores the audit record as custom properties, replacing the properties of a previous record.
+func (d *Document) setAuditProperties(record AuditRecord) error {
+	values := []struct{ name, element string }{
+		{"TemplateHash", "<vt:lpwstr>" + xmlEscape(record.TemplateHash) + "</vt:lpwstr>"},
+		{"DataHash", "<vt:lpwstr>" + xmlEscape(record.DataHash) + "</vt:lpwstr>"},
+		{"RendererVersion", "<vt:lpwstr>" + xmlEscape(record.RendererVersion) + "</vt:lpwstr>"},
+		{"Rendered", "<vt:filetime>" + record.Rendered.UTC().Format(time.RFC3339) + "</vt:filetime>"},
+		{"UserID", "<vt:lpwstr>" + xmlEscape(record.UserID) + "</vt:lpwstr>"},
+	}
+
+	partName := d.customPropertiesPart()
+	data, exists := d.loadPackageFile(partName)
+	if !exists {
+		data = []byte(xmlDeclaration + `<Properties xmlns="http://schemas.openxmlformats.org/officeDocument/2006/custom-properties" ` +
+			`xmlns:vt="http://schemas.openxmlformats.org/officeDocument/2006/docPropsVTypes"></Properties>`)
+		if err := d.addPart(partName, data, CustomPropertiesContentType); err != nil {
+			return err
+		}
+		if _, err := d.addRelationship("", CustomPropertiesRelationshipType, partName); err != nil {
+			return err
+		}
+	}
+
+	names := make(map[string]bool)
+	for _, value := range values {
+		names[auditPropertyPrefix+value.name] = true
+	}
+	data = customPropertyRegex.ReplaceAllFunc(data, func(property []byte) []byte {
+		if names[string(customPropertyRegex.FindSubmatch(property)[1])] {
+			return nil
+		}
+		return property
+	})
+	// property ids start at 2 and are unique within the part
+	pid := 2
+	for _, match := range propertyIDRegex.FindAllSubmatch(data, -1) {
+		if id, _ := strconv.Atoi(string(match[1])); id >= pid {
+			pid = id + 1
+		}
+	}
+	var properties bytes.Buffer
+	for i, value := range values {
+		fmt.Fprintf(&properties, `<property fmtid="%s" pid="%d" name="%s">%s</property>`,
+			customPropertiesFormatID, pid+i, auditPropertyPrefix+value.name, value.element)
+	}
+	end := bytes.LastIndex(data, []byte("</Properties>"))
+	if end < 0 {
+		return fmt.Errorf("invalid custom properties %s", partName)
+	}
+	d.setPackageFile(partName, append(append(append([]byte{}, data[:end]...), properties.Bytes()...), data[end:]...))
+	return nil
+}
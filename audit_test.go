@@ -0,0 +1,72 @@
+package docx
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDocument_SetAuditRecord(t *testing.T) {
+	tpl, err := LoadTemplate("./test/template.docx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := map[string]interface{}{"key": "value", "amount": 42}
+	record, err := NewAuditRecord(tpl, data, "user-17")
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, _ := NewAuditRecord(tpl, map[string]interface{}{"amount": 42, "key": "value"}, "user-17")
+	if record.DataHash != again.DataHash || record.TemplateHash != contentHash(readFile(t, "./test/template.docx")) ||
+		record.RendererVersion == "" || time.Since(record.Rendered) > time.Minute {
+		t.Fatalf("unexpected audit record %+v", record)
+	}
+
+	for _, storage := range []AuditStorage{AuditCustomXML, AuditProperties} {
+		doc, err := OpenBytes(readFile(t, "./test/template.docx"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, exists := doc.AuditRecord(); exists {
+			t.Fatalf("the template has no audit record")
+		}
+		previous := record
+		previous.UserID = "user-1"
+		if err := doc.SetAuditRecord(previous, storage); err != nil {
+			t.Fatal(err)
+		}
+		if err := doc.SetAuditRecord(record, storage); err != nil {
+			t.Fatal(err)
+		}
+
+		var buf bytes.Buffer
+		if err := doc.Write(&buf); err != nil {
+			t.Fatal(err)
+		}
+		doc.Close()
+		written, err := OpenBytes(buf.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if read, exists := written.AuditRecord(); !exists || read != record {
+			t.Errorf("storage %d: expected %+v, got %+v", storage, record, read)
+		}
+		switch storage {
+		case AuditCustomXML:
+			if parts := written.CustomXML().Parts(); len(parts) != 1 {
+				t.Errorf("expected a single custom XML part, got %d", len(parts))
+			}
+		case AuditProperties:
+			data, _ := written.loadPackageFile("docProps/custom.xml")
+			custom := string(data)
+			if strings.Count(custom, `name="AuditUserID"`) != 1 || !strings.Contains(custom, `pid="2" name="AuditTemplateHash"`) {
+				t.Errorf("unexpected custom properties %s", custom)
+			}
+			if props := written.documentProperties(); props["audituserid"] != "user-17" {
+				t.Errorf("the audit record is not available as document property")
+			}
+		}
+		written.Close()
+	}
+}
@@ -0,0 +1,258 @@
+package docx
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// bidi.go implements the options for values with right-to-left text, e.g. Arabic or Hebrew names inserted into
+// an English template. The values are wrapped into markers when they are inserted, the markers are resolved
+// after all placeholders of the part are replaced, since only then the surrounding run and paragraph are final.
+
+var (
+	// rightToLeftMarkerRegex matches a value with right-to-left text wrapped by markRightToLeft.
+	rightToLeftMarkerRegex = regexp.MustCompile(`(?s)\[\[docx-rtl\]\](.*?)\[\[docx-rtl-end\]\]`)
+	// paragraphBidiRegex matches the property of right-to-left paragraphs.
+	paragraphBidiRegex = regexp.MustCompile(`<w:bidi\b[^>]*/>`)
+)
+
+// runPropertiesOrder lists the children of the run properties (<w:rPr>) in the order of the schema.
+var runPropertiesOrder = []string{"w:rStyle", "w:rFonts", "w:b", "w:bCs", "w:i", "w:iCs", "w:caps", "w:smallCaps",
+	"w:strike", "w:dstrike", "w:outline", "w:shadow", "w:emboss", "w:imprint", "w:noProof", "w:snapToGrid",
+	"w:vanish", "w:webHidden", "w:color", "w:spacing", "w:w", "w:kern", "w:position", "w:sz", "w:szCs",
+	"w:highlight", "w:u", "w:effect", "w:bdr", "w:shd", "w:fitText", "w:vertAlign", "w:rtl", "w:cs", "w:em",
+	"w:lang", "w:eastAsianLayout", "w:specVanish", "w:oMath"}
+
+// complexScriptProperties maps run properties to their variant for complex scripts, which Word applies to
+// Arabic and Hebrew text instead.
+var complexScriptProperties = [][2]string{{"w:b", "w:bCs"}, {"w:i", "w:iCs"}, {"w:sz", "w:szCs"}}
+
+// RightToLeftOptions controls how values containing right-to-left text (Arabic, Hebrew, ...) are inserted by
+// the template actions, see Document.SetRightToLeft.
+type RightToLeftOptions struct {
+	// MarkRuns puts each such value into a run of its own, which is marked with <w:rtl/> and the language of the
+	// value. The run keeps the formatting of the placeholder, bold, italic and the font size are applied to the
+	// complex script as well.
+	MarkRuns bool
+	// Language is the language of the marked runs, e.g. fa-IR. If empty, ar-SA or he-IL is derived from the
+	// script of the value.
+	Language string
+	// Embed wraps such values into the Unicode characters RLE and PDF when they are inserted into left-to-right
+	// paragraphs, so punctuation and numbers at the ends of the value keep their order.
+	Embed bool
+}
+
+// SetRightToLeft sets how values containing right-to-left text are inserted, see RightToLeftOptions.
+// By default, they are inserted into the run of the placeholder like all other values.
+func (tr *TemplateReplacer) SetRightToLeft(options RightToLeftOptions) {
+	tr.rightToLeft = options
+}
+
+// SetRightToLeft sets how values containing right-to-left text (Arabic, Hebrew, ...) are inserted. Without
+// options they are inserted like all other values, which Word displays in the wrong direction if the run
+// or the paragraph is left-to-right.
+func (d *Document) SetRightToLeft(options RightToLeftOptions) {
+	d.templateReplacer.SetRightToLeft(options)
+}
+
+// markRightToLeft wraps the escaped value into markers if it contains right-to-left text and any of the
+// right-to-left options is enabled. XML written by helpers such as {{image}} is not marked.
+func (tr *TemplateReplacer) markRightToLeft(value string) string {
+	if !tr.rightToLeft.MarkRuns && !tr.rightToLeft.Embed || strings.Contains(value, "<") || !containsRightToLeft(value) {
+		return value
+	}
+	return "[[docx-rtl]]" + value + "[[docx-rtl-end]]"
+}
+
+// escapeValue is the docxEscape function of executeFragment. It passes the value of the action to the replace
+// hooks, escapes it like escapeTemplateValue and marks values with right-to-left text and, if enabled, all
+// values for their bookmarks. Invalid characters are handled according to the InvalidCharPolicy.
+func (tr *TemplateReplacer) escapeValue(action string, value interface{}) (string, error) {
+	return tr.escapeActionValue(action, value, false)
+}
+
+// escapeScopedValue is the docxScoped function of executeFragment, which escapes the values of actions inside
+// {{range}} and {{with}} like escapeValue. Their actions are not evaluated again by Rerender.
+func (tr *TemplateReplacer) escapeScopedValue(action string, value interface{}) (string, error) {
+	return tr.escapeActionValue(action, value, true)
+}
+
+// escapeActionValue implements escapeValue and escapeScopedValue.
+func (tr *TemplateReplacer) escapeActionValue(action string, value interface{}, scoped bool) (string, error) {
+	switch value.(type) {
+	case cellMarker, rawXML:
+		return escapeTemplateValue(value), nil
+	}
+	text := ""
+	if value != nil {
+		text = fmt.Sprint(value)
+	}
+	text = tr.document.applyReplaceHooks(tr.part, action, text)
+	text, err := sanitizeValue(text, tr.document.invalidChars)
+	if err != nil {
+		return "", err
+	}
+	if scoped {
+		return tr.markValue(valueName(action), "", tr.markRightToLeft(escapeMarkers(xmlEscape(text)))), nil
+	}
+	return tr.markValue(valueName(action), action, tr.markRightToLeft(escapeMarkers(xmlEscape(text)))), nil
+}
+
+// resolveRightToLeft resolves the markers written by markRightToLeft in all XML parts.
+func (tr *TemplateReplacer) resolveRightToLeft() error {
+	for _, fileName := range tr.document.xmlParts() {
+		data := tr.document.GetFile(fileName)
+		if !rightToLeftMarkerRegex.Match(data) {
+			continue
+		}
+		if err := tr.document.SetFile(fileName, resolveRightToLeftMarkers(data, tr.rightToLeft)); err != nil {
+			return err
+		}
+		if err := tr.document.refreshRuns(fileName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveRightToLeftMarkers replaces the markers written by markRightToLeft according to the options.
+func resolveRightToLeftMarkers(data []byte, options RightToLeftOptions) []byte {
+	for offset := 0; ; {
+		loc := rightToLeftMarkerRegex.FindSubmatchIndex(data[offset:])
+		if loc == nil {
+			return data
+		}
+		for i := range loc {
+			loc[i] += offset
+		}
+		value := data[loc[2]:loc[3]]
+
+		run := runStart(data, loc[0])
+		text := textStart(data, loc[0])
+		if options.Embed && !paragraphIsRightToLeft(data, loc[0]) {
+			value = []byte("\u202b" + string(value) + "\u202c") // RLE and PDF
+		}
+		if !options.MarkRuns || run < 0 || text < run {
+			data = applyReplacements(data, []replacement{{int64(loc[0]), int64(loc[1]), value}})
+			offset = loc[0] + len(value)
+			continue
+		}
+
+		// the text element is split, the value gets a run of its own between the parts of the original run
+		properties := runPropertiesRegex.Find(data[run:text])
+		textEnd := text + bytes.IndexByte(data[text:], '>') + 1
+		textTag := setTagAttr(data[text:textEnd], "xml:space", "preserve")
+		language := options.Language
+		if language == "" {
+			language = rightToLeftLanguage(string(value))
+		}
+
+		var split []byte
+		split = append(split, textTag...)
+		split = append(split, data[textEnd:loc[0]]...)
+		split = append(split, "</w:t></w:r><w:r>"...)
+		split = append(split, rightToLeftRunProperties(properties, language)...)
+		split = append(split, `<w:t xml:space="preserve">`...)
+		split = append(split, value...)
+		split = append(split, "</w:t></w:r>"...)
+		offset = text + len(split)
+		split = append(split, "<w:r>"...)
+		split = append(split, properties...)
+		split = append(split, textTag...)
+		data = applyReplacements(data, []replacement{{int64(text), int64(loc[1]), split}})
+	}
+}
+
+// rightToLeftRunProperties returns the properties of the run containing a right-to-left value, based on the
+// properties of the run of its placeholder.
+func rightToLeftRunProperties(properties []byte, language string) []byte {
+	var content []byte
+	if properties != nil {
+		content = append(content, properties[len("<w:rPr>"):len(properties)-len("</w:rPr>")]...)
+	}
+	for _, variant := range complexScriptProperties {
+		tag := regexp.MustCompile(`<` + variant[0] + `\b[^>]*/>`).Find(content)
+		if tag != nil && !regexp.MustCompile(`<`+variant[1]+`\b`).Match(content) {
+			element := append([]byte("<"+variant[1]), tag[len(variant[0])+1:]...)
+			content = insertChild(content, element, runPropertyFollowers(variant[1]))
+		}
+	}
+	if !regexp.MustCompile(`<w:rtl\b`).Match(content) {
+		content = insertChild(content, []byte("<w:rtl/>"), runPropertyFollowers("w:rtl"))
+	}
+	if language != "" {
+		if loc := langTagRegex.FindIndex(content); loc != nil {
+			lang := setTagAttr(content[loc[0]:loc[1]], "w:bidi", language)
+			content = applyReplacements(content, []replacement{{int64(loc[0]), int64(loc[1]), lang}})
+		} else {
+			content = insertChild(content, []byte(`<w:lang w:bidi="`+xmlEscape(language)+`"/>`), runPropertyFollowers("w:lang"))
+		}
+	}
+	return append(append([]byte("<w:rPr>"), content...), "</w:rPr>"...)
+}
+
+// runPropertyFollowers returns the run properties which follow the given one according to the schema.
+func runPropertyFollowers(name string) []string {
+	for i, property := range runPropertiesOrder {
+		if property == name {
+			return runPropertiesOrder[i+1:]
+		}
+	}
+	return nil
+}
+
+// textStart returns the offset of the open tag of the text element containing the given offset, or -1.
+func textStart(data []byte, offset int) int {
+	start := -1
+	for _, tag := range []string{"<w:t>", "<w:t "} {
+		if i := bytes.LastIndex(data[:offset], []byte(tag)); i > start {
+			start = i
+		}
+	}
+	return start
+}
+
+// paragraphIsRightToLeft reports whether the paragraph containing the given offset is a right-to-left paragraph.
+func paragraphIsRightToLeft(data []byte, offset int) bool {
+	start := -1
+	for _, tag := range []string{"<w:p>", "<w:p "} {
+		if i := bytes.LastIndex(data[:offset], []byte(tag)); i > start {
+			start = i
+		}
+	}
+	if start < 0 {
+		return false
+	}
+	properties := paragraphPropertiesRegex.Find(data[start:offset])
+	bidi := paragraphBidiRegex.Find(properties)
+	return bidi != nil && onOffTagValue(bidi)
+}
+
+// containsRightToLeft reports whether the text contains letters of a right-to-left script.
+func containsRightToLeft(text string) bool {
+	return strings.IndexFunc(text, isRightToLeft) >= 0
+}
+
+// isRightToLeft reports whether the rune is a letter of a right-to-left script.
+func isRightToLeft(r rune) bool {
+	return unicode.IsLetter(r) && unicode.In(r, unicode.Hebrew, unicode.Arabic, unicode.Syriac, unicode.Thaana, unicode.Nko)
+}
+
+// rightToLeftLanguage returns the language of the first right-to-left letter of the text, or an empty string
+// if it cannot be derived from the script.
+func rightToLeftLanguage(text string) string {
+	i := strings.IndexFunc(text, isRightToLeft)
+	if i < 0 {
+		return ""
+	}
+	switch r := []rune(text[i:])[0]; {
+	case unicode.Is(unicode.Hebrew, r):
+		return "he-IL"
+	case unicode.Is(unicode.Arabic, r):
+		return "ar-SA"
+	}
+	return ""
+}
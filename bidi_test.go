@@ -0,0 +1,69 @@
+package docx
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDocument_SetRightToLeft(t *testing.T) {
+	body := `<w:body>` +
+		`<w:p><w:r><w:rPr><w:b/><w:sz w:val="24"/></w:rPr><w:t>Customer: {{.name}}! {{.city}}</w:t></w:r></w:p>` +
+		`<w:p><w:pPr><w:bidi/></w:pPr><w:r><w:t>{{.name}}</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>{{range .names}}</w:t></w:r></w:p><w:p><w:r><w:t>{{.}}</w:t></w:r></w:p><w:p><w:r><w:t>{{end}}</w:t></w:r></w:p>`
+	data := map[string]interface{}{"name": "محمد (2)", "city": "Haifa", "names": []string{"דוד", "Anna"}}
+
+	tests := []struct {
+		name     string
+		options  RightToLeftOptions
+		expected []string
+	}{
+		{"default", RightToLeftOptions{}, []string{
+			`<w:t>Customer: محمد (2)! Haifa</w:t>`,
+			`<w:t>דוד</w:t>`,
+		}},
+		{"marked runs", RightToLeftOptions{MarkRuns: true}, []string{
+			`<w:t xml:space="preserve">Customer: </w:t></w:r>` +
+				`<w:r><w:rPr><w:b/><w:bCs/><w:sz w:val="24"/><w:szCs w:val="24"/><w:rtl/><w:lang w:bidi="ar-SA"/></w:rPr><w:t xml:space="preserve">محمد (2)</w:t></w:r>` +
+				`<w:r><w:rPr><w:b/><w:sz w:val="24"/></w:rPr><w:t xml:space="preserve">! Haifa</w:t>`,
+			`<w:r><w:rPr><w:rtl/><w:lang w:bidi="he-IL"/></w:rPr><w:t xml:space="preserve">דוד</w:t></w:r>`,
+			`<w:t>Anna</w:t>`,
+		}},
+		{"language and embedding", RightToLeftOptions{MarkRuns: true, Language: "fa-IR", Embed: true}, []string{
+			`<w:lang w:bidi="fa-IR"/></w:rPr><w:t xml:space="preserve">` + "\u202bمحمد (2)\u202c</w:t>",
+			`<w:pPr><w:bidi/></w:pPr><w:r><w:t xml:space="preserve"></w:t></w:r><w:r><w:rPr><w:rtl/><w:lang w:bidi="fa-IR"/></w:rPr><w:t xml:space="preserve">محمد (2)</w:t></w:r>`,
+		}},
+		{"embedding only", RightToLeftOptions{Embed: true}, []string{
+			"<w:t>Customer: \u202bمحمد (2)\u202c! Haifa</w:t>",
+			`<w:pPr><w:bidi/></w:pPr><w:r><w:t>محمد (2)</w:t>`,
+			"<w:t>\u202bדוד\u202c</w:t>",
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			doc, err := OpenBytes(rewriteArchive(t, readFile(t, "./test/template.docx"), func(name string, data []byte) []byte {
+				if name == DocumentXml {
+					return []byte(strings.Replace(string(data), "<w:body>", body, 1))
+				}
+				return data
+			}, nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer doc.Close()
+
+			doc.SetRightToLeft(test.options)
+			if err := doc.ExecuteTemplate(data); err != nil {
+				t.Fatal(err)
+			}
+			documentXml := string(doc.GetFile(DocumentXml))
+			for _, expected := range test.expected {
+				if !strings.Contains(documentXml, expected) {
+					t.Errorf("expected %s in %s", expected, documentXml[:strings.Index(documentXml, "<w:sectPr")])
+				}
+			}
+			if strings.Contains(documentXml, "[[docx-rtl") {
+				t.Errorf("the markers were not resolved")
+			}
+		})
+	}
+}
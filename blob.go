@@ -0,0 +1,191 @@
+package docx
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// documentMimeType is the media type of .docx files, sent when documents are uploaded over HTTP.
+const documentMimeType = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+
+// BlobDriver reads and writes the documents of a URL scheme, e.g. s3:// or gs://, for the URL-based convenience
+// functions. Drivers are registered with RegisterBlobDriver.
+type BlobDriver interface {
+	// Read returns the content of the object at the URL.
+	Read(ctx context.Context, u *url.URL) ([]byte, error)
+	// Write stores the data as the object at the URL.
+	Write(ctx context.Context, u *url.URL, data []byte) error
+}
+
+var (
+	blobDriversMu sync.RWMutex
+	// blobDrivers are the drivers by URL scheme. Local files and plain HTTP are supported out of the box, the
+	// latter works with the presigned URLs of S3, MinIO and GCS.
+	blobDrivers = map[string]BlobDriver{
+		"file":  fileBlobDriver{},
+		"http":  httpBlobDriver{},
+		"https": httpBlobDriver{},
+	}
+)
+
+// RegisterBlobDriver registers the driver for the URL scheme, replacing the driver registered before. The drivers
+// for file, http and https may be replaced as well, e.g. to use an HTTP client with authentication.
+func RegisterBlobDriver(scheme string, driver BlobDriver) {
+	blobDriversMu.Lock()
+	defer blobDriversMu.Unlock()
+	blobDrivers[scheme] = driver
+}
+
+// blobDriver returns the driver for the URL. URLs without scheme are paths of local files.
+func blobDriver(rawURL string) (BlobDriver, *url.URL, error) {
+	u, err := url.Parse(rawURL)
+	// a single letter is the drive of a Windows path, e.g. C:\templates\offer.docx
+	if err != nil || len(u.Scheme) <= 1 {
+		return fileBlobDriver{}, &url.URL{Scheme: "file", Path: rawURL}, nil
+	}
+	blobDriversMu.RLock()
+	defer blobDriversMu.RUnlock()
+	driver, exists := blobDrivers[u.Scheme]
+	if !exists {
+		return nil, nil, fmt.Errorf("no blob driver registered for scheme %s", u.Scheme)
+	}
+	return driver, u, nil
+}
+
+// ReadURL reads the document at the URL with the driver registered for its scheme.
+func ReadURL(ctx context.Context, rawURL string) ([]byte, error) {
+	driver, u, err := blobDriver(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	data, err := driver.Read(ctx, u)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s: %w", redactURL(u), err)
+	}
+	return data, nil
+}
+
+// WriteURL writes the document to the URL with the driver registered for its scheme.
+func WriteURL(ctx context.Context, rawURL string, data []byte) error {
+	driver, u, err := blobDriver(rawURL)
+	if err != nil {
+		return err
+	}
+	if err := driver.Write(ctx, u, data); err != nil {
+		return fmt.Errorf("unable to write %s: %w", redactURL(u), err)
+	}
+	return nil
+}
+
+// CompleteTemplateFromURLToURL is a convenience function that reads a template from a URL, processes it with data
+// and writes the result to another URL, e.g. from s3://templates/offer.docx to s3://output/offer-42.docx.
+// The objects are accessed with the drivers registered for the schemes, see RegisterBlobDriver.
+func CompleteTemplateFromURLToURL(ctx context.Context, srcURL, dstURL string, data TemplateData, opts ...Option) error {
+	templateBytes, err := ReadURL(ctx, srcURL)
+	if err != nil {
+		return fmt.Errorf("failed to read template: %w", err)
+	}
+	output, err := CompleteTemplateFromBytesToBytes(templateBytes, data, opts...)
+	if err != nil {
+		return err
+	}
+	return WriteURL(ctx, dstURL, output)
+}
+
+// CompleteReplaceAllFromURLToURL is a convenience function that reads a document from a URL, replaces all
+// placeholders and writes the result to another URL, see CompleteTemplateFromURLToURL.
+func CompleteReplaceAllFromURLToURL(ctx context.Context, srcURL, dstURL string, replaceMap PlaceholderMap, opts ...Option) error {
+	templateBytes, err := ReadURL(ctx, srcURL)
+	if err != nil {
+		return fmt.Errorf("failed to read template: %w", err)
+	}
+	output, err := CompleteReplaceAllFromBytesToBytes(templateBytes, replaceMap, opts...)
+	if err != nil {
+		return err
+	}
+	return WriteURL(ctx, dstURL, output)
+}
+
+// redactURL returns the URL without its query and user information, which may contain credentials,
+// e.g. the signature of a presigned URL.
+func redactURL(u *url.URL) string {
+	redacted := *u
+	redacted.User, redacted.RawQuery, redacted.ForceQuery = nil, "", false
+	return redacted.String()
+}
+
+// fileBlobDriver reads and writes local files.
+type fileBlobDriver struct{}
+
+// filePath returns the path of the file URL, e.g. /tmp/offer.docx for file:///tmp/offer.docx.
+func (fileBlobDriver) filePath(u *url.URL) string {
+	if u.Opaque != "" {
+		return filepath.FromSlash(u.Opaque)
+	}
+	return filepath.FromSlash(u.Host + u.Path)
+}
+
+// Read implements BlobDriver.
+func (d fileBlobDriver) Read(_ context.Context, u *url.URL) ([]byte, error) {
+	return os.ReadFile(d.filePath(u))
+}
+
+// Write implements BlobDriver.
+func (d fileBlobDriver) Write(_ context.Context, u *url.URL, data []byte) error {
+	return os.WriteFile(d.filePath(u), data, 0o644)
+}
+
+// httpBlobDriver reads objects with GET and writes them with PUT requests.
+type httpBlobDriver struct{}
+
+// doRequest sends the request, the URL is redacted in errors.
+func doRequest(request *http.Request) (*http.Response, error) {
+	response, err := http.DefaultClient.Do(request)
+	if urlErr, ok := err.(*url.Error); ok {
+		urlErr.URL = redactURL(request.URL)
+	}
+	return response, err
+}
+
+// Read implements BlobDriver.
+func (httpBlobDriver) Read(ctx context.Context, u *url.URL) ([]byte, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := doRequest(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("unexpected status %s", response.Status)
+	}
+	return io.ReadAll(response.Body)
+}
+
+// Write implements BlobDriver.
+func (httpBlobDriver) Write(ctx context.Context, u *url.URL, data []byte) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPut, u.String(), bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", documentMimeType)
+	response, err := doRequest(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	_, _ = io.Copy(io.Discard, response.Body)
+	if response.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected status %s", response.Status)
+	}
+	return nil
+}
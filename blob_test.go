@@ -0,0 +1,104 @@
+package docx
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// memoryBlobDriver keeps the objects in memory by URL.
+type memoryBlobDriver struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (d *memoryBlobDriver) Read(_ context.Context, u *url.URL) ([]byte, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	data, exists := d.objects[u.String()]
+	if !exists {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return data, nil
+}
+
+func (d *memoryBlobDriver) Write(_ context.Context, u *url.URL, data []byte) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.objects[u.String()] = data
+	return nil
+}
+
+func TestCompleteTemplateFromURLToURL(t *testing.T) {
+	template := rewriteArchive(t, readFile(t, "./test/template.docx"), func(name string, data []byte) []byte {
+		if name == DocumentXml {
+			return []byte(strings.Replace(string(data), "<w:body>", `<w:body><w:p><w:r><w:t>Offer for {{.name}}</w:t></w:r></w:p>`, 1))
+		}
+		return data
+	}, nil)
+	data := map[string]interface{}{"name": "ACME"}
+	rendered := func(t *testing.T, output []byte) {
+		doc, err := OpenBytes(output)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer doc.Close()
+		if !strings.Contains(string(doc.GetFile(DocumentXml)), "Offer for ACME") {
+			t.Errorf("the template was not rendered")
+		}
+	}
+	ctx := context.Background()
+
+	t.Run("registered driver", func(t *testing.T) {
+		driver := &memoryBlobDriver{objects: map[string][]byte{"mem://templates/offer.docx": template}}
+		RegisterBlobDriver("mem", driver)
+		if err := CompleteTemplateFromURLToURL(ctx, "mem://templates/offer.docx", "mem://output/offer.docx", data); err != nil {
+			t.Fatal(err)
+		}
+		rendered(t, driver.objects["mem://output/offer.docx"])
+		if err := CompleteTemplateFromURLToURL(ctx, "unknown://templates/offer.docx", "mem://output/offer.docx", data); err == nil {
+			t.Errorf("expected an error for an unknown scheme")
+		}
+	})
+
+	t.Run("files", func(t *testing.T) {
+		output := filepath.Join(t.TempDir(), "offer.docx")
+		if err := CompleteTemplateFromURLToURL(ctx, "./test/template.docx", "file://"+filepath.ToSlash(output), data); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ReadURL(ctx, output); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("presigned URLs", func(t *testing.T) {
+		var uploaded []byte
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case r.Method == http.MethodGet && r.URL.Path == "/templates/offer.docx":
+				_, _ = w.Write(template)
+			case r.Method == http.MethodPut && r.Header.Get("Content-Type") == documentMimeType:
+				uploaded, _ = io.ReadAll(r.Body)
+			default:
+				http.Error(w, "forbidden", http.StatusForbidden)
+			}
+		}))
+		defer server.Close()
+
+		if err := CompleteTemplateFromURLToURL(ctx, server.URL+"/templates/offer.docx?X-Amz-Signature=secret", server.URL+"/output/offer.docx", data); err != nil {
+			t.Fatal(err)
+		}
+		rendered(t, uploaded)
+
+		_, err := ReadURL(ctx, server.URL+"/missing.docx?X-Amz-Signature=secret")
+		if err == nil || !strings.Contains(err.Error(), "403") || strings.Contains(err.Error(), "secret") {
+			t.Errorf("expected a redacted error for the forbidden object, got %v", err)
+		}
+	})
+}
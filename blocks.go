@@ -0,0 +1,394 @@
+package docx
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// blocks.go implements control structures which span multiple paragraphs, e.g.
+//
+//	{{if .showClause}}
+//	... any number of paragraphs, tables and images ...
+//	{{else}}
+//	...
+//	{{end}}
+//
+// Each of the actions must be the only content of its paragraph. The paragraphs holding the actions are removed
+// and the content of the chosen branch is kept as a unit, including its formatting. The content of {{range}} and
+// {{with}} blocks is executed as a whole, so it is repeated or evaluated with another dot.
+// Blocks which start and end inside the same paragraph are left to the regular placeholder processing.
+
+var (
+	// blockActionRegex matches a single template action inside the text of a paragraph.
+	blockActionRegex = regexp.MustCompile(`\{\{(.*?)\}\}`)
+)
+
+// blockParagraph is a paragraph of a part together with its position and plain text.
+type blockParagraph struct {
+	Start    int64 // offset of the <w:p> open tag
+	End      int64 // offset behind the </w:p> close tag
+	Parent   int64 // offset of the open tag of the parent element, e.g. <w:body> or <w:tc>
+	Text     string
+	Embedded bool // the paragraph contains drawings or objects besides its text
+}
+
+// blockAction is a control action ({{if}}, {{else}}, {{end}}, ...) which is the only content of its paragraph.
+type blockAction struct {
+	Paragraph blockParagraph
+	Action    string // the complete action, e.g. {{if .showClause}}
+	Kind      string // if, else, end or the keyword of another block action like range or with
+	Pipeline  string // the condition of if and else if actions
+}
+
+// templateBlock is a control structure spanning multiple paragraphs.
+type templateBlock struct {
+	Actions  []blockAction // the opening action, all else actions and the end action
+	Children []*templateBlock
+}
+
+// Start returns the offset of the paragraph holding the opening action.
+func (b *templateBlock) Start() int64 {
+	return b.Actions[0].Paragraph.Start
+}
+
+// End returns the offset behind the paragraph holding the end action.
+func (b *templateBlock) End() int64 {
+	return b.Actions[len(b.Actions)-1].Paragraph.End
+}
+
+// executeBlocks resolves all blocks spanning multiple paragraphs in the document, headers and footers.
+// Modified parts are parsed again, so that the placeholders inside the kept content are processed afterwards.
+func (tr *TemplateReplacer) executeBlocks() error {
+	for _, fileName := range tr.document.xmlParts() {
+		tr.part = fileName
+		data := tr.document.GetFile(fileName)
+		blocks, err := parseTemplateBlocks(data)
+		if err != nil && tr.mode == ExecuteParts {
+			// e.g. a condition starting inside a paragraph and ending in another one
+			tr.debugLog("Leaving the blocks of %s to the execution of the part: %v", fileName, err)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to parse blocks in %s: %w", fileName, err)
+		}
+		if len(tr.definitions) > 0 {
+			calls, err := tr.definitionCalls(data)
+			if err != nil {
+				return fmt.Errorf("failed to parse blocks in %s: %w", fileName, err)
+			}
+			for _, call := range calls {
+				blocks = nestBlock(blocks, call)
+			}
+		}
+		if len(blocks) == 0 {
+			continue
+		}
+
+		tr.debugLog("Found %d paragraph blocks in %s", len(blocks), fileName)
+		var out bytes.Buffer
+		if err := tr.renderBlocks(&out, data, 0, int64(len(data)), blocks); err != nil {
+			return err
+		}
+		if err := tr.document.SetFile(fileName, out.Bytes()); err != nil {
+			return err
+		}
+		if err := tr.document.refreshRuns(fileName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renderBlocks writes data[start:end] to out. Conditional blocks are replaced by the content of their chosen branch,
+// range and with blocks and calls of definitions by their executed content. Other blocks are written unchanged. Failed blocks are reported
+// as TemplateError.
+func (tr *TemplateReplacer) renderBlocks(out *bytes.Buffer, data []byte, start, end int64, blocks []*templateBlock) error {
+	pos := start
+	for _, block := range blocks {
+		if block.Start() < start || block.End() > end {
+			continue
+		}
+		out.Write(data[pos:block.Start()])
+		pos = block.End()
+
+		switch block.Actions[0].Kind {
+		case "if":
+		case "range", "with", "template":
+			// the content is repeated or evaluated with another dot, so it is executed as a whole
+			// the rows and columns of the tables are repeated with the dot of the block
+			fragment, err := inlineTableRanges([]byte(blockFragment(data, block)))
+			var result []byte
+			if err == nil {
+				result, err = tr.executeFragment(string(fragment), tr.data)
+			}
+			if err != nil {
+				templateErr := newTemplateError(data, tr.part, block.Start(), block.Actions[0].Action, err)
+				if tr.skipPanic(templateErr) {
+					continue
+				}
+				return templateErr
+			}
+			if err := tr.partSizeError(result, out.Len()+len(data)-int(block.End())); err != nil {
+				templateErr := newTemplateError(data, tr.part, block.Start(), block.Actions[0].Action, err)
+				if !tr.truncate(templateErr) {
+					return templateErr
+				}
+				result = truncateElements(result, tr.limits.MaxPartSize-out.Len()-len(data)+int(block.End()))
+			}
+			out.Write(result)
+			continue
+		default:
+			out.Write(data[block.Start():block.End()])
+			continue
+		}
+
+		for i, action := range block.Actions[:len(block.Actions)-1] {
+			chosen := action.Kind == "else" && action.Pipeline == ""
+			if !chosen {
+				var err error
+				if chosen, err = tr.evaluateCondition(action.Pipeline); err != nil {
+					templateErr := newTemplateError(data, tr.part, action.Paragraph.Start, action.Action, err)
+					if tr.skipPanic(templateErr) {
+						break
+					}
+					return templateErr
+				}
+			}
+			if chosen {
+				tr.debugLog("Keeping branch %s", action.Action)
+				next := block.Actions[i+1].Paragraph.Start
+				if err := tr.renderBlocks(out, data, action.Paragraph.End, next, block.Children); err != nil {
+					return err
+				}
+				break
+			}
+		}
+	}
+	out.Write(data[pos:end])
+	return nil
+}
+
+// blockFragment returns the block as template fragment. The paragraphs holding the actions of the block
+// and of all nested blocks are replaced by the actions themselves.
+func blockFragment(data []byte, block *templateBlock) string {
+	var actions []blockAction
+	var collect func(block *templateBlock)
+	collect = func(block *templateBlock) {
+		actions = append(actions, block.Actions...)
+		for _, child := range block.Children {
+			collect(child)
+		}
+	}
+	collect(block)
+	sort.Slice(actions, func(i, j int) bool {
+		return actions[i].Paragraph.Start < actions[j].Paragraph.Start
+	})
+
+	var sb strings.Builder
+	pos := block.Start()
+	for _, action := range actions {
+		sb.Write(data[pos:action.Paragraph.Start])
+		sb.WriteString(action.Action)
+		pos = action.Paragraph.End
+	}
+	return sb.String()
+}
+
+// evaluateCondition evaluates the pipeline of an {{if}} action with the template data and functions.
+// Conditions referencing missing fields are false, unless the missing key policy is MissingKeyError.
+func (tr *TemplateReplacer) evaluateCondition(pipeline string) (bool, error) {
+	tmpl, err := tr.tmpl.Parse("{{if " + stripAnnotations(pipeline) + "}}true{{end}}")
+	if err != nil {
+		return false, fmt.Errorf("failed to parse condition %s: %w", pipeline, err)
+	}
+	nilSafeFields(tmpl.Tree.Root)
+
+	buf := tr.newLimitedWriter()
+	if err := tmpl.Execute(buf, tr.data); err != nil {
+		if tr.isMissingFieldError(err) && tr.missingKey != MissingKeyError {
+			tr.debugLog("Condition %s references a missing field: %v", pipeline, err)
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to evaluate condition %s: %w", pipeline, err)
+	}
+	return buf.String() == "true", nil
+}
+
+// parseTemplateBlocks finds all blocks whose actions are in different paragraphs.
+// The returned blocks are in document order, nested blocks are returned as children.
+func parseTemplateBlocks(data []byte) ([]*templateBlock, error) {
+	paragraphs, err := findBlockParagraphs(data)
+	if err != nil {
+		return nil, err
+	}
+	actions, err := findBlockActions(paragraphs)
+	if err != nil {
+		return nil, err
+	}
+
+	var blocks []*templateBlock
+	var stack []*templateBlock
+	for _, action := range actions {
+		switch action.Kind {
+		case "else":
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("unexpected %s", action.Action)
+			}
+			top := stack[len(stack)-1]
+			top.Actions = append(top.Actions, action)
+		case "end":
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("unexpected %s", action.Action)
+			}
+			block := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			block.Actions = append(block.Actions, action)
+			for _, a := range block.Actions[1:] {
+				if a.Paragraph.Parent != block.Actions[0].Paragraph.Parent {
+					return nil, fmt.Errorf("%s and %s must be in the same table cell or text box", block.Actions[0].Action, a.Action)
+				}
+			}
+			if len(stack) > 0 {
+				stack[len(stack)-1].Children = append(stack[len(stack)-1].Children, block)
+			} else {
+				blocks = append(blocks, block)
+			}
+		default:
+			stack = append(stack, &templateBlock{Actions: []blockAction{action}})
+		}
+	}
+	if len(stack) > 0 {
+		return nil, fmt.Errorf("missing {{end}} for %s", stack[len(stack)-1].Actions[0].Action)
+	}
+	return blocks, nil
+}
+
+// findBlockActions returns the control actions which are not closed inside their own paragraph.
+// An error is returned if such an action shares its paragraph with other content.
+func findBlockActions(paragraphs []blockParagraph) ([]blockAction, error) {
+	var actions []blockAction
+	for _, paragraph := range paragraphs {
+		var unmatched []blockAction
+		var open []blockAction
+		for _, match := range blockActionRegex.FindAllStringSubmatch(paragraph.Text, -1) {
+			action := blockAction{Paragraph: paragraph, Action: match[0]}
+			action.Kind, action.Pipeline = blockActionKind(match[1])
+			switch action.Kind {
+			case "":
+				continue
+			case "else":
+				if len(open) == 0 {
+					unmatched = append(unmatched, action)
+				}
+			case "end":
+				if len(open) > 0 {
+					open = open[:len(open)-1]
+				} else {
+					unmatched = append(unmatched, action)
+				}
+			default:
+				open = append(open, action)
+			}
+		}
+		unmatched = append(unmatched, open...)
+
+		if len(unmatched) == 0 {
+			continue
+		}
+		if len(unmatched) > 1 || strings.TrimSpace(paragraph.Text) != unmatched[0].Action || paragraph.Embedded {
+			return nil, fmt.Errorf("the block action %s must be the only content of its paragraph", unmatched[0].Action)
+		}
+		actions = append(actions, unmatched[0])
+	}
+	return actions, nil
+}
+
+// blockActionKind returns the kind of the action with the given content (without the delimiters)
+// and the pipeline of if and else if actions. The kind is empty for all actions which are not part of a block.
+func blockActionKind(content string) (string, string) {
+	content = withoutTrimMarkers(content)
+	fields := strings.Fields(content)
+	if len(fields) == 0 {
+		return "", ""
+	}
+	switch fields[0] {
+	case "if":
+		return "if", strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(content), "if"))
+	case "else":
+		rest := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(content), "else"))
+		if strings.HasPrefix(rest, "if ") {
+			return "else", strings.TrimSpace(strings.TrimPrefix(rest, "if"))
+		}
+		return "else", ""
+	case "end":
+		return "end", ""
+	case "range", "with", "block", "define":
+		return fields[0], ""
+	}
+	return "", ""
+}
+
+// findBlockParagraphs returns all paragraphs of the part in document order, including paragraphs
+// nested in table cells and text boxes.
+func findBlockParagraphs(data []byte) ([]blockParagraph, error) {
+	docReader := NewReader(string(data))
+	decoder := xml.NewDecoder(docReader)
+
+	var paragraphs []blockParagraph
+	var elements []int64 // offsets of the currently open elements
+	var open []int       // indices of the currently open paragraphs
+	inText := false
+	for {
+		start := docReader.Pos()
+		tok, err := decoder.Token()
+		if tok == nil || err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error getting token: %s", err)
+		}
+
+		switch elem := tok.(type) {
+		case xml.StartElement:
+			switch elem.Name.Local {
+			case ParagraphElementName:
+				parent := int64(-1)
+				if len(elements) > 0 {
+					parent = elements[len(elements)-1]
+				}
+				paragraphs = append(paragraphs, blockParagraph{Start: start, Parent: parent})
+				open = append(open, len(paragraphs)-1)
+			case "t":
+				inText = true
+			case "drawing", "pict", "object":
+				if len(open) > 0 {
+					paragraphs[open[len(open)-1]].Embedded = true
+				}
+			}
+			elements = append(elements, start)
+		case xml.EndElement:
+			if len(elements) > 0 {
+				elements = elements[:len(elements)-1]
+			}
+			switch elem.Name.Local {
+			case ParagraphElementName:
+				if len(open) > 0 {
+					paragraphs[open[len(open)-1]].End = docReader.Pos()
+					open = open[:len(open)-1]
+				}
+			case "t":
+				inText = false
+			}
+		case xml.CharData:
+			if inText && len(open) > 0 {
+				paragraphs[open[len(open)-1]].Text += string(elem)
+			}
+		}
+	}
+	return paragraphs, nil
+}
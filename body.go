@@ -0,0 +1,271 @@
+package docx
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	// ParagraphElementName is the local name of the XML tag for paragraphs (<w:p>)
+	ParagraphElementName = "p"
+	// TableElementName is the local name of the XML tag for tables (<w:tbl>)
+	TableElementName = "tbl"
+	// TableRowElementName is the local name of the XML tag for table rows (<w:tr>)
+	TableRowElementName = "tr"
+	// TableCellElementName is the local name of the XML tag for table cells (<w:tc>)
+	TableCellElementName = "tc"
+)
+
+// bodyBlock is a block-level element of the document body, either a paragraph or a table.
+type bodyBlock struct {
+	Paragraph *bodyParagraph
+	Table     *bodyTable
+}
+
+// bodyParagraph is a simplified, read-only view of a <w:p> element.
+type bodyParagraph struct {
+	Style     string // value of <w:pStyle>
+	Alignment string // value of <w:jc>
+	Runs      []bodyRun
+}
+
+// Text returns the plain text of the paragraph.
+func (p *bodyParagraph) Text() string {
+	var sb strings.Builder
+	for _, run := range p.Runs {
+		sb.WriteString(run.Text)
+	}
+	return sb.String()
+}
+
+// bodyRun is a simplified, read-only view of a <w:r> element and its basic formatting.
+type bodyRun struct {
+	Text      string
+	Bold      bool
+	Italic    bool
+	Underline bool
+	Strike    bool
+}
+
+// bodyTable is a simplified, read-only view of a <w:tbl> element.
+type bodyTable struct {
+	Rows []bodyTableRow
+}
+
+// bodyTableRow is a single <w:tr> of a bodyTable.
+type bodyTableRow struct {
+	Cells []bodyTableCell
+}
+
+// bodyTableCell is a single <w:tc> of a bodyTableRow. Cells may contain nested tables.
+type bodyTableCell struct {
+	Blocks []bodyBlock
+}
+
+// Text returns the plain text of all paragraphs inside the cell, separated by newlines.
+func (c *bodyTableCell) Text() string {
+	return blocksText(c.Blocks)
+}
+
+// blocksText returns the plain text of the given blocks. Paragraphs are separated by newlines,
+// table cells by tabs.
+func blocksText(blocks []bodyBlock) string {
+	var lines []string
+	for _, block := range blocks {
+		if block.Paragraph != nil {
+			lines = append(lines, block.Paragraph.Text())
+			continue
+		}
+		for _, row := range block.Table.Rows {
+			var cells []string
+			for _, cell := range row.Cells {
+				cells = append(cells, strings.ReplaceAll(cell.Text(), "\n", " "))
+			}
+			lines = append(lines, strings.Join(cells, "\t"))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// parseBody parses the block-level content (paragraphs and tables) of the given part.
+// Anything which is not a paragraph or table (e.g. section properties) is ignored, as is the content of
+// drawings and other embedded objects.
+func parseBody(data []byte) ([]bodyBlock, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	blocks, err := parseBlocks(decoder)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("unable to parse body: %s", err)
+	}
+	return blocks, nil
+}
+
+// parseBlocks collects all paragraphs and tables until the element which contains them is closed.
+func parseBlocks(decoder *xml.Decoder) ([]bodyBlock, error) {
+	var blocks []bodyBlock
+	for {
+		tok, err := decoder.Token()
+		if err != nil {
+			return blocks, err
+		}
+
+		switch elem := tok.(type) {
+		case xml.StartElement:
+			switch elem.Name.Local {
+			case ParagraphElementName:
+				paragraph, err := parseParagraph(decoder)
+				if err != nil {
+					return blocks, err
+				}
+				blocks = append(blocks, bodyBlock{Paragraph: paragraph})
+			case TableElementName:
+				table, err := parseTable(decoder)
+				if err != nil {
+					return blocks, err
+				}
+				blocks = append(blocks, bodyBlock{Table: table})
+			}
+		case xml.EndElement:
+			if elem.Name.Local == TableCellElementName {
+				return blocks, nil
+			}
+		}
+	}
+}
+
+// parseTable parses the rows and cells of a table until </w:tbl>.
+func parseTable(decoder *xml.Decoder) (*bodyTable, error) {
+	table := &bodyTable{}
+	for {
+		tok, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		switch elem := tok.(type) {
+		case xml.StartElement:
+			switch elem.Name.Local {
+			case TableRowElementName:
+				table.Rows = append(table.Rows, bodyTableRow{})
+			case TableCellElementName:
+				blocks, err := parseBlocks(decoder)
+				if err != nil {
+					return nil, err
+				}
+				if len(table.Rows) > 0 {
+					row := &table.Rows[len(table.Rows)-1]
+					row.Cells = append(row.Cells, bodyTableCell{Blocks: blocks})
+				}
+			}
+		case xml.EndElement:
+			if elem.Name.Local == TableElementName {
+				return table, nil
+			}
+		}
+	}
+}
+
+// parseParagraph parses the runs of a paragraph until </w:p>.
+func parseParagraph(decoder *xml.Decoder) (*bodyParagraph, error) {
+	paragraph := &bodyParagraph{}
+	var run *bodyRun
+	inText := false
+	inRunProperties := false
+
+	for {
+		tok, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		switch elem := tok.(type) {
+		case xml.StartElement:
+			switch elem.Name.Local {
+			case "drawing", "pict", "object", "AlternateContent", "del", "moveFrom":
+				// embedded objects have their own paragraphs and deleted text is not part of the content
+				if err := decoder.Skip(); err != nil {
+					return nil, err
+				}
+			case RunElementName:
+				run = &bodyRun{}
+			case "rPr":
+				inRunProperties = run != nil
+			case "b":
+				if inRunProperties {
+					run.Bold = isOnOff(elem)
+				}
+			case "i":
+				if inRunProperties {
+					run.Italic = isOnOff(elem)
+				}
+			case "u":
+				if inRunProperties {
+					run.Underline = attrValue(elem, "val") != "none"
+				}
+			case "strike":
+				if inRunProperties {
+					run.Strike = isOnOff(elem)
+				}
+			case "pStyle":
+				paragraph.Style = attrValue(elem, "val")
+			case "jc":
+				if run == nil {
+					paragraph.Alignment = attrValue(elem, "val")
+				}
+			case TextElementName:
+				inText = run != nil
+			case "tab":
+				if run != nil && !inRunProperties {
+					run.Text += "\t"
+				}
+			case "br", "cr":
+				if run != nil {
+					run.Text += "\n"
+				}
+			case "noBreakHyphen":
+				if run != nil {
+					run.Text += "-"
+				}
+			}
+		case xml.CharData:
+			if inText {
+				run.Text += string(elem)
+			}
+		case xml.EndElement:
+			switch elem.Name.Local {
+			case TextElementName:
+				inText = false
+			case "rPr":
+				inRunProperties = false
+			case RunElementName:
+				if run != nil {
+					paragraph.Runs = append(paragraph.Runs, *run)
+				}
+				run = nil
+			case ParagraphElementName:
+				return paragraph, nil
+			}
+		}
+	}
+}
+
+// isOnOff evaluates a WordprocessingML on/off property such as <w:b/> or <w:b w:val="false"/>.
+func isOnOff(elem xml.StartElement) bool {
+	switch attrValue(elem, "val") {
+	case "0", "false", "off":
+		return false
+	}
+	return true
+}
+
+// attrValue returns the value of the attribute with the given local name or an empty string.
+func attrValue(elem xml.StartElement, local string) string {
+	for _, attr := range elem.Attr {
+		if attr.Name.Local == local {
+			return attr.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,228 @@
+package docx
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// builder.go creates documents without a template: NewDocument starts with a minimal package consisting of the
+// content types, the relationships, the main document and the styles, the content is appended block by block.
+
+const (
+	// OfficeDocumentRelationshipType is the relationship type from the package to its main document part.
+	OfficeDocumentRelationshipType = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument"
+	// StylesRelationshipType is the relationship type of the style definitions (word/styles.xml).
+	StylesRelationshipType = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles"
+	// StylesContentType is the content type of the style definitions.
+	StylesContentType = "application/vnd.openxmlformats-officedocument.wordprocessingml.styles+xml"
+
+	// builderTextWidth is the width of the text area of built documents in twips: a letter page with margins of
+	// one inch.
+	builderTextWidth = 9360
+)
+
+// builderStyles are the style definitions of built documents: Normal, the headings Heading1 to Heading6 and the
+// table style TableGrid.
+var builderStyles = `<w:styles xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">` +
+	`<w:docDefaults><w:rPrDefault><w:rPr><w:rFonts w:ascii="Calibri" w:hAnsi="Calibri" w:eastAsia="Calibri" w:cs="Calibri"/>` +
+	`<w:sz w:val="22"/><w:szCs w:val="22"/><w:lang w:val="en-US"/></w:rPr></w:rPrDefault>` +
+	`<w:pPrDefault><w:pPr><w:spacing w:after="160" w:line="259" w:lineRule="auto"/></w:pPr></w:pPrDefault></w:docDefaults>` +
+	`<w:style w:type="paragraph" w:default="1" w:styleId="Normal"><w:name w:val="Normal"/><w:qFormat/></w:style>` +
+	builderHeadingStyles() +
+	`<w:style w:type="table" w:default="1" w:styleId="TableNormal"><w:name w:val="Normal Table"/><w:uiPriority w:val="99"/><w:semiHidden/>` +
+	`<w:tblPr><w:tblInd w:w="0" w:type="dxa"/><w:tblCellMar><w:top w:w="0" w:type="dxa"/><w:left w:w="108" w:type="dxa"/>` +
+	`<w:bottom w:w="0" w:type="dxa"/><w:right w:w="108" w:type="dxa"/></w:tblCellMar></w:tblPr></w:style>` +
+	`<w:style w:type="table" w:styleId="TableGrid"><w:name w:val="Table Grid"/><w:basedOn w:val="TableNormal"/><w:uiPriority w:val="39"/>` +
+	`<w:pPr><w:spacing w:after="0" w:line="240" w:lineRule="auto"/></w:pPr><w:tblPr><w:tblBorders>` +
+	`<w:top w:val="single" w:sz="4" w:space="0" w:color="auto"/><w:left w:val="single" w:sz="4" w:space="0" w:color="auto"/>` +
+	`<w:bottom w:val="single" w:sz="4" w:space="0" w:color="auto"/><w:right w:val="single" w:sz="4" w:space="0" w:color="auto"/>` +
+	`<w:insideH w:val="single" w:sz="4" w:space="0" w:color="auto"/><w:insideV w:val="single" w:sz="4" w:space="0" w:color="auto"/>` +
+	`</w:tblBorders></w:tblPr></w:style></w:styles>`
+
+// textEscaper escapes the text of runs. Quotes are kept like Word keeps them, they delimit the strings of template
+// actions, e.g. {{index . "first-name"}}.
+var textEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// builderHeadingStyles returns the definitions of the styles Heading1 to Heading6.
+func builderHeadingStyles() string {
+	sizes := []int{32, 26, 24, 22, 22, 22} // in half-points
+	var styles strings.Builder
+	for i, size := range sizes {
+		fmt.Fprintf(&styles, `<w:style w:type="paragraph" w:styleId="Heading%d"><w:name w:val="heading %d"/>`+
+			`<w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:uiPriority w:val="9"/><w:qFormat/>`+
+			`<w:pPr><w:keepNext/><w:keepLines/><w:spacing w:before="240" w:after="80"/><w:outlineLvl w:val="%d"/></w:pPr>`+
+			`<w:rPr><w:b/><w:bCs/><w:sz w:val="%d"/><w:szCs w:val="%d"/></w:rPr></w:style>`, i+1, i+1, i, size, size)
+	}
+	return styles.String()
+}
+
+// Builder appends content to a document created from scratch, see NewDocument. The methods return the builder,
+// so calls can be chained; the first error is returned by Build.
+type Builder struct {
+	blocks []builderBlock
+	err    error
+}
+
+// builderBlock is a block of the body of a built document: either its XML or an image which is added when the
+// document is built.
+type builderBlock struct {
+	xml   string
+	image *Image
+}
+
+// NewDocument returns a builder for a new document, for cases where no template exists at all:
+//
+//	doc, err := docx.NewDocument().
+//		Heading(1, "Report").
+//		Paragraph("Generated on {{.date}}").
+//		Table([][]string{{"Name", "Amount"}, {"Anna", "12"}}).
+//		Build()
+//
+// The document uses the styles Normal, Heading1 to Heading6 and TableGrid on a letter page. Like any other
+// document it may contain placeholders and be processed with ExecuteTemplate or ReplaceAll.
+func NewDocument() *Builder {
+	return &Builder{}
+}
+
+// Paragraph appends a paragraph with the text. Line breaks in the text start a new line of the paragraph.
+func (b *Builder) Paragraph(text string) *Builder {
+	b.blocks = append(b.blocks, builderBlock{xml: `<w:p>` + builderRuns(text) + `</w:p>`})
+	return b
+}
+
+// Heading appends a heading of the given level, from 1 to 6.
+func (b *Builder) Heading(level int, text string) *Builder {
+	if level < 1 || level > 6 {
+		b.setErr(fmt.Errorf("invalid heading level %d, expected 1 to 6", level))
+		return b
+	}
+	b.blocks = append(b.blocks, builderBlock{xml: fmt.Sprintf(`<w:p><w:pPr><w:pStyle w:val="Heading%d"/></w:pPr>%s</w:p>`, level, builderRuns(text))})
+	return b
+}
+
+// Table appends a table with the rows of cells. The columns share the width of the page, rows with fewer cells
+// than the longest row are filled with empty cells.
+func (b *Builder) Table(rows [][]string) *Builder {
+	columns := 0
+	for _, row := range rows {
+		columns = max(columns, len(row))
+	}
+	if columns == 0 {
+		b.setErr(fmt.Errorf("the table has no cells"))
+		return b
+	}
+
+	width := builderTextWidth / columns
+	var table strings.Builder
+	table.WriteString(`<w:tbl><w:tblPr><w:tblStyle w:val="TableGrid"/><w:tblW w:w="0" w:type="auto"/>` +
+		`<w:tblLook w:val="04A0" w:firstRow="1" w:lastRow="0" w:firstColumn="1" w:lastColumn="0" w:noHBand="0" w:noVBand="1"/></w:tblPr><w:tblGrid>`)
+	for range columns {
+		fmt.Fprintf(&table, `<w:gridCol w:w="%d"/>`, width)
+	}
+	table.WriteString(`</w:tblGrid>`)
+	for _, row := range rows {
+		table.WriteString(`<w:tr>`)
+		for i := range columns {
+			text := ""
+			if i < len(row) {
+				text = row[i]
+			}
+			fmt.Fprintf(&table, `<w:tc><w:tcPr><w:tcW w:w="%d" w:type="dxa"/></w:tcPr><w:p>%s</w:p></w:tc>`, width, builderRuns(text))
+		}
+		table.WriteString(`</w:tr>`)
+	}
+	table.WriteString(`</w:tbl>`)
+	b.blocks = append(b.blocks, builderBlock{xml: table.String()})
+	return b
+}
+
+// Image appends a paragraph showing the image, see Image for its size.
+func (b *Builder) Image(img Image) *Builder {
+	b.blocks = append(b.blocks, builderBlock{image: &img})
+	return b
+}
+
+// Build creates the document. The options are applied like by Open. The builder may be used further, each call
+// creates a new document.
+func (b *Builder) Build(opts ...Option) (*Document, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	doc, err := OpenBytes(builderPackage(), opts...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create document: %w", err)
+	}
+
+	var body strings.Builder
+	for _, block := range b.blocks {
+		if block.image == nil {
+			body.WriteString(block.xml)
+			continue
+		}
+		drawing, err := doc.addImageDrawing(DocumentXml, *block.image)
+		if err != nil {
+			return nil, fmt.Errorf("unable to add image: %w", err)
+		}
+		body.WriteString(`<w:p><w:r>` + drawing + `</w:r></w:p>`)
+	}
+	documentXml := strings.Replace(string(doc.GetFile(DocumentXml)), "<w:body>", "<w:body>"+body.String(), 1)
+	if err := doc.SetFile(DocumentXml, []byte(documentXml)); err != nil {
+		return nil, err
+	}
+	if err := doc.refreshRuns(DocumentXml); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// setErr records the first error of the builder.
+func (b *Builder) setErr(err error) {
+	if b.err == nil {
+		b.err = err
+	}
+}
+
+// builderRuns returns the runs of the text, one per line, separated by runs with a line break (<w:br/>).
+func builderRuns(text string) string {
+	if text == "" {
+		return ""
+	}
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		lines[i] = `<w:r><w:t xml:space="preserve">` + textEscaper.Replace(line) + `</w:t></w:r>`
+	}
+	return strings.Join(lines, `<w:r><w:br/></w:r>`)
+}
+
+// builderPackage returns the archive of an empty document.
+func builderPackage() []byte {
+	parts := []struct{ name, data string }{
+		{ContentTypesXml, `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+			`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+			`<Default Extension="xml" ContentType="application/xml"/>` +
+			`<Override PartName="/` + DocumentXml + `" ContentType="` + DocxContentType + `"/>` +
+			`<Override PartName="/` + StylesXml + `" ContentType="` + StylesContentType + `"/></Types>`},
+		{PackageRelsXml, `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="` + OfficeDocumentRelationshipType + `" Target="` + DocumentXml + `"/></Relationships>`},
+		{DocumentRelsXml, `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="` + StylesRelationshipType + `" Target="styles.xml"/></Relationships>`},
+		{DocumentXml, `<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main" ` +
+			`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><w:body>` +
+			`<w:sectPr><w:pgSz w:w="12240" w:h="15840"/>` +
+			`<w:pgMar w:top="1440" w:right="1440" w:bottom="1440" w:left="1440" w:header="720" w:footer="720" w:gutter="0"/>` +
+			`</w:sectPr></w:body></w:document>`},
+		{StylesXml, builderStyles},
+	}
+
+	var buf bytes.Buffer
+	zipWriter := zip.NewWriter(&buf)
+	for _, part := range parts {
+		// writing to a buffer cannot fail
+		fw, _ := zipWriter.Create(part.name)
+		_, _ = fw.Write([]byte(xmlDeclaration + part.data))
+	}
+	_ = zipWriter.Close()
+	return buf.Bytes()
+}
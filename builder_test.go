@@ -0,0 +1,70 @@
+package docx
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func TestNewDocument(t *testing.T) {
+	var logo bytes.Buffer
+	if err := png.Encode(&logo, image.NewRGBA(image.Rect(0, 0, 20, 10))); err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := NewDocument().
+		Heading(1, "Report").
+		Paragraph("Dear {{.name}},\nthe numbers <of> this month:").
+		Table([][]string{{"Item", "Amount"}, {"Rent", "1200"}, {"Total"}}).
+		Image(Image{Data: logo.Bytes(), Width: 40, AltText: "Logo"}).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.ExecuteTemplate(map[string]interface{}{"name": "Anna"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := doc.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	written, err := OpenBytes(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	documentXml := string(written.GetFile(DocumentXml))
+	for _, expected := range []string{
+		`<w:pStyle w:val="Heading1"/></w:pPr><w:r><w:t xml:space="preserve">Report</w:t>`,
+		`Dear Anna,</w:t></w:r><w:r><w:br/></w:r><w:r><w:t xml:space="preserve">the numbers &lt;of&gt; this month:</w:t>`,
+		`<w:tblStyle w:val="TableGrid"/>`,
+		`<w:gridCol w:w="4680"/><w:gridCol w:w="4680"/>`,
+		`<w:t xml:space="preserve">Total</w:t></w:r></w:p></w:tc><w:tc><w:tcPr><w:tcW w:w="4680" w:type="dxa"/></w:tcPr><w:p></w:p></w:tc>`,
+		`<wp:extent cx="381000" cy="190500"/>`,
+	} {
+		if !strings.Contains(documentXml, expected) {
+			t.Errorf("expected %s in %s", expected, documentXml)
+		}
+	}
+	if drawings := written.Drawings(); len(drawings) != 1 || drawings[0].AltText != "Logo" {
+		t.Errorf("unexpected drawings %+v", drawings)
+	}
+	if media := written.mediaFiles; len(media) != 1 || media[0] != "word/media/image1.png" {
+		t.Errorf("unexpected media files %v", media)
+	}
+	if styles, _ := written.loadPackageFile(StylesXml); !bytes.Contains(styles, []byte(`w:styleId="Heading6"`)) {
+		t.Errorf("expected the heading styles in %s", styles)
+	}
+	if docType := written.Type(); docType != TypeDocx {
+		t.Errorf("expected a docx document, got %s", docType)
+	}
+
+	if _, err := NewDocument().Heading(7, "Too deep").Paragraph("text").Build(); err == nil || !strings.Contains(err.Error(), "invalid heading level 7") {
+		t.Errorf("expected an error for the heading level, got %v", err)
+	}
+	if _, err := NewDocument().Table(nil).Build(); err == nil {
+		t.Errorf("expected an error for an empty table")
+	}
+}
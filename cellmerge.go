@@ -0,0 +1,193 @@
+package docx
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// cellmerge.go implements the {{vmerge}} and {{hmerge}} helpers, which merge the cells of rows and columns
+// cloned by {{rows}} and {{columns}}. The helpers write a marker into the cell, which is replaced by the
+// matching cell properties after all rows and columns of the part are expanded.
+
+var (
+	// cellMarkerRegex matches the markers written by the merge helpers.
+	cellMarkerRegex = regexp.MustCompile(`\[\[docx-(vmerge|hmerge) ([0-9a-f]*)\]\]`)
+	// tableCellOpenTagRegex matches the open tag of a table cell including the following whitespace.
+	tableCellOpenTagRegex = regexp.MustCompile(`^<w:tc\b[^>]*>\s*`)
+)
+
+// cellPropertiesOrder lists the children of the table cell properties (<w:tcPr>) in the order of the schema.
+var cellPropertiesOrder = []string{"w:cnfStyle", "w:tcW", "w:gridSpan", "w:hMerge", "w:vMerge", "w:tcBorders", "w:shd",
+	"w:noWrap", "w:tcMar", "w:textDirection", "w:tcFitText", "w:vAlign", "w:hideMark", "w:headers", "w:cellIns",
+	"w:cellDel", "w:cellMerge", "w:tcPrChange"}
+
+// cellMarker is the output of a merge helper. Unlike all other values it is not escaped.
+type cellMarker string
+
+// vmergeHelper implements {{vmerge}} and {{vmerge KEY}}. The cell is merged with the cell above if that cell
+// was marked with the same key, so consecutive rows with the same key (e.g. a group name) share one cell.
+// Without a key, all consecutive marked cells of a column are merged.
+func vmergeHelper(key ...interface{}) cellMarker {
+	return cellMarker("[[docx-vmerge " + hex.EncodeToString([]byte(fmt.Sprint(key...))) + "]]")
+}
+
+// hmergeHelper implements {{hmerge COLUMNS}}. The cell is merged with the following cells of its row,
+// so it spans the given number of grid columns.
+func hmergeHelper(columns int) cellMarker {
+	return cellMarker(fmt.Sprintf("[[docx-hmerge %x]]", columns))
+}
+
+// mergeCells replaces all markers written by the merge helpers by the matching cell properties.
+func mergeCells(data []byte) ([]byte, error) {
+	for {
+		if !cellMarkerRegex.Match(data) {
+			return data, nil
+		}
+		tables, err := findTables(data)
+		if err != nil {
+			return nil, err
+		}
+
+		// nested tables follow their parent, so the last marked table does not contain other marked tables
+		var marked *tableLayout
+		for _, table := range tables {
+			for _, row := range table.Rows {
+				for _, cell := range row.Cells {
+					if cellMarkerRegex.MatchString(cell.Text) {
+						marked = table
+					}
+				}
+			}
+		}
+		if marked == nil {
+			return data, nil
+		}
+		data = mergeTableCells(data, marked)
+	}
+}
+
+// mergeTableCells merges the marked cells of a single table.
+func mergeTableCells(data []byte, table *tableLayout) []byte {
+	type mergeState struct {
+		row int
+		key string
+	}
+	above := make(map[int]mergeState) // the last vmerge of each grid column
+	var replacements []replacement
+
+	for r, row := range table.Rows {
+		removed := make(map[int]bool)
+		for c, cell := range row.Cells {
+			matches := cellMarkerRegex.FindAllStringSubmatch(cell.Text, -1)
+			if removed[c] || len(matches) == 0 {
+				continue
+			}
+
+			cellXml := cellMarkerRegex.ReplaceAll(data[cell.Start:cell.End], nil)
+			column := row.gridColumn(c)
+			for _, match := range matches {
+				switch match[1] {
+				case "vmerge":
+					if prev, exists := above[column]; exists && prev.row == r-1 && prev.key == match[2] {
+						cellXml = clearCellContent(setCellProperty(cellXml, "w:vMerge", []byte(`<w:vMerge/>`)))
+					} else {
+						cellXml = setCellProperty(cellXml, "w:vMerge", []byte(`<w:vMerge w:val="restart"/>`))
+					}
+					above[column] = mergeState{r, match[2]}
+				case "hmerge":
+					columns, _ := strconv.ParseInt(match[2], 16, 0)
+					span, width := cell.GridSpan, cellWidth(cellXml)
+					for j := c + 1; j < len(row.Cells) && int64(span) < columns; j++ {
+						next := row.Cells[j]
+						span += next.GridSpan
+						width += cellWidth(data[next.Start:next.End])
+						removed[j] = true
+						replacements = append(replacements, replacement{next.Start, next.End, nil})
+					}
+					if span > 1 {
+						cellXml = setCellProperty(cellXml, "w:gridSpan", []byte(fmt.Sprintf(`<w:gridSpan w:val="%d"/>`, span)))
+					}
+					if tcW := cellProperty(cellXml, "w:tcW"); tcW != nil && width > 0 {
+						cellXml = setCellProperty(cellXml, "w:tcW", setTagAttr(tcW, "w:w", strconv.Itoa(width)))
+					}
+				}
+			}
+			replacements = append(replacements, replacement{cell.Start, cell.End, cellXml})
+		}
+	}
+
+	sort.Slice(replacements, func(i, j int) bool {
+		return replacements[i].Start < replacements[j].Start
+	})
+	return applyReplacements(data, replacements)
+}
+
+// splitCellProperties splits the cell into its open tag, the content of its properties (<w:tcPr>) and the rest.
+func splitCellProperties(cellXml []byte) (openTag, properties, rest []byte) {
+	openTag = tableCellOpenTagRegex.Find(cellXml)
+	rest = cellXml[len(openTag):]
+	switch {
+	case bytes.HasPrefix(rest, []byte("<w:tcPr/>")):
+		rest = rest[len("<w:tcPr/>"):]
+	case bytes.HasPrefix(rest, []byte("<w:tcPr>")):
+		if end := bytes.Index(rest, []byte("</w:tcPr>")); end >= 0 {
+			properties = rest[len("<w:tcPr>"):end]
+			rest = rest[end+len("</w:tcPr>"):]
+		}
+	}
+	return openTag, properties, rest
+}
+
+// cellProperty returns the element with the given name (e.g. w:tcW) of the cell properties, or nil.
+func cellProperty(cellXml []byte, name string) []byte {
+	_, properties, _ := splitCellProperties(cellXml)
+	return regexp.MustCompile(`<` + regexp.QuoteMeta(name) + `\b[^>]*/>`).Find(properties)
+}
+
+// setCellProperty sets the element with the given name (e.g. w:vMerge) of the cell properties.
+// An existing element is replaced, otherwise the element is inserted in the order of the schema.
+func setCellProperty(cellXml []byte, name string, element []byte) []byte {
+	openTag, properties, rest := splitCellProperties(cellXml)
+	if openTag == nil {
+		return cellXml
+	}
+	properties = regexp.MustCompile(`<`+regexp.QuoteMeta(name)+`\b[^>]*/>`).ReplaceAll(properties, nil)
+
+	var followers []string
+	for i, property := range cellPropertiesOrder {
+		if property == name {
+			followers = cellPropertiesOrder[i+1:]
+		}
+	}
+	properties = insertChild(properties, element, followers)
+
+	out := append([]byte{}, openTag...)
+	out = append(out, []byte("<w:tcPr>")...)
+	out = append(out, properties...)
+	out = append(out, []byte("</w:tcPr>")...)
+	return append(out, rest...)
+}
+
+// clearCellContent replaces the content of the cell by a single empty paragraph, as required for cells
+// which continue a vertical merge.
+func clearCellContent(cellXml []byte) []byte {
+	openTag, properties, _ := splitCellProperties(cellXml)
+	out := append([]byte{}, openTag...)
+	if properties != nil {
+		out = append(out, []byte("<w:tcPr>")...)
+		out = append(out, properties...)
+		out = append(out, []byte("</w:tcPr>")...)
+	}
+	return append(out, []byte("<w:p/></w:tc>")...)
+}
+
+// cellWidth returns the width of the cell (<w:tcW>) or 0 if it is unknown.
+func cellWidth(cellXml []byte) int {
+	value, _ := getTagAttr(cellProperty(cellXml, "w:tcW"), "w:w")
+	width, _ := strconv.Atoi(value)
+	return width
+}
@@ -0,0 +1,267 @@
+package docx
+
+import (
+	"encoding/binary"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+// cfb.go writes compound files (the OLE structured storage of the binary Office formats), which embedded
+// OLE objects are stored in. Only streams inside the root storage are supported.
+
+const (
+	cfbSectorSize     = 512
+	cfbMiniSectorSize = 64
+	cfbMiniCutoff     = 4096
+	cfbDirEntrySize   = 128
+	cfbHeaderDIFAT    = 109 // FAT sector locations inside the header
+
+	cfbFreeSector   = 0xFFFFFFFF
+	cfbEndOfChain   = 0xFFFFFFFE
+	cfbFATSector    = 0xFFFFFFFD
+	cfbDIFATSector  = 0xFFFFFFFC
+	cfbNoStream     = 0xFFFFFFFF
+	cfbRootObject   = 5 // the object type of the root storage
+	cfbStreamObject = 2 // the object type of streams
+)
+
+// cfbStream is a stream of a compound file.
+type cfbStream struct {
+	Name string
+	Data []byte
+}
+
+// writeCompoundFile returns a compound file (version 3) whose root storage has the given CLSID and streams.
+func writeCompoundFile(clsid [16]byte, streams []cfbStream) []byte {
+	// the directory is a red-black tree ordered by the length and the upper case of the names
+	streams = append([]cfbStream(nil), streams...)
+	sort.Slice(streams, func(i, j int) bool {
+		a, b := utf16.Encode([]rune(streams[i].Name)), utf16.Encode([]rune(streams[j].Name))
+		if len(a) != len(b) {
+			return len(a) < len(b)
+		}
+		return strings.ToUpper(streams[i].Name) < strings.ToUpper(streams[j].Name)
+	})
+
+	// small streams are stored in the mini stream, which is stored like a regular stream
+	var miniStream []byte
+	var miniFAT []uint32
+	miniStart := make([]uint32, len(streams))
+	sectors := make([]int, len(streams)) // the number of sectors of the regular streams
+	for i, stream := range streams {
+		if len(stream.Data) >= cfbMiniCutoff {
+			sectors[i] = sectorCount(len(stream.Data), cfbSectorSize)
+			continue
+		}
+		miniStart[i] = uint32(len(miniFAT))
+		if len(stream.Data) == 0 {
+			miniStart[i] = cfbEndOfChain
+		}
+		count := sectorCount(len(stream.Data), cfbMiniSectorSize)
+		miniFAT = appendChain(miniFAT, len(miniFAT), count)
+		miniStream = append(miniStream, padded(stream.Data, cfbMiniSectorSize)...)
+	}
+
+	dirSectors := sectorCount((len(streams)+1)*cfbDirEntrySize, cfbSectorSize)
+	miniFATSectors := sectorCount(len(miniFAT)*4, cfbSectorSize)
+	miniStreamSectors := sectorCount(len(miniStream), cfbSectorSize)
+	content := dirSectors + miniFATSectors + miniStreamSectors
+	for _, count := range sectors {
+		content += count
+	}
+
+	// the FAT and the DIFAT sectors are part of the FAT themselves
+	fatSectors, difatSectors := 0, 0
+	for {
+		needed := sectorCount((content+fatSectors+difatSectors)*4, cfbSectorSize)
+		neededDIFAT := 0
+		if needed > cfbHeaderDIFAT {
+			neededDIFAT = sectorCount(needed-cfbHeaderDIFAT, cfbSectorSize/4-1)
+		}
+		if needed == fatSectors && neededDIFAT == difatSectors {
+			break
+		}
+		fatSectors, difatSectors = needed, neededDIFAT
+	}
+
+	// the sectors are laid out as FAT, DIFAT, directory, mini FAT, mini stream and regular streams
+	var fat []uint32
+	for i := 0; i < fatSectors; i++ {
+		fat = append(fat, cfbFATSector)
+	}
+	for i := 0; i < difatSectors; i++ {
+		fat = append(fat, cfbDIFATSector)
+	}
+	dirStart := len(fat)
+	fat = appendChain(fat, len(fat), dirSectors)
+	miniFATStart := uint32(cfbEndOfChain)
+	if miniFATSectors > 0 {
+		miniFATStart = uint32(len(fat))
+	}
+	fat = appendChain(fat, len(fat), miniFATSectors)
+	miniStreamStart := uint32(cfbEndOfChain)
+	if miniStreamSectors > 0 {
+		miniStreamStart = uint32(len(fat))
+	}
+	fat = appendChain(fat, len(fat), miniStreamSectors)
+	starts := make([]uint32, len(streams))
+	for i, count := range sectors {
+		starts[i] = miniStart[i]
+		if len(streams[i].Data) >= cfbMiniCutoff {
+			starts[i] = uint32(len(fat))
+			fat = appendChain(fat, len(fat), count)
+		}
+	}
+	for len(fat) < fatSectors*cfbSectorSize/4 {
+		fat = append(fat, cfbFreeSector)
+	}
+
+	// directory: the root entry followed by the streams
+	var dir []byte
+	left, right := make([]uint32, len(streams)), make([]uint32, len(streams))
+	red := make([]bool, len(streams))
+	root := cfbTree(left, right, red, 0, len(streams)-1, 0, treeDepth(len(streams)))
+	dir = append(dir, cfbDirEntry("Root Entry", cfbRootObject, false, cfbNoStream, cfbNoStream, root, clsid, miniStreamStart, uint64(len(miniStream)))...)
+	for i, stream := range streams {
+		dir = append(dir, cfbDirEntry(stream.Name, cfbStreamObject, red[i], left[i], right[i], cfbNoStream, [16]byte{}, starts[i], uint64(len(stream.Data)))...)
+	}
+	for len(dir)%cfbSectorSize != 0 {
+		dir = append(dir, cfbDirEntry("", 0, false, cfbNoStream, cfbNoStream, cfbNoStream, [16]byte{}, 0, 0)...)
+	}
+
+	// header
+	header := make([]byte, cfbSectorSize)
+	copy(header, []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1})
+	binary.LittleEndian.PutUint16(header[24:], 0x003E)
+	binary.LittleEndian.PutUint16(header[26:], 0x0003)
+	binary.LittleEndian.PutUint16(header[28:], 0xFFFE)
+	binary.LittleEndian.PutUint16(header[30:], 9)
+	binary.LittleEndian.PutUint16(header[32:], 6)
+	binary.LittleEndian.PutUint32(header[44:], uint32(fatSectors))
+	binary.LittleEndian.PutUint32(header[48:], uint32(dirStart))
+	binary.LittleEndian.PutUint32(header[56:], cfbMiniCutoff)
+	binary.LittleEndian.PutUint32(header[60:], miniFATStart)
+	binary.LittleEndian.PutUint32(header[64:], uint32(miniFATSectors))
+	binary.LittleEndian.PutUint32(header[68:], cfbEndOfChain)
+	if difatSectors > 0 {
+		binary.LittleEndian.PutUint32(header[68:], uint32(fatSectors))
+	}
+	binary.LittleEndian.PutUint32(header[72:], uint32(difatSectors))
+	for i := 0; i < cfbHeaderDIFAT; i++ {
+		location := uint32(cfbFreeSector)
+		if i < fatSectors {
+			location = uint32(i)
+		}
+		binary.LittleEndian.PutUint32(header[76+4*i:], location)
+	}
+
+	out := header
+	out = appendUint32s(out, fat)
+	for i := 0; i < difatSectors; i++ {
+		// each DIFAT sector holds the locations of further FAT sectors and the location of the next DIFAT sector
+		entries := make([]uint32, cfbSectorSize/4)
+		for j := range entries[:len(entries)-1] {
+			entries[j] = cfbFreeSector
+			if fatIndex := cfbHeaderDIFAT + i*(len(entries)-1) + j; fatIndex < fatSectors {
+				entries[j] = uint32(fatIndex)
+			}
+		}
+		entries[len(entries)-1] = cfbEndOfChain
+		if i < difatSectors-1 {
+			entries[len(entries)-1] = uint32(fatSectors + i + 1)
+		}
+		out = appendUint32s(out, entries)
+	}
+	out = append(out, dir...)
+	for len(miniFAT)%(cfbSectorSize/4) != 0 {
+		miniFAT = append(miniFAT, cfbFreeSector)
+	}
+	out = appendUint32s(out, miniFAT)
+	out = append(out, padded(miniStream, cfbSectorSize)...)
+	for _, stream := range streams {
+		if len(stream.Data) >= cfbMiniCutoff {
+			out = append(out, padded(stream.Data, cfbSectorSize)...)
+		}
+	}
+	return out
+}
+
+// cfbTree builds a balanced binary search tree of the directory entries lo to hi and returns the id of its root.
+// Entries on the deepest level of an incomplete tree are red, so all paths have the same number of black entries.
+func cfbTree(left, right []uint32, red []bool, lo, hi, depth, maxDepth int) uint32 {
+	if lo > hi {
+		return cfbNoStream
+	}
+	mid := (lo + hi) / 2
+	left[mid] = cfbTree(left, right, red, lo, mid-1, depth+1, maxDepth)
+	right[mid] = cfbTree(left, right, red, mid+1, hi, depth+1, maxDepth)
+	red[mid] = depth == maxDepth && len(left)+1 != 1<<(maxDepth+1)
+	return uint32(mid + 1) // the root entry has id 0
+}
+
+// treeDepth returns the depth of the deepest entry of a balanced tree with n entries.
+func treeDepth(n int) int {
+	depth := -1
+	for ; n > 0; n /= 2 {
+		depth++
+	}
+	return depth
+}
+
+// cfbDirEntry returns a directory entry.
+func cfbDirEntry(name string, objectType byte, red bool, left, right, child uint32, clsid [16]byte, start uint32, size uint64) []byte {
+	entry := make([]byte, cfbDirEntrySize)
+	encoded := utf16.Encode([]rune(name))
+	for i, c := range encoded {
+		binary.LittleEndian.PutUint16(entry[2*i:], c)
+	}
+	if name != "" {
+		binary.LittleEndian.PutUint16(entry[64:], uint16(2*len(encoded)+2))
+	}
+	entry[66] = objectType
+	entry[67] = 1 // black
+	if red {
+		entry[67] = 0
+	}
+	binary.LittleEndian.PutUint32(entry[68:], left)
+	binary.LittleEndian.PutUint32(entry[72:], right)
+	binary.LittleEndian.PutUint32(entry[76:], child)
+	copy(entry[80:], clsid[:])
+	binary.LittleEndian.PutUint32(entry[116:], start)
+	binary.LittleEndian.PutUint64(entry[120:], size)
+	return entry
+}
+
+// appendChain appends a chain of count sectors starting at sector first to the allocation table.
+func appendChain(table []uint32, first, count int) []uint32 {
+	for i := 0; i < count; i++ {
+		next := uint32(first + i + 1)
+		if i == count-1 {
+			next = cfbEndOfChain
+		}
+		table = append(table, next)
+	}
+	return table
+}
+
+// sectorCount returns the number of sectors needed for size bytes.
+func sectorCount(size, sectorSize int) int {
+	return (size + sectorSize - 1) / sectorSize
+}
+
+// padded returns the data padded with zeros to a multiple of the sector size.
+func padded(data []byte, sectorSize int) []byte {
+	if len(data)%sectorSize == 0 {
+		return data
+	}
+	return append(append([]byte(nil), data...), make([]byte, sectorSize-len(data)%sectorSize)...)
+}
+
+// appendUint32s appends the values in little endian byte order.
+func appendUint32s(out []byte, values []uint32) []byte {
+	for _, value := range values {
+		out = binary.LittleEndian.AppendUint32(out, value)
+	}
+	return out
+}
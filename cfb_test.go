@@ -0,0 +1,153 @@
+package docx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+	"unicode/utf16"
+)
+
+// readCompoundFile returns the CLSID of the root storage and the streams of a compound file. The directory
+// tree is walked from the root, so streams which are not part of the tree are missing.
+func readCompoundFile(t *testing.T, data []byte) ([16]byte, map[string][]byte) {
+	t.Helper()
+	var clsid [16]byte
+	if len(data) < cfbSectorSize || !bytes.HasPrefix(data, []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}) {
+		t.Fatalf("no compound file")
+	}
+	if (len(data)-cfbSectorSize)%cfbSectorSize != 0 {
+		t.Fatalf("the size %d is not a multiple of the sector size", len(data))
+	}
+	le := binary.LittleEndian
+	sector := func(n uint32) []byte {
+		offset := cfbSectorSize * (int(n) + 1)
+		if offset+cfbSectorSize > len(data) {
+			t.Fatalf("sector %d is out of range", n)
+		}
+		return data[offset : offset+cfbSectorSize]
+	}
+
+	// the FAT sectors are listed in the header and in the DIFAT sectors
+	var fatSectors []uint32
+	for i := 0; i < cfbHeaderDIFAT; i++ {
+		fatSectors = append(fatSectors, le.Uint32(data[76+4*i:]))
+	}
+	for next := le.Uint32(data[68:]); next != cfbEndOfChain && next != cfbFreeSector; {
+		difat := sector(next)
+		for i := 0; i < cfbSectorSize/4-1; i++ {
+			fatSectors = append(fatSectors, le.Uint32(difat[4*i:]))
+		}
+		next = le.Uint32(difat[cfbSectorSize-4:])
+	}
+	fatSectors = fatSectors[:le.Uint32(data[44:])]
+	var fat []uint32
+	for _, n := range fatSectors {
+		for i := 0; i < cfbSectorSize; i += 4 {
+			fat = append(fat, le.Uint32(sector(n)[i:]))
+		}
+	}
+	chain := func(table []uint32, start uint32, read func(uint32) []byte) []byte {
+		var out []byte
+		for n := start; n != cfbEndOfChain; n = table[n] {
+			if int(n) >= len(table) || len(out) > len(data) {
+				t.Fatalf("invalid chain starting at %d", start)
+			}
+			out = append(out, read(n)...)
+		}
+		return out
+	}
+
+	dir := chain(fat, le.Uint32(data[48:]), sector)
+	entry := func(id uint32) []byte { return dir[id*cfbDirEntrySize : (id+1)*cfbDirEntrySize] }
+	root := entry(0)
+	if root[66] != cfbRootObject {
+		t.Fatalf("unexpected type %d of the root entry", root[66])
+	}
+	copy(clsid[:], root[80:96])
+
+	var miniFAT []uint32
+	if start := le.Uint32(data[60:]); start != cfbEndOfChain {
+		miniFATData := chain(fat, start, sector)
+		for i := 0; i < len(miniFATData); i += 4 {
+			miniFAT = append(miniFAT, le.Uint32(miniFATData[i:]))
+		}
+	}
+	var miniStream []byte
+	if start := le.Uint32(root[116:]); start != cfbEndOfChain {
+		miniStream = chain(fat, start, sector)
+	}
+	miniSector := func(n uint32) []byte {
+		return miniStream[int(n)*cfbMiniSectorSize : (int(n)+1)*cfbMiniSectorSize]
+	}
+
+	streams := make(map[string][]byte)
+	var walk func(id uint32, black int) int
+	walk = func(id uint32, black int) int {
+		if id == cfbNoStream {
+			return black
+		}
+		e := entry(id)
+		if e[66] != cfbStreamObject {
+			t.Fatalf("unexpected type %d of entry %d", e[66], id)
+		}
+		name := make([]uint16, le.Uint16(e[64:])/2-1)
+		for i := range name {
+			name[i] = le.Uint16(e[2*i:])
+		}
+		size, start := le.Uint64(e[120:]), le.Uint32(e[116:])
+		var content []byte
+		if size >= cfbMiniCutoff {
+			content = chain(fat, start, sector)
+		} else if size > 0 {
+			content = chain(miniFAT, start, miniSector)
+		}
+		streams[string(utf16.Decode(name))] = content[:size]
+
+		if e[67] == 1 {
+			black++
+		}
+		left, right := walk(le.Uint32(e[68:]), black), walk(le.Uint32(e[72:]), black)
+		if left != right {
+			t.Fatalf("the paths below entry %d have different numbers of black entries", id)
+		}
+		return left
+	}
+	walk(le.Uint32(root[76:]), 0)
+	return clsid, streams
+}
+
+func TestWriteCompoundFile(t *testing.T) {
+	pattern := func(size int) []byte {
+		data := make([]byte, size)
+		for i := range data {
+			data[i] = byte(i * 7)
+		}
+		return data
+	}
+	clsid := [16]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16}
+
+	tests := map[string][]cfbStream{
+		"empty":        nil,
+		"mini streams": {{"\x01Ole", pattern(20)}, {"\x01CompObj", pattern(76)}, {"Empty", nil}, {"Contents", pattern(4095)}},
+		"regular streams": {{"First", pattern(4096)}, {"Second", pattern(100000)}, {"Small", pattern(1)},
+			{"A", pattern(2)}, {"Longer name", pattern(3)}, {"B", pattern(5000)}},
+		"DIFAT": {{"Large", pattern(8 << 20)}, {"Small", pattern(10)}},
+	}
+	for name, streams := range tests {
+		t.Run(name, func(t *testing.T) {
+			data := writeCompoundFile(clsid, streams)
+			readCLSID, read := readCompoundFile(t, data)
+			if readCLSID != clsid {
+				t.Errorf("unexpected CLSID %x", readCLSID)
+			}
+			if len(read) != len(streams) {
+				t.Errorf("expected %d streams, got %d", len(streams), len(read))
+			}
+			for _, stream := range streams {
+				if content, found := read[stream.Name]; !found || !bytes.Equal(content, stream.Data) {
+					t.Errorf("stream %q was not written correctly", stream.Name)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package docx
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// maxInsertions limits the number of documents inserted by a single template execution,
+// so clauses which (indirectly) insert themselves are detected.
+const maxInsertions = 1000
+
+var (
+	// insertionActionRegex matches the start of a {{clause}} or {{embed}} action in the text of a paragraph.
+	insertionActionRegex = regexp.MustCompile(`\{\{-?\s*(clause|embed)\s`)
+	// insertionMarkerRegex matches the marker written by the clause and embed functions.
+	insertionMarkerRegex = regexp.MustCompile(`\[\[docx-insert (\d+)\]\]`)
+)
+
+// ClauseLibrary is a registry of clauses by name. A clause is a document whose body is inserted into other
+// documents, either with InsertClause or with the {{clause "name"}} template function.
+type ClauseLibrary struct {
+	clauses map[string]*Document
+}
+
+// NewClauseLibrary creates an empty clause library.
+func NewClauseLibrary() *ClauseLibrary {
+	return &ClauseLibrary{clauses: make(map[string]*Document)}
+}
+
+// LoadClauseLibrary loads all .docx files of the directory into a new clause library.
+// The name of a clause is its file name without extension, e.g. nda_mutual for nda_mutual.docx.
+func LoadClauseLibrary(dir string) (*ClauseLibrary, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.docx"))
+	if err != nil {
+		return nil, err
+	}
+
+	library := NewClauseLibrary()
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		clause, err := OpenBytes(data)
+		if err != nil {
+			return nil, fmt.Errorf("unable to open clause %s: %w", path, err)
+		}
+		library.Add(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)), clause)
+	}
+	return library, nil
+}
+
+// Add adds the clause to the library. An existing clause with the same name is replaced.
+func (l *ClauseLibrary) Add(name string, clause *Document) {
+	l.clauses[name] = clause
+}
+
+// Clause returns the clause with the given name.
+func (l *ClauseLibrary) Clause(name string) (*Document, bool) {
+	clause, exists := l.clauses[name]
+	return clause, exists
+}
+
+// Names returns the sorted names of all clauses of the library.
+func (l *ClauseLibrary) Names() []string {
+	names := make([]string, 0, len(l.clauses))
+	for name := range l.clauses {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// InsertClause replaces the paragraph whose text is the anchor (e.g. [CONFIDENTIALITY]) by the body of the clause.
+// Images and other parts used by the clause are copied. Styles the document does not define are taken over from
+// the clause, lists of the clause get numbering definitions of their own, so they neither continue nor change
+// the lists of the document.
+func (d *Document) InsertClause(anchor string, clause *Document) error {
+	return d.insertDocument(anchor, clause)
+}
+
+// SetClauseLibrary sets the library of the clauses which are inserted by {{clause "name"}}, see ClauseLibrary.
+// If the language is set with SetLanguage, localized clauses such as nda.fr (nda.fr.docx) are preferred.
+func (d *Document) SetClauseLibrary(library *ClauseLibrary) {
+	d.templateReplacer.SetClauseLibrary(library)
+}
+
+// SetClauseLibrary sets the library of the clauses which are inserted by {{clause "name"}}.
+func (tr *TemplateReplacer) SetClauseLibrary(library *ClauseLibrary) {
+	tr.clauses = library
+}
+
+// clauseHelper implements {{clause NAME}}, see insertionMarker.
+func (tr *TemplateReplacer) clauseHelper(name string) (rawXML, error) {
+	if tr.clauses == nil {
+		return "", fmt.Errorf("clause %s: no clause library set", name)
+	}
+	clause, exists := tr.localizedClause(name)
+	if !exists {
+		return "", fmt.Errorf("clause %s not found", name)
+	}
+	return tr.insertionMarker(clause), nil
+}
+
+// insertionMarker returns a marker which is replaced by the body of the document after the template is executed,
+// so the clause and embed functions may be used inside of blocks spanning multiple paragraphs.
+func (tr *TemplateReplacer) insertionMarker(doc *Document) rawXML {
+	tr.insertions = append(tr.insertions, doc)
+	return rawXML("[[docx-insert " + strconv.Itoa(len(tr.insertions)-1) + "]]")
+}
+
+// insertDocuments replaces all paragraphs of the document body consisting of {{clause}} and {{embed}} actions
+// (or the markers written by them) by the bodies of the inserted documents. It returns the number of inserted
+// documents, which must not exceed max.
+func (tr *TemplateReplacer) insertDocuments(max int) (int, error) {
+	inserted := 0
+	for {
+		data := tr.document.GetFile(DocumentXml)
+		paragraphs, err := findBlockParagraphs(data)
+		if err != nil {
+			return inserted, err
+		}
+
+		found := false
+		for _, paragraph := range paragraphs {
+			text := strings.TrimSpace(paragraph.Text)
+			if !insertionActionRegex.MatchString(text) && !insertionMarkerRegex.MatchString(text) {
+				continue
+			}
+
+			markers := text
+			if insertionActionRegex.MatchString(text) {
+				result, err := tr.executeFragment(text, tr.data)
+				if err != nil {
+					return inserted, newTemplateError(data, DocumentXml, paragraph.Start, text, err)
+				}
+				markers = string(result)
+			}
+			if strings.TrimSpace(insertionMarkerRegex.ReplaceAllString(markers, "")) != "" {
+				return inserted, fmt.Errorf("the clause and embed actions in %q must be the only content of their paragraph", text)
+			}
+
+			var documents []*Document
+			for _, match := range insertionMarkerRegex.FindAllStringSubmatch(markers, -1) {
+				index, _ := strconv.Atoi(match[1])
+				if index >= len(tr.insertions) {
+					return inserted, fmt.Errorf("unknown document marker %s", match[0])
+				}
+				documents = append(documents, tr.insertions[index])
+			}
+			if inserted += len(documents); inserted > max {
+				return inserted, fmt.Errorf("more than %d documents inserted, does a clause insert itself?", max)
+			}
+			tr.debugLog("Inserting %d documents", len(documents))
+			if err := tr.document.replaceParagraph(paragraph, documents...); err != nil {
+				return inserted, fmt.Errorf("failed to insert %q: %w", text, err)
+			}
+			found = true
+			break
+		}
+		if !found {
+			return inserted, nil
+		}
+	}
+}
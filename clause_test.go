@@ -0,0 +1,118 @@
+package docx
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// numberedDocument returns the test template with a list using numId 1 and the given paragraphs before it.
+func numberedDocument(t *testing.T, paragraphs string) []byte {
+	numbering := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<w:numbering xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">` +
+		`<w:abstractNum w:abstractNumId="0"><w:nsid w:val="11111111"/><w:lvl w:ilvl="0"><w:numFmt w:val="decimal"/></w:lvl></w:abstractNum>` +
+		`<w:num w:numId="1"><w:abstractNumId w:val="0"/></w:num></w:numbering>`
+	return rewriteArchive(t, readFile(t, "./test/template.docx"), func(name string, data []byte) []byte {
+		switch name {
+		case DocumentXml:
+			return []byte(strings.Replace(string(data), "<w:body>", "<w:body>"+paragraphs+
+				`<w:p><w:pPr><w:numPr><w:ilvl w:val="0"/><w:numId w:val="1"/></w:numPr></w:pPr><w:r><w:t>List item</w:t></w:r></w:p>`, 1))
+		case DocumentRelsXml:
+			return []byte(strings.Replace(string(data), "</Relationships>", `<Relationship Id="rId90" Type="`+NumberingRelationshipType+`" Target="numbering.xml"/></Relationships>`, 1))
+		case "[Content_Types].xml":
+			return []byte(strings.Replace(string(data), "</Types>", `<Override PartName="/word/numbering.xml" ContentType="`+NumberingContentType+`"/></Types>`, 1))
+		}
+		return data
+	}, map[string][]byte{"word/numbering.xml": []byte(numbering)})
+}
+
+func TestDocument_InsertClause(t *testing.T) {
+	doc, err := OpenBytes(numberedDocument(t, `<w:p><w:r><w:t>[CONFIDENTIALITY]</w:t></w:r></w:p>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+	clause, err := OpenBytes(numberedDocument(t, `<w:p><w:r><w:t>Confidentiality clause</w:t></w:r></w:p>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clause.Close()
+
+	if err := doc.InsertClause("[CONFIDENTIALITY]", clause); err != nil {
+		t.Fatal(err)
+	}
+	documentXml := string(doc.GetFile(DocumentXml))
+	if strings.Contains(documentXml, "[CONFIDENTIALITY]") || !strings.Contains(documentXml, "Confidentiality clause") {
+		t.Fatalf("the clause was not inserted")
+	}
+	if strings.Count(documentXml, `<w:numId w:val="1"/>`) != 1 || strings.Count(documentXml, `<w:numId w:val="2"/>`) != 1 {
+		t.Errorf("expected the list of the clause to use a new numbering")
+	}
+
+	numbering, _ := doc.loadPackageFile("word/numbering.xml")
+	if !strings.Contains(string(numbering), `<w:num w:numId="1"><w:abstractNumId w:val="0"/>`) || !strings.Contains(string(numbering), `<w:num w:numId="2"><w:abstractNumId w:val="1"/>`) {
+		t.Errorf("unexpected numbering: %s", numbering)
+	}
+	if strings.Count(string(numbering), `<w:nsid w:val="11111111"/>`) != 1 {
+		t.Errorf("the copied list must get a new nsid")
+	}
+
+	var buf bytes.Buffer
+	if err := doc.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := doc.InsertClause("[MISSING]", clause); err == nil {
+		t.Errorf("expected an error for a missing anchor")
+	}
+}
+
+func TestDocument_ClauseTemplate(t *testing.T) {
+	dir := t.TempDir()
+	clauseData := numberedDocument(t, `<w:p><w:r><w:t>Mutual NDA for {{.party}}</w:t></w:r></w:p>`)
+	if err := os.WriteFile(filepath.Join(dir, "nda_mutual.docx"), clauseData, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("ignored"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	library, err := LoadClauseLibrary(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := library.Names(); len(names) != 1 || names[0] != "nda_mutual" {
+		t.Fatalf("unexpected clauses %v", names)
+	}
+
+	doc, err := OpenBytes(numberedDocument(t, `<w:p><w:r><w:t>{{if .nda}}</w:t></w:r></w:p>`+
+		`<w:p><w:r><w:t>{{clause "nda_mutual"}}</w:t></w:r></w:p>`+
+		`<w:p><w:r><w:t>{{end}}</w:t></w:r></w:p>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+	doc.SetClauseLibrary(library)
+	if err := doc.ExecuteTemplate(map[string]interface{}{"nda": true, "party": "ACME"}); err != nil {
+		t.Fatal(err)
+	}
+
+	documentXml := string(doc.GetFile(DocumentXml))
+	if !strings.Contains(documentXml, "Mutual NDA for ACME") || strings.Contains(documentXml, "docx-clause") {
+		t.Errorf("the clause was not inserted and executed")
+	}
+	if strings.Count(documentXml, `<w:numId w:val="2"/>`) != 1 {
+		t.Errorf("expected the list of the clause to use a new numbering")
+	}
+
+	unknown, err := OpenBytes(numberedDocument(t, `<w:p><w:r><w:t>{{clause "unknown"}}</w:t></w:r></w:p>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer unknown.Close()
+	unknown.SetClauseLibrary(library)
+	if err := unknown.ExecuteTemplate(map[string]interface{}{}); err == nil {
+		t.Errorf("expected an error for an unknown clause")
+	}
+}
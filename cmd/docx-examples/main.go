@@ -0,0 +1,170 @@
+// Command docx-examples generates the templates of the examples, so they run out of the box:
+//
+//	go run ./cmd/docx-examples
+//	cd examples/simple && go run .
+//
+// Each example directory receives a template.docx built with docx.NewDocument; the images example also receives
+// the logo.png and signature.png it puts into the document.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/izetmolla/docx"
+)
+
+// example is a generated example: the directory below the examples directory and its template.
+type example struct {
+	name     string
+	template func() (*docx.Builder, error)
+	files    func() (map[string][]byte, error) // additional files of the example directory, may be nil
+}
+
+var examples = []example{
+	{name: "simple", template: simpleTemplate},
+	{name: "advanced", template: advancedTemplate},
+	{name: "images", template: imagesTemplate, files: imagesFiles},
+	{name: "tables", template: tablesTemplate},
+}
+
+func main() {
+	dir := flag.String("dir", "examples", "the directory of the examples")
+	flag.Parse()
+
+	for _, ex := range examples {
+		if err := generate(*dir, ex); err != nil {
+			log.Fatalf("unable to generate the %s example: %v", ex.name, err)
+		}
+		log.Printf("generated %s", filepath.Join(*dir, ex.name, "template.docx"))
+	}
+}
+
+// generate writes the template and the additional files of the example.
+func generate(dir string, ex example) error {
+	target := filepath.Join(dir, ex.name)
+	if err := os.MkdirAll(target, 0o755); err != nil {
+		return err
+	}
+	builder, err := ex.template()
+	if err != nil {
+		return err
+	}
+	doc, err := builder.Build()
+	if err != nil {
+		return err
+	}
+	defer doc.Close()
+	if err := doc.WriteToFile(filepath.Join(target, "template.docx")); err != nil {
+		return err
+	}
+
+	if ex.files == nil {
+		return nil
+	}
+	files, err := ex.files()
+	if err != nil {
+		return err
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(target, name), data, 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// simpleTemplate uses plain placeholders.
+func simpleTemplate() (*docx.Builder, error) {
+	return docx.NewDocument().
+		Heading(1, "Hello {{.name}}!").
+		Paragraph("Your age is {{.age}} years old.").
+		Paragraph("Email: {{.email}}").
+		Paragraph("Title: {{.title}}").
+		Paragraph("Company: {{.company}}"), nil
+}
+
+// advancedTemplate uses nested fields, conditions, loops and the custom functions of the example.
+func advancedTemplate() (*docx.Builder, error) {
+	return docx.NewDocument().
+		Heading(1, "{{.company.Name | upper}}").
+		Paragraph("Founded in {{.company.Founded}}, revenue {{.company.Revenue | formatCurrency}}").
+		Paragraph("Report of {{formatDate .currentDate}}, version {{.version}} ({{.year}})").
+		Heading(2, "Employees").
+		Paragraph("{{range .company.Employees}}").
+		Paragraph("{{.Name}} ({{.Age}}, in five years {{add .Age 5}}), {{.Email | lower}}{{if not .Active}}, inactive{{end}}").
+		Paragraph("Skills: {{join .Skills \", \"}}").
+		Paragraph("{{end}}").
+		Heading(2, "Statistics").
+		Table([][]string{
+			{"Employees", "Active", "Average age"},
+			{"{{.stats.totalEmployees}}", "{{.stats.activeEmployees}}", "{{.stats.averageAge}}"},
+		}), nil
+}
+
+// imagesTemplate shows two placeholder images, word/media/image1.png and word/media/image2.png, which the example
+// replaces with logo.png and signature.png.
+func imagesTemplate() (*docx.Builder, error) {
+	logo, err := placeholderPNG(color.RGBA{R: 0xcc, G: 0xcc, B: 0xcc, A: 0xff})
+	if err != nil {
+		return nil, err
+	}
+	signature, err := placeholderPNG(color.RGBA{R: 0xee, G: 0xee, B: 0xee, A: 0xff})
+	if err != nil {
+		return nil, err
+	}
+	return docx.NewDocument().
+		Image(docx.Image{Data: logo, Width: 160, Height: 80, Name: "Logo", AltText: "Company logo"}).
+		Heading(1, "{{.title}}").
+		Paragraph("{{.reportType}} of the {{.department}} department").
+		Paragraph("Date: {{.date}}").
+		Paragraph("Author: {{.author}}").
+		Image(docx.Image{Data: signature, Width: 160, Height: 80, Name: "Signature", AltText: "Signature"}), nil
+}
+
+// imagesFiles returns the images the example puts into the document.
+func imagesFiles() (map[string][]byte, error) {
+	logo, err := placeholderPNG(color.RGBA{R: 0x1f, G: 0x4e, B: 0x79, A: 0xff})
+	if err != nil {
+		return nil, err
+	}
+	signature, err := placeholderPNG(color.RGBA{R: 0x38, G: 0x76, B: 0x1d, A: 0xff})
+	if err != nil {
+		return nil, err
+	}
+	return map[string][]byte{"logo.png": logo, "signature.png": signature}, nil
+}
+
+// tablesTemplate repeats a table row per item.
+func tablesTemplate() (*docx.Builder, error) {
+	return docx.NewDocument().
+		Heading(1, "Invoice {{.number}}").
+		Paragraph("Customer: {{.customer}}").
+		Table([][]string{
+			{"Item", "Quantity", "Price"},
+			{"{{rows .items}}{{.name}}", "{{.quantity}}", "{{printf \"%.2f\" .price}}"},
+		}).
+		Paragraph("Total: {{printf \"%.2f\" .total}}"), nil
+}
+
+// placeholderPNG returns an image of 160x80 pixels filled with the color.
+func placeholderPNG(fill color.Color) ([]byte, error) {
+	img := image.NewRGBA(image.Rect(0, 0, 160, 80))
+	for y := range 80 {
+		for x := range 160 {
+			img.Set(x, y, fill)
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("unable to encode image: %w", err)
+	}
+	return buf.Bytes(), nil
+}
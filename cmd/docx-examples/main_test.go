@@ -0,0 +1,37 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/izetmolla/docx"
+)
+
+func TestGenerate(t *testing.T) {
+	dir := t.TempDir()
+	for _, ex := range examples {
+		if err := generate(dir, ex); err != nil {
+			t.Fatalf("unable to generate the %s example: %v", ex.name, err)
+		}
+
+		doc, err := docx.Open(filepath.Join(dir, ex.name, "template.docx"))
+		if err != nil {
+			t.Fatalf("unable to open the template of the %s example: %v", ex.name, err)
+		}
+		doc.Close()
+
+		if ex.files == nil {
+			continue
+		}
+		files, err := ex.files()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for name := range files {
+			if _, err := os.Stat(filepath.Join(dir, ex.name, name)); err != nil {
+				t.Errorf("expected the file %s in the %s example: %v", name, ex.name, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,506 @@
+package docx
+
+import (
+	"bytes"
+	"fmt"
+	"hash/crc32"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	// ContentPlaceholder marks the paragraph of a layout document which is replaced by the content, see Compose.
+	ContentPlaceholder = "{{content}}"
+
+	// NumberingRelationshipType is the relationship type of the numbering definitions (word/numbering.xml).
+	NumberingRelationshipType = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/numbering"
+	// NumberingContentType is the content type of the numbering definitions.
+	NumberingContentType = "application/vnd.openxmlformats-officedocument.wordprocessingml.numbering+xml"
+)
+
+var (
+	// bodyRegex matches the content of the document body.
+	bodyRegex = regexp.MustCompile(`(?s)<w:body>(.*)</w:body>`)
+	// headerFooterReferenceRegex matches the references to headers and footers inside section properties.
+	headerFooterReferenceRegex = regexp.MustCompile(`<w:(?:header|footer)Reference\b[^>]*/>`)
+	// relationshipAttrRegex matches the attributes which reference a relationship of the part.
+	relationshipAttrRegex = regexp.MustCompile(`\br:(id|embed|link|pict|dm|lo|qs|cs)="([^"]*)"`)
+	// namespaceAttrRegex matches a namespace declaration inside a start tag.
+	namespaceAttrRegex = regexp.MustCompile(`\sxmlns:(\w+)="[^"]*"`)
+	// documentOpenTagRegex matches the root element of the main document part.
+	documentOpenTagRegex = regexp.MustCompile(`<w:document\b[^>]*>`)
+	// styleRegex matches a style definition of the styles part.
+	styleRegex = regexp.MustCompile(`(?s)<w:style\b[^>]*>.*?</w:style>`)
+	// styleReferenceRegex matches the references to other styles inside content or style definitions.
+	styleReferenceRegex = regexp.MustCompile(`<w:(?:pStyle|rStyle|tblStyle|basedOn|next|link)\s+w:val="([^"]*)"`)
+	// numIdRegex matches the reference to a numbering definition inside the numbering properties of a paragraph.
+	numIdRegex = regexp.MustCompile(`<w:numId\s+w:val="(\d+)"\s*/>`)
+	// numRegex matches a numbering definition instance (<w:num>) of the numbering part.
+	numRegex = regexp.MustCompile(`(?s)<w:num\s[^>]*?w:numId="(\d+)"[^>]*>.*?</w:num>`)
+	// abstractNumRegex matches an abstract numbering definition of the numbering part.
+	abstractNumRegex = regexp.MustCompile(`(?s)<w:abstractNum\s[^>]*?w:abstractNumId="(\d+)"[^>]*>.*?</w:abstractNum>`)
+	// abstractNumIdRegex matches the reference of a numbering definition instance to its abstract definition.
+	abstractNumIdRegex = regexp.MustCompile(`<w:abstractNumId\s+w:val="(\d+)"\s*/>`)
+	// nsidRegex matches the unique id of an abstract numbering definition.
+	nsidRegex = regexp.MustCompile(`<w:nsid\s+w:val="([0-9A-Fa-f]*)"\s*/>`)
+)
+
+// Compose combines a layout document (headers, footers, cover page, styles) with a content document: the paragraph
+// of the layout which consists of ContentPlaceholder is replaced by the body of the content. Images, hyperlinks and
+// other parts referenced by the content are copied, styles the layout does not define are taken over from the content.
+// The sections of the content use the headers and footers of the layout. Neither of the documents is modified.
+func Compose(layout, content *Document) (*Document, error) {
+	var buf bytes.Buffer
+	if err := layout.Write(&buf); err != nil {
+		return nil, fmt.Errorf("unable to copy layout: %w", err)
+	}
+	doc, err := OpenBytes(buf.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	if err := doc.insertDocument(ContentPlaceholder, content); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// insertDocument replaces the paragraph whose text is the anchor by the body of the source document.
+func (d *Document) insertDocument(anchor string, source *Document) error {
+	paragraph, err := d.anchorParagraph(anchor)
+	if err != nil {
+		return err
+	}
+	return d.replaceParagraph(paragraph, source)
+}
+
+// anchorParagraph returns the first paragraph of the main document part whose text is the anchor.
+func (d *Document) anchorParagraph(anchor string) (blockParagraph, error) {
+	paragraphs, err := findBlockParagraphs(d.GetFile(DocumentXml))
+	if err != nil {
+		return blockParagraph{}, err
+	}
+	for _, paragraph := range paragraphs {
+		if strings.TrimSpace(paragraph.Text) == anchor {
+			return paragraph, nil
+		}
+	}
+	return blockParagraph{}, fmt.Errorf("no paragraph %s found", anchor)
+}
+
+// replaceParagraph replaces the paragraph of the main document part by the bodies of the source documents.
+func (d *Document) replaceParagraph(paragraph blockParagraph, sources ...*Document) error {
+	length := len(d.GetFile(DocumentXml))
+	var body []byte
+	for _, source := range sources {
+		sourceBody, err := d.importBody(source)
+		if err != nil {
+			return err
+		}
+		body = append(body, sourceBody...)
+	}
+
+	// importing the body might have declared additional namespaces in the root element, which moves the paragraph
+	return d.replaceParagraphXml(paragraph, body, int64(len(d.GetFile(DocumentXml))-length))
+}
+
+// replaceParagraphXml replaces the paragraph of the main document part by the block level content.
+// The offset is added to the positions of the paragraph, which were determined before the part changed.
+func (d *Document) replaceParagraphXml(paragraph blockParagraph, body []byte, offset int64) error {
+	documentXml := d.GetFile(DocumentXml)
+	if paragraph.Parent >= 0 && bytes.HasPrefix(documentXml[paragraph.Parent+offset:], []byte("<w:tc")) &&
+		!bytes.HasSuffix(bytes.TrimSpace(body), []byte("</w:p>")) && !bytes.HasSuffix(bytes.TrimSpace(body), []byte("<w:p/>")) {
+		// table cells must end with a paragraph
+		body = append(body, []byte("<w:p/>")...)
+	}
+	documentXml = applyReplacements(documentXml, []replacement{{paragraph.Start + offset, paragraph.End + offset, body}})
+	if err := d.SetFile(DocumentXml, documentXml); err != nil {
+		return err
+	}
+	return d.refreshRuns(DocumentXml)
+}
+
+// importBody returns the body of the source document, prepared to be inserted into the document body.
+// The parts referenced by the body are copied, its relationships and styles are added to the document
+// and the namespaces it uses are declared. The section properties of the last section are left out.
+func (d *Document) importBody(source *Document) ([]byte, error) {
+	match := bodyRegex.FindSubmatch(source.GetFile(DocumentXml))
+	if match == nil {
+		return nil, fmt.Errorf("the document does not have a body")
+	}
+	body := withoutFinalSection(match[1])
+	return d.importContent(source, headerFooterReferenceRegex.ReplaceAll(body, nil))
+}
+
+// importContent prepares block level content of the main document part of the source document to be inserted
+// into the document body, see importBody.
+func (d *Document) importContent(source *Document, body []byte) ([]byte, error) {
+	body, err := d.importRelationships(source, DocumentXml, DocumentXml, body)
+	if err != nil {
+		return nil, err
+	}
+	if body, err = d.keepOutlineLevels(source, body); err != nil {
+		return nil, err
+	}
+	styles, err := d.missingStyles(source, body)
+	if err != nil {
+		return nil, err
+	}
+
+	// the lists of the body and the imported styles get numbering definitions of their own
+	numIds, err := d.importNumbering(source, append(append([]byte{}, body...), styles...))
+	if err != nil {
+		return nil, err
+	}
+	renumber := func(content []byte) []byte {
+		return numIdRegex.ReplaceAllFunc(content, func(tag []byte) []byte {
+			if id, exists := numIds[string(numIdRegex.FindSubmatch(tag)[1])]; exists {
+				return []byte(`<w:numId w:val="` + id + `"/>`)
+			}
+			return tag
+		})
+	}
+	body = renumber(body)
+	if err := d.appendStyles(renumber(styles)); err != nil {
+		return nil, err
+	}
+	if err := d.importNamespaces(source); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// withoutFinalSection removes the section properties of the last section from the end of the body.
+// The properties of other sections are part of the last paragraph of their section and are kept.
+func withoutFinalSection(body []byte) []byte {
+	i := bytes.LastIndex(body, []byte("<w:sectPr"))
+	if i < 0 || bytes.Contains(body[i:], []byte("</w:p>")) {
+		return body
+	}
+	return body[:i]
+}
+
+// importRelationships copies the relationships of the source part which are referenced by the content to the
+// target part and returns the content with the new relationship ids. Internal targets are copied.
+func (d *Document) importRelationships(source *Document, sourcePart, targetPart string, content []byte) ([]byte, error) {
+	sourceRels, err := source.packageRelationships(sourcePart)
+	if err != nil {
+		return nil, err
+	}
+	rels, err := d.packageRelationships(targetPart)
+	if err != nil {
+		return nil, err
+	}
+
+	copied := make(map[string]string) // source part => document part
+	ids := make(map[string]string)    // source id => new id
+	for _, match := range relationshipAttrRegex.FindAllSubmatch(content, -1) {
+		id := string(match[2])
+		if _, done := ids[id]; done {
+			continue
+		}
+		for _, rel := range sourceRels.Relationships {
+			if rel.ID != id {
+				continue
+			}
+			if rel.TargetMode == TargetModeExternal {
+				ids[id] = rels.add(rel.Type, rel.Target, rel.TargetMode)
+				break
+			}
+			copiedPart, err := d.copyPart(source, resolveTarget(sourcePart, rel.Target), copied)
+			if err != nil {
+				return nil, err
+			}
+			ids[id] = rels.add(rel.Type, relativeTarget(targetPart, copiedPart), "")
+			break
+		}
+	}
+	if err := d.storeRelationships(targetPart, rels); err != nil {
+		return nil, err
+	}
+
+	return relationshipAttrRegex.ReplaceAllFunc(content, func(attr []byte) []byte {
+		match := relationshipAttrRegex.FindSubmatch(attr)
+		if id, exists := ids[string(match[2])]; exists {
+			return []byte(`r:` + string(match[1]) + `="` + id + `"`)
+		}
+		return attr
+	}), nil
+}
+
+// copyPart copies a part of the source document and all parts it references into this document
+// and returns the name of the copy. Parts which were copied before are reused.
+func (d *Document) copyPart(source *Document, sourcePart string, copied map[string]string) (string, error) {
+	if name, exists := copied[sourcePart]; exists {
+		return name, nil
+	}
+	data, exists := source.partData(sourcePart)
+	if !exists {
+		return "", fmt.Errorf("part %s not found", sourcePart)
+	}
+	sourceTypes, err := source.packageContentTypes()
+	if err != nil {
+		return "", err
+	}
+	contentType := sourceTypes.lookup("/" + sourcePart)
+
+	extension := path.Ext(sourcePart)
+	prefix := strings.TrimRight(strings.TrimSuffix(sourcePart, extension), "0123456789")
+	name := nextPartName(prefix, extension, d.partNames())
+	copied[sourcePart] = name
+
+	if err := d.ensureContentType(name, contentType); err != nil {
+		return "", err
+	}
+	if MediaPathRegex.MatchString(name) {
+		d.setMediaFile(name, data)
+	} else {
+		d.setPackageFile(name, data)
+	}
+
+	// the relationships of the part keep their ids, only their targets are copied as well
+	if _, exists := source.loadPackageFile(relsPartName(sourcePart)); !exists {
+		return name, nil
+	}
+	rels, err := source.packageRelationships(sourcePart)
+	if err != nil {
+		return "", err
+	}
+	for i, rel := range rels.Relationships {
+		if rel.TargetMode == TargetModeExternal {
+			continue
+		}
+		target, err := d.copyPart(source, resolveTarget(sourcePart, rel.Target), copied)
+		if err != nil {
+			return "", err
+		}
+		rels.Relationships[i].Target = relativeTarget(name, target)
+	}
+	return name, d.storeRelationships(name, rels)
+}
+
+// partData returns the content of any part of the document.
+func (d *Document) partData(partName string) ([]byte, bool) {
+	d.loadLazyFile(partName)
+	if data, exists := d.files[partName]; exists {
+		return data, true
+	}
+	return d.loadPackageFile(partName)
+}
+
+// partNames returns the names of all parts of the document, including added parts.
+func (d *Document) partNames() []string {
+	var names []string
+	for _, file := range d.zipFile.File {
+		if !d.removedFiles[file.Name] {
+			names = append(names, file.Name)
+		}
+	}
+	names = append(names, d.addedFiles()...)
+	return append(names, d.addedPackageFiles()...)
+}
+
+// missingStyles returns the definitions of the styles used by the content (and the styles they are based on)
+// which are defined by the source document but not by this document. Styles defined by both documents keep
+// their definition of this document.
+func (d *Document) missingStyles(source *Document, content []byte) ([]byte, error) {
+	sourceStyles, exists := source.loadPackageFile(StylesXml)
+	if !exists {
+		return nil, nil
+	}
+	styles, exists := d.loadPackageFile(StylesXml)
+	if !exists {
+		return nil, nil
+	}
+
+	definitions := make(map[string][]byte)
+	for _, style := range styleRegex.FindAll(sourceStyles, -1) {
+		if id, ok := getTagAttr(style, "w:styleId"); ok {
+			definitions[id] = style
+		}
+	}
+	defined := make(map[string]bool)
+	for _, style := range styleRegex.FindAll(styles, -1) {
+		if id, ok := getTagAttr(style, "w:styleId"); ok {
+			defined[id] = true
+		}
+	}
+
+	var added []byte
+	queue := styleReferenceRegex.FindAllSubmatch(content, -1)
+	for len(queue) > 0 {
+		id := string(queue[0][1])
+		queue = queue[1:]
+		definition, known := definitions[id]
+		if defined[id] || !known {
+			continue
+		}
+		defined[id] = true
+		added = append(added, definition...)
+		queue = append(queue, styleReferenceRegex.FindAllSubmatch(definition, -1)...)
+	}
+	return added, nil
+}
+
+// appendStyles appends the style definitions to the styles part.
+func (d *Document) appendStyles(definitions []byte) error {
+	if len(definitions) == 0 {
+		return nil
+	}
+	styles, _ := d.loadPackageFile(StylesXml)
+	end := bytes.LastIndex(styles, []byte("</w:styles>"))
+	if end < 0 {
+		return fmt.Errorf("invalid styles part")
+	}
+	d.setPackageFile(StylesXml, insertAt(styles, end, definitions))
+	return nil
+}
+
+// importNumbering copies the numbering definitions referenced by the content from the source document.
+// Each copy gets new ids, so the lists of the content neither continue nor change the lists of this document.
+// The returned map contains the new id of each copied numbering definition.
+func (d *Document) importNumbering(source *Document, content []byte) (map[string]string, error) {
+	var used []string
+	for _, match := range numIdRegex.FindAllSubmatch(content, -1) {
+		if id := string(match[1]); id != "0" && !slices.Contains(used, id) {
+			used = append(used, id)
+		}
+	}
+	if len(used) == 0 {
+		return nil, nil
+	}
+	sourceNumbering, exists := source.partData(source.numberingPart())
+	if !exists {
+		return nil, nil
+	}
+	numberingPart := d.numberingPart()
+	numbering, exists := d.partData(numberingPart)
+	if !exists {
+		numbering = []byte(xmlDeclaration + `<w:numbering ` + wordprocessingNamespaces + `></w:numbering>`)
+		if err := d.addPart(numberingPart, numbering, NumberingContentType); err != nil {
+			return nil, err
+		}
+		if _, err := d.addRelationship(DocumentXml, NumberingRelationshipType, numberingPart); err != nil {
+			return nil, err
+		}
+	}
+
+	nextId := func(re *regexp.Regexp) int {
+		next := 1
+		for _, match := range re.FindAllSubmatch(numbering, -1) {
+			if id, err := strconv.Atoi(string(match[1])); err == nil && id >= next {
+				next = id + 1
+			}
+		}
+		return next
+	}
+	nextNum, nextAbstract := nextId(numRegex), nextId(abstractNumRegex)
+
+	sourceNums := make(map[string][]byte)
+	for _, match := range numRegex.FindAllSubmatch(sourceNumbering, -1) {
+		sourceNums[string(match[1])] = match[0]
+	}
+	sourceAbstracts := make(map[string][]byte)
+	for _, match := range abstractNumRegex.FindAllSubmatch(sourceNumbering, -1) {
+		sourceAbstracts[string(match[1])] = match[0]
+	}
+
+	ids := make(map[string]string)
+	abstractIds := make(map[string]string)
+	var abstracts, nums []byte
+	for _, id := range used {
+		num, exists := sourceNums[id]
+		if !exists {
+			continue
+		}
+		abstractId := ""
+		if match := abstractNumIdRegex.FindSubmatch(num); match != nil {
+			abstractId = string(match[1])
+		}
+		abstract, exists := sourceAbstracts[abstractId]
+		if !exists {
+			continue
+		}
+
+		newAbstractId, copied := abstractIds[abstractId]
+		if !copied {
+			newAbstractId = strconv.Itoa(nextAbstract)
+			nextAbstract++
+			abstractIds[abstractId] = newAbstractId
+			openTag := abstract[:bytes.IndexByte(abstract, '>')+1]
+			abstract = append(setTagAttr(openTag, "w:abstractNumId", newAbstractId), abstract[len(openTag):]...)
+			// Word joins lists whose abstract definitions have the same nsid
+			abstract = nsidRegex.ReplaceAll(abstract, []byte(fmt.Sprintf(`<w:nsid w:val="%08X"/>`, crc32.ChecksumIEEE(append(numbering, abstract...)))))
+			abstracts = append(abstracts, abstract...)
+		}
+
+		newId := strconv.Itoa(nextNum)
+		nextNum++
+		ids[id] = newId
+		openTag := num[:bytes.IndexByte(num, '>')+1]
+		num = append(setTagAttr(openTag, "w:numId", newId), num[len(openTag):]...)
+		num = abstractNumIdRegex.ReplaceAll(num, []byte(`<w:abstractNumId w:val="`+newAbstractId+`"/>`))
+		nums = append(nums, num...)
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	// the abstract definitions precede all numbering definition instances
+	abstractsAt := bytes.LastIndex(numbering, []byte("</w:abstractNum>"))
+	if abstractsAt >= 0 {
+		abstractsAt += len("</w:abstractNum>")
+	} else if loc := regexp.MustCompile(`<w:num\s|<w:numIdMacAtCleanup\b|</w:numbering>`).FindIndex(numbering); loc != nil {
+		abstractsAt = loc[0]
+	} else {
+		return nil, fmt.Errorf("invalid numbering part")
+	}
+	numbering = insertAt(numbering, abstractsAt, abstracts)
+	numsAt := bytes.Index(numbering, []byte("<w:numIdMacAtCleanup"))
+	if numsAt < 0 {
+		numsAt = bytes.LastIndex(numbering, []byte("</w:numbering>"))
+	}
+	numbering = insertAt(numbering, numsAt, nums)
+	d.setPackageFile(numberingPart, numbering)
+	return ids, nil
+}
+
+// importNamespaces declares the namespaces of the root element of the source document which are not declared
+// by the root element of this document, so that the imported content stays valid.
+func (d *Document) importNamespaces(source *Document) error {
+	sourceTag := documentOpenTagRegex.Find(source.GetFile(DocumentXml))
+	documentXml := d.GetFile(DocumentXml)
+	loc := documentOpenTagRegex.FindIndex(documentXml)
+	if sourceTag == nil || loc == nil {
+		return nil
+	}
+	tag := documentXml[loc[0]:loc[1]]
+
+	declared := make(map[string]bool)
+	for _, match := range namespaceAttrRegex.FindAllSubmatch(tag, -1) {
+		declared[string(match[1])] = true
+	}
+	var missing []byte
+	for _, match := range namespaceAttrRegex.FindAllSubmatch(sourceTag, -1) {
+		if !declared[string(match[1])] {
+			missing = append(missing, match[0]...)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	end := len(tag) - 1
+	newTag := append(append(append([]byte{}, tag[:end]...), missing...), tag[end:]...)
+	return d.SetFile(DocumentXml, append(append(append([]byte{}, documentXml[:loc[0]]...), newTag...), documentXml[loc[1]:]...))
+}
+
+// numberingPart returns the name of the numbering part, which might not exist yet.
+func (d *Document) numberingPart() string {
+	if targets := d.relationshipTargets(DocumentXml, NumberingRelationshipType); len(targets) > 0 {
+		return targets[0]
+	}
+	return "word/numbering.xml"
+}
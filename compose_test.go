@@ -0,0 +1,101 @@
+package docx
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestCompose(t *testing.T) {
+	template := readFile(t, "./test/template.docx")
+	layout, err := OpenBytes(rewriteArchive(t, template, func(name string, data []byte) []byte {
+		if name == DocumentXml {
+			return []byte(strings.Replace(string(data), "<w:body>", "<w:body><w:p><w:r><w:t>Cover page</w:t></w:r></w:p><w:p><w:r><w:t>{{content}}</w:t></w:r></w:p>", 1))
+		}
+		if name == StylesXml {
+			return []byte(strings.Replace(string(data), "</w:styles>", `<w:style w:type="paragraph" w:styleId="Shared"><w:name w:val="Shared"/><w:rPr><w:b/></w:rPr></w:style></w:styles>`, 1))
+		}
+		return data
+	}, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer layout.Close()
+
+	content, err := OpenBytes(rewriteArchive(t, template, func(name string, data []byte) []byte {
+		switch name {
+		case DocumentXml:
+			return []byte(strings.Replace(string(data), "<w:body>", `<w:body>`+
+				`<w:p><w:pPr><w:pStyle w:val="Clause"/></w:pPr><w:r><w:t>Content paragraph</w:t></w:r></w:p>`+
+				`<w:p><w:pPr><w:pStyle w:val="Shared"/></w:pPr><w:hyperlink r:id="rId99"><w:r><w:t>Link</w:t></w:r></w:hyperlink></w:p>`, 1))
+		case StylesXml:
+			return []byte(strings.Replace(string(data), "</w:styles>", `<w:style w:type="paragraph" w:styleId="Clause"><w:name w:val="Clause"/><w:basedOn w:val="ClauseBase"/></w:style>`+
+				`<w:style w:type="paragraph" w:styleId="ClauseBase"><w:name w:val="Clause Base"/></w:style>`+
+				`<w:style w:type="paragraph" w:styleId="Shared"><w:name w:val="Shared"/><w:rPr><w:i/></w:rPr></w:style></w:styles>`, 1))
+		case DocumentRelsXml:
+			return []byte(strings.Replace(string(data), "</Relationships>", `<Relationship Id="rId99" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink" Target="https://example.com" TargetMode="External"/></Relationships>`, 1))
+		}
+		return data
+	}, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer content.Close()
+
+	doc, err := Compose(layout, content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+
+	documentXml := string(doc.GetFile(DocumentXml))
+	if strings.Contains(documentXml, "{{content}}") || !strings.Contains(documentXml, "Cover page") || !strings.Contains(documentXml, "Content paragraph") {
+		t.Errorf("the content was not inserted into the layout")
+	}
+	if strings.Count(documentXml, "<w:sectPr") != 1 {
+		t.Errorf("expected only the section of the layout, got %d sections", strings.Count(documentXml, "<w:sectPr"))
+	}
+	if strings.Contains(string(layout.GetFile(DocumentXml)), "Content paragraph") {
+		t.Errorf("the layout was modified")
+	}
+
+	// the image of the content is copied next to the image of the layout
+	images := doc.relationshipTargets(DocumentXml, ImageRelationshipType)
+	if len(images) != 2 || images[1] != "word/media/image2.jpg" {
+		t.Fatalf("expected a copy of the image, got %v", images)
+	}
+	if !bytes.Equal(doc.GetFile(images[1]), content.GetFile("word/media/image1.jpg")) {
+		t.Errorf("the image was not copied")
+	}
+	rels, err := doc.packageRelationships(DocumentXml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, rel := range rels.Relationships {
+		if rel.Target == "https://example.com" && !strings.Contains(documentXml, `<w:hyperlink r:id="`+rel.ID+`">`) {
+			t.Errorf("the hyperlink does not use the relationship %s", rel.ID)
+		}
+	}
+
+	styles, _ := doc.loadPackageFile(StylesXml)
+	for _, expected := range []string{`w:styleId="Clause"`, `w:styleId="ClauseBase"`, `<w:style w:type="paragraph" w:styleId="Shared"><w:name w:val="Shared"/><w:rPr><w:b/>`} {
+		if !strings.Contains(string(styles), expected) {
+			t.Errorf("expected %s in the styles", expected)
+		}
+	}
+	if strings.Count(string(styles), `w:styleId="Shared"`) != 1 {
+		t.Errorf("the style of the layout must be kept")
+	}
+
+	var buf bytes.Buffer
+	if err := doc.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenBytes(buf.Bytes()); err != nil {
+		t.Errorf("invalid output: %s", err)
+	}
+
+	if _, err := Compose(content, layout); err == nil {
+		t.Errorf("expected an error for a layout without %s", ContentPlaceholder)
+	}
+}
@@ -0,0 +1,454 @@
+package docx
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// CustomXMLRelationshipType is the relationship type from the main document to a custom XML part.
+	CustomXMLRelationshipType = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/customXml"
+	// CustomXMLPropsRelationshipType is the relationship type from a custom XML part to its properties part.
+	CustomXMLPropsRelationshipType = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/customXmlProps"
+	// CustomXMLPropsContentType is the content type of custom XML properties parts (itemProps*.xml).
+	CustomXMLPropsContentType = "application/vnd.openxmlformats-officedocument.customXmlProperties+xml"
+)
+
+var (
+	// CustomXMLPathRegex matches all custom XML data parts inside the docx-archive.
+	CustomXMLPathRegex = regexp.MustCompile(`^customXml/item([0-9]+)\.xml$`)
+	// itemIDRegex extracts the store item id from a custom XML properties part.
+	itemIDRegex = regexp.MustCompile(`ds:itemID="([^"]*)"`)
+	// dataBindingRegex matches the data binding of a structured document tag.
+	dataBindingRegex = regexp.MustCompile(`<w:dataBinding\b[^>]*/>`)
+	// textRunRegex matches a complete <w:t> element including its text, or an empty <w:t/>.
+	textRunRegex = regexp.MustCompile(`(?s)<w:t(?:\s[^>]*)?/>|<w:t(?:\s[^>]*)?>.*?</w:t>`)
+	// paragraphTagRegex matches the open tag of a paragraph or an empty <w:p/>.
+	paragraphTagRegex = regexp.MustCompile(`<w:p(?:\s[^>]*)?/?>`)
+	// xpathStepRegex parses a single step of the supported XPath subset, e.g. ns0:item[2].
+	xpathStepRegex = regexp.MustCompile(`^(?:[^:\[\]]+:)?([^:\[\]]+)(?:\[([0-9]+)\])?$`)
+)
+
+// CustomXMLPart is a custom XML data part (customXml/item*.xml) which carries structured data next to the
+// human-readable content. Structured document tags (content controls) can be bound to values of the part.
+type CustomXMLPart struct {
+	Name string // path of the part inside the archive, e.g. customXml/item1.xml
+	ID   string // the store item id, e.g. {6C3C8BC8-F283-45AE-878A-BAB7291924A1}
+	Data []byte
+}
+
+// CustomXML provides access to the custom XML parts of a document. Use Document.CustomXML() to obtain it.
+type CustomXML struct {
+	document *Document
+}
+
+// CustomXML returns the custom XML parts of the document.
+func (d *Document) CustomXML() *CustomXML {
+	return &CustomXML{document: d}
+}
+
+// Parts returns all custom XML parts, ordered by their name.
+func (c *CustomXML) Parts() []CustomXMLPart {
+	var names []string
+	for _, file := range c.document.zipFile.File {
+		if CustomXMLPathRegex.MatchString(file.Name) && !c.document.removedFiles[file.Name] {
+			names = append(names, file.Name)
+		}
+	}
+	for _, name := range c.document.addedPackageFiles() {
+		if CustomXMLPathRegex.MatchString(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return customXMLIndex(names[i]) < customXMLIndex(names[j])
+	})
+
+	var parts []CustomXMLPart
+	for _, name := range names {
+		data, _ := c.document.loadPackageFile(name)
+		parts = append(parts, CustomXMLPart{
+			Name: name,
+			ID:   c.itemID(name),
+			Data: data,
+		})
+	}
+	return parts
+}
+
+// Get returns the custom XML part with the given store item id.
+// The comparison is case-insensitive and the curly braces are optional.
+func (c *CustomXML) Get(id string) (CustomXMLPart, bool) {
+	for _, part := range c.Parts() {
+		if sameItemID(part.ID, id) {
+			return part, true
+		}
+	}
+	return CustomXMLPart{}, false
+}
+
+// Add adds a new custom XML part with the given data and a newly generated store item id.
+// The properties part, the relationships and the content types are created as well.
+func (c *CustomXML) Add(data []byte) (CustomXMLPart, error) {
+	if err := validateXml(data); err != nil {
+		return CustomXMLPart{}, err
+	}
+	if _, exists := c.document.packageFiles[DocumentRelsXml]; !exists {
+		return CustomXMLPart{}, fmt.Errorf("invalid docx archive, %s is missing", DocumentRelsXml)
+	}
+
+	index := 1
+	for _, part := range c.Parts() {
+		if i := customXMLIndex(part.Name); i >= index {
+			index = i + 1
+		}
+	}
+	id, err := newItemID()
+	if err != nil {
+		return CustomXMLPart{}, err
+	}
+
+	name := fmt.Sprintf("customXml/item%d.xml", index)
+	propsName := fmt.Sprintf("customXml/itemProps%d.xml", index)
+
+	props := []byte(xmlDeclaration +
+		`<ds:datastoreItem ds:itemID="` + id + `" xmlns:ds="http://schemas.openxmlformats.org/officeDocument/2006/customXml"><ds:schemaRefs/></ds:datastoreItem>`)
+
+	if err := c.document.addPart(name, data, "application/xml"); err != nil {
+		return CustomXMLPart{}, err
+	}
+	if err := c.document.addPart(propsName, props, CustomXMLPropsContentType); err != nil {
+		return CustomXMLPart{}, err
+	}
+	if _, err := c.document.addRelationship(name, CustomXMLPropsRelationshipType, propsName); err != nil {
+		return CustomXMLPart{}, err
+	}
+	if _, err := c.document.addRelationship(DocumentXml, CustomXMLRelationshipType, name); err != nil {
+		return CustomXMLPart{}, err
+	}
+
+	return CustomXMLPart{Name: name, ID: id, Data: data}, nil
+}
+
+// Replace replaces the data of the custom XML part with the given store item id.
+// Bound content controls are not updated automatically, see Document.UpdateDataBindings.
+func (c *CustomXML) Replace(id string, data []byte) error {
+	if err := validateXml(data); err != nil {
+		return err
+	}
+	part, exists := c.Get(id)
+	if !exists {
+		return fmt.Errorf("custom XML part %s not found", id)
+	}
+	c.document.setPackageFile(part.Name, data)
+	return nil
+}
+
+// itemID returns the store item id of the given custom XML part, as defined in its properties part.
+func (c *CustomXML) itemID(name string) string {
+	match := CustomXMLPathRegex.FindStringSubmatch(name)
+	if match == nil {
+		return ""
+	}
+	props, exists := c.document.loadPackageFile("customXml/itemProps" + match[1] + ".xml")
+	if !exists {
+		return ""
+	}
+	if id := itemIDRegex.FindSubmatch(props); id != nil {
+		return string(id[1])
+	}
+	return ""
+}
+
+// DataBinding is the binding of a structured document tag (content control) to a value of a custom XML part.
+type DataBinding struct {
+	FileName    string // the part which contains the content control
+	XPath       string // the XPath of the bound value inside the custom XML part
+	StoreItemID string // the store item id of the custom XML part
+}
+
+// DataBindings returns the data bindings of all content controls in the document body, headers and footers.
+func (d *Document) DataBindings() []DataBinding {
+	var bindings []DataBinding
+	for _, fileName := range d.xmlParts() {
+		for _, tag := range dataBindingRegex.FindAll(d.GetFile(fileName), -1) {
+			binding := DataBinding{FileName: fileName}
+			binding.XPath, _ = getTagAttr(tag, "w:xpath")
+			binding.StoreItemID, _ = getTagAttr(tag, "w:storeItemID")
+			bindings = append(bindings, binding)
+		}
+	}
+	return bindings
+}
+
+// UpdateDataBindings writes the current values of the custom XML parts into all bound content controls.
+// Word does this itself when the document is opened, but other consumers (e.g. converters or indexers) only see
+// the cached content. Only simple XPath expressions consisting of element names with optional positions
+// (e.g. /ns0:invoice[1]/ns0:customer[1]/ns0:name[1]) and an optional trailing attribute are supported.
+// Bindings which cannot be resolved are left unchanged.
+func (d *Document) UpdateDataBindings() error {
+	customXML := d.CustomXML()
+	trees := make(map[string]*xmlNode)
+
+	for _, fileName := range d.xmlParts() {
+		data := d.GetFile(fileName)
+		sdts := findStructuredDocumentTags(data)
+
+		// replace from the back, so the earlier positions stay valid
+		for i := len(sdts) - 1; i >= 0; i-- {
+			sdt := sdts[i]
+			tag := dataBindingRegex.Find(data[sdt.Start:sdt.End])
+			if tag == nil {
+				continue
+			}
+			xpath, _ := getTagAttr(tag, "w:xpath")
+			storeItemID, _ := getTagAttr(tag, "w:storeItemID")
+
+			tree, exists := trees[storeItemID]
+			if !exists {
+				part, found := customXML.Get(storeItemID)
+				if !found {
+					continue
+				}
+				var err error
+				if tree, err = parseXmlTree(part.Data); err != nil {
+					return fmt.Errorf("unable to parse custom XML part %s: %w", part.Name, err)
+				}
+				trees[storeItemID] = tree
+			}
+
+			value, found := tree.evaluate(xpath)
+			if !found {
+				continue
+			}
+			content := setStructuredDocumentTagText(data[sdt.Start:sdt.End], value)
+			data = append(append(append([]byte{}, data[:sdt.Start]...), content...), data[sdt.End:]...)
+		}
+		if err := d.SetFile(fileName, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// findStructuredDocumentTags returns the positions of all innermost <w:sdt> elements, i.e. those which
+// do not contain other structured document tags.
+func findStructuredDocumentTags(data []byte) []Position {
+	type openTag struct {
+		start    int64
+		hasChild bool
+	}
+	var positions []Position
+	var stack []openTag
+	for offset := 0; offset < len(data); {
+		open := bytes.Index(data[offset:], []byte("<w:sdt>"))
+		end := bytes.Index(data[offset:], []byte("</w:sdt>"))
+		if end < 0 {
+			break
+		}
+		if open >= 0 && open < end {
+			if len(stack) > 0 {
+				stack[len(stack)-1].hasChild = true
+			}
+			stack = append(stack, openTag{start: int64(offset + open)})
+			offset += open + len("<w:sdt>")
+			continue
+		}
+		offset += end + len("</w:sdt>")
+		if len(stack) > 0 {
+			tag := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if !tag.hasChild {
+				positions = append(positions, Position{Start: tag.start, End: int64(offset)})
+			}
+		}
+	}
+	return positions
+}
+
+// setStructuredDocumentTagText sets the text of the first <w:t> inside the <w:sdtContent> to the value and clears
+// all remaining texts. A content control without any <w:t> gets a run with the value, inside its first paragraph
+// if it contains paragraphs. The placeholder flag (<w:showingPlcHdr/>) is removed since the control now has a value.
+func setStructuredDocumentTagText(sdt []byte, value string) []byte {
+	contentStart := bytes.Index(sdt, []byte("<w:sdtContent>"))
+	if contentStart < 0 {
+		return sdt
+	}
+	properties := bytes.Replace(sdt[:contentStart], []byte("<w:showingPlcHdr/>"), nil, 1)
+	content := sdt[contentStart:]
+
+	first := true
+	content = textRunRegex.ReplaceAllFunc(content, func(text []byte) []byte {
+		selfClosing := bytes.HasSuffix(text, []byte("/>"))
+		if !first {
+			if selfClosing {
+				return text
+			}
+			openEnd := bytes.IndexByte(text, '>') + 1
+			return append(append([]byte{}, text[:openEnd]...), []byte("</w:t>")...)
+		}
+		first = false
+		openTag := text[:bytes.IndexByte(text, '>')+1]
+		if selfClosing {
+			openTag = append(append([]byte{}, text[:len(text)-2]...), '>')
+		}
+		openTag = setTagAttr(openTag, "xml:space", "preserve")
+		return append(append(openTag, []byte(xmlEscape(value))...), []byte("</w:t>")...)
+	})
+	if first {
+		content = insertStructuredDocumentTagRun(content, []byte(`<w:r><w:t xml:space="preserve">`+xmlEscape(value)+`</w:t></w:r>`))
+	}
+	return append(append([]byte{}, properties...), content...)
+}
+
+// insertStructuredDocumentTagRun inserts the run into the <w:sdtContent> starting the content: at the end of its
+// first paragraph or, if it contains no paragraph, at its beginning.
+func insertStructuredDocumentTagRun(content, run []byte) []byte {
+	paragraph := paragraphTagRegex.FindIndex(content)
+	if paragraph == nil {
+		return insertAt(content, len("<w:sdtContent>"), run)
+	}
+	if bytes.HasSuffix(content[paragraph[0]:paragraph[1]], []byte("/>")) {
+		tag := content[paragraph[0] : paragraph[1]-2]
+		expanded := append(append(append(append([]byte{}, tag...), '>'), run...), []byte("</w:p>")...)
+		return append(append(append([]byte{}, content[:paragraph[0]]...), expanded...), content[paragraph[1]:]...)
+	}
+	end := bytes.Index(content[paragraph[1]:], []byte("</w:p>"))
+	if end < 0 {
+		return content
+	}
+	return insertAt(content, paragraph[1]+end, run)
+}
+
+// xmlNode is a minimal element tree used to evaluate data binding XPaths.
+type xmlNode struct {
+	Name     string
+	Attrs    map[string]string
+	Text     string
+	Children []*xmlNode
+}
+
+// parseXmlTree parses the XML into a tree. The returned node is a virtual document node
+// whose only child is the root element.
+func parseXmlTree(data []byte) (*xmlNode, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	document := &xmlNode{}
+	stack := []*xmlNode{document}
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch elem := tok.(type) {
+		case xml.StartElement:
+			node := &xmlNode{Name: elem.Name.Local, Attrs: make(map[string]string)}
+			for _, attr := range elem.Attr {
+				node.Attrs[attr.Name.Local] = attr.Value
+			}
+			parent := stack[len(stack)-1]
+			parent.Children = append(parent.Children, node)
+			stack = append(stack, node)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		case xml.CharData:
+			stack[len(stack)-1].Text += string(elem)
+		}
+	}
+	return document, nil
+}
+
+// evaluate resolves the XPath relative to the node. Prefixes are ignored, elements are matched by local name.
+func (n *xmlNode) evaluate(xpath string) (string, bool) {
+	current := n
+	steps := strings.Split(strings.TrimPrefix(xpath, "/"), "/")
+	for i, step := range steps {
+		if step == "text()" && i == len(steps)-1 {
+			break
+		}
+		if strings.HasPrefix(step, "@") && i == len(steps)-1 {
+			name := strings.TrimPrefix(step, "@")
+			if colon := strings.Index(name, ":"); colon >= 0 {
+				name = name[colon+1:]
+			}
+			value, exists := current.Attrs[name]
+			return value, exists
+		}
+
+		match := xpathStepRegex.FindStringSubmatch(step)
+		if match == nil {
+			return "", false
+		}
+		position := 1
+		if match[2] != "" {
+			position, _ = strconv.Atoi(match[2])
+		}
+
+		var next *xmlNode
+		count := 0
+		for _, child := range current.Children {
+			if child.Name == match[1] {
+				count++
+				if count == position {
+					next = child
+					break
+				}
+			}
+		}
+		if next == nil {
+			return "", false
+		}
+		current = next
+	}
+	return current.Text, true
+}
+
+// customXMLIndex returns the number of the custom XML part, e.g. 2 for customXml/item2.xml.
+func customXMLIndex(name string) int {
+	match := CustomXMLPathRegex.FindStringSubmatch(name)
+	if match == nil {
+		return 0
+	}
+	index, _ := strconv.Atoi(match[1])
+	return index
+}
+
+// sameItemID compares two store item ids, ignoring case and curly braces.
+func sameItemID(a, b string) bool {
+	return strings.EqualFold(strings.Trim(a, "{}"), strings.Trim(b, "{}"))
+}
+
+// newItemID generates a new random store item id in registry format, e.g. {6C3C8BC8-F283-45AE-878A-BAB7291924A1}.
+func newItemID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("unable to generate item id: %s", err)
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("{%X-%X-%X-%X-%X}", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+// validateXml ensures that the data is well-formed XML.
+func validateXml(data []byte) error {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		_, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("invalid XML: %s", err)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package docx
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// makeBoundDocument adds a content control to the given archive which is bound to the given store item.
+func makeBoundDocument(t testing.TB, src []byte, storeItemID string) *Document {
+	sdt := `<w:p><w:sdt><w:sdtPr><w:showingPlcHdr/><w:dataBinding w:xpath="/ns0:invoice[1]/ns0:customer[1]" w:storeItemID="` + storeItemID + `"/></w:sdtPr>` +
+		`<w:sdtContent><w:r><w:t>Click here</w:t></w:r><w:r><w:t xml:space="preserve"> to enter text.</w:t></w:r></w:sdtContent></w:sdt></w:p>`
+	archive := rewriteArchive(t, src, func(name string, data []byte) []byte {
+		if name == DocumentXml {
+			return bytes.Replace(data, []byte("<w:body>"), []byte("<w:body>"+sdt), 1)
+		}
+		return data
+	}, nil)
+
+	doc, err := OpenBytes(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func TestCustomXML_AddAndReplace(t *testing.T) {
+	doc, err := Open("./test/template.docx")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	part, err := doc.CustomXML().Add([]byte(`<invoice><customer>ACME</customer></invoice>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if part.Name != "customXml/item1.xml" || part.ID == "" {
+		t.Errorf("unexpected part %s %s", part.Name, part.ID)
+	}
+	if _, err := doc.CustomXML().Add([]byte(`<invalid>`)); err == nil {
+		t.Error("expected an error when adding invalid XML")
+	}
+	if err := doc.CustomXML().Replace(strings.ToLower(strings.Trim(part.ID, "{}")), []byte(`<invoice><customer>Globex</customer></invoice>`)); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := doc.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out, err := OpenBytes(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := out.CustomXML().Parts()
+	if len(parts) != 1 {
+		t.Fatalf("expected 1 custom XML part, got %d", len(parts))
+	}
+	if parts[0].ID != part.ID || !bytes.Contains(parts[0].Data, []byte("Globex")) {
+		t.Errorf("unexpected part after round trip: %s %s", parts[0].ID, parts[0].Data)
+	}
+	if !bytes.Contains(out.packageFiles[DocumentRelsXml], []byte(`Target="../customXml/item1.xml"`)) {
+		t.Error("custom XML relationship is missing")
+	}
+	if !bytes.Contains(out.packageFiles[ContentTypesXml], []byte(`PartName="/customXml/itemProps1.xml"`)) {
+		t.Error("custom XML properties content type is missing")
+	}
+}
+
+func TestDocument_UpdateDataBindings(t *testing.T) {
+	doc, err := Open("./test/template.docx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part, err := doc.CustomXML().Add([]byte(`<ns0:invoice xmlns:ns0="urn:invoice"><ns0:customer>ACME &amp; Sons</ns0:customer></ns0:invoice>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := doc.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	bound := makeBoundDocument(t, buf.Bytes(), part.ID)
+
+	if bindings := bound.DataBindings(); len(bindings) != 1 || bindings[0].StoreItemID != part.ID {
+		t.Fatalf("unexpected data bindings %v", bindings)
+	}
+	if err := bound.UpdateDataBindings(); err != nil {
+		t.Fatal(err)
+	}
+
+	documentXml := string(bound.GetFile(DocumentXml))
+	if !strings.Contains(documentXml, `<w:t xml:space="preserve">ACME &amp; Sons</w:t></w:r><w:r><w:t xml:space="preserve"></w:t>`) {
+		t.Error("bound content control was not updated")
+	}
+	if strings.Contains(documentXml, "<w:showingPlcHdr/>") {
+		t.Error("placeholder flag was not removed")
+	}
+}
+
+func TestSetStructuredDocumentTagText(t *testing.T) {
+	properties := `<w:sdtPr><w:showingPlcHdr/></w:sdtPr>`
+	for _, test := range []struct {
+		content  string
+		expected string
+	}{
+		{`<w:r><w:t/></w:r><w:r><w:t xml:space="preserve">old</w:t></w:r>`,
+			`<w:r><w:t xml:space="preserve">A &amp; B</w:t></w:r><w:r><w:t xml:space="preserve"></w:t></w:r>`},
+		{`<w:r><w:t>old</w:t></w:r><w:r><w:t xml:space="preserve"/></w:r>`,
+			`<w:r><w:t xml:space="preserve">A &amp; B</w:t></w:r><w:r><w:t xml:space="preserve"/></w:r>`},
+		{`<w:p><w:pPr><w:jc w:val="center"/></w:pPr></w:p>`,
+			`<w:p><w:pPr><w:jc w:val="center"/></w:pPr><w:r><w:t xml:space="preserve">A &amp; B</w:t></w:r></w:p>`},
+		{`<w:p w:rsidR="00AB"/>`,
+			`<w:p w:rsidR="00AB"><w:r><w:t xml:space="preserve">A &amp; B</w:t></w:r></w:p>`},
+		{``, `<w:r><w:t xml:space="preserve">A &amp; B</w:t></w:r>`},
+	} {
+		sdt := `<w:sdt>` + properties + `<w:sdtContent>` + test.content + `</w:sdtContent></w:sdt>`
+		expected := `<w:sdt><w:sdtPr></w:sdtPr><w:sdtContent>` + test.expected + `</w:sdtContent></w:sdt>`
+		if result := string(setStructuredDocumentTagText([]byte(sdt), "A & B")); result != expected {
+			t.Errorf("expected %s, got %s", expected, result)
+		}
+	}
+}
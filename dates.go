@@ -0,0 +1,153 @@
+package docx
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// dates.go implements the date helpers {{addDays}}, {{addMonths}}, {{endOfMonth}}, {{weekday}} and {{between}},
+// so due dates and validity periods can be computed in the template. The helpers accept time.Time values and
+// strings, which are parsed with the layouts set with SetDateLayouts. Dates are returned as time.Time, so they can
+// be passed to other helpers or formatted, e.g. {{(addDays .date 30).Format "02.01.2006"}}.
+
+// defaultDateLayouts are the layouts of date strings if none are set with SetDateLayouts.
+var defaultDateLayouts = []string{"2006-01-02", time.RFC3339, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "02.01.2006"}
+
+// weekdayNames lists the names of the weekdays by language, starting with Sunday like time.Weekday.
+var weekdayNames = map[string][7]string{
+	"en": {"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"},
+	"de": {"Sonntag", "Montag", "Dienstag", "Mittwoch", "Donnerstag", "Freitag", "Samstag"},
+	"fr": {"dimanche", "lundi", "mardi", "mercredi", "jeudi", "vendredi", "samedi"},
+}
+
+// SetDateLayouts sets the layouts of date strings passed to the date helpers, e.g. "02/01/2006". The layouts are
+// tried in the given order, see time.Parse. By default, ISO 8601 dates and timestamps and 02.01.2006 are accepted.
+func (d *Document) SetDateLayouts(layouts ...string) {
+	d.templateReplacer.dateLayouts = layouts
+}
+
+// parseDate returns the time.Time or the date string parsed with the date layouts.
+func (tr *TemplateReplacer) parseDate(helper string, value interface{}) (time.Time, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, nil
+	case *time.Time:
+		if v != nil {
+			return *v, nil
+		}
+	case string:
+		layouts := tr.dateLayouts
+		if len(layouts) == 0 {
+			layouts = defaultDateLayouts
+		}
+		for _, layout := range layouts {
+			if date, err := time.Parse(layout, strings.TrimSpace(v)); err == nil {
+				return date, nil
+			}
+		}
+		return time.Time{}, fmt.Errorf("%s: %q does not match any date layout (%s)", helper, v, strings.Join(layouts, ", "))
+	}
+	return time.Time{}, fmt.Errorf("%s: %v is not a date", helper, value)
+}
+
+// addDaysHelper implements {{addDays DATE DAYS}}, e.g. {{addDays .invoiceDate 30}}. Negative days go back.
+func (tr *TemplateReplacer) addDaysHelper(date interface{}, days int) (time.Time, error) {
+	t, err := tr.parseDate("addDays", date)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.AddDate(0, 0, days), nil
+}
+
+// addMonthsHelper implements {{addMonths DATE MONTHS}}, e.g. {{addMonths .start 12}}. Unlike time.AddDate, the
+// day is limited to the end of the month: one month after January 31 is the end of February.
+func (tr *TemplateReplacer) addMonthsHelper(date interface{}, months int) (time.Time, error) {
+	t, err := tr.parseDate("addMonths", date)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return addMonths(t, months), nil
+}
+
+// addMonths adds the months to the date, the day is limited to the end of the month.
+func addMonths(t time.Time, months int) time.Time {
+	first := time.Date(t.Year(), t.Month(), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location()).AddDate(0, months, 0)
+	return first.AddDate(0, 0, min(t.Day(), daysIn(first))-1)
+}
+
+// daysIn returns the number of days of the month of the date.
+func daysIn(t time.Time) int {
+	return time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, t.Location()).Day()
+}
+
+// endOfMonthHelper implements {{endOfMonth DATE}}, the last day of the month of the date.
+func (tr *TemplateReplacer) endOfMonthHelper(date interface{}) (time.Time, error) {
+	t, err := tr.parseDate("endOfMonth", date)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Date(t.Year(), t.Month(), daysIn(t), 0, 0, 0, 0, t.Location()), nil
+}
+
+// weekdayHelper implements {{weekday DATE LANGUAGE}}, e.g. {{weekday .date}} writes Monday and
+// {{weekday .date "de"}} Montag. Without language, the language of the locale is used.
+func (tr *TemplateReplacer) weekdayHelper(date interface{}, tag ...string) (string, error) {
+	if len(tag) > 1 {
+		return "", fmt.Errorf("weekday: expected at most one language, got %d", len(tag))
+	}
+	t, err := tr.parseDate("weekday", date)
+	if err != nil {
+		return "", err
+	}
+	language := tr.locale
+	if len(tag) == 1 {
+		language = tag[0]
+	}
+	names, exists := weekdayNames[languageCode(language)]
+	if !exists {
+		return "", fmt.Errorf("weekday: weekdays are not supported in %q", language)
+	}
+	return names[t.Weekday()], nil
+}
+
+// betweenHelper implements {{between FROM TO UNIT}}, the number of whole days, weeks, months or years from the
+// first to the second date, e.g. {{between .start .end "months"}}. The unit is days by default. The result is
+// negative if the second date is before the first one.
+func (tr *TemplateReplacer) betweenHelper(from, to interface{}, unit ...string) (int, error) {
+	if len(unit) > 1 {
+		return 0, fmt.Errorf("between: expected at most one unit, got %d", len(unit))
+	}
+	start, err := tr.parseDate("between", from)
+	if err != nil {
+		return 0, err
+	}
+	end, err := tr.parseDate("between", to)
+	if err != nil {
+		return 0, err
+	}
+	if end.Before(start) {
+		n, err := tr.betweenHelper(to, from, unit...)
+		return -n, err
+	}
+
+	// whole days in the calendar, regardless of daylight saving time
+	days := int(time.Date(end.Year(), end.Month(), end.Day(), 0, 0, 0, 0, time.UTC).Sub(
+		time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)).Hours() / 24)
+	switch {
+	case len(unit) == 0 || unit[0] == "days":
+		return days, nil
+	case unit[0] == "weeks":
+		return days / 7, nil
+	case unit[0] == "months" || unit[0] == "years":
+		months := (end.Year()-start.Year())*12 + int(end.Month()-start.Month())
+		if addMonths(start, months).After(end) {
+			months--
+		}
+		if unit[0] == "years" {
+			return months / 12, nil
+		}
+		return months, nil
+	}
+	return 0, fmt.Errorf("between: unknown unit %q, expected days, weeks, months or years", unit[0])
+}
@@ -0,0 +1,98 @@
+package docx
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDateHelpers(t *testing.T) {
+	tr := NewTemplateReplacer(nil)
+	date := func(value string) time.Time {
+		t.Helper()
+		parsed, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parsed
+	}
+
+	for _, test := range []struct {
+		start    interface{}
+		months   int
+		expected string
+	}{
+		{"2024-01-31", 1, "2024-02-29"},
+		{"2023-01-31", 1, "2023-02-28"},
+		{"2024-03-31", -1, "2024-02-29"},
+		{"2024-08-15", 6, "2025-02-15"},
+		{date("2024-12-31"), 12, "2025-12-31"},
+	} {
+		if result, err := tr.addMonthsHelper(test.start, test.months); err != nil || result.Format("2006-01-02") != test.expected {
+			t.Errorf("expected %s for %v + %d months, got %v, %v", test.expected, test.start, test.months, result, err)
+		}
+	}
+	if result, _ := tr.addDaysHelper("28.02.2024", 2); result.Format("2006-01-02") != "2024-03-01" {
+		t.Errorf("unexpected result %v", result)
+	}
+	if result, _ := tr.endOfMonthHelper("2024-02-10T12:00:00Z"); result.Format("2006-01-02") != "2024-02-29" {
+		t.Errorf("unexpected end of month %v", result)
+	}
+	if result, _ := tr.weekdayHelper("2024-07-01"); result != "Monday" {
+		t.Errorf("unexpected weekday %s", result)
+	}
+	if result, _ := tr.weekdayHelper("2024-07-01", "fr-CH"); result != "lundi" {
+		t.Errorf("unexpected weekday %s", result)
+	}
+
+	for _, test := range []struct {
+		from, to string
+		unit     []string
+		expected int
+	}{
+		{"2024-01-01", "2024-03-01", nil, 60},
+		{"2024-03-01", "2024-01-01", nil, -60},
+		{"2024-01-01", "2024-03-01", []string{"weeks"}, 8},
+		{"2024-01-31", "2024-02-29", []string{"months"}, 1},
+		{"2024-01-15", "2024-02-14", []string{"months"}, 0},
+		{"2020-02-29", "2024-02-28", []string{"years"}, 3},
+		{"2020-02-29", "2024-02-29", []string{"years"}, 4},
+	} {
+		if result, err := tr.betweenHelper(test.from, test.to, test.unit...); err != nil || result != test.expected {
+			t.Errorf("expected %d between %s and %s %v, got %d, %v", test.expected, test.from, test.to, test.unit, result, err)
+		}
+	}
+
+	if _, err := tr.addDaysHelper("07/01/2024", 1); err == nil || !strings.Contains(err.Error(), "does not match any date layout") {
+		t.Errorf("expected an error for an unknown layout, got %v", err)
+	}
+	if _, err := tr.betweenHelper("2024-01-01", "2024-02-01", "decades"); err == nil {
+		t.Errorf("expected an error for an unknown unit")
+	}
+	if _, err := tr.weekdayHelper(42); err == nil {
+		t.Errorf("expected an error for a value which is not a date")
+	}
+}
+
+func TestDocument_DateHelpers(t *testing.T) {
+	doc, err := NewDocument().
+		Paragraph(`Due on {{weekday (addDays .invoiceDate 30)}}, {{(addDays .invoiceDate 30).Format "January 2, 2006"}}`).
+		Paragraph(`Valid for {{between .start .end "months"}} months until {{(endOfMonth .end).Format "02.01.2006"}}`).
+		Build(WithDateLayouts("01/02/2006"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+	data := map[string]interface{}{"invoiceDate": "06/03/2024", "start": "01/15/2024", "end": "07/15/2024"}
+	if err := doc.ExecuteTemplate(data); err != nil {
+		t.Fatal(err)
+	}
+	texts, err := paragraphTexts(doc.GetFile(DocumentXml))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "Due on Wednesday, July 3, 2024|Valid for 6 months until 31.07.2024"
+	if text := strings.Join(texts, "|"); text != expected {
+		t.Errorf("expected %q, got %q", expected, text)
+	}
+}
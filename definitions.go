@@ -0,0 +1,199 @@
+package docx
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// definitions.go implements {{define}} and {{template}} across the parts of the document. The definitions of all
+// parts are collected into the associated templates before any placeholder is executed, so a footer can call a
+// template defined in the body. Definitions which span multiple paragraphs, e.g.
+//
+//	{{define "signature"}}
+//	... any number of paragraphs and tables ...
+//	{{end}}
+//
+// are called by a paragraph whose only content is {{template "signature" .}}. The paragraph is replaced by the
+// executed paragraphs of the definition. Definitions inside a paragraph, e.g.
+// {{define "name"}}{{.first}} {{.last}}{{end}}, are called inline. The definitions are removed from the document.
+
+var (
+	// definitionActionRegex matches a {{define}} action, the first group is the quoted name of the template.
+	definitionActionRegex = regexp.MustCompile("^\\{\\{-?\\s*define\\s+(\"(?:[^\"\\\\]|\\\\.)*\"|`[^`]*`)")
+	// templateCallRegex matches a paragraph text consisting of a {{template}} action, the first group is the quoted
+	// name of the template.
+	templateCallRegex = regexp.MustCompile("^\\{\\{-?\\s*template\\s+(\"(?:[^\"\\\\]|\\\\.)*\"|`[^`]*`)[^{}]*\\}\\}$")
+)
+
+// collectDefinitions parses the definitions of all parts into the associated templates and removes them from the
+// parts.
+func (tr *TemplateReplacer) collectDefinitions() error {
+	for _, fileName := range tr.document.xmlParts() {
+		if !bytes.Contains(tr.document.GetFile(fileName), []byte("define")) {
+			continue
+		}
+		tr.part = fileName
+		if err := tr.collectParagraphDefinitions(fileName); err != nil {
+			return err
+		}
+		if err := tr.collectInlineDefinitions(fileName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// collectParagraphDefinitions collects the definitions spanning multiple paragraphs of the part.
+func (tr *TemplateReplacer) collectParagraphDefinitions(fileName string) error {
+	data := tr.document.GetFile(fileName)
+	blocks, err := parseTemplateBlocks(data)
+	if err != nil && tr.mode == ExecuteParts {
+		// the part is executed as a whole, see executeBlocks
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to parse blocks in %s: %w", fileName, err)
+	}
+
+	var out bytes.Buffer
+	pos := int64(0)
+	for _, block := range blocks {
+		if block.Actions[0].Kind != "define" {
+			continue
+		}
+		if err := tr.define(blockFragment(data, block), true); err != nil {
+			return newTemplateError(data, fileName, block.Start(), block.Actions[0].Action, err)
+		}
+		out.Write(data[pos:block.Start()])
+		pos = block.End()
+	}
+	if pos == 0 {
+		return nil
+	}
+	out.Write(data[pos:])
+
+	if err := tr.document.SetFile(fileName, out.Bytes()); err != nil {
+		return err
+	}
+	return tr.document.refreshRuns(fileName)
+}
+
+// collectInlineDefinitions collects the definitions inside the paragraphs of the part.
+func (tr *TemplateReplacer) collectInlineDefinitions(fileName string) error {
+	placeholders, err := ParseTemplatePlaceholders(tr.document.runParsers[fileName].Runs(), tr.document.GetFile(fileName), fileName)
+	if err != nil {
+		return err
+	}
+
+	removed := false
+	// Process in reverse order, so that earlier positions remain valid after removing definitions
+	for i := len(placeholders) - 1; i >= 0; i-- {
+		placeholder := placeholders[i]
+		if !definitionActionRegex.MatchString(placeholder.TemplateContent) {
+			continue
+		}
+		if err := tr.define(placeholder.TemplateContent, false); err != nil {
+			return placeholder.error(tr.document.GetFile(fileName), err)
+		}
+		if err := tr.replacePlaceholder(placeholder, ""); err != nil {
+			return fmt.Errorf("failed to remove definition: %w", err)
+		}
+		removed = true
+	}
+	if !removed {
+		return nil
+	}
+	return tr.document.refreshRuns(fileName)
+}
+
+// define parses the definition into the associated templates. The values of definitions spanning paragraphs are
+// escaped like the values of range blocks, so their content has to be executed with executeFragment.
+func (tr *TemplateReplacer) define(definition string, paragraphs bool) error {
+	definition = stripAnnotations(rawActionRegex.ReplaceAllStringFunc(definition, html.UnescapeString))
+	match := definitionActionRegex.FindStringSubmatch(definition)
+	if match == nil {
+		return fmt.Errorf("invalid definition %s", definition)
+	}
+	name, err := strconv.Unquote(match[1])
+	if err != nil {
+		return fmt.Errorf("invalid template name %s: %w", match[1], err)
+	}
+
+	// parsed with the functions of fragments, which are only added to the template of the definition
+	parsed, err := tr.tmpl.Clone()
+	if err != nil {
+		return err
+	}
+	parsed, err = parsed.Funcs(fragmentFuncs).Funcs(template.FuncMap{"docxEscape": tr.escapeValue, "docxScoped": tr.escapeScopedValue}).Parse(definition)
+	if err != nil {
+		return fmt.Errorf("failed to parse template: %w", err)
+	}
+	tree := parsed.Lookup(name).Tree
+	nilSafeFields(tree.Root)
+	if paragraphs {
+		escapeActions(tree.Root, "docxScoped", "docxScoped")
+	} else {
+		escapeActions(tree.Root, "docxMarkers", "docxMarkers")
+	}
+	// recursive definitions are stopped by the timeout like loops
+	limitLoops(tree.Root)
+	tree.Root.Nodes = append([]parse.Node{deadlineAction(tree.Root.Position())}, tree.Root.Nodes...)
+	if _, err := tr.tmpl.AddParseTree(name, tree); err != nil {
+		return err
+	}
+
+	tr.debugLog("Defined template %s", name)
+	if tr.definitions == nil {
+		tr.definitions = make(map[string]bool)
+	}
+	tr.definitions[name] = paragraphs
+	return nil
+}
+
+// definitionCalls returns the paragraphs calling definitions which span multiple paragraphs as blocks of the kind
+// template, so renderBlocks replaces them by the executed definition.
+func (tr *TemplateReplacer) definitionCalls(data []byte) ([]*templateBlock, error) {
+	paragraphs, err := findBlockParagraphs(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var calls []*templateBlock
+	for _, paragraph := range paragraphs {
+		text := strings.TrimSpace(paragraph.Text)
+		match := templateCallRegex.FindStringSubmatch(text)
+		if match == nil {
+			continue
+		}
+		if name, err := strconv.Unquote(match[1]); err != nil || !tr.definitions[name] {
+			continue
+		}
+		if paragraph.Embedded {
+			return nil, fmt.Errorf("the action %s must be the only content of its paragraph", text)
+		}
+		calls = append(calls, &templateBlock{Actions: []blockAction{{Paragraph: paragraph, Action: text, Kind: "template"}}})
+	}
+	return calls, nil
+}
+
+// nestBlock adds the block to the blocks in document order, or to the children of the block containing it.
+func nestBlock(blocks []*templateBlock, block *templateBlock) []*templateBlock {
+	for _, parent := range blocks {
+		if parent.Start() < block.Start() && block.End() <= parent.End() {
+			parent.Children = nestBlock(parent.Children, block)
+			return blocks
+		}
+	}
+	blocks = append(blocks, block)
+	sort.Slice(blocks, func(i, j int) bool {
+		return blocks[i].Start() < blocks[j].Start()
+	})
+	return blocks
+}
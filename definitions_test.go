@@ -0,0 +1,56 @@
+package docx
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTemplateReplacer_Definitions(t *testing.T) {
+	doc, err := NewDocument().
+		Paragraph(`{{define "signature"}}`).Paragraph(`Kind regards`).Paragraph(`{{.sender}}`).Paragraph(`{{end}}`).
+		Paragraph(`Dear {{template "name" .customer}},{{define "name"}}{{.first}} {{.last}}{{end}}`).
+		Paragraph(`{{range .copies}}`).Paragraph(`Copy {{.}}`).Paragraph(`{{template "signature" $}}`).Paragraph(`{{end}}`).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+	footer, err := doc.AddFooter(FooterDefault, `{{template "signature" .}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data := map[string]interface{}{"sender": "Smith & Sons", "copies": []int{1, 2},
+		"customer": map[string]interface{}{"first": "Jane", "last": "Doe"}}
+	if err := doc.ExecuteTemplate(data); err != nil {
+		t.Fatal(err)
+	}
+	texts, err := paragraphTexts(doc.GetFile(DocumentXml))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "Dear Jane Doe,|Copy 1|Kind regards|Smith & Sons|Copy 2|Kind regards|Smith & Sons"
+	if text := strings.Join(texts, "|"); text != expected {
+		t.Errorf("expected %q, got %q", expected, text)
+	}
+	texts, err = paragraphTexts(doc.GetFile(footer))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := strings.Join(texts, "|"); text != "Kind regards|Smith & Sons" {
+		t.Errorf("unexpected footer %q", text)
+	}
+}
+
+func TestTemplateReplacer_DefinitionErrors(t *testing.T) {
+	doc, err := NewDocument().Paragraph(`{{define "broken"}}`).Paragraph(`{{.name`).Paragraph(`{{end}}`).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+
+	err = doc.ExecuteTemplate(map[string]interface{}{"name": "Jane"})
+	if err == nil || !strings.Contains(err.Error(), `{{define "broken"}}`) {
+		t.Errorf("expected the definition to be located, got %v", err)
+	}
+}
@@ -0,0 +1,330 @@
+package docx
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// diagram.go implements the templating of SmartArt diagrams. The text of the nodes of a diagram is stored in its
+// data part (word/diagrams/data1.xml), Word additionally caches the rendered shapes including their text in a
+// drawing part (word/diagrams/drawing1.xml). Both are processed, so the diagram shows the rendered text whether
+// Word uses the cache or lays out the diagram again.
+
+var (
+	// DiagramDataPathRegex matches the data parts of SmartArt diagrams inside the docx-archive.
+	DiagramDataPathRegex = regexp.MustCompile(`^word/diagrams/data[0-9]*\.xml$`)
+	// DiagramDrawingPathRegex matches the parts caching the rendered shapes of SmartArt diagrams.
+	DiagramDrawingPathRegex = regexp.MustCompile(`^word/diagrams/drawing[0-9]*\.xml$`)
+	// drawingTextRegex matches a DrawingML text element including its text, e.g. <a:t>CEO</a:t>.
+	drawingTextRegex = regexp.MustCompile(`(?s)(<a:t(?:\s[^>]*)?>)(.*?)</a:t>`)
+	// drawingParagraphRegex matches a DrawingML paragraph.
+	drawingParagraphRegex = regexp.MustCompile(`(?s)<a:p(?:\s[^>]*)?(?:/>|>.*?</a:p>)`)
+	// drawingParagraphPropsRegex matches the properties of a DrawingML paragraph.
+	drawingParagraphPropsRegex = regexp.MustCompile(`(?s)<a:pPr\b(?:[^>]*/>|.*?</a:pPr>)`)
+	// drawingRunPropsRegex matches the properties of a DrawingML run.
+	drawingRunPropsRegex = regexp.MustCompile(`(?s)<a:rPr\b(?:[^>]*/>|.*?</a:rPr>)`)
+)
+
+// diagramPoint is a point of the data model of a diagram, i.e. a node or one of its presentation shapes.
+type diagramPoint struct {
+	ID      string   // the model id, e.g. {2F5D0C3A-...}
+	Type    string   // doc for the root, pres for presentation points, empty for regular nodes
+	AssocID string   // the node a presentation point belongs to
+	Text    Position // the content of the text (<dgm:t>), Start is -1 if the point has no text
+}
+
+// diagramModel is the parsed data model of a diagram.
+type diagramModel struct {
+	points   []diagramPoint
+	children map[string][]string // the ids of the child nodes by the id of their parent, ordered by position
+}
+
+// Diagrams returns the names of the data parts of all SmartArt diagrams, e.g. word/diagrams/data1.xml.
+func (d *Document) Diagrams() []string {
+	var names []string
+	for _, name := range d.partNames() {
+		if DiagramDataPathRegex.MatchString(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// diagramParts returns the data and drawing parts of all SmartArt diagrams.
+func (d *Document) diagramParts() []string {
+	var names []string
+	for _, name := range d.partNames() {
+		if DiagramDataPathRegex.MatchString(name) || DiagramDrawingPathRegex.MatchString(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// executeDiagrams executes the actions inside the node texts of all SmartArt diagrams. Each text is executed on
+// its own, so actions must not be split across runs.
+func (tr *TemplateReplacer) executeDiagrams() error {
+	for _, part := range tr.document.diagramParts() {
+		data, _ := tr.document.loadPackageFile(part)
+		if !bytes.Contains(data, []byte("{{")) {
+			continue
+		}
+		tr.part = part
+		var out bytes.Buffer
+		pos := 0
+		for _, match := range drawingTextRegex.FindAllSubmatchIndex(data, -1) {
+			text := string(data[match[4]:match[5]])
+			if len(findTemplateActions(text)) == 0 {
+				continue
+			}
+			result, err := tr.executeFragment(text, tr.data)
+			if err != nil {
+				return newTemplateError(data, part, int64(match[4]), text, err)
+			}
+			out.Write(data[pos:match[4]])
+			out.Write(result)
+			pos = match[5]
+		}
+		if pos == 0 {
+			continue
+		}
+		out.Write(data[pos:])
+		tr.document.setPackageFile(part, out.Bytes())
+	}
+	return nil
+}
+
+// SetDiagramText sets the text of a node of a SmartArt diagram, e.g. to fill an org chart or a process diagram
+// with data. The diagram is the name of its data part (see Diagrams) and the node path consists of the positions
+// of the node and its ancestors, starting with 1 and separated by slashes: "2" is the second top-level node,
+// "2/1" its first child. The formatting of the first run of the node is kept, the cached drawing of the diagram
+// is updated as well.
+func (d *Document) SetDiagramText(diagram, nodePath, value string) error {
+	if !DiagramDataPathRegex.MatchString(diagram) {
+		return fmt.Errorf("invalid diagram %s", diagram)
+	}
+	data, exists := d.loadPackageFile(diagram)
+	if !exists {
+		return fmt.Errorf("diagram %s not found", diagram)
+	}
+	model, err := parseDiagramModel(data)
+	if err != nil {
+		return fmt.Errorf("failed to parse diagram %s: %w", diagram, err)
+	}
+	node, err := model.node(nodePath)
+	if err != nil {
+		return fmt.Errorf("node %s of diagram %s: %w", nodePath, diagram, err)
+	}
+	if node.Text.Start < 0 {
+		return fmt.Errorf("node %s of diagram %s has no text", nodePath, diagram)
+	}
+	d.setPackageFile(diagram, replaceRange(data, node.Text, setDrawingText(data[node.Text.Start:node.Text.End], value)))
+
+	// the cached shapes refer to the presentation points of the node
+	var shapes []string
+	for _, point := range model.points {
+		if point.Type == "pres" && point.AssocID == node.ID {
+			shapes = append(shapes, point.ID)
+		}
+	}
+	for _, part := range d.diagramParts() {
+		if !DiagramDrawingPathRegex.MatchString(part) {
+			continue
+		}
+		drawing, _ := d.loadPackageFile(part)
+		updated, changed, err := setDiagramShapeText(drawing, shapes, value)
+		if err != nil {
+			return fmt.Errorf("failed to parse diagram drawing %s: %w", part, err)
+		}
+		if changed {
+			d.setPackageFile(part, updated)
+		}
+	}
+	return nil
+}
+
+// node returns the node at the given path, see SetDiagramText.
+func (m *diagramModel) node(nodePath string) (*diagramPoint, error) {
+	var id string
+	for _, point := range m.points {
+		if point.Type == "doc" {
+			id = point.ID
+			break
+		}
+	}
+	if id == "" {
+		return nil, fmt.Errorf("the diagram has no root")
+	}
+	if nodePath == "" {
+		return nil, fmt.Errorf("empty node path")
+	}
+	for _, step := range strings.Split(nodePath, "/") {
+		position, err := strconv.Atoi(step)
+		if err != nil || position < 1 {
+			return nil, fmt.Errorf("invalid position %q", step)
+		}
+		children := m.children[id]
+		if position > len(children) {
+			return nil, fmt.Errorf("position %d not found, the parent has %d children", position, len(children))
+		}
+		id = children[position-1]
+	}
+	for i := range m.points {
+		if m.points[i].ID == id {
+			return &m.points[i], nil
+		}
+	}
+	return nil, fmt.Errorf("point %s not found", id)
+}
+
+// parseDiagramModel parses the points and the parent-child connections of the data part of a diagram.
+func parseDiagramModel(data []byte) (*diagramModel, error) {
+	docReader := NewReader(string(data))
+	decoder := xml.NewDecoder(docReader)
+
+	type connection struct {
+		source, destination string
+		order               int
+	}
+	var connections []connection
+	model := &diagramModel{children: make(map[string][]string)}
+	var point *diagramPoint
+	depth, pointDepth := 0, 0
+	for {
+		tok, err := decoder.Token()
+		if tok == nil || err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error getting token: %s", err)
+		}
+
+		switch elem := tok.(type) {
+		case xml.StartElement:
+			depth++
+			switch {
+			case elem.Name.Local == "pt" && point == nil:
+				point = &diagramPoint{ID: attrValue(elem, "modelId"), Type: attrValue(elem, "type"), Text: Position{Start: -1, End: -1}}
+				pointDepth = depth
+			case elem.Name.Local == "prSet" && point != nil && depth == pointDepth+1:
+				point.AssocID = attrValue(elem, "presAssocID")
+			case elem.Name.Local == "t" && point != nil && depth == pointDepth+1:
+				point.Text.Start = docReader.Pos()
+			case elem.Name.Local == "cxn":
+				// connections without type connect a parent with its child
+				if cxnType := attrValue(elem, "type"); cxnType == "" || cxnType == "parOf" {
+					order, _ := strconv.Atoi(attrValue(elem, "srcOrd"))
+					connections = append(connections, connection{source: attrValue(elem, "srcId"), destination: attrValue(elem, "destId"), order: order})
+				}
+			}
+		case xml.EndElement:
+			switch {
+			case elem.Name.Local == "t" && point != nil && depth == pointDepth+1:
+				// the decoder reads ahead after character data, so the close tag is searched from its end
+				point.Text.End = int64(bytes.LastIndexByte(data[:docReader.Pos()], '<'))
+				if point.Text.End < point.Text.Start {
+					// a singleton tag, <dgm:t/>, which cannot hold text
+					point.Text = Position{Start: -1, End: -1}
+				}
+			case elem.Name.Local == "pt" && point != nil && depth == pointDepth:
+				model.points = append(model.points, *point)
+				point = nil
+			}
+			depth--
+		}
+	}
+
+	sort.SliceStable(connections, func(i, j int) bool {
+		return connections[i].order < connections[j].order
+	})
+	for _, c := range connections {
+		model.children[c.source] = append(model.children[c.source], c.destination)
+	}
+	return model, nil
+}
+
+// setDiagramShapeText sets the text of the cached shapes with the given model ids.
+func setDiagramShapeText(data []byte, shapes []string, value string) ([]byte, bool, error) {
+	docReader := NewReader(string(data))
+	decoder := xml.NewDecoder(docReader)
+
+	var bodies []Position // the content of the text bodies of the shapes
+	inShape := false
+	var bodyStart int64
+	for {
+		tok, err := decoder.Token()
+		if tok == nil || err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, false, fmt.Errorf("error getting token: %s", err)
+		}
+
+		switch elem := tok.(type) {
+		case xml.StartElement:
+			switch elem.Name.Local {
+			case "sp":
+				inShape = false
+				for _, id := range shapes {
+					if attrValue(elem, "modelId") == id {
+						inShape = true
+					}
+				}
+			case "txBody":
+				bodyStart = docReader.Pos()
+			}
+		case xml.EndElement:
+			switch elem.Name.Local {
+			case "sp":
+				inShape = false
+			case "txBody":
+				if end := int64(bytes.LastIndexByte(data[:docReader.Pos()], '<')); inShape && end > bodyStart {
+					bodies = append(bodies, Position{Start: bodyStart, End: end})
+				}
+			}
+		}
+	}
+
+	for i := len(bodies) - 1; i >= 0; i-- {
+		data = replaceRange(data, bodies[i], setDrawingText(data[bodies[i].Start:bodies[i].End], value))
+	}
+	return data, len(bodies) > 0, nil
+}
+
+// setDrawingText returns the content of a DrawingML text body with its paragraphs replaced by a single paragraph
+// holding the value. The body properties and the properties of the first paragraph and run are kept.
+func setDrawingText(body []byte, value string) []byte {
+	paragraphs := drawingParagraphRegex.FindAllIndex(body, -1)
+	var paragraph []byte
+	start, end := len(body), len(body)
+	if len(paragraphs) > 0 {
+		start, end = paragraphs[0][0], paragraphs[len(paragraphs)-1][1]
+		paragraph = body[paragraphs[0][0]:paragraphs[0][1]]
+	}
+
+	var out bytes.Buffer
+	out.Write(body[:start])
+	out.WriteString("<a:p>")
+	out.Write(drawingParagraphPropsRegex.Find(paragraph))
+	out.WriteString("<a:r>")
+	out.Write(drawingRunPropsRegex.Find(paragraph))
+	out.WriteString("<a:t>" + xmlEscape(value) + "</a:t></a:r></a:p>")
+	out.Write(body[end:])
+	return out.Bytes()
+}
+
+// replaceRange returns a copy of the data with the given range replaced.
+func replaceRange(data []byte, position Position, replacement []byte) []byte {
+	out := make([]byte, 0, len(data)+len(replacement))
+	out = append(out, data[:position.Start]...)
+	out = append(out, replacement...)
+	return append(out, data[position.End:]...)
+}
@@ -0,0 +1,94 @@
+package docx
+
+import (
+	"strings"
+	"testing"
+)
+
+const diagramData = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<dgm:dataModel xmlns:dgm="http://schemas.openxmlformats.org/drawingml/2006/diagram" xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main"><dgm:ptLst>` +
+	`<dgm:pt modelId="{0}" type="doc"><dgm:prSet/><dgm:spPr/><dgm:t><a:bodyPr/><a:lstStyle/><a:p><a:endParaRPr lang="en-US"/></a:p></dgm:t></dgm:pt>` +
+	`<dgm:pt modelId="{1}"><dgm:prSet phldrT="[Text]"/><dgm:spPr/><dgm:t><a:bodyPr/><a:lstStyle/><a:p><a:r><a:rPr lang="en-US" b="1"/><a:t>{{.ceo}}</a:t></a:r></a:p></dgm:t></dgm:pt>` +
+	`<dgm:pt modelId="{3}"><dgm:prSet phldrT="[Text]"/><dgm:spPr/><dgm:t><a:bodyPr/><a:lstStyle/><a:p><a:r><a:rPr lang="en-US"/><a:t>CFO</a:t></a:r></a:p></dgm:t></dgm:pt>` +
+	`<dgm:pt modelId="{2}"><dgm:prSet phldrT="[Text]"/><dgm:spPr/><dgm:t><a:bodyPr/><a:lstStyle/><a:p><a:r><a:rPr lang="en-US"/><a:t>CTO {key}</a:t></a:r></a:p></dgm:t></dgm:pt>` +
+	`<dgm:pt modelId="{3P}" type="pres"><dgm:prSet presAssocID="{3}" presName="rootText"/><dgm:spPr/></dgm:pt>` +
+	`</dgm:ptLst><dgm:cxnLst>` +
+	`<dgm:cxn modelId="{C1}" srcId="{0}" destId="{1}" srcOrd="0" destOrd="0"/>` +
+	`<dgm:cxn modelId="{C3}" srcId="{1}" destId="{3}" srcOrd="1" destOrd="0"/>` +
+	`<dgm:cxn modelId="{C2}" srcId="{1}" destId="{2}" srcOrd="0" destOrd="0"/>` +
+	`<dgm:cxn modelId="{C4}" type="presOf" srcId="{3}" destId="{3P}" srcOrd="0" destOrd="0"/>` +
+	`</dgm:cxnLst></dgm:dataModel>`
+
+const diagramDrawing = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<dsp:drawing xmlns:dsp="http://schemas.microsoft.com/office/drawing/2008/diagram" xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main"><dsp:spTree>` +
+	`<dsp:sp modelId="{1P}"><dsp:txBody><a:bodyPr/><a:p><a:r><a:rPr lang="en-US" b="1"/><a:t>{{.ceo}}</a:t></a:r></a:p></dsp:txBody></dsp:sp>` +
+	`<dsp:sp modelId="{3P}"><dsp:txBody><a:bodyPr/><a:lstStyle/><a:p><a:pPr algn="ctr"/><a:r><a:rPr lang="en-US"/><a:t>C</a:t></a:r><a:r><a:t>FO</a:t></a:r></a:p></dsp:txBody></dsp:sp>` +
+	`</dsp:spTree></dsp:drawing>`
+
+func diagramDocument(t *testing.T) *Document {
+	doc, err := OpenBytes(rewriteArchive(t, readFile(t, "./test/template.docx"), nil, map[string][]byte{
+		"word/diagrams/data1.xml":    []byte(diagramData),
+		"word/diagrams/drawing1.xml": []byte(diagramDrawing),
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func TestDocument_ExecuteTemplate_Diagram(t *testing.T) {
+	doc := diagramDocument(t)
+	defer doc.Close()
+	if err := doc.ExecuteTemplate(map[string]interface{}{"ceo": "Anna & Co"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.ReplaceAll(PlaceholderMap{"key": "Bob"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, part := range []string{"word/diagrams/data1.xml", "word/diagrams/drawing1.xml"} {
+		data, _ := doc.partData(part)
+		if !strings.Contains(string(data), "<a:t>Anna &amp; Co</a:t>") {
+			t.Errorf("the placeholder of %s was not replaced", part)
+		}
+	}
+	if data, _ := doc.partData("word/diagrams/data1.xml"); !strings.Contains(string(data), "<a:t>CTO Bob</a:t>") {
+		t.Errorf("the string placeholder was not replaced")
+	}
+	for _, issue := range doc.Lint() {
+		if strings.HasPrefix(issue.Part, "word/diagrams/") {
+			t.Errorf("unexpected issue %s", issue)
+		}
+	}
+}
+
+func TestDocument_SetDiagramText(t *testing.T) {
+	doc := diagramDocument(t)
+	defer doc.Close()
+
+	if diagrams := doc.Diagrams(); len(diagrams) != 1 || diagrams[0] != "word/diagrams/data1.xml" {
+		t.Fatalf("unexpected diagrams %v", diagrams)
+	}
+	if err := doc.SetDiagramText("word/diagrams/data1.xml", "1/2", "Chief <Financial> Officer"); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := doc.partData("word/diagrams/data1.xml")
+	if !strings.Contains(string(data), `<dgm:pt modelId="{3}"><dgm:prSet phldrT="[Text]"/><dgm:spPr/><dgm:t><a:bodyPr/><a:lstStyle/><a:p><a:r><a:rPr lang="en-US"/><a:t>Chief &lt;Financial&gt; Officer</a:t></a:r></a:p></dgm:t>`) {
+		t.Errorf("the text of the node was not set: %s", data)
+	}
+	drawing, _ := doc.partData("word/diagrams/drawing1.xml")
+	if !strings.Contains(string(drawing), `<dsp:txBody><a:bodyPr/><a:lstStyle/><a:p><a:pPr algn="ctr"/><a:r><a:rPr lang="en-US"/><a:t>Chief &lt;Financial&gt; Officer</a:t></a:r></a:p></dsp:txBody>`) {
+		t.Errorf("the cached shape was not updated: %s", drawing)
+	}
+
+	for _, tt := range []struct{ diagram, path string }{
+		{"word/diagrams/data2.xml", "1"},
+		{"word/diagrams/data1.xml", "2"},
+		{"word/diagrams/data1.xml", "1/x"},
+		{"word/diagrams/data1.xml", ""},
+		{"word/document.xml", "1"},
+	} {
+		if err := doc.SetDiagramText(tt.diagram, tt.path, "value"); err == nil {
+			t.Errorf("expected an error for node %q of %s", tt.path, tt.diagram)
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package docx
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ChangeType describes how a paragraph differs between two documents.
+type ChangeType int
+
+const (
+	// ChangeAdded marks a paragraph which only exists in the new document.
+	ChangeAdded ChangeType = iota
+	// ChangeRemoved marks a paragraph which only exists in the old document.
+	ChangeRemoved
+	// ChangeModified marks a paragraph of the old document which was replaced by a paragraph of the new document.
+	ChangeModified
+)
+
+// String implements the Stringer interface.
+func (t ChangeType) String() string {
+	switch t {
+	case ChangeAdded:
+		return "added"
+	case ChangeRemoved:
+		return "removed"
+	default:
+		return "modified"
+	}
+}
+
+// Change is a single difference between two documents, as returned by Diff.
+// Paragraphs are numbered in document order, starting at 0. Paragraphs inside of tables are included.
+// The index of the side which does not have the paragraph is -1.
+type Change struct {
+	Type     ChangeType
+	OldIndex int
+	NewIndex int
+	OldText  string
+	NewText  string
+}
+
+// String returns a human-readable representation of the change.
+func (c Change) String() string {
+	switch c.Type {
+	case ChangeAdded:
+		return fmt.Sprintf("+ [%d] %s", c.NewIndex, c.NewText)
+	case ChangeRemoved:
+		return fmt.Sprintf("- [%d] %s", c.OldIndex, c.OldText)
+	default:
+		return fmt.Sprintf("~ [%d→%d] %s → %s", c.OldIndex, c.NewIndex, c.OldText, c.NewText)
+	}
+}
+
+// Diff compares the paragraph text of the main body of both documents and returns all changes needed
+// to get from document a to document b. Formatting is ignored, see DiffWithFormatting.
+func Diff(a, b *Document) ([]Change, error) {
+	return diffDocuments(a, b, false)
+}
+
+// DiffWithFormatting behaves like Diff, but paragraphs which only differ in their formatting
+// (bold, italic, underline, strike, paragraph style and alignment) are reported as modified as well.
+func DiffWithFormatting(a, b *Document) ([]Change, error) {
+	return diffDocuments(a, b, true)
+}
+
+// diffDocuments computes the paragraph diff of both documents.
+func diffDocuments(a, b *Document, withFormatting bool) ([]Change, error) {
+	oldParagraphs, err := documentParagraphs(a)
+	if err != nil {
+		return nil, err
+	}
+	newParagraphs, err := documentParagraphs(b)
+	if err != nil {
+		return nil, err
+	}
+
+	key := func(p *bodyParagraph) string {
+		if withFormatting {
+			return paragraphFormatKey(p)
+		}
+		return p.Text()
+	}
+	oldKeys := make([]string, len(oldParagraphs))
+	for i, p := range oldParagraphs {
+		oldKeys[i] = key(p)
+	}
+	newKeys := make([]string, len(newParagraphs))
+	for i, p := range newParagraphs {
+		newKeys[i] = key(p)
+	}
+
+	var changes []Change
+	var removed, added []int
+
+	// flush turns the pending removals and additions of one hunk into changes.
+	// Removals and additions at the same position within the hunk are reported as modification.
+	flush := func() {
+		for i := 0; i < len(removed) || i < len(added); i++ {
+			switch {
+			case i < len(removed) && i < len(added):
+				changes = append(changes, Change{
+					Type:     ChangeModified,
+					OldIndex: removed[i],
+					NewIndex: added[i],
+					OldText:  oldParagraphs[removed[i]].Text(),
+					NewText:  newParagraphs[added[i]].Text(),
+				})
+			case i < len(removed):
+				changes = append(changes, Change{Type: ChangeRemoved, OldIndex: removed[i], NewIndex: -1, OldText: oldParagraphs[removed[i]].Text()})
+			default:
+				changes = append(changes, Change{Type: ChangeAdded, OldIndex: -1, NewIndex: added[i], NewText: newParagraphs[added[i]].Text()})
+			}
+		}
+		removed, added = nil, nil
+	}
+
+	for _, op := range diffStrings(oldKeys, newKeys) {
+		switch op.kind {
+		case diffEqual:
+			flush()
+		case diffRemove:
+			removed = append(removed, op.oldIndex)
+		case diffAdd:
+			added = append(added, op.newIndex)
+		}
+	}
+	flush()
+
+	return changes, nil
+}
+
+// documentParagraphs returns all paragraphs of the main body in document order.
+func documentParagraphs(d *Document) ([]*bodyParagraph, error) {
+	blocks, err := parseBody(d.GetFile(DocumentXml))
+	if err != nil {
+		return nil, err
+	}
+	return flattenParagraphs(blocks), nil
+}
+
+// paragraphFormatKey returns a comparison key which includes the text as well as the formatting of the paragraph.
+func paragraphFormatKey(p *bodyParagraph) string {
+	var sb strings.Builder
+	sb.WriteString(p.Style + "|" + p.Alignment)
+	for _, run := range p.Runs {
+		if run.Text == "" {
+			continue
+		}
+		fmt.Fprintf(&sb, "|%t%t%t%t:%s", run.Bold, run.Italic, run.Underline, run.Strike, run.Text)
+	}
+	return sb.String()
+}
+
+// diffOpKind is the kind of an edit operation produced by diffStrings.
+type diffOpKind int
+
+const (
+	diffEqual diffOpKind = iota
+	diffRemove
+	diffAdd
+)
+
+// diffOp is a single edit operation referencing the index in the old and/or new sequence.
+type diffOp struct {
+	kind     diffOpKind
+	oldIndex int
+	newIndex int
+}
+
+// diffStrings computes the shortest edit script between both sequences using the linear space variant of
+// Myers' algorithm, which splits the sequences at the middle snake of the shortest path and recurses.
+func diffStrings(a, b []string) []diffOp {
+	return diffRange(make([]diffOp, 0, len(a)+len(b)), a, b, 0, 0)
+}
+
+// diffRange appends the edit script of both sequences to ops. aStart and bStart are the indexes of the
+// first items in the complete sequences.
+func diffRange(ops []diffOp, a, b []string, aStart, bStart int) []diffOp {
+	// the common prefix and suffix are equal, no need to search for a path through them
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		ops = append(ops, diffOp{kind: diffEqual, oldIndex: aStart + prefix, newIndex: bStart + prefix})
+		prefix++
+	}
+	a, b = a[prefix:], b[prefix:]
+	aStart, bStart = aStart+prefix, bStart+prefix
+	suffix := 0
+	for suffix < len(a) && suffix < len(b) && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	a, b = a[:len(a)-suffix], b[:len(b)-suffix]
+
+	switch {
+	case len(a) == 0:
+		for i := range b {
+			ops = append(ops, diffOp{kind: diffAdd, oldIndex: -1, newIndex: bStart + i})
+		}
+	case len(b) == 0:
+		for i := range a {
+			ops = append(ops, diffOp{kind: diffRemove, oldIndex: aStart + i, newIndex: -1})
+		}
+	default:
+		x, y, u, v := middleSnake(a, b)
+		ops = diffRange(ops, a[:x], b[:y], aStart, bStart)
+		for i := 0; i < u-x; i++ {
+			ops = append(ops, diffOp{kind: diffEqual, oldIndex: aStart + x + i, newIndex: bStart + y + i})
+		}
+		ops = diffRange(ops, a[u:], b[v:], aStart+u, bStart+v)
+	}
+
+	for i := 0; i < suffix; i++ {
+		ops = append(ops, diffOp{kind: diffEqual, oldIndex: aStart + len(a) + i, newIndex: bStart + len(b) + i})
+	}
+	return ops
+}
+
+// middleSnake returns the start (x, y) and end (u, v) of the snake in the middle of the shortest path
+// through both sequences. It searches forward from the start and backward from the end at the same time
+// until both searches overlap, so it only keeps the furthest reaching x of every diagonal.
+func middleSnake(a, b []string) (x, y, u, v int) {
+	n, m := len(a), len(b)
+	delta := n - m
+	odd := delta%2 != 0
+	maxD := (n + m + 1) / 2
+	offset := maxD + 1
+	// forward[k] is the furthest x on diagonal k from the start, backward[k] the furthest distance
+	// from the end on the diagonal k of the reversed sequences, which is the diagonal delta-k
+	forward := make([]int, 2*maxD+3)
+	backward := make([]int, 2*maxD+3)
+
+	for d := 0; d <= maxD; d++ {
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && forward[offset+k-1] < forward[offset+k+1]) {
+				x = forward[offset+k+1]
+			} else {
+				x = forward[offset+k-1] + 1
+			}
+			y := x - k
+			startX, startY := x, y
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			forward[offset+k] = x
+			if reverse := delta - k; odd && reverse >= -(d-1) && reverse <= d-1 && x+backward[offset+reverse] >= n {
+				return startX, startY, x, y
+			}
+		}
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && backward[offset+k-1] < backward[offset+k+1]) {
+				x = backward[offset+k+1]
+			} else {
+				x = backward[offset+k-1] + 1
+			}
+			y := x - k
+			startX, startY := x, y
+			for x < n && y < m && a[n-1-x] == b[m-1-y] {
+				x++
+				y++
+			}
+			backward[offset+k] = x
+			if reverse := delta - k; !odd && reverse >= -d && reverse <= d && x+forward[offset+reverse] >= n {
+				return n - x, m - y, n - startX, m - startY
+			}
+		}
+	}
+	// unreachable, the searches overlap after at most maxD steps
+	return 0, 0, 0, 0
+}
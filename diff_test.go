@@ -0,0 +1,130 @@
+package docx
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func TestDiffStrings(t *testing.T) {
+	a := []string{"a", "b", "c", "d"}
+	b := []string{"a", "c", "x", "d", "e"}
+
+	var removed, added, equal int
+	for _, op := range diffStrings(a, b) {
+		switch op.kind {
+		case diffEqual:
+			if a[op.oldIndex] != b[op.newIndex] {
+				t.Errorf("equal op references different values %s and %s", a[op.oldIndex], b[op.newIndex])
+			}
+			equal++
+		case diffRemove:
+			removed++
+		case diffAdd:
+			added++
+		}
+	}
+	if equal != 3 || removed != 1 || added != 2 {
+		t.Errorf("expected 3 equal, 1 removed and 2 added, got %d, %d and %d", equal, removed, added)
+	}
+}
+
+func TestDiffStrings_Shortest(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	sequence := func(length int) []string {
+		items := make([]string, length)
+		for i := range items {
+			items[i] = string(rune('a' + random.Intn(4)))
+		}
+		return items
+	}
+	for i := 0; i < 200; i++ {
+		a, b := sequence(random.Intn(30)), sequence(random.Intn(30))
+
+		// the length of the longest common subsequence
+		lcs := make([][]int, len(a)+1)
+		for x := range lcs {
+			lcs[x] = make([]int, len(b)+1)
+		}
+		for x := len(a) - 1; x >= 0; x-- {
+			for y := len(b) - 1; y >= 0; y-- {
+				if a[x] == b[y] {
+					lcs[x][y] = lcs[x+1][y+1] + 1
+				} else {
+					lcs[x][y] = max(lcs[x+1][y], lcs[x][y+1])
+				}
+			}
+		}
+
+		var x, y, equal int
+		for _, op := range diffStrings(a, b) {
+			switch op.kind {
+			case diffEqual:
+				if op.oldIndex != x || op.newIndex != y || a[x] != b[y] {
+					t.Fatalf("invalid equal op %v at %d, %d for %v and %v", op, x, y, a, b)
+				}
+				x, y, equal = x+1, y+1, equal+1
+			case diffRemove:
+				if op.oldIndex != x {
+					t.Fatalf("invalid remove op %v at %d for %v and %v", op, x, a, b)
+				}
+				x++
+			case diffAdd:
+				if op.newIndex != y {
+					t.Fatalf("invalid add op %v at %d for %v and %v", op, y, a, b)
+				}
+				y++
+			}
+		}
+		if x != len(a) || y != len(b) || equal != lcs[0][0] {
+			t.Fatalf("expected %d equal items for %v and %v, got %d", lcs[0][0], a, b, equal)
+		}
+	}
+
+	// completely different sequences must not need quadratic memory
+	a, b := make([]string, 5000), make([]string, 5000)
+	for i := range a {
+		a[i], b[i] = fmt.Sprint("a", i), fmt.Sprint("b", i)
+	}
+	if ops := diffStrings(a, b); len(ops) != 10000 {
+		t.Errorf("expected 10000 operations, got %d", len(ops))
+	}
+}
+
+func TestDiff(t *testing.T) {
+	src := readFile(t, "./test/template.docx")
+	a, err := OpenBytes(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	modified := rewriteArchive(t, src, func(name string, data []byte) []byte {
+		if name == DocumentXml {
+			return bytes.Replace(data, []byte("Nothing is replaced here."), []byte("Something changed."), 1)
+		}
+		return data
+	}, nil)
+	b, err := OpenBytes(modified)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := Diff(a, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("expected no changes when comparing a document with itself, got %v", changes)
+	}
+
+	changes, err = Diff(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 {
+		t.Fatalf("expected 1 change, got %v", changes)
+	}
+	if changes[0].Type != ChangeModified || changes[0].NewText != "This is just some text. Something changed." {
+		t.Errorf("unexpected change %s", changes[0])
+	}
+}
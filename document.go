@@ -3,17 +3,27 @@ package docx
 import (
 	"archive/zip"
 	"bytes"
+	"compress/flate"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"text/template"
+
+	"github.com/izetmolla/docx/templating"
 )
 
 const (
 	// DocumentXml is the relative path where the actual document content resides inside the docx-archive.
 	DocumentXml = "word/document.xml"
+	// FootnotesXml is the relative path of the footnotes inside the docx-archive.
+	FootnotesXml = "word/footnotes.xml"
+	// EndnotesXml is the relative path of the endnotes inside the docx-archive.
+	EndnotesXml = "word/endnotes.xml"
+	// CommentsXml is the relative path of the comments inside the docx-archive.
+	CommentsXml = "word/comments.xml"
 )
 
 var (
@@ -23,8 +33,27 @@ var (
 	FooterPathRegex = regexp.MustCompile(`word/footer[0-9]*.xml`)
 	// MediaPathRegex matches all media files inside the docx-archive.
 	MediaPathRegex = regexp.MustCompile(`word/media/*`)
+	// FootnotesPathRegex matches the part holding the footnotes and their separators.
+	FootnotesPathRegex = regexp.MustCompile(`^word/footnotes\.xml$`)
+	// EndnotesPathRegex matches the part holding the endnotes and their separators.
+	EndnotesPathRegex = regexp.MustCompile(`^word/endnotes\.xml$`)
+	// CommentsPathRegex matches the part holding the comments.
+	CommentsPathRegex = regexp.MustCompile(`^word/comments\.xml$`)
 )
 
+// storyTypes are the parts holding the stories of a document besides the main document, together with the
+// option selecting them, see OpenOptions.Parts. Text boxes are part of the story they are anchored in.
+var storyTypes = []struct {
+	part  Parts
+	regex *regexp.Regexp
+}{
+	{PartHeaders, HeaderPathRegex},
+	{PartFooters, FooterPathRegex},
+	{PartFootnotes, FootnotesPathRegex},
+	{PartEndnotes, EndnotesPathRegex},
+	{PartComments, CommentsPathRegex},
+}
+
 // PlaceholderMap represents a map of placeholder keys to their replacement values
 type PlaceholderMap map[string]string
 
@@ -41,11 +70,42 @@ type Document struct {
 	headerFiles []string
 	// paths to all footer files inside the zip archive
 	footerFiles []string
+	// paths to the footnotes, endnotes and comments inside the zip archive
+	noteFiles []string
 	// paths to all media files inside the zip archive
 	mediaFiles []string
 	// The document contains multiple files which eventually need a parser each.
 	// The map key is the file path inside the document to which the parser belongs.
 	runParsers map[string]*RunParser
+	// package-level files (e.g. [Content_Types].xml) which are not processed by templates
+	// but may need to be rewritten when the document is written
+	packageFiles FileMap
+	// files of the original archive which are left out when the document is written
+	removedFiles map[string]bool
+	// the files of the original archive by their names, see archiveFile
+	archiveIndex map[string]*zip.File
+	// the files of the archive which could not be read, see Warnings
+	warnings        []Warning
+	unreadableFiles map[string]bool
+	// the last id of a drawing added by the library, see nextDrawingID
+	lastDrawingID int
+	// converts EMF, WMF and TIFF images before they are added, see SetImageConverter
+	imageConverter ImageConverter
+	// media files which are read on first access, only used if the document was opened with OpenOptions.Lazy
+	lazyFiles map[string]*zip.File
+	// the functions which transform the values before they replace the placeholders, see OnReplace
+	replaceHooks []ReplaceHook
+	// the functions which transform the XML of the modified parts when the document is written, see OnWrite
+	writeTransforms []WriteTransform
+	// the handling of templates incompatible with the data or this package, see SetVersionPolicy
+	versionPolicy VersionPolicy
+	// the handling of invalid characters in values, see SetInvalidCharPolicy
+	invalidChars InvalidCharPolicy
+
+	// type of the package as detected from its main content type
+	docType DocumentType
+	// type of the package which is written, see SetOutputType
+	outputType DocumentType
 
 	// Template processing components
 	templateReplacer *TemplateReplacer
@@ -55,7 +115,13 @@ type Document struct {
 
 // Open will open and parse the file pointed to by path.
 // The file must be a valid docx file or an error is returned.
-func Open(path string) (*Document, error) {
+func Open(path string, opts ...Option) (*Document, error) {
+	return OpenWithOptions(path, OpenOptions{}, opts...)
+}
+
+// OpenWithOptions opens and parses the file pointed to by path like Open, with the parse-time behaviors set by
+// the options.
+func OpenWithOptions(path string, options OpenOptions, opts ...Option) (*Document, error) {
 	fh, err := os.Open(path)
 	if err != nil {
 		return nil, fmt.Errorf("unable to open .docx file: %s", err)
@@ -63,23 +129,36 @@ func Open(path string) (*Document, error) {
 
 	rc, err := zip.OpenReader(path)
 	if err != nil {
+		_ = fh.Close()
 		return nil, fmt.Errorf("unable to open zip reader: %s", err)
 	}
 
-	return newDocument(&rc.Reader, path, fh)
+	doc, err := newDocument(&rc.Reader, path, fh, options, opts)
+	if err != nil {
+		_ = rc.Close()
+		_ = fh.Close()
+		return nil, err
+	}
+	return doc, nil
 }
 
 // OpenBytes allows to create a Document from a byte slice.
 // It behaves just like Open().
 //
 // Note: In this case, the docxFile property will be nil!
-func OpenBytes(b []byte) (*Document, error) {
+func OpenBytes(b []byte, opts ...Option) (*Document, error) {
+	return OpenBytesWithOptions(b, OpenOptions{}, opts...)
+}
+
+// OpenBytesWithOptions creates a Document from a byte slice like OpenBytes, with the parse-time behaviors set
+// by the options.
+func OpenBytesWithOptions(b []byte, options OpenOptions, opts ...Option) (*Document, error) {
 	rc, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
 	if err != nil {
 		return nil, fmt.Errorf("unable to open zip reader: %s", err)
 	}
 
-	return newDocument(rc, "", nil)
+	return newDocument(rc, "", nil, options, opts)
 }
 
 // newDocument will create a new document struct given the zipFile.
@@ -87,27 +166,59 @@ func OpenBytes(b []byte) (*Document, error) {
 //
 // newDocument will parse the docx archive and validate that at least a 'document.xml' exists.
 // If 'word/document.xml' is missing, an error is returned since the docx cannot be correct.
-// Then all files are parsed for their runs before returning the new document.
-func newDocument(zipFile *zip.Reader, path string, docxFile *os.File) (*Document, error) {
+// Then all files are parsed for their runs and the options are applied before returning the new document.
+func newDocument(zipFile *zip.Reader, path string, docxFile *os.File, options OpenOptions, opts []Option) (*Document, error) {
+	if err := options.validate(zipFile); err != nil {
+		return nil, err
+	}
 	doc := &Document{
-		docxFile:   docxFile,
-		zipFile:    zipFile,
-		path:       path,
-		files:      make(FileMap),
-		runParsers: make(map[string]*RunParser),
+		docxFile:     docxFile,
+		zipFile:      zipFile,
+		path:         path,
+		files:        make(FileMap),
+		runParsers:   make(map[string]*RunParser),
+		packageFiles: make(FileMap),
+		removedFiles: make(map[string]bool),
 	}
 
-	if err := doc.parseArchive(); err != nil {
+	if options.Lazy {
+		doc.lazyFiles = make(map[string]*zip.File)
+	}
+	if err := doc.parseArchive(options.parts()); err != nil {
 		return nil, fmt.Errorf("error parsing document: %s", err)
 	}
+	if options.FailFast {
+		if err := doc.warningsError(); err != nil {
+			return nil, err
+		}
+	}
 
 	// a valid docx document should really contain a document.xml :)
 	if _, exists := doc.files[DocumentXml]; !exists {
 		return nil, fmt.Errorf("invalid docx archive, %s is missing", DocumentXml)
 	}
 
+	// .docm, .dotx and .dotm packages are handled just like .docx, only their main content type differs
+	docType, err := detectDocumentType(doc.packageFiles[ContentTypesXml])
+	if err != nil {
+		return nil, err
+	}
+	doc.docType = docType
+	doc.outputType = docType
+
+	if options.AcceptRevisions {
+		for _, name := range doc.xmlParts() {
+			doc.files[name] = acceptRevisions(doc.files[name])
+		}
+	}
+
 	// parse all files for template processing
 	for name, data := range doc.files {
+		if runs, exists := options.runs[name]; exists && !options.AcceptRevisions {
+			doc.runParsers[name] = &RunParser{doc: data, runs: runs}
+			continue
+		}
+
 		// find all runs
 		doc.runParsers[name] = NewRunParser(data)
 		err := doc.runParsers[name].Execute()
@@ -115,6 +226,11 @@ func newDocument(zipFile *zip.Reader, path string, docxFile *os.File) (*Document
 			return nil, err
 		}
 	}
+	if options.Delimiters != [2]string{} {
+		if err := doc.translateDelimiters(options.Delimiters[0], options.Delimiters[1]); err != nil {
+			return nil, err
+		}
+	}
 
 	// Initialize template replacer
 	doc.templateReplacer = NewTemplateReplacer(doc)
@@ -122,6 +238,7 @@ func newDocument(zipFile *zip.Reader, path string, docxFile *os.File) (*Document
 	// Initialize string replacer
 	doc.stringReplacer = NewStringReplacer(doc)
 
+	doc.applyOptions(opts)
 	return doc, nil
 }
 
@@ -131,11 +248,32 @@ func (d *Document) ExecuteTemplate(data TemplateData) error {
 	return d.templateReplacer.ExecuteTemplateWithData(data)
 }
 
+// ExecuteTemplatePasses processes the template placeholders like ExecuteTemplate in up to the given number of
+// passes, so values inserted in one pass may contain placeholders which are processed in the next one.
+func (d *Document) ExecuteTemplatePasses(data TemplateData, passes int) error {
+	return d.templateReplacer.ExecuteTemplatePasses(data, passes)
+}
+
 // ExecuteTemplateWithFuncs processes all template placeholders with custom functions.
 func (d *Document) ExecuteTemplateWithFuncs(data TemplateData, funcMap template.FuncMap) error {
 	return d.templateReplacer.ExecuteTemplateWithFuncs(data, funcMap)
 }
 
+// SetTemplateEngine sets an alternative engine which evaluates the placeholders, e.g. a moustache-style engine.
+// The document still finds the placeholders and replaces them with the XML-escaped results.
+// Pass nil to use text/template again.
+func (d *Document) SetTemplateEngine(engine templating.Engine) {
+	d.templateReplacer.SetEngine(engine)
+}
+
+// SetDocxtemplaterSyntax enables or disables the compatibility mode for templates written for docxtemplater.
+// In this mode, the tags {name}, {#items}...{/items}, {^items}...{/items} and {%image} are understood
+// in addition to the regular actions, so existing templates can be used without changes. Tags which Word split
+// across multiple runs are joined, sections whose tags do not match are reported as TemplateError.
+func (d *Document) SetDocxtemplaterSyntax(enabled bool) {
+	d.templateReplacer.SetDocxtemplaterSyntax(enabled)
+}
+
 // AddTemplateFuncs adds custom functions to the template processor.
 func (d *Document) AddTemplateFuncs(funcMap template.FuncMap) {
 	d.templateReplacer.AddFuncs(funcMap)
@@ -166,16 +304,16 @@ func (d *Document) ReplaceAll(replaceMap PlaceholderMap) error {
 // CompleteTemplate is a convenience function that opens a template, processes it with data,
 // and writes the result to a file. The output file will be created in the same directory
 // as the template with "_output" suffix.
-func CompleteTemplate(templatePath string, data TemplateData) error {
-	return CompleteTemplateToFile(templatePath, data, "")
+func CompleteTemplate(templatePath string, data TemplateData, opts ...Option) error {
+	return CompleteTemplateToFile(templatePath, data, "", opts...)
 }
 
 // CompleteTemplateToFile is a convenience function that opens a template, processes it with data,
 // and writes the result to the specified output file. If outputPath is empty, it will create
 // an output file in the same directory as the template with "_output" suffix.
-func CompleteTemplateToFile(templatePath string, data TemplateData, outputPath string) error {
+func CompleteTemplateToFile(templatePath string, data TemplateData, outputPath string, opts ...Option) error {
 	// Open the template document
-	doc, err := Open(templatePath)
+	doc, err := Open(templatePath, opts...)
 	if err != nil {
 		return fmt.Errorf("failed to open template: %w", err)
 	}
@@ -204,39 +342,14 @@ func CompleteTemplateToFile(templatePath string, data TemplateData, outputPath s
 
 // CompleteTemplateWithFuncs is a convenience function that opens a template, processes it with data
 // and custom functions, and writes the result to a file.
-func CompleteTemplateWithFuncs(templatePath string, data TemplateData, funcMap template.FuncMap) error {
-	return CompleteTemplateWithFuncsToFile(templatePath, data, funcMap, "")
+func CompleteTemplateWithFuncs(templatePath string, data TemplateData, funcMap template.FuncMap, opts ...Option) error {
+	return CompleteTemplateWithFuncsToFile(templatePath, data, funcMap, "", opts...)
 }
 
 // CompleteTemplateWithFuncsToFile is a convenience function that opens a template, processes it with data
 // and custom functions, and writes the result to the specified output file.
-func CompleteTemplateWithFuncsToFile(templatePath string, data TemplateData, funcMap template.FuncMap, outputPath string) error {
-	// Open the template document
-	doc, err := Open(templatePath)
-	if err != nil {
-		return fmt.Errorf("failed to open template: %w", err)
-	}
-	defer doc.Close()
-
-	// Process the template with data and functions
-	err = doc.ExecuteTemplateWithFuncs(data, funcMap)
-	if err != nil {
-		return fmt.Errorf("failed to execute template: %w", err)
-	}
-
-	// Determine output path if not provided
-	if outputPath == "" {
-		// Create output path by adding "_output" before the extension
-		outputPath = generateOutputPath(templatePath)
-	}
-
-	// Write the result
-	err = doc.WriteToFile(outputPath)
-	if err != nil {
-		return fmt.Errorf("failed to write output file: %w", err)
-	}
-
-	return nil
+func CompleteTemplateWithFuncsToFile(templatePath string, data TemplateData, funcMap template.FuncMap, outputPath string, opts ...Option) error {
+	return CompleteTemplateToFile(templatePath, data, outputPath, append([]Option{WithFuncs(funcMap)}, opts...)...)
 }
 
 // generateOutputPath creates an output file path by adding "_output" before the file extension
@@ -256,63 +369,47 @@ func generateOutputPath(templatePath string) string {
 
 // CompleteTemplateToBytes is a convenience function that opens a template, processes it with data,
 // and returns the result as bytes. Perfect for uploading to cloud storage like MinIO, S3, etc.
-func CompleteTemplateToBytes(templatePath string, data TemplateData) ([]byte, error) {
-	return CompleteTemplateWithFuncsToBytes(templatePath, data, nil)
-}
-
-// CompleteTemplateWithFuncsToBytes is a convenience function that opens a template, processes it with data
-// and custom functions, and returns the result as bytes. Perfect for uploading to cloud storage.
-func CompleteTemplateWithFuncsToBytes(templatePath string, data TemplateData, funcMap template.FuncMap) ([]byte, error) {
+func CompleteTemplateToBytes(templatePath string, data TemplateData, opts ...Option) ([]byte, error) {
 	// Open the template document
-	doc, err := Open(templatePath)
+	doc, err := Open(templatePath, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open template: %w", err)
 	}
 	defer doc.Close()
 
-	// Process the template with data and functions
-	if funcMap != nil {
-		err = doc.ExecuteTemplateWithFuncs(data, funcMap)
-	} else {
-		err = doc.ExecuteTemplate(data)
-	}
-	if err != nil {
-		return nil, fmt.Errorf("failed to execute template: %w", err)
-	}
-
-	// Write the result to a buffer
-	var buf bytes.Buffer
-	err = doc.Write(&buf)
-	if err != nil {
-		return nil, fmt.Errorf("failed to write document to buffer: %w", err)
-	}
+	return executeToBytes(doc, data)
+}
 
-	return buf.Bytes(), nil
+// CompleteTemplateWithFuncsToBytes is a convenience function that opens a template, processes it with data
+// and custom functions, and returns the result as bytes. Perfect for uploading to cloud storage.
+func CompleteTemplateWithFuncsToBytes(templatePath string, data TemplateData, funcMap template.FuncMap, opts ...Option) ([]byte, error) {
+	return CompleteTemplateToBytes(templatePath, data, append([]Option{WithFuncs(funcMap)}, opts...)...)
 }
 
 // CompleteTemplateFromBytesToBytes is a convenience function that processes template bytes with data
 // and returns the result as bytes. Perfect for serverless environments where you get template from MinIO
 // and want to return processed bytes for upload back to MinIO - no file system involved.
-func CompleteTemplateFromBytesToBytes(templateBytes []byte, data TemplateData) ([]byte, error) {
-	return CompleteTemplateFromBytesToBytesWithFuncs(templateBytes, data, nil)
-}
-
-// CompleteTemplateFromBytesToBytesWithFuncs is a convenience function that processes template bytes with data
-// and custom functions, returning the result as bytes. Perfect for serverless environments and cloud processing.
-func CompleteTemplateFromBytesToBytesWithFuncs(templateBytes []byte, data TemplateData, funcMap template.FuncMap) ([]byte, error) {
+func CompleteTemplateFromBytesToBytes(templateBytes []byte, data TemplateData, opts ...Option) ([]byte, error) {
 	// Open the template document from bytes
-	doc, err := OpenBytes(templateBytes)
+	doc, err := OpenBytes(templateBytes, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open template from bytes: %w", err)
 	}
 	defer doc.Close()
 
-	// Process the template with data and functions
-	if funcMap != nil {
-		err = doc.ExecuteTemplateWithFuncs(data, funcMap)
-	} else {
-		err = doc.ExecuteTemplate(data)
-	}
+	return executeToBytes(doc, data)
+}
+
+// CompleteTemplateFromBytesToBytesWithFuncs is a convenience function that processes template bytes with data
+// and custom functions, returning the result as bytes. Perfect for serverless environments and cloud processing.
+func CompleteTemplateFromBytesToBytesWithFuncs(templateBytes []byte, data TemplateData, funcMap template.FuncMap, opts ...Option) ([]byte, error) {
+	return CompleteTemplateFromBytesToBytes(templateBytes, data, append([]Option{WithFuncs(funcMap)}, opts...)...)
+}
+
+// executeToBytes processes the template of the document with data and returns the result as bytes.
+func executeToBytes(doc *Document, data TemplateData) ([]byte, error) {
+	// Process the template with data
+	err := doc.ExecuteTemplate(data)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute template: %w", err)
 	}
@@ -330,16 +427,16 @@ func CompleteTemplateFromBytesToBytesWithFuncs(templateBytes []byte, data Templa
 // CompleteReplaceAll is a convenience function that opens a document, replaces all placeholders,
 // and writes the result to a file. The output file will be created in the same directory
 // as the template with "_output" suffix.
-func CompleteReplaceAll(templatePath string, replaceMap PlaceholderMap) error {
-	return CompleteReplaceAllToFile(templatePath, replaceMap, "")
+func CompleteReplaceAll(templatePath string, replaceMap PlaceholderMap, opts ...Option) error {
+	return CompleteReplaceAllToFile(templatePath, replaceMap, "", opts...)
 }
 
 // CompleteReplaceAllToFile is a convenience function that opens a document, replaces all placeholders,
 // and writes the result to the specified output file. If outputPath is empty, it will create
 // an output file in the same directory as the template with "_output" suffix.
-func CompleteReplaceAllToFile(templatePath string, replaceMap PlaceholderMap, outputPath string) error {
+func CompleteReplaceAllToFile(templatePath string, replaceMap PlaceholderMap, outputPath string, opts ...Option) error {
 	// Open the template document
-	doc, err := Open(templatePath)
+	doc, err := Open(templatePath, opts...)
 	if err != nil {
 		return fmt.Errorf("failed to open template: %w", err)
 	}
@@ -368,43 +465,35 @@ func CompleteReplaceAllToFile(templatePath string, replaceMap PlaceholderMap, ou
 
 // CompleteReplaceAllToBytes is a convenience function that opens a document, replaces all placeholders,
 // and returns the result as bytes. Perfect for uploading to cloud storage like MinIO, S3, etc.
-func CompleteReplaceAllToBytes(templatePath string, replaceMap PlaceholderMap) ([]byte, error) {
+func CompleteReplaceAllToBytes(templatePath string, replaceMap PlaceholderMap, opts ...Option) ([]byte, error) {
 	// Open the template document
-	doc, err := Open(templatePath)
+	doc, err := Open(templatePath, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open template: %w", err)
 	}
 	defer doc.Close()
 
-	// Replace all placeholders
-	err = doc.ReplaceAll(replaceMap)
-	if err != nil {
-		return nil, fmt.Errorf("failed to replace placeholders: %w", err)
-	}
-
-	// Write the result to a buffer
-	var buf bytes.Buffer
-	err = doc.Write(&buf)
-	if err != nil {
-		return nil, fmt.Errorf("failed to write document to buffer: %w", err)
-	}
-
-	return buf.Bytes(), nil
+	return replaceAllToBytes(doc, replaceMap)
 }
 
 // CompleteReplaceAllFromBytesToBytes is a convenience function that processes template bytes with placeholders
 // and returns the result as bytes. Perfect for serverless environments where you get template from MinIO
 // and want to return processed bytes for upload back to MinIO - no file system involved.
-func CompleteReplaceAllFromBytesToBytes(templateBytes []byte, replaceMap PlaceholderMap) ([]byte, error) {
+func CompleteReplaceAllFromBytesToBytes(templateBytes []byte, replaceMap PlaceholderMap, opts ...Option) ([]byte, error) {
 	// Open the template document from bytes
-	doc, err := OpenBytes(templateBytes)
+	doc, err := OpenBytes(templateBytes, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open template from bytes: %w", err)
 	}
 	defer doc.Close()
 
+	return replaceAllToBytes(doc, replaceMap)
+}
+
+// replaceAllToBytes replaces all placeholders of the document and returns the result as bytes.
+func replaceAllToBytes(doc *Document, replaceMap PlaceholderMap) ([]byte, error) {
 	// Replace all placeholders
-	err = doc.ReplaceAll(replaceMap)
+	err := doc.ReplaceAll(replaceMap)
 	if err != nil {
 		return nil, fmt.Errorf("failed to replace placeholders: %w", err)
 	}
@@ -421,6 +510,7 @@ func CompleteReplaceAllFromBytesToBytes(templateBytes []byte, replaceMap Placeho
 
 // GetFile returns the content of the given fileName if it exists.
 func (d *Document) GetFile(fileName string) []byte {
+	d.loadLazyFile(fileName)
 	if f, exists := d.files[fileName]; exists {
 		return f
 	}
@@ -430,13 +520,25 @@ func (d *Document) GetFile(fileName string) []byte {
 // SetFile allows setting the file contents of the given file.
 // The fileName must be known, otherwise an error is returned.
 func (d *Document) SetFile(fileName string, fileBytes []byte) error {
-	if _, exists := d.files[fileName]; !exists {
+	if !d.hasFile(fileName) {
 		return fmt.Errorf("unregistered file %s", fileName)
 	}
+	delete(d.lazyFiles, fileName)
 	d.files[fileName] = fileBytes
 	return nil
 }
 
+// refreshRuns parses the runs of the given file again. It must be called after the file was modified
+// in a way which moves existing runs, before the placeholders of the file are processed.
+func (d *Document) refreshRuns(fileName string) error {
+	parser := NewRunParser(d.GetFile(fileName))
+	if err := parser.Execute(); err != nil {
+		return err
+	}
+	d.runParsers[fileName] = parser
+	return nil
+}
+
 // parseArchive will go through the docx zip archive and read them into the FileMap.
 // Files inside the FileMap are those which can be modified by the lib.
 // Currently not all files are read, only:
@@ -444,40 +546,53 @@ func (d *Document) SetFile(fileName string, fileBytes []byte) error {
 //   - word/header*.xml
 //   - word/footer*.xml
 //   - word/media/*
-func (d *Document) parseArchive() error {
-	readZipFile := func(file *zip.File) []byte {
-		readCloser, err := file.Open()
-		if err != nil {
-			return nil
-		}
-		defer func() {
-			_ = readCloser.Close()
-		}()
-		fileBytes, err := io.ReadAll(readCloser)
-		if err != nil {
-			return nil
-		}
-		return fileBytes
-	}
-
+//   - the images referenced by the files above, see loadImageParts
+//
+// Additionally, [Content_Types].xml, _rels/.rels and word/_rels/document.xml.rels are read into the packageFiles.
+// They are never processed as templates. Headers and footers which are not part of the given parts are left
+// out, media files are only registered if the document is lazy (see OpenOptions).
+func (d *Document) parseArchive(parts Parts) error {
 	for _, file := range d.zipFile.File {
-		if file.Name == DocumentXml {
-			d.files[DocumentXml] = readZipFile(file)
+		isPackageFile := file.Name == ContentTypesXml || file.Name == PackageRelsXml || file.Name == DocumentRelsXml
+		isStory := false
+		for _, story := range storyTypes {
+			if story.regex.MatchString(file.Name) && parts&story.part != 0 {
+				isStory = true
+			}
 		}
-		if HeaderPathRegex.MatchString(file.Name) {
-			d.files[file.Name] = readZipFile(file)
-			d.headerFiles = append(d.headerFiles, file.Name)
+		isMedia := MediaPathRegex.MatchString(file.Name)
+		if !isPackageFile && !isStory && !isMedia && file.Name != DocumentXml {
+			continue
 		}
-		if FooterPathRegex.MatchString(file.Name) {
-			d.files[file.Name] = readZipFile(file)
-			d.footerFiles = append(d.footerFiles, file.Name)
+		if isMedia && d.lazyFiles != nil {
+			d.lazyFiles[file.Name] = file
+			d.mediaFiles = append(d.mediaFiles, file.Name)
+			continue
 		}
-		if MediaPathRegex.MatchString(file.Name) {
-			d.files[file.Name] = readZipFile(file)
+
+		data, err := readZipFileBytes(file)
+		if err != nil {
+			if file.Name == DocumentXml {
+				return fmt.Errorf("unable to read %s: %w", DocumentXml, err)
+			}
+			// the file is left out, so it is copied unchanged when the document is written
+			d.warn(file.Name, err)
+			continue
+		}
+		switch {
+		case isPackageFile:
+			d.packageFiles[file.Name] = data
+		case file.Name == DocumentXml:
+			d.files[DocumentXml] = data
+		case isStory:
+			d.files[file.Name] = data
+			d.addStoryFile(file.Name)
+		case isMedia:
+			d.files[file.Name] = data
 			d.mediaFiles = append(d.mediaFiles, file.Name)
 		}
 	}
-	return nil
+	return d.loadImageParts()
 }
 
 // WriteToFile will write the document to a new file.
@@ -508,69 +623,120 @@ func (d *Document) WriteToFile(file string) error {
 // Docx files are basically zip archives with many XMLs included.
 // Files which cannot be modified through this lib will just be read from the original docx and copied into the writer.
 func (d *Document) Write(writer io.Writer) error {
+	return d.WriteWithOptions(writer, WriteOptions{})
+}
+
+// WriteWithOptions writes the document like Write, with the save-time behaviors set by the options.
+func (d *Document) WriteWithOptions(writer io.Writer, options WriteOptions) error {
 	zipWriter := zip.NewWriter(writer)
 	defer func() {
 		_ = zipWriter.Close()
 	}()
 
-	// writeModifiedFile will check if the given zipFile is a file which was modified and writes it.
-	// If the file is not one of the modified files, false is returned.
-	writeModifiedFile := func(writer io.Writer, zipFile *zip.File) (bool, error) {
-		isModified := d.isModifiedFile(zipFile.Name)
-		if !isModified {
-			return false, nil
+	if level := options.CompressionLevel; level != 0 {
+		if level < flate.HuffmanOnly || level > flate.BestCompression {
+			return fmt.Errorf("invalid compression level %d", level)
 		}
-		if err := d.files.Write(writer, zipFile.Name); err != nil {
-			return false, fmt.Errorf("unable to writeFile %s: %s", zipFile.Name, err)
+		zipWriter.RegisterCompressor(zip.Deflate, func(out io.Writer) (io.WriteCloser, error) {
+			return flate.NewWriter(out, level)
+		})
+	}
+	pruned := make(map[string]bool)
+	if options.PruneMedia {
+		var err error
+		if pruned, err = d.unusedMedia(); err != nil {
+			return err
 		}
-		return true, nil
+		d.loadPackageFile(ContentTypesXml)
+	}
+	if options.UpdateFieldsOnOpen {
+		// the settings are rewritten, so they are loaded like any other modified package file
+		d.loadPackageFile(SettingsXml)
 	}
 
-	// write all files into the zip archive (docx-file)
+	// all files of the original archive, followed by the files which were added to the document (e.g. a new header)
+	// and the package files which were added to the document (e.g. a thumbnail)
+	archiveFiles := make(map[string]*zip.File)
+	var names []string
 	for _, zipFile := range d.zipFile.File {
-		fw, err := zipWriter.Create(zipFile.Name)
-		if err != nil {
-			return fmt.Errorf("unable to create writer: %s", err)
+		// e.g. the VBA project when a .docm is written as .docx
+		if d.isDroppedFile(zipFile.Name) || pruned[zipFile.Name] {
+			continue
 		}
-
-		// write all files which might've been modified by us
-		written, err := writeModifiedFile(fw, zipFile)
-		if err != nil {
-			return err
+		archiveFiles[zipFile.Name] = zipFile
+		names = append(names, zipFile.Name)
+	}
+	for _, name := range append(d.addedFiles(), d.addedPackageFiles()...) {
+		if !pruned[name] {
+			names = append(names, name)
 		}
-		if written {
+	}
+	if options.Deterministic {
+		sort.SliceStable(names, func(i, j int) bool {
+			if (names[i] == ContentTypesXml) != (names[j] == ContentTypesXml) {
+				return names[i] == ContentTypesXml
+			}
+			return names[i] < names[j]
+		})
+	}
+
+	modifiedFiles := d.modifiedFiles()
+	for _, name := range names {
+		if d.unreadableFiles[name] && archiveFiles[name] != nil && !d.isLoadedFile(name) {
+			if err := copyRawZipFile(zipWriter, archiveFiles[name]); err != nil {
+				return err
+			}
+			continue
+		}
+		_, isPackageFile := d.packageFiles[name]
+		original := archiveFiles[name]
+		if !isPackageFile && original != nil && (!modifiedFiles[name] || d.lazyFiles[name] != nil) {
+			// all files which we don't touch here (e.g. _rels.xml) are copied from the original with their metadata,
+			// they are streamed, so files larger than the memory (zip64) are supported as well
+			if err := copyUnmodifiedFile(zipWriter, original, options); err != nil {
+				return err
+			}
 			continue
 		}
 
-		// all files which we don't touch here (e.g. _rels.xml) are just copied from the original
-		readCloser, err := zipFile.Open()
-		if err != nil {
-			return fmt.Errorf("unable to open %s: %s", zipFile.Name, err)
+		header := &zip.FileHeader{Name: name, Method: zip.Deflate}
+		if options.Deterministic {
+			header.Modified = deterministicModTime
 		}
-		_, err = fw.Write(readBytes(readCloser))
+		fw, err := zipWriter.CreateHeader(header)
 		if err != nil {
-			return fmt.Errorf("unable to writeFile zipFile %s: %s", zipFile.Name, err)
+			return fmt.Errorf("unable to create writer: %s", err)
 		}
-		err = readCloser.Close()
-		if err != nil {
-			return fmt.Errorf("unable to close reader for %s: %s", zipFile.Name, err)
+
+		var data []byte
+		if isPackageFile {
+			// package-level files depend on the output type and the options
+			data = d.outputPackageFileWithOptions(name, options, pruned)
+		} else {
+			// all files which might've been modified by us
+			if _, exists := d.files[name]; !exists {
+				return fmt.Errorf("unable to writeFile %s: file not found %s", name, name)
+			}
+			data = d.outputFile(name, options)
+		}
+		if data, err = d.applyWriteTransforms(name, data); err != nil {
+			return err
+		}
+		if _, err := fw.Write(data); err != nil {
+			return fmt.Errorf("unable to writeFile %s: %s", name, err)
 		}
 	}
-	return nil
+	// the central directory is written when the archive is closed
+	return zipWriter.Close()
 }
 
-// isModifiedFile will look through all modified files and check if the searchFileName exists
-func (d *Document) isModifiedFile(searchFileName string) bool {
-	allFiles := append(d.headerFiles, d.footerFiles...)
-	allFiles = append(allFiles, d.mediaFiles...)
-	allFiles = append(allFiles, DocumentXml)
-
-	for _, file := range allFiles {
-		if searchFileName == file {
-			return true
-		}
+// modifiedFiles returns the files which might have been modified by us: the XML parts and the media files.
+func (d *Document) modifiedFiles() map[string]bool {
+	modified := make(map[string]bool, len(d.mediaFiles)+len(d.xmlParts()))
+	for _, file := range append(d.xmlParts(), d.mediaFiles...) {
+		modified[file] = true
 	}
-	return false
+	return modified
 }
 
 // Close will close everything :)
@@ -0,0 +1,313 @@
+package docx
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/izetmolla/docx/templating"
+)
+
+// docxtemplater.go implements a compatibility mode for templates written for docxtemplater, see
+// SetDocxtemplaterSyntax. Before the template is executed, the docxtemplater tags are translated into actions:
+//
+//	{name}               {{dtValue . "name"}}
+//	{#items}...{/items}  {{range dtSection . "items"}}...{{end}}
+//	{^items}...{/items}  {{range dtInverted . "items"}}...{{end}}
+//	{%logo}              {{dtImage . "logo"}}
+//
+// Sections whose tags are the only content of their paragraphs become paragraph blocks, sections starting and
+// ending in different cells of the same table row repeat the row just like {{rows}}. Sections inside a single
+// paragraph are executed after all blocks and rows are expanded.
+
+var (
+	// docxtemplaterTagRegex matches a docxtemplater tag. The optional braces around it are used to skip Go actions.
+	docxtemplaterTagRegex = regexp.MustCompile(`(\{?)\{([#^/%]?)([^{}<>]*)\}(\}?)`)
+	// docxtemplaterNameRegex matches the names supported inside of tags, i.e. dotted paths like user.address.city.
+	docxtemplaterNameRegex = regexp.MustCompile(`^(\.|[\p{L}\p{N}_]+(\.[\p{L}\p{N}_]+)*)$`)
+	// inlineSectionRegex matches the opening action of a translated section.
+	inlineSectionRegex = regexp.MustCompile(`\{\{range dt(Section|Inverted) `)
+)
+
+// SetDocxtemplaterSyntax enables or disables the compatibility mode for docxtemplater templates.
+// In this mode, the tags {name}, {#section}...{/section}, {^inverted}...{/inverted} and {%image} are understood
+// in addition to the regular actions.
+func (tr *TemplateReplacer) SetDocxtemplaterSyntax(enabled bool) {
+	tr.docxtemplater = enabled
+	if enabled {
+		tr.AddFuncs(template.FuncMap{
+			"dtValue":    tr.docxtemplaterValue,
+			"dtSection":  tr.docxtemplaterSection,
+			"dtInverted": tr.docxtemplaterInverted,
+			"dtImage":    tr.docxtemplaterImage,
+		})
+	}
+}
+
+// translateDocxtemplaterTags replaces the docxtemplater tags of all parts by the matching actions. Tags which
+// Word split across multiple runs are moved into the run they start in first, like NormalizePlaceholders does for
+// actions. Sections whose tags do not match are reported as TemplateError.
+func (tr *TemplateReplacer) translateDocxtemplaterTags() error {
+	for _, fileName := range tr.document.xmlParts() {
+		original := tr.document.GetFile(fileName)
+		data := original
+		if parser, exists := tr.document.runParsers[fileName]; exists {
+			data, _ = moveActions(data, parser.Runs().WithText(), findDocxtemplaterTags, keepAction)
+		}
+		if err := checkDocxtemplaterSections(data, fileName); err != nil {
+			return err
+		}
+		translated, err := translateDocxtemplaterRows(data)
+		if err != nil {
+			return fmt.Errorf("failed to translate table rows in %s: %w", fileName, err)
+		}
+		translated = translateDocxtemplaterTags(translated)
+		if bytes.Equal(translated, original) {
+			continue
+		}
+		if err := tr.document.SetFile(fileName, translated); err != nil {
+			return err
+		}
+		if err := tr.document.refreshRuns(fileName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// findDocxtemplaterTags finds the docxtemplater tags in the text of a paragraph, see moveActions.
+func findDocxtemplaterTags(text string) []templateAction {
+	var tags []templateAction
+	for _, match := range docxtemplaterTagRegex.FindAllStringSubmatchIndex(text, -1) {
+		if isDocxtemplaterTag(text, match) {
+			tags = append(tags, templateAction{Start: match[0], End: match[1], Left: 1, Right: 1})
+		}
+	}
+	return tags
+}
+
+// isDocxtemplaterTag reports whether the match of docxtemplaterTagRegex is a tag with a plain name
+// and not part of a Go action.
+func isDocxtemplaterTag(text string, match []int) bool {
+	return match[3] == match[2] && match[9] == match[8] && docxtemplaterNameRegex.MatchString(strings.TrimSpace(text[match[6]:match[7]]))
+}
+
+// checkDocxtemplaterSections reports the first section tag of the part without matching tag, e.g. a closing tag
+// whose opening tag is still split across multiple runs.
+func checkDocxtemplaterSections(data []byte, part string) error {
+	type section struct {
+		offset int
+		tag    string
+		name   string
+	}
+	var open []section
+	text := string(data)
+	for _, match := range docxtemplaterTagRegex.FindAllStringSubmatchIndex(text, -1) {
+		if !isDocxtemplaterTag(text, match) {
+			continue
+		}
+		tag, name := text[match[0]:match[1]], strings.TrimSpace(text[match[6]:match[7]])
+		switch text[match[4]:match[5]] {
+		case "#", "^":
+			open = append(open, section{match[0], tag, name})
+		case "/":
+			if len(open) == 0 {
+				return newTemplateError(data, part, int64(match[0]), tag, errors.New("the tag closes no section"))
+			}
+			if last := open[len(open)-1]; last.name != name {
+				return newTemplateError(data, part, int64(match[0]), tag, fmt.Errorf("the tag does not close the section %s", last.tag))
+			}
+			open = open[:len(open)-1]
+		}
+	}
+	if len(open) > 0 {
+		return newTemplateError(data, part, int64(open[0].offset), open[0].tag, errors.New("the section is not closed"))
+	}
+	return nil
+}
+
+// translateDocxtemplaterRows translates all sections which start and end in different cells of a table row.
+// The opening tag is replaced by {{rows ...}}, the closing tag is removed.
+func translateDocxtemplaterRows(data []byte) ([]byte, error) {
+	for {
+		tables, err := findTables(data)
+		if err != nil {
+			return nil, err
+		}
+		translated, found := translateNextDocxtemplaterRow(data, tables)
+		if !found {
+			return data, nil
+		}
+		data = translated
+	}
+}
+
+// translateNextDocxtemplaterRow translates the first table row section. Since all offsets change,
+// only one row is translated per call.
+func translateNextDocxtemplaterRow(data []byte, tables []*tableLayout) ([]byte, bool) {
+	for _, table := range tables {
+		for _, row := range table.Rows {
+			for c, cell := range row.Cells {
+				for _, open := range docxtemplaterTagRegex.FindAllStringSubmatch(cell.Text, -1) {
+					if open[1] != "" || open[4] != "" || (open[2] != "#" && open[2] != "^") {
+						continue
+					}
+					name := strings.TrimSpace(open[3])
+					closeTag := "{/" + name + "}"
+					for _, next := range row.Cells[c+1:] {
+						if !strings.Contains(next.Text, closeTag) {
+							continue
+						}
+						openXml, closeXml := data[cell.Start:cell.End], data[next.Start:next.End]
+						if !strings.Contains(string(openXml), open[0]) || !strings.Contains(string(closeXml), closeTag) {
+							// split tags are left to the regular translation, which ignores them
+							break
+						}
+
+						function := "dtSection"
+						if open[2] == "^" {
+							function = "dtInverted"
+						}
+						action := "{{rows " + function + " . " + strconv.Quote(name) + "}}"
+						return applyReplacements(data, []replacement{
+							{cell.Start, cell.End, []byte(strings.Replace(string(openXml), open[0], action, 1))},
+							{next.Start, next.End, []byte(strings.Replace(string(closeXml), closeTag, "", 1))},
+						}), true
+					}
+				}
+			}
+		}
+	}
+	return data, false
+}
+
+// translateDocxtemplaterTags translates all remaining tags. Tags which do not contain a plain name are left
+// unchanged, just like Go actions.
+func translateDocxtemplaterTags(data []byte) []byte {
+	return docxtemplaterTagRegex.ReplaceAllFunc(data, func(tag []byte) []byte {
+		match := docxtemplaterTagRegex.FindSubmatch(tag)
+		name := strings.TrimSpace(string(match[3]))
+		if len(match[1]) > 0 || len(match[4]) > 0 || !docxtemplaterNameRegex.MatchString(name) {
+			return tag
+		}
+
+		quoted := strconv.Quote(name)
+		switch string(match[2]) {
+		case "#":
+			return []byte("{{range dtSection . " + quoted + "}}")
+		case "^":
+			return []byte("{{range dtInverted . " + quoted + "}}")
+		case "/":
+			return []byte("{{end}}")
+		case "%":
+			return []byte("{{dtImage . " + quoted + "}}")
+		}
+		return []byte("{{dtValue . " + quoted + "}}")
+	})
+}
+
+// executeInlineSections executes all paragraphs which contain a complete section, e.g. {#vip}VIP {/vip}{name}.
+// It is called after all blocks and rows are expanded, so the remaining sections are evaluated with the data.
+func (tr *TemplateReplacer) executeInlineSections() error {
+	for _, fileName := range tr.document.xmlParts() {
+		tr.part = fileName
+		data := tr.document.GetFile(fileName)
+		if !inlineSectionRegex.Match(data) {
+			continue
+		}
+		paragraphs, err := findBlockParagraphs(data)
+		if err != nil {
+			return fmt.Errorf("failed to parse paragraphs in %s: %w", fileName, err)
+		}
+
+		var replacements []replacement
+		end := int64(0)
+		for _, paragraph := range paragraphs {
+			if paragraph.Start < end || !inlineSectionRegex.MatchString(paragraph.Text) {
+				continue
+			}
+			paragraphXml := data[paragraph.Start:paragraph.End]
+			if len(inlineSectionRegex.FindAll(paragraphXml, -1)) != len(inlineSectionRegex.FindAllString(paragraph.Text, -1)) {
+				return fmt.Errorf("the section tags in %q must not be split across multiple runs", paragraph.Text)
+			}
+			result, err := tr.executeFragment(string(paragraphXml), tr.data)
+			if err != nil {
+				return newTemplateError(data, fileName, paragraph.Start, inlineSectionRegex.FindString(paragraph.Text), err)
+			}
+			replacements = append(replacements, replacement{paragraph.Start, paragraph.End, result})
+			end = paragraph.End
+		}
+		if err := tr.document.SetFile(fileName, applyReplacements(data, replacements)); err != nil {
+			return err
+		}
+		if err := tr.document.refreshRuns(fileName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// docxtemplaterLookup resolves the name in the current scope and falls back to the data.
+func (tr *TemplateReplacer) docxtemplaterLookup(scope interface{}, name string) (interface{}, bool) {
+	if value, exists := templating.Lookup(scope, name); exists {
+		return value, true
+	}
+	return templating.Lookup(tr.data, name)
+}
+
+// docxtemplaterValue implements {name}. Missing values are empty.
+func (tr *TemplateReplacer) docxtemplaterValue(scope interface{}, name string) (rawXML, error) {
+	value, exists := tr.docxtemplaterLookup(scope, name)
+	if !exists || value == nil {
+		return "", nil
+	}
+	text, err := sanitizeValue(fmt.Sprint(value), tr.document.invalidChars)
+	if err != nil {
+		return "", err
+	}
+	return rawXML(tr.markValue(bookmarkName(name), "", tr.markRightToLeft(escapeMarkers(xmlEscape(text))))), nil
+}
+
+// docxtemplaterSection implements {#name}: lists are repeated once per item, other true values are shown once
+// with the value as scope if it is an object. False, empty and missing values are not shown.
+func (tr *TemplateReplacer) docxtemplaterSection(scope interface{}, name string) []interface{} {
+	value, exists := tr.docxtemplaterLookup(scope, name)
+	if !exists || !isTrue(value) {
+		return nil
+	}
+
+	v := reflect.ValueOf(value)
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		items := make([]interface{}, v.Len())
+		for i := range items {
+			items[i] = v.Index(i).Interface()
+		}
+		return items
+	case reflect.Map, reflect.Struct:
+		return []interface{}{value}
+	}
+	return []interface{}{scope}
+}
+
+// docxtemplaterInverted implements {^name}: the content is shown once if the section would not be shown.
+func (tr *TemplateReplacer) docxtemplaterInverted(scope interface{}, name string) []interface{} {
+	if len(tr.docxtemplaterSection(scope, name)) > 0 {
+		return nil
+	}
+	return []interface{}{scope}
+}
+
+// docxtemplaterImage implements {%name}, see the image function.
+func (tr *TemplateReplacer) docxtemplaterImage(scope interface{}, name string) (rawXML, error) {
+	value, _ := tr.docxtemplaterLookup(scope, name)
+	return tr.imageHelper(value)
+}
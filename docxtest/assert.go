@@ -0,0 +1,196 @@
+package docxtest
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/izetmolla/docx"
+)
+
+// wordprocessingML is the namespace of the elements of the main document.
+const wordprocessingML = "http://schemas.openxmlformats.org/wordprocessingml/2006/main"
+
+// UpdateEnv is the environment variable which makes AssertGolden write the golden files instead of comparing
+// them, e.g. DOCXTEST_UPDATE=1 go test ./...
+const UpdateEnv = "DOCXTEST_UPDATE"
+
+// Paragraphs returns the text of all paragraphs of the part in document order, including the paragraphs of
+// tables and text boxes. Tabs are returned as \t, line breaks as \n.
+func Paragraphs(doc *docx.Document, part string) ([]string, error) {
+	var texts []string
+	err := walk(doc, part, func(event walkEvent) {
+		if event.paragraphEnd {
+			texts = append(texts, event.text)
+		}
+	})
+	return texts, err
+}
+
+// Outline returns the structure of the part as text: a line per paragraph outside of tables and a line per table
+// row with the text of its cells, e.g. "| Desk | 2 |". Paragraphs inside a cell are separated by " / ".
+func Outline(doc *docx.Document, part string) (string, error) {
+	var lines []string
+	var cells []string
+	err := walk(doc, part, func(event walkEvent) {
+		switch {
+		case event.paragraphEnd && event.tableDepth == 0:
+			lines = append(lines, event.text)
+		case event.paragraphEnd && len(cells) > 0:
+			if cell := &cells[len(cells)-1]; *cell == "" {
+				*cell = event.text
+			} else {
+				*cell += " / " + event.text
+			}
+		case event.cellStart && event.tableDepth == 1:
+			cells = append(cells, "")
+		case event.rowEnd && event.tableDepth == 1:
+			lines = append(lines, "| "+strings.Join(cells, " | ")+" |")
+			cells = nil
+		}
+	})
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(lines, "\n") + "\n", nil
+}
+
+// AssertText fails the test unless the paragraphs of the main document have the expected text, see Paragraphs.
+func AssertText(tb testing.TB, doc *docx.Document, expected ...string) {
+	tb.Helper()
+	texts, err := Paragraphs(doc, docx.DocumentXml)
+	if err != nil {
+		tb.Fatalf("unable to read the paragraphs: %v", err)
+	}
+	if len(texts) != len(expected) {
+		tb.Errorf("expected %d paragraphs, got %d: %q", len(expected), len(texts), texts)
+		return
+	}
+	for i := range texts {
+		if texts[i] != expected[i] {
+			tb.Errorf("paragraph %d: expected %q, got %q", i, expected[i], texts[i])
+		}
+	}
+}
+
+// AssertGolden fails the test unless the outline of the main document equals the content of the golden file,
+// see Outline. The golden file is written instead if the environment variable DOCXTEST_UPDATE is set.
+func AssertGolden(tb testing.TB, doc *docx.Document, golden string) {
+	tb.Helper()
+	outline, err := Outline(doc, docx.DocumentXml)
+	if err != nil {
+		tb.Fatalf("unable to read the outline: %v", err)
+	}
+	if os.Getenv(UpdateEnv) != "" {
+		if err := os.MkdirAll(filepath.Dir(golden), 0o755); err != nil {
+			tb.Fatal(err)
+		}
+		if err := os.WriteFile(golden, []byte(outline), 0o644); err != nil {
+			tb.Fatal(err)
+		}
+		return
+	}
+
+	expected, err := os.ReadFile(golden)
+	if errors.Is(err, os.ErrNotExist) {
+		tb.Fatalf("golden file %s not found, run the test with %s=1 to create it", golden, UpdateEnv)
+	}
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if got := string(expected); got != outline {
+		tb.Errorf("the document differs from %s:\n%s", golden, diffLines(strings.Split(got, "\n"), strings.Split(outline, "\n")))
+	}
+}
+
+// walkEvent is an element of the part passed to the callback of walk.
+type walkEvent struct {
+	paragraphEnd bool   // a paragraph ended, text is its text
+	cellStart    bool   // a table cell started
+	rowEnd       bool   // a table row ended
+	tableDepth   int    // the number of open tables
+	text         string // the text of the ended paragraph
+}
+
+// walk decodes the part and calls the callback for the paragraphs, cells and rows. The fallback content Word
+// writes for older readers is skipped, so text boxes are not read twice.
+func walk(doc *docx.Document, part string, callback func(walkEvent)) error {
+	data := doc.GetFile(part)
+	if data == nil {
+		return fmt.Errorf("file %s not found", part)
+	}
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+
+	var texts []*strings.Builder // the texts of the open paragraphs
+	tableDepth, fallbackDepth := 0, 0
+	inRun, inText := false, false
+	for {
+		tok, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", part, err)
+		}
+
+		switch elem := tok.(type) {
+		case xml.StartElement:
+			switch name := wordName(elem.Name); {
+			case elem.Name.Local == "Fallback":
+				fallbackDepth++
+			case fallbackDepth > 0:
+			case name == "p":
+				texts = append(texts, &strings.Builder{})
+			case name == "tbl":
+				tableDepth++
+			case name == "tc":
+				callback(walkEvent{cellStart: true, tableDepth: tableDepth})
+			case name == "r":
+				inRun = true
+			case name == "t":
+				inText = true
+			case name == "tab" && inRun && len(texts) > 0:
+				texts[len(texts)-1].WriteString("\t")
+			case name == "br" && inRun && len(texts) > 0:
+				texts[len(texts)-1].WriteString("\n")
+			}
+		case xml.EndElement:
+			switch name := wordName(elem.Name); {
+			case elem.Name.Local == "Fallback":
+				fallbackDepth--
+			case fallbackDepth > 0:
+			case name == "p" && len(texts) > 0:
+				text := texts[len(texts)-1].String()
+				texts = texts[:len(texts)-1]
+				callback(walkEvent{paragraphEnd: true, tableDepth: tableDepth, text: text})
+			case name == "tbl":
+				tableDepth--
+			case name == "tr":
+				callback(walkEvent{rowEnd: true, tableDepth: tableDepth})
+			case name == "r":
+				inRun = false
+			case name == "t":
+				inText = false
+			}
+		case xml.CharData:
+			if inText && fallbackDepth == 0 && len(texts) > 0 {
+				texts[len(texts)-1].Write(elem)
+			}
+		}
+	}
+}
+
+// wordName returns the local name of WordprocessingML elements, empty for elements of other namespaces, e.g. the
+// paragraphs of DrawingML.
+func wordName(name xml.Name) string {
+	if name.Space != wordprocessingML {
+		return ""
+	}
+	return name.Local
+}
@@ -0,0 +1,300 @@
+package docxtest
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// CompareOption controls which differences AssertEqualDocx ignores. Options are combined with |, e.g.
+// IgnoreRsids|IgnoreTimestamps.
+type CompareOption uint
+
+const (
+	// IgnoreRsids ignores the revision ids Word adds to paragraphs and runs whenever a document is edited, e.g.
+	// w:rsidR, and the list of them in the settings.
+	IgnoreRsids CompareOption = 1 << iota
+	// IgnoreTimestamps ignores the creation, modification and print dates of the document properties and the
+	// dates of comments and tracked changes.
+	IgnoreTimestamps
+)
+
+// maxDiffLines is the maximum number of differing lines reported per part.
+const maxDiffLines = 40
+
+// AssertEqualDocx fails the test unless the actual document equals the document at the expected path. XML parts
+// are compared after normalizing them, i.e. the order of attributes and the XML declaration do not matter, and the
+// differences are reported as lines of the indented XML. Other parts, e.g. images, have to be equal byte by byte.
+// The expected document is written instead if the environment variable DOCXTEST_UPDATE is set.
+func AssertEqualDocx(tb testing.TB, expectedPath string, actual []byte, options CompareOption) {
+	tb.Helper()
+	if os.Getenv(UpdateEnv) != "" {
+		if err := os.MkdirAll(filepath.Dir(expectedPath), 0o755); err != nil {
+			tb.Fatal(err)
+		}
+		if err := os.WriteFile(expectedPath, actual, 0o644); err != nil {
+			tb.Fatal(err)
+		}
+		return
+	}
+
+	expected, err := os.ReadFile(expectedPath)
+	if err != nil {
+		tb.Fatalf("unable to read the expected document, run the test with %s=1 to create it: %v", UpdateEnv, err)
+	}
+	differences, err := CompareDocx(expected, actual, options)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if len(differences) > 0 {
+		tb.Errorf("the document differs from %s:\n%s", expectedPath, strings.Join(differences, "\n"))
+	}
+}
+
+// CompareDocx compares two docx archives like AssertEqualDocx and returns a readable description of each differing
+// part, none if the documents are equal.
+func CompareDocx(expected, actual []byte, options CompareOption) ([]string, error) {
+	expectedParts, err := readParts(expected)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the expected document: %w", err)
+	}
+	actualParts, err := readParts(actual)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the actual document: %w", err)
+	}
+
+	names := make(map[string]bool)
+	for name := range expectedParts {
+		names[name] = true
+	}
+	for name := range actualParts {
+		names[name] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	var differences []string
+	for _, name := range sorted {
+		want, inExpected := expectedParts[name]
+		got, inActual := actualParts[name]
+		switch {
+		case !inActual:
+			differences = append(differences, fmt.Sprintf("%s: missing", name))
+		case !inExpected:
+			differences = append(differences, fmt.Sprintf("%s: unexpected", name))
+		case !isXmlPart(name):
+			if !bytes.Equal(want, got) {
+				differences = append(differences, fmt.Sprintf("%s: the content differs (%d bytes, expected %d)", name, len(got), len(want)))
+			}
+		default:
+			wantLines, err := normalizeXml(want, options)
+			if err != nil {
+				return nil, fmt.Errorf("unable to read %s of the expected document: %w", name, err)
+			}
+			gotLines, err := normalizeXml(got, options)
+			if err != nil {
+				return nil, fmt.Errorf("unable to read %s of the actual document: %w", name, err)
+			}
+			if diff := diffLines(wantLines, gotLines); diff != "" {
+				differences = append(differences, fmt.Sprintf("%s:\n%s", name, diff))
+			}
+		}
+	}
+	return differences, nil
+}
+
+// readParts returns the files of the archive by name.
+func readParts(data []byte) (map[string][]byte, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	parts := make(map[string][]byte)
+	for _, file := range archive.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		reader, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+		content, err := io.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			return nil, fmt.Errorf("unable to read %s: %w", file.Name, err)
+		}
+		parts[file.Name] = content
+	}
+	return parts, nil
+}
+
+// isXmlPart returns true if the part is compared as XML.
+func isXmlPart(name string) bool {
+	return strings.HasSuffix(name, ".xml") || strings.HasSuffix(name, ".rels")
+}
+
+// normalizeXml returns the XML indented with one element or text per line. Attributes are sorted, the XML
+// declaration, comments and the white space between elements are left out.
+func normalizeXml(data []byte, options CompareOption) ([]string, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	var lines []string
+	var open []xml.Name
+	skipDepth := 0 // the depth of the open elements which are ignored
+	for {
+		tok, err := decoder.RawToken()
+		if err == io.EOF {
+			return lines, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		indent := strings.Repeat("  ", len(open))
+		switch elem := tok.(type) {
+		case xml.StartElement:
+			open = append(open, elem.Name)
+			if skipDepth > 0 || ignoredElement(elem.Name, options) {
+				skipDepth++
+				continue
+			}
+			var attrs []string
+			for _, attr := range elem.Attr {
+				if !ignoredAttr(attr.Name, options) {
+					attrs = append(attrs, fmt.Sprintf(" %s=%q", qualifiedName(attr.Name), attr.Value))
+				}
+			}
+			sort.Strings(attrs)
+			lines = append(lines, indent+"<"+qualifiedName(elem.Name)+strings.Join(attrs, "")+">")
+		case xml.EndElement:
+			if len(open) > 0 {
+				open = open[:len(open)-1]
+			}
+			if skipDepth > 0 {
+				skipDepth--
+				continue
+			}
+			lines = append(lines, strings.Repeat("  ", len(open))+"</"+qualifiedName(elem.Name)+">")
+		case xml.CharData:
+			if skipDepth > 0 || len(bytes.TrimSpace(elem)) == 0 && (len(open) == 0 || open[len(open)-1].Local != "t") {
+				continue
+			}
+			lines = append(lines, fmt.Sprintf("%s%q", indent, string(elem)))
+		}
+	}
+}
+
+// ignoredElement returns true if the element and its content are left out of the comparison.
+func ignoredElement(name xml.Name, options CompareOption) bool {
+	switch name.Local {
+	case "rsids":
+		return options&IgnoreRsids != 0
+	case "created", "modified", "lastPrinted":
+		return options&IgnoreTimestamps != 0
+	}
+	return false
+}
+
+// ignoredAttr returns true if the attribute is left out of the comparison.
+func ignoredAttr(name xml.Name, options CompareOption) bool {
+	if strings.HasPrefix(name.Local, "rsid") {
+		return options&IgnoreRsids != 0
+	}
+	return name.Local == "date" && options&IgnoreTimestamps != 0
+}
+
+// qualifiedName returns the name with its namespace prefix, e.g. w:p.
+func qualifiedName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}
+
+// diffLines returns the lines which were removed from or added to the expected lines, empty if they are equal.
+// Lines common to both are left out, each change is preceded by its line number in the expected lines.
+func diffLines(expected, actual []string) string {
+	// the common beginning and end are skipped, so large parts with small changes are compared quickly
+	prefix := 0
+	for prefix < len(expected) && prefix < len(actual) && expected[prefix] == actual[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(expected)-prefix && suffix < len(actual)-prefix &&
+		expected[len(expected)-1-suffix] == actual[len(actual)-1-suffix] {
+		suffix++
+	}
+	want := expected[prefix : len(expected)-suffix]
+	got := actual[prefix : len(actual)-suffix]
+	if len(want) == 0 && len(got) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	lines := 0
+	write := func(format string, args ...interface{}) {
+		if lines++; lines <= maxDiffLines {
+			fmt.Fprintf(&sb, format, args...)
+		}
+	}
+	if len(want)*len(got) > 1_000_000 {
+		// too large for the longest common subsequence, the whole range is reported
+		write("line %d:\n", prefix+1)
+		for _, line := range want {
+			write("- %s\n", line)
+		}
+		for _, line := range got {
+			write("+ %s\n", line)
+		}
+	} else {
+		// lengths[i][j] is the length of the longest common subsequence of want[i:] and got[j:]
+		lengths := make([][]int, len(want)+1)
+		for i := range lengths {
+			lengths[i] = make([]int, len(got)+1)
+		}
+		for i := len(want) - 1; i >= 0; i-- {
+			for j := len(got) - 1; j >= 0; j-- {
+				if want[i] == got[j] {
+					lengths[i][j] = lengths[i+1][j+1] + 1
+				} else if lengths[i+1][j] >= lengths[i][j+1] {
+					lengths[i][j] = lengths[i+1][j]
+				} else {
+					lengths[i][j] = lengths[i][j+1]
+				}
+			}
+		}
+		inHunk := false
+		for i, j := 0, 0; i < len(want) || j < len(got); {
+			switch {
+			case i < len(want) && j < len(got) && want[i] == got[j]:
+				inHunk = false
+				i++
+				j++
+				continue
+			case !inHunk:
+				write("line %d:\n", prefix+i+1)
+				inHunk = true
+			}
+			if j >= len(got) || i < len(want) && lengths[i+1][j] >= lengths[i][j+1] {
+				write("- %s\n", want[i])
+				i++
+			} else {
+				write("+ %s\n", got[j])
+				j++
+			}
+		}
+	}
+	if lines > maxDiffLines {
+		fmt.Fprintf(&sb, "... %d more lines\n", lines-maxDiffLines)
+	}
+	return sb.String()
+}
@@ -0,0 +1,149 @@
+// Package docxtest helps to unit-test docx templates without committing binary files.
+//
+// Fixtures are minimal documents built in memory. Their paragraphs consist of the given runs, so templates can be
+// tested with the formatting and the fragmented placeholders Word produces, e.g.
+//
+//	doc := docxtest.New().
+//		Paragraph(docxtest.Text("Dear "), docxtest.Bold("{{.name}}")).
+//		Fragmented("Total: {{.to", "tal}}").
+//		Table([][]string{{"Item", "Amount"}, {"{{rows .items}}{{.name}}", "{{.amount}}"}}).
+//		Open(t)
+//
+// The rendered documents are checked with AssertText, which compares the text of the paragraphs, AssertGolden,
+// which compares the outline of the document, including its tables, with a golden file, or AssertEqualDocx, which
+// compares all parts with an expected document.
+package docxtest
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/izetmolla/docx"
+)
+
+// textEscaper escapes the text of runs and cells for XML.
+var textEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// Run is a run of a fixture paragraph, i.e. text with the same formatting.
+type Run struct {
+	Text   string
+	Bold   bool
+	Italic bool
+}
+
+// Text returns a run without formatting.
+func Text(text string) Run {
+	return Run{Text: text}
+}
+
+// Bold returns a bold run.
+func Bold(text string) Run {
+	return Run{Text: text, Bold: true}
+}
+
+// Italic returns an italic run.
+func Italic(text string) Run {
+	return Run{Text: text, Italic: true}
+}
+
+// xml returns the run as WordprocessingML.
+func (r Run) xml() string {
+	var sb strings.Builder
+	sb.WriteString(`<w:r>`)
+	if r.Bold || r.Italic {
+		sb.WriteString(`<w:rPr>`)
+		if r.Bold {
+			sb.WriteString(`<w:b/>`)
+		}
+		if r.Italic {
+			sb.WriteString(`<w:i/>`)
+		}
+		sb.WriteString(`</w:rPr>`)
+	}
+	sb.WriteString(`<w:t xml:space="preserve">` + textEscaper.Replace(r.Text) + `</w:t></w:r>`)
+	return sb.String()
+}
+
+// Fixture builds a minimal document in memory. Create it with New.
+type Fixture struct {
+	body strings.Builder
+}
+
+// New returns a fixture without content.
+func New() *Fixture {
+	return &Fixture{}
+}
+
+// Paragraph appends a paragraph consisting of the runs.
+func (f *Fixture) Paragraph(runs ...Run) *Fixture {
+	f.body.WriteString(`<w:p>`)
+	for _, run := range runs {
+		f.body.WriteString(run.xml())
+	}
+	f.body.WriteString(`</w:p>`)
+	return f
+}
+
+// Fragmented appends a paragraph whose text is split into runs of the same formatting with spell checking marks
+// between them, like Word splits placeholders which were edited, e.g. Fragmented("Hello {{.na", "me}}").
+func (f *Fixture) Fragmented(parts ...string) *Fixture {
+	f.body.WriteString(`<w:p>`)
+	for i, part := range parts {
+		if i > 0 {
+			f.body.WriteString(`<w:proofErr w:type="spellStart"/>`)
+		}
+		f.body.WriteString(Text(part).xml())
+	}
+	f.body.WriteString(`</w:p>`)
+	return f
+}
+
+// Table appends a table with one row per slice of cell texts.
+func (f *Fixture) Table(rows [][]string) *Fixture {
+	f.body.WriteString(`<w:tbl><w:tblPr><w:tblW w:w="0" w:type="auto"/></w:tblPr>`)
+	for _, row := range rows {
+		f.body.WriteString(`<w:tr>`)
+		for _, cell := range row {
+			f.body.WriteString(`<w:tc><w:p>` + Text(cell).xml() + `</w:p></w:tc>`)
+		}
+		f.body.WriteString(`</w:tr>`)
+	}
+	f.body.WriteString(`</w:tbl>`)
+	return f
+}
+
+// Bytes returns the fixture as docx archive.
+func (f *Fixture) Bytes() ([]byte, error) {
+	doc, err := docx.NewDocument().Build()
+	if err != nil {
+		return nil, err
+	}
+	defer doc.Close()
+
+	documentXml := strings.Replace(string(doc.GetFile(docx.DocumentXml)), "<w:body>", "<w:body>"+f.body.String(), 1)
+	if err := doc.SetFile(docx.DocumentXml, []byte(documentXml)); err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := doc.Write(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Open opens the fixture as document with the options. The test fails if the fixture cannot be opened, the
+// document is closed when the test ends.
+func (f *Fixture) Open(tb testing.TB, opts ...docx.Option) *docx.Document {
+	tb.Helper()
+	data, err := f.Bytes()
+	if err != nil {
+		tb.Fatalf("unable to build the fixture: %v", err)
+	}
+	doc, err := docx.OpenBytes(data, opts...)
+	if err != nil {
+		tb.Fatalf("unable to open the fixture: %v", err)
+	}
+	tb.Cleanup(func() { doc.Close() })
+	return doc
+}
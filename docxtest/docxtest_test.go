@@ -0,0 +1,94 @@
+package docxtest
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/izetmolla/docx"
+)
+
+func render(t *testing.T) *docx.Document {
+	return renderName(t, "Jane")
+}
+
+func renderName(t *testing.T, name string) *docx.Document {
+	doc := New().
+		Paragraph(Text("Dear "), Bold("{{.name}}"), Italic(" & co"), Text(",")).
+		Fragmented("Total: {{.to", "tal}}").
+		Table([][]string{{"Item", "Amount"}, {"{{rows .items}}{{.name}}", "{{.amount}}"}}).
+		Open(t)
+	if err := doc.NormalizePlaceholders(); err != nil {
+		t.Fatal(err)
+	}
+	data := map[string]interface{}{"name": name, "total": 12, "items": []map[string]interface{}{
+		{"name": "Desk", "amount": 2}, {"name": "Chair", "amount": 4}}}
+	if err := doc.ExecuteTemplate(data); err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func TestFixture(t *testing.T) {
+	doc := render(t)
+	AssertText(t, doc, "Dear Jane & co,", "Total: 12", "Item", "Amount", "Desk", "2", "Chair", "4")
+
+	data := string(doc.GetFile(docx.DocumentXml))
+	if !strings.Contains(data, `<w:b/></w:rPr><w:t xml:space="preserve">Jane</w:t>`) {
+		t.Errorf("expected the name to be bold, got %s", data)
+	}
+}
+
+func TestAssertGolden(t *testing.T) {
+	AssertGolden(t, render(t), "testdata/letter.golden")
+
+	outline, err := Outline(render(t), docx.DocumentXml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(outline, "\n")
+	if diff := diffLines(lines, strings.Split(strings.Replace(outline, "Desk", "Lamp", 1), "\n")); diff != "line 4:\n- | Desk | 2 |\n+ | Lamp | 2 |\n" {
+		t.Errorf("unexpected diff %q", diff)
+	}
+}
+
+func write(t *testing.T, doc *docx.Document) []byte {
+	var buf bytes.Buffer
+	if err := doc.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestAssertEqualDocx(t *testing.T) {
+	expected := write(t, render(t))
+	path := filepath.Join(t.TempDir(), "letter.docx")
+	if err := os.WriteFile(path, expected, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	AssertEqualDocx(t, path, write(t, render(t)), IgnoreRsids|IgnoreTimestamps)
+
+	differences, err := CompareDocx(expected, write(t, renderName(t, "Joe")), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(differences) != 1 || !strings.Contains(differences[0], "word/document.xml:\nline ") ||
+		!strings.Contains(differences[0], `"Jane"`) || !strings.Contains(differences[0], `"Joe"`) {
+		t.Errorf("unexpected differences %q", differences)
+	}
+
+	// Word adds revision ids when the document is edited
+	edited := render(t)
+	data := strings.Replace(string(edited.GetFile(docx.DocumentXml)), "<w:p>", `<w:p w:rsidR="00A1B2C3">`, 1)
+	if err := edited.SetFile(docx.DocumentXml, []byte(data)); err != nil {
+		t.Fatal(err)
+	}
+	if differences, err := CompareDocx(expected, write(t, edited), IgnoreRsids); err != nil || len(differences) > 0 {
+		t.Errorf("expected the revision ids to be ignored, got %q %v", differences, err)
+	}
+	if differences, err := CompareDocx(expected, write(t, edited), 0); err != nil || len(differences) != 1 {
+		t.Errorf("expected the revision ids to differ, got %q %v", differences, err)
+	}
+}
@@ -0,0 +1,64 @@
+package docx
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// embedHelper implements {{embed VALUE}}: the paragraph is replaced by the body of another document, e.g.
+// {{range .attachments}}{{embed .file}}{{end}}. The value is the content of a .docx file, a Document or, if
+// enabled with SetEmbedDir, the path of a .docx file inside the embed directory.
+// Images, hyperlinks, styles and lists are taken over just like for clauses, see InsertClause.
+func (tr *TemplateReplacer) embedHelper(value interface{}) (rawXML, error) {
+	var doc *Document
+	var err error
+	switch v := value.(type) {
+	case *Document:
+		doc = v
+	case []byte:
+		doc, err = OpenBytes(v)
+	case string:
+		var data []byte
+		if data, err = tr.readEmbedFile(v); err == nil {
+			doc, err = OpenBytes(data)
+		}
+	default:
+		return "", fmt.Errorf("embed: unsupported value of type %T", value)
+	}
+	if err != nil {
+		return "", fmt.Errorf("embed: %w", err)
+	}
+	return tr.insertionMarker(doc), nil
+}
+
+// SetEmbedDir allows {{embed}} to read the .docx files of paths given by the data, see SetEmbedDir of
+// TemplateReplacer.
+func (d *Document) SetEmbedDir(dir string) {
+	d.templateReplacer.SetEmbedDir(dir)
+}
+
+// SetEmbedDir allows {{embed}} to read the .docx files of paths given by the data. The paths are relative
+// to the directory and must not leave it, neither by .. nor by symbolic links. Paths are rejected if no
+// directory is set, which is the default, because the data must not be able to read arbitrary files.
+func (tr *TemplateReplacer) SetEmbedDir(dir string) {
+	tr.embedDir = dir
+}
+
+// readEmbedFile reads the file of the path inside the embed directory.
+func (tr *TemplateReplacer) readEmbedFile(name string) ([]byte, error) {
+	if tr.embedDir == "" {
+		return nil, fmt.Errorf("paths such as %s are not allowed, see SetEmbedDir", name)
+	}
+	name = filepath.ToSlash(name)
+	if !fs.ValidPath(name) {
+		return nil, fmt.Errorf("invalid path %s", name)
+	}
+	root, err := os.OpenRoot(tr.embedDir)
+	if err != nil {
+		return nil, err
+	}
+	defer root.Close()
+	return root.ReadFile(filepath.FromSlash(name))
+}
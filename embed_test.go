@@ -0,0 +1,111 @@
+package docx
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDocument_Embed(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "terms.docx")
+	if err := os.WriteFile(path, numberedDocument(t, `<w:p><w:r><w:t>Terms attachment</w:t></w:r></w:p>`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := OpenBytes(numberedDocument(t, `<w:p><w:r><w:t>{{range .attachments}}</w:t></w:r><w:r><w:t>{{embed .file}}{{end}}</w:t></w:r></w:p>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+	doc.SetEmbedDir(dir)
+	err = doc.ExecuteTemplate(map[string]interface{}{
+		"attachments": []map[string]interface{}{
+			{"file": "terms.docx"},
+			{"file": numberedDocument(t, `<w:p><w:r><w:t>Price list attachment</w:t></w:r></w:p>`)},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	documentXml := string(doc.GetFile(DocumentXml))
+	terms, prices := strings.Index(documentXml, "Terms attachment"), strings.Index(documentXml, "Price list attachment")
+	if terms < 0 || prices < terms || strings.Contains(documentXml, "docx-insert") {
+		t.Fatalf("the attachments were not embedded in order")
+	}
+	// the image of each attachment is copied, every list gets a numbering of its own
+	if images := doc.relationshipTargets(DocumentXml, ImageRelationshipType); len(images) != 3 {
+		t.Errorf("expected the images of both attachments, got %v", images)
+	}
+	for _, id := range []string{"1", "2", "3"} {
+		if strings.Count(documentXml, `<w:numId w:val="`+id+`"/>`) != 1 {
+			t.Errorf("expected one list with numId %s", id)
+		}
+	}
+
+	empty, err := OpenBytes(numberedDocument(t, `<w:p><w:r><w:t>{{range .attachments}}{{embed .file}}{{end}}</w:t></w:r></w:p>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer empty.Close()
+	if err := empty.ExecuteTemplate(map[string]interface{}{"attachments": nil}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(empty.GetFile(DocumentXml)), "{{") {
+		t.Errorf("the paragraph without attachments must be removed")
+	}
+
+	invalid, err := OpenBytes(numberedDocument(t, `<w:p><w:r><w:t>See {{embed .file}}</w:t></w:r></w:p>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer invalid.Close()
+	if err := invalid.ExecuteTemplate(map[string]interface{}{"file": readFile(t, path)}); err == nil {
+		t.Errorf("expected an error for an embed action next to text")
+	}
+}
+
+func TestDocument_EmbedPaths(t *testing.T) {
+	dir := t.TempDir()
+	attachments := filepath.Join(dir, "attachments")
+	if err := os.Mkdir(attachments, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{filepath.Join(dir, "secret.docx"), filepath.Join(attachments, "terms.docx")} {
+		if err := os.WriteFile(path, numberedDocument(t, `<w:p><w:r><w:t>Attachment</w:t></w:r></w:p>`), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(filepath.Join(dir, "secret.docx"), filepath.Join(attachments, "link.docx")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		dir  string
+		file string
+		ok   bool
+	}{
+		{"", "terms.docx", false},
+		{"", filepath.Join(attachments, "terms.docx"), false},
+		{attachments, "terms.docx", true},
+		{attachments, "../secret.docx", false},
+		{attachments, filepath.Join(dir, "secret.docx"), false},
+		{attachments, "link.docx", false},
+	}
+	for _, test := range tests {
+		doc, err := OpenBytes(numberedDocument(t, `<w:p><w:r><w:t>{{embed .file}}</w:t></w:r></w:p>`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		doc.SetEmbedDir(test.dir)
+		err = doc.ExecuteTemplate(map[string]interface{}{"file": test.file})
+		if test.ok && err != nil {
+			t.Errorf("expected %s to be embedded from %q, got %v", test.file, test.dir, err)
+		} else if !test.ok && err == nil {
+			t.Errorf("expected an error for %s in %q", test.file, test.dir)
+		}
+		doc.Close()
+	}
+}
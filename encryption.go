@@ -0,0 +1,183 @@
+package docx
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// encryption.go implements the helpers for templates stored encrypted at rest. Templates are decrypted in memory
+// and opened with OpenBytes, so their plaintext never touches the disk.
+
+// TemplateCipher encrypts and decrypts the templates stored at rest, see AESGCM and KMSCipher.
+type TemplateCipher interface {
+	Encrypt(ctx context.Context, plaintext []byte) ([]byte, error)
+	Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error)
+}
+
+// envelopeVersion is the first byte of the templates encrypted by KMSCipher.
+const envelopeVersion = 1
+
+// AESGCM returns a cipher encrypting templates with AES-GCM and the 16, 24 or 32 bytes long key. The encrypted
+// template is the random 12 bytes nonce followed by the ciphertext and the authentication tag.
+func AESGCM(key []byte) TemplateCipher {
+	return aesGCM{key: key}
+}
+
+// aesGCM is the TemplateCipher returned by AESGCM.
+type aesGCM struct {
+	key []byte
+}
+
+// Encrypt implements TemplateCipher.
+func (c aesGCM) Encrypt(_ context.Context, plaintext []byte) ([]byte, error) {
+	return sealGCM(c.key, plaintext)
+}
+
+// Decrypt implements TemplateCipher.
+func (c aesGCM) Decrypt(_ context.Context, ciphertext []byte) ([]byte, error) {
+	return openGCM(c.key, ciphertext)
+}
+
+// KMSCipher encrypts each template with a new AES-256 data key, which is stored with the template wrapped by a key
+// management service (envelope encryption). The callbacks call the service, e.g. the Encrypt and Decrypt operations
+// of AWS KMS or Google Cloud KMS, or the transit engine of Vault. Wrap is only needed to encrypt templates.
+//
+// The encrypted template starts with a version byte and the length of the wrapped key as 16 bit big-endian
+// integer, followed by the wrapped key and the template encrypted like AESGCM with the data key.
+type KMSCipher struct {
+	Wrap   func(ctx context.Context, dataKey []byte) ([]byte, error)
+	Unwrap func(ctx context.Context, wrappedKey []byte) ([]byte, error)
+}
+
+// Encrypt implements TemplateCipher.
+func (c KMSCipher) Encrypt(ctx context.Context, plaintext []byte) ([]byte, error) {
+	if c.Wrap == nil {
+		return nil, errors.New("the KMS cipher has no Wrap callback")
+	}
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, err
+	}
+	wrappedKey, err := c.Wrap(ctx, dataKey)
+	if err != nil {
+		return nil, fmt.Errorf("unable to wrap the data key: %w", err)
+	}
+	if len(wrappedKey) > 0xFFFF {
+		return nil, fmt.Errorf("the wrapped data key is too long (%d bytes)", len(wrappedKey))
+	}
+	sealed, err := sealGCM(dataKey, plaintext)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]byte, 3, 3+len(wrappedKey)+len(sealed))
+	result[0] = envelopeVersion
+	binary.BigEndian.PutUint16(result[1:], uint16(len(wrappedKey)))
+	result = append(result, wrappedKey...)
+	return append(result, sealed...), nil
+}
+
+// Decrypt implements TemplateCipher.
+func (c KMSCipher) Decrypt(ctx context.Context, ciphertext []byte) ([]byte, error) {
+	if c.Unwrap == nil {
+		return nil, errors.New("the KMS cipher has no Unwrap callback")
+	}
+	if len(ciphertext) < 3 || ciphertext[0] != envelopeVersion {
+		return nil, errors.New("unable to decrypt the template: unknown envelope format")
+	}
+	keyLength := int(binary.BigEndian.Uint16(ciphertext[1:]))
+	if len(ciphertext) < 3+keyLength {
+		return nil, errors.New("unable to decrypt the template: the envelope is truncated")
+	}
+	dataKey, err := c.Unwrap(ctx, ciphertext[3:3+keyLength])
+	if err != nil {
+		return nil, fmt.Errorf("unable to unwrap the data key: %w", err)
+	}
+	return openGCM(dataKey, ciphertext[3+keyLength:])
+}
+
+// newGCM returns AES-GCM with the key.
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// sealGCM encrypts the plaintext with AES-GCM and a random nonce, which precedes the ciphertext.
+func sealGCM(key, plaintext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize(), gcm.NonceSize()+len(plaintext)+gcm.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// openGCM decrypts and authenticates the ciphertext of sealGCM.
+func openGCM(key, ciphertext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < gcm.NonceSize()+gcm.Overhead() {
+		return nil, errors.New("unable to decrypt the template: the ciphertext is truncated")
+	}
+	plaintext, err := gcm.Open(nil, ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decrypt the template: %w", err)
+	}
+	return plaintext, nil
+}
+
+// OpenEncrypted decrypts the template in memory and opens it like OpenBytes.
+func OpenEncrypted(ctx context.Context, data []byte, templateCipher TemplateCipher, opts ...Option) (*Document, error) {
+	plaintext, err := templateCipher.Decrypt(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+	return OpenBytes(plaintext, opts...)
+}
+
+// OpenEncryptedFile reads the encrypted template at the path, decrypts it in memory and opens it like OpenBytes.
+func OpenEncryptedFile(ctx context.Context, path string, templateCipher TemplateCipher, opts ...Option) (*Document, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return OpenEncrypted(ctx, data, templateCipher, opts...)
+}
+
+// encryptedSource is a TemplateSource decrypting the templates of another source.
+type encryptedSource struct {
+	TemplateSource
+	cipher TemplateCipher
+}
+
+// EncryptedSource returns a source decrypting the templates read from the source, so a TemplateStore serves
+// templates stored encrypted at rest.
+func EncryptedSource(source TemplateSource, templateCipher TemplateCipher) TemplateSource {
+	return encryptedSource{TemplateSource: source, cipher: templateCipher}
+}
+
+// Read implements TemplateSource.
+func (s encryptedSource) Read(ctx context.Context, name string) ([]byte, error) {
+	data, err := s.TemplateSource.Read(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := s.cipher.Decrypt(ctx, data)
+	if err != nil {
+		return nil, fmt.Errorf("template %s: %w", name, err)
+	}
+	return plaintext, nil
+}
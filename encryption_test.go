@@ -0,0 +1,78 @@
+package docx
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOpenEncrypted(t *testing.T) {
+	ctx := context.Background()
+	template := readFile(t, "./test/template.docx")
+	key := bytes.Repeat([]byte{7}, 32)
+
+	encrypted, err := AESGCM(key).Encrypt(ctx, template)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(encrypted, []byte("word/document.xml")) {
+		t.Fatalf("the template is not encrypted")
+	}
+	doc, err := OpenEncrypted(ctx, encrypted, AESGCM(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc.Close()
+
+	if _, err := OpenEncrypted(ctx, encrypted, AESGCM(bytes.Repeat([]byte{8}, 32))); err == nil {
+		t.Errorf("expected an error for a wrong key")
+	}
+	encrypted[len(encrypted)-1] ^= 1
+	if _, err := OpenEncrypted(ctx, encrypted, AESGCM(key)); err == nil {
+		t.Errorf("expected an error for a modified template")
+	}
+}
+
+func TestKMSCipher(t *testing.T) {
+	ctx := context.Background()
+	masterKey := bytes.Repeat([]byte{1}, 16)
+	kms := KMSCipher{
+		Wrap: func(ctx context.Context, dataKey []byte) ([]byte, error) {
+			return AESGCM(masterKey).Encrypt(ctx, dataKey)
+		},
+		Unwrap: func(ctx context.Context, wrappedKey []byte) ([]byte, error) {
+			return AESGCM(masterKey).Decrypt(ctx, wrappedKey)
+		},
+	}
+	encrypted, err := kms.Encrypt(ctx, readFile(t, "./test/template.docx"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "offer.docx"), encrypted, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	doc, err := OpenEncryptedFile(ctx, filepath.Join(dir, "offer.docx"), kms)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc.Close()
+
+	store, err := NewTemplateStore(ctx, EncryptedSource(DirSource(dir), kms))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, ok := store.Template("offer"); !ok {
+		t.Errorf("the encrypted template was not loaded by the store")
+	}
+
+	denied := errors.New("access denied")
+	kms.Unwrap = func(context.Context, []byte) ([]byte, error) { return nil, denied }
+	if _, err := OpenEncrypted(ctx, encrypted, kms); !errors.Is(err, denied) {
+		t.Errorf("expected the error of the key management service, got %v", err)
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"log"
+
+	"github.com/izetmolla/docx"
+)
+
+func main() {
+	// Open document
+	doc, err := docx.Open("template.docx")
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer doc.Close()
+
+	// The row containing {{rows .items}} is repeated for each item
+	items := []map[string]interface{}{
+		{"name": "Desk", "quantity": 1, "price": 249.0},
+		{"name": "Chair", "quantity": 4, "price": 89.5},
+		{"name": "Lamp", "quantity": 2, "price": 35.25},
+	}
+	total := 0.0
+	for _, item := range items {
+		total += float64(item["quantity"].(int)) * item["price"].(float64)
+	}
+	data := map[string]interface{}{
+		"number":   "2024-001",
+		"customer": "Tech Corp",
+		"items":    items,
+		"total":    total,
+	}
+
+	// Execute template
+	err = doc.ExecuteTemplate(data)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Save document
+	err = doc.WriteToFile("output.docx")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	log.Println("Document processed successfully!")
+}
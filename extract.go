@@ -0,0 +1,434 @@
+package docx
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// extract.go recovers the data of a filled document from its template, see Extract. Each paragraph of the template
+// becomes a pattern in which the placeholders match any text, the patterns are matched against the paragraphs of
+// the filled document in order. Blocks of paragraphs ({{range}}, {{if}}, {{with}}) and table rows repeated with
+// {{rows}} are matched as long as their first paragraph matches.
+
+// fieldChainRegex matches a pipeline consisting of a field reference, e.g. .customer.name.
+var fieldChainRegex = regexp.MustCompile(`^(?:\.[\p{L}_][\p{L}\p{Nd}_]*)+$`)
+
+// extractNode is a paragraph of the template or a block of paragraphs.
+type extractNode struct {
+	text     string         // the text of the paragraph of the template
+	pattern  *regexp.Regexp // matches the rendered paragraph, nil for blocks
+	fields   [][]string     // the field of each group of the pattern, nil for values which are not extracted
+	literal  int            // the length of the literal text of the pattern, longer patterns are more specific
+	kind     string         // range, with, if or block for blocks
+	field    []string       // the field of range and with blocks, nil if it is not a field reference
+	children []*extractNode
+	inElse   bool // the else branch of the block is parsed, its paragraphs are not matched
+}
+
+// Extract recovers the data of a document filled from the template, e.g. a form which was returned by a user.
+// The paragraphs of the template body are matched against the paragraphs of the filled document in order and
+// the text at the positions of the placeholders becomes the value of their fields: {{.customer.name}} yields
+// {"customer": {"name": "..."}}, the placeholders of ReplaceAll ({name}) are recovered as well. Blocks of
+// paragraphs repeated by {{range .items}} and table rows repeated by {{rows .items}} become arrays of items.
+//
+// Only placeholders which consist of a field reference are recovered, values of pipelines (e.g. {{.total |
+// money}}) and paragraphs with inline conditions cannot be mapped back. With a schema, values of numbers, integers
+// and booleans are converted, the schema may be nil to keep all values as strings.
+func Extract(template, filled *Document, schema *Schema) (TemplateData, error) {
+	templateBlocks, err := parseBody(template.GetFile(DocumentXml))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template: %w", err)
+	}
+	filledParagraphs, err := documentParagraphs(filled)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read filled document: %w", err)
+	}
+
+	parser := &extractParser{}
+	parser.blocks(templateBlocks)
+	data := make(map[string]interface{})
+	newExtractMatcher(filledParagraphs).match(parser.root, 0, data, nil)
+	if schema == nil {
+		return data, nil
+	}
+	converted, err := convertExtracted("", data, schema)
+	if err != nil {
+		return nil, err
+	}
+	return converted, nil
+}
+
+// extractParser builds the nodes of the paragraphs of the template.
+type extractParser struct {
+	root  []*extractNode
+	stack []*extractNode // the open blocks
+}
+
+// add adds the node to the innermost open block.
+func (p *extractParser) add(node *extractNode) {
+	if len(p.stack) == 0 {
+		p.root = append(p.root, node)
+		return
+	}
+	if block := p.stack[len(p.stack)-1]; !block.inElse {
+		block.children = append(block.children, node)
+	}
+}
+
+// blocks adds the paragraphs of the blocks. Table rows with a {{rows}} action become range blocks.
+func (p *extractParser) blocks(blocks []bodyBlock) {
+	for _, block := range blocks {
+		if block.Paragraph != nil {
+			p.paragraph(block.Paragraph.Text())
+			continue
+		}
+		for _, row := range block.Table.Rows {
+			var texts []string
+			for _, cell := range row.Cells {
+				for _, paragraph := range flattenParagraphs(cell.Blocks) {
+					texts = append(texts, paragraph.Text())
+				}
+			}
+			field, repeated := rowsField(texts)
+			if !repeated {
+				for _, text := range texts {
+					p.paragraph(text)
+				}
+				continue
+			}
+			rowParser := &extractParser{}
+			for _, text := range texts {
+				rowParser.paragraph(text)
+			}
+			p.add(&extractNode{kind: "range", field: field, children: rowParser.root})
+		}
+	}
+}
+
+// rowsField returns the field of the {{rows}} action of the paragraphs of a table row and removes the action.
+func rowsField(texts []string) ([]string, bool) {
+	for i, text := range texts {
+		for _, action := range findTemplateActions(text) {
+			fields := strings.Fields(withoutTrimMarkers(text[action.Start+action.Left : action.End-action.Right]))
+			if len(fields) == 2 && fields[0] == "rows" {
+				texts[i] = text[:action.Start] + text[action.End:]
+				return fieldPath(fields[1]), true
+			}
+		}
+	}
+	return nil, false
+}
+
+// paragraph adds a paragraph of the template: a block action which is the only content of its paragraph opens or
+// closes a block, all other paragraphs are matched.
+func (p *extractParser) paragraph(text string) {
+	actions := findTemplateActions(text)
+	if len(actions) == 1 && strings.TrimSpace(text) == text[actions[0].Start:actions[0].End] {
+		content := strings.TrimSpace(withoutTrimMarkers(text[actions[0].Start+actions[0].Left : actions[0].End-actions[0].Right]))
+		switch kind, _ := blockActionKind(content); kind {
+		case "if", "with", "range", "block", "define":
+			block := &extractNode{kind: kind}
+			if kind == "with" || kind == "range" {
+				pipeline := strings.TrimSpace(strings.TrimPrefix(content, kind))
+				if _, variable, declared := strings.Cut(pipeline, ":="); declared {
+					pipeline = variable
+				}
+				block.field = fieldPath(pipeline)
+			}
+			p.add(block)
+			p.stack = append(p.stack, block)
+			return
+		case "else":
+			if len(p.stack) > 0 {
+				p.stack[len(p.stack)-1].inElse = true
+			}
+			return
+		case "end":
+			if len(p.stack) > 0 {
+				p.stack = p.stack[:len(p.stack)-1]
+			}
+			return
+		}
+	}
+	p.add(paragraphNode(text, actions))
+}
+
+// paragraphNode returns the node matching the rendered paragraph. Paragraphs with inline block actions match any
+// paragraph, their text depends on the data.
+func paragraphNode(text string, actions []templateAction) *extractNode {
+	node := &extractNode{text: text}
+	var pattern strings.Builder
+	pattern.WriteString(`^(?s)`)
+	pos := 0
+	for _, action := range actions {
+		literalPattern(&pattern, node, text[pos:action.Start])
+		pos = action.End
+		content := strings.TrimSpace(withoutTrimMarkers(text[action.Start+action.Left : action.End-action.Right]))
+		if kind, _ := blockActionKind(content); kind != "" {
+			return &extractNode{text: text, pattern: regexp.MustCompile(`^(?s).*$`), literal: -1}
+		}
+		if strings.HasPrefix(content, "/*") {
+			// comments have no output
+			continue
+		}
+		pattern.WriteString(`(.*?)`)
+		node.fields = append(node.fields, fieldPath(content))
+	}
+	literalPattern(&pattern, node, text[pos:])
+	pattern.WriteString(`$`)
+	node.pattern = regexp.MustCompile(pattern.String())
+	return node
+}
+
+// literalPattern appends the pattern of the text between actions, the placeholders of ReplaceAll in it are groups.
+func literalPattern(pattern *strings.Builder, node *extractNode, text string) {
+	pos := 0
+	for _, match := range stringPlaceholderRegex.FindAllStringIndex(text, -1) {
+		pattern.WriteString(regexp.QuoteMeta(text[pos:match[0]]))
+		node.literal += match[0] - pos
+		pattern.WriteString(`(.*?)`)
+		node.fields = append(node.fields, []string{text[match[0]+1 : match[1]-1]})
+		pos = match[1]
+	}
+	pattern.WriteString(regexp.QuoteMeta(text[pos:]))
+	node.literal += len(text) - pos
+}
+
+// fieldPath returns the names of the field referenced by the pipeline, e.g. [customer name] for .customer.name
+// or index . "customer" "name". The dot itself is the empty path, other pipelines return nil.
+func fieldPath(pipeline string) []string {
+	pipeline = strings.TrimSpace(pipeline)
+	switch {
+	case pipeline == ".":
+		return []string{}
+	case fieldChainRegex.MatchString(pipeline):
+		return strings.Split(pipeline[1:], ".")
+	case strings.HasPrefix(pipeline, "index . "):
+		var path []string
+		for _, quoted := range strings.Fields(strings.TrimPrefix(pipeline, "index . ")) {
+			name, err := strconv.Unquote(quoted)
+			if err != nil {
+				return nil
+			}
+			path = append(path, name)
+		}
+		return path
+	}
+	return nil
+}
+
+// extractMatcher matches the nodes of a template against the paragraphs of a document.
+type extractMatcher struct {
+	paragraphs []string
+	events     []matchEvent // the outcome for each paragraph of the document and template in document order
+}
+
+// matchEvent records that a paragraph of the document was matched or skipped or that a paragraph of the template
+// (node) was not found.
+type matchEvent struct {
+	node    *extractNode
+	index   int
+	matched bool
+}
+
+// newExtractMatcher returns a matcher for the paragraphs.
+func newExtractMatcher(paragraphs []*bodyParagraph) *extractMatcher {
+	m := &extractMatcher{}
+	for _, paragraph := range paragraphs {
+		m.paragraphs = append(m.paragraphs, paragraph.Text())
+	}
+	return m
+}
+
+// match matches the nodes against the paragraphs starting at pos and stores the values in data. It returns the
+// position behind the last matched paragraph. Paragraphs of the template which are not found are skipped. The
+// follows are the paragraphs which may follow the nodes, they end blocks, see startsBlock.
+func (m *extractMatcher) match(nodes []*extractNode, pos int, data map[string]interface{}, follows []*extractNode) int {
+	paragraphs := m.paragraphs
+	for i, node := range nodes {
+		if node.pattern != nil {
+			found := false
+			for j := pos; j < len(paragraphs) && !found; j++ {
+				match := node.pattern.FindStringSubmatch(paragraphs[j])
+				if match == nil {
+					continue
+				}
+				for k, field := range node.fields {
+					if field != nil {
+						setExtracted(data, field, match[k+1])
+					}
+				}
+				m.skip(j)
+				m.events = append(m.events, matchEvent{index: j, matched: true})
+				pos, found = j+1, true
+			}
+			if !found {
+				m.events = append(m.events, matchEvent{node: node})
+			}
+			continue
+		}
+
+		// a block is matched if its first paragraph follows
+		guard := firstParagraph(node.children)
+		if guard == nil {
+			continue
+		}
+		nodeFollows := follows
+		if next := firstParagraph(nodes[i+1:]); next != nil {
+			nodeFollows = []*extractNode{next}
+		}
+		switch node.kind {
+		case "range":
+			var items []interface{}
+			itemFollows := append([]*extractNode{guard}, nodeFollows...)
+			for pos < len(paragraphs) && startsBlock(guard, nodeFollows, paragraphs[pos]) {
+				item := make(map[string]interface{})
+				next := m.match(node.children, pos, item, itemFollows)
+				if next == pos {
+					break
+				}
+				if value, isValue := item[""]; isValue && len(item) == 1 {
+					// the range is over values, e.g. {{range .tags}}{{.}}{{end}}
+					items = append(items, value)
+				} else {
+					items = append(items, item)
+				}
+				pos = next
+			}
+			if node.field != nil && len(items) > 0 {
+				setExtracted(data, node.field, items)
+			}
+		case "with":
+			if pos < len(paragraphs) && startsBlock(guard, nodeFollows, paragraphs[pos]) {
+				scope := make(map[string]interface{})
+				pos = m.match(node.children, pos, scope, nodeFollows)
+				if node.field != nil {
+					setExtracted(data, node.field, scope)
+				}
+			}
+		default:
+			if pos < len(paragraphs) && startsBlock(guard, nodeFollows, paragraphs[pos]) {
+				pos = m.match(node.children, pos, data, nodeFollows)
+			}
+		}
+	}
+	return pos
+}
+
+// skip records the paragraphs before the given one which were not matched yet as skipped.
+func (m *extractMatcher) skip(end int) {
+	pos := 0
+	for i := len(m.events) - 1; i >= 0; i-- {
+		if m.events[i].node == nil {
+			pos = m.events[i].index + 1
+			break
+		}
+	}
+	for ; pos < end; pos++ {
+		m.events = append(m.events, matchEvent{index: pos})
+	}
+}
+
+// startsBlock reports whether the paragraph starts (another repetition of) the block with the given first
+// paragraph. Placeholders match any text, so the paragraph rather belongs to a following paragraph of the template
+// if that one matches as well and is more specific, e.g. "Total: {{.total}}" after a block starting with
+// "{{.item}}".
+func startsBlock(guard *extractNode, follows []*extractNode, paragraph string) bool {
+	if !guard.pattern.MatchString(paragraph) {
+		return false
+	}
+	for _, follow := range follows {
+		if follow.literal > guard.literal && follow.pattern.MatchString(paragraph) {
+			return false
+		}
+	}
+	return true
+}
+
+// firstParagraph returns the first paragraph of the nodes, nil if there is none.
+func firstParagraph(nodes []*extractNode) *extractNode {
+	for _, node := range nodes {
+		if node.pattern != nil {
+			return node
+		}
+		if paragraph := firstParagraph(node.children); paragraph != nil {
+			return paragraph
+		}
+	}
+	return nil
+}
+
+// setExtracted stores the value at the path, the first value found for a field is kept. The empty path stores the
+// value under the empty name, see extractMatcher.match.
+func setExtracted(data map[string]interface{}, path []string, value interface{}) {
+	if len(path) == 0 {
+		path = []string{""}
+	}
+	for _, name := range path[:len(path)-1] {
+		nested, isMap := data[name].(map[string]interface{})
+		if !isMap {
+			if _, exists := data[name]; exists {
+				return
+			}
+			nested = make(map[string]interface{})
+			data[name] = nested
+		}
+		data = nested
+	}
+	if _, exists := data[path[len(path)-1]]; !exists {
+		data[path[len(path)-1]] = value
+	}
+}
+
+// convertExtracted converts the extracted strings to the types of the schema.
+func convertExtracted(path string, value interface{}, schema *Schema) (interface{}, error) {
+	if schema == nil {
+		return value, nil
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for name, property := range v {
+			converted, err := convertExtracted(joinPath(path, name), property, schema.Properties[name])
+			if err != nil {
+				return nil, err
+			}
+			v[name] = converted
+		}
+	case []interface{}:
+		for i, item := range v {
+			converted, err := convertExtracted(path+"["+strconv.Itoa(i)+"]", item, schema.Items)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = converted
+		}
+	case string:
+		text := strings.TrimSpace(v)
+		switch {
+		case slices.Contains(schema.Type, "null") && text == "":
+			return nil, nil
+		case slices.Contains(schema.Type, "integer"):
+			n, err := strconv.ParseInt(text, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %q is not an integer", schemaPath(path), v)
+			}
+			return n, nil
+		case slices.Contains(schema.Type, "number"):
+			n, err := strconv.ParseFloat(text, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %q is not a number", schemaPath(path), v)
+			}
+			return n, nil
+		case slices.Contains(schema.Type, "boolean"):
+			b, err := strconv.ParseBool(text)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %q is not a boolean", schemaPath(path), v)
+			}
+			return b, nil
+		}
+	}
+	return value, nil
+}
@@ -0,0 +1,113 @@
+package docx
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExtract(t *testing.T) {
+	type item struct {
+		Name   string   `docx:"name"`
+		Amount float64  `docx:"amount"`
+		Tags   []string `docx:"tags"`
+	}
+	type offer struct {
+		Customer string `docx:"customer"`
+		City     string `docx:"city"`
+		Express  bool   `docx:"express"`
+		Items    []item `docx:"items"`
+	}
+	schema := SchemaFor(offer{})
+	template, err := GenerateTemplate(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := template.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	tpl, err := NewTemplate(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	filled, err := Render(tpl, offer{
+		Customer: "Anna Smith",
+		City:     "Zurich",
+		Express:  true,
+		Items:    []item{{Name: "Desk", Amount: 250.5, Tags: []string{"wood", "oak"}}, {Name: "Chair", Amount: 80}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the user edits the returned form
+	edited := strings.Replace(string(filled.GetFile(DocumentXml)), "Zurich", "Bern", 1)
+	if err := filled.SetFile(DocumentXml, []byte(edited)); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := Extract(template, filled, schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"customer": "Anna Smith",
+		"city":     "Bern",
+		"express":  true,
+		"items": []interface{}{
+			map[string]interface{}{"name": "Desk", "amount": 250.5, "tags": []interface{}{"wood", "oak"}},
+			map[string]interface{}{"name": "Chair", "amount": 80.0},
+		},
+	}
+	if !reflect.DeepEqual(data, expected) {
+		t.Errorf("expected %v, got %v", expected, data)
+	}
+
+	if data, err := Extract(template, filled, nil); err != nil || data.(map[string]interface{})["express"] != "true" {
+		t.Errorf("expected the values to be kept as strings without schema, got %v, %v", data, err)
+	}
+
+	edited = strings.Replace(edited, "250.5", "a lot", 1)
+	if err := filled.SetFile(DocumentXml, []byte(edited)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Extract(template, filled, schema); err == nil || !strings.Contains(err.Error(), `items[0].amount: "a lot" is not a number`) {
+		t.Errorf("expected an error for the amount, got %v", err)
+	}
+}
+
+func TestExtract_Rows(t *testing.T) {
+	template, err := NewDocument().
+		Paragraph("Invoice {number} for {{.customer.name}}").
+		Table([][]string{{"Item", "Amount"}, {"{{rows .lines}}{{.item}}", "{{.amount}}"}}).
+		Paragraph("Total: {{.total | printf \"%.2f\"}}").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	filled, err := NewDocument().
+		Paragraph("Invoice 2024-17 for Anna").
+		Table([][]string{{"Item", "Amount"}, {"Desk", "250"}, {"Chair", "80"}}).
+		Paragraph("Total: 330.00").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := Extract(template, filled, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]interface{}{
+		"number":   "2024-17",
+		"customer": map[string]interface{}{"name": "Anna"},
+		"lines": []interface{}{
+			map[string]interface{}{"item": "Desk", "amount": "250"},
+			map[string]interface{}{"item": "Chair", "amount": "80"},
+		},
+	}
+	if !reflect.DeepEqual(data, expected) {
+		t.Errorf("expected %v, got %v", expected, data)
+	}
+}
@@ -0,0 +1,688 @@
+package docx
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	// CorePropertiesRelationshipType is the relationship type of the core properties (docProps/core.xml).
+	CorePropertiesRelationshipType = "http://schemas.openxmlformats.org/package/2006/relationships/metadata/core-properties"
+	// ExtendedPropertiesRelationshipType is the relationship type of the extended properties (docProps/app.xml).
+	ExtendedPropertiesRelationshipType = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/extended-properties"
+	// CustomPropertiesRelationshipType is the relationship type of the custom properties (docProps/custom.xml).
+	CustomPropertiesRelationshipType = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/custom-properties"
+)
+
+var (
+	// fldSimpleRegex matches a simple field (<w:fldSimple>) including its cached result.
+	fldSimpleRegex = regexp.MustCompile(`(?s)<w:fldSimple\b[^>]*?(?:/>|>(.*?)</w:fldSimple>)`)
+	// complexFieldRegex matches the elements of complex fields: the field characters and the instructions.
+	complexFieldRegex = regexp.MustCompile(`(?s)<w:fldChar\b[^>]*?(?:/>|>.*?</w:fldChar>)|<w:instrText\b[^>]*>(.*?)</w:instrText>`)
+	// bookmarkTextRegex matches the start and the end of bookmarks (first group) and the text elements (second group).
+	bookmarkTextRegex = regexp.MustCompile(`(?s)<w:bookmark(Start|End)\b[^>]*>|<w:t(?:\s[^>]*)?>(.*?)</w:t>`)
+	// runPropertiesRegex matches the properties of a run.
+	runPropertiesRegex = regexp.MustCompile(`(?s)<w:rPr>.*?</w:rPr>`)
+)
+
+// corePropertyNames maps the elements of the core properties to the names used by DOCPROPERTY.
+var corePropertyNames = map[string]string{
+	"title":          "Title",
+	"subject":        "Subject",
+	"creator":        "Author",
+	"keywords":       "Keywords",
+	"description":    "Comments",
+	"category":       "Category",
+	"lastModifiedBy": "LastSavedBy",
+	"revision":       "RevisionNumber",
+	"created":        "CreateTime",
+	"modified":       "LastSavedTime",
+	"lastPrinted":    "LastPrinted",
+}
+
+// FieldOptions controls the evaluation of fields by UpdateFields.
+type FieldOptions struct {
+	Now        time.Time         // the time used by DATE and TIME, the current time if zero
+	FileName   string            // the file name used by FILENAME, the path of the opened document if empty
+	Properties map[string]string // document properties for DOCPROPERTY, they take precedence over the stored ones
+	Freeze     bool              // replace the fields by their results, so Word does not update them when opened
+}
+
+// UpdateFields evaluates the simple fields of the document body, headers and footers and replaces their cached
+// results: DATE, TIME, CREATEDATE, SAVEDATE, PRINTDATE, FILENAME, DOCPROPERTY and the property fields AUTHOR,
+// TITLE, SUBJECT, KEYWORDS, COMMENTS and LASTSAVEDBY. Date (\@) and text (\*) format switches are honored.
+// The captions are renumbered: SEQ fields are counted in the order of each part, so "Table 3" stays correct after
+// clauses, figures or tables were inserted or removed, and REF fields show the new text of the bookmarks they
+// refer to, e.g. "see Table 3". All other fields (e.g. PAGE or TOC) as well as fields containing other fields are
+// left unchanged, see WriteOptions.UpdateFieldsOnOpen to have Word update them.
+func (d *Document) UpdateFields(options FieldOptions) error {
+	if options.Now.IsZero() {
+		options.Now = time.Now()
+	}
+	if options.FileName == "" {
+		options.FileName = d.path
+	}
+	properties := d.documentProperties()
+	for name, value := range options.Properties {
+		properties[strings.ToLower(name)] = value
+	}
+
+	err := d.updateFieldResults(options.Freeze, func(data []byte) fieldEvaluator {
+		sequences := sequenceResults(data)
+		return func(offset int, instruction string) (string, bool) {
+			if result, exists := sequences[offset]; exists {
+				return result, true
+			}
+			return evaluateField(instruction, options, properties)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	// the references are updated last, so they show the new numbers of the captions
+	bookmarks := bookmarkTexts(d.GetFile(DocumentXml))
+	return d.updateFieldResults(options.Freeze, func([]byte) fieldEvaluator {
+		return func(_ int, instruction string) (string, bool) {
+			return evaluateReference(instruction, bookmarks)
+		}
+	})
+}
+
+// fieldEvaluator returns the result of the field with the instruction which starts at the offset of the part, false
+// if the field is left unchanged.
+type fieldEvaluator func(offset int, instruction string) (string, bool)
+
+// updateFieldResults replaces the results of the fields of all parts with the results of the evaluator created for
+// each part.
+func (d *Document) updateFieldResults(freeze bool, evaluator func(data []byte) fieldEvaluator) error {
+	for _, fileName := range d.xmlParts() {
+		data := d.GetFile(fileName)
+		replacements := updateFieldReplacements(data, evaluator(data), freeze)
+		if len(replacements) == 0 {
+			continue
+		}
+		if err := d.SetFile(fileName, applyReplacements(data, replacements)); err != nil {
+			return err
+		}
+		if err := d.refreshRuns(fileName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateFieldReplacements returns the sorted replacements which update the results of all fields of the part
+// which can be evaluated. Frozen fields are replaced by their results.
+func updateFieldReplacements(data []byte, evaluate fieldEvaluator, freeze bool) []replacement {
+	var replacements []replacement
+
+	for _, loc := range fldSimpleRegex.FindAllSubmatchIndex(data, -1) {
+		field := data[loc[0]:loc[1]]
+		openTag := field[:bytes.IndexByte(field, '>')+1]
+		instruction, _ := getTagAttr(openTag, "w:instr")
+		result, ok := evaluate(loc[0], instruction)
+		if !ok {
+			continue
+		}
+
+		var content []byte
+		if loc[2] >= 0 {
+			content = data[loc[2]:loc[3]]
+		}
+		if bytes.Contains(content, []byte("<w:p")) {
+			continue
+		}
+		run := fieldResultRun(runPropertiesRegex.Find(content), result)
+		if !freeze {
+			tag := string(openTag)
+			if strings.HasSuffix(tag, "/>") {
+				tag = strings.TrimSuffix(tag, "/>") + ">"
+			}
+			run = []byte(tag + string(run) + "</w:fldSimple>")
+		}
+		replacements = append(replacements, replacement{int64(loc[0]), int64(loc[1]), run})
+	}
+
+	type complexField struct {
+		begin, separate, endStart, endEnd int
+		instruction                       strings.Builder
+		nested                            bool
+	}
+	var open []*complexField
+	for _, loc := range complexFieldRegex.FindAllSubmatchIndex(data, -1) {
+		element := data[loc[0]:loc[1]]
+		if loc[2] >= 0 {
+			if len(open) > 0 {
+				open[len(open)-1].instruction.WriteString(html.UnescapeString(string(data[loc[2]:loc[3]])))
+			}
+			continue
+		}
+
+		fieldType, _ := getTagAttr(element, "w:fldCharType")
+		switch fieldType {
+		case "begin":
+			if len(open) > 0 {
+				open[len(open)-1].nested = true
+			}
+			open = append(open, &complexField{begin: runStart(data, loc[0])})
+		case "separate":
+			if len(open) > 0 {
+				open[len(open)-1].separate = runEnd(data, loc[1])
+			}
+		case "end":
+			if len(open) == 0 {
+				continue
+			}
+			field := open[len(open)-1]
+			open = open[:len(open)-1]
+			field.endStart, field.endEnd = runStart(data, loc[0]), runEnd(data, loc[1])
+			if field.nested || field.begin < 0 || field.endStart < 0 || field.endEnd < 0 {
+				continue
+			}
+
+			result, ok := evaluate(field.begin, field.instruction.String())
+			if !ok {
+				continue
+			}
+			cached := data[field.endStart:field.endStart]
+			if field.separate > 0 {
+				cached = data[field.separate:field.endStart]
+			}
+			if bytes.Contains(cached, []byte("<w:p")) {
+				continue
+			}
+
+			properties := runPropertiesRegex.Find(cached)
+			if properties == nil {
+				properties = runPropertiesRegex.Find(data[field.begin:runEnd(data, field.begin)])
+			}
+			run := fieldResultRun(properties, result)
+			switch {
+			case freeze:
+				replacements = append(replacements, replacement{int64(field.begin), int64(field.endEnd), run})
+			case field.separate > 0:
+				replacements = append(replacements, replacement{int64(field.separate), int64(field.endStart), run})
+			default:
+				run = append([]byte(`<w:r><w:fldChar w:fldCharType="separate"/></w:r>`), run...)
+				replacements = append(replacements, replacement{int64(field.endStart), int64(field.endStart), run})
+			}
+		}
+	}
+
+	sort.Slice(replacements, func(i, j int) bool {
+		return replacements[i].Start < replacements[j].Start
+	})
+	// a simple field might be part of the result of a complex field, keep the outer replacement
+	var filtered []replacement
+	for _, r := range replacements {
+		if n := len(filtered); n > 0 && r.Start < filtered[n-1].End {
+			continue
+		}
+		filtered = append(filtered, r)
+	}
+	return filtered
+}
+
+// fieldResultRun returns a run with the given properties which shows the result of a field.
+func fieldResultRun(properties []byte, result string) []byte {
+	return []byte(`<w:r>` + string(properties) + `<w:t xml:space="preserve">` + xmlEscape(result) + `</w:t></w:r>`)
+}
+
+// runStart returns the offset of the open tag of the run containing the given offset, or -1.
+func runStart(data []byte, offset int) int {
+	start := -1
+	for _, tag := range []string{"<w:r>", "<w:r "} {
+		if i := bytes.LastIndex(data[:offset], []byte(tag)); i > start {
+			start = i
+		}
+	}
+	return start
+}
+
+// runEnd returns the offset behind the close tag of the run containing the given offset, or -1.
+func runEnd(data []byte, offset int) int {
+	i := bytes.Index(data[offset:], []byte("</w:r>"))
+	if i < 0 {
+		return -1
+	}
+	return offset + i + len("</w:r>")
+}
+
+// evaluateField evaluates the instruction of a field, e.g. DATE \@ "dd.MM.yyyy". The result is false
+// if the field is not supported or refers to a property which does not exist.
+func evaluateField(instruction string, options FieldOptions, properties map[string]string) (string, bool) {
+	args, switches := splitFieldInstruction(instruction)
+	if len(args) == 0 {
+		return "", false
+	}
+
+	var result string
+	switch name := strings.ToUpper(args[0]); name {
+	case "DATE":
+		result = formatFieldDate(options.Now, switches["@"], "M/d/yyyy")
+	case "TIME":
+		result = formatFieldDate(options.Now, switches["@"], "h:mm AM/PM")
+	case "CREATEDATE", "SAVEDATE", "PRINTDATE":
+		property := map[string]string{"CREATEDATE": "createtime", "SAVEDATE": "lastsavedtime", "PRINTDATE": "lastprinted"}[name]
+		date, err := time.Parse(time.RFC3339, properties[property])
+		if err != nil {
+			return "", false
+		}
+		result = formatFieldDate(date.In(options.Now.Location()), switches["@"], "M/d/yyyy h:mm:ss AM/PM")
+	case "FILENAME":
+		if options.FileName == "" {
+			return "", false
+		}
+		result = filepath.Base(options.FileName)
+		if _, fullPath := switches["p"]; fullPath {
+			result = options.FileName
+		}
+	case "DOCPROPERTY":
+		if len(args) < 2 {
+			return "", false
+		}
+		value, exists := properties[strings.ToLower(args[1])]
+		if !exists {
+			return "", false
+		}
+		result = value
+		if date, err := time.Parse(time.RFC3339, value); err == nil && switches["@"] != "" {
+			result = formatFieldDate(date.In(options.Now.Location()), switches["@"], "")
+		}
+	case "AUTHOR", "TITLE", "SUBJECT", "KEYWORDS", "COMMENTS", "LASTSAVEDBY":
+		value, exists := properties[strings.ToLower(name)]
+		if !exists {
+			return "", false
+		}
+		result = value
+	default:
+		return "", false
+	}
+	return formatFieldText(result, switches["*"]), true
+}
+
+// sequenceResults numbers the SEQ fields of the part in document order and returns their results by the offsets of
+// the fields. Sequences using \s, which restart at each heading of a level, are left unchanged.
+func sequenceResults(data []byte) map[int]string {
+	type sequenceField struct {
+		offset      int
+		instruction string
+	}
+	var fields []sequenceField
+	updateFieldReplacements(data, func(offset int, instruction string) (string, bool) {
+		if args, _ := splitFieldInstruction(instruction); len(args) > 1 && strings.EqualFold(args[0], "SEQ") {
+			fields = append(fields, sequenceField{offset, instruction})
+		}
+		return "", false
+	}, false)
+	sort.Slice(fields, func(i, j int) bool {
+		return fields[i].offset < fields[j].offset
+	})
+
+	unsupported := make(map[string]bool)
+	for _, field := range fields {
+		if args, switches := splitFieldInstruction(field.instruction); hasSwitch(switches, "s") {
+			unsupported[strings.ToLower(args[1])] = true
+		}
+	}
+	results := make(map[int]string)
+	counters := make(map[string]int)
+	for _, field := range fields {
+		args, switches := splitFieldInstruction(field.instruction)
+		identifier := strings.ToLower(args[1])
+		if unsupported[identifier] {
+			continue
+		}
+		switch {
+		case hasSwitch(switches, "r") && len(args) > 2:
+			n, err := strconv.Atoi(args[2])
+			if err != nil {
+				continue
+			}
+			counters[identifier] = n
+		case hasSwitch(switches, "c"):
+		default:
+			counters[identifier]++
+		}
+		if hasSwitch(switches, "h") {
+			results[field.offset] = ""
+			continue
+		}
+		results[field.offset] = formatFieldText(formatSequenceNumber(counters[identifier], switches["*"]), switches["*"])
+	}
+	return results
+}
+
+// hasSwitch returns true if the instruction has the switch, see splitFieldInstruction.
+func hasSwitch(switches map[string]string, name string) bool {
+	_, exists := switches[name]
+	return exists
+}
+
+// formatSequenceNumber formats the number of a SEQ field with the numbering format switch (\*): ARABIC, ROMAN or
+// ALPHABETIC, written in lower case for lower case letters, e.g. \* roman. Other formats use arabic numbers.
+func formatSequenceNumber(n int, format string) string {
+	lower := format == strings.ToLower(format)
+	var result string
+	switch strings.ToLower(format) {
+	case "roman":
+		numeral, err := romanHelper(n)
+		if err != nil {
+			return strconv.Itoa(n)
+		}
+		result = numeral
+	case "alphabetic":
+		if n < 1 {
+			return strconv.Itoa(n)
+		}
+		// Word repeats the letter after Z: AA, BB, ...
+		result = strings.Repeat(string(rune('A'+(n-1)%26)), (n-1)/26+1)
+	default:
+		return strconv.Itoa(n)
+	}
+	if lower {
+		return strings.ToLower(result)
+	}
+	return result
+}
+
+// bookmarkTexts returns the text of the bookmarks of the part by their names. Field instructions and deleted text
+// are left out, the results of fields are part of the text.
+func bookmarkTexts(data []byte) map[string]string {
+	texts := make(map[string]string)
+	open := make(map[string]string) // the names of the open bookmarks by their ids
+	contents := make(map[string]*strings.Builder)
+	for _, match := range bookmarkTextRegex.FindAllSubmatch(data, -1) {
+		tag := match[0]
+		id, _ := getTagAttr(tag, "w:id")
+		switch string(match[1]) {
+		case "Start":
+			if name, exists := getTagAttr(tag, "w:name"); exists {
+				open[id] = name
+				contents[id] = &strings.Builder{}
+			}
+		case "End":
+			if name, exists := open[id]; exists {
+				texts[name] = contents[id].String()
+				delete(open, id)
+			}
+		default:
+			for id := range open {
+				contents[id].WriteString(html.UnescapeString(string(match[2])))
+			}
+		}
+	}
+	return texts
+}
+
+// evaluateReference evaluates a REF field with the text of the bookmark it refers to. References to the numbers of
+// paragraphs or to footnotes (\f, \n, \p, \r, \w) are not supported.
+func evaluateReference(instruction string, bookmarks map[string]string) (string, bool) {
+	args, switches := splitFieldInstruction(instruction)
+	if len(args) < 2 || !strings.EqualFold(args[0], "REF") {
+		return "", false
+	}
+	for _, name := range []string{"f", "n", "p", "r", "w"} {
+		if hasSwitch(switches, name) {
+			return "", false
+		}
+	}
+	text, exists := bookmarks[args[1]]
+	if !exists {
+		return "", false
+	}
+	return formatFieldText(text, switches["*"]), true
+}
+
+// splitFieldInstruction splits the instruction of a field into its arguments (including the field name)
+// and its switches. Quoted arguments are unquoted, switches are returned without the backslash.
+func splitFieldInstruction(instruction string) ([]string, map[string]string) {
+	var tokens []string
+	var current strings.Builder
+	inQuotes, quoted := false, false
+	flush := func() {
+		if current.Len() > 0 || quoted {
+			tokens = append(tokens, current.String())
+		}
+		current.Reset()
+		quoted = false
+	}
+	for _, r := range instruction {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+			quoted = true
+		case unicode.IsSpace(r) && !inQuotes:
+			flush()
+		default:
+			current.WriteRune(r)
+		}
+	}
+	flush()
+
+	var args []string
+	switches := make(map[string]string)
+	for i := 0; i < len(tokens); i++ {
+		if !strings.HasPrefix(tokens[i], `\`) || len(tokens[i]) < 2 {
+			args = append(args, tokens[i])
+			continue
+		}
+		name := tokens[i][1:]
+		switch {
+		case name == "@" || name == "*" || name == "#":
+			if i+1 < len(tokens) {
+				// \* switches may be given multiple times, e.g. \* Upper \* MERGEFORMAT
+				if switches[name] == "" || strings.EqualFold(switches[name], "MERGEFORMAT") {
+					switches[name] = tokens[i+1]
+				}
+				i++
+			}
+		default:
+			switches[name] = ""
+		}
+	}
+	return args, switches
+}
+
+// formatFieldText applies a text format switch (\* Upper, \* Lower, \* Caps or \* FirstCap) to the result.
+func formatFieldText(result, format string) string {
+	switch strings.ToLower(format) {
+	case "upper":
+		return strings.ToUpper(result)
+	case "lower":
+		return strings.ToLower(result)
+	case "caps":
+		words := strings.Fields(result)
+		for i, word := range words {
+			runes := []rune(strings.ToLower(word))
+			runes[0] = unicode.ToUpper(runes[0])
+			words[i] = string(runes)
+		}
+		return strings.Join(words, " ")
+	case "firstcap":
+		runes := []rune(result)
+		if len(runes) > 0 {
+			runes[0] = unicode.ToUpper(runes[0])
+		}
+		return string(runes)
+	}
+	return result
+}
+
+// formatFieldDate formats the time with a date picture (\@ switch) such as dddd, MMMM d, yyyy or HH:mm.
+// The default picture is used if the picture is empty.
+func formatFieldDate(t time.Time, picture, defaultPicture string) string {
+	if picture == "" {
+		picture = defaultPicture
+	}
+	if picture == "" {
+		return t.Format(time.RFC3339)
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(picture); {
+		c := picture[i]
+		if c == '\'' {
+			end := strings.IndexByte(picture[i+1:], '\'')
+			if end < 0 {
+				sb.WriteString(picture[i+1:])
+				break
+			}
+			sb.WriteString(picture[i+1 : i+1+end])
+			i += end + 2
+			continue
+		}
+		if strings.HasPrefix(picture[i:], "AM/PM") || strings.HasPrefix(picture[i:], "am/pm") {
+			value := t.Format("PM")
+			if c == 'a' {
+				value = strings.ToLower(value)
+			}
+			sb.WriteString(value)
+			i += len("AM/PM")
+			continue
+		}
+
+		n := 1
+		for i+n < len(picture) && picture[i+n] == c {
+			n++
+		}
+		switch c {
+		case 'y', 'Y':
+			if n <= 2 {
+				fmt.Fprintf(&sb, "%02d", t.Year()%100)
+			} else {
+				fmt.Fprintf(&sb, "%04d", t.Year())
+			}
+		case 'M':
+			switch n {
+			case 1:
+				fmt.Fprintf(&sb, "%d", int(t.Month()))
+			case 2:
+				fmt.Fprintf(&sb, "%02d", int(t.Month()))
+			case 3:
+				sb.WriteString(t.Month().String()[:3])
+			default:
+				sb.WriteString(t.Month().String())
+			}
+		case 'd', 'D':
+			switch n {
+			case 1:
+				fmt.Fprintf(&sb, "%d", t.Day())
+			case 2:
+				fmt.Fprintf(&sb, "%02d", t.Day())
+			case 3:
+				sb.WriteString(t.Weekday().String()[:3])
+			default:
+				sb.WriteString(t.Weekday().String())
+			}
+		case 'H', 'h', 'm', 's':
+			value := map[byte]int{'H': t.Hour(), 'h': (t.Hour()+11)%12 + 1, 'm': t.Minute(), 's': t.Second()}[c]
+			if n >= 2 {
+				fmt.Fprintf(&sb, "%02d", value)
+			} else {
+				fmt.Fprintf(&sb, "%d", value)
+			}
+		default:
+			sb.WriteString(picture[i : i+n])
+		}
+		i += n
+	}
+	return sb.String()
+}
+
+// documentProperties returns the core, extended and custom properties of the document by their lowercase
+// DOCPROPERTY names, e.g. author or company.
+func (d *Document) documentProperties() map[string]string {
+	properties := make(map[string]string)
+	partName := func(relType, defaultName string) string {
+		if targets := d.relationshipTargets("", relType); len(targets) > 0 {
+			return targets[0]
+		}
+		return defaultName
+	}
+
+	if data, exists := d.loadPackageFile(partName(CorePropertiesRelationshipType, "docProps/core.xml")); exists {
+		for name, value := range leafElements(data) {
+			if property, known := corePropertyNames[name]; known {
+				properties[strings.ToLower(property)] = value
+			}
+		}
+	}
+	if data, exists := d.loadPackageFile(partName(ExtendedPropertiesRelationshipType, "docProps/app.xml")); exists {
+		for name, value := range leafElements(data) {
+			properties[strings.ToLower(name)] = value
+		}
+	}
+	if data, exists := d.loadPackageFile(partName(CustomPropertiesRelationshipType, "docProps/custom.xml")); exists {
+		for name, value := range customProperties(data) {
+			properties[strings.ToLower(name)] = value
+		}
+	}
+	return properties
+}
+
+// leafElements returns the text of all elements without child elements by their local name.
+func leafElements(data []byte) map[string]string {
+	elements := make(map[string]string)
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	var name string
+	var text strings.Builder
+	for {
+		tok, err := decoder.Token()
+		if err != nil {
+			return elements
+		}
+		switch elem := tok.(type) {
+		case xml.StartElement:
+			name = elem.Name.Local
+			text.Reset()
+		case xml.CharData:
+			text.Write(elem)
+		case xml.EndElement:
+			if name == elem.Name.Local {
+				elements[name] = text.String()
+			}
+			name = ""
+		}
+	}
+}
+
+// customProperties returns the values of the custom properties (docProps/custom.xml) by their names.
+func customProperties(data []byte) map[string]string {
+	properties := make(map[string]string)
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	var property string
+	var text strings.Builder
+	for {
+		tok, err := decoder.Token()
+		if err != nil {
+			return properties
+		}
+		switch elem := tok.(type) {
+		case xml.StartElement:
+			if elem.Name.Local == "property" {
+				property = attrValue(elem, "name")
+				text.Reset()
+			}
+		case xml.CharData:
+			if property != "" {
+				text.Write(elem)
+			}
+		case xml.EndElement:
+			if elem.Name.Local == "property" {
+				properties[property] = strings.TrimSpace(text.String())
+				property = ""
+			}
+		}
+	}
+}
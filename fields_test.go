@@ -0,0 +1,147 @@
+package docx
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDocument_UpdateFields(t *testing.T) {
+	complexField := func(instruction, cached string) string {
+		return `<w:p><w:r><w:rPr><w:b/></w:rPr><w:fldChar w:fldCharType="begin"/></w:r>` +
+			`<w:r><w:instrText xml:space="preserve">` + instruction + `</w:instrText></w:r>` +
+			`<w:r><w:fldChar w:fldCharType="separate"/></w:r>` +
+			`<w:r><w:rPr><w:i/></w:rPr><w:t>` + cached + `</w:t></w:r>` +
+			`<w:r><w:fldChar w:fldCharType="end"/></w:r></w:p>`
+	}
+	body := complexField(` DATE \@ &quot;dddd, d. MMMM yyyy HH:mm&quot; `, "old date") +
+		complexField(` DOCPROPERTY  "Project Name" \* Upper \* MERGEFORMAT `, "old project") +
+		complexField(` CREATEDATE \@ "yyyy-MM-dd" `, "old created") +
+		complexField(` PAGE `, "7") +
+		`<w:p><w:fldSimple w:instr=" FILENAME "><w:r><w:t>old.docx</w:t></w:r></w:fldSimple></w:p>` +
+		`<w:p><w:fldSimple w:instr=" DOCPROPERTY Missing "><w:r><w:t>missing</w:t></w:r></w:fldSimple></w:p>`
+	custom := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<Properties xmlns="http://schemas.openxmlformats.org/officeDocument/2006/custom-properties" xmlns:vt="http://schemas.openxmlformats.org/officeDocument/2006/docPropsVTypes">` +
+		`<property fmtid="{D5CDD505-2E9C-101B-9397-08002B2CF9AE}" pid="2" name="Project Name"><vt:lpwstr>Apollo</vt:lpwstr></property></Properties>`
+
+	modify := func(name string, data []byte) []byte {
+		if name == DocumentXml {
+			return []byte(strings.Replace(string(data), "<w:body>", "<w:body>"+body, 1))
+		}
+		return data
+	}
+	open := func(t *testing.T) *Document {
+		doc, err := OpenBytes(rewriteArchive(t, readFile(t, "./test/template.docx"), modify, map[string][]byte{"docProps/custom.xml": []byte(custom)}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return doc
+	}
+	options := FieldOptions{
+		Now:      time.Date(2024, time.March, 5, 14, 7, 0, 0, time.UTC),
+		FileName: "/tmp/contracts/offer.docx",
+	}
+
+	t.Run("update", func(t *testing.T) {
+		doc := open(t)
+		defer doc.Close()
+		if err := doc.UpdateFields(options); err != nil {
+			t.Fatal(err)
+		}
+		documentXml := string(doc.GetFile(DocumentXml))
+		for _, expected := range []string{
+			`<w:r><w:rPr><w:i/></w:rPr><w:t xml:space="preserve">Tuesday, 5. March 2024 14:07</w:t></w:r>`,
+			">APOLLO<", ">2020-09-07<", ">7<", ">offer.docx<", ">missing<",
+		} {
+			if !strings.Contains(documentXml, expected) {
+				t.Errorf("expected %q in the document", expected)
+			}
+		}
+		if strings.Contains(documentXml, "old ") {
+			t.Errorf("cached results were not replaced")
+		}
+		if count := strings.Count(documentXml, `w:fldCharType="begin"`); count != 4 {
+			t.Errorf("expected the fields to be kept, found %d", count)
+		}
+	})
+
+	t.Run("freeze", func(t *testing.T) {
+		doc := open(t)
+		defer doc.Close()
+		options := options
+		options.Freeze = true
+		options.Properties = map[string]string{"project name": "Gemini"}
+		if err := doc.UpdateFields(options); err != nil {
+			t.Fatal(err)
+		}
+		documentXml := string(doc.GetFile(DocumentXml))
+		if !strings.Contains(documentXml, ">GEMINI<") || !strings.Contains(documentXml, `<w:p><w:r><w:t xml:space="preserve">offer.docx</w:t></w:r></w:p>`) {
+			t.Errorf("fields were not frozen")
+		}
+		if count := strings.Count(documentXml, `w:fldCharType="begin"`); count != 1 {
+			t.Errorf("expected only the PAGE field to remain, found %d fields", count)
+		}
+		if !strings.Contains(documentXml, `w:instr=" DOCPROPERTY Missing "`) {
+			t.Errorf("unknown properties must not be frozen")
+		}
+	})
+}
+
+func TestDocument_UpdateFieldsCaptions(t *testing.T) {
+	simpleField := func(instruction, cached string) string {
+		return `<w:fldSimple w:instr="` + instruction + `"><w:r><w:t>` + cached + `</w:t></w:r></w:fldSimple>`
+	}
+	complexField := func(instruction, cached string) string {
+		return `<w:r><w:fldChar w:fldCharType="begin"/></w:r><w:r><w:instrText xml:space="preserve">` + instruction + `</w:instrText></w:r>` +
+			`<w:r><w:fldChar w:fldCharType="separate"/></w:r><w:r><w:t>` + cached + `</w:t></w:r><w:r><w:fldChar w:fldCharType="end"/></w:r>`
+	}
+	// the second caption was numbered before a table was inserted in front of it
+	body := `<w:p><w:r><w:t xml:space="preserve">Table </w:t></w:r>` + simpleField(` SEQ Table \* ARABIC `, "1") + `</w:p>` +
+		`<w:p><w:bookmarkStart w:id="0" w:name="_Ref2"/><w:r><w:t xml:space="preserve">Table </w:t></w:r>` +
+		complexField(` SEQ Table \* ARABIC `, "1") + `<w:bookmarkEnd w:id="0"/><w:r><w:t>: Prices</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t xml:space="preserve">See </w:t></w:r>` + complexField(` REF _Ref2 \h `, "Table 1") + `</w:p>` +
+		`<w:p>` + simpleField(` REF _Ref2 \* Upper `, "TABLE 1") + simpleField(` REF _Ref2 \n `, "1") + simpleField(` REF Missing `, "?") + `</w:p>` +
+		`<w:p>` + simpleField(` SEQ Figure \* ROMAN `, "9") + simpleField(` SEQ Figure \c \* roman `, "9") + simpleField(` SEQ Figure \* ALPHABETIC `, "9") + `</w:p>` +
+		`<w:p>` + simpleField(` SEQ Table \r 5 `, "9") + simpleField(` SEQ Table \h `, "9") + simpleField(` SEQ Equation \s 1 `, "9") + `</w:p>`
+	doc, err := OpenBytes(rewriteArchive(t, readFile(t, "./test/template.docx"), func(name string, data []byte) []byte {
+		if name == DocumentXml {
+			return []byte(strings.Replace(string(data), "<w:body>", "<w:body>"+body, 1))
+		}
+		return data
+	}, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+	if err := doc.UpdateFields(FieldOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	texts, err := paragraphTexts(doc.GetFile(DocumentXml))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"Table 1", "Table 2: Prices", "See Table 2", "TABLE 21?", "IiB", "59"}
+	for i, text := range expected {
+		if i >= len(texts) || texts[i] != text {
+			t.Errorf("paragraph %d: expected %q, got %q", i, text, texts)
+			break
+		}
+	}
+}
+
+func TestFormatFieldDate(t *testing.T) {
+	date := time.Date(2024, time.January, 9, 8, 5, 3, 0, time.UTC)
+	tests := map[string]string{
+		"dd.MM.yy":               "09.01.24",
+		"d/M/yyyy h:mm:ss am/pm": "9/1/2024 8:05:03 am",
+		"ddd, MMM d 'at' HH:mm":  "Tue, Jan 9 at 08:05",
+		"hh 'o''clock' AM/PM":    "08 oclock AM",
+		"":                       "1/9/2024",
+	}
+	for picture, expected := range tests {
+		if result := formatFieldDate(date, picture, "M/d/yyyy"); result != expected {
+			t.Errorf("%q: expected %q, got %q", picture, expected, result)
+		}
+	}
+}
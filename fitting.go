@@ -0,0 +1,273 @@
+package docx
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// fitting.go implements the helpers which fit values into a fixed layout, e.g. the name on a certificate:
+// {{fit}} truncates a value, {{wrap}} breaks it into lines and {{shrink}} reduces the font size of a value which
+// is wider than its placeholder allows. The widths are given in characters.
+
+var (
+	// shrinkMarkerRegex matches a value wrapped by shrinkHelper, the groups are the width, the length and the value.
+	shrinkMarkerRegex = regexp.MustCompile(`(?s)\[\[docx-shrink ([0-9]+)/([0-9]+)\]\](.*?)\[\[docx-shrink-end\]\]`)
+	// fontSizeRegex matches the font size of run properties in half-points.
+	fontSizeRegex = regexp.MustCompile(`<w:sz\s+w:val="([0-9]+)"`)
+	// complexFontSizeRegex matches the font size of the complex script of run properties.
+	complexFontSizeRegex = regexp.MustCompile(`<w:szCs\b[^>]*/>`)
+	// runStyleRegex matches the character style of a run, the group is the style id.
+	runStyleRegex = regexp.MustCompile(`<w:rStyle\s+w:val="([^"]*)"`)
+)
+
+const (
+	// ellipsis is appended to the values truncated by {{fit}}.
+	ellipsis = "…"
+	// defaultFontSize is the font size in half-points of runs without a size in their properties and styles.
+	defaultFontSize = 20
+	// minFontSize is the smallest font size in half-points {{shrink}} reduces values to.
+	minFontSize = 2
+)
+
+// helperText returns the text of a helper argument, nil is empty.
+func helperText(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+// fitHelper implements {{fit VALUE WIDTH}}, e.g. {{fit .title 60}}: values longer than the width are truncated
+// and end with an ellipsis, so the result has at most WIDTH characters.
+func fitHelper(value interface{}, width int) (string, error) {
+	if width < 1 {
+		return "", fmt.Errorf("fit: the width must be at least 1, got %d", width)
+	}
+	text := helperText(value)
+	runes := []rune(text)
+	if len(runes) <= width {
+		return text, nil
+	}
+	return strings.TrimRightFunc(string(runes[:width-1]), unicode.IsSpace) + ellipsis, nil
+}
+
+// wrapHelper implements {{wrap VALUE WIDTH}}, e.g. {{wrap .desc 80}}: the value is broken into lines of at most
+// WIDTH characters at spaces, words longer than a line are split. The lines are separated by line breaks of the
+// run, line breaks of the value are kept.
+func (tr *TemplateReplacer) wrapHelper(value interface{}, width int) (rawXML, error) {
+	if width < 1 {
+		return "", fmt.Errorf("wrap: the width must be at least 1, got %d", width)
+	}
+	text, err := sanitizeValue(helperText(value), tr.document.invalidChars)
+	if err != nil {
+		return "", fmt.Errorf("wrap: %w", err)
+	}
+	lines := wrapText(text, width)
+	for i, line := range lines {
+		lines[i] = escapeMarkers(xmlEscape(line))
+	}
+	return rawXML(strings.Join(lines, `</w:t><w:br/><w:t xml:space="preserve">`)), nil
+}
+
+// wrapText breaks the text into lines of at most width characters.
+func wrapText(text string, width int) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			// words longer than a line are split into lines of their own
+			for utf8.RuneCountInString(word) > width {
+				if line != "" {
+					lines = append(lines, line)
+					line = ""
+				}
+				runes := []rune(word)
+				lines = append(lines, string(runes[:width]))
+				word = string(runes[width:])
+			}
+			switch {
+			case word == "":
+			case line == "":
+				line = word
+			case utf8.RuneCountInString(line)+1+utf8.RuneCountInString(word) <= width:
+				line += " " + word
+			default:
+				lines = append(lines, line)
+				line = word
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// shrinkHelper implements {{shrink VALUE WIDTH}}, e.g. {{shrink .name 30}}: a value longer than the width is put
+// into a run of its own whose font size is reduced by the ratio of the width to the length of the value, so it
+// takes about the space of WIDTH characters. Shorter values are inserted unchanged.
+func (tr *TemplateReplacer) shrinkHelper(value interface{}, width int) (rawXML, error) {
+	if width < 1 {
+		return "", fmt.Errorf("shrink: the width must be at least 1, got %d", width)
+	}
+	text, err := sanitizeValue(helperText(value), tr.document.invalidChars)
+	if err != nil {
+		return "", fmt.Errorf("shrink: %w", err)
+	}
+	length := utf8.RuneCountInString(text)
+	if length <= width {
+		return rawXML(escapeMarkers(xmlEscape(text))), nil
+	}
+	return rawXML(fmt.Sprintf("[[docx-shrink %d/%d]]%s[[docx-shrink-end]]", width, length, escapeMarkers(xmlEscape(text)))), nil
+}
+
+// resolveFontSizes resolves the markers written by shrinkHelper in all XML parts.
+func (tr *TemplateReplacer) resolveFontSizes() error {
+	styles, _ := tr.document.loadPackageFile(StylesXml)
+	for _, fileName := range tr.document.xmlParts() {
+		data := tr.document.GetFile(fileName)
+		if !shrinkMarkerRegex.Match(data) {
+			continue
+		}
+		if err := tr.document.SetFile(fileName, resolveShrinkMarkers(data, styles)); err != nil {
+			return err
+		}
+		if err := tr.document.refreshRuns(fileName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveShrinkMarkers puts each value wrapped by shrinkHelper into a run of its own between the parts of the run
+// of its placeholder. The run keeps the formatting of the placeholder with a reduced font size. Values outside of
+// runs are inserted without their markers.
+func resolveShrinkMarkers(data, styles []byte) []byte {
+	for offset := 0; ; {
+		loc := shrinkMarkerRegex.FindSubmatchIndex(data[offset:])
+		if loc == nil {
+			return data
+		}
+		for i := range loc {
+			loc[i] += offset
+		}
+		width, _ := strconv.Atoi(string(data[loc[2]:loc[3]]))
+		length, _ := strconv.Atoi(string(data[loc[4]:loc[5]]))
+		value := data[loc[6]:loc[7]]
+
+		run := runStart(data, loc[0])
+		text := textStart(data, loc[0])
+		if run < 0 || text < run || length < 1 {
+			data = applyReplacements(data, []replacement{{int64(loc[0]), int64(loc[1]), value}})
+			offset = loc[0] + len(value)
+			continue
+		}
+
+		properties := runPropertiesRegex.Find(data[run:text])
+		size := fontSize(styles, paragraphProperties(data, run), properties) * width / length
+		if size < minFontSize {
+			size = minFontSize
+		}
+		textEnd := text + bytes.IndexByte(data[text:], '>') + 1
+		textTag := setTagAttr(data[text:textEnd], "xml:space", "preserve")
+
+		var split []byte
+		split = append(split, textTag...)
+		split = append(split, data[textEnd:loc[0]]...)
+		split = append(split, "</w:t></w:r><w:r>"...)
+		split = append(split, sizedRunProperties(properties, size)...)
+		split = append(split, `<w:t xml:space="preserve">`...)
+		split = append(split, value...)
+		split = append(split, "</w:t></w:r>"...)
+		offset = text + len(split)
+		split = append(split, "<w:r>"...)
+		split = append(split, properties...)
+		split = append(split, textTag...)
+		data = applyReplacements(data, []replacement{{int64(text), int64(loc[1]), split}})
+	}
+}
+
+// sizedRunProperties returns the run properties with the font size of both scripts set to the given half-points.
+func sizedRunProperties(properties []byte, size int) []byte {
+	var content []byte
+	if properties != nil {
+		content = append(content, properties[len("<w:rPr>"):len(properties)-len("</w:rPr>")]...)
+	}
+	content = regexp.MustCompile(`<w:sz\b[^>]*/>`).ReplaceAll(content, nil)
+	content = complexFontSizeRegex.ReplaceAll(content, nil)
+	content = insertChild(content, []byte(`<w:sz w:val="`+strconv.Itoa(size)+`"/>`), runPropertyFollowers("w:sz"))
+	content = insertChild(content, []byte(`<w:szCs w:val="`+strconv.Itoa(size)+`"/>`), runPropertyFollowers("w:szCs"))
+	return append(append([]byte("<w:rPr>"), content...), "</w:rPr>"...)
+}
+
+// paragraphProperties returns the properties of the paragraph containing the given offset, nil if it has none.
+func paragraphProperties(data []byte, offset int) []byte {
+	start := -1
+	for _, tag := range []string{"<w:p>", "<w:p "} {
+		if i := bytes.LastIndex(data[:offset], []byte(tag)); i > start {
+			start = i
+		}
+	}
+	if start < 0 {
+		return nil
+	}
+	return paragraphStartRegex.FindSubmatch(data[start:offset])[1]
+}
+
+// fontSize returns the font size in half-points of a run: the size of its properties, its character style, the
+// style of its paragraph, the default paragraph style or the document defaults, in this order.
+func fontSize(styles, paragraphProperties, runProperties []byte) int {
+	if size, exists := propertySize(runProperties); exists {
+		return size
+	}
+	definitions := make(map[string][]byte)
+	defaultStyle := ""
+	for _, style := range styleRegex.FindAll(styles, -1) {
+		tag := style[:bytes.IndexByte(style, '>')+1]
+		if id, exists := getTagAttr(tag, "w:styleId"); exists {
+			definitions[id] = style
+			styleType, _ := getTagAttr(tag, "w:type")
+			if isDefault, _ := getTagAttr(tag, "w:default"); styleType == "paragraph" && (isDefault == "1" || isDefault == "true") {
+				defaultStyle = id
+			}
+		}
+	}
+	paragraphStyle := defaultStyle
+	if match := paragraphStyleRegex.FindSubmatch(paragraphProperties); match != nil {
+		paragraphStyle = string(match[1])
+	}
+	var styleIDs []string
+	if match := runStyleRegex.FindSubmatch(runProperties); match != nil {
+		styleIDs = append(styleIDs, string(match[1]))
+	}
+	for _, id := range append(styleIDs, paragraphStyle) {
+		// the depth is limited, so cyclic definitions end
+		for depth := 0; id != "" && depth < 20; depth++ {
+			style := definitions[id]
+			if size, exists := propertySize(runPropertiesRegex.Find(style)); exists {
+				return size
+			}
+			id = ""
+			if match := basedOnRegex.FindSubmatch(style); match != nil {
+				id = string(match[1])
+			}
+		}
+	}
+	if size, exists := propertySize(docDefaultsRegex.Find(styles)); exists {
+		return size
+	}
+	return defaultFontSize
+}
+
+// propertySize returns the font size of the run properties in half-points.
+func propertySize(properties []byte) (int, bool) {
+	match := fontSizeRegex.FindSubmatch(properties)
+	if match == nil {
+		return 0, false
+	}
+	size, err := strconv.Atoi(string(match[1]))
+	return size, err == nil
+}
@@ -0,0 +1,117 @@
+package docx
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFitHelper(t *testing.T) {
+	for _, test := range []struct {
+		value    interface{}
+		width    int
+		expected string
+	}{
+		{"Certificate of Completion", 40, "Certificate of Completion"},
+		{"Certificate of Completion", 15, "Certificate of…"},
+		{"Zertifikat für Ärztinnen", 12, "Zertifikat…"},
+		{12345, 3, "12…"},
+		{nil, 3, ""},
+	} {
+		if fitted, err := fitHelper(test.value, test.width); err != nil || fitted != test.expected {
+			t.Errorf("expected %v fitted to %d to be %q, got %q (%v)", test.value, test.width, test.expected, fitted, err)
+		}
+	}
+	if _, err := fitHelper("value", 0); err == nil {
+		t.Errorf("expected an error for an invalid width")
+	}
+}
+
+func TestWrapText(t *testing.T) {
+	for _, test := range []struct {
+		text     string
+		width    int
+		expected []string
+	}{
+		{"short", 10, []string{"short"}},
+		{"the quick brown fox jumps over the lazy dog", 10, []string{"the quick", "brown fox", "jumps over", "the lazy", "dog"}},
+		{"a supercalifragilistic word", 8, []string{"a", "supercal", "ifragili", "stic", "word"}},
+		{"first line\nsecond line", 20, []string{"first line", "second line"}},
+		{"", 5, []string{""}},
+	} {
+		if lines := wrapText(test.text, test.width); !reflect.DeepEqual(lines, test.expected) {
+			t.Errorf("expected %q wrapped at %d to be %q, got %q", test.text, test.width, test.expected, lines)
+		}
+	}
+}
+
+func TestTemplateReplacer_FittingHelpers(t *testing.T) {
+	body := `<w:body>` +
+		`<w:p><w:r><w:rPr><w:b/><w:sz w:val="48"/></w:rPr><w:t>Awarded to {{shrink .name 10}}!</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>{{shrink .short 10}}</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>{{wrap .desc 12}}</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>{{fit .title 8}}</w:t></w:r></w:p>`
+
+	doc, err := OpenBytes(rewriteArchive(t, readFile(t, "./test/template.docx"), func(name string, data []byte) []byte {
+		if name == DocumentXml {
+			return []byte(strings.Replace(string(data), "<w:body>", body, 1))
+		}
+		return data
+	}, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+
+	err = doc.ExecuteTemplate(map[string]interface{}{
+		"name":  "Maximilian Mustermann", // 21 characters shrink the font size from 24pt to 48*10/21 half-points
+		"short": "Anna",
+		"desc":  "Completed the course & passed the exam",
+		"title": "Advanced Templating",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	documentXml := string(doc.GetFile(DocumentXml))
+	if strings.Contains(documentXml, "[[docx-shrink") {
+		t.Fatalf("the markers were not resolved")
+	}
+	for _, expected := range []string{
+		`<w:r><w:rPr><w:b/><w:sz w:val="48"/></w:rPr><w:t xml:space="preserve">Awarded to </w:t></w:r>` +
+			`<w:r><w:rPr><w:b/><w:sz w:val="22"/><w:szCs w:val="22"/></w:rPr><w:t xml:space="preserve">Maximilian Mustermann</w:t></w:r>` +
+			`<w:r><w:rPr><w:b/><w:sz w:val="48"/></w:rPr><w:t xml:space="preserve">!</w:t></w:r>`,
+		`<w:t>Anna</w:t>`,
+		`<w:t>Completed</w:t><w:br/><w:t xml:space="preserve">the course &amp;</w:t><w:br/><w:t xml:space="preserve">passed the</w:t><w:br/><w:t xml:space="preserve">exam</w:t>`,
+		`<w:t>Advance…</w:t>`,
+	} {
+		if !strings.Contains(documentXml, expected) {
+			t.Errorf("expected %s in %s", expected, documentXml[strings.Index(documentXml, "<w:body>"):strings.Index(documentXml, "<w:sectPr")])
+		}
+	}
+}
+
+func TestFontSize(t *testing.T) {
+	styles := []byte(`<w:styles><w:docDefaults><w:rPrDefault><w:rPr><w:sz w:val="22"/></w:rPr></w:rPrDefault></w:docDefaults>` +
+		`<w:style w:type="paragraph" w:default="1" w:styleId="Normal"><w:name w:val="Normal"/></w:style>` +
+		`<w:style w:type="paragraph" w:styleId="Title"><w:basedOn w:val="Normal"/><w:rPr><w:sz w:val="56"/></w:rPr></w:style>` +
+		`<w:style w:type="paragraph" w:styleId="Subtitle"><w:basedOn w:val="Title"/></w:style>` +
+		`<w:style w:type="character" w:styleId="Strong"><w:rPr><w:b/><w:sz w:val="30"/></w:rPr></w:style></w:styles>`)
+	for _, test := range []struct {
+		paragraph, run string
+		expected       int
+	}{
+		{"", "", 22},
+		{"", `<w:rPr><w:sz w:val="40"/></w:rPr>`, 40},
+		{`<w:pPr><w:pStyle w:val="Title"/></w:pPr>`, "", 56},
+		{`<w:pPr><w:pStyle w:val="Subtitle"/></w:pPr>`, "", 56},
+		{`<w:pPr><w:pStyle w:val="Title"/></w:pPr>`, `<w:rPr><w:rStyle w:val="Strong"/></w:rPr>`, 30},
+	} {
+		if size := fontSize(styles, []byte(test.paragraph), []byte(test.run)); size != test.expected {
+			t.Errorf("expected the font size %d for %s %s, got %d", test.expected, test.paragraph, test.run, size)
+		}
+	}
+	if size := fontSize(nil, nil, nil); size != defaultFontSize {
+		t.Errorf("expected the default font size without styles, got %d", size)
+	}
+}
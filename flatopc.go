@@ -0,0 +1,264 @@
+package docx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// FlatOPCNamespace is the namespace of the Flat OPC (<pkg:package>) format.
+	FlatOPCNamespace = "http://schemas.microsoft.com/office/2006/xmlPackage"
+
+	// xmlDeclaration is prepended to every XML part which is extracted from a Flat OPC package.
+	xmlDeclaration = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n"
+)
+
+// flatPackage is the representation of a Flat OPC document.
+type flatPackage struct {
+	XMLName xml.Name   `xml:"package"`
+	Parts   []flatPart `xml:"part"`
+}
+
+// flatPart is a single part inside a Flat OPC document.
+// The content is either an XML fragment (xmlData) or base64 encoded (binaryData).
+type flatPart struct {
+	Name        string `xml:"name,attr"`
+	ContentType string `xml:"contentType,attr"`
+	XmlData     *struct {
+		Inner []byte `xml:",innerxml"`
+	} `xml:"xmlData"`
+	BinaryData *string `xml:"binaryData"`
+}
+
+// OpenFlatOPC will open and parse the Flat OPC XML file pointed to by path.
+// Flat OPC is the single-file XML representation of a docx package (<pkg:package>), as e.g. produced by
+// Word's "Word XML Document" format.
+func OpenFlatOPC(path string, opts ...Option) (*Document, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open flat OPC file: %s", err)
+	}
+
+	archive, err := flatOPCToZip(b)
+	if err != nil {
+		return nil, err
+	}
+	zipFile, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return nil, fmt.Errorf("unable to open zip reader: %s", err)
+	}
+
+	return newDocument(zipFile, path, nil, OpenOptions{}, opts)
+}
+
+// OpenFlatOPCBytes allows to create a Document from a Flat OPC byte slice.
+// It behaves just like OpenFlatOPC().
+func OpenFlatOPCBytes(b []byte, opts ...Option) (*Document, error) {
+	archive, err := flatOPCToZip(b)
+	if err != nil {
+		return nil, err
+	}
+	return OpenBytes(archive, opts...)
+}
+
+// flatOPCToZip converts the Flat OPC document into a regular zip-based package.
+// Since Flat OPC does not have a [Content_Types].xml part, it is generated from the content types of the parts.
+func flatOPCToZip(b []byte) ([]byte, error) {
+	var pkg flatPackage
+	if err := xml.Unmarshal(b, &pkg); err != nil {
+		return nil, fmt.Errorf("unable to parse flat OPC package: %s", err)
+	}
+	if pkg.XMLName.Space != FlatOPCNamespace {
+		return nil, fmt.Errorf("invalid flat OPC package, unexpected root element %s", pkg.XMLName.Local)
+	}
+
+	var buf bytes.Buffer
+	zipWriter := zip.NewWriter(&buf)
+
+	types := &opcContentTypes{}
+	for _, part := range pkg.Parts {
+		var data []byte
+		switch {
+		case part.XmlData != nil:
+			data = append([]byte(xmlDeclaration), bytes.TrimSpace(part.XmlData.Inner)...)
+		case part.BinaryData != nil:
+			decoded, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(*part.BinaryData), ""))
+			if err != nil {
+				return nil, fmt.Errorf("unable to decode binary data of part %s: %s", part.Name, err)
+			}
+			data = decoded
+		default:
+			return nil, fmt.Errorf("part %s has neither xmlData nor binaryData", part.Name)
+		}
+
+		fw, err := zipWriter.Create(strings.TrimPrefix(part.Name, "/"))
+		if err != nil {
+			return nil, fmt.Errorf("unable to create writer: %s", err)
+		}
+		if _, err := fw.Write(data); err != nil {
+			return nil, fmt.Errorf("unable to write part %s: %s", part.Name, err)
+		}
+
+		types.setOverride(part.Name, part.ContentType)
+	}
+	contentTypesXml, err := types.marshal()
+	if err != nil {
+		return nil, err
+	}
+
+	fw, err := zipWriter.Create(ContentTypesXml)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create writer: %s", err)
+	}
+	if _, err := fw.Write(contentTypesXml); err != nil {
+		return nil, fmt.Errorf("unable to write %s: %s", ContentTypesXml, err)
+	}
+	if err := zipWriter.Close(); err != nil {
+		return nil, fmt.Errorf("unable to close zip writer: %s", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// WriteFlatOPCToFile will write the document as Flat OPC XML to a new file.
+// Just like WriteToFile, the target file cannot be the same as the path of this document.
+func (d *Document) WriteFlatOPCToFile(file string) error {
+	if file == d.path {
+		return fmt.Errorf("WriteFlatOPCToFile cannot write into the original file while it's open")
+	}
+
+	err := os.MkdirAll(filepath.Dir(file), 0755)
+	if err != nil {
+		return fmt.Errorf("unable to ensure path directories: %s", err)
+	}
+
+	target, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = target.Close()
+	}()
+
+	return d.WriteFlatOPC(target)
+}
+
+// WriteFlatOPC writes the document as a single Flat OPC XML file (<pkg:package>).
+// XML parts are embedded as xmlData, all other parts (e.g. images) are base64 encoded as binaryData.
+func (d *Document) WriteFlatOPC(writer io.Writer) error {
+	var archive bytes.Buffer
+	if err := d.Write(&archive); err != nil {
+		return err
+	}
+	zipReader, err := zip.NewReader(bytes.NewReader(archive.Bytes()), int64(archive.Len()))
+	if err != nil {
+		return fmt.Errorf("unable to open zip reader: %s", err)
+	}
+
+	var types *opcContentTypes
+	for _, zipFile := range zipReader.File {
+		if zipFile.Name != ContentTypesXml {
+			continue
+		}
+		data, err := readZipFileBytes(zipFile)
+		if err != nil {
+			return err
+		}
+		if types, err = parseContentTypes(data); err != nil {
+			return err
+		}
+	}
+	if types == nil {
+		return fmt.Errorf("invalid docx archive, %s is missing", ContentTypesXml)
+	}
+
+	out := new(strings.Builder)
+	out.WriteString(xmlDeclaration)
+	out.WriteString(`<?mso-application progid="Word.Document"?>` + "\n")
+	out.WriteString(`<pkg:package xmlns:pkg="` + FlatOPCNamespace + `">`)
+
+	for _, zipFile := range zipReader.File {
+		if zipFile.Name == ContentTypesXml || strings.HasSuffix(zipFile.Name, "/") {
+			continue
+		}
+		data, err := readZipFileBytes(zipFile)
+		if err != nil {
+			return err
+		}
+
+		partName := "/" + zipFile.Name
+		contentType := types.lookup(partName)
+		fmt.Fprintf(out, `<pkg:part pkg:name="%s" pkg:contentType="%s">`, xmlEscape(partName), xmlEscape(contentType))
+		if isXmlContentType(contentType) {
+			out.WriteString(`<pkg:xmlData>`)
+			out.Write(stripXmlDeclaration(data))
+			out.WriteString(`</pkg:xmlData>`)
+		} else {
+			out.WriteString(`<pkg:binaryData>`)
+			out.WriteString(base64.StdEncoding.EncodeToString(data))
+			out.WriteString(`</pkg:binaryData>`)
+		}
+		out.WriteString(`</pkg:part>`)
+	}
+	out.WriteString(`</pkg:package>`)
+
+	_, err = io.WriteString(writer, out.String())
+	return err
+}
+
+// readZipFileBytes reads the whole content of the given zip file.
+func readZipFileBytes(zipFile *zip.File) ([]byte, error) {
+	readCloser, err := zipFile.Open()
+	if err != nil {
+		return nil, fmt.Errorf("unable to open %s: %s", zipFile.Name, err)
+	}
+	defer func() {
+		_ = readCloser.Close()
+	}()
+	return io.ReadAll(readCloser)
+}
+
+// copyZipFile copies the uncompressed content of the zip file to the writer without reading it into memory.
+func copyZipFile(writer io.Writer, zipFile *zip.File) error {
+	readCloser, err := zipFile.Open()
+	if err != nil {
+		return fmt.Errorf("unable to open %s: %s", zipFile.Name, err)
+	}
+	defer func() {
+		_ = readCloser.Close()
+	}()
+	if _, err := io.Copy(writer, readCloser); err != nil {
+		return fmt.Errorf("unable to writeFile %s: %s", zipFile.Name, err)
+	}
+	return nil
+}
+
+// isXmlContentType returns true if parts of the given content type contain XML.
+func isXmlContentType(contentType string) bool {
+	return contentType == "application/xml" || strings.HasSuffix(contentType, "+xml")
+}
+
+// stripXmlDeclaration removes a leading <?xml ...?> declaration, which must not occur inside xmlData.
+func stripXmlDeclaration(data []byte) []byte {
+	data = bytes.TrimLeft(data, "\ufeff \t\r\n")
+	if bytes.HasPrefix(data, []byte("<?xml")) {
+		if end := bytes.Index(data, []byte("?>")); end >= 0 {
+			data = data[end+2:]
+		}
+	}
+	return bytes.TrimSpace(data)
+}
+
+// xmlEscape escapes the given string for use inside an XML attribute value or text.
+func xmlEscape(s string) string {
+	var buf strings.Builder
+	_ = xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
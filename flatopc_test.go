@@ -0,0 +1,55 @@
+package docx
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDocument_FlatOPCRoundTrip(t *testing.T) {
+	doc, err := Open("./test/template.docx")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var flat bytes.Buffer
+	if err := doc.WriteFlatOPC(&flat); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(flat.String(), `<pkg:part pkg:name="/word/document.xml"`) {
+		t.Error("flat OPC output does not contain the main document part")
+	}
+	if !strings.Contains(flat.String(), `<pkg:binaryData>`) {
+		t.Error("flat OPC output does not contain binary parts")
+	}
+
+	out, err := OpenFlatOPCBytes(flat.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(stripXmlDeclaration(out.GetFile(DocumentXml)), stripXmlDeclaration(doc.GetFile(DocumentXml))) {
+		t.Error("document.xml changed during the flat OPC round trip")
+	}
+	for _, media := range doc.mediaFiles {
+		if !bytes.Equal(out.GetFile(media), doc.GetFile(media)) {
+			t.Errorf("media file %s changed during the flat OPC round trip", media)
+		}
+	}
+
+	if err := out.ExecuteTemplate(map[string]interface{}{"name": "John Doe"}); err != nil {
+		t.Fatal(err)
+	}
+	var archive bytes.Buffer
+	if err := out.Write(&archive); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenBytes(archive.Bytes()); err != nil {
+		t.Errorf("unable to open docx converted from flat OPC: %s", err)
+	}
+}
+
+func TestOpenFlatOPCBytes_Invalid(t *testing.T) {
+	if _, err := OpenFlatOPCBytes([]byte(`<foo/>`)); err == nil {
+		t.Error("expected an error for a non flat OPC document")
+	}
+}
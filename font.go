@@ -0,0 +1,254 @@
+package docx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	// FontTableRelationshipType is the relationship type of the font table of the main document part.
+	FontTableRelationshipType = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/fontTable"
+	// FontRelationshipType is the relationship type of the fonts embedded into the font table.
+	FontRelationshipType = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/font"
+
+	// fontTableContentType is the content type of the font table.
+	fontTableContentType = "application/vnd.openxmlformats-officedocument.wordprocessingml.fontTable+xml"
+	// obfuscatedFontContentType is the content type of embedded fonts.
+	obfuscatedFontContentType = "application/vnd.openxmlformats-officedocument.obfuscatedFont"
+)
+
+var (
+	// embedTrueTypeFontsRegex matches the setting which keeps the embedded fonts when Word saves the document.
+	embedTrueTypeFontsRegex = regexp.MustCompile(`<w:embedTrueTypeFonts\b[^>]*/>`)
+	// fontsOpenTagRegex matches the root element of the font table.
+	fontsOpenTagRegex = regexp.MustCompile(`<w:fonts\b[^>]*>`)
+	// fontsCloseTagRegex matches the end of the font table.
+	fontsCloseTagRegex = regexp.MustCompile(`</w:fonts>\s*$`)
+)
+
+// fontPropertiesOrder lists the children of a font of the font table (<w:font>) in the order of the schema.
+var fontPropertiesOrder = []string{"w:altName", "w:panose1", "w:charset", "w:family", "w:notTrueType", "w:pitch",
+	"w:sig", "w:embedRegular", "w:embedBold", "w:embedItalic", "w:embedBoldItalic"}
+
+// embedTrueTypeFontsFollowers lists the children of the settings which follow <w:embedTrueTypeFonts> according to
+// the schema. Only elements which commonly appear in documents are listed.
+var embedTrueTypeFontsFollowers = append([]string{"w:embedSystemFonts", "w:saveSubsetFonts", "w:saveFormsData",
+	"w:mirrorMargins", "w:alignBordersAndEdges", "w:bordersDoNotSurroundHeader", "w:bordersDoNotSurroundFooter",
+	"w:gutterAtTop", "w:hideSpellingErrors", "w:hideGrammaticalErrors", "w:activeWritingStyle", "w:proofState",
+	"w:formsDesign", "w:attachedTemplate", "w:linkStyles", "w:stylePaneFormatFilter", "w:stylePaneSortMethod",
+	"w:documentType", "w:mailMerge", "w:revisionView", "w:trackRevisions", "w:doNotTrackMoves",
+	"w:doNotTrackFormatting", "w:documentProtection", "w:autoFormatOverride", "w:styleLockTheme",
+	"w:styleLockQFSet", "w:defaultTabStop", "w:autoHyphenation", "w:consecutiveHyphenLimit", "w:hyphenationZone",
+	"w:doNotHyphenateCaps", "w:showEnvelope", "w:summaryLength", "w:clickAndTypeStyle", "w:defaultTableStyle",
+	"w:evenAndOddHeaders"}, evenAndOddHeadersFollowers...)
+
+// EmbedFont embeds the TrueType or OpenType font into the document, so it is displayed with the font on machines
+// where the font is not installed. The name is the font family used by the runs, e.g. "Corporate Sans". The style
+// (regular, bold, italic or bold italic) is read from the font, so the fonts of a family are embedded one by one;
+// a font embedded before with the same name and style is replaced. As required by the specification, the font is
+// obfuscated with a random key. Fonts whose license does not permit embedding are rejected.
+func (d *Document) EmbedFont(font []byte, name string) error {
+	if name == "" {
+		return fmt.Errorf("the name of the font is empty")
+	}
+	style, err := embeddedFontStyle(font)
+	if err != nil {
+		return err
+	}
+	fontTable, err := d.fontTablePart()
+	if err != nil {
+		return err
+	}
+	fonts, exists := d.partData(fontTable)
+	if !exists {
+		return fmt.Errorf("font table %s not found", fontTable)
+	}
+
+	// the font of the same style embedded before is replaced
+	entry := regexp.MustCompile(`(?s)<w:font\s+w:name="` + regexp.QuoteMeta(xmlEscape(name)) + `"\s*(?:/>|>.*?</w:font>)`).FindIndex(fonts)
+	if entry != nil {
+		if tag := regexp.MustCompile(`<` + style + `\b[^>]*/>`).Find(fonts[entry[0]:entry[1]]); tag != nil {
+			rels, err := d.packageRelationships(fontTable)
+			if err != nil {
+				return err
+			}
+			id, _ := getTagAttr(tag, "r:id")
+			for _, rel := range rels.Relationships {
+				if rel.ID == id && rel.Type == FontRelationshipType {
+					if err := d.removePart(resolveTarget(fontTable, rel.Target)); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+
+	key, err := newItemID()
+	if err != nil {
+		return err
+	}
+	obfuscated, err := obfuscateFont(font, key)
+	if err != nil {
+		return err
+	}
+	partName := nextPartName("word/fonts/font", ".odttf", d.partNames())
+	if err := d.addPart(partName, obfuscated, obfuscatedFontContentType); err != nil {
+		return err
+	}
+	id, err := d.addRelationship(fontTable, FontRelationshipType, partName)
+	if err != nil {
+		return err
+	}
+	embed := []byte(`<` + style + ` r:id="` + id + `" w:fontKey="` + key + `"/>`)
+
+	if entry == nil {
+		end := fontsCloseTagRegex.FindIndex(fonts)
+		if end == nil {
+			return fmt.Errorf("invalid font table %s", fontTable)
+		}
+		element := []byte(`<w:font w:name="` + xmlEscape(name) + `"><w:charset w:val="00"/><w:family w:val="auto"/>` +
+			`<w:pitch w:val="variable"/>` + string(embed) + `</w:font>`)
+		fonts = insertAt(fonts, end[0], element)
+	} else {
+		element := fonts[entry[0]:entry[1]]
+		open := bytes.IndexByte(element, '>') + 1
+		var content []byte
+		if bytes.HasSuffix(element[:open], []byte("/>")) {
+			open = len(element)
+		} else {
+			content = element[open : len(element)-len("</w:font>")]
+		}
+		content = regexp.MustCompile(`<`+style+`\b[^>]*/>`).ReplaceAll(content, nil)
+		content = insertChild(content, embed, fontPropertyFollowers(style))
+		openTag := bytes.TrimSuffix(bytes.TrimSuffix(element[:open], []byte("/>")), []byte(">"))
+		replaced := append(append(append(append([]byte{}, openTag...), '>'), content...), "</w:font>"...)
+		fonts = applyReplacements(fonts, []replacement{{int64(entry[0]), int64(entry[1]), replaced}})
+	}
+	if open := fontsOpenTagRegex.Find(fonts); open != nil && !bytes.Contains(open, []byte("xmlns:r=")) {
+		fonts = bytes.Replace(fonts, open, setTagAttr(open, "xmlns:r", "http://schemas.openxmlformats.org/officeDocument/2006/relationships"), 1)
+	}
+	d.setPackageFile(fontTable, fonts)
+
+	return d.enableEmbeddedFonts()
+}
+
+// fontTablePart returns the font table of the main document part. A font table is added if the document
+// does not have one.
+func (d *Document) fontTablePart() (string, error) {
+	if targets := d.relationshipTargets(DocumentXml, FontTableRelationshipType); len(targets) > 0 {
+		return targets[0], nil
+	}
+	partName := "word/fontTable.xml"
+	fonts := []byte(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n" +
+		`<w:fonts ` + wordprocessingNamespaces + `></w:fonts>`)
+	if err := d.addPart(partName, fonts, fontTableContentType); err != nil {
+		return "", err
+	}
+	if _, err := d.addRelationship(DocumentXml, FontTableRelationshipType, partName); err != nil {
+		return "", err
+	}
+	return partName, nil
+}
+
+// enableEmbeddedFonts enables the setting which makes Word keep the embedded fonts when it saves the document.
+func (d *Document) enableEmbeddedFonts() error {
+	settings, exists := d.loadPackageFile(SettingsXml)
+	if !exists {
+		return nil
+	}
+	if tag := embedTrueTypeFontsRegex.Find(settings); tag != nil && onOffTagValue(tag) {
+		return nil
+	}
+	settings = embedTrueTypeFontsRegex.ReplaceAll(settings, nil)
+	open := settingsOpenTagRegex.FindIndex(settings)
+	closeTag := bytes.LastIndex(settings, []byte("</w:settings>"))
+	if open == nil || closeTag < open[1] {
+		return fmt.Errorf("invalid settings part %s", SettingsXml)
+	}
+	children := insertChild(settings[open[1]:closeTag], []byte("<w:embedTrueTypeFonts/>"), embedTrueTypeFontsFollowers)
+	d.packageFiles[SettingsXml] = append(append(append([]byte{}, settings[:open[1]]...), children...), settings[closeTag:]...)
+	return nil
+}
+
+// fontPropertyFollowers returns the children of a font which follow the given one according to the schema.
+func fontPropertyFollowers(name string) []string {
+	for i, property := range fontPropertiesOrder {
+		if property == name {
+			return fontPropertiesOrder[i+1:]
+		}
+	}
+	return nil
+}
+
+// embeddedFontStyle returns the element of the font table which embeds the font (e.g. w:embedBold) according
+// to its style. An error is returned if the data is not a TrueType or OpenType font or if its license
+// restricts embedding.
+func embeddedFontStyle(font []byte) (string, error) {
+	tables, err := fontTables(font)
+	if err != nil {
+		return "", err
+	}
+	head := tables["head"]
+	if len(head) < 46 {
+		return "", fmt.Errorf("invalid font, the head table is missing")
+	}
+	// the embedding permissions: restricted license embedding must not be embedded
+	if os2 := tables["OS/2"]; len(os2) >= 10 && binary.BigEndian.Uint16(os2[8:])&0x000F == 0x0002 {
+		return "", fmt.Errorf("the license of the font does not permit embedding")
+	}
+
+	switch macStyle := binary.BigEndian.Uint16(head[44:]); macStyle & 0x3 {
+	case 1:
+		return "w:embedBold", nil
+	case 2:
+		return "w:embedItalic", nil
+	case 3:
+		return "w:embedBoldItalic", nil
+	}
+	return "w:embedRegular", nil
+}
+
+// fontTables returns the tables of a TrueType or OpenType font by their tag.
+func fontTables(font []byte) (map[string][]byte, error) {
+	if len(font) < 12 {
+		return nil, fmt.Errorf("invalid font, the data is too short")
+	}
+	switch version := string(font[:4]); version {
+	case "\x00\x01\x00\x00", "OTTO", "true":
+	default:
+		return nil, fmt.Errorf("unsupported font, expected TrueType or OpenType data")
+	}
+
+	tables := make(map[string][]byte)
+	count := int(binary.BigEndian.Uint16(font[4:]))
+	for i := 0; i < count; i++ {
+		record := 12 + 16*i
+		if record+16 > len(font) {
+			return nil, fmt.Errorf("invalid font, the table directory is truncated")
+		}
+		offset, length := int(binary.BigEndian.Uint32(font[record+8:])), int(binary.BigEndian.Uint32(font[record+12:]))
+		if offset < 0 || length < 0 || offset+length > len(font) {
+			return nil, fmt.Errorf("invalid font, the %s table is out of range", font[record:record+4])
+		}
+		tables[string(font[record:record+4])] = font[offset : offset+length]
+	}
+	return tables, nil
+}
+
+// obfuscateFont obfuscates the font for embedding: the first 32 bytes are combined with the bytes of the key,
+// a GUID in registry format, in reverse order. Applying it again restores the font.
+func obfuscateFont(font []byte, key string) ([]byte, error) {
+	guid, err := hex.DecodeString(strings.NewReplacer("{", "", "}", "", "-", "").Replace(key))
+	if err != nil || len(guid) != 16 {
+		return nil, fmt.Errorf("invalid font key %s", key)
+	}
+	obfuscated := append([]byte(nil), font...)
+	for i := 0; i < 32 && i < len(obfuscated); i++ {
+		obfuscated[i] ^= guid[15-i%16]
+	}
+	return obfuscated, nil
+}
@@ -0,0 +1,111 @@
+package docx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// testFont returns a minimal TrueType font with the given style bits (1 bold, 2 italic) and embedding permissions.
+func testFont(macStyle, fsType uint16) []byte {
+	head := make([]byte, 54)
+	binary.BigEndian.PutUint16(head[44:], macStyle)
+	os2 := make([]byte, 78)
+	binary.BigEndian.PutUint16(os2[8:], fsType)
+
+	font := []byte{0, 1, 0, 0, 0, 2, 0, 0, 0, 0, 0, 0}
+	offset := uint32(12 + 2*16)
+	for _, table := range []struct {
+		tag  string
+		data []byte
+	}{{"OS/2", os2}, {"head", head}} {
+		font = append(font, table.tag...)
+		font = binary.BigEndian.AppendUint32(font, 0) // checksum
+		font = binary.BigEndian.AppendUint32(font, offset)
+		font = binary.BigEndian.AppendUint32(font, uint32(len(table.data)))
+		offset += uint32(len(table.data))
+	}
+	return append(append(font, os2...), head...)
+}
+
+func TestDocument_EmbedFont(t *testing.T) {
+	doc, err := Open("./test/template.docx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+
+	regular, bold := testFont(0, 0), testFont(1, 8)
+	if err := doc.EmbedFont(regular, "Corporate Sans"); err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.EmbedFont(bold, "Corporate Sans"); err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.EmbedFont(testFont(3, 0), "Arial"); err != nil {
+		t.Fatal(err)
+	}
+	// embedding the same style again replaces the font
+	if err := doc.EmbedFont(regular, "Corporate Sans"); err != nil {
+		t.Fatal(err)
+	}
+
+	fonts, _ := doc.partData("word/fontTable.xml")
+	entry := regexp.MustCompile(`<w:font w:name="Corporate Sans">.*?</w:font>`).Find(fonts)
+	if entry == nil || !bytes.Contains(entry, []byte(`<w:pitch w:val="variable"/><w:embedRegular r:id=`)) ||
+		!bytes.Contains(entry, []byte(`<w:embedBold r:id=`)) || bytes.Count(entry, []byte("<w:embedRegular")) != 1 {
+		t.Fatalf("unexpected font entry %s", entry)
+	}
+	if !regexp.MustCompile(`<w:font w:name="Arial">.*?<w:sig [^>]*/><w:embedBoldItalic r:id="[^"]*" w:fontKey="\{[0-9A-F-]{36}\}"/></w:font>`).Match(fonts) {
+		t.Errorf("the font was not added to the existing entry")
+	}
+
+	targets := doc.relationshipTargets("word/fontTable.xml", FontRelationshipType)
+	if len(targets) != 3 {
+		t.Fatalf("expected 3 embedded fonts, got %v", targets)
+	}
+	for _, target := range targets {
+		if !strings.HasPrefix(target, "word/fonts/font") || !strings.HasSuffix(target, ".odttf") {
+			t.Errorf("unexpected font part %s", target)
+		}
+	}
+
+	// the regular font is the last one, applying the key again restores it
+	tag := regexp.MustCompile(`<w:embedRegular r:id="[^"]*" w:fontKey="([^"]*)"/>`).FindSubmatch(entry)
+	obfuscated, _ := doc.partData(targets[len(targets)-1])
+	if bytes.Equal(obfuscated, regular) {
+		t.Errorf("the font was not obfuscated")
+	}
+	restored, err := obfuscateFont(obfuscated, string(tag[1]))
+	if err != nil || !bytes.Equal(restored, regular) {
+		t.Errorf("the font was not obfuscated with its key")
+	}
+	if settings, _ := doc.partData(SettingsXml); !bytes.Contains(settings, []byte("<w:embedTrueTypeFonts/>")) {
+		t.Errorf("the embedded fonts were not enabled in the settings")
+	}
+
+	var buf bytes.Buffer
+	if err := doc.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	written, err := OpenBytes(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	types, err := written.packageContentTypes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if contentType := types.lookup("/" + targets[0]); contentType != obfuscatedFontContentType {
+		t.Errorf("unexpected content type %s", contentType)
+	}
+
+	if err := doc.EmbedFont(testFont(0, 2), "Restricted"); err == nil {
+		t.Errorf("expected an error for a font with restricted license")
+	}
+	if err := doc.EmbedFont([]byte("no font data"), "Invalid"); err == nil {
+		t.Errorf("expected an error for invalid font data")
+	}
+}
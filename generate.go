@@ -0,0 +1,131 @@
+package docx
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// identifierRegex matches the names which can be used as fields in template actions, e.g. {{.name}}.
+var identifierRegex = regexp.MustCompile(`^[\p{L}_][\p{L}\p{Nd}_]*$`)
+
+// GenerateTemplate creates a starter template for the data described by the schema, e.g. GenerateTemplate(
+// SchemaFor(Offer{})) or a schema read by LoadSchema. Every field gets a labeled placeholder, nested objects a
+// heading with their fields and arrays a {{range}} block repeating the fields of their items, so template authors
+// start from a skeleton which matches the data and only style it in Word. The fields of structs keep their order,
+// the properties of parsed schemas are sorted by name.
+func GenerateTemplate(schema *Schema) (*Document, error) {
+	if schema == nil || !schema.isObject() {
+		return nil, fmt.Errorf("the schema must describe an object")
+	}
+	builder := NewDocument()
+	generateFields(builder, schema, nil, 1)
+	return builder.Build()
+}
+
+// generateFields appends the fields of the object at the path. Inside of {{range}} blocks the path is relative
+// to the item.
+func generateFields(builder *Builder, schema *Schema, path []string, level int) {
+	for _, name := range schema.propertyNames() {
+		property := schema.Properties[name]
+		fieldPath := append(slices.Clip(path), name)
+		label := fieldLabel(name)
+		switch {
+		case property.isObject():
+			builder.Heading(min(level, 6), label)
+			generateFields(builder, property, fieldPath, level+1)
+		case property.isArray():
+			builder.Heading(min(level, 6), label)
+			builder.Paragraph("{{range " + fieldReference(fieldPath) + "}}")
+			if items := property.Items; items != nil && items.isObject() {
+				generateFields(builder, items, nil, level+1)
+			} else {
+				builder.Paragraph("{{.}}")
+			}
+			builder.Paragraph("{{end}}")
+		default:
+			builder.Paragraph(label + ": {{" + fieldReference(fieldPath) + "}}")
+		}
+	}
+}
+
+// isObject reports whether the schema describes an object with properties.
+func (s *Schema) isObject() bool {
+	return len(s.Properties) > 0 && (len(s.Type) == 0 || slices.Contains(s.Type, "object"))
+}
+
+// isArray reports whether the schema describes an array.
+func (s *Schema) isArray() bool {
+	return slices.Contains(s.Type, "array") || (len(s.Type) == 0 && s.Items != nil)
+}
+
+// propertyNames returns the names of the properties in the order of the fields of the struct or sorted by name.
+func (s *Schema) propertyNames() []string {
+	if len(s.order) == len(s.Properties) {
+		return s.order
+	}
+	names := make([]string, 0, len(s.Properties))
+	for name := range s.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// fieldReference returns the template expression of the field at the path, e.g. .customer.name. Names which are
+// no identifiers are looked up with index, e.g. index . "first-name".
+func fieldReference(path []string) string {
+	if len(path) == 0 {
+		return "."
+	}
+	for _, name := range path {
+		if !identifierRegex.MatchString(name) {
+			quoted := make([]string, len(path))
+			for i, name := range path {
+				quoted[i] = strconv.Quote(name)
+			}
+			return "index . " + strings.Join(quoted, " ")
+		}
+	}
+	return "." + strings.Join(path, ".")
+}
+
+// fieldLabel returns the label of a field for humans, e.g. "Customer name" for customerName or customer_name.
+func fieldLabel(name string) string {
+	var words []string
+	var word []rune
+	runes := []rune(name)
+	for i, r := range runes {
+		startsWord := unicode.IsUpper(r) && i > 0 &&
+			(unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1])))
+		if r == '_' || r == '-' || r == ' ' || startsWord {
+			if len(word) > 0 {
+				words = append(words, string(word))
+			}
+			word = nil
+			if !startsWord {
+				continue
+			}
+		}
+		word = append(word, r)
+	}
+	if len(word) > 0 {
+		words = append(words, string(word))
+	}
+	for i, w := range words {
+		// acronyms such as ID or VAT keep their case
+		if strings.ToUpper(w) != w {
+			words[i] = strings.ToLower(w)
+		}
+	}
+	label := []rune(strings.Join(words, " "))
+	if len(label) == 0 {
+		return name
+	}
+	label[0] = unicode.ToUpper(label[0])
+	return string(label)
+}
@@ -0,0 +1,101 @@
+package docx
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGenerateTemplate(t *testing.T) {
+	type address struct {
+		Street string `docx:"street"`
+		City   string `docx:"city"`
+	}
+	type item struct {
+		Name   string   `docx:"name"`
+		Amount float64  `docx:"amount"`
+		Tags   []string `docx:"tags"`
+	}
+	type offer struct {
+		CustomerName string  `docx:"customerName,required"`
+		VatID        string  `docx:"vat-id"`
+		Address      address `docx:"address"`
+		Items        []item  `docx:"items"`
+	}
+
+	doc, err := GenerateTemplate(SchemaFor(offer{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	texts, err := paragraphTexts(doc.GetFile(DocumentXml))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{
+		"Customer name: {{.customerName}}",
+		`Vat id: {{index . "vat-id"}}`,
+		"Address", "Street: {{.address.street}}", "City: {{.address.city}}",
+		"Items", "{{range .items}}", "Name: {{.name}}", "Amount: {{.amount}}",
+		"Tags", "{{range .tags}}", "{{.}}", "{{end}}",
+		"{{end}}",
+	}
+	if !reflect.DeepEqual(texts, expected) {
+		t.Errorf("expected the paragraphs\n%q, got\n%q", expected, texts)
+	}
+
+	var buf bytes.Buffer
+	if err := doc.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	tpl, err := NewTemplate(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	rendered, err := Render(tpl, offer{
+		CustomerName: "Anna",
+		VatID:        "CHE-123",
+		Address:      address{Street: "Main Street 1", City: "Zurich"},
+		Items:        []item{{Name: "Desk", Amount: 250, Tags: []string{"wood", "oak"}}, {Name: "Chair", Amount: 80}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	texts, _ = paragraphTexts(rendered.GetFile(DocumentXml))
+	text := strings.Join(texts, "|")
+	for _, part := range []string{"Customer name: Anna", "Vat id: CHE-123", "City: Zurich", "Name: Desk|Amount: 250|Tags|wood|oak|Name: Chair"} {
+		if !strings.Contains(text, part) {
+			t.Errorf("expected %q in %q", part, text)
+		}
+	}
+
+	schema, err := ParseSchema([]byte(`{"type": "object", "properties": {"total": {"type": "number"}, "date": {"type": "string"}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err = GenerateTemplate(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if texts, _ := paragraphTexts(doc.GetFile(DocumentXml)); !reflect.DeepEqual(texts, []string{"Date: {{.date}}", "Total: {{.total}}"}) {
+		t.Errorf("expected the properties sorted by name, got %q", texts)
+	}
+	if _, err := GenerateTemplate(&Schema{Type: schemaTypes{"string"}}); err == nil {
+		t.Errorf("expected an error for a schema which doesn't describe an object")
+	}
+}
+
+func TestFieldLabel(t *testing.T) {
+	for name, expected := range map[string]string{
+		"customerName":  "Customer name",
+		"customer_name": "Customer name",
+		"VatID":         "Vat ID",
+		"HTTPServer":    "HTTP server",
+		"total":         "Total",
+		"_":             "_",
+	} {
+		if label := fieldLabel(name); label != expected {
+			t.Errorf("expected %q for %s, got %q", expected, name, label)
+		}
+	}
+}
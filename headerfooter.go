@@ -0,0 +1,396 @@
+package docx
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const (
+	// HeaderRelationshipType is the relationship type from the main document to a header part.
+	HeaderRelationshipType = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/header"
+	// FooterRelationshipType is the relationship type from the main document to a footer part.
+	FooterRelationshipType = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/footer"
+	// HeaderContentType is the content type of header parts.
+	HeaderContentType = "application/vnd.openxmlformats-officedocument.wordprocessingml.header+xml"
+	// FooterContentType is the content type of footer parts.
+	FooterContentType = "application/vnd.openxmlformats-officedocument.wordprocessingml.footer+xml"
+
+	// wordprocessingNamespaces are the namespace declarations used for newly created parts.
+	wordprocessingNamespaces = `xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main" ` +
+		`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"`
+)
+
+var (
+	// SectionPropertiesRegex matches the open tag of all section properties (<w:sectPr>), including singleton tags.
+	// It also matches the previous section properties recorded by tracked changes, see sectionProperties.
+	SectionPropertiesRegex = regexp.MustCompile(`<w:sectPr(\s[^>]*)?/?>`)
+	// sectionPropertiesChangeRegex matches the tracked changes of section properties (<w:sectPrChange>).
+	sectionPropertiesChangeRegex = regexp.MustCompile(`(?s)<w:sectPrChange\b[^>]*?(?:/>|>.*?</w:sectPrChange>)`)
+	// titlePageRegex matches the <w:titlePg> element of the section properties.
+	titlePageRegex = regexp.MustCompile(`<w:titlePg\b[^>]*/>`)
+	// evenAndOddHeadersRegex matches the <w:evenAndOddHeaders> element of the settings part.
+	evenAndOddHeadersRegex = regexp.MustCompile(`<w:evenAndOddHeaders\b[^>]*/>`)
+	// settingsOpenTagRegex matches the root element of the settings part.
+	settingsOpenTagRegex = regexp.MustCompile(`<w:settings\b[^>]*>`)
+)
+
+// HeaderFooterType defines on which pages of a section a header or footer is shown.
+type HeaderFooterType string
+
+const (
+	// HeaderDefault is shown on all pages of a section, unless a first page or even page header is defined.
+	HeaderDefault HeaderFooterType = "default"
+	// HeaderFirst is shown on the first page of a section if the section has a title page.
+	HeaderFirst HeaderFooterType = "first"
+	// HeaderEven is shown on even pages if different odd and even headers are enabled.
+	HeaderEven HeaderFooterType = "even"
+	// HeaderOdd is shown on odd pages, which is the same as the default header.
+	HeaderOdd = HeaderDefault
+
+	// FooterDefault is the footer equivalent of HeaderDefault.
+	FooterDefault = HeaderDefault
+	// FooterFirst is the footer equivalent of HeaderFirst.
+	FooterFirst = HeaderFirst
+	// FooterEven is the footer equivalent of HeaderEven.
+	FooterEven = HeaderEven
+	// FooterOdd is the footer equivalent of HeaderOdd.
+	FooterOdd = HeaderOdd
+)
+
+// AllSections can be passed instead of a section index to apply a change to all sections of the document.
+const AllSections = -1
+
+// titlePageFollowers lists the children of the section properties which follow <w:titlePg> according to the schema.
+var titlePageFollowers = []string{"w:textDirection", "w:bidi", "w:rtlGutter", "w:docGrid", "w:printerSettings", "w:sectPrChange"}
+
+// evenAndOddHeadersFollowers lists the children of the settings which follow <w:evenAndOddHeaders> according to the schema.
+// Only elements which commonly appear in documents are listed.
+var evenAndOddHeadersFollowers = []string{"w:bookFoldRevPrinting", "w:bookFoldPrinting", "w:bookFoldPrintingSheets",
+	"w:drawingGridHorizontalSpacing", "w:drawingGridVerticalSpacing", "w:displayHorizontalDrawingGridEvery",
+	"w:displayVerticalDrawingGridEvery", "w:doNotUseMarginsForDrawingGridOrigin", "w:drawingGridHorizontalOrigin",
+	"w:drawingGridVerticalOrigin", "w:doNotShadeFormData", "w:noPunctuationKerning", "w:characterSpacingControl",
+	"w:printTwoOnOne", "w:strictFirstAndLastChars", "w:noLineBreaksAfter", "w:noLineBreaksBefore",
+	"w:savePreviewPicture", "w:doNotValidateAgainstSchema", "w:saveInvalidXml", "w:ignoreMixedContent",
+	"w:alwaysShowPlaceholderText", "w:doNotDemarcateInvalidXml", "w:saveXmlDataOnly", "w:useXSLTWhenSaving",
+	"w:saveThroughXslt", "w:showXMLTags", "w:alwaysMergeEmptyNamespace", "w:updateFields", "w:hdrShapeDefaults",
+	"w:footnotePr", "w:endnotePr", "w:compat", "w:docVars", "w:rsids", "m:mathPr", "w:attachedSchema",
+	"w:themeFontLang", "w:clrSchemeMapping", "w:doNotIncludeSubdocsInStats", "w:doNotAutoCompressPictures",
+	"w:forceUpgrade", "w:captions", "w:readModeInkLockDown", "w:smartTagType", "sl:schemaLibrary",
+	"w:shapeDefaults", "w:doNotEmbedSmartTags", "w:decimalSymbol", "w:listSeparator"}
+
+// AddHeader creates a new header part with the given text and references it from every section of the document.
+// Every line of the text becomes a paragraph. Existing headers of the same type are replaced in all sections,
+// their parts are removed once no section references them anymore.
+// Placeholders inside the text are processed just like in all other headers.
+// The name of the new part (e.g. word/header2.xml) is returned.
+func (d *Document) AddHeader(headerType HeaderFooterType, text string) (string, error) {
+	return d.AddSectionHeader(AllSections, headerType, text)
+}
+
+// AddFooter creates a new footer part with the given text and references it from every section of the document.
+// It behaves just like AddHeader.
+func (d *Document) AddFooter(footerType HeaderFooterType, text string) (string, error) {
+	return d.AddSectionFooter(AllSections, footerType, text)
+}
+
+// AddSectionHeader creates a new header part with the given text and references it from a single section.
+// Sections are numbered in document order, starting at 0. Pass AllSections to reference it from all sections.
+// A first page header is only shown if the section has a title page (see SetTitlePage), an even page
+// header only if even and odd headers are enabled (see SetEvenAndOddHeaders).
+func (d *Document) AddSectionHeader(section int, headerType HeaderFooterType, text string) (string, error) {
+	return d.addHeaderFooter(section, headerType, "header", "w:hdr", textParagraphs(text))
+}
+
+// AddSectionFooter creates a new footer part with the given text and references it from a single section.
+// It behaves just like AddSectionHeader.
+func (d *Document) AddSectionFooter(section int, footerType HeaderFooterType, text string) (string, error) {
+	return d.addHeaderFooter(section, footerType, "footer", "w:ftr", textParagraphs(text))
+}
+
+// SectionCount returns the number of sections of the document.
+func (d *Document) SectionCount() int {
+	return len(sectionProperties(d.GetFile(DocumentXml)))
+}
+
+// SetTitlePage enables or disables a different first page (<w:titlePg/>) for the given section.
+// If enabled, the first page of the section shows the first page header and footer instead of the default ones.
+// Pass AllSections to change all sections.
+func (d *Document) SetTitlePage(section int, enabled bool) error {
+	documentXml, err := modifySections(d.GetFile(DocumentXml), section, func(properties []byte) []byte {
+		properties = titlePageRegex.ReplaceAll(properties, nil)
+		if enabled {
+			properties = insertChild(properties, []byte("<w:titlePg/>"), titlePageFollowers)
+		}
+		return properties
+	})
+	if err != nil {
+		return err
+	}
+	return d.SetFile(DocumentXml, documentXml)
+}
+
+// TitlePage returns whether the given section has a different first page.
+func (d *Document) TitlePage(section int) (bool, error) {
+	ranges, err := sectionRanges(d.GetFile(DocumentXml))
+	if err != nil {
+		return false, err
+	}
+	if section < 0 || section >= len(ranges) {
+		return false, fmt.Errorf("section %d not found, the document has %d sections", section, len(ranges))
+	}
+	tag := titlePageRegex.Find(d.GetFile(DocumentXml)[ranges[section][0]:ranges[section][1]])
+	return tag != nil && onOffTagValue(tag), nil
+}
+
+// SetEvenAndOddHeaders enables or disables different headers and footers for even and odd pages.
+// If enabled, even pages show the even page header and footer and odd pages the default ones.
+// Unlike the title page, this is a setting of the whole document and not of a single section.
+func (d *Document) SetEvenAndOddHeaders(enabled bool) error {
+	settings, exists := d.loadPackageFile(SettingsXml)
+	if !exists {
+		return fmt.Errorf("invalid docx archive, %s is missing", SettingsXml)
+	}
+	settings = evenAndOddHeadersRegex.ReplaceAll(settings, nil)
+	if enabled {
+		open := settingsOpenTagRegex.FindIndex(settings)
+		closeTag := bytes.LastIndex(settings, []byte("</w:settings>"))
+		if open == nil || closeTag < open[1] {
+			return fmt.Errorf("invalid settings part %s", SettingsXml)
+		}
+		children := insertChild(settings[open[1]:closeTag], []byte("<w:evenAndOddHeaders/>"), evenAndOddHeadersFollowers)
+		settings = append(append(append([]byte{}, settings[:open[1]]...), children...), settings[closeTag:]...)
+	}
+	d.packageFiles[SettingsXml] = settings
+	return nil
+}
+
+// EvenAndOddHeaders returns whether different headers and footers for even and odd pages are enabled.
+func (d *Document) EvenAndOddHeaders() bool {
+	settings, exists := d.loadPackageFile(SettingsXml)
+	if !exists {
+		return false
+	}
+	tag := evenAndOddHeadersRegex.Find(settings)
+	return tag != nil && onOffTagValue(tag)
+}
+
+// addHeaderFooter creates the part, its content type and relationship and adds the references to the section.
+// The kind is either 'header' or 'footer', the rootElement is the matching root tag (w:hdr or w:ftr).
+func (d *Document) addHeaderFooter(section int, hfType HeaderFooterType, kind, rootElement, content string) (string, error) {
+	switch hfType {
+	case HeaderDefault, HeaderFirst, HeaderEven:
+	default:
+		return "", fmt.Errorf("invalid %s type %s", kind, hfType)
+	}
+
+	ranges, err := sectionRanges(d.GetFile(DocumentXml))
+	if err != nil {
+		return "", err
+	}
+	if section != AllSections && (section < 0 || section >= len(ranges)) {
+		return "", fmt.Errorf("section %d not found, the document has %d sections", section, len(ranges))
+	}
+
+	relType, contentType := HeaderRelationshipType, HeaderContentType
+	if kind == "footer" {
+		relType, contentType = FooterRelationshipType, FooterContentType
+	}
+
+	// the name must not collide with any part, including the headers and footers which were not loaded
+	fileName := nextPartName("word/"+kind, ".xml", d.partNames())
+	data := []byte(xmlDeclaration + "<" + rootElement + " " + wordprocessingNamespaces + ">" + content + "</" + rootElement + ">")
+
+	if err := d.ensureContentType(fileName, contentType); err != nil {
+		return "", err
+	}
+	if err := d.addFile(fileName, data); err != nil {
+		return "", err
+	}
+	id, err := d.addRelationship(DocumentXml, relType, fileName)
+	if err != nil {
+		return "", err
+	}
+
+	element := "w:" + kind + "Reference"
+	reference := []byte(`<` + element + ` w:type="` + string(hfType) + `" r:id="` + id + `"/>`)
+	existingRegex := regexp.MustCompile(`<` + element + `\s[^>]*w:type="` + string(hfType) + `"[^>]*/>`)
+	var replaced []string
+	documentXml, err := modifySections(d.GetFile(DocumentXml), section, func(properties []byte) []byte {
+		for _, tag := range existingRegex.FindAll(properties, -1) {
+			if id, exists := getTagAttr(tag, "r:id"); exists {
+				replaced = append(replaced, id)
+			}
+		}
+		// references must be the first children of the section properties
+		return append(append([]byte{}, reference...), existingRegex.ReplaceAll(properties, nil)...)
+	})
+	if err != nil {
+		return "", err
+	}
+	if err := d.SetFile(DocumentXml, documentXml); err != nil {
+		return "", err
+	}
+	return fileName, d.removeUnreferencedHeaderFooters(replaced)
+}
+
+// removeUnreferencedHeaderFooters removes the header and footer parts of the relationships with the given ids,
+// unless they are still referenced by a section, e.g. by another section or a tracked change.
+func (d *Document) removeUnreferencedHeaderFooters(ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	rels, err := d.packageRelationships(DocumentXml)
+	if err != nil {
+		return err
+	}
+	targets := make(map[string]string)
+	for _, rel := range rels.Relationships {
+		if rel.TargetMode != TargetModeExternal && (rel.Type == HeaderRelationshipType || rel.Type == FooterRelationshipType) {
+			targets[rel.ID] = resolveTarget(DocumentXml, rel.Target)
+		}
+	}
+
+	referenced := make(map[string]bool)
+	for _, tag := range headerFooterReferenceRegex.FindAll(d.GetFile(DocumentXml), -1) {
+		if id, exists := getTagAttr(tag, "r:id"); exists && targets[id] != "" {
+			referenced[targets[id]] = true
+		}
+	}
+	for _, id := range ids {
+		part := targets[id]
+		if part == "" || referenced[part] {
+			continue
+		}
+		if err := d.removePart(part); err != nil {
+			return err
+		}
+		referenced[part] = true
+	}
+	return nil
+}
+
+// modifySections replaces the content of the section properties of the given section (or AllSections)
+// with the result of modify. Singleton section properties (<w:sectPr/>) are expanded first.
+func modifySections(documentXml []byte, section int, modify func(properties []byte) []byte) ([]byte, error) {
+	positions := sectionProperties(documentXml)
+	for i := len(positions) - 1; i >= 0; i-- {
+		tag := documentXml[positions[i][0]:positions[i][1]]
+		if bytes.HasSuffix(tag, []byte("/>")) {
+			expanded := append(append([]byte{}, tag[:len(tag)-2]...), []byte("></w:sectPr>")...)
+			documentXml = append(append(append([]byte{}, documentXml[:positions[i][0]]...), expanded...), documentXml[positions[i][1]:]...)
+		}
+	}
+
+	ranges, err := sectionRanges(documentXml)
+	if err != nil {
+		return nil, err
+	}
+	if section != AllSections && (section < 0 || section >= len(ranges)) {
+		return nil, fmt.Errorf("section %d not found, the document has %d sections", section, len(ranges))
+	}
+
+	// modify from the back, so the earlier positions stay valid
+	for i := len(ranges) - 1; i >= 0; i-- {
+		if section != AllSections && section != i {
+			continue
+		}
+		start, end := ranges[i][0], ranges[i][1]
+		properties := modify(documentXml[start:end])
+		documentXml = append(append(append([]byte{}, documentXml[:start]...), properties...), documentXml[end:]...)
+	}
+	return documentXml, nil
+}
+
+// sectionRanges returns the start and end offsets of the content of all section properties, without their
+// tracked changes. Singleton section properties have an empty range right behind the tag.
+func sectionRanges(documentXml []byte) ([][2]int, error) {
+	positions := sectionProperties(documentXml)
+	if len(positions) == 0 {
+		return nil, fmt.Errorf("the document does not have any sections")
+	}
+	changes := sectionPropertiesChangeRegex.FindAllIndex(documentXml, -1)
+
+	ranges := make([][2]int, 0, len(positions))
+	for _, pos := range positions {
+		if bytes.HasSuffix(documentXml[pos[0]:pos[1]], []byte("/>")) {
+			ranges = append(ranges, [2]int{pos[1], pos[1]})
+			continue
+		}
+		// the tracked change is the last child, the range ends in front of it so the previous properties
+		// inside the change are neither read nor modified
+		end, changeStart := pos[1], -1
+		for {
+			closeTag := bytes.Index(documentXml[end:], []byte("</w:sectPr>"))
+			if closeTag < 0 {
+				return nil, fmt.Errorf("invalid section properties at offset %d", pos[0])
+			}
+			change := containingRange(changes, end+closeTag)
+			if change == nil {
+				end += closeTag
+				break
+			}
+			if changeStart < 0 {
+				changeStart = change[0]
+			}
+			end = change[1]
+		}
+		if changeStart >= 0 {
+			end = changeStart
+		}
+		ranges = append(ranges, [2]int{pos[1], end})
+	}
+	return ranges, nil
+}
+
+// sectionProperties returns the positions of the open tags of all section properties, except the previous
+// section properties inside tracked changes (<w:sectPrChange>).
+func sectionProperties(documentXml []byte) [][]int {
+	changes := sectionPropertiesChangeRegex.FindAllIndex(documentXml, -1)
+	var positions [][]int
+	for _, pos := range SectionPropertiesRegex.FindAllIndex(documentXml, -1) {
+		if containingRange(changes, pos[0]) == nil {
+			positions = append(positions, pos)
+		}
+	}
+	return positions
+}
+
+// containingRange returns the range which contains the offset, or nil.
+func containingRange(ranges [][]int, offset int) []int {
+	for _, r := range ranges {
+		if offset >= r[0] && offset < r[1] {
+			return r
+		}
+	}
+	return nil
+}
+
+// textParagraphs converts the text into paragraphs, one per line.
+func textParagraphs(text string) string {
+	var sb strings.Builder
+	for _, line := range strings.Split(text, "\n") {
+		sb.WriteString("<w:p>")
+		if line != "" {
+			sb.WriteString(`<w:r><w:t xml:space="preserve">` + xmlEscape(line) + `</w:t></w:r>`)
+		}
+		sb.WriteString("</w:p>")
+	}
+	return sb.String()
+}
+
+// nextPartName returns the first name of the form prefix + number + suffix (e.g. word/header3.xml)
+// which is not used by any of the given existing parts.
+func nextPartName(prefix, suffix string, existing []string) string {
+	used := make(map[string]bool)
+	for _, name := range existing {
+		used[name] = true
+	}
+	for i := 1; ; i++ {
+		name := fmt.Sprintf("%s%d%s", prefix, i, suffix)
+		if !used[name] {
+			return name
+		}
+	}
+}
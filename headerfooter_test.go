@@ -0,0 +1,200 @@
+package docx
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDocument_AddHeader(t *testing.T) {
+	doc, err := Open("./test/template.docx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	header, err := doc.AddHeader(HeaderFirst, "Logo for {{.name}}\nSecond line")
+	if err != nil {
+		t.Fatal(err)
+	}
+	footer, err := doc.AddFooter(FooterEven, "Even page")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.SetTitlePage(AllSections, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.SetEvenAndOddHeaders(true); err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.ExecuteTemplate(map[string]interface{}{"name": "ACME"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := doc.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	doc.Close()
+
+	out, err := OpenBytes(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+
+	if !strings.Contains(string(out.GetFile(header)), "Logo for ACME") {
+		t.Errorf("header %s was not processed: %s", header, out.GetFile(header))
+	}
+	if !strings.Contains(string(out.GetFile(footer)), "Even page") {
+		t.Errorf("footer %s not found", footer)
+	}
+	if types, _ := out.packageContentTypes(); types.lookup("/"+header) != HeaderContentType {
+		t.Errorf("missing content type of %s", header)
+	}
+
+	documentXml := string(out.GetFile(DocumentXml))
+	if !strings.Contains(documentXml, `<w:headerReference w:type="first"`) ||
+		!strings.Contains(documentXml, `<w:footerReference w:type="even"`) {
+		t.Errorf("missing header or footer references in the section properties")
+	}
+	if titlePage, err := out.TitlePage(0); err != nil || !titlePage {
+		t.Errorf("expected a title page, got %v (%v)", titlePage, err)
+	}
+	if !out.EvenAndOddHeaders() {
+		t.Errorf("expected even and odd headers to be enabled")
+	}
+
+	if err := out.SetTitlePage(0, false); err != nil {
+		t.Fatal(err)
+	}
+	if titlePage, _ := out.TitlePage(0); titlePage {
+		t.Errorf("expected the title page to be disabled")
+	}
+	if _, err := out.AddSectionHeader(out.SectionCount(), HeaderDefault, "x"); err == nil {
+		t.Errorf("expected an error for a missing section")
+	}
+}
+
+func TestDocument_SectionPropertiesChange(t *testing.T) {
+	change := `<w:sectPrChange w:id="1" w:author="A"><w:sectPr><w:headerReference w:type="default" r:id="rId99"/><w:pgSz w:w="11906" w:h="16838"/><w:titlePg/></w:sectPr></w:sectPrChange>`
+	modify := func(name string, data []byte) []byte {
+		if name == DocumentXml {
+			i := bytes.LastIndex(data, []byte("</w:sectPr>"))
+			return append(append(append([]byte{}, data[:i]...), change...), data[i:]...)
+		}
+		return data
+	}
+	doc, err := OpenBytes(rewriteArchive(t, readFile(t, "./test/template.docx"), modify, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+
+	sections := doc.SectionCount()
+	if titlePage, err := doc.TitlePage(sections - 1); err != nil || titlePage {
+		t.Errorf("expected no title page in the last section, got %v (%v)", titlePage, err)
+	}
+	if err := doc.SetTitlePage(AllSections, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := doc.AddHeader(HeaderDefault, "Header"); err != nil {
+		t.Fatal(err)
+	}
+	if doc.SectionCount() != sections {
+		t.Errorf("expected %d sections, got %d", sections, doc.SectionCount())
+	}
+	documentXml := string(doc.GetFile(DocumentXml))
+	if !strings.Contains(documentXml, change+"</w:sectPr>") {
+		t.Errorf("the tracked change of the section properties was modified")
+	}
+	if strings.Count(documentXml, "<w:titlePg/>") != sections+1 {
+		t.Errorf("expected a title page in each of the %d sections", sections)
+	}
+	if titlePage, err := doc.TitlePage(sections - 1); err != nil || !titlePage {
+		t.Errorf("expected a title page in the last section, got %v (%v)", titlePage, err)
+	}
+}
+
+func TestDocument_AddHeader_Replace(t *testing.T) {
+	doc, err := Open("./test/template.docx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+
+	first, err := doc.AddHeader(HeaderDefault, "First")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := doc.AddHeader(HeaderDefault, "Second")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.GetFile(first) != nil {
+		t.Errorf("the replaced header %s was not removed", first)
+	}
+	if types, _ := doc.packageContentTypes(); types.lookup("/"+first) == HeaderContentType {
+		t.Errorf("the content type of the replaced header %s was not removed", first)
+	}
+	rels, err := doc.packageRelationships(DocumentXml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, rel := range rels.Relationships {
+		if resolveTarget(DocumentXml, rel.Target) == first {
+			t.Errorf("the relationship %s to the replaced header was not removed", rel.ID)
+		}
+	}
+	if !strings.Contains(string(doc.GetFile(second)), "Second") {
+		t.Errorf("header %s not found", second)
+	}
+
+	var buf bytes.Buffer
+	if err := doc.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out, err := OpenBytes(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	if out.GetFile(first) != nil || out.GetFile(second) == nil {
+		t.Errorf("expected only the header %s in the written document", second)
+	}
+}
+
+func TestDocument_AddHeader_UnloadedParts(t *testing.T) {
+	doc, err := OpenWithOptions("./test/template.docx", OpenOptions{Parts: PartBody})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+
+	original, _ := doc.loadPackageFile("word/header1.xml")
+	header, err := doc.AddHeader(HeaderEven, "Even page")
+	if err != nil {
+		t.Fatal(err)
+	}
+	footer, err := doc.AddFooter(FooterEven, "Even page")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header == "word/header1.xml" || footer == "word/footer1.xml" {
+		t.Fatalf("the new parts %s and %s replace the parts of the template", header, footer)
+	}
+
+	var buf bytes.Buffer
+	if err := doc.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out, err := OpenBytes(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	if kept, _ := out.loadPackageFile("word/header1.xml"); !bytes.Equal(kept, original) {
+		t.Errorf("the header of the template was not kept")
+	}
+	if !strings.Contains(string(out.GetFile(header)), "Even page") {
+		t.Errorf("header %s not found", header)
+	}
+}
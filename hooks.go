@@ -0,0 +1,68 @@
+package docx
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PlaceholderInfo describes the placeholder whose value is passed to a ReplaceHook, see also
+// ListTemplatePlaceholders.
+type PlaceholderInfo struct {
+	Part        string // The part containing the placeholder, e.g. word/document.xml or word/header1.xml
+	Placeholder string // The placeholder, e.g. {{.name}} or {name}
+	Field       string // The field the placeholder references, e.g. customer.name, empty if none
+	Type        string // The type declared by an annotation or by SetFieldTypes, e.g. money, empty if none
+}
+
+// ReplaceHook transforms the value of a placeholder before it replaces the placeholder, see OnReplace.
+type ReplaceHook func(p PlaceholderInfo, value string) string
+
+// OnReplace adds a hook which is called with the value of each placeholder before the value replaces it, e.g. to
+// trim values or to mask personal data in all templates without changing them. The hooks are called in the order
+// they were added, each one with the value returned by the previous one. They are called for the placeholders of
+// ExecuteTemplate and ReplaceAll; inside blocks and tables, each printed value is passed separately. Values are
+// passed as text before they are escaped, XML written by helpers such as {{image}} is not passed to the hooks.
+func (d *Document) OnReplace(hook ReplaceHook) {
+	d.replaceHooks = append(d.replaceHooks, hook)
+}
+
+// applyReplaceHooks returns the value transformed by all hooks added with OnReplace.
+func (d *Document) applyReplaceHooks(part, placeholder, value string) string {
+	if len(d.replaceHooks) == 0 {
+		return value
+	}
+	info := d.templateReplacer.placeholderInfo(part, placeholder)
+	for _, hook := range d.replaceHooks {
+		value = hook(info, value)
+	}
+	return value
+}
+
+// WriteTransform transforms the XML of a part before it is written, see OnWrite. The returned data is written
+// instead of the given data, which must not be modified in place.
+type WriteTransform func(part string, data []byte) ([]byte, error)
+
+// OnWrite adds a transform which is called with the XML of each part the document wrote or modified (e.g.
+// word/document.xml, the headers and footers, the relationships and the content types) whenever the document is
+// written, e.g. to inject custom XML or to strip vendor-specific nodes. Parts copied unchanged from the template
+// and media files are not passed. The transforms are called in the order they were added, each one with the data
+// returned by the previous one; the first error aborts the write and is returned by it. The document itself is not
+// modified, so writing it again transforms the original XML again.
+func (d *Document) OnWrite(transform WriteTransform) {
+	d.writeTransforms = append(d.writeTransforms, transform)
+}
+
+// applyWriteTransforms returns the data of the part transformed by all transforms added with OnWrite.
+func (d *Document) applyWriteTransforms(part string, data []byte) ([]byte, error) {
+	if !strings.HasSuffix(part, ".xml") && !strings.HasSuffix(part, ".rels") {
+		return data, nil
+	}
+	for _, transform := range d.writeTransforms {
+		transformed, err := transform(part, data)
+		if err != nil {
+			return nil, fmt.Errorf("unable to transform %s: %w", part, err)
+		}
+		data = transformed
+	}
+	return data, nil
+}
@@ -0,0 +1,110 @@
+package docx
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDocument_OnReplace(t *testing.T) {
+	body := `<w:body><w:p><w:r><w:t>Customer: {{.name}}</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>{{range .emails}}</w:t></w:r></w:p><w:p><w:r><w:t>Mail {{.}}</w:t></w:r></w:p><w:p><w:r><w:t>{{end}}</w:t></w:r></w:p>`
+	doc, err := OpenBytes(rewriteArchive(t, readFile(t, "./test/template.docx"), func(name string, data []byte) []byte {
+		if name == DocumentXml {
+			return []byte(strings.Replace(string(data), "<w:body>", body, 1))
+		}
+		return data
+	}, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+
+	var placeholders []string
+	doc.OnReplace(func(p PlaceholderInfo, value string) string {
+		placeholders = append(placeholders, p.Part+" "+p.Placeholder)
+		return strings.TrimSpace(value)
+	})
+	doc.OnReplace(func(p PlaceholderInfo, value string) string {
+		// mask the local part of e-mail addresses
+		if at := strings.Index(value, "@"); at > 0 {
+			return strings.Repeat("*", at) + value[at:]
+		}
+		return value
+	})
+
+	data := map[string]interface{}{"name": "  Anna Smith ", "emails": []string{"anna@example.com"}}
+	if err := doc.ExecuteTemplate(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.ReplaceAll(PlaceholderMap{"key": " value "}); err != nil {
+		t.Fatal(err)
+	}
+
+	documentXml := string(doc.GetFile(DocumentXml))
+	for _, expected := range []string{"<w:t>Customer: Anna Smith</w:t>", "<w:t>Mail ****@example.com</w:t>", "<w:t>value</w:t>"} {
+		if !strings.Contains(documentXml, expected) {
+			t.Errorf("expected %s in the document", expected)
+		}
+	}
+	joined := strings.Join(placeholders, "\n")
+	for _, expected := range []string{"word/document.xml {{.name}}", "word/document.xml {{.}}", "word/document.xml {key}", "word/header1.xml {key}"} {
+		if !strings.Contains(joined, expected) {
+			t.Errorf("expected the hook to be called for %s, got %v", expected, placeholders)
+		}
+	}
+}
+
+func TestDocument_OnWrite(t *testing.T) {
+	doc, err := Open("./test/template.docx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+	if err := doc.ReplaceAll(PlaceholderMap{"key": "value"}); err != nil {
+		t.Fatal(err)
+	}
+	original := string(doc.GetFile(DocumentXml))
+
+	var parts []string
+	doc.OnWrite(func(part string, data []byte) ([]byte, error) {
+		parts = append(parts, part)
+		return bytes.Replace(data, []byte("value"), []byte("first"), -1), nil
+	})
+	doc.OnWrite(func(part string, data []byte) ([]byte, error) {
+		return bytes.Replace(data, []byte("first"), []byte("second"), -1), nil
+	})
+
+	var buf bytes.Buffer
+	if err := doc.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(strings.Join(parts, " "), DocumentXml) {
+		t.Errorf("expected the transform to be called for %s, got %v", DocumentXml, parts)
+	}
+	for _, part := range parts {
+		if strings.HasPrefix(part, "word/media/") {
+			t.Errorf("the media file %s was passed to the transform", part)
+		}
+	}
+	written, err := OpenBytes(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer written.Close()
+	if documentXml := string(written.GetFile(DocumentXml)); !strings.Contains(documentXml, "second") || strings.Contains(documentXml, "first") {
+		t.Errorf("the transforms were not applied in order")
+	}
+	if string(doc.GetFile(DocumentXml)) != original {
+		t.Errorf("the transforms modified the document")
+	}
+
+	failure := errors.New("failure")
+	doc.OnWrite(func(part string, data []byte) ([]byte, error) {
+		return nil, failure
+	})
+	if err := doc.Write(&bytes.Buffer{}); !errors.Is(err, failure) {
+		t.Errorf("expected the error of the transform, got %v", err)
+	}
+}
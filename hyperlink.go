@@ -0,0 +1,123 @@
+package docx
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+// HyperlinkRelationshipType is the relationship type of the targets of hyperlinks.
+const HyperlinkRelationshipType = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/hyperlink"
+
+var (
+	// hyperlinkRegex matches a hyperlink (<w:hyperlink>), the first group is its start tag and the second group
+	// its content. Empty hyperlinks do not match the groups.
+	hyperlinkRegex = regexp.MustCompile(`(?s)<w:hyperlink\b[^>]*/>|(<w:hyperlink\b[^>]*>)(.*?)</w:hyperlink>`)
+	// hyperlinkTextRegex matches the text elements of the display text of a hyperlink.
+	hyperlinkTextRegex = regexp.MustCompile(`(?s)<w:t(?:\s[^>]*)?>(.*?)</w:t>`)
+)
+
+// SetHyperlinkTarget sets the URL of the hyperlinks whose display text equals the given text, e.g.
+// SetHyperlinkTarget("View invoice", url) for a link inserted in Word with a placeholder address. The display text
+// is compared without leading and trailing white space; call it after ExecuteTemplate to match the rendered text
+// of hyperlinks containing placeholders. The relationships of the hyperlinks are updated, hyperlinks to bookmarks
+// and hyperlinks sharing their relationship with other hyperlinks receive a new one. An error is returned if no
+// hyperlink of the main document, the headers, the footers, the notes or the comments has the text.
+func (d *Document) SetHyperlinkTarget(text, target string) error {
+	if target == "" {
+		return fmt.Errorf("missing target of the hyperlink %q", text)
+	}
+	found := false
+	for _, part := range d.xmlParts() {
+		changed, err := d.setHyperlinkTarget(part, strings.TrimSpace(text), target)
+		if err != nil {
+			return err
+		}
+		found = found || changed
+	}
+	if !found {
+		return fmt.Errorf("hyperlink %q not found", text)
+	}
+	return nil
+}
+
+// setHyperlinkTarget sets the target of the hyperlinks of the part with the display text and returns true if the
+// part contains any.
+func (d *Document) setHyperlinkTarget(part, text, target string) (bool, error) {
+	data := d.GetFile(part)
+	matches := hyperlinkRegex.FindAllSubmatchIndex(data, -1)
+
+	// the relationships referenced by other hyperlinks must not change
+	matching := make([]bool, len(matches))
+	shared := make(map[string]bool)
+	found := false
+	for i, match := range matches {
+		if match[2] < 0 {
+			continue
+		}
+		matching[i] = hyperlinkText(data[match[4]:match[5]]) == text
+		found = found || matching[i]
+		if id, exists := getTagAttr(data[match[2]:match[3]], "r:id"); exists && !matching[i] {
+			shared[id] = true
+		}
+	}
+	if !found {
+		return false, nil
+	}
+
+	rels, err := d.packageRelationships(part)
+	if err != nil {
+		return false, err
+	}
+	newID := ""
+	for i := len(matches) - 1; i >= 0; i-- {
+		if !matching[i] {
+			continue
+		}
+		tag := data[matches[i][2]:matches[i][3]]
+		if id, exists := getTagAttr(tag, "r:id"); exists && !shared[id] && updateHyperlinkRelationship(rels, id, target) {
+			continue
+		}
+		if newID == "" {
+			newID = rels.add(HyperlinkRelationshipType, target, TargetModeExternal)
+		}
+		newTag := tagAttrRegex("w:anchor").ReplaceAllLiteral(tag, nil)
+		newTag = setTagAttr(newTag, "r:id", newID)
+		data = bytes.Join([][]byte{data[:matches[i][2]], newTag, data[matches[i][3]:]}, nil)
+	}
+
+	if err := d.storeRelationships(part, rels); err != nil {
+		return false, err
+	}
+	if newID == "" {
+		return true, nil
+	}
+	if err := d.SetFile(part, data); err != nil {
+		return false, err
+	}
+	return true, d.refreshRuns(part)
+}
+
+// updateHyperlinkRelationship sets the target of the hyperlink relationship with the id and returns false if
+// there is none.
+func updateHyperlinkRelationship(rels *opcRelationships, id, target string) bool {
+	for i := range rels.Relationships {
+		if rel := &rels.Relationships[i]; rel.ID == id && rel.Type == HyperlinkRelationshipType {
+			rel.Target = target
+			rel.TargetMode = TargetModeExternal
+			return true
+		}
+	}
+	return false
+}
+
+// hyperlinkText returns the display text of the hyperlink content without leading and trailing white space.
+func hyperlinkText(content []byte) string {
+	var text strings.Builder
+	for _, match := range hyperlinkTextRegex.FindAllSubmatch(content, -1) {
+		text.WriteString(html.UnescapeString(string(match[1])))
+	}
+	return strings.TrimSpace(text.String())
+}
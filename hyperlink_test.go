@@ -0,0 +1,101 @@
+package docx
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// hyperlinkDocument returns the test template with the hyperlinks in front of its content. The relationships
+// rId90 and rId91 link to https://example.com/old.
+func hyperlinkDocument(t *testing.T, hyperlinks string) []byte {
+	return rewriteArchive(t, readFile(t, "./test/template.docx"), func(name string, data []byte) []byte {
+		switch name {
+		case DocumentXml:
+			return []byte(strings.Replace(string(data), "<w:body>", "<w:body><w:p>"+hyperlinks+"</w:p>", 1))
+		case DocumentRelsXml:
+			return []byte(strings.Replace(string(data), "</Relationships>",
+				`<Relationship Id="rId90" Type="`+HyperlinkRelationshipType+`" Target="https://example.com/old" TargetMode="External"/>`+
+					`<Relationship Id="rId91" Type="`+HyperlinkRelationshipType+`" Target="https://example.com/old" TargetMode="External"/></Relationships>`, 1))
+		}
+		return data
+	}, nil)
+}
+
+// hyperlinkTargets returns the targets of the hyperlinks of the main document by their display text.
+func hyperlinkTargets(t *testing.T, doc *Document) map[string]string {
+	rels, err := doc.Relationships(DocumentXml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	targets := make(map[string]string)
+	data := doc.GetFile(DocumentXml)
+	for _, match := range hyperlinkRegex.FindAllSubmatch(data, -1) {
+		id, _ := getTagAttr(match[1], "r:id")
+		for _, rel := range rels {
+			if rel.ID == id && rel.External() {
+				targets[hyperlinkText(match[2])] = rel.Target
+			}
+		}
+	}
+	return targets
+}
+
+func TestDocument_SetHyperlinkTarget(t *testing.T) {
+	run := func(text string) string {
+		return `<w:r><w:rPr><w:rStyle w:val="Hyperlink"/></w:rPr><w:t xml:space="preserve">` + text + `</w:t></w:r>`
+	}
+	doc, err := OpenBytes(hyperlinkDocument(t,
+		`<w:hyperlink r:id="rId90" w:history="1">`+run("View {{.")+run("document}}")+`</w:hyperlink>`+
+			`<w:hyperlink w:anchor="terms" w:history="1">`+run("Terms")+`</w:hyperlink>`+
+			`<w:hyperlink r:id="rId91">`+run("Shop")+`</w:hyperlink><w:hyperlink r:id="rId91">`+run("Store")+`</w:hyperlink>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+
+	// placeholders inside the display text are rendered like all others
+	if err := doc.NormalizePlaceholders(); err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.ExecuteTemplate(map[string]interface{}{"document": "invoice"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.SetHyperlinkTarget("View invoice", "https://example.com/invoices?id=42&download=1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.SetHyperlinkTarget("Terms", "https://example.com/terms"); err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.SetHyperlinkTarget(" Shop ", "https://shop.example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.SetHyperlinkTarget("Unknown", "https://example.com"); err == nil {
+		t.Error("expected an error for a missing hyperlink")
+	}
+
+	var buf bytes.Buffer
+	if err := doc.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	written, err := OpenBytes(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer written.Close()
+	expected := map[string]string{
+		"View invoice": "https://example.com/invoices?id=42&download=1",
+		"Terms":        "https://example.com/terms",
+		"Shop":         "https://shop.example.com",
+		"Store":        "https://example.com/old",
+	}
+	targets := hyperlinkTargets(t, written)
+	for text, target := range expected {
+		if targets[text] != target {
+			t.Errorf("expected %q to link to %s, got %q", text, target, targets[text])
+		}
+	}
+	if strings.Contains(string(written.GetFile(DocumentXml)), `w:anchor="terms"`) {
+		t.Error("expected the bookmark of the hyperlink to be removed")
+	}
+}
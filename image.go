@@ -0,0 +1,275 @@
+package docx
+
+import (
+	"bytes"
+	"fmt"
+	_ "image/gif"  // register the GIF format for image.DecodeConfig
+	_ "image/jpeg" // register the JPEG format for image.DecodeConfig
+	_ "image/png"  // register the PNG format for image.DecodeConfig
+	"regexp"
+	"strconv"
+)
+
+const (
+	// ImageRelationshipType is the relationship type of images referenced by drawings.
+	ImageRelationshipType = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/image"
+
+	// emuPerPixel converts pixels (at 96 dpi) to English Metric Units, the unit of DrawingML.
+	emuPerPixel = 9525
+)
+
+// blipEmbedRegex matches the reference of a picture to its image part.
+var blipEmbedRegex = regexp.MustCompile(`<a:blip\b[^>]*\br:embed="([^"]*)"`)
+
+// imageContentTypes maps the formats of image.DecodeConfig to their file extension and content type.
+var imageContentTypes = map[string][2]string{
+	"png":  {"png", "image/png"},
+	"jpeg": {"jpeg", "image/jpeg"},
+	"gif":  {"gif", "image/gif"},
+}
+
+// Image is an image which is inserted into the document by the {{image}} template function.
+// Instead of an Image, the raw image data ([]byte) may be passed, it is inserted with its natural size.
+type Image struct {
+	Data    []byte // PNG, JPEG, GIF, EMF, WMF or TIFF data, see SetImageConverter
+	Width   int    // width in pixels, 0 uses the width of the image or keeps the aspect ratio if Height is set
+	Height  int    // height in pixels, 0 uses the height of the image or keeps the aspect ratio if Width is set
+	Name    string // the name of the drawing as shown in Word's selection pane
+	AltText string // the alternative text of the drawing
+}
+
+// rawXML is the output of helpers which write XML, e.g. {{image}}. Unlike all other values it is not escaped.
+type rawXML string
+
+// imageHelper implements {{image VALUE}}. The image is added to the package and a drawing is inserted in place
+// of the action, which splits the surrounding run. Missing values are empty.
+func (tr *TemplateReplacer) imageHelper(value interface{}) (rawXML, error) {
+	var img Image
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case Image:
+		img = v
+	case *Image:
+		if v == nil {
+			return "", nil
+		}
+		img = *v
+	case []byte:
+		img = Image{Data: v}
+	default:
+		return "", fmt.Errorf("image: unsupported value of type %T, expected docx.Image or []byte", value)
+	}
+
+	img.Name, img.AltText = escapeMarkers(img.Name), escapeMarkers(img.AltText)
+	drawing, err := tr.document.addImageDrawing(tr.part, img)
+	if err != nil {
+		return "", fmt.Errorf("image: %w", err)
+	}
+	// the action is part of a text element, the drawing needs a run of its own
+	return rawXML(`</w:t></w:r><w:r>` + drawing + `</w:r><w:r><w:t xml:space="preserve">`), nil
+}
+
+// ReplaceImageByName replaces the image of all pictures whose name (as shown in Word's selection pane) or
+// alternative text is the given name, see Drawing. Unlike replacing the media file itself, this keeps working
+// when Word renumbers the media files of the template. The pictures keep their size. Pictures sharing the image
+// with the named picture show the new image as well.
+func (d *Document) ReplaceImageByName(name string, data []byte) error {
+	data, format, err := d.prepareImage(data)
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for _, drawing := range d.Drawings() {
+		if drawing.Name != name && drawing.AltText != name {
+			continue
+		}
+		if drawing.relID == "" {
+			return fmt.Errorf("drawing %s is not a picture", name)
+		}
+		if err := d.replaceImagePart(drawing.FileName, drawing.relID, data, format.Extension, format.ContentType); err != nil {
+			return err
+		}
+		found = true
+	}
+	if !found {
+		return fmt.Errorf("image %s not found", name)
+	}
+	return nil
+}
+
+// replaceImagePart replaces the image the relationship of the part refers to. If the content type of the image
+// part does not fit the new image, a new image part is added and the relationship is changed to it.
+func (d *Document) replaceImagePart(sourcePart, relID string, data []byte, extension, contentType string) error {
+	rels, err := d.packageRelationships(sourcePart)
+	if err != nil {
+		return err
+	}
+	types, err := d.packageContentTypes()
+	if err != nil {
+		return err
+	}
+
+	for i, rel := range rels.Relationships {
+		if rel.ID != relID {
+			continue
+		}
+		if rel.Type != ImageRelationshipType || rel.TargetMode == TargetModeExternal {
+			return fmt.Errorf("relationship %s of %s does not refer to an embedded image", relID, sourcePart)
+		}
+		target := resolveTarget(sourcePart, rel.Target)
+		if types.lookup("/"+target) == contentType {
+			d.setMediaFile(target, data)
+			return nil
+		}
+
+		fileName, err := d.addMedia("image", data, extension, contentType)
+		if err != nil {
+			return err
+		}
+		rels.Relationships[i].Target = relativeTarget(sourcePart, fileName)
+		return d.storeRelationships(sourcePart, rels)
+	}
+	return fmt.Errorf("relationship %s of %s not found", relID, sourcePart)
+}
+
+// drawingImageID returns the relationship id of the image shown by the drawing whose <wp:docPr> ends at the
+// given offset, or an empty string if the drawing is not a picture.
+func drawingImageID(data []byte, offset int) string {
+	rest := data[offset:]
+	end := len(rest)
+	if i := bytes.Index(rest, []byte("</w:drawing>")); i >= 0 {
+		end = i
+	}
+	// drawings nested in text boxes have properties of their own
+	if loc := docPrRegex.FindIndex(rest); loc != nil && loc[0] < end {
+		end = loc[0]
+	}
+	if match := blipEmbedRegex.FindSubmatch(rest[:end]); match != nil {
+		return string(match[1])
+	}
+	return ""
+}
+
+// imageRelationships returns the image parts referenced by the source part by relationship id.
+func (d *Document) imageRelationships(sourcePart string) map[string]string {
+	images := make(map[string]string)
+	rels, err := d.packageRelationships(sourcePart)
+	if err != nil {
+		return images
+	}
+	for _, rel := range rels.Relationships {
+		if rel.Type == ImageRelationshipType && rel.TargetMode != TargetModeExternal {
+			images[rel.ID] = resolveTarget(sourcePart, rel.Target)
+		}
+	}
+	return images
+}
+
+// loadImageParts reads the images referenced by the document, headers and footers which parseArchive did not
+// read, e.g. images of headers stored outside of word/media. They are handled like all other media files.
+func (d *Document) loadImageParts() error {
+	for _, part := range d.xmlParts() {
+		for _, target := range d.imageRelationships(part) {
+			if d.hasFile(target) {
+				continue
+			}
+			file := d.archiveFile(target)
+			if file == nil {
+				continue
+			}
+			data, err := readZipFileBytes(file)
+			if err != nil {
+				d.warn(target, err)
+				continue
+			}
+			d.setMediaFile(target, data)
+		}
+	}
+	return nil
+}
+
+// addImageDrawing adds the image to the package, references it from the given part and returns the
+// <w:drawing> element which shows it inline.
+func (d *Document) addImageDrawing(sourcePart string, img Image) (string, error) {
+	data, format, err := d.prepareImage(img.Data)
+	if err != nil {
+		return "", err
+	}
+
+	width, height := img.Width, img.Height
+	switch {
+	case width == 0 && height == 0:
+		width, height = format.Width, format.Height
+	case width == 0 && format.Height > 0:
+		width = height * format.Width / format.Height
+	case height == 0 && format.Width > 0:
+		height = width * format.Height / format.Width
+	}
+	if width == 0 || height == 0 {
+		return "", fmt.Errorf("the size of the %s image is unknown, set its width and height", format.Name)
+	}
+
+	fileName, err := d.addMedia("image", data, format.Extension, format.ContentType)
+	if err != nil {
+		return "", err
+	}
+	relID, err := d.addRelationship(sourcePart, ImageRelationshipType, fileName)
+	if err != nil {
+		return "", err
+	}
+
+	id := d.nextDrawingID()
+	name := img.Name
+	if name == "" {
+		name = "Picture " + strconv.Itoa(id)
+	}
+	cx, cy := width*emuPerPixel, height*emuPerPixel
+
+	return fmt.Sprintf(`<w:drawing>`+
+		`<wp:inline distT="0" distB="0" distL="0" distR="0" xmlns:wp="http://schemas.openxmlformats.org/drawingml/2006/wordprocessingDrawing">`+
+		`<wp:extent cx="%d" cy="%d"/><wp:docPr id="%d" name="%s" descr="%s"/>`+
+		`<wp:cNvGraphicFramePr><a:graphicFrameLocks xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main" noChangeAspect="1"/></wp:cNvGraphicFramePr>`+
+		`<a:graphic xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main">`+
+		`<a:graphicData uri="http://schemas.openxmlformats.org/drawingml/2006/picture">`+
+		`<pic:pic xmlns:pic="http://schemas.openxmlformats.org/drawingml/2006/picture">`+
+		`<pic:nvPicPr><pic:cNvPr id="0" name="%s"/><pic:cNvPicPr/></pic:nvPicPr>`+
+		`<pic:blipFill><a:blip r:embed="%s" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"/><a:stretch><a:fillRect/></a:stretch></pic:blipFill>`+
+		`<pic:spPr><a:xfrm><a:off x="0" y="0"/><a:ext cx="%d" cy="%d"/></a:xfrm><a:prstGeom prst="rect"><a:avLst/></a:prstGeom></pic:spPr>`+
+		`</pic:pic></a:graphicData></a:graphic></wp:inline></w:drawing>`,
+		cx, cy, id, xmlEscape(name), xmlEscape(img.AltText), xmlEscape(name), relID, cx, cy), nil
+}
+
+// addMedia adds a new media part (e.g. word/media/image3.png) and returns its name.
+func (d *Document) addMedia(prefix string, data []byte, extension, contentType string) (string, error) {
+	fileName := nextPartName("word/media/"+prefix, "."+extension, d.partNames())
+	if err := d.ensureContentType(fileName, contentType); err != nil {
+		return "", err
+	}
+	d.setMediaFile(fileName, data)
+	return fileName, nil
+}
+
+// setMediaFile adds a media file, which is accessible by GetFile and SetFile like the media files of the archive.
+func (d *Document) setMediaFile(fileName string, data []byte) {
+	if !d.hasFile(fileName) {
+		d.mediaFiles = append(d.mediaFiles, fileName)
+	}
+	delete(d.lazyFiles, fileName)
+	d.files[fileName] = data
+	delete(d.removedFiles, fileName)
+}
+
+// nextDrawingID returns an id which is not used by any drawing of the document yet. The ids handed out
+// before are remembered, since their drawings might not be part of the document yet.
+func (d *Document) nextDrawingID() int {
+	id := d.lastDrawingID + 1
+	for _, drawing := range d.Drawings() {
+		if n, err := strconv.Atoi(drawing.ID); err == nil && n >= id {
+			id = n + 1
+		}
+	}
+	d.lastDrawingID = id
+	return id
+}
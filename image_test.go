@@ -0,0 +1,149 @@
+package docx
+
+import (
+	"bytes"
+	"image"
+	"image/gif"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func TestDocument_ReplaceImageByName(t *testing.T) {
+	encode := func(width int, format string) []byte {
+		var buf bytes.Buffer
+		img := image.NewRGBA(image.Rect(0, 0, width, 10))
+		var err error
+		if format == "gif" {
+			err = gif.Encode(&buf, img, nil)
+		} else {
+			err = png.Encode(&buf, img)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+
+	doc, err := Open("./test/template.docx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+
+	var drawings string
+	for _, img := range []Image{{Data: encode(10, "png"), Name: "Logo", AltText: "Company logo"}, {Data: encode(20, "png"), Name: "Photo"}} {
+		drawing, err := doc.addImageDrawing(DocumentXml, img)
+		if err != nil {
+			t.Fatal(err)
+		}
+		drawings += `<w:p><w:r>` + drawing + `</w:r></w:p>`
+	}
+	if err := doc.SetFile(DocumentXml, []byte(strings.Replace(string(doc.GetFile(DocumentXml)), "<w:body>", "<w:body>"+drawings, 1))); err != nil {
+		t.Fatal(err)
+	}
+	targets := doc.relationshipTargets(DocumentXml, ImageRelationshipType)
+	logo, photo := targets[len(targets)-2], targets[len(targets)-1]
+
+	// the same format replaces the image part
+	replacement := encode(30, "png")
+	if err := doc.ReplaceImageByName("Logo", replacement); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(doc.GetFile(logo), replacement) {
+		t.Errorf("the image part %s was not replaced", logo)
+	}
+
+	// other formats are added as new part, the alternative text identifies the picture as well
+	replacement = encode(40, "gif")
+	if err := doc.ReplaceImageByName("Company logo", replacement); err != nil {
+		t.Fatal(err)
+	}
+	targets = doc.relationshipTargets(DocumentXml, ImageRelationshipType)
+	if target := targets[len(targets)-2]; !strings.HasSuffix(target, ".gif") || !bytes.Equal(doc.GetFile(target), replacement) {
+		t.Errorf("expected the relationship to refer to the new gif image, got %s", target)
+	}
+	if targets[len(targets)-1] != photo || len(doc.GetFile(photo)) == len(replacement) {
+		t.Errorf("the image of another picture was changed")
+	}
+
+	if err := doc.ReplaceImageByName("Missing", replacement); err == nil {
+		t.Errorf("expected an error for a missing picture")
+	}
+	if err := doc.ReplaceImageByName("Logo", []byte("no image")); err == nil {
+		t.Errorf("expected an error for invalid image data")
+	}
+
+	var out bytes.Buffer
+	if err := doc.Write(&out); err != nil {
+		t.Fatal(err)
+	}
+	written, err := OpenBytes(out.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	targets = written.relationshipTargets(DocumentXml, ImageRelationshipType)
+	if !bytes.Equal(written.GetFile(targets[len(targets)-2]), replacement) {
+		t.Errorf("the new image was not written")
+	}
+}
+
+func TestDocument_HeaderImages(t *testing.T) {
+	var logo bytes.Buffer
+	if err := png.Encode(&logo, image.NewRGBA(image.Rect(0, 0, 10, 10))); err != nil {
+		t.Fatal(err)
+	}
+	drawing := `<w:drawing><wp:inline><wp:extent cx="95250" cy="95250"/><wp:docPr id="7" name="Header logo"/>` +
+		`<a:graphic><a:graphicData><pic:pic><pic:blipFill><a:blip r:embed="rId1"/></pic:blipFill></pic:pic></a:graphicData></a:graphic></wp:inline></w:drawing>`
+	doc, err := OpenBytes(rewriteArchive(t, readFile(t, "./test/template.docx"), func(name string, data []byte) []byte {
+		switch name {
+		case "word/header1.xml":
+			return []byte(strings.Replace(string(data), "<w:tab/>", "<w:tab/></w:r><w:r>"+drawing, 1))
+		case ContentTypesXml:
+			return []byte(strings.Replace(string(data), "<Default ", `<Default Extension="png" ContentType="image/png"/><Default `, 1))
+		}
+		return data
+	}, map[string][]byte{
+		"word/_rels/header1.xml.rels": []byte(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+			`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="` + ImageRelationshipType + `" Target="images/logo.png"/></Relationships>`),
+		"word/images/logo.png": logo.Bytes(),
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+
+	// images outside of word/media are read as well
+	if !bytes.Equal(doc.GetFile("word/images/logo.png"), logo.Bytes()) {
+		t.Fatalf("the header image was not read")
+	}
+	var found *Drawing
+	for _, d := range doc.Drawings() {
+		if d.Name == "Header logo" {
+			found = &d
+		}
+	}
+	if found == nil || found.FileName != "word/header1.xml" || found.Image != "word/images/logo.png" {
+		t.Fatalf("the header drawing was not found: %+v", found)
+	}
+
+	var replacement bytes.Buffer
+	if err := png.Encode(&replacement, image.NewRGBA(image.Rect(0, 0, 20, 20))); err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.ReplaceImageByName("Header logo", replacement.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := doc.Write(&out); err != nil {
+		t.Fatal(err)
+	}
+	written, err := OpenBytes(out.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(written.GetFile("word/images/logo.png"), replacement.Bytes()) {
+		t.Errorf("the header image was not replaced")
+	}
+}
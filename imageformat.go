@@ -0,0 +1,146 @@
+package docx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+)
+
+// imageformat.go detects the format and the natural size of image data. Besides the formats of the image
+// package (PNG, JPEG and GIF), the metafiles (EMF and WMF) and TIFF images of enterprise templates are
+// recognized. They are passed through as they are, or converted by the ImageConverter of the document.
+
+// ImageConverter converts image data of the given format (emf, wmf or tiff) to PNG, JPEG or GIF data,
+// e.g. by calling an external tool. See Document.SetImageConverter.
+type ImageConverter func(data []byte, format string) ([]byte, error)
+
+// imageFormat describes image data which is added to the package.
+type imageFormat struct {
+	Name        string // png, jpeg, gif, emf, wmf or tiff
+	Extension   string
+	ContentType string
+	Width       int // natural width in pixels, 0 if unknown
+	Height      int // natural height in pixels, 0 if unknown
+}
+
+// passthroughContentTypes maps the formats which are not decoded by the image package to their file extension
+// and content type.
+var passthroughContentTypes = map[string][2]string{
+	"emf":  {"emf", "image/x-emf"},
+	"wmf":  {"wmf", "image/x-wmf"},
+	"tiff": {"tiff", "image/tiff"},
+}
+
+// SetImageConverter sets the converter for EMF, WMF and TIFF images which are inserted by {{image}} or
+// ReplaceImageByName. Without converter, these images are added as they are.
+func (d *Document) SetImageConverter(converter ImageConverter) {
+	d.imageConverter = converter
+}
+
+// prepareImage returns the image data which is added to the package together with its format. EMF, WMF and
+// TIFF data is converted if an image converter is set.
+func (d *Document) prepareImage(data []byte) ([]byte, imageFormat, error) {
+	format, err := decodeImageFormat(data)
+	if err != nil {
+		return nil, format, err
+	}
+	if _, passthrough := passthroughContentTypes[format.Name]; !passthrough || d.imageConverter == nil {
+		return data, format, nil
+	}
+
+	converted, err := d.imageConverter(data, format.Name)
+	if err != nil {
+		return nil, format, fmt.Errorf("unable to convert %s image: %w", format.Name, err)
+	}
+	convertedFormat, err := decodeImageFormat(converted)
+	if err != nil {
+		return nil, format, fmt.Errorf("invalid result of the %s image conversion: %w", format.Name, err)
+	}
+	if _, passthrough := passthroughContentTypes[convertedFormat.Name]; passthrough {
+		return nil, format, fmt.Errorf("the %s image was converted to %s instead of PNG, JPEG or GIF", format.Name, convertedFormat.Name)
+	}
+	return converted, convertedFormat, nil
+}
+
+// decodeImageFormat detects the format and the natural size of the image data.
+func decodeImageFormat(data []byte) (imageFormat, error) {
+	if config, name, err := image.DecodeConfig(bytes.NewReader(data)); err == nil {
+		contentType, supported := imageContentTypes[name]
+		if !supported {
+			return imageFormat{}, fmt.Errorf("unsupported image format %s", name)
+		}
+		return imageFormat{name, contentType[0], contentType[1], config.Width, config.Height}, nil
+	}
+
+	var name string
+	var width, height int
+	switch {
+	case len(data) >= 88 && binary.LittleEndian.Uint32(data) == 1 && string(data[40:44]) == " EMF":
+		// the frame of the header is given in 0.01 mm
+		name = "emf"
+		width = hundredthMillimetersToPixels(int32(binary.LittleEndian.Uint32(data[32:])) - int32(binary.LittleEndian.Uint32(data[24:])))
+		height = hundredthMillimetersToPixels(int32(binary.LittleEndian.Uint32(data[36:])) - int32(binary.LittleEndian.Uint32(data[28:])))
+	case len(data) >= 22 && binary.LittleEndian.Uint32(data) == 0x9AC6CDD7:
+		// the placeable header gives the bounding box in units per inch
+		name = "wmf"
+		if inch := int(binary.LittleEndian.Uint16(data[14:])); inch > 0 {
+			width = (int(int16(binary.LittleEndian.Uint16(data[10:]))) - int(int16(binary.LittleEndian.Uint16(data[6:])))) * 96 / inch
+			height = (int(int16(binary.LittleEndian.Uint16(data[12:]))) - int(int16(binary.LittleEndian.Uint16(data[8:])))) * 96 / inch
+		}
+	case len(data) >= 18 && (bytes.HasPrefix(data, []byte{1, 0, 9, 0}) || bytes.HasPrefix(data, []byte{2, 0, 9, 0})):
+		// metafiles without placeable header do not define their size
+		name = "wmf"
+	case bytes.HasPrefix(data, []byte("II*\x00")) || bytes.HasPrefix(data, []byte("MM\x00*")):
+		name = "tiff"
+		width, height = tiffSize(data)
+	default:
+		return imageFormat{}, fmt.Errorf("unable to decode image: %w", image.ErrFormat)
+	}
+
+	contentType := passthroughContentTypes[name]
+	return imageFormat{name, contentType[0], contentType[1], max(width, 0), max(height, 0)}, nil
+}
+
+// hundredthMillimetersToPixels converts a length in 0.01 mm to pixels at 96 dpi.
+func hundredthMillimetersToPixels(length int32) int {
+	return int(int64(length) * 96 / 2540)
+}
+
+// tiffSize returns the size of the first image of the TIFF data, or 0 if it cannot be read.
+func tiffSize(data []byte) (int, int) {
+	if len(data) < 8 {
+		return 0, 0
+	}
+	var order binary.ByteOrder = binary.LittleEndian
+	if data[0] == 'M' {
+		order = binary.BigEndian
+	}
+	ifd := int(order.Uint32(data[4:]))
+	if ifd < 0 || ifd+2 > len(data) {
+		return 0, 0
+	}
+
+	var width, height int
+	entries := int(order.Uint16(data[ifd:]))
+	for i := 0; i < entries; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(data) {
+			break
+		}
+		var value int
+		switch order.Uint16(data[entry+2:]) {
+		case 3: // SHORT
+			value = int(order.Uint16(data[entry+8:]))
+		case 4: // LONG
+			value = int(order.Uint32(data[entry+8:]))
+		}
+		switch order.Uint16(data[entry:]) {
+		case 256: // ImageWidth
+			width = value
+		case 257: // ImageLength
+			height = value
+		}
+	}
+	return width, height
+}
@@ -0,0 +1,122 @@
+package docx
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+// testMetafiles returns a minimal EMF, placeable WMF and TIFF image of 96x48 pixels.
+func testMetafiles() map[string][]byte {
+	emf := make([]byte, 88)
+	binary.LittleEndian.PutUint32(emf, 1)
+	for i, v := range []uint32{0, 0, 2540, 1270} { // the frame in 0.01 mm
+		binary.LittleEndian.PutUint32(emf[24+4*i:], v)
+	}
+	copy(emf[40:], " EMF")
+
+	wmf := make([]byte, 40)
+	binary.LittleEndian.PutUint32(wmf, 0x9AC6CDD7)
+	for i, v := range []uint16{0, 0, 1440, 720, 1440} { // the bounding box and the units per inch
+		binary.LittleEndian.PutUint16(wmf[6+2*i:], v)
+	}
+
+	tiff := []byte("II*\x00\x08\x00\x00\x00\x02\x00")
+	tiff = binary.LittleEndian.AppendUint16(tiff, 256)
+	tiff = binary.LittleEndian.AppendUint16(tiff, 3)
+	tiff = binary.LittleEndian.AppendUint32(tiff, 1)
+	tiff = binary.LittleEndian.AppendUint32(tiff, 96)
+	tiff = binary.LittleEndian.AppendUint16(tiff, 257)
+	tiff = binary.LittleEndian.AppendUint16(tiff, 4)
+	tiff = binary.LittleEndian.AppendUint32(tiff, 1)
+	tiff = binary.LittleEndian.AppendUint32(tiff, 48)
+
+	return map[string][]byte{"emf": emf, "wmf": wmf, "tiff": tiff}
+}
+
+func TestDecodeImageFormat(t *testing.T) {
+	for name, data := range testMetafiles() {
+		format, err := decodeImageFormat(data)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if format.Name != name || format.Extension != passthroughContentTypes[name][0] || format.Width != 96 || format.Height != 48 {
+			t.Errorf("%s: unexpected format %+v", name, format)
+		}
+	}
+
+	format, err := decodeImageFormat([]byte{1, 0, 9, 0, 0, 3, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0})
+	if err != nil || format.Name != "wmf" || format.Width != 0 {
+		t.Errorf("expected a WMF image of unknown size, got %+v, %v", format, err)
+	}
+	if _, err := decodeImageFormat([]byte("no image")); !errors.Is(err, image.ErrFormat) {
+		t.Errorf("expected image.ErrFormat, got %v", err)
+	}
+}
+
+func TestDocument_SetImageConverter(t *testing.T) {
+	metafiles := testMetafiles()
+	doc, err := OpenBytes(rewriteArchive(t, readFile(t, "./test/template.docx"), func(name string, data []byte) []byte {
+		if name == DocumentXml {
+			return []byte(strings.Replace(string(data), "<w:body>", "<w:body>"+
+				`<w:p><w:r><w:t>{{image .emf}}{{image .tiff}}</w:t></w:r></w:p>`, 1))
+		}
+		return data
+	}, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+
+	var converted bytes.Buffer
+	if err := png.Encode(&converted, image.NewRGBA(image.Rect(0, 0, 10, 5))); err != nil {
+		t.Fatal(err)
+	}
+	doc.SetImageConverter(func(data []byte, format string) ([]byte, error) {
+		if format != "tiff" {
+			return nil, errors.New("unexpected format " + format)
+		}
+		return converted.Bytes(), nil
+	})
+
+	// the converter fails for EMF images
+	if err := doc.ExecuteTemplate(map[string]interface{}{"emf": metafiles["emf"], "tiff": metafiles["tiff"]}); err == nil {
+		t.Fatal("expected the error of the converter")
+	}
+
+	// without converter the images are passed through, otherwise they are converted
+	doc.SetImageConverter(nil)
+	if err := doc.ExecuteTemplate(map[string]interface{}{"emf": Image{Data: metafiles["emf"], Width: 192}, "tiff": nil}); err != nil {
+		t.Fatal(err)
+	}
+	targets := doc.relationshipTargets(DocumentXml, ImageRelationshipType)
+	emf := targets[len(targets)-1]
+	if !strings.HasSuffix(emf, ".emf") || !bytes.Equal(doc.GetFile(emf), metafiles["emf"]) {
+		t.Errorf("expected the EMF image to be passed through, got %s", emf)
+	}
+	if !strings.Contains(string(doc.GetFile(DocumentXml)), `<wp:extent cx="1828800" cy="914400"/>`) {
+		t.Errorf("expected the size of the EMF image to keep its aspect ratio")
+	}
+	types, err := doc.packageContentTypes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if contentType := types.lookup("/" + emf); contentType != "image/x-emf" {
+		t.Errorf("unexpected content type %s", contentType)
+	}
+
+	doc.SetImageConverter(func(data []byte, format string) ([]byte, error) {
+		return converted.Bytes(), nil
+	})
+	if err := doc.ReplaceImageByName("Picture "+doc.Drawings()[0].ID, metafiles["wmf"]); err != nil {
+		t.Fatal(err)
+	}
+	targets = doc.relationshipTargets(DocumentXml, ImageRelationshipType)
+	if target := targets[len(targets)-1]; !strings.HasSuffix(target, ".png") || !bytes.Equal(doc.GetFile(target), converted.Bytes()) {
+		t.Errorf("expected the converted image, got %s", target)
+	}
+}
@@ -0,0 +1,182 @@
+package docx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Manifest describes a document without processing it, see Inspect.
+type Manifest struct {
+	Type  DocumentType
+	Parts []PartInfo // All files of the archive in the order of the archive
+	Media []string   // The media files, e.g. word/media/image1.png
+	// Placeholders counts the template actions (e.g. {{.name}}) and the placeholders of ReplaceAll (e.g. {name})
+	// in the body, headers, footers, footnotes, endnotes, comments and SmartArt diagrams. The text of shapes
+	// which Word stores twice (see textbox.go) is counted once.
+	Placeholders map[string]int
+	// Properties are the core, extended and custom properties by their lowercase names, e.g. title or company.
+	Properties map[string]string
+}
+
+// PartInfo describes a file of the archive of a document.
+type PartInfo struct {
+	Name           string // The path inside the archive, e.g. word/document.xml
+	ContentType    string // The content type declared in [Content_Types].xml, empty if there is none
+	Size           int64  // The uncompressed size in bytes
+	CompressedSize int64  // The size in bytes inside the archive
+}
+
+// Inspect reads the manifest of the document at the given path, e.g. to index a catalog of thousands of templates.
+// Only the parts holding text and properties are read and the runs are not parsed, so inspecting a document is
+// much faster than opening it.
+func Inspect(path string) (Manifest, error) {
+	rc, err := zip.OpenReader(path)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("unable to open zip reader: %s", err)
+	}
+	defer rc.Close()
+	return inspect(&rc.Reader)
+}
+
+// InspectBytes reads the manifest of the document like Inspect.
+func InspectBytes(b []byte) (Manifest, error) {
+	rc, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		return Manifest{}, fmt.Errorf("unable to open zip reader: %s", err)
+	}
+	return inspect(rc)
+}
+
+// inspect reads the manifest of the archive. The parts are read through a document which is not parsed.
+func inspect(zipFile *zip.Reader) (Manifest, error) {
+	doc := &Document{
+		zipFile:      zipFile,
+		files:        make(FileMap),
+		packageFiles: make(FileMap),
+		removedFiles: make(map[string]bool),
+	}
+	if !doc.hasArchiveFile(DocumentXml) {
+		return Manifest{}, fmt.Errorf("invalid docx archive, %s is missing", DocumentXml)
+	}
+	contentTypesXml, _ := doc.loadPackageFile(ContentTypesXml)
+	docType, err := detectDocumentType(contentTypesXml)
+	if err != nil {
+		return Manifest{}, err
+	}
+	types, _ := parseContentTypes(contentTypesXml)
+
+	manifest := Manifest{Type: docType, Placeholders: make(map[string]int), Properties: doc.documentProperties()}
+	for _, file := range zipFile.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		info := PartInfo{Name: file.Name, Size: int64(file.UncompressedSize64), CompressedSize: int64(file.CompressedSize64)}
+		if types != nil {
+			info.ContentType = types.lookup("/" + file.Name)
+		}
+		manifest.Parts = append(manifest.Parts, info)
+		if MediaPathRegex.MatchString(file.Name) {
+			manifest.Media = append(manifest.Media, file.Name)
+		}
+		if !isStoryPart(file.Name) {
+			continue
+		}
+
+		data, err := readZipFileBytes(file)
+		if err != nil {
+			return Manifest{}, err
+		}
+		texts, err := paragraphTexts(data)
+		if err != nil {
+			return Manifest{}, fmt.Errorf("failed to read %s: %w", file.Name, err)
+		}
+		for _, text := range texts {
+			countPlaceholders(text, manifest.Placeholders)
+		}
+	}
+	return manifest, nil
+}
+
+// isStoryPart returns true if the part holds text which is processed by templates: the main document, the parts of
+// the other stories (see storyTypes) and the data of SmartArt diagrams.
+func isStoryPart(name string) bool {
+	if name == DocumentXml || DiagramDataPathRegex.MatchString(name) {
+		return true
+	}
+	for _, story := range storyTypes {
+		if story.regex.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// paragraphTexts returns the text of all paragraphs of the part, including paragraphs nested in tables and text
+// boxes. The fallback of shapes (<mc:Fallback>) is skipped, it repeats the text of the choice.
+func paragraphTexts(data []byte) ([]string, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+
+	var texts []string
+	var open []int     // indices of the currently open paragraphs
+	fallbackDepth := 0 // the number of open fallback elements
+	inText := false
+	for {
+		tok, err := decoder.Token()
+		if tok == nil || err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error getting token: %s", err)
+		}
+
+		switch elem := tok.(type) {
+		case xml.StartElement:
+			switch {
+			case elem.Name.Local == "Fallback":
+				fallbackDepth++
+			case fallbackDepth > 0:
+			case elem.Name.Local == ParagraphElementName:
+				texts = append(texts, "")
+				open = append(open, len(texts)-1)
+			case elem.Name.Local == TextElementName:
+				inText = true
+			}
+		case xml.EndElement:
+			switch {
+			case elem.Name.Local == "Fallback":
+				fallbackDepth--
+			case fallbackDepth > 0:
+			case elem.Name.Local == ParagraphElementName && len(open) > 0:
+				open = open[:len(open)-1]
+			case elem.Name.Local == TextElementName:
+				inText = false
+			}
+		case xml.CharData:
+			if inText && fallbackDepth == 0 && len(open) > 0 {
+				texts[open[len(open)-1]] += string(elem)
+			}
+		}
+	}
+	return texts, nil
+}
+
+// countPlaceholders adds the template actions and the placeholders of ReplaceAll in the text to the counts.
+func countPlaceholders(text string, counts map[string]int) {
+	var rest strings.Builder
+	pos := 0
+	for _, action := range findTemplateActions(text) {
+		counts[text[action.Start:action.End]]++
+		rest.WriteString(text[pos:action.Start])
+		// the action is left out, so its braces are not taken for a placeholder of ReplaceAll
+		rest.WriteByte(' ')
+		pos = action.End
+	}
+	rest.WriteString(text[pos:])
+	for _, placeholder := range stringPlaceholderRegex.FindAllString(rest.String(), -1) {
+		counts[placeholder]++
+	}
+}
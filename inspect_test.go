@@ -0,0 +1,50 @@
+package docx
+
+import (
+	"testing"
+)
+
+func TestInspect(t *testing.T) {
+	manifest, err := Inspect("./test/template.docx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest.Type != TypeDocx {
+		t.Errorf("unexpected type %s", manifest.Type)
+	}
+	if len(manifest.Media) != 1 || manifest.Media[0] != "word/media/image1.jpg" {
+		t.Errorf("unexpected media %v", manifest.Media)
+	}
+	var document PartInfo
+	for _, part := range manifest.Parts {
+		if part.Name == DocumentXml {
+			document = part
+		}
+	}
+	if document.ContentType != TypeDocx.ContentType() {
+		t.Errorf("unexpected content type %s", document.ContentType)
+	}
+	if document.Size == 0 || document.CompressedSize == 0 {
+		t.Errorf("the sizes of %s are missing", DocumentXml)
+	}
+	// including the placeholders in the header and the footer and the one split across runs
+	if count := manifest.Placeholders["{key}"]; count != 9 {
+		t.Errorf("expected 9 placeholders {key}, got %d", count)
+	}
+	if manifest.Properties["application"] != "Microsoft Office Word" {
+		t.Errorf("unexpected properties %v", manifest.Properties)
+	}
+}
+
+func TestInspectBytes_TextBox(t *testing.T) {
+	manifest, err := InspectBytes(textBoxDocument(t, `<w:p><w:r><w:t>{{.name}} {name}</w:t></w:r></w:p>`, `<w:p><w:r><w:t>{{.name}} {name}</w:t></w:r></w:p>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest.Placeholders["{{.name}}"] != 1 || manifest.Placeholders["{name}"] != 1 {
+		t.Errorf("the text box must be counted once, got %v", manifest.Placeholders)
+	}
+	if _, exists := manifest.Placeholders["{.name}"]; exists {
+		t.Errorf("the action was taken for a placeholder of ReplaceAll")
+	}
+}
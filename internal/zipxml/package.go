@@ -0,0 +1,130 @@
+// Package zipxml fills the placeholders of zip packages of XML parts, like OpenDocument texts, presentations
+// and spreadsheets. The formats differ in their paragraph and text elements only, see Syntax.
+package zipxml
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// file is an entry of the package.
+type file struct {
+	name   string
+	method uint16
+	data   []byte
+}
+
+// Package holds the files of a zip package in memory, in their original order.
+type Package struct {
+	files []*file
+}
+
+// ReadPackage reads all files of the zip package.
+func ReadPackage(data []byte) (*Package, error) {
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("unable to open the package: %w", err)
+	}
+	pkg := &Package{}
+	for _, entry := range reader.File {
+		content, err := readEntry(entry)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read %s: %w", entry.Name, err)
+		}
+		pkg.files = append(pkg.files, &file{name: entry.Name, method: entry.Method, data: content})
+	}
+	return pkg, nil
+}
+
+// readEntry returns the uncompressed content of the entry.
+func readEntry(entry *zip.File) ([]byte, error) {
+	reader, err := entry.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = reader.Close()
+	}()
+	return io.ReadAll(reader)
+}
+
+// Names returns the names of the files whose name starts with the prefix and ends with the suffix.
+func (p *Package) Names(prefix, suffix string) []string {
+	var names []string
+	for _, f := range p.files {
+		if strings.HasPrefix(f.name, prefix) && strings.HasSuffix(f.name, suffix) {
+			names = append(names, f.name)
+		}
+	}
+	return names
+}
+
+// GetFile returns the content of the file, or nil if it does not exist.
+func (p *Package) GetFile(name string) []byte {
+	for _, f := range p.files {
+		if f.name == name {
+			return f.data
+		}
+	}
+	return nil
+}
+
+// SetFile replaces the content of an existing file or adds a new file to the package.
+func (p *Package) SetFile(name string, data []byte) {
+	for _, f := range p.files {
+		if f.name == name {
+			f.data = data
+			return
+		}
+	}
+	p.files = append(p.files, &file{name: name, method: zip.Deflate, data: data})
+}
+
+// Write writes the package to the writer. If first is not empty, that file is written first and uncompressed,
+// e.g. the mimetype file of OpenDocument packages.
+func (p *Package) Write(writer io.Writer, first string) error {
+	zipWriter := zip.NewWriter(writer)
+	files := make([]*file, 0, len(p.files))
+	for _, f := range p.files {
+		if first != "" && f.name == first {
+			files = append([]*file{f}, files...)
+		} else {
+			files = append(files, f)
+		}
+	}
+	for _, f := range files {
+		method := f.method
+		if first != "" && f.name == first {
+			method = zip.Store
+		}
+		entry, err := zipWriter.CreateHeader(&zip.FileHeader{Name: f.name, Method: method})
+		if err != nil {
+			return err
+		}
+		if _, err := entry.Write(f.data); err != nil {
+			return err
+		}
+	}
+	return zipWriter.Close()
+}
+
+// WriteToFile writes the package to a new file like Write. The directories of the path are created if necessary.
+func (p *Package) WriteToFile(path, first string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("unable to ensure path directories: %s", err)
+	}
+	target, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := p.Write(target, first); err != nil {
+		_ = target.Close()
+		return err
+	}
+	return target.Close()
+}
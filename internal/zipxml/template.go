@@ -0,0 +1,298 @@
+package zipxml
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html"
+	"regexp"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/izetmolla/docx"
+	"github.com/izetmolla/docx/templating"
+)
+
+var (
+	// tagRegex matches a start, end or empty element tag, or a declaration.
+	tagRegex = regexp.MustCompile(`<[^>]*>`)
+	// tagNameRegex matches the name of a tag, the first group is empty for start tags and / for end tags.
+	tagNameRegex = regexp.MustCompile(`^<(/?)([\w.:-]+)`)
+	// controlActionRegex matches the actions of text/template which must be executed together with their block.
+	controlActionRegex = regexp.MustCompile(`^-?\s*(?:if|else|end|range|with|define|block|template|break|continue)\b`)
+	// textEscaper escapes character data.
+	textEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+)
+
+// Syntax describes the paragraphs and the text of the XML parts of a format.
+type Syntax struct {
+	// Paragraphs are the elements whose text is searched for placeholders, e.g. text:p and text:h.
+	Paragraphs []string
+	// Text is the element holding the text of the runs of a paragraph, e.g. a:t. If empty, all character data of
+	// a paragraph is its text.
+	Text string
+	// Inline are the elements which do not interrupt the text of a paragraph without a Text element, e.g. spans.
+	Inline map[string]bool
+	// Breaks are the elements which interrupt the text of a paragraph with a Text element, e.g. line breaks.
+	Breaks map[string]bool
+	// Escape escapes an inserted value, the value is escaped as character data if nil.
+	Escape func(value string) string
+}
+
+// interrupts reports whether the element interrupts the text of a paragraph, so placeholders cannot span it.
+func (s Syntax) interrupts(name string) bool {
+	if s.Text != "" {
+		return s.Breaks[name]
+	}
+	return !s.Inline[name]
+}
+
+// escape escapes an inserted value.
+func (s Syntax) escape(value string) string {
+	if s.Escape != nil {
+		return s.Escape(value)
+	}
+	return textEscaper.Replace(value)
+}
+
+// Template evaluates the placeholders of the parts of a package like the docx package does: with text/template
+// by default or with an alternative engine.
+type Template struct {
+	Engine     templating.Engine
+	Funcs      template.FuncMap
+	MissingKey docx.MissingKeyPolicy
+}
+
+// AddFuncs adds custom functions to the text/template placeholders.
+func (t *Template) AddFuncs(funcMap template.FuncMap) {
+	if t.Funcs == nil {
+		t.Funcs = make(template.FuncMap)
+	}
+	for name, fn := range funcMap {
+		t.Funcs[name] = fn
+	}
+}
+
+// Execute evaluates the placeholders of the parts with the data. Control structures of text/template are
+// supported within a paragraph.
+func (t *Template) Execute(pkg *Package, parts []string, syntax Syntax, data docx.TemplateData) error {
+	if data == nil {
+		return fmt.Errorf("template data not set")
+	}
+	if t.Engine != nil {
+		left, right := t.Engine.Delimiters()
+		return t.replaceParts(pkg, parts, syntax, left, right, false, func(expression string) (string, error) {
+			return t.Engine.Execute(expression, data)
+		})
+	}
+	return t.replaceParts(pkg, parts, syntax, "{{", "}}", true, func(expression string) (string, error) {
+		return t.executeAction("{{"+expression+"}}", data)
+	})
+}
+
+// ReplaceAll replaces the string-based placeholders delimited with { and } with the values of the map.
+// Placeholders which are not in the map are left unchanged.
+func (t *Template) ReplaceAll(pkg *Package, parts []string, syntax Syntax, replaceMap docx.PlaceholderMap) error {
+	return t.replaceParts(pkg, parts, syntax, "{", "}", false, func(key string) (string, error) {
+		value, exists := replaceMap[key]
+		if !exists {
+			return "", templating.ErrMissingValue
+		}
+		return value, nil
+	})
+}
+
+// executeAction executes the actions of a placeholder with text/template.
+func (t *Template) executeAction(action string, data docx.TemplateData) (string, error) {
+	tmpl, err := template.New("zipxml-template").Funcs(t.Funcs).Option("missingkey=error").Parse(action)
+	if err != nil {
+		return "", fmt.Errorf("invalid placeholder %s: %w", action, err)
+	}
+	var buf strings.Builder
+	if err := tmpl.Execute(&buf, data); err != nil {
+		if isMissingValueError(err) {
+			return "", fmt.Errorf("%w: %s", templating.ErrMissingValue, action)
+		}
+		return "", fmt.Errorf("unable to execute placeholder %s: %w", action, err)
+	}
+	return buf.String(), nil
+}
+
+// isMissingValueError reports whether text/template failed because a field or key does not exist in the data.
+func isMissingValueError(err error) bool {
+	for _, message := range []string{"map has no entry for key", "can't evaluate field", "nil pointer evaluating"} {
+		if strings.Contains(err.Error(), message) {
+			return true
+		}
+	}
+	return false
+}
+
+// evaluator returns the replacement of the expression of a placeholder.
+type evaluator func(expression string) (string, error)
+
+// replaceParts replaces the placeholders of the parts. With joinControls, the placeholders of a paragraph
+// containing control structures of text/template are evaluated together, see evaluateParagraph.
+func (t *Template) replaceParts(pkg *Package, parts []string, syntax Syntax, left, right string, joinControls bool, evaluate evaluator) error {
+	if left == "" || right == "" {
+		return errors.New("the template engine has empty delimiters")
+	}
+	for _, part := range parts {
+		data := pkg.GetFile(part)
+		if data == nil {
+			continue
+		}
+		replaced, err := t.replacePlaceholders(data, syntax, left, right, joinControls, evaluate)
+		if err != nil {
+			return fmt.Errorf("%s: %w", part, err)
+		}
+		pkg.SetFile(part, replaced)
+	}
+	return nil
+}
+
+// segment is the character data between two tags of a paragraph.
+type segment struct {
+	start, end int    // the position of the escaped character data in the part
+	offset     int    // the position of the text in the text of the paragraph
+	text       string // the unescaped text
+}
+
+// replacement replaces a range of the text of a paragraph.
+type replacement struct {
+	start, end int
+	value      string
+}
+
+// replacePlaceholders replaces the placeholders of each paragraph of the part. The text of a paragraph is
+// collected across its runs, so placeholders split by formatting are found.
+func (t *Template) replacePlaceholders(data []byte, syntax Syntax, left, right string, joinControls bool, evaluate evaluator) ([]byte, error) {
+	placeholderRegex := regexp.MustCompile(`(?s)` + regexp.QuoteMeta(left) + `(.*?)` + regexp.QuoteMeta(right))
+	var result bytes.Buffer
+	written := 0
+	var segments []segment
+	var text strings.Builder
+	flush := func() error {
+		defer func() {
+			segments = segments[:0]
+			text.Reset()
+		}()
+		replacements, err := t.evaluateParagraph(text.String(), placeholderRegex, len(left), len(right), joinControls, evaluate)
+		if err != nil || len(replacements) == 0 {
+			return err
+		}
+		for _, seg := range segments {
+			result.Write(data[written:seg.start])
+			result.WriteString(replaceSegment(seg, replacements, syntax))
+			written = seg.end
+		}
+		return nil
+	}
+
+	depth := 0
+	inText := syntax.Text == ""
+	position := 0
+	for _, tag := range tagRegex.FindAllIndex(data, -1) {
+		if depth > 0 && inText && tag[0] > position {
+			unescaped := html.UnescapeString(string(data[position:tag[0]]))
+			segments = append(segments, segment{start: position, end: tag[0], offset: text.Len(), text: unescaped})
+			text.WriteString(unescaped)
+		}
+		position = tag[1]
+
+		match := tagNameRegex.FindSubmatch(data[tag[0]:tag[1]])
+		if match == nil {
+			continue
+		}
+		name := string(match[2])
+		closing, empty := len(match[1]) > 0, bytes.HasSuffix(data[tag[0]:tag[1]], []byte("/>"))
+		if syntax.Text != "" && name == syntax.Text {
+			inText = !closing && !empty
+			continue
+		}
+		paragraph := slices.Contains(syntax.Paragraphs, name)
+		if !paragraph && !syntax.interrupts(name) {
+			continue
+		}
+		if err := flush(); err != nil {
+			return nil, err
+		}
+		if paragraph {
+			switch {
+			case closing:
+				depth--
+			case !empty:
+				depth++
+			}
+		}
+	}
+	result.Write(data[written:])
+	return result.Bytes(), nil
+}
+
+// evaluateParagraph evaluates the placeholders of the text of a paragraph. With joinControls, the text from the
+// first to the last placeholder of a paragraph containing control structures is evaluated as a whole, so
+// {{if}} and {{range}} work within the paragraph.
+func (t *Template) evaluateParagraph(text string, placeholderRegex *regexp.Regexp, leftLen, rightLen int, joinControls bool,
+	evaluate evaluator) ([]replacement, error) {
+	matches := placeholderRegex.FindAllStringSubmatchIndex(text, -1)
+	if len(matches) == 0 {
+		return nil, nil
+	}
+	if joinControls {
+		for _, match := range matches {
+			if controlActionRegex.MatchString(text[match[2]:match[3]]) {
+				first, last := matches[0], matches[len(matches)-1]
+				matches = [][]int{{first[0], last[1], first[0] + leftLen, last[1] - rightLen}}
+				break
+			}
+		}
+	}
+
+	var replacements []replacement
+	for _, match := range matches {
+		value, err := evaluate(text[match[2]:match[3]])
+		if errors.Is(err, templating.ErrMissingValue) {
+			switch t.MissingKey {
+			case docx.MissingKeyKeep:
+				continue
+			case docx.MissingKeyEmpty:
+				value, err = "", nil
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		replacements = append(replacements, replacement{start: match[0], end: match[1], value: value})
+	}
+	return replacements, nil
+}
+
+// replaceSegment returns the escaped character data of the segment with the replacements applied. A value is
+// inserted into the segment its placeholder starts in, the rest of the placeholder is removed from the others.
+func replaceSegment(seg segment, replacements []replacement, syntax Syntax) string {
+	var result, kept strings.Builder
+	flushKept := func() {
+		result.WriteString(textEscaper.Replace(kept.String()))
+		kept.Reset()
+	}
+	for i := 0; i < len(seg.text); i++ {
+		position := seg.offset + i
+		replaced := false
+		for _, r := range replacements {
+			if position == r.start {
+				flushKept()
+				result.WriteString(syntax.escape(r.value))
+			}
+			if position >= r.start && position < r.end {
+				replaced = true
+			}
+		}
+		if !replaced {
+			kept.WriteByte(seg.text[i])
+		}
+	}
+	flushKept()
+	return result.String()
+}
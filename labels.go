@@ -0,0 +1,251 @@
+package docx
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// labels.go creates the classic mail merge outputs besides letters: sheets of address labels and envelopes.
+// Each record is rendered with a small template, e.g. a label.docx containing "{{.name}}", "{{.street}}" and
+// "{{.city}}" paragraphs, and the rendered bodies are laid out on the pages of a new document.
+
+// LabelSheet describes a sheet of labels arranged in a grid. All lengths are in twips (1/1440 inch).
+type LabelSheet struct {
+	PageWidth, PageHeight   int
+	TopMargin, LeftMargin   int // position of the first label
+	LabelWidth, LabelHeight int
+	HorizontalGap           int // space between the columns
+	VerticalGap             int // space between the rows
+	Columns, Rows           int
+}
+
+var (
+	// Avery5160 is a letter sheet of 30 address labels of 2 5/8 x 1 inch.
+	Avery5160 = LabelSheet{PageWidth: 12240, PageHeight: 15840, TopMargin: 720, LeftMargin: 270,
+		LabelWidth: 3780, LabelHeight: 1440, HorizontalGap: 180, Columns: 3, Rows: 10}
+	// Avery5163 is a letter sheet of 10 shipping labels of 4 x 2 inch.
+	Avery5163 = LabelSheet{PageWidth: 12240, PageHeight: 15840, TopMargin: 720, LeftMargin: 225,
+		LabelWidth: 5760, LabelHeight: 2880, HorizontalGap: 270, Columns: 2, Rows: 5}
+	// AveryL7163 is an A4 sheet of 14 address labels of 99.1 x 38.1 mm.
+	AveryL7163 = LabelSheet{PageWidth: 11906, PageHeight: 16838, TopMargin: 856, LeftMargin: 266,
+		LabelWidth: 5618, LabelHeight: 2160, HorizontalGap: 142, Columns: 2, Rows: 7}
+)
+
+// Envelope describes the page of an envelope and the position of the delivery address. All lengths are in twips.
+type Envelope struct {
+	Width, Height           int // the envelope is printed in landscape orientation
+	AddressLeft, AddressTop int // position of the delivery address on the envelope
+	AddressWidth            int
+	ReturnAddress           string // printed in the top left corner, lines are separated by line breaks
+	ReturnAddressMargin     int    // distance of the return address from the edges, half an inch if zero
+}
+
+var (
+	// Envelope10 is the US #10 envelope of 9 1/2 x 4 1/8 inch.
+	Envelope10 = Envelope{Width: 13680, Height: 5940, AddressLeft: 5760, AddressTop: 2880, AddressWidth: 5760}
+	// EnvelopeDL is the DL envelope of 220 x 110 mm.
+	EnvelopeDL = Envelope{Width: 12474, Height: 6237, AddressLeft: 5670, AddressTop: 2835, AddressWidth: 5669}
+	// EnvelopeC5 is the C5 envelope of 229 x 162 mm.
+	EnvelopeC5 = Envelope{Width: 12983, Height: 9185, AddressLeft: 6237, AddressTop: 4252, AddressWidth: 5669}
+)
+
+// labelCellMargin is the space between the edges of a label and its text.
+const labelCellMargin = 86
+
+// Labels renders the template for each record and places the results on sheets of labels, row by row, e.g.
+// Labels(tpl, addresses, docx.Avery5160). The labels of a sheet form a table with rows of exact height, the
+// remaining labels of the last sheet are empty. The records are bound like the data of Render.
+func Labels[T any](tpl *Template, records []T, sheet LabelSheet) (*Document, error) {
+	if sheet.Columns < 1 || sheet.Rows < 1 || sheet.LabelWidth < 1 || sheet.LabelHeight < 1 {
+		return nil, fmt.Errorf("invalid label sheet %+v", sheet)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("no records to merge")
+	}
+	used := sheet.Rows*sheet.LabelHeight + (sheet.Rows-1)*sheet.VerticalGap
+	if sheet.TopMargin+used > sheet.PageHeight || sheet.LeftMargin+sheet.Columns*sheet.LabelWidth+(sheet.Columns-1)*sheet.HorizontalGap > sheet.PageWidth {
+		return nil, fmt.Errorf("the labels do not fit on the page")
+	}
+
+	// the bottom margin leaves space for the rows of one sheet only, so the rows of the next sheet start a new page
+	bottom := max(0, sheet.PageHeight-sheet.TopMargin-used-20)
+	doc, err := newMergeDocument(fmt.Sprintf(`<w:pgSz w:w="%d" w:h="%d"/>`+
+		`<w:pgMar w:top="%d" w:right="0" w:bottom="%d" w:left="%d" w:header="0" w:footer="0" w:gutter="0"/>`,
+		sheet.PageWidth, sheet.PageHeight, sheet.TopMargin, bottom, sheet.LeftMargin))
+	if err != nil {
+		return nil, err
+	}
+
+	cells := make([]string, 0, len(records))
+	for i, record := range records {
+		body, err := doc.renderFragment(tpl, record)
+		if err != nil {
+			doc.Close()
+			return nil, fmt.Errorf("unable to render record %d: %w", i+1, err)
+		}
+		if !bytes.HasSuffix(bytes.TrimSpace(body), []byte("</w:p>")) && !bytes.HasSuffix(bytes.TrimSpace(body), []byte("<w:p/>")) {
+			// table cells must end with a paragraph
+			body = append(body, []byte("<w:p/>")...)
+		}
+		cells = append(cells, string(body))
+	}
+	perSheet := sheet.Columns * sheet.Rows
+	for len(cells)%perSheet != 0 {
+		cells = append(cells, "<w:p/>")
+	}
+
+	var table strings.Builder
+	width := sheet.Columns*sheet.LabelWidth + (sheet.Columns-1)*sheet.HorizontalGap
+	fmt.Fprintf(&table, `<w:tbl><w:tblPr><w:tblW w:w="%d" w:type="dxa"/><w:tblInd w:w="0" w:type="dxa"/><w:tblLayout w:type="fixed"/>`+
+		`<w:tblCellMar><w:left w:w="%d" w:type="dxa"/><w:right w:w="%d" w:type="dxa"/></w:tblCellMar>`+
+		`<w:tblLook w:val="0000" w:firstRow="0" w:lastRow="0" w:firstColumn="0" w:lastColumn="0" w:noHBand="0" w:noVBand="0"/></w:tblPr><w:tblGrid>`,
+		width, labelCellMargin, labelCellMargin)
+	for column := range sheet.Columns {
+		if column > 0 && sheet.HorizontalGap > 0 {
+			fmt.Fprintf(&table, `<w:gridCol w:w="%d"/>`, sheet.HorizontalGap)
+		}
+		fmt.Fprintf(&table, `<w:gridCol w:w="%d"/>`, sheet.LabelWidth)
+	}
+	table.WriteString(`</w:tblGrid>`)
+	row := func(height int, cells []string) {
+		fmt.Fprintf(&table, `<w:tr><w:trPr><w:cantSplit/><w:trHeight w:val="%d" w:hRule="exact"/></w:trPr>`, height)
+		for column, cell := range cells {
+			if column > 0 && sheet.HorizontalGap > 0 {
+				fmt.Fprintf(&table, `<w:tc><w:tcPr><w:tcW w:w="%d" w:type="dxa"/></w:tcPr><w:p/></w:tc>`, sheet.HorizontalGap)
+			}
+			fmt.Fprintf(&table, `<w:tc><w:tcPr><w:tcW w:w="%d" w:type="dxa"/></w:tcPr>%s</w:tc>`, sheet.LabelWidth, cell)
+		}
+		table.WriteString(`</w:tr>`)
+	}
+	for start := 0; start < len(cells); start += sheet.Columns {
+		if sheet.VerticalGap > 0 && start%perSheet != 0 {
+			row(sheet.VerticalGap, slices.Repeat([]string{"<w:p/>"}, sheet.Columns))
+		}
+		row(sheet.LabelHeight, cells[start:start+sheet.Columns])
+	}
+	table.WriteString(`</w:tbl>`)
+
+	// the body ends with a paragraph, it is kept small so it fits below the labels of the last sheet
+	table.WriteString(`<w:p><w:pPr><w:spacing w:before="0" w:after="0" w:line="14" w:lineRule="exact"/><w:rPr><w:sz w:val="2"/></w:rPr></w:pPr></w:p>`)
+	if err := doc.setMergeBody([]byte(table.String())); err != nil {
+		doc.Close()
+		return nil, err
+	}
+	return doc, nil
+}
+
+// Envelopes renders the template for each record as the delivery address of an envelope, e.g.
+// Envelopes(tpl, addresses, docx.EnvelopeDL). Every envelope is a page of its own; the address is placed in a
+// frame at the position of the envelope, the return address in the top left corner. The records are bound like
+// the data of Render.
+func Envelopes[T any](tpl *Template, records []T, envelope Envelope) (*Document, error) {
+	if envelope.Width < 1 || envelope.Height < 1 || envelope.AddressWidth < 1 {
+		return nil, fmt.Errorf("invalid envelope %+v", envelope)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("no records to merge")
+	}
+	margin := envelope.ReturnAddressMargin
+	if margin == 0 {
+		margin = 720
+	}
+	doc, err := newMergeDocument(fmt.Sprintf(`<w:pgSz w:w="%d" w:h="%d" w:orient="landscape"/>`+
+		`<w:pgMar w:top="%d" w:right="%d" w:bottom="%d" w:left="%d" w:header="0" w:footer="0" w:gutter="0"/>`,
+		envelope.Width, envelope.Height, margin, margin, margin, margin))
+	if err != nil {
+		return nil, err
+	}
+
+	frame := []byte(fmt.Sprintf(`<w:framePr w:w="%d" w:hSpace="180" w:wrap="around" w:hAnchor="page" w:vAnchor="page" w:x="%d" w:y="%d"/>`,
+		envelope.AddressWidth, envelope.AddressLeft, envelope.AddressTop))
+	returnAddress := []byte(`<w:p><w:pPr><w:spacing w:after="0"/></w:pPr>` + builderRuns(envelope.ReturnAddress) + `</w:p>`)
+	var body []byte
+	for i, record := range records {
+		address, err := doc.renderFragment(tpl, record)
+		if err != nil {
+			doc.Close()
+			return nil, fmt.Errorf("unable to render record %d: %w", i+1, err)
+		}
+		if address, err = framedParagraphs(address, frame); err != nil {
+			doc.Close()
+			return nil, err
+		}
+		// the return address anchors the frame and starts the page of the envelope
+		envelopeBody := append(append([]byte{}, returnAddress...), address...)
+		if i > 0 {
+			envelopeBody = pageBreakBefore(envelopeBody)
+		}
+		body = append(body, envelopeBody...)
+	}
+	if err := doc.setMergeBody(body); err != nil {
+		doc.Close()
+		return nil, err
+	}
+	return doc, nil
+}
+
+// newMergeDocument returns a new document whose section has the properties.
+func newMergeDocument(properties string) (*Document, error) {
+	doc, err := NewDocument().Build()
+	if err != nil {
+		return nil, err
+	}
+	documentXml, err := modifySections(doc.GetFile(DocumentXml), AllSections, func([]byte) []byte {
+		return []byte(properties)
+	})
+	if err == nil {
+		err = doc.SetFile(DocumentXml, documentXml)
+	}
+	if err != nil {
+		doc.Close()
+		return nil, err
+	}
+	return doc, nil
+}
+
+// renderFragment renders the template with the record and returns its body, imported into the document.
+func (d *Document) renderFragment(tpl *Template, record interface{}) ([]byte, error) {
+	rendered, err := tpl.render(SchemaFor(record), bindData(reflect.ValueOf(record)), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer rendered.Close()
+	return d.importBody(rendered)
+}
+
+// setMergeBody inserts the content in front of the section properties of the document body.
+func (d *Document) setMergeBody(content []byte) error {
+	documentXml := d.GetFile(DocumentXml)
+	loc := bodyRegex.FindSubmatchIndex(documentXml)
+	if loc == nil {
+		return fmt.Errorf("the document does not have a body")
+	}
+	documentXml = insertAt(documentXml, loc[2], content)
+	if err := d.SetFile(DocumentXml, documentXml); err != nil {
+		return err
+	}
+	return d.refreshRuns(DocumentXml)
+}
+
+// framedParagraphs puts the paragraphs of the body into the frame. Consecutive paragraphs with the same frame
+// properties share the frame.
+func framedParagraphs(body, frame []byte) ([]byte, error) {
+	wrapped := append(append([]byte("<w:body>"), body...), []byte("</w:body>")...)
+	paragraphs, err := findBlockParagraphs(wrapped)
+	if err != nil {
+		return nil, err
+	}
+	var replacements []replacement
+	for _, paragraph := range paragraphs {
+		if paragraph.Parent != 0 {
+			continue
+		}
+		framed := setNestedProperty(wrapped[paragraph.Start:paragraph.End], paragraphOpenTagRegex, "w:pPr", "w:framePr", frame, paragraphPropertiesOrder)
+		replacements = append(replacements, replacement{paragraph.Start, paragraph.End, framed})
+	}
+	wrapped = applyReplacements(wrapped, replacements)
+	return wrapped[len("<w:body>") : len(wrapped)-len("</w:body>")], nil
+}
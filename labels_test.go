@@ -0,0 +1,106 @@
+package docx
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// addressTemplate returns a template with the name and the city of an address.
+func addressTemplate(t *testing.T) *Template {
+	doc, err := NewDocument().Paragraph("{{.name}}").Paragraph("{{.city}}").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+	var buf bytes.Buffer
+	if err := doc.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	tpl, err := NewTemplate(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tpl
+}
+
+func TestLabels(t *testing.T) {
+	addresses := []map[string]interface{}{
+		{"name": "Ann", "city": "Bern"}, {"name": "Bob", "city": "Basel"}, {"name": "Cid", "city": "Chur"},
+		{"name": "Dan", "city": "Genf"}, {"name": "Eve", "city": "Thun"},
+	}
+	tests := []struct {
+		name  string
+		sheet LabelSheet
+		rows  int // rows of labels
+		gaps  int // rows between the labels
+		cells int // cells of the width of a label, including the empty labels and the rows between the labels
+	}{
+		{"Avery5163", Avery5163, 5, 0, 10},
+		{"vertical gaps", LabelSheet{PageWidth: 12240, PageHeight: 15840, TopMargin: 720, LeftMargin: 720,
+			LabelWidth: 5000, LabelHeight: 3000, VerticalGap: 360, Columns: 2, Rows: 2}, 4, 2, 12},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := Labels(addressTemplate(t), addresses, tt.sheet)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer doc.Close()
+
+			documentXml := string(doc.GetFile(DocumentXml))
+			if strings.Count(documentXml, "<w:tbl>") != 1 {
+				t.Errorf("expected a single table of labels")
+			}
+			if count := strings.Count(documentXml, `<w:trHeight w:val="`+strconv.Itoa(tt.sheet.LabelHeight)+`" w:hRule="exact"/>`); count != tt.rows {
+				t.Errorf("expected %d rows of labels, got %d", tt.rows, count)
+			}
+			if count := strings.Count(documentXml, `<w:trHeight w:val="`+strconv.Itoa(tt.sheet.VerticalGap)+`" w:hRule="exact"/>`); tt.gaps > 0 && count != tt.gaps {
+				t.Errorf("expected %d rows between the labels, got %d", tt.gaps, count)
+			}
+			if count := strings.Count(documentXml, `<w:tcW w:w="`+strconv.Itoa(tt.sheet.LabelWidth)+`" w:type="dxa"/>`); count != tt.cells {
+				t.Errorf("expected %d cells, got %d", tt.cells, count)
+			}
+			if !strings.Contains(documentXml, `<w:pgSz w:w="12240" w:h="15840"/>`) {
+				t.Errorf("expected the page size of the sheet")
+			}
+			texts, err := paragraphTexts(doc.GetFile(DocumentXml))
+			if err != nil {
+				t.Fatal(err)
+			}
+			text := strings.Join(texts, "|")
+			if !strings.Contains(text, "Ann|Bern") || strings.Index(text, "Ann") > strings.Index(text, "Eve") || strings.Contains(text, "{{") {
+				t.Errorf("expected the addresses in order, got %q", text)
+			}
+		})
+	}
+
+	if _, err := Labels(addressTemplate(t), addresses, LabelSheet{PageWidth: 12240, PageHeight: 15840, LabelWidth: 6000, LabelHeight: 1440, Columns: 3, Rows: 1}); err == nil {
+		t.Errorf("expected an error for labels wider than the page")
+	}
+}
+
+func TestEnvelopes(t *testing.T) {
+	envelope := EnvelopeDL
+	envelope.ReturnAddress = "ACME\nMain Street 1"
+	doc, err := Envelopes(addressTemplate(t), []map[string]interface{}{{"name": "Ann", "city": "Bern"}, {"name": "Bob", "city": "Basel"}}, envelope)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+
+	documentXml := string(doc.GetFile(DocumentXml))
+	if !strings.Contains(documentXml, `<w:pgSz w:w="12474" w:h="6237" w:orient="landscape"/>`) {
+		t.Errorf("expected the page size of the envelope")
+	}
+	if count := strings.Count(documentXml, `w:x="5670" w:y="2835"`); count != 4 {
+		t.Errorf("expected the address lines of both envelopes in frames, got %d", count)
+	}
+	if count := strings.Count(documentXml, "<w:pageBreakBefore/>"); count != 1 {
+		t.Errorf("expected the second envelope on a new page, got %d page breaks", count)
+	}
+	if strings.Count(documentXml, "Main Street 1") != 2 || !strings.Contains(documentXml, "Basel") {
+		t.Errorf("expected the return address and the delivery address on each envelope")
+	}
+}
@@ -0,0 +1,85 @@
+package docx
+
+import (
+	"regexp"
+	"strings"
+)
+
+// language.go selects the localized variants of a template, so one template with the static text in several
+// languages can be rendered with the same data. A variant of a part is stored next to the part with the language
+// before the extension, e.g. word/document.fr.xml or word/header1.de-CH.xml. Clauses are localized the same way:
+// {{clause "nda"}} inserts the clause nda.fr if the language is fr and the library contains it.
+
+// languageVariantRegex matches the name of a localized variant of a part, the groups are the name of the part
+// without extension and the language.
+var languageVariantRegex = regexp.MustCompile(`^(.+)\.([A-Za-z]{2,3}(?:-[A-Za-z0-9]{1,8})*)\.xml$`)
+
+// languageFallbacks returns the variants to look for, the most specific first: fr-CH, fr for fr-CH.
+func languageFallbacks(language string) []string {
+	var fallbacks []string
+	for language != "" {
+		fallbacks = append(fallbacks, language)
+		i := strings.LastIndex(language, "-")
+		if i < 0 {
+			break
+		}
+		language = language[:i]
+	}
+	return fallbacks
+}
+
+// selectLanguage replaces the XML parts by their variants for the language set with SetLanguage, falling back
+// to less specific variants and the part itself. All variants are removed from the document afterwards, so the
+// rendered document only contains the selected text.
+func (tr *TemplateReplacer) selectLanguage() error {
+	variants := make(map[string]map[string]string)
+	for _, name := range tr.document.partNames() {
+		match := languageVariantRegex.FindStringSubmatch(name)
+		if match == nil || !tr.document.isXmlPart(match[1]+".xml") {
+			continue
+		}
+		part := match[1] + ".xml"
+		if variants[part] == nil {
+			variants[part] = make(map[string]string)
+		}
+		variants[part][strings.ToLower(match[2])] = name
+	}
+
+	for _, part := range tr.document.xmlParts() {
+		if variants[part] == nil {
+			continue
+		}
+		for _, language := range languageFallbacks(tr.language) {
+			variant, exists := variants[part][strings.ToLower(language)]
+			if !exists {
+				continue
+			}
+			data, _ := tr.document.partData(variant)
+			tr.debugLog("Using %s for the language %s", variant, tr.language)
+			if err := tr.document.SetFile(part, data); err != nil {
+				return err
+			}
+			if err := tr.document.refreshRuns(part); err != nil {
+				return err
+			}
+			break
+		}
+		for _, variant := range variants[part] {
+			if err := tr.document.removePart(variant); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// localizedClause returns the clause of the library in the language set with SetLanguage, falling back to less
+// specific languages and the clause itself.
+func (tr *TemplateReplacer) localizedClause(name string) (*Document, bool) {
+	for _, language := range languageFallbacks(tr.language) {
+		if clause, exists := tr.clauses.Clause(name + "." + language); exists {
+			return clause, true
+		}
+	}
+	return tr.clauses.Clause(name)
+}
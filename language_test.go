@@ -0,0 +1,126 @@
+package docx
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDocument_SetLanguage_Variants(t *testing.T) {
+	variant := func(text string) []byte {
+		return []byte(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+			`<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>` +
+			`<w:p><w:r><w:t>` + text + `</w:t></w:r></w:p><w:p><w:r><w:t>{{clause "closing"}}</w:t></w:r></w:p>` +
+			`</w:body></w:document>`)
+	}
+	clause := func(text string) *Document {
+		t.Helper()
+		doc, err := NewDocument().Paragraph(text).Build()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return doc
+	}
+	library := NewClauseLibrary()
+	library.Add("closing", clause("Kind regards"))
+	library.Add("closing.fr", clause("Meilleures salutations"))
+
+	tests := []struct {
+		language string
+		expected []string
+	}{
+		{"", []string{"Dear Anna", "Kind regards"}},
+		{"fr-CH", []string{"Chère Anna", "Meilleures salutations"}},
+		{"de-CH", []string{"Liebe Anna", "Kind regards"}},
+		{"it", []string{"Dear Anna", "Kind regards"}},
+	}
+	for _, test := range tests {
+		t.Run(test.language, func(t *testing.T) {
+			doc, err := NewDocument().Paragraph("Dear {{.name}}").Paragraph(`{{clause "closing"}}`).Build()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer doc.Close()
+			for name, text := range map[string]string{"word/document.fr.xml": "Chère {{.name}}", "word/document.de-CH.xml": "Liebe {{.name}}"} {
+				if err := doc.addPart(name, variant(text), "application/xml"); err != nil {
+					t.Fatal(err)
+				}
+			}
+			doc.SetClauseLibrary(library)
+			if test.language != "" {
+				if err := doc.SetLanguage(test.language); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := doc.ExecuteTemplate(map[string]interface{}{"name": "Anna"}); err != nil {
+				t.Fatal(err)
+			}
+			texts, err := paragraphTexts(doc.GetFile(DocumentXml))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(texts, "|") != strings.Join(test.expected, "|") {
+				t.Errorf("expected %q, got %q", test.expected, texts)
+			}
+
+			var buf bytes.Buffer
+			if err := doc.Write(&buf); err != nil {
+				t.Fatal(err)
+			}
+			written, err := OpenBytes(buf.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer written.Close()
+			for _, name := range written.partNames() {
+				if strings.Contains(name, "document.fr") || strings.Contains(name, "document.de-CH") {
+					t.Errorf("expected the variant %s to be removed", name)
+				}
+			}
+		})
+	}
+
+	if fallbacks := languageFallbacks("de-CH-1901"); strings.Join(fallbacks, ",") != "de-CH-1901,de-CH,de" {
+		t.Errorf("unexpected fallbacks %v", fallbacks)
+	}
+}
+
+func TestDocument_SetLanguage_Defaults(t *testing.T) {
+	tests := []struct {
+		defaults string
+		expected string
+	}{
+		{`<w:docDefaults/>`, `<w:docDefaults><w:rPrDefault><w:rPr><w:lang w:val="de-DE"/></w:rPr></w:rPrDefault></w:docDefaults>`},
+		{`<w:docDefaults><w:pPrDefault/></w:docDefaults>`, `<w:docDefaults><w:rPrDefault><w:rPr><w:lang w:val="de-DE"/></w:rPr></w:rPrDefault><w:pPrDefault/></w:docDefaults>`},
+		{`<w:docDefaults><w:rPrDefault/></w:docDefaults>`, `<w:docDefaults><w:rPrDefault><w:rPr><w:lang w:val="de-DE"/></w:rPr></w:rPrDefault></w:docDefaults>`},
+		{`<w:docDefaults><w:rPrDefault>` + "\n" + `</w:rPrDefault></w:docDefaults>`, `<w:docDefaults><w:rPrDefault><w:rPr><w:lang w:val="de-DE"/></w:rPr>` + "\n" + `</w:rPrDefault></w:docDefaults>`},
+		{`<w:docDefaults><w:rPrDefault><w:rPr/></w:rPrDefault></w:docDefaults>`, `<w:docDefaults><w:rPrDefault><w:rPr><w:lang w:val="de-DE"/></w:rPr></w:rPrDefault></w:docDefaults>`},
+		{`<w:docDefaults><w:rPrDefault>` + "\n  " + `<w:rPr><w:sz w:val="22"/><w:eastAsianLayout w:vert="1"/></w:rPr></w:rPrDefault></w:docDefaults>`,
+			`<w:docDefaults><w:rPrDefault>` + "\n  " + `<w:rPr><w:sz w:val="22"/><w:lang w:val="de-DE"/><w:eastAsianLayout w:vert="1"/></w:rPr></w:rPrDefault></w:docDefaults>`},
+		{`<w:docDefaults><w:rPrDefault><w:rPr><w:lang w:eastAsia="zh-CN"/></w:rPr></w:rPrDefault></w:docDefaults>`,
+			`<w:docDefaults><w:rPrDefault><w:rPr><w:lang w:eastAsia="zh-CN" w:val="de-DE"/></w:rPr></w:rPrDefault></w:docDefaults>`},
+	}
+	for _, test := range tests {
+		modify := func(name string, data []byte) []byte {
+			if name == StylesXml {
+				return docDefaultsRegex.ReplaceAllLiteral(data, []byte(test.defaults))
+			}
+			return data
+		}
+		doc, err := OpenBytes(rewriteArchive(t, readFile(t, "./test/template.docx"), modify, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := doc.SetLanguage("de-DE"); err != nil {
+			t.Fatal(err)
+		}
+		styles, _ := doc.loadPackageFile(StylesXml)
+		if defaults := docDefaultsRegex.Find(styles); string(defaults) != test.expected {
+			t.Errorf("expected %s for %s, got %s", test.expected, test.defaults, defaults)
+		}
+		if doc.Language() != "de-DE" {
+			t.Errorf("expected the language de-DE for %s, got %s", test.defaults, doc.Language())
+		}
+		doc.Close()
+	}
+}
@@ -0,0 +1,197 @@
+package docx
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"text/template/parse"
+	"time"
+)
+
+// limits.go bounds the execution of the template, so crafted templates such as {{range 1000000000}}...{{end}}
+// or recursive {{template}} calls cannot keep the process busy or exhaust its memory. The limits apply to every
+// placeholder and every block on its own, the part size limit to the XML parts generated by repeated blocks and
+// table rows.
+
+var (
+	// ErrExecutionTimeout is returned if executing a placeholder or block takes longer than the timeout set with
+	// SetExecutionLimits.
+	ErrExecutionTimeout = errors.New("template execution timed out")
+	// ErrOutputLimit is returned if a placeholder or block writes more than the output limit set with
+	// SetExecutionLimits.
+	ErrOutputLimit = errors.New("template output limit exceeded")
+	// ErrPartSizeLimit is returned if a part grows larger than the part size limit set with SetExecutionLimits.
+	ErrPartSizeLimit = errors.New("part size limit exceeded")
+)
+
+// ExecutionLimits bounds the execution of each placeholder and block of the template and the size of the parts.
+// Zero values are unlimited.
+type ExecutionLimits struct {
+	Timeout     time.Duration  // The maximum execution time of each placeholder and block
+	MaxOutput   int            // The maximum size of the output of each placeholder and block in bytes
+	MaxPartSize int            // The maximum size of each XML part after the execution in bytes
+	Overflow    OverflowPolicy // The handling of blocks and table rows which exceed MaxPartSize
+}
+
+// SetExecutionLimits bounds the execution time and the output size of each placeholder and block. The failing
+// action is returned as TemplateError wrapping ErrExecutionTimeout or ErrOutputLimit. The time is checked
+// whenever the template writes output, in every iteration of {{range}} and in every call of a defined template,
+// so loops which write nothing are stopped as well. A single call of a slow function is not interrupted.
+// Alternative engines set with SetEngine are not bounded.
+// Parts larger than MaxPartSize after the execution are returned as error wrapping ErrPartSizeLimit. Blocks and
+// table rows repeated by {{range}} and {{rows}} are checked while they are expanded, so they fail with a
+// TemplateError naming the action or, with OverflowTruncate, are cut to the paragraphs and rows which fit.
+func (d *Document) SetExecutionLimits(limits ExecutionLimits) {
+	d.templateReplacer.limits = limits
+}
+
+// Truncations returns the blocks and table rows cut by the last template execution with OverflowTruncate. The
+// errors are TemplateError wrapping ErrPartSizeLimit, so the truncated content can be located.
+func (d *Document) Truncations() []error {
+	return d.templateReplacer.truncated
+}
+
+// limitedWriter collects the output of a template execution and fails once the limits are exceeded. The
+// template execution stops at the first failed write.
+type limitedWriter struct {
+	bytes.Buffer
+	limits   ExecutionLimits
+	deadline time.Time
+}
+
+// newLimitedWriter returns a writer for an execution starting now. The deadline also applies to the docxDeadline
+// calls of the execution, see limitLoops.
+func (tr *TemplateReplacer) newLimitedWriter() *limitedWriter {
+	w := &limitedWriter{limits: tr.limits}
+	if tr.limits.Timeout > 0 {
+		w.deadline = time.Now().Add(tr.limits.Timeout)
+	}
+	tr.deadline = w.deadline
+	return w
+}
+
+// Write implements io.Writer.
+func (w *limitedWriter) Write(p []byte) (int, error) {
+	if w.limits.MaxOutput > 0 && w.Len()+len(p) > w.limits.MaxOutput {
+		return 0, fmt.Errorf("%w: the output is larger than %d bytes", ErrOutputLimit, w.limits.MaxOutput)
+	}
+	if err := checkDeadline(w.deadline, w.limits.Timeout); err != nil {
+		return 0, err
+	}
+	return w.Buffer.Write(p)
+}
+
+// checkDeadline returns an error wrapping ErrExecutionTimeout if the deadline has passed. A zero deadline never
+// passes.
+func checkDeadline(deadline time.Time, timeout time.Duration) error {
+	if !deadline.IsZero() && time.Now().After(deadline) {
+		return fmt.Errorf("%w: the execution took longer than %s", ErrExecutionTimeout, timeout)
+	}
+	return nil
+}
+
+// deadlineHelper is the docxDeadline function, which stops the execution once the deadline of the current
+// execution has passed. It writes nothing.
+func (tr *TemplateReplacer) deadlineHelper() (string, error) {
+	return "", checkDeadline(tr.deadline, tr.limits.Timeout)
+}
+
+// limitLoops inserts a docxDeadline call at the beginning of every {{range}} of the parsed template, so loops
+// which write nothing are bounded by the timeout as well. It is called after escapeActions, so the calls are not
+// escaped themselves.
+func limitLoops(node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			limitLoops(child)
+		}
+	case *parse.IfNode:
+		limitLoops(n.List)
+		limitLoops(n.ElseList)
+	case *parse.RangeNode:
+		limitLoops(n.List)
+		limitLoops(n.ElseList)
+		if n.List != nil {
+			n.List.Nodes = append([]parse.Node{deadlineAction(n.Position())}, n.List.Nodes...)
+		}
+	case *parse.WithNode:
+		limitLoops(n.List)
+		limitLoops(n.ElseList)
+	}
+}
+
+// deadlineAction returns the action {{docxDeadline}} at the given position.
+func deadlineAction(pos parse.Pos) *parse.ActionNode {
+	return &parse.ActionNode{NodeType: parse.NodeAction, Pos: pos, Pipe: &parse.PipeNode{
+		NodeType: parse.NodePipe,
+		Pos:      pos,
+		Cmds: []*parse.CommandNode{{
+			NodeType: parse.NodeCommand,
+			Pos:      pos,
+			Args:     []parse.Node{parse.NewIdentifier("docxDeadline").SetPos(pos)},
+		}},
+	}}
+}
+
+// partSizeError returns an error if the part would be larger than the part size limit with the output, rest is the
+// size of the part without the output.
+func (tr *TemplateReplacer) partSizeError(output []byte, rest int) error {
+	size := rest + len(output)
+	if tr.limits.MaxPartSize <= 0 || size <= tr.limits.MaxPartSize {
+		return nil
+	}
+	return fmt.Errorf("%w: %s would be %d bytes, the limit is %d bytes", ErrPartSizeLimit, tr.part, size, tr.limits.MaxPartSize)
+}
+
+// truncate returns true if the content exceeding the part size limit is truncated according to the overflow
+// policy. The error is recorded, see Truncations.
+func (tr *TemplateReplacer) truncate(err error) bool {
+	if tr.limits.Overflow != OverflowTruncate {
+		return false
+	}
+	tr.debugLog("Truncating: %v", err)
+	tr.truncated = append(tr.truncated, err)
+	return true
+}
+
+// checkPartSizes returns an error if any part is larger than the part size limit.
+func (tr *TemplateReplacer) checkPartSizes() error {
+	if tr.limits.MaxPartSize <= 0 {
+		return nil
+	}
+	for _, fileName := range tr.document.xmlParts() {
+		if size := len(tr.document.GetFile(fileName)); size > tr.limits.MaxPartSize {
+			return fmt.Errorf("%w: %s is %d bytes, the limit is %d bytes", ErrPartSizeLimit, fileName, size, tr.limits.MaxPartSize)
+		}
+	}
+	return nil
+}
+
+// truncateElements returns the longest beginning of the XML fragment which consists of complete elements, e.g.
+// paragraphs or table rows, and is not larger than size bytes.
+func truncateElements(fragment []byte, size int) []byte {
+	decoder := xml.NewDecoder(bytes.NewReader(fragment))
+	depth, end := 0, 0
+	for {
+		tok, err := decoder.RawToken()
+		if err != nil {
+			return fragment[:end]
+		}
+		switch tok.(type) {
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			if depth--; depth == 0 {
+				offset := int(decoder.InputOffset())
+				if offset > size {
+					return fragment[:end]
+				}
+				end = offset
+			}
+		}
+	}
+}
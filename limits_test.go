@@ -0,0 +1,117 @@
+package docx
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDocument_ExecutionLimits(t *testing.T) {
+	for _, test := range []struct {
+		paragraphs []string
+		limits     ExecutionLimits
+		expected   error
+		action     string
+	}{
+		{[]string{`Name: {{.name}}`, `{{range 100000000}}x{{end}}`}, ExecutionLimits{MaxOutput: 1000}, ErrOutputLimit, "{{range 100000000}}x{{end}}"},
+		{[]string{`{{range 100000000}}`, `Row {{.}}`, `{{end}}`}, ExecutionLimits{Timeout: 50 * time.Millisecond}, ErrExecutionTimeout, "{{range 100000000}}"},
+	} {
+		builder := NewDocument()
+		for _, paragraph := range test.paragraphs {
+			builder.Paragraph(paragraph)
+		}
+		doc, err := builder.Build(WithExecutionLimits(test.limits))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = doc.ExecuteTemplate(map[string]interface{}{"name": "Jane"})
+		doc.Close()
+		var templateErr *TemplateError
+		if !errors.Is(err, test.expected) || !errors.As(err, &templateErr) {
+			t.Errorf("expected %v, got %v", test.expected, err)
+		} else if templateErr.Action != test.action {
+			t.Errorf("expected the error of %s, got %s", test.action, templateErr.Action)
+		}
+	}
+
+	// placeholders within the limits are executed
+	doc, err := NewDocument().Paragraph(`{{range 3}}x{{end}}`).Build(WithExecutionLimits(ExecutionLimits{Timeout: time.Second, MaxOutput: 10}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+	if err := doc.ExecuteTemplate(map[string]interface{}{}); err != nil {
+		t.Fatal(err)
+	}
+	if texts, _ := paragraphTexts(doc.GetFile(DocumentXml)); len(texts) != 1 || texts[0] != "xxx" {
+		t.Errorf("unexpected paragraphs %q", texts)
+	}
+}
+
+func TestDocument_PartSizeLimit(t *testing.T) {
+	items := make([]int, 1000)
+	for i := range items {
+		items[i] = i
+	}
+	data := map[string]interface{}{"items": items}
+	build := func(overflow OverflowPolicy) *Document {
+		doc, err := NewDocument().
+			Paragraph(`{{range .items}}`).Paragraph(`Item {{.}}`).Paragraph(`{{end}}`).
+			Table([][]string{{"Name"}, {"{{rows .items}}{{.}}"}}).
+			Build()
+		if err != nil {
+			t.Fatal(err)
+		}
+		doc.SetExecutionLimits(ExecutionLimits{MaxPartSize: len(doc.GetFile(DocumentXml)) + 4000, Overflow: overflow})
+		return doc
+	}
+
+	doc := build(OverflowError)
+	err := doc.ExecuteTemplate(data)
+	doc.Close()
+	var templateErr *TemplateError
+	if !errors.Is(err, ErrPartSizeLimit) || !errors.As(err, &templateErr) || templateErr.Action != "{{range .items}}" {
+		t.Errorf("expected the range to exceed the limit, got %v", err)
+	}
+
+	doc = build(OverflowTruncate)
+	defer doc.Close()
+	if err := doc.ExecuteTemplate(data); err != nil {
+		t.Fatal(err)
+	}
+	if size := len(doc.GetFile(DocumentXml)); size > doc.templateReplacer.limits.MaxPartSize {
+		t.Errorf("the part has %d bytes, more than the limit", size)
+	}
+	texts, err := paragraphTexts(doc.GetFile(DocumentXml))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(texts) < 10 || len(texts) > 500 || texts[0] != "Item 0" {
+		t.Errorf("expected the items to be truncated, got %d paragraphs", len(texts))
+	}
+	truncations := doc.Truncations()
+	if len(truncations) != 2 || !errors.As(truncations[1], &templateErr) || templateErr.Action != "{{rows .items}}" {
+		t.Errorf("expected the range and the rows to be truncated, got %v", truncations)
+	}
+}
+
+func TestDocument_ExecutionTimeoutWithoutOutput(t *testing.T) {
+	for _, paragraph := range []string{
+		`A {{range 200000000}}{{end}} B`,
+		`{{define "loop"}}{{template "loop" .}}{{template "loop" .}}{{end}}{{template "loop" .}}`,
+	} {
+		doc, err := NewDocument().Paragraph(paragraph).Build(WithExecutionLimits(ExecutionLimits{Timeout: 50 * time.Millisecond}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		start := time.Now()
+		err = doc.ExecuteTemplate(map[string]interface{}{})
+		doc.Close()
+		if !errors.Is(err, ErrExecutionTimeout) {
+			t.Errorf("expected %s to time out, got %v", paragraph, err)
+		}
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Errorf("expected %s to be stopped after the timeout, took %s", paragraph, elapsed)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package docx
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// lint.go implements LintTemplate, which checks a template for problems that would break or silently skip
+// placeholders when the template is executed.
+
+// lintErrorPrefixRegex matches the prefix of the errors of text/template, e.g. "template: lint:1: ".
+var lintErrorPrefixRegex = regexp.MustCompile(`^template: [^:]*:\d+(:\d+)?: (executing "[^"]*" at <[^>]*>: )?`)
+
+// LintIssue is a problem of a template found by LintTemplate.
+type LintIssue struct {
+	Part      string // The part containing the problem, e.g. word/document.xml or word/header1.xml
+	Paragraph int    // The number of the paragraph inside the part (starting with 1), 0 if the whole part is affected
+	Text      string // The text of the paragraph
+	Message   string
+}
+
+// String returns the issue with its location, e.g. word/document.xml, paragraph 3: ... in "Dear {{.name}"
+func (i LintIssue) String() string {
+	location := i.Part
+	if i.Paragraph > 0 {
+		location += fmt.Sprintf(", paragraph %d", i.Paragraph)
+	}
+	if i.Text == "" {
+		return location + ": " + i.Message
+	}
+	return fmt.Sprintf("%s: %s in %q", location, i.Message, i.Text)
+}
+
+// LintTemplate checks the template at the given path, so template authors get feedback before the template
+// is deployed. See Document.Lint for the checks. If the file cannot be opened, the only issue describes why.
+func LintTemplate(path string) []LintIssue {
+	doc, err := Open(path)
+	if err != nil {
+		return []LintIssue{{Part: path, Message: err.Error()}}
+	}
+	defer doc.Close()
+	return doc.Lint()
+}
+
+// Lint checks the template for
+//   - placeholders which are split across multiple runs (differently formatted parts of an action),
+//   - if, range and with blocks without matching end and vice versa,
+//   - syntax errors and functions which are not defined (see AddTemplateFuncs),
+//   - braces and quotes which Word replaced by typographic quotes and
+//   - placeholders inside parts which are not processed, e.g. the glossary or parts left out by OpenOptions.Parts.
+//
+// The issues are sorted by part and paragraph. Split placeholders and typographic quotes are repaired by
+// NormalizePlaceholders.
+func (d *Document) Lint() []LintIssue {
+	var issues []LintIssue
+	processed := make(map[string]bool)
+	for _, part := range d.xmlParts() {
+		processed[part] = true
+		issues = append(issues, d.templateReplacer.lintPart(part)...)
+	}
+
+	for _, part := range d.partNames() {
+		if processed[part] || !strings.HasSuffix(part, ".xml") || DiagramDataPathRegex.MatchString(part) || DiagramDrawingPathRegex.MatchString(part) {
+			continue
+		}
+		processed[part] = true
+		if data, _ := d.partData(part); strings.Contains(string(data), "{{") {
+			issues = append(issues, LintIssue{Part: part, Message: "placeholders in this part are not processed"})
+		}
+	}
+
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].Part != issues[j].Part {
+			return issues[i].Part < issues[j].Part
+		}
+		return issues[i].Paragraph < issues[j].Paragraph
+	})
+	return issues
+}
+
+// lintPart checks the paragraphs of a processed part.
+func (tr *TemplateReplacer) lintPart(part string) []LintIssue {
+	data := tr.document.GetFile(part)
+	paragraphs, err := findBlockParagraphs(data)
+	if err != nil {
+		return []LintIssue{{Part: part, Message: err.Error()}}
+	}
+
+	var issues []LintIssue
+	report := func(index int, message string) {
+		issues = append(issues, LintIssue{Part: part, Paragraph: index + 1, Text: strings.TrimSpace(paragraphs[index].Text), Message: message})
+	}
+
+	var runActions []string // the actions found inside single runs
+	if parser, exists := tr.document.runParsers[part]; exists {
+		for _, run := range parser.Runs().WithText() {
+			text := html.UnescapeString(run.GetText(data))
+			for _, action := range findTemplateActions(text) {
+				runActions = append(runActions, text[action.Start:action.End])
+			}
+		}
+	}
+	inRuns := make(map[string]int)
+	for _, action := range runActions {
+		inRuns[action]++
+	}
+
+	var open []int // paragraphs of the open blocks spanning multiple paragraphs
+	for index, paragraph := range paragraphs {
+		text := paragraph.Text
+		if strings.Contains(text, smartLeftDelimiter) || strings.Contains(text, smartRightDelimiter) {
+			report(index, "braces were replaced by typographic quotes, use {{ and }}")
+		}
+
+		actions := findTemplateActions(text)
+		blockParagraph := len(actions) == 1 && strings.TrimSpace(text) == text[actions[0].Start:actions[0].End]
+		// actions which are the only content of their paragraph are processed with the text of the paragraph
+		paragraphLevel := false
+		if blockParagraph {
+			kind, _ := blockActionKind(text[actions[0].Start+actions[0].Left : actions[0].End-actions[0].Right])
+			paragraphLevel = kind != "" || insertionActionRegex.MatchString(text)
+		}
+		for _, action := range actions {
+			actionText := text[action.Start:action.End]
+			if strings.ContainsAny(actionText, "“”‘’") && action.Left == 2 {
+				report(index, fmt.Sprintf("the action %s contains typographic quotes, use \" instead", actionText))
+			}
+			if inRuns[actionText] > 0 {
+				inRuns[actionText]--
+			} else if !paragraphLevel {
+				report(index, fmt.Sprintf("the action %s is split across runs with different formatting", actionText))
+			}
+		}
+
+		for _, group := range groupTemplateActions(text, actions) {
+			groupText := text[group.Start:group.End]
+			if group.Left != 2 || group.Right != 2 {
+				continue
+			}
+			kind, pipeline := "", ""
+			if len(findTemplateActions(groupText)) == 1 {
+				// complete control structures inside the paragraph are parsed as they are
+				kind, pipeline = blockActionKind(text[group.Start+group.Left : group.End-group.Right])
+			}
+
+			snippet := groupText
+			switch kind {
+			case "if", "range", "with", "block", "define":
+				if !blockParagraph {
+					report(index, fmt.Sprintf("%s must be closed in the same paragraph or be the only content of its paragraph", groupText))
+				} else {
+					open = append(open, index)
+				}
+				snippet += "{{end}}"
+			case "else", "end":
+				if !blockParagraph {
+					report(index, fmt.Sprintf("%s without matching {{if}}, {{range}} or {{with}} in the same paragraph", groupText))
+				} else if len(open) == 0 {
+					report(index, fmt.Sprintf("%s without matching {{if}}, {{range}} or {{with}}", groupText))
+				} else if kind == "end" {
+					open = open[:len(open)-1]
+				}
+				if pipeline == "" {
+					continue
+				}
+				snippet = "{{if " + pipeline + "}}{{end}}"
+			}
+			if match := rowsActionRegex.FindStringSubmatch(groupText); match != nil {
+				snippet = "{{" + match[1] + "}}"
+			} else if match := columnsActionRegex.FindStringSubmatch(groupText); match != nil {
+				snippet = "{{" + match[1] + "}}"
+			}
+			if err := tr.lintSyntax(snippet); err != nil {
+				report(index, err.Error())
+			}
+		}
+	}
+	for _, index := range open {
+		report(index, fmt.Sprintf("%s without matching {{end}}", strings.TrimSpace(paragraphs[index].Text)))
+	}
+	return issues
+}
+
+// lintSyntax parses the actions with the functions of the template, which reports syntax errors and
+// functions which are not defined.
+func (tr *TemplateReplacer) lintSyntax(actions string) error {
+	tmpl, err := tr.tmpl.Clone()
+	if err != nil {
+		return err
+	}
+	if _, err := tmpl.New("lint").Funcs(fragmentFuncs).Parse(stripAnnotations(actions)); err != nil {
+		return fmt.Errorf("%s", lintErrorPrefixRegex.ReplaceAllString(err.Error(), ""))
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package docx
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLintTemplate(t *testing.T) {
+	data := rewriteArchive(t, readFile(t, "./test/template.docx"), func(name string, data []byte) []byte {
+		if name == DocumentXml {
+			return []byte(strings.Replace(string(data), "<w:body>", "<w:body>"+
+				`<w:p><w:r><w:t>Dear {{.name}}, {{if .vip}}VIP{{end}}</w:t></w:r></w:p>`+
+				`<w:p><w:r><w:t>Split {{.</w:t></w:r><w:r><w:rPr><w:b/></w:rPr><w:t>total}}</w:t></w:r></w:p>`+
+				`<w:p><w:r><w:t>{{if .a}}</w:t></w:r></w:p>`+
+				`<w:p><w:r><w:t>{{rows .lines}}{{unknownFunc .x}}</w:t></w:r></w:p>`+
+				`<w:p><w:r><w:t>{{end}}</w:t></w:r></w:p>`+
+				`<w:p><w:r><w:t>{{end}}</w:t></w:r></w:p>`+
+				`<w:p><w:r><w:t>Quote {{printf “%s” .x}} and ““.smart””</w:t></w:r></w:p>`+
+				`<w:p><w:r><w:t>{{range .items}}</w:t></w:r></w:p>`+
+				`<w:p><w:r><w:t>Inline {{with .x}} open</w:t></w:r></w:p>`, 1))
+		}
+		return data
+	}, map[string][]byte{"word/glossary/document.xml": []byte(`<w:glossaryDocument><w:t>{{.note}}</w:t></w:glossaryDocument>`)})
+	path := filepath.Join(t.TempDir(), "lint.docx")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var found []string
+	for _, issue := range LintTemplate(path) {
+		found = append(found, issue.String())
+	}
+	expected := []string{
+		`word/document.xml, paragraph 2: the action {{.total}} is split across runs with different formatting in "Split {{.total}}"`,
+		`word/document.xml, paragraph 4: function "unknownFunc" not defined in "{{rows .lines}}{{unknownFunc .x}}"`,
+		`word/document.xml, paragraph 6: {{end}} without matching {{if}}, {{range}} or {{with}} in "{{end}}"`,
+		`word/document.xml, paragraph 7: braces were replaced by typographic quotes, use {{ and }} in "Quote {{printf “%s” .x}} and ““.smart””"`,
+		`word/document.xml, paragraph 7: the action {{printf “%s” .x}} contains typographic quotes, use " instead in "Quote {{printf “%s” .x}} and ““.smart””"`,
+		`word/document.xml, paragraph 7: unrecognized character in action: U+201C '“' in "Quote {{printf “%s” .x}} and ““.smart””"`,
+		`word/document.xml, paragraph 8: {{range .items}} without matching {{end}} in "{{range .items}}"`,
+		`word/document.xml, paragraph 9: {{with .x}} must be closed in the same paragraph or be the only content of its paragraph in "Inline {{with .x}} open"`,
+		`word/glossary/document.xml: placeholders in this part are not processed`,
+	}
+	if strings.Join(found, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected\n%s\ngot\n%s", strings.Join(expected, "\n"), strings.Join(found, "\n"))
+	}
+
+	if issues := LintTemplate(filepath.Join(t.TempDir(), "missing.docx")); len(issues) != 1 {
+		t.Errorf("expected an issue for a missing file, got %v", issues)
+	}
+}
@@ -0,0 +1,282 @@
+package docx
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+)
+
+// FootnoteNumbering controls the numbering of the footnotes of the records merged by MailMerge.
+type FootnoteNumbering int
+
+const (
+	// ContinueFootnotes numbers the footnotes of all records in sequence, unless the template restarts them per
+	// section or page. This is the default.
+	ContinueFootnotes FootnoteNumbering = iota
+	// RestartFootnotes restarts the numbering of the footnotes with each record.
+	RestartFootnotes
+)
+
+// MailMergeOptions controls how MailMerge assembles the records.
+type MailMergeOptions struct {
+	// TemplateSections starts each record with a section break and applies the section properties of the
+	// template to it: page size, margins, columns and the headers and footers rendered for the record.
+	// Otherwise the records are separated by page breaks and share the headers and footers of the first record.
+	TemplateSections bool
+	// RestartPageNumbering starts the page numbers of each record at 1, or at the start value of the template.
+	// It implies TemplateSections.
+	RestartPageNumbering bool
+	// Footnotes controls the numbering of the footnotes, RestartFootnotes implies TemplateSections.
+	Footnotes FootnoteNumbering
+}
+
+// sectionPropertiesOrder lists the children of the section properties (<w:sectPr>) in the order of the schema.
+var sectionPropertiesOrder = []string{"w:headerReference", "w:footerReference", "w:footnotePr", "w:endnotePr",
+	"w:type", "w:pgSz", "w:pgMar", "w:paperSrc", "w:pgBorders", "w:lnNumType", "w:pgNumType", "w:cols",
+	"w:formProt", "w:vAlign", "w:noEndnote", "w:titlePg", "w:textDirection", "w:bidi", "w:rtlGutter",
+	"w:docGrid", "w:printerSettings", "w:sectPrChange"}
+
+var (
+	// sameSectionTypeRegex matches the section types which do not start a new page.
+	sameSectionTypeRegex = regexp.MustCompile(`<w:type\s+w:val="(?:continuous|nextColumn)"\s*/>`)
+	// pageNumberTypeRegex matches the page numbering of the section properties.
+	pageNumberTypeRegex = regexp.MustCompile(`<w:pgNumType\b[^>]*/>`)
+	// footnotePropertiesRegex matches the footnote properties of the section properties, the first group is
+	// their content.
+	footnotePropertiesRegex = regexp.MustCompile(`(?s)<w:footnotePr\b[^>]*?(?:/>|>(.*?)</w:footnotePr>)`)
+	// numberingRestartRegex matches the restart of the footnote numbering.
+	numberingRestartRegex = regexp.MustCompile(`<w:numRestart\b[^>]*/>`)
+)
+
+// noteStories are the parts of the footnotes and endnotes with the elements of their notes.
+var noteStories = []struct{ part, element string }{{FootnotesXml, "footnote"}, {EndnotesXml, "endnote"}}
+
+// MailMerge renders the template once per record and assembles the results into a single document in which
+// every record starts on a new page, e.g. to batch-print official letters. The records are bound like the data
+// of Render. Images, hyperlinks, lists, footnotes and endnotes of every record are kept; see MailMergeOptions
+// for the sections, page numbers and footnote numbering of the records.
+func MailMerge[T any](tpl *Template, records []T, options MailMergeOptions) (*Document, error) {
+	if len(records) == 0 {
+		return nil, fmt.Errorf("no records to merge")
+	}
+	sections := options.TemplateSections || options.RestartPageNumbering || options.Footnotes == RestartFootnotes
+
+	var doc *Document
+	var body, final []byte
+	for i, record := range records {
+		rendered, err := tpl.render(SchemaFor(record), bindData(reflect.ValueOf(record)), nil, nil)
+		if err != nil {
+			if doc != nil {
+				doc.Close()
+			}
+			return nil, fmt.Errorf("unable to render record %d: %w", i+1, err)
+		}
+		if doc == nil {
+			doc = rendered
+		}
+		recordBody, err := doc.importRecord(rendered, sections, options)
+		if rendered != doc {
+			rendered.Close()
+		}
+		if err != nil {
+			doc.Close()
+			return nil, fmt.Errorf("unable to merge record %d: %w", i+1, err)
+		}
+
+		switch {
+		case sections:
+			// the section of the previous record ends with its last paragraph
+			if i > 0 {
+				main := withoutFinalSection(body)
+				body = bytes.Join([][]byte{main, []byte("<w:p><w:pPr>"), body[len(main):], []byte("</w:pPr></w:p>")}, nil)
+			}
+		case i == 0:
+			main := withoutFinalSection(recordBody)
+			final = recordBody[len(main):]
+			recordBody = main
+		default:
+			recordBody = pageBreakBefore(recordBody)
+		}
+		body = append(body, recordBody...)
+	}
+
+	documentXml := doc.GetFile(DocumentXml)
+	loc := bodyRegex.FindSubmatchIndex(documentXml)
+	documentXml = bytes.Join([][]byte{documentXml[:loc[2]], body, final, documentXml[loc[3]:]}, nil)
+	if err := doc.SetFile(DocumentXml, documentXml); err != nil {
+		doc.Close()
+		return nil, err
+	}
+	if err := doc.refreshRuns(DocumentXml); err != nil {
+		doc.Close()
+		return nil, err
+	}
+	return doc, nil
+}
+
+// importRecord returns the body of a rendered record, prepared to be appended to the document body. With
+// sections the body keeps its final section properties and the headers and footers they reference, otherwise
+// the final section properties are only kept for the document itself.
+func (d *Document) importRecord(source *Document, sections bool, options MailMergeOptions) ([]byte, error) {
+	match := bodyRegex.FindSubmatch(source.GetFile(DocumentXml))
+	if match == nil {
+		return nil, fmt.Errorf("the document does not have a body")
+	}
+	body := match[1]
+	if source != d {
+		var err error
+		if sections {
+			body, err = d.importContent(source, body)
+		} else {
+			body, err = d.importBody(source)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if body, err = d.importNotes(source, body); err != nil {
+			return nil, err
+		}
+	}
+	if !sections {
+		return body, nil
+	}
+
+	if len(withoutFinalSection(body)) == len(body) {
+		body = append(append([]byte{}, body...), []byte("<w:sectPr/>")...)
+	}
+	// the first section of the record starts a new page and restarts the numbering
+	return modifySections(body, 0, func(properties []byte) []byte {
+		properties = sameSectionTypeRegex.ReplaceAll(properties, nil)
+		if options.RestartPageNumbering {
+			properties = restartPageNumbering(properties)
+		}
+		if options.Footnotes == RestartFootnotes {
+			properties = restartFootnotes(properties)
+		}
+		return properties
+	})
+}
+
+// restartPageNumbering lets the section start with page 1, unless its page numbering has a start value.
+func restartPageNumbering(properties []byte) []byte {
+	if tag := pageNumberTypeRegex.Find(properties); tag != nil {
+		if _, exists := getTagAttr(tag, "w:start"); exists {
+			return properties
+		}
+		return bytes.Replace(properties, tag, setTagAttr(tag, "w:start", "1"), 1)
+	}
+	return insertChild(properties, []byte(`<w:pgNumType w:start="1"/>`), sectionFollowers("w:pgNumType"))
+}
+
+// restartFootnotes restarts the numbering of the footnotes at the start of the section.
+func restartFootnotes(properties []byte) []byte {
+	restart := []byte(`<w:numRestart w:val="eachSect"/>`)
+	loc := footnotePropertiesRegex.FindSubmatchIndex(properties)
+	if loc == nil {
+		return insertChild(properties, bytes.Join([][]byte{[]byte("<w:footnotePr>"), restart, []byte("</w:footnotePr>")}, nil),
+			sectionFollowers("w:footnotePr"))
+	}
+	var content []byte
+	if loc[2] >= 0 {
+		content = numberingRestartRegex.ReplaceAll(properties[loc[2]:loc[3]], nil)
+	}
+	// the restart is the last child of the footnote properties
+	footnoteProperties := bytes.Join([][]byte{[]byte("<w:footnotePr>"), content, restart, []byte("</w:footnotePr>")}, nil)
+	return applyReplacements(properties, []replacement{{int64(loc[0]), int64(loc[1]), footnoteProperties}})
+}
+
+// sectionFollowers returns the children of the section properties which follow the element according to the schema.
+func sectionFollowers(name string) []string {
+	return sectionPropertiesOrder[slices.Index(sectionPropertiesOrder, name)+1:]
+}
+
+// pageBreakBefore lets the body start on a new page. A page break is added in front of the first paragraph,
+// a body starting with a table gets a paragraph containing the break.
+func pageBreakBefore(body []byte) []byte {
+	trimmed := bytes.TrimLeft(body, " \t\r\n")
+	if !paragraphOpenTagRegex.Match(trimmed) {
+		return append([]byte(`<w:p><w:r><w:br w:type="page"/></w:r></w:p>`), body...)
+	}
+	return setNestedProperty(trimmed, paragraphOpenTagRegex, "w:pPr", "w:pageBreakBefore", []byte("<w:pageBreakBefore/>"), paragraphPropertiesOrder)
+}
+
+// importNotes copies the footnotes and endnotes of the source document which are referenced by the body and
+// returns the body with the ids of the copies.
+func (d *Document) importNotes(source *Document, body []byte) ([]byte, error) {
+	for _, story := range noteStories {
+		referenceRegex := regexp.MustCompile(`<w:` + story.element + `Reference\b[^>]*/>`)
+		references := referenceRegex.FindAll(body, -1)
+		if len(references) == 0 {
+			continue
+		}
+		sourceNotes, exists := source.partData(story.part)
+		if !exists {
+			return nil, fmt.Errorf("part %s not found", story.part)
+		}
+		notes, exists := d.partData(story.part)
+		if !exists {
+			return nil, fmt.Errorf("part %s not found", story.part)
+		}
+
+		// the copies get ids behind the highest id of the document
+		noteRegex := regexp.MustCompile(`(?s)<w:` + story.element + `\b[^>]*>.*?</w:` + story.element + `>`)
+		next := 0
+		for _, note := range noteRegex.FindAll(notes, -1) {
+			if id, err := strconv.Atoi(attrOf(note[:bytes.IndexByte(note, '>')], "w:id")); err == nil && id >= next {
+				next = id + 1
+			}
+		}
+		sourceNoteByID := make(map[string][]byte)
+		for _, note := range noteRegex.FindAll(sourceNotes, -1) {
+			sourceNoteByID[attrOf(note[:bytes.IndexByte(note, '>')], "w:id")] = note
+		}
+
+		ids := make(map[string]string) // source id => new id
+		var copies []byte
+		for _, reference := range references {
+			id := attrOf(reference, "w:id")
+			if _, done := ids[id]; done {
+				continue
+			}
+			note, exists := sourceNoteByID[id]
+			if !exists {
+				return nil, fmt.Errorf("%s %s not found", story.element, id)
+			}
+			ids[id] = strconv.Itoa(next)
+			next++
+			end := bytes.IndexByte(note, '>') + 1
+			copies = append(append(copies, setTagAttr(note[:end], "w:id", ids[id])...), note[end:]...)
+		}
+		copies, err := d.importRelationships(source, story.part, story.part, copies)
+		if err != nil {
+			return nil, err
+		}
+
+		end := bytes.LastIndex(notes, []byte("</w:"+story.element+"s>"))
+		if end < 0 {
+			return nil, fmt.Errorf("invalid part %s", story.part)
+		}
+		notes = insertAt(notes, end, copies)
+		if d.hasFile(story.part) {
+			if err := d.SetFile(story.part, notes); err != nil {
+				return nil, err
+			}
+			if err := d.refreshRuns(story.part); err != nil {
+				return nil, err
+			}
+		} else {
+			d.setPackageFile(story.part, notes)
+		}
+
+		body = referenceRegex.ReplaceAllFunc(body, func(tag []byte) []byte {
+			if id, exists := ids[attrOf(tag, "w:id")]; exists {
+				return setTagAttr(tag, "w:id", id)
+			}
+			return tag
+		})
+	}
+	return body, nil
+}
@@ -0,0 +1,115 @@
+package docx
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// mailMergeTemplate returns the test template with a letter, a footnote and a header addressed to {{.name}}.
+func mailMergeTemplate(t *testing.T) *Template {
+	data := rewriteArchive(t, readFile(t, "./test/template.docx"), func(name string, data []byte) []byte {
+		switch name {
+		case DocumentXml:
+			return []byte(strings.Replace(string(data), "<w:body>", `<w:body><w:p><w:r><w:t>Dear {{.name}}</w:t></w:r>`+
+				`<w:r><w:footnoteReference w:id="5"/></w:r></w:p>`, 1))
+		case FootnotesXml:
+			return []byte(strings.Replace(string(data), "</w:footnotes>", `<w:footnote w:id="5"><w:p><w:r><w:t>Note for {{.name}}</w:t></w:r></w:p></w:footnote></w:footnotes>`, 1))
+		case "word/header1.xml":
+			return []byte(strings.Replace(string(data), "</w:hdr>", `<w:p><w:r><w:t>To {{.name}}</w:t></w:r></w:p></w:hdr>`, 1))
+		}
+		return data
+	}, nil)
+	tpl, err := NewTemplate(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tpl
+}
+
+func TestMailMerge(t *testing.T) {
+	records := []map[string]interface{}{{"name": "Ann"}, {"name": "Bob"}, {"name": "Cid"}}
+	referenceRegex := regexp.MustCompile(`<w:footnoteReference w:id="(\d+)"/>`)
+
+	tests := []struct {
+		name     string
+		options  MailMergeOptions
+		sections int
+		restarts int
+	}{
+		{"page breaks", MailMergeOptions{}, 1, 0},
+		{"template sections", MailMergeOptions{TemplateSections: true}, 3, 0},
+		{"restarts", MailMergeOptions{RestartPageNumbering: true, Footnotes: RestartFootnotes}, 3, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := MailMerge(mailMergeTemplate(t), records, tt.options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer doc.Close()
+
+			var buf bytes.Buffer
+			if err := doc.Write(&buf); err != nil {
+				t.Fatal(err)
+			}
+			written, err := OpenBytes(buf.Bytes())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer written.Close()
+
+			documentXml := string(written.GetFile(DocumentXml))
+			ann, bob, cid := strings.Index(documentXml, "Dear Ann"), strings.Index(documentXml, "Dear Bob"), strings.Index(documentXml, "Dear Cid")
+			if ann < 0 || ann > bob || bob > cid {
+				t.Errorf("expected the records in order")
+			}
+			if count := strings.Count(documentXml, "<w:sectPr"); count != tt.sections {
+				t.Errorf("expected %d sections, got %d", tt.sections, count)
+			}
+			if tt.sections == 1 && strings.Count(documentXml, "<w:pageBreakBefore/>") != 2 {
+				t.Errorf("expected page breaks in front of the second and third record")
+			}
+			if tt.sections > 1 && strings.Contains(documentXml, `w:val="continuous"`) {
+				t.Errorf("the records must start on a new page")
+			}
+			if count := strings.Count(documentXml, `<w:pgNumType w:start="1"/>`); count != tt.restarts {
+				t.Errorf("expected %d page number restarts, got %d", tt.restarts, count)
+			}
+			if count := strings.Count(documentXml, `<w:numRestart w:val="eachSect"/>`); count != tt.restarts {
+				t.Errorf("expected %d footnote restarts, got %d", tt.restarts, count)
+			}
+
+			// every record references a footnote of its own
+			footnotes := string(written.GetFile(FootnotesXml))
+			ids := make(map[string]bool)
+			for _, match := range referenceRegex.FindAllStringSubmatch(documentXml, -1) {
+				ids[match[1]] = true
+				if !strings.Contains(footnotes, `<w:footnote w:id="`+match[1]+`">`) {
+					t.Errorf("footnote %s not found", match[1])
+				}
+			}
+			if len(ids) != 3 || !strings.Contains(footnotes, "Note for Ann") || !strings.Contains(footnotes, "Note for Cid") {
+				t.Errorf("expected the footnotes of the three records, got ids %v", ids)
+			}
+
+			// with sections every record has the header rendered for it
+			headers := 0
+			for _, file := range written.Files(HeaderFiles) {
+				if strings.Contains(string(written.GetFile(file)), "To ") {
+					headers++
+				}
+			}
+			if expected := min(tt.sections, 3); headers != expected {
+				t.Errorf("expected %d headers, got %d", expected, headers)
+			}
+		})
+	}
+}
+
+func TestMailMerge_NoRecords(t *testing.T) {
+	if _, err := MailMerge(mailMergeTemplate(t), []map[string]interface{}{}, MailMergeOptions{}); err == nil {
+		t.Errorf("expected an error without records")
+	}
+}
@@ -0,0 +1,134 @@
+package docx
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// templateManifestVersion is the version of the manifest format, manifests of other versions are rejected.
+const templateManifestVersion = 1
+
+// TemplateManifest is the pre-compiled index of a template, see CompileManifest. It is serializable as JSON, so it
+// can be stored alongside the template, e.g. in object storage, and loaded with LoadManifest to skip parsing the
+// template on each rendering.
+type TemplateManifest struct {
+	Version int            `json:"version"`
+	Hash    string         `json:"hash"` // The SHA-256 hash of the template file
+	Parts   []ManifestPart `json:"parts"`
+}
+
+// ManifestPart is the index of a part of the template which may contain placeholders.
+type ManifestPart struct {
+	Name         string                `json:"name"` // The part name, e.g. word/document.xml
+	Hash         string                `json:"hash"` // The SHA-256 hash of the part
+	Placeholders []ManifestPlaceholder `json:"placeholders,omitempty"`
+	// Runs are the byte offsets of the runs: the start and end of the open and the close tag, followed by
+	// the same four offsets of the text if the run has a text.
+	Runs [][]int64 `json:"runs,omitempty"`
+}
+
+// ManifestPlaceholder is a placeholder of a part with its byte offsets inside the part.
+type ManifestPlaceholder struct {
+	Content string `json:"content"`
+	Start   int64  `json:"start"`
+	End     int64  `json:"end"`
+}
+
+// CompileManifest parses the template and returns its manifest with the placeholders and runs of all parts.
+func (t *Template) CompileManifest() (*TemplateManifest, error) {
+	doc, err := OpenBytes(t.data)
+	if err != nil {
+		return nil, err
+	}
+	defer doc.Close()
+
+	manifest := &TemplateManifest{Version: templateManifestVersion, Hash: contentHash(t.data)}
+	for _, name := range doc.xmlParts() {
+		data := doc.GetFile(name)
+		runs := doc.runParsers[name].Runs()
+		placeholders, err := ParseTemplatePlaceholders(runs, data, name)
+		if err != nil {
+			return nil, err
+		}
+
+		part := ManifestPart{Name: name, Hash: contentHash(data)}
+		for _, placeholder := range placeholders {
+			part.Placeholders = append(part.Placeholders, ManifestPlaceholder{
+				Content: placeholder.TemplateContent,
+				Start:   placeholder.Placeholder.StartPos(),
+				End:     placeholder.Placeholder.EndPos(),
+			})
+		}
+		for _, run := range runs {
+			offsets := []int64{run.OpenTag.Start, run.OpenTag.End, run.CloseTag.Start, run.CloseTag.End}
+			if run.HasText {
+				offsets = append(offsets, run.Text.OpenTag.Start, run.Text.OpenTag.End, run.Text.CloseTag.Start, run.Text.CloseTag.End)
+			}
+			part.Runs = append(part.Runs, offsets)
+		}
+		manifest.Parts = append(manifest.Parts, part)
+	}
+	return manifest, nil
+}
+
+// LoadManifest sets the manifest compiled for the template before, so the runs of the parts are taken from the
+// manifest instead of parsing the parts on each rendering. An error is returned if the manifest does not belong
+// to the template or has an unsupported version.
+func (t *Template) LoadManifest(manifest *TemplateManifest) error {
+	if manifest.Version != templateManifestVersion {
+		return fmt.Errorf("unsupported manifest version %d", manifest.Version)
+	}
+	if manifest.Hash != contentHash(t.data) {
+		return fmt.Errorf("the manifest does not belong to the template")
+	}
+	for _, part := range manifest.Parts {
+		for _, offsets := range part.Runs {
+			if len(offsets) != 4 && len(offsets) != 8 {
+				return fmt.Errorf("invalid run offsets %v in %s", offsets, part.Name)
+			}
+		}
+	}
+	t.manifest = manifest
+	return nil
+}
+
+// ParseTemplateManifest reads a manifest serialized as JSON.
+func ParseTemplateManifest(data []byte) (*TemplateManifest, error) {
+	manifest := &TemplateManifest{}
+	if err := json.Unmarshal(data, manifest); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	return manifest, nil
+}
+
+// manifestRuns returns new runs for the parts of the manifest, or nil if no manifest is loaded.
+func (t *Template) manifestRuns() map[string]DocumentRuns {
+	if t.manifest == nil {
+		return nil
+	}
+	parts := make(map[string]DocumentRuns, len(t.manifest.Parts))
+	for _, part := range t.manifest.Parts {
+		runs := DocumentRuns{}
+		for _, offsets := range part.Runs {
+			run := NewEmptyRun()
+			run.OpenTag = Position{offsets[0], offsets[1]}
+			run.CloseTag = Position{offsets[2], offsets[3]}
+			if len(offsets) == 8 {
+				run.HasText = true
+				run.Text.OpenTag = Position{offsets[4], offsets[5]}
+				run.Text.CloseTag = Position{offsets[6], offsets[7]}
+			}
+			runs = append(runs, run)
+		}
+		parts[part.Name] = runs
+	}
+	return parts
+}
+
+// contentHash returns the hex-encoded SHA-256 hash of the data.
+func contentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,71 @@
+package docx
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestTemplate_CompileManifest(t *testing.T) {
+	data := rewriteArchive(t, readFile(t, "./test/template.docx"), func(name string, data []byte) []byte {
+		if name == DocumentXml {
+			return []byte(strings.Replace(string(data), "<w:body>", "<w:body>"+
+				`<w:p><w:r><w:t>Offer for {{.customer_name}}</w:t></w:r><w:r><w:t>, {{.city}}</w:t></w:r></w:p>`, 1))
+		}
+		return data
+	}, nil)
+	tpl, err := NewTemplate(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest, err := tpl.CompileManifest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest.Parts[0].Name != DocumentXml || len(manifest.Parts[0].Placeholders) == 0 {
+		t.Fatalf("unexpected manifest %+v", manifest.Parts)
+	}
+	placeholder := manifest.Parts[0].Placeholders[0]
+	if placeholder.Content != "{{.customer_name}}" {
+		t.Errorf("unexpected placeholder %+v", placeholder)
+	}
+
+	serialized, err := json.Marshal(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := ParseTemplateManifest(serialized)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cached, err := NewTemplate(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cached.LoadManifest(loaded); err != nil {
+		t.Fatal(err)
+	}
+
+	values := map[string]interface{}{"customer_name": "ACME", "city": "Bern"}
+	for _, template := range []*Template{tpl, cached} {
+		doc, err := Render(template, values)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if documentXml := string(doc.GetFile(DocumentXml)); !strings.Contains(documentXml, "Offer for ACME") || !strings.Contains(documentXml, ", Bern") {
+			t.Errorf("the template was not rendered")
+		}
+		doc.Close()
+	}
+
+	other, err := LoadTemplate("./test/template.docx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := other.LoadManifest(manifest); err == nil {
+		t.Errorf("expected an error for the manifest of another template")
+	}
+	if _, err := ParseTemplateManifest([]byte("no manifest")); err == nil {
+		t.Errorf("expected an error for an invalid manifest")
+	}
+}
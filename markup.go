@@ -0,0 +1,72 @@
+package docx
+
+import (
+	"bytes"
+	"html"
+	"regexp"
+)
+
+// markup.go contains helpers which operate directly on the raw XML bytes of a part.
+// They are used where the exact formatting of the surrounding markup must be preserved,
+// which is not possible when decoding and encoding the XML with encoding/xml.
+
+// tagAttrRegex returns a regex matching the given attribute (including the leading whitespace) inside a tag.
+func tagAttrRegex(name string) *regexp.Regexp {
+	return regexp.MustCompile(`\s` + regexp.QuoteMeta(name) + `="[^"]*"`)
+}
+
+// getTagAttr returns the unescaped value of the attribute inside the given start tag.
+func getTagAttr(tag []byte, name string) (string, bool) {
+	match := regexp.MustCompile(`\s` + regexp.QuoteMeta(name) + `="([^"]*)"`).FindSubmatch(tag)
+	if match == nil {
+		return "", false
+	}
+	return html.UnescapeString(string(match[1])), true
+}
+
+// setTagAttr sets the attribute inside the given start tag (e.g. <wp:docPr id="1"/>) and returns the new tag.
+// An existing attribute is replaced, otherwise the attribute is appended.
+func setTagAttr(tag []byte, name, value string) []byte {
+	attr := []byte(` ` + name + `="` + xmlEscape(value) + `"`)
+	re := tagAttrRegex(name)
+	if re.Match(tag) {
+		return re.ReplaceAllLiteral(tag, attr)
+	}
+
+	end := len(tag) - 1
+	if bytes.HasSuffix(tag, []byte("/>")) {
+		end = len(tag) - 2
+	}
+	out := make([]byte, 0, len(tag)+len(attr))
+	out = append(out, tag[:end]...)
+	out = append(out, attr...)
+	out = append(out, tag[end:]...)
+	return out
+}
+
+// insertAt inserts the content at the given byte offset.
+func insertAt(data []byte, offset int, content []byte) []byte {
+	out := make([]byte, 0, len(data)+len(content))
+	out = append(out, data[:offset]...)
+	out = append(out, content...)
+	return append(out, data[offset:]...)
+}
+
+// insertChild inserts the child element into the given element content, in front of the first existing
+// sibling which must follow it according to the schema. Without such a sibling the child is appended.
+func insertChild(content, child []byte, followers []string) []byte {
+	pos := len(content)
+	for _, follower := range followers {
+		loc := regexp.MustCompile(`<` + regexp.QuoteMeta(follower) + `[\s/>]`).FindIndex(content)
+		if loc != nil && loc[0] < pos {
+			pos = loc[0]
+		}
+	}
+	return insertAt(content, pos, child)
+}
+
+// onOffTagValue evaluates the raw tag of a WordprocessingML on/off property such as <w:titlePg/>.
+func onOffTagValue(tag []byte) bool {
+	value, exists := getTagAttr(tag, "w:val")
+	return !exists || (value != "false" && value != "0" && value != "off")
+}
@@ -0,0 +1,277 @@
+package docx
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// money.go implements the {{money}} and {{amountInWords}} helpers. Amounts are rounded half away from zero to the
+// minor units of their currency (e.g. 2 for EUR, 0 for JPY), the rounding works on the decimal digits, so 1.005
+// rounds to 1.01. The symbol, its position and the separators depend on the locale set with SetLocale.
+
+// Money is an amount in a currency given by its ISO 4217 code, e.g. Money{Amount: 1234.5, Currency: "EUR"}.
+// {{money .total}} formats it according to the locale, printed without the helper it is formatted like in en.
+type Money struct {
+	Amount   float64
+	Currency string
+}
+
+// String implements fmt.Stringer.
+func (m Money) String() string {
+	formatted, err := formatMoney(m, m.Currency, "")
+	if err != nil {
+		return fmt.Sprintf("%v %s", m.Amount, m.Currency)
+	}
+	return formatted
+}
+
+// currency describes the formatting of a currency.
+type currency struct {
+	symbol     string
+	minorUnits int
+}
+
+// currencies lists the symbols and minor units of common currencies. Other currencies are written with their
+// code and 2 minor units.
+var currencies = map[string]currency{
+	"AUD": {"A$", 2}, "BHD": {"BHD", 3}, "BRL": {"R$", 2}, "CAD": {"CA$", 2}, "CHF": {"CHF", 2},
+	"CNY": {"CN¥", 2}, "CZK": {"Kč", 2}, "DKK": {"kr.", 2}, "EUR": {"€", 2}, "GBP": {"£", 2},
+	"HKD": {"HK$", 2}, "HUF": {"Ft", 2}, "ILS": {"₪", 2}, "INR": {"₹", 2}, "ISK": {"kr", 0},
+	"JPY": {"¥", 0}, "KRW": {"₩", 0}, "KWD": {"KWD", 3}, "MXN": {"MX$", 2}, "NOK": {"kr", 2},
+	"NZD": {"NZ$", 2}, "OMR": {"OMR", 3}, "PLN": {"zł", 2}, "RUB": {"₽", 2}, "SEK": {"kr", 2},
+	"TRY": {"₺", 2}, "USD": {"$", 2}, "VND": {"₫", 0}, "ZAR": {"R", 2},
+}
+
+// moneyFormat describes how a locale writes amounts of money.
+type moneyFormat struct {
+	decimal     string
+	group       string
+	symbolAfter bool
+	space       bool // Separate the symbol from the amount by a non-breaking space
+}
+
+// moneyFormats lists the formats by language and locale, en is used for unknown locales.
+var moneyFormats = map[string]moneyFormat{
+	"en":    {".", ",", false, false},
+	"en-IN": {".", ",", false, false},
+	"de":    {",", ".", true, true},
+	"de-CH": {".", "’", false, true},
+	"de-LI": {".", "’", false, true},
+	"fr":    {",", "\u202f", true, true},
+	"fr-CH": {",", "\u202f", true, true},
+	"it":    {",", ".", true, true},
+	"it-CH": {".", "’", false, true},
+	"es":    {",", ".", true, true},
+	"pt":    {",", ".", true, true},
+	"pt-BR": {",", ".", false, true},
+	"nl":    {",", ".", false, true},
+	"sv":    {",", "\u00a0", true, true},
+	"da":    {",", ".", true, true},
+	"nb":    {",", "\u00a0", true, true},
+	"pl":    {",", "\u00a0", true, true},
+	"cs":    {",", "\u00a0", true, true},
+	"ja":    {".", ",", false, false},
+	"zh":    {".", ",", false, false},
+}
+
+var (
+	// currencyCodeRegex matches ISO 4217 currency codes.
+	currencyCodeRegex = regexp.MustCompile(`^[A-Z]{3}$`)
+	// decimalRegex matches decimal numbers without exponent.
+	decimalRegex = regexp.MustCompile(`^[+-]?(?:[0-9]+\.?[0-9]*|\.[0-9]+)$`)
+)
+
+// moneyHelper implements {{money AMOUNT CURRENCY}}, e.g. {{money .total "EUR"}} writes €1,234.50 in en and
+// 1.234,50 € in de. The currency may be left out for Money values.
+func (tr *TemplateReplacer) moneyHelper(amount interface{}, code ...string) (string, error) {
+	if len(code) > 1 {
+		return "", fmt.Errorf("money: expected at most one currency, got %d", len(code))
+	}
+	currency := ""
+	if len(code) == 1 {
+		currency = code[0]
+	}
+	return formatMoney(amount, currency, tr.locale)
+}
+
+// amountInWordsHelper implements {{amountInWords AMOUNT LANGUAGE}}, e.g. {{amountInWords .total "en"}} writes
+// "one thousand two hundred thirty-four and 50/100" for cheques and contracts. Without language, the language
+// of the locale is used. The minor units are written as fraction, according to the currency of Money values.
+func (tr *TemplateReplacer) amountInWordsHelper(amount interface{}, tag ...string) (string, error) {
+	language, err := tr.helperLanguage("amountInWords", tag)
+	if err != nil {
+		return "", err
+	}
+	minorUnits := 2
+	if m, isMoney := amount.(Money); isMoney {
+		minorUnits = currencyOf(m.Currency).minorUnits
+	}
+	negative, whole, fraction, err := roundAmount(amount, minorUnits)
+	if err != nil {
+		return "", fmt.Errorf("amountInWords: %w", err)
+	}
+	number, err := strconv.ParseUint(whole, 10, 64)
+	if err != nil {
+		return "", fmt.Errorf("amountInWords: %s is too large", whole)
+	}
+	words := language.cardinal(number)
+	if negative {
+		words = language.minus + " " + words
+	}
+	if minorUnits == 0 {
+		return words, nil
+	}
+	return words + " " + language.and + " " + fraction + "/1" + strings.Repeat("0", minorUnits), nil
+}
+
+// formatMoney formats the amount in the currency according to the locale. Money values use their currency unless
+// another one is given.
+func formatMoney(amount interface{}, code, locale string) (string, error) {
+	if m, isMoney := amount.(Money); isMoney && code == "" {
+		code = m.Currency
+	}
+	if !currencyCodeRegex.MatchString(code) {
+		return "", fmt.Errorf("money: invalid currency code %q", code)
+	}
+	currency := currencyOf(code)
+	negative, whole, fraction, err := roundAmount(amount, currency.minorUnits)
+	if err != nil {
+		return "", fmt.Errorf("money: %w", err)
+	}
+
+	tag, format := "en", moneyFormats["en"]
+	for _, fallback := range languageFallbacks(locale) {
+		if f, exists := moneyFormats[fallback]; exists {
+			tag, format = fallback, f
+			break
+		}
+	}
+	number := groupDigits(whole, format.group, tag == "en-IN")
+	if fraction != "" {
+		number += format.decimal + fraction
+	}
+
+	// symbols ending with a letter such as CHF are always separated from the amount, a non-breaking space keeps
+	// them on the same line
+	separator := ""
+	symbol := []rune(currency.symbol)
+	if format.space || !format.symbolAfter && unicode.IsLetter(symbol[len(symbol)-1]) {
+		separator = "\u00a0"
+	}
+	var formatted string
+	if format.symbolAfter {
+		formatted = number + separator + currency.symbol
+	} else {
+		formatted = currency.symbol + separator + number
+	}
+	if negative {
+		formatted = "-" + formatted
+	}
+	return formatted, nil
+}
+
+// currencyOf returns the formatting of the currency.
+func currencyOf(code string) currency {
+	if currency, exists := currencies[code]; exists {
+		return currency
+	}
+	return currency{code, 2}
+}
+
+// roundAmount rounds the amount half away from zero to the given number of decimal places and returns its sign
+// and the digits before and after the decimal point. The amount is a number, a numeric string or a Money value.
+func roundAmount(amount interface{}, places int) (negative bool, whole, fraction string, err error) {
+	digits, err := decimalDigits(amount)
+	if err != nil {
+		return false, "", "", err
+	}
+
+	negative = strings.HasPrefix(digits, "-")
+	digits = strings.TrimLeft(digits, "+-")
+	whole, fraction, _ = strings.Cut(digits, ".")
+	if whole == "" {
+		whole = "0"
+	}
+	if len(fraction) <= places {
+		fraction += strings.Repeat("0", places-len(fraction))
+	} else {
+		roundUp := fraction[places] >= '5'
+		fraction = fraction[:places]
+		if roundUp {
+			incremented := []byte(whole + fraction)
+			i := len(incremented) - 1
+			for ; i >= 0 && incremented[i] == '9'; i-- {
+				incremented[i] = '0'
+			}
+			if i < 0 {
+				incremented = append([]byte{'1'}, incremented...)
+			} else {
+				incremented[i]++
+			}
+			whole, fraction = string(incremented[:len(incremented)-places]), string(incremented[len(incremented)-places:])
+		}
+	}
+	whole = strings.TrimLeft(whole, "0")
+	if whole == "" {
+		whole = "0"
+	}
+	if whole == "0" && strings.Trim(fraction, "0") == "" {
+		negative = false
+	}
+	return negative, whole, fraction, nil
+}
+
+// decimalDigits returns the number, numeric string or Money value as decimal number without exponent.
+func decimalDigits(amount interface{}) (string, error) {
+	var digits string
+	switch a := amount.(type) {
+	case Money:
+		digits = strconv.FormatFloat(a.Amount, 'f', -1, 64)
+	case float64:
+		digits = strconv.FormatFloat(a, 'f', -1, 64)
+	case float32:
+		digits = strconv.FormatFloat(float64(a), 'f', -1, 32)
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		digits = fmt.Sprint(a)
+	case json.Number:
+		digits = a.String()
+	case string:
+		digits = strings.TrimSpace(a)
+	default:
+		return "", fmt.Errorf("%v is not a number", amount)
+	}
+	if !decimalRegex.MatchString(digits) {
+		// e.g. 1.5e6, the exponent is expanded
+		f, err := strconv.ParseFloat(digits, 64)
+		if err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+			return "", fmt.Errorf("%q is not a number", digits)
+		}
+		digits = strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return digits, nil
+}
+
+// groupDigits inserts the separator between groups of thousands. Indian grouping puts the separator between
+// groups of hundreds above the thousands, e.g. 12,34,567.
+func groupDigits(whole, separator string, indian bool) string {
+	if len(whole) <= 3 {
+		return whole
+	}
+	head, tail := whole[:len(whole)-3], whole[len(whole)-3:]
+	size := 3
+	if indian {
+		size = 2
+	}
+	var groups []string
+	for len(head) > size {
+		groups = append([]string{head[len(head)-size:]}, groups...)
+		head = head[:len(head)-size]
+	}
+	groups = append([]string{head}, groups...)
+	return strings.Join(append(groups, tail), separator)
+}
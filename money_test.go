@@ -0,0 +1,71 @@
+package docx
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFormatMoney(t *testing.T) {
+	tests := []struct {
+		amount   interface{}
+		currency string
+		locale   string
+		expected string
+	}{
+		{1234.5, "EUR", "", "€1,234.50"},
+		{1234.5, "EUR", "de-DE", "1.234,50\u00a0€"},
+		{1234.5, "CHF", "de-CH", "CHF\u00a01’234.50"},
+		{1234.5, "CHF", "en", "CHF\u00a01,234.50"},
+		{-1234567.891, "EUR", "fr-FR", "-1\u202f234\u202f567,89\u00a0€"},
+		{1.005, "USD", "", "$1.01"},
+		{"2.675", "USD", "", "$2.68"},
+		{0.999, "USD", "", "$1.00"},
+		{-0.001, "USD", "", "$0.00"},
+		{1234.5, "JPY", "ja", "¥1,235"},
+		{12.3456, "KWD", "", "KWD\u00a012.346"},
+		{1234567, "INR", "en-IN", "₹12,34,567.00"},
+		{1e6, "XYZ", "", "XYZ\u00a01,000,000.00"},
+		{Money{Amount: 99.9, Currency: "GBP"}, "", "", "£99.90"},
+		{Money{Amount: 99.9, Currency: "GBP"}, "EUR", "", "€99.90"},
+	}
+	for _, test := range tests {
+		formatted, err := formatMoney(test.amount, test.currency, test.locale)
+		if err != nil {
+			t.Errorf("%v %s: %v", test.amount, test.currency, err)
+		} else if formatted != test.expected {
+			t.Errorf("%v %s in %q: expected %q, got %q", test.amount, test.currency, test.locale, test.expected, formatted)
+		}
+	}
+
+	if _, err := formatMoney(12, "euro", ""); err == nil {
+		t.Errorf("expected an error for an invalid currency code")
+	}
+	if _, err := formatMoney("a lot", "EUR", ""); err == nil {
+		t.Errorf("expected an error for an amount which is not a number")
+	}
+	if s := (Money{Amount: 5, Currency: "USD"}).String(); s != "$5.00" {
+		t.Errorf("unexpected string %q", s)
+	}
+}
+
+func TestDocument_MoneyHelpers(t *testing.T) {
+	doc, err := NewDocument().
+		Paragraph(`Total: {{money .total "EUR"}} ({{amountInWords .total}})`).
+		Paragraph(`{{money .fee}}, {{amountInWords .fee "en"}}`).
+		Build(WithLocale("de-CH"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+	if err := doc.ExecuteTemplate(map[string]interface{}{"total": 1234.5, "fee": Money{Amount: 3000, Currency: "JPY"}}); err != nil {
+		t.Fatal(err)
+	}
+	texts, err := paragraphTexts(doc.GetFile(DocumentXml))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "Total: €\u00a01’234.50 (eintausendzweihundertvierunddreißig und 50/100)|¥\u00a03’000, three thousand"
+	if text := strings.Join(texts, "|"); text != expected {
+		t.Errorf("expected %q, got %q", expected, text)
+	}
+}
@@ -0,0 +1,185 @@
+package docx
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"text/template/parse"
+
+	"github.com/izetmolla/docx/templating"
+)
+
+// nilsafe.go makes field chains like {{.order.customer.address.city}} nil-safe: if any value in the middle of
+// the chain is nil, the chain results in an empty value instead of aborting the execution. The chains are
+// replaced by calls of the docxField function after parsing, so this works in conditions and loops as well.
+
+// fieldReferenceRegex matches the field chain an action starts with, e.g. user.name in {{.user.name | upper}}.
+var fieldReferenceRegex = regexp.MustCompile(`\{\{-?\s*\.([\p{L}\p{N}_]+(?:\.[\p{L}\p{N}_]+)*)`)
+
+// emptyValue is the result of a field chain with a nil value in the middle. It prints as empty string,
+// is false in conditions and has no items to range over.
+type emptyValue map[string]interface{}
+
+// String implements fmt.Stringer.
+func (emptyValue) String() string {
+	return ""
+}
+
+// fieldHelper implements docxField, which resolves the dotted path of fields, map keys and methods without
+// arguments in the data. Missing map keys result in nil, just like in text/template, missing fields are errors.
+func fieldHelper(data interface{}, path string) (interface{}, error) {
+	value := data
+	names := strings.Split(path, ".")
+	for i, name := range names {
+		if isNil(value) {
+			if i == 0 {
+				return nil, nil
+			}
+			return emptyValue(nil), nil
+		}
+		next, exists := templating.Lookup(value, name)
+		if !exists {
+			if indirect(reflect.ValueOf(value)).Kind() == reflect.Map {
+				return nil, nil
+			}
+			if behindNilEmbedded(value, name) {
+				return emptyValue(nil), nil
+			}
+			return nil, fmt.Errorf("can't evaluate field %s in type %T", name, value)
+		}
+		value = next
+	}
+	return value, nil
+}
+
+// pathExists reports whether the field chain can be resolved in the data. Chains with a nil value
+// in the middle exist, their result is empty.
+func pathExists(data interface{}, path string) bool {
+	value := data
+	for _, name := range strings.Split(path, ".") {
+		if isNil(value) {
+			return true
+		}
+		next, exists := templating.Lookup(value, name)
+		if !exists {
+			return behindNilEmbedded(value, name)
+		}
+		value = next
+	}
+	return true
+}
+
+// behindNilEmbedded reports whether the name is an exported field of the struct which is promoted
+// through a nil embedded pointer.
+func behindNilEmbedded(value interface{}, name string) bool {
+	v := indirect(reflect.ValueOf(value))
+	if v.Kind() != reflect.Struct {
+		return false
+	}
+	field, exists := v.Type().FieldByName(name)
+	if !exists || !field.IsExported() || len(field.Index) < 2 {
+		return false
+	}
+	_, err := v.FieldByIndexErr(field.Index)
+	return err != nil
+}
+
+// isNil reports whether the value is nil or a nil pointer, interface, map or slice.
+func isNil(value interface{}) bool {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Invalid:
+		return true
+	case reflect.Pointer, reflect.Interface, reflect.Map, reflect.Slice:
+		return v.IsNil()
+	}
+	return false
+}
+
+// indirect follows pointers and interfaces.
+func indirect(v reflect.Value) reflect.Value {
+	for (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) && !v.IsNil() {
+		v = v.Elem()
+	}
+	return v
+}
+
+// nilSafeFields replaces all field chains of the parsed template which consist of more than one field
+// by calls of docxField. Chains which call a method with arguments are kept.
+func nilSafeFields(node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			nilSafeFields(child)
+		}
+	case *parse.ActionNode:
+		nilSafeFields(n.Pipe)
+	case *parse.IfNode:
+		nilSafeFields(&n.BranchNode)
+	case *parse.RangeNode:
+		nilSafeFields(&n.BranchNode)
+	case *parse.WithNode:
+		nilSafeFields(&n.BranchNode)
+	case *parse.BranchNode:
+		nilSafeFields(n.Pipe)
+		nilSafeFields(n.List)
+		nilSafeFields(n.ElseList)
+	case *parse.TemplateNode:
+		nilSafeFields(n.Pipe)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for c, cmd := range n.Cmds {
+			for i, arg := range cmd.Args {
+				if i == 0 && (len(cmd.Args) > 1 || c > 0) {
+					// the chain is called with arguments
+					continue
+				}
+				cmd.Args[i] = nilSafeField(arg)
+			}
+		}
+	}
+}
+
+// nilSafeField returns the call of docxField which replaces the field chain, or the node itself.
+func nilSafeField(node parse.Node) parse.Node {
+	var receiver parse.Node
+	var fields []string
+	switch n := node.(type) {
+	case *parse.FieldNode:
+		receiver, fields = &parse.DotNode{NodeType: parse.NodeDot, Pos: n.Pos}, n.Ident
+	case *parse.VariableNode:
+		if len(n.Ident) < 2 {
+			return node
+		}
+		receiver, fields = &parse.VariableNode{NodeType: parse.NodeVariable, Pos: n.Pos, Ident: n.Ident[:1]}, n.Ident[1:]
+	case *parse.PipeNode:
+		nilSafeFields(n)
+		return node
+	default:
+		return node
+	}
+	if len(fields) < 2 {
+		return node
+	}
+
+	path := strings.Join(fields, ".")
+	return &parse.PipeNode{
+		NodeType: parse.NodePipe,
+		Pos:      node.Position(),
+		Cmds: []*parse.CommandNode{{
+			NodeType: parse.NodeCommand,
+			Pos:      node.Position(),
+			Args: []parse.Node{
+				parse.NewIdentifier("docxField").SetPos(node.Position()),
+				receiver,
+				&parse.StringNode{NodeType: parse.NodeString, Pos: node.Position(), Quoted: `"` + path + `"`, Text: path},
+			},
+		}},
+	}
+}
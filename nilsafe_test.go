@@ -0,0 +1,89 @@
+package docx
+
+import (
+	"strings"
+	"testing"
+)
+
+type nilSafeAddress struct {
+	City string
+}
+
+type nilSafeCustomer struct {
+	Address *nilSafeAddress
+}
+
+type nilSafeBranch struct {
+	*nilSafeAddress
+	Name string
+}
+
+func TestDocument_NilSafeFields(t *testing.T) {
+	doc, err := OpenBytes(rewriteArchive(t, readFile(t, "./test/template.docx"), func(name string, data []byte) []byte {
+		if name == DocumentXml {
+			return []byte(strings.Replace(string(data), "<w:body>", "<w:body>"+
+				`<w:p><w:r><w:t>City: [{{.order.customer.address.city}}] [{{.buyer.Address.City}}] [{{.order.missing}}]</w:t></w:r></w:p>`+
+				`<w:p><w:r><w:t>{{if .order.customer.address.city}}</w:t></w:r></w:p>`+
+				`<w:p><w:r><w:t>Has city</w:t></w:r></w:p>`+
+				`<w:p><w:r><w:t>{{else}}</w:t></w:r></w:p>`+
+				`<w:p><w:r><w:t>No city</w:t></w:r></w:p>`+
+				`<w:p><w:r><w:t>{{end}}</w:t></w:r></w:p>`+
+				`<w:p><w:r><w:t>{{range .order.customer.orders}}</w:t></w:r></w:p>`+
+				`<w:p><w:r><w:t>Order</w:t></w:r></w:p>`+
+				`<w:p><w:r><w:t>{{end}}</w:t></w:r></w:p>`+
+				`<w:p><w:r><w:t>{{with $o := .order}}</w:t></w:r></w:p>`+
+				`<w:p><w:r><w:t>Variable [{{$o.customer.address.city}}]</w:t></w:r></w:p>`+
+				`<w:p><w:r><w:t>{{end}}</w:t></w:r></w:p>`, 1))
+		}
+		return data
+	}, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+
+	err = doc.ExecuteTemplate(map[string]interface{}{
+		"order": map[string]interface{}{"customer": nil},
+		"buyer": &nilSafeCustomer{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	documentXml := string(doc.GetFile(DocumentXml))
+	for _, expected := range []string{"City: [] [] [{{.order.missing}}]", "No city", "Variable []"} {
+		if !strings.Contains(documentXml, expected) {
+			t.Errorf("expected %q in the document", expected)
+		}
+	}
+	for _, unexpected := range []string{"Has city", "Order"} {
+		if strings.Contains(documentXml, unexpected) {
+			t.Errorf("unexpected %q in the document", unexpected)
+		}
+	}
+}
+
+func TestFieldHelper(t *testing.T) {
+	data := map[string]interface{}{
+		"customer": &nilSafeCustomer{Address: &nilSafeAddress{City: "Berlin"}},
+		"empty":    &nilSafeCustomer{},
+	}
+	if value, err := fieldHelper(data, "customer.Address.City"); err != nil || value != "Berlin" {
+		t.Errorf("expected Berlin, got %v (%v)", value, err)
+	}
+	if value, err := fieldHelper(data, "empty.Address.City"); err != nil || value == nil || value.(emptyValue) != nil {
+		t.Errorf("expected an empty value, got %v (%v)", value, err)
+	}
+	if value, err := fieldHelper(map[string]interface{}{"branch": nilSafeBranch{Name: "HQ"}}, "branch.City"); err != nil || value == nil || value.(emptyValue) != nil {
+		t.Errorf("expected an empty value for an embedded nil pointer, got %v (%v)", value, err)
+	}
+	if !pathExists(map[string]interface{}{"branch": nilSafeBranch{}}, "branch.City") {
+		t.Errorf("expected the field behind an embedded nil pointer to exist")
+	}
+	if value, err := fieldHelper(data, "unknown.name"); err != nil || value != nil {
+		t.Errorf("expected nil for a missing key, got %v (%v)", value, err)
+	}
+	if _, err := fieldHelper(data, "customer.Phone"); err == nil {
+		t.Errorf("expected an error for a missing field")
+	}
+}
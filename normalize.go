@@ -0,0 +1,203 @@
+package docx
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+)
+
+// normalize.go implements NormalizePlaceholders, which repairs placeholders Word damaged while the template
+// was edited.
+
+// paragraphBoundaryRegex matches the markup between runs of different paragraphs.
+var paragraphBoundaryRegex = regexp.MustCompile(`</w:p>|<w:p[\s>]`)
+
+// NormalizePlaceholders repairs the placeholders of the document, so it renders reliably thereafter:
+//   - placeholders split across multiple runs (e.g. because a part was formatted differently or Word inserted
+//     spell checking marks) are moved into their first run, runs which are left empty are removed and
+//   - braces and quotes Word replaced by typographic quotes (““.name””, {{eq .status “open”}}) are straightened.
+//
+// The placeholder takes the formatting of its first run. Write the document to keep the cleaned template.
+func (d *Document) NormalizePlaceholders() error {
+	for _, part := range d.xmlParts() {
+		parser, exists := d.runParsers[part]
+		if !exists {
+			continue
+		}
+		data, changed := normalizeRuns(d.GetFile(part), parser.Runs().WithText())
+		if !changed {
+			continue
+		}
+		if err := d.SetFile(part, data); err != nil {
+			return err
+		}
+		if err := d.refreshRuns(part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// normalizeRuns repairs the placeholders inside the text runs of each paragraph and returns the new part data.
+func normalizeRuns(data []byte, runs DocumentRuns) ([]byte, bool) {
+	return moveActions(data, runs, findTemplateActions, normalizeAction)
+}
+
+// moveActions moves the actions found by find into the run they start in, repaired by repair, and returns the
+// new part data.
+func moveActions(data []byte, runs DocumentRuns, find func(string) []templateAction, repair func(string, templateAction) string) ([]byte, bool) {
+	// the new text of each run, see normalizeParagraph
+	texts := make([]string, len(runs))
+	for start := 0; start < len(runs); {
+		end := start + 1
+		for end < len(runs) && !paragraphBoundaryRegex.Match(data[runs[end-1].CloseTag.End:runs[end].OpenTag.Start]) {
+			end++
+		}
+		copy(texts[start:end], normalizeParagraph(data, runs[start:end], find, repair))
+		start = end
+	}
+
+	changed := false
+	for i := len(runs) - 1; i >= 0; i-- {
+		run := runs[i]
+		if texts[i] == run.GetText(data) {
+			continue
+		}
+		changed = true
+
+		var out bytes.Buffer
+		if texts[i] == "" && isTextOnlyRun(data, run) {
+			out.Write(data[:run.OpenTag.Start])
+			out.Write(data[run.CloseTag.End:])
+			data = out.Bytes()
+			continue
+		}
+
+		tag := data[run.Text.OpenTag.Start:run.Text.OpenTag.End]
+		if strings.TrimSpace(texts[i]) != texts[i] {
+			tag = setTagAttr(tag, "xml:space", "preserve")
+		}
+		out.Write(data[:run.Text.OpenTag.Start])
+		out.Write(tag)
+		out.WriteString(texts[i])
+		out.Write(data[run.Text.CloseTag.Start:])
+		data = out.Bytes()
+	}
+	return data, changed
+}
+
+// isTextOnlyRun reports whether the run contains nothing but its properties and its text.
+func isTextOnlyRun(data []byte, run *Run) bool {
+	content := bytes.Replace(data[run.OpenTag.End:run.CloseTag.Start], data[run.Text.OpenTag.Start:run.Text.CloseTag.End], nil, 1)
+	content = bytes.ReplaceAll(runPropertiesRegex.ReplaceAll(content, nil), []byte("<w:rPr/>"), nil)
+	return len(bytes.TrimSpace(content)) == 0
+}
+
+// normalizeParagraph returns the new texts of the runs of a paragraph. Each action is repaired and moved
+// into the run it starts in, the text around the actions stays in its run.
+func normalizeParagraph(data []byte, runs DocumentRuns, find func(string) []templateAction, repair func(string, templateAction) string) []string {
+	var text strings.Builder
+	offsets := make([]int, len(runs)+1) // the offsets of the runs inside the text of the paragraph
+	for i, run := range runs {
+		offsets[i] = text.Len()
+		text.WriteString(run.GetText(data))
+	}
+	offsets[len(runs)] = text.Len()
+	paragraph := text.String()
+
+	texts := make([]string, len(runs))
+	// appendText adds the text between from and to to the runs it belongs to
+	appendText := func(from, to int) {
+		for i := range runs {
+			if start, end := max(from, offsets[i]), min(to, offsets[i+1]); start < end {
+				texts[i] += paragraph[start:end]
+			}
+		}
+	}
+
+	pos := 0
+	for _, action := range find(paragraph) {
+		appendText(pos, action.Start)
+		first := 0
+		for first < len(runs)-1 && offsets[first+1] <= action.Start {
+			first++
+		}
+		texts[first] += repair(paragraph[action.Start:action.End], action)
+		pos = action.End
+	}
+	appendText(pos, len(paragraph))
+	return texts
+}
+
+// findDelimitedActions returns a function which finds the placeholders enclosed by the delimiters of an
+// alternative engine. Like in parseDelimitedPlaceholders, each left delimiter is paired with the next right one.
+func findDelimitedActions(left, right string) func(string) []templateAction {
+	return func(text string) []templateAction {
+		var actions []templateAction
+		for offset := 0; ; {
+			start := strings.Index(text[offset:], left)
+			if start < 0 {
+				return actions
+			}
+			start += offset
+			end := strings.Index(text[start+len(left):], right)
+			if end < 0 {
+				return actions
+			}
+			end += start + len(left) + len(right)
+			actions = append(actions, templateAction{Start: start, End: end, Left: len(left), Right: len(right)})
+			offset = end
+		}
+	}
+}
+
+// keepAction returns the action unchanged, the placeholders of alternative engines are only moved.
+func keepAction(text string, _ templateAction) string {
+	return text
+}
+
+// normalizeAction replaces the typographic delimiters and quotes of the action.
+func normalizeAction(text string, action templateAction) string {
+	content := straightenQuotes(text[action.Left : len(text)-action.Right])
+	return "{{" + content + "}}"
+}
+
+// straightenQuotes replaces the typographic quotes enclosing strings by straight double quotes. Apostrophes
+// inside strings are kept, e.g. “it’s” becomes "it’s".
+func straightenQuotes(content string) string {
+	var out strings.Builder
+	closing := "" // the quotes which close the current string, empty outside of strings
+	escaped := false
+	for _, r := range content {
+		typographic := strings.ContainsRune("“”‘’", r)
+		switch {
+		case escaped:
+			escaped = false
+			typographic = false
+		case closing == "":
+			switch {
+			case r == '"':
+				closing = `"`
+			case r == '“' || r == '”':
+				closing = "\"“”"
+			case r == '‘' || r == '’':
+				closing = "‘’"
+			case r == '`' || r == '\'':
+				closing = string(r)
+			}
+		case r == '\\' && closing != "`":
+			escaped = true
+			typographic = false
+		case strings.ContainsRune(closing, r):
+			closing = ""
+		default:
+			typographic = false
+		}
+
+		if typographic {
+			r = '"'
+		}
+		out.WriteRune(r)
+	}
+	return out.String()
+}
@@ -0,0 +1,78 @@
+package docx
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDocument_NormalizePlaceholders(t *testing.T) {
+	doc, err := OpenBytes(rewriteArchive(t, readFile(t, "./test/template.docx"), func(name string, data []byte) []byte {
+		if name == DocumentXml {
+			return []byte(strings.Replace(string(data), "<w:body>", "<w:body>"+
+				`<w:p><w:r><w:rPr><w:b/></w:rPr><w:t xml:space="preserve">Dear {{.</w:t></w:r><w:proofErr w:type="spellStart"/>`+
+				`<w:r><w:t>name</w:t></w:r><w:proofErr w:type="spellEnd"/><w:r><w:rPr><w:i/></w:rPr><w:t xml:space="preserve">}}, welcome {{</w:t></w:r>`+
+				`<w:r><w:t>.city}}</w:t></w:r></w:p>`+
+				`<w:p><w:r><w:t>Status: {{if eq .status “open”}}open{{else}}closed{{end}}</w:t></w:r></w:p>`+
+				`<w:p><w:r><w:t>Smart ““.name””</w:t></w:r></w:p>`+
+				`<w:p><w:r><w:t>{{.</w:t></w:r></w:p><w:p><w:r><w:t>unclosed}}</w:t></w:r></w:p>`, 1))
+		}
+		return data
+	}, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+
+	if err := doc.NormalizePlaceholders(); err != nil {
+		t.Fatal(err)
+	}
+	documentXml := string(doc.GetFile(DocumentXml))
+	for _, expected := range []string{
+		`<w:r><w:rPr><w:b/></w:rPr><w:t xml:space="preserve">Dear {{.name}}</w:t></w:r><w:proofErr w:type="spellStart"/><w:proofErr w:type="spellEnd"/>`,
+		`<w:r><w:rPr><w:i/></w:rPr><w:t xml:space="preserve">, welcome {{.city}}</w:t></w:r></w:p>`,
+		`{{if eq .status "open"}}open{{else}}closed{{end}}`,
+		`Smart {{.name}}`,
+		// actions spanning paragraphs are not moved
+		`<w:t>{{.</w:t></w:r></w:p><w:p><w:r><w:t>unclosed}}</w:t>`,
+	} {
+		if !strings.Contains(documentXml, expected) {
+			t.Errorf("expected %q in the document", expected)
+		}
+	}
+	for _, issue := range doc.Lint() {
+		if issue.Paragraph <= 3 {
+			t.Errorf("unexpected issue after normalizing: %s", issue)
+		}
+	}
+
+	if err := doc.ExecuteTemplate(map[string]interface{}{"name": "Bob", "city": "Berlin", "status": "open"}); err != nil {
+		t.Fatal(err)
+	}
+	documentXml = string(doc.GetFile(DocumentXml))
+	for _, expected := range []string{">Dear Bob<", ">, welcome Berlin<", "Status: open<", "Smart Bob<"} {
+		if !strings.Contains(documentXml, expected) {
+			t.Errorf("expected %q in the document", expected)
+		}
+	}
+}
+
+func TestStraightenQuotes(t *testing.T) {
+	tests := map[string]string{
+		`eq .status “open”`:           `eq .status "open"`,
+		`eq .status ‘open’`:           `eq .status "open"`,
+		`printf “it’s %s” .name`:      `printf "it’s %s" .name`,
+		`printf "“quoted”"`:           `printf "“quoted”"`,
+		"printf `“raw”`":              "printf `“raw”`",
+		`printf “say \“hi\””`:         `printf "say \“hi\”"`,
+		`.name`:                       `.name`,
+		`eq .a “x” | printf “%v”`:     `eq .a "x" | printf "%v"`,
+		`index .m “key” | not`:        `index .m "key" | not`,
+		`eq .c 'a'`:                   `eq .c 'a'`,
+		`and (eq .a “x”) (eq .b “y”)`: `and (eq .a "x") (eq .b "y")`,
+	}
+	for input, expected := range tests {
+		if result := straightenQuotes(input); result != expected {
+			t.Errorf("straightenQuotes(%q): expected %q, got %q", input, expected, result)
+		}
+	}
+}
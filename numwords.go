@@ -0,0 +1,378 @@
+package docx
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// numwords.go writes numbers in words in English, German and French, e.g. for amounts on cheques and contracts,
+// and implements the {{numToWords}}, {{ordinal}} and {{roman}} helpers for text like "thirty (30) days".
+
+// numberLanguage writes numbers in words in a language.
+type numberLanguage struct {
+	minus    string // The word for negative numbers
+	and      string // The word between the amount and its minor units, see amountInWords
+	cardinal func(n uint64) string
+	ordinal  func(n uint64) string // The ordinal in digits, e.g. 1st
+}
+
+// numberLanguages lists the supported languages by their language code.
+var numberLanguages = map[string]numberLanguage{
+	"en": {minus: "minus", and: "and", cardinal: englishCardinal, ordinal: englishOrdinal},
+	"de": {minus: "minus", and: "und", cardinal: germanCardinal, ordinal: germanOrdinal},
+	"fr": {minus: "moins", and: "et", cardinal: frenchCardinal, ordinal: frenchOrdinal},
+}
+
+// languageCode returns the language of the BCP 47 tag, e.g. de for de-CH. English is used for the empty tag.
+func languageCode(tag string) string {
+	code, _, _ := strings.Cut(strings.ToLower(tag), "-")
+	if code == "" {
+		return "en"
+	}
+	return code
+}
+
+// numberLanguageOf returns the number words of the language of the BCP 47 tag, see languageCode.
+func numberLanguageOf(tag string) (numberLanguage, error) {
+	language, exists := numberLanguages[languageCode(tag)]
+	if !exists {
+		return numberLanguage{}, fmt.Errorf("numbers in words are not supported in %q", tag)
+	}
+	return language, nil
+}
+
+// helperLanguage returns the language given to a helper, e.g. {{numToWords .days "fr"}}, or the language of the
+// locale set with SetLocale.
+func (tr *TemplateReplacer) helperLanguage(helper string, tag []string) (numberLanguage, error) {
+	if len(tag) > 1 {
+		return numberLanguage{}, fmt.Errorf("%s: expected at most one language, got %d", helper, len(tag))
+	}
+	language := tr.locale
+	if len(tag) == 1 {
+		language = tag[0]
+	}
+	numbers, err := numberLanguageOf(language)
+	if err != nil {
+		return numberLanguage{}, fmt.Errorf("%s: %w", helper, err)
+	}
+	return numbers, nil
+}
+
+// numToWordsHelper implements {{numToWords NUMBER LANGUAGE}}, e.g. {{numToWords .days}} ({{.days}}) days writes
+// thirty (30) days. Without language, the language of the locale is used.
+func (tr *TemplateReplacer) numToWordsHelper(value interface{}, tag ...string) (string, error) {
+	language, err := tr.helperLanguage("numToWords", tag)
+	if err != nil {
+		return "", err
+	}
+	negative, n, err := wholeNumber(value)
+	if err != nil {
+		return "", fmt.Errorf("numToWords: %w", err)
+	}
+	if negative {
+		return language.minus + " " + language.cardinal(n), nil
+	}
+	return language.cardinal(n), nil
+}
+
+// ordinalHelper implements {{ordinal NUMBER LANGUAGE}}, e.g. {{ordinal 21}} writes 21st, 21. in de and 21e in fr.
+func (tr *TemplateReplacer) ordinalHelper(value interface{}, tag ...string) (string, error) {
+	language, err := tr.helperLanguage("ordinal", tag)
+	if err != nil {
+		return "", err
+	}
+	negative, n, err := wholeNumber(value)
+	if err != nil {
+		return "", fmt.Errorf("ordinal: %w", err)
+	}
+	if negative {
+		return "", fmt.Errorf("ordinal: %v is negative", value)
+	}
+	return language.ordinal(n), nil
+}
+
+// romanNumerals lists the values of the Roman numerals including the subtractive forms, the largest first.
+var romanNumerals = []struct {
+	value   uint64
+	numeral string
+}{
+	{1000, "M"}, {900, "CM"}, {500, "D"}, {400, "CD"}, {100, "C"}, {90, "XC"},
+	{50, "L"}, {40, "XL"}, {10, "X"}, {9, "IX"}, {5, "V"}, {4, "IV"}, {1, "I"},
+}
+
+// romanHelper implements {{roman NUMBER}}, e.g. {{roman 2024}} writes MMXXIV. Numbers from 1 to 3999 are supported.
+func romanHelper(value interface{}) (string, error) {
+	negative, n, err := wholeNumber(value)
+	if err != nil {
+		return "", fmt.Errorf("roman: %w", err)
+	}
+	if negative || n < 1 || n > 3999 {
+		return "", fmt.Errorf("roman: %v is not between 1 and 3999", value)
+	}
+	var numeral strings.Builder
+	for _, roman := range romanNumerals {
+		for ; n >= roman.value; n -= roman.value {
+			numeral.WriteString(roman.numeral)
+		}
+	}
+	return numeral.String(), nil
+}
+
+// wholeNumber returns the sign and the absolute value of a whole number given as number or numeric string.
+func wholeNumber(value interface{}) (bool, uint64, error) {
+	digits, err := decimalDigits(value)
+	if err != nil {
+		return false, 0, err
+	}
+	negative := strings.HasPrefix(digits, "-")
+	whole, fraction, _ := strings.Cut(strings.TrimLeft(digits, "+-"), ".")
+	if strings.Trim(fraction, "0") != "" {
+		return false, 0, fmt.Errorf("%s is not a whole number", digits)
+	}
+	if whole == "" {
+		whole = "0"
+	}
+	n, err := strconv.ParseUint(whole, 10, 64)
+	if err != nil {
+		return false, 0, fmt.Errorf("%s is too large", digits)
+	}
+	return negative && n > 0, n, nil
+}
+
+// thousandGroups splits the number into groups of three digits, the least significant group first.
+func thousandGroups(n uint64) []uint64 {
+	var groups []uint64
+	for ; n > 0; n /= 1000 {
+		groups = append(groups, n%1000)
+	}
+	return groups
+}
+
+var (
+	englishUnits = []string{"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine", "ten",
+		"eleven", "twelve", "thirteen", "fourteen", "fifteen", "sixteen", "seventeen", "eighteen", "nineteen"}
+	englishTens   = []string{"", "", "twenty", "thirty", "forty", "fifty", "sixty", "seventy", "eighty", "ninety"}
+	englishScales = []string{"", "thousand", "million", "billion", "trillion", "quadrillion", "quintillion"}
+)
+
+// englishCardinal writes the number in English words, e.g. one thousand two hundred thirty-four.
+func englishCardinal(n uint64) string {
+	if n == 0 {
+		return englishUnits[0]
+	}
+	var words []string
+	groups := thousandGroups(n)
+	for i := len(groups) - 1; i >= 0; i-- {
+		if groups[i] == 0 {
+			continue
+		}
+		words = append(words, englishHundreds(groups[i]))
+		if i > 0 {
+			words = append(words, englishScales[i])
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// englishHundreds writes a number from 1 to 999 in English words.
+func englishHundreds(n uint64) string {
+	var words []string
+	if n >= 100 {
+		words = append(words, englishUnits[n/100], "hundred")
+		n %= 100
+	}
+	switch {
+	case n == 0:
+	case n < 20:
+		words = append(words, englishUnits[n])
+	case n%10 == 0:
+		words = append(words, englishTens[n/10])
+	default:
+		words = append(words, englishTens[n/10]+"-"+englishUnits[n%10])
+	}
+	return strings.Join(words, " ")
+}
+
+var (
+	germanUnits = []string{"null", "eins", "zwei", "drei", "vier", "fünf", "sechs", "sieben", "acht", "neun", "zehn",
+		"elf", "zwölf", "dreizehn", "vierzehn", "fünfzehn", "sechzehn", "siebzehn", "achtzehn", "neunzehn"}
+	germanTens   = []string{"", "", "zwanzig", "dreißig", "vierzig", "fünfzig", "sechzig", "siebzig", "achtzig", "neunzig"}
+	germanScales = [][2]string{{}, {}, {"Million", "Millionen"}, {"Milliarde", "Milliarden"}, {"Billion", "Billionen"},
+		{"Billiarde", "Billiarden"}, {"Trillion", "Trillionen"}}
+)
+
+// germanCardinal writes the number in German words, e.g. eintausendzweihundertvierunddreißig. Numbers below one
+// million are written as one word, the millions and above are separate words: zwei Millionen einhunderttausend.
+func germanCardinal(n uint64) string {
+	if n == 0 {
+		return germanUnits[0]
+	}
+	var words []string
+	groups := thousandGroups(n)
+	for i := len(groups) - 1; i >= 2; i-- {
+		switch groups[i] {
+		case 0:
+		case 1:
+			// all scales are feminine: eine Million, eine Milliarde
+			words = append(words, "eine "+germanScales[i][0])
+		default:
+			words = append(words, germanHundreds(groups[i], false)+" "+germanScales[i][1])
+		}
+	}
+	below := ""
+	if len(groups) > 1 && groups[1] > 0 {
+		below = germanHundreds(groups[1], false) + "tausend"
+	}
+	if groups[0] > 0 {
+		below += germanHundreds(groups[0], true)
+	}
+	if below != "" {
+		words = append(words, below)
+	}
+	return strings.Join(words, " ")
+}
+
+// germanHundreds writes a number from 1 to 999 in German words. A final one is eins, in compounds such as
+// einhundert or eintausend it is ein.
+func germanHundreds(n uint64, final bool) string {
+	word := ""
+	if n >= 100 {
+		word = germanOne(n/100, false) + "hundert"
+		n %= 100
+	}
+	switch {
+	case n == 0:
+	case n < 20:
+		word += germanOne(n, final)
+	case n%10 == 0:
+		word += germanTens[n/10]
+	default:
+		word += germanOne(n%10, false) + "und" + germanTens[n/10]
+	}
+	return word
+}
+
+// germanOne writes a number below 20, one is ein unless it is final.
+func germanOne(n uint64, final bool) string {
+	if n == 1 && !final {
+		return "ein"
+	}
+	return germanUnits[n]
+}
+
+var (
+	frenchUnits = []string{"zéro", "un", "deux", "trois", "quatre", "cinq", "six", "sept", "huit", "neuf", "dix",
+		"onze", "douze", "treize", "quatorze", "quinze", "seize"}
+	frenchTens   = []string{"", "dix", "vingt", "trente", "quarante", "cinquante", "soixante"}
+	frenchScales = []string{"", "mille", "million", "milliard", "billion", "billiard", "trillion"}
+)
+
+// frenchCardinal writes the number in French words, e.g. mille deux cent trente-quatre.
+func frenchCardinal(n uint64) string {
+	if n == 0 {
+		return frenchUnits[0]
+	}
+	var words []string
+	groups := thousandGroups(n)
+	for i := len(groups) - 1; i >= 0; i-- {
+		group := groups[i]
+		switch {
+		case group == 0:
+		case i == 0:
+			words = append(words, frenchHundreds(group, true))
+		case i == 1 && group == 1:
+			words = append(words, "mille")
+		case i == 1:
+			// mille is invariable and makes cent and vingt invariable: deux cent mille
+			words = append(words, frenchHundreds(group, false), "mille")
+		case group == 1:
+			words = append(words, "un", frenchScales[i])
+		default:
+			// million etc. are nouns, so cent and vingt keep their plural: deux cents millions
+			words = append(words, frenchHundreds(group, true), frenchScales[i]+"s")
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// frenchHundreds writes a number from 1 to 999 in French words. The plurals of cents and quatre-vingts are only
+// used if nothing follows them.
+func frenchHundreds(n uint64, final bool) string {
+	var words []string
+	if hundreds, rest := n/100, n%100; hundreds > 0 {
+		word := "cent"
+		if hundreds > 1 {
+			word = frenchUnits[hundreds] + " cent"
+			if rest == 0 && final {
+				word += "s"
+			}
+		}
+		words = append(words, word)
+		n = rest
+	}
+	if n > 0 {
+		words = append(words, frenchTensAndUnits(n, final))
+	}
+	return strings.Join(words, " ")
+}
+
+// frenchTensAndUnits writes a number from 1 to 99 in French words.
+func frenchTensAndUnits(n uint64, final bool) string {
+	tens, units := n/10, n%10
+	switch {
+	case n <= 16:
+		return frenchUnits[n]
+	case n < 20:
+		return "dix-" + frenchUnits[units]
+	case tens == 7:
+		if units == 1 {
+			return "soixante et onze"
+		}
+		return "soixante-" + frenchTensAndUnits(10+units, final)
+	case tens == 8:
+		if units == 0 {
+			if final {
+				return "quatre-vingts"
+			}
+			return "quatre-vingt"
+		}
+		return "quatre-vingt-" + frenchUnits[units]
+	case tens == 9:
+		return "quatre-vingt-" + frenchTensAndUnits(10+units, final)
+	case units == 0:
+		return frenchTens[tens]
+	case units == 1:
+		return frenchTens[tens] + " et un"
+	}
+	return frenchTens[tens] + "-" + frenchUnits[units]
+}
+
+// englishOrdinal writes the ordinal in digits with the English suffix, e.g. 1st, 2nd, 3rd, 11th or 21st.
+func englishOrdinal(n uint64) string {
+	suffix := "th"
+	if n%100 < 11 || n%100 > 13 {
+		switch n % 10 {
+		case 1:
+			suffix = "st"
+		case 2:
+			suffix = "nd"
+		case 3:
+			suffix = "rd"
+		}
+	}
+	return strconv.FormatUint(n, 10) + suffix
+}
+
+// germanOrdinal writes the ordinal in digits followed by a period, e.g. 21.
+func germanOrdinal(n uint64) string {
+	return strconv.FormatUint(n, 10) + "."
+}
+
+// frenchOrdinal writes the ordinal in digits with the French suffix, 1er for the first and e for all others.
+func frenchOrdinal(n uint64) string {
+	if n == 1 {
+		return "1er"
+	}
+	return strconv.FormatUint(n, 10) + "e"
+}
@@ -0,0 +1,119 @@
+package docx
+
+import "testing"
+
+func TestNumberWords(t *testing.T) {
+	tests := map[string]map[uint64]string{
+		"en": {
+			0:       "zero",
+			15:      "fifteen",
+			42:      "forty-two",
+			100:     "one hundred",
+			1234:    "one thousand two hundred thirty-four",
+			2000010: "two million ten",
+		},
+		"de": {
+			1:       "eins",
+			21:      "einundzwanzig",
+			101:     "einhunderteins",
+			1001:    "eintausendeins",
+			31000:   "einunddreißigtausend",
+			1234:    "eintausendzweihundertvierunddreißig",
+			1000000: "eine Million",
+			2100000: "zwei Millionen einhunderttausend",
+		},
+		"fr": {
+			21:        "vingt et un",
+			71:        "soixante et onze",
+			77:        "soixante-dix-sept",
+			80:        "quatre-vingts",
+			81:        "quatre-vingt-un",
+			91:        "quatre-vingt-onze",
+			200:       "deux cents",
+			201:       "deux cent un",
+			1000:      "mille",
+			80000:     "quatre-vingt mille",
+			200000000: "deux cents millions",
+			1234:      "mille deux cent trente-quatre",
+		},
+	}
+	for code, numbers := range tests {
+		language, err := numberLanguageOf(code)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for n, expected := range numbers {
+			if words := language.cardinal(n); words != expected {
+				t.Errorf("%s: expected %q for %d, got %q", code, expected, n, words)
+			}
+		}
+	}
+	if _, err := numberLanguageOf("xx"); err == nil {
+		t.Errorf("expected an error for an unsupported language")
+	}
+}
+
+func TestNumberHelpers(t *testing.T) {
+	tr := NewTemplateReplacer(nil)
+	for _, test := range []struct {
+		helper   func(interface{}, ...string) (string, error)
+		value    interface{}
+		language []string
+		expected string
+	}{
+		{tr.numToWordsHelper, 30, nil, "thirty"},
+		{tr.numToWordsHelper, "-12", nil, "minus twelve"},
+		{tr.numToWordsHelper, 2.0, []string{"fr-CH"}, "deux"},
+		{tr.ordinalHelper, 1, nil, "1st"},
+		{tr.ordinalHelper, 12, nil, "12th"},
+		{tr.ordinalHelper, 22, nil, "22nd"},
+		{tr.ordinalHelper, 113, nil, "113th"},
+		{tr.ordinalHelper, 3, []string{"de"}, "3."},
+		{tr.ordinalHelper, 1, []string{"fr"}, "1er"},
+		{tr.ordinalHelper, 2, []string{"fr"}, "2e"},
+	} {
+		result, err := test.helper(test.value, test.language...)
+		if err != nil {
+			t.Errorf("%v: %v", test.value, err)
+		} else if result != test.expected {
+			t.Errorf("expected %q for %v, got %q", test.expected, test.value, result)
+		}
+	}
+	for _, value := range []interface{}{2.5, "ten", -1} {
+		if _, err := tr.ordinalHelper(value); err == nil {
+			t.Errorf("expected an error for the ordinal of %v", value)
+		}
+	}
+
+	for n, expected := range map[int]string{1: "I", 4: "IV", 9: "IX", 14: "XIV", 40: "XL", 90: "XC", 400: "CD", 1994: "MCMXCIV", 2024: "MMXXIV", 3999: "MMMCMXCIX"} {
+		if numeral, err := romanHelper(n); err != nil || numeral != expected {
+			t.Errorf("expected %s for %d, got %s, %v", expected, n, numeral, err)
+		}
+	}
+	for _, value := range []interface{}{0, 4000, -5} {
+		if _, err := romanHelper(value); err == nil {
+			t.Errorf("expected an error for the Roman numeral of %v", value)
+		}
+	}
+}
+
+func TestDocument_NumberHelpers(t *testing.T) {
+	doc, err := NewDocument().
+		Paragraph("The notice period is {{numToWords .days}} ({{.days}}) days.").
+		Paragraph("Article {{roman .article}}, {{ordinal .paragraph}} paragraph").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+	if err := doc.ExecuteTemplate(map[string]interface{}{"days": 30, "article": 14, "paragraph": 2}); err != nil {
+		t.Fatal(err)
+	}
+	texts, err := paragraphTexts(doc.GetFile(DocumentXml))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(texts) != 2 || texts[0] != "The notice period is thirty (30) days." || texts[1] != "Article XIV, 2nd paragraph" {
+		t.Errorf("unexpected paragraphs %q", texts)
+	}
+}
@@ -0,0 +1,188 @@
+// Package odt fills OpenDocument text templates (.odt and .ott files) with the placeholder syntax of the docx
+// package, for customers who mandate ODF output.
+//
+// The placeholders of the body (content.xml) and of the headers, footers and styles (styles.xml) are evaluated
+// like those of a docx document: with text/template by default or with an alternative templating.Engine.
+// Placeholders may be split across spans with different formatting, the result takes the formatting of the span
+// the placeholder starts in. Control structures like {{if}} and {{range}} are supported within a paragraph;
+// blocks spanning several paragraphs or table rows are not.
+package odt
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/izetmolla/docx"
+	"github.com/izetmolla/docx/internal/zipxml"
+	"github.com/izetmolla/docx/templating"
+)
+
+const (
+	// MimeTypeFile is the first file of the package, it contains the media type of the document.
+	MimeTypeFile = "mimetype"
+	// ContentXml contains the body of the document.
+	ContentXml = "content.xml"
+	// StylesXml contains the styles and the master pages with the headers and footers of the document.
+	StylesXml = "styles.xml"
+
+	// TextMimeType is the media type of OpenDocument texts (.odt).
+	TextMimeType = "application/vnd.oasis.opendocument.text"
+	// TextTemplateMimeType is the media type of OpenDocument text templates (.ott).
+	TextTemplateMimeType = "application/vnd.oasis.opendocument.text-template"
+)
+
+var _ docx.TemplateDocument = (*Document)(nil)
+
+// syntax describes the paragraphs and headings of OpenDocument texts, whose character data is their text.
+var syntax = zipxml.Syntax{
+	Paragraphs: []string{"text:p", "text:h"},
+	Inline: map[string]bool{
+		"text:span":               true,
+		"text:a":                  true,
+		"text:bookmark":           true,
+		"text:bookmark-start":     true,
+		"text:bookmark-end":       true,
+		"text:reference-mark":     true,
+		"text:reference-mark-end": true,
+		"text:soft-page-break":    true,
+		"text:change-start":       true,
+		"text:change-end":         true,
+		"office:annotation-end":   true,
+	},
+	Escape: escapeValue,
+}
+
+// textEscaper escapes character data.
+var textEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// Document is an OpenDocument text which is filled like a docx document.
+type Document struct {
+	path     string
+	pkg      *zipxml.Package
+	template zipxml.Template
+}
+
+// Open opens the OpenDocument text or text template at the path.
+func Open(path string) (*Document, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := OpenBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	doc.path = path
+	return doc, nil
+}
+
+// OpenBytes opens the OpenDocument text or text template in memory.
+func OpenBytes(data []byte) (*Document, error) {
+	pkg, err := zipxml.ReadPackage(data)
+	if err != nil {
+		return nil, err
+	}
+	switch mimeType := strings.TrimSpace(string(pkg.GetFile(MimeTypeFile))); mimeType {
+	case TextMimeType, TextTemplateMimeType:
+	default:
+		return nil, fmt.Errorf("not an OpenDocument text: unsupported media type %q", mimeType)
+	}
+	if pkg.GetFile(ContentXml) == nil {
+		return nil, fmt.Errorf("not an OpenDocument text: %s is missing", ContentXml)
+	}
+	return &Document{pkg: pkg}, nil
+}
+
+// GetFile returns the content of the file of the package, or nil if it does not exist.
+func (d *Document) GetFile(name string) []byte {
+	return d.pkg.GetFile(name)
+}
+
+// SetFile replaces the content of an existing file or adds a new file to the package.
+func (d *Document) SetFile(name string, data []byte) {
+	d.pkg.SetFile(name, data)
+}
+
+// SetTemplateEngine sets an alternative engine which evaluates the placeholders, e.g. a moustache-style engine.
+// Pass nil to use text/template again.
+func (d *Document) SetTemplateEngine(engine templating.Engine) {
+	d.template.Engine = engine
+}
+
+// AddTemplateFuncs adds custom functions to the text/template placeholders.
+func (d *Document) AddTemplateFuncs(funcMap template.FuncMap) {
+	d.template.AddFuncs(funcMap)
+}
+
+// SetMissingKeyPolicy sets the handling of placeholders which reference missing values. By default they are
+// left unchanged.
+func (d *Document) SetMissingKeyPolicy(policy docx.MissingKeyPolicy) {
+	d.template.MissingKey = policy
+}
+
+// ExecuteTemplate evaluates the placeholders of the body, headers and footers with the data.
+func (d *Document) ExecuteTemplate(data docx.TemplateData) error {
+	return d.template.Execute(d.pkg, []string{ContentXml, StylesXml}, syntax, data)
+}
+
+// ReplaceAll replaces the string-based placeholders delimited with { and } with the values of the map.
+// Placeholders which are not in the map are left unchanged.
+func (d *Document) ReplaceAll(replaceMap docx.PlaceholderMap) error {
+	return d.template.ReplaceAll(d.pkg, []string{ContentXml, StylesXml}, syntax, replaceMap)
+}
+
+// escapeValue escapes a value as character data of a paragraph. Line breaks, tabs and consecutive spaces, which
+// OpenDocument collapses in character data, are written as their elements.
+func escapeValue(value string) string {
+	var result strings.Builder
+	spaces := 0
+	flushSpaces := func() {
+		if spaces > 1 {
+			fmt.Fprintf(&result, `<text:s text:c="%d"/>`, spaces-1)
+		}
+		spaces = 0
+	}
+	for _, r := range value {
+		if r == ' ' {
+			if spaces == 0 {
+				result.WriteByte(' ')
+			}
+			spaces++
+			continue
+		}
+		flushSpaces()
+		switch r {
+		case '\n':
+			result.WriteString("<text:line-break/>")
+		case '\t':
+			result.WriteString("<text:tab/>")
+		case '\r':
+		default:
+			result.WriteString(textEscaper.Replace(string(r)))
+		}
+	}
+	flushSpaces()
+	return result.String()
+}
+
+// Write writes the document to the writer. The mimetype file is written first and uncompressed, as required by
+// the OpenDocument format.
+func (d *Document) Write(writer io.Writer) error {
+	return d.pkg.Write(writer, MimeTypeFile)
+}
+
+// WriteToFile writes the document to a new file. The directories of the path are created if necessary.
+func (d *Document) WriteToFile(file string) error {
+	if file == d.path {
+		return fmt.Errorf("WriteToFile cannot write into the original template")
+	}
+	return d.pkg.WriteToFile(file, MimeTypeFile)
+}
+
+// Close releases the content of the document.
+func (d *Document) Close() {
+	d.pkg = nil
+}
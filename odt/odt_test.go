@@ -0,0 +1,133 @@
+package odt
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/izetmolla/docx"
+	"github.com/izetmolla/docx/templating"
+)
+
+// testTemplate returns an OpenDocument text with the body and the master page content of the styles.
+func testTemplate(t *testing.T, mimeType, body, masterPage string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+	files := []struct{ name, content string }{
+		{MimeTypeFile, mimeType},
+		{"META-INF/manifest.xml", `<?xml version="1.0" encoding="UTF-8"?><manifest:manifest/>`},
+		{ContentXml, `<?xml version="1.0" encoding="UTF-8"?><office:document-content><office:body><office:text>` +
+			body + `</office:text></office:body></office:document-content>`},
+		{StylesXml, `<?xml version="1.0" encoding="UTF-8"?><office:document-styles><office:master-styles>` +
+			`<style:master-page style:name="Standard">` + masterPage + `</style:master-page></office:master-styles></office:document-styles>`},
+	}
+	for _, f := range files {
+		entry, err := writer.Create(f.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := entry.Write([]byte(f.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDocument_ExecuteTemplate(t *testing.T) {
+	body := `<text:h text:outline-level="1">Offer {{.Number}}</text:h>` +
+		`<text:p text:style-name="P1">Dear <text:span text:style-name="T1">{{.Na</text:span>me}},</text:p>` +
+		`<text:p>{{if .VIP}}Premium{{else}}Standard{{end}} customer</text:p>` +
+		`<text:p>{{.Missing}}<text:tab/>{{.Address}}</text:p>`
+	doc, err := OpenBytes(testTemplate(t, TextMimeType, body, `<style:header><text:p>{{.Name}}</text:p></style:header>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+
+	data := map[string]interface{}{"Number": 42, "Name": "Anna & Co", "VIP": true, "Address": "Main St 1\nBerlin"}
+	if err := doc.ExecuteTemplate(data); err != nil {
+		t.Fatal(err)
+	}
+	content := string(doc.GetFile(ContentXml))
+	for _, expected := range []string{`<text:h text:outline-level="1">Offer 42</text:h>`,
+		`<text:p text:style-name="P1">Dear <text:span text:style-name="T1">Anna &amp; Co</text:span>,</text:p>`,
+		`<text:p>Premium customer</text:p>`,
+		`<text:p>{{.Missing}}<text:tab/>Main St 1<text:line-break/>Berlin</text:p>`} {
+		if !strings.Contains(content, expected) {
+			t.Errorf("expected %s in %s", expected, content)
+		}
+	}
+	if styles := string(doc.GetFile(StylesXml)); !strings.Contains(styles, `<style:header><text:p>Anna &amp; Co</text:p></style:header>`) {
+		t.Errorf("the header was not filled: %s", styles)
+	}
+
+	doc.SetMissingKeyPolicy(docx.MissingKeyError)
+	if err := doc.ExecuteTemplate(data); !errors.Is(err, templating.ErrMissingValue) {
+		t.Errorf("expected a missing value error, got %v", err)
+	}
+}
+
+func TestDocument_TemplateEngine(t *testing.T) {
+	doc, err := OpenBytes(testTemplate(t, TextTemplateMimeType, `<text:p>Hello <text:span>{{customer.</text:span>name}}  {code}</text:p>`, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+
+	var template docx.TemplateDocument = doc
+	template.SetTemplateEngine(templating.Mustache{})
+	if err := template.ExecuteTemplate(map[string]interface{}{"customer": map[string]string{"name": "A  B"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := template.ReplaceAll(docx.PlaceholderMap{"code": "<42>"}); err != nil {
+		t.Fatal(err)
+	}
+	if content := string(doc.GetFile(ContentXml)); !strings.Contains(content, `<text:p>Hello <text:span>A <text:s text:c="1"/>B</text:span>  &lt;42&gt;</text:p>`) {
+		t.Errorf("unexpected content %s", content)
+	}
+}
+
+func TestDocument_Write(t *testing.T) {
+	doc, err := OpenBytes(testTemplate(t, TextMimeType, `<text:p>{{.}}</text:p>`, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+	if err := doc.ExecuteTemplate("done"); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := doc.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	reader, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first := reader.File[0]; first.Name != MimeTypeFile || first.Method != zip.Store {
+		t.Errorf("the mimetype must be the first file and uncompressed, got %s with method %d", first.Name, first.Method)
+	}
+	written, err := OpenBytes(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content := string(written.GetFile(ContentXml)); !strings.Contains(content, "<text:p>done</text:p>") {
+		t.Errorf("unexpected content %s", content)
+	}
+}
+
+func TestOpenBytes_Invalid(t *testing.T) {
+	if _, err := OpenBytes(testTemplate(t, "application/vnd.oasis.opendocument.spreadsheet", "", "")); err == nil {
+		t.Errorf("expected an error for a spreadsheet")
+	}
+	if _, err := OpenBytes([]byte("no zip")); err == nil {
+		t.Errorf("expected an error for invalid data")
+	}
+}
@@ -0,0 +1,399 @@
+package docx
+
+import (
+	"encoding/xml"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// opc.go implements the Open Packaging Conventions layer of the library: the models of [Content_Types].xml
+// and the relationship parts (*.rels) as well as adding and removing parts while keeping both consistent.
+// The models are parsed from the packageFiles on every access and stored back immediately after
+// every modification, so the packageFiles always stay the single source of truth.
+
+const (
+	// contentTypesNamespace is the namespace of [Content_Types].xml.
+	contentTypesNamespace = "http://schemas.openxmlformats.org/package/2006/content-types"
+	// relationshipsNamespace is the namespace of all relationship parts.
+	relationshipsNamespace = "http://schemas.openxmlformats.org/package/2006/relationships"
+	// TargetModeExternal marks relationships whose target is not a part of the package (e.g. hyperlinks).
+	TargetModeExternal = "External"
+)
+
+// opcContentTypes is the model of [Content_Types].xml.
+type opcContentTypes struct {
+	XMLName   xml.Name      `xml:"http://schemas.openxmlformats.org/package/2006/content-types Types"`
+	Defaults  []opcDefault  `xml:"Default"`
+	Overrides []opcOverride `xml:"Override"`
+}
+
+// opcDefault maps a file extension to a content type.
+type opcDefault struct {
+	Extension   string `xml:"Extension,attr"`
+	ContentType string `xml:"ContentType,attr"`
+}
+
+// opcOverride sets the content type of a single part, overriding the defaults.
+type opcOverride struct {
+	PartName    string `xml:"PartName,attr"`
+	ContentType string `xml:"ContentType,attr"`
+}
+
+// parseContentTypes parses the given [Content_Types].xml.
+func parseContentTypes(contentTypesXml []byte) (*opcContentTypes, error) {
+	var types opcContentTypes
+	if err := xml.Unmarshal(contentTypesXml, &types); err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %s", ContentTypesXml, err)
+	}
+	return &types, nil
+}
+
+// lookup returns the content type of the given part name (e.g. '/word/document.xml').
+// Overrides take precedence over the defaults which are matched by the file extension.
+func (ct *opcContentTypes) lookup(partName string) string {
+	for _, override := range ct.Overrides {
+		if strings.EqualFold(override.PartName, partName) {
+			return override.ContentType
+		}
+	}
+	if def := ct.defaultFor(strings.TrimPrefix(path.Ext(partName), ".")); def != nil {
+		return def.ContentType
+	}
+	return ""
+}
+
+// defaultFor returns the Default entry of the given extension or nil.
+func (ct *opcContentTypes) defaultFor(extension string) *opcDefault {
+	for i := range ct.Defaults {
+		if strings.EqualFold(ct.Defaults[i].Extension, extension) {
+			return &ct.Defaults[i]
+		}
+	}
+	return nil
+}
+
+// setDefault registers the content type for the extension unless the extension is already known.
+func (ct *opcContentTypes) setDefault(extension, contentType string) {
+	if ct.defaultFor(extension) == nil {
+		ct.Defaults = append(ct.Defaults, opcDefault{Extension: extension, ContentType: contentType})
+	}
+}
+
+// setOverride sets the content type of the given part.
+func (ct *opcContentTypes) setOverride(partName, contentType string) {
+	for i := range ct.Overrides {
+		if strings.EqualFold(ct.Overrides[i].PartName, partName) {
+			ct.Overrides[i].ContentType = contentType
+			return
+		}
+	}
+	ct.Overrides = append(ct.Overrides, opcOverride{PartName: partName, ContentType: contentType})
+}
+
+// removeOverride removes the Override entry of the given part, if any.
+func (ct *opcContentTypes) removeOverride(partName string) {
+	overrides := ct.Overrides[:0]
+	for _, override := range ct.Overrides {
+		if !strings.EqualFold(override.PartName, partName) {
+			overrides = append(overrides, override)
+		}
+	}
+	ct.Overrides = overrides
+}
+
+// removeContentType removes all Default and Override entries with the given content type.
+func (ct *opcContentTypes) removeContentType(contentType string) {
+	defaults := ct.Defaults[:0]
+	for _, def := range ct.Defaults {
+		if def.ContentType != contentType {
+			defaults = append(defaults, def)
+		}
+	}
+	ct.Defaults = defaults
+
+	overrides := ct.Overrides[:0]
+	for _, override := range ct.Overrides {
+		if override.ContentType != contentType {
+			overrides = append(overrides, override)
+		}
+	}
+	ct.Overrides = overrides
+}
+
+// marshal serializes the model, including the XML declaration.
+func (ct *opcContentTypes) marshal() ([]byte, error) {
+	data, err := xml.Marshal(ct)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal %s: %s", ContentTypesXml, err)
+	}
+	return append([]byte(xmlDeclaration), data...), nil
+}
+
+// opcRelationships is the model of a relationship part (*.rels).
+type opcRelationships struct {
+	XMLName       xml.Name          `xml:"http://schemas.openxmlformats.org/package/2006/relationships Relationships"`
+	Relationships []opcRelationship `xml:"Relationship"`
+}
+
+// opcRelationship is a single relationship from the source part to a target.
+type opcRelationship struct {
+	ID         string `xml:"Id,attr"`
+	Type       string `xml:"Type,attr"`
+	Target     string `xml:"Target,attr"`
+	TargetMode string `xml:"TargetMode,attr,omitempty"`
+}
+
+// parseRelationships parses the given relationship part.
+func parseRelationships(relsXml []byte) (*opcRelationships, error) {
+	var rels opcRelationships
+	if err := xml.Unmarshal(relsXml, &rels); err != nil {
+		return nil, fmt.Errorf("unable to parse relationships: %s", err)
+	}
+	return &rels, nil
+}
+
+// add adds a new relationship and returns its newly generated id.
+func (r *opcRelationships) add(relType, target, targetMode string) string {
+	maxId := 0
+	for _, rel := range r.Relationships {
+		if id, err := strconv.Atoi(strings.TrimPrefix(rel.ID, "rId")); err == nil && id > maxId {
+			maxId = id
+		}
+	}
+	id := "rId" + strconv.Itoa(maxId+1)
+	r.Relationships = append(r.Relationships, opcRelationship{ID: id, Type: relType, Target: target, TargetMode: targetMode})
+	return id
+}
+
+// removeIf removes all relationships for which the condition is true and returns how many were removed.
+func (r *opcRelationships) removeIf(condition func(rel opcRelationship) bool) int {
+	kept := r.Relationships[:0]
+	for _, rel := range r.Relationships {
+		if !condition(rel) {
+			kept = append(kept, rel)
+		}
+	}
+	removed := len(r.Relationships) - len(kept)
+	r.Relationships = kept
+	return removed
+}
+
+// marshal serializes the model, including the XML declaration.
+func (r *opcRelationships) marshal() ([]byte, error) {
+	data, err := xml.Marshal(r)
+	if err != nil {
+		return nil, fmt.Errorf("unable to marshal relationships: %s", err)
+	}
+	return append([]byte(xmlDeclaration), data...), nil
+}
+
+// relsPartName returns the name of the relationship part belonging to the source part.
+// The package relationships (_rels/.rels) belong to the empty source part.
+func relsPartName(sourcePart string) string {
+	dir, file := path.Split(sourcePart)
+	return dir + "_rels/" + file + ".rels"
+}
+
+// resolveTarget resolves the relationship target relative to the source part and returns the part name
+// inside the archive, e.g. '../customXml/item1.xml' of word/document.xml resolves to customXml/item1.xml.
+func resolveTarget(sourcePart, target string) string {
+	if strings.HasPrefix(target, "/") {
+		return strings.TrimPrefix(path.Clean(target), "/")
+	}
+	return strings.TrimPrefix(path.Clean("/"+path.Join(path.Dir(sourcePart), target)), "/")
+}
+
+// relativeTarget returns the target of a relationship from the source part to the target part.
+func relativeTarget(sourcePart, targetPart string) string {
+	sourceDir := strings.Split(path.Dir(sourcePart), "/")
+	if path.Dir(sourcePart) == "." {
+		sourceDir = nil
+	}
+	target := strings.Split(targetPart, "/")
+
+	common := 0
+	for common < len(sourceDir) && common < len(target)-1 && sourceDir[common] == target[common] {
+		common++
+	}
+	parts := make([]string, 0, len(sourceDir)-common+len(target)-common)
+	for i := common; i < len(sourceDir); i++ {
+		parts = append(parts, "..")
+	}
+	parts = append(parts, target[common:]...)
+	return strings.Join(parts, "/")
+}
+
+// packageContentTypes returns the model of [Content_Types].xml.
+func (d *Document) packageContentTypes() (*opcContentTypes, error) {
+	data, exists := d.loadPackageFile(ContentTypesXml)
+	if !exists {
+		return nil, fmt.Errorf("invalid docx archive, %s is missing", ContentTypesXml)
+	}
+	return parseContentTypes(data)
+}
+
+// storeContentTypes serializes the model back into [Content_Types].xml.
+func (d *Document) storeContentTypes(types *opcContentTypes) error {
+	data, err := types.marshal()
+	if err != nil {
+		return err
+	}
+	d.setPackageFile(ContentTypesXml, data)
+	return nil
+}
+
+// packageRelationships returns the model of the relationships of the given source part.
+// If the source part does not have any relationships yet, an empty model is returned.
+func (d *Document) packageRelationships(sourcePart string) (*opcRelationships, error) {
+	data, exists := d.loadPackageFile(relsPartName(sourcePart))
+	if !exists {
+		return &opcRelationships{}, nil
+	}
+	return parseRelationships(data)
+}
+
+// storeRelationships serializes the model back into the relationship part of the source part.
+// The relationship part is created if it did not exist before.
+func (d *Document) storeRelationships(sourcePart string, rels *opcRelationships) error {
+	relsPart := relsPartName(sourcePart)
+	if _, exists := d.loadPackageFile(relsPart); !exists {
+		if err := d.ensureContentType(relsPart, "application/vnd.openxmlformats-package.relationships+xml"); err != nil {
+			return err
+		}
+	}
+
+	data, err := rels.marshal()
+	if err != nil {
+		return err
+	}
+	d.setPackageFile(relsPart, data)
+	return nil
+}
+
+// relationshipTargets returns the resolved part names of all internal relationships of the given type.
+func (d *Document) relationshipTargets(sourcePart, relType string) []string {
+	rels, err := d.packageRelationships(sourcePart)
+	if err != nil {
+		return nil
+	}
+	var targets []string
+	for _, rel := range rels.Relationships {
+		if rel.Type == relType && rel.TargetMode != TargetModeExternal {
+			targets = append(targets, resolveTarget(sourcePart, rel.Target))
+		}
+	}
+	return targets
+}
+
+// addRelationship adds a relationship of the given type from the source part to the target part
+// and returns the id of the new relationship.
+func (d *Document) addRelationship(sourcePart, relType, targetPart string) (string, error) {
+	rels, err := d.packageRelationships(sourcePart)
+	if err != nil {
+		return "", err
+	}
+	id := rels.add(relType, relativeTarget(sourcePart, targetPart), "")
+	return id, d.storeRelationships(sourcePart, rels)
+}
+
+// removeRelationships removes all relationships of the given type from the source part.
+func (d *Document) removeRelationships(sourcePart, relType string) error {
+	rels, err := d.packageRelationships(sourcePart)
+	if err != nil {
+		return err
+	}
+	if rels.removeIf(func(rel opcRelationship) bool { return rel.Type == relType }) == 0 {
+		return nil
+	}
+	return d.storeRelationships(sourcePart, rels)
+}
+
+// ensureContentType makes sure that the part has the given content type. If possible, a Default for the
+// extension is registered, otherwise an Override for the part is added.
+func (d *Document) ensureContentType(partName, contentType string) error {
+	types, err := d.packageContentTypes()
+	if err != nil {
+		return err
+	}
+	if types.lookup("/"+partName) == contentType {
+		return nil
+	}
+	extension := strings.TrimPrefix(path.Ext(partName), ".")
+	if types.defaultFor(extension) == nil && !isXmlContentType(contentType) {
+		types.setDefault(extension, contentType)
+	} else {
+		types.setOverride("/"+partName, contentType)
+	}
+	return d.storeContentTypes(types)
+}
+
+// addPart adds a new part (or replaces an existing one) and registers its content type.
+// Relationships to the new part are up to the caller, see addRelationship.
+func (d *Document) addPart(partName string, data []byte, contentType string) error {
+	if err := d.ensureContentType(partName, contentType); err != nil {
+		return err
+	}
+	d.setPackageFile(partName, data)
+	return nil
+}
+
+// removePart removes the part from the package. Its content type Override, its own relationship part and all
+// relationships of other parts which target it are removed as well, so the package stays consistent.
+func (d *Document) removePart(partName string) error {
+	types, err := d.packageContentTypes()
+	if err != nil {
+		return err
+	}
+	types.removeOverride("/" + partName)
+	if err := d.storeContentTypes(types); err != nil {
+		return err
+	}
+
+	for _, relsPart := range d.relsParts() {
+		if relsPart == relsPartName(partName) {
+			continue
+		}
+		sourcePart := relsSourcePart(relsPart)
+		rels, err := d.packageRelationships(sourcePart)
+		if err != nil {
+			return err
+		}
+		removed := rels.removeIf(func(rel opcRelationship) bool {
+			return rel.TargetMode != TargetModeExternal && resolveTarget(sourcePart, rel.Target) == partName
+		})
+		if removed > 0 {
+			if err := d.storeRelationships(sourcePart, rels); err != nil {
+				return err
+			}
+		}
+	}
+
+	d.removePackageFile(relsPartName(partName))
+	d.removePackageFile(partName)
+	d.forgetFile(partName)
+	return nil
+}
+
+// relsParts returns the names of all relationship parts of the package.
+func (d *Document) relsParts() []string {
+	var names []string
+	for _, file := range d.zipFile.File {
+		if strings.HasSuffix(file.Name, ".rels") && !d.removedFiles[file.Name] {
+			names = append(names, file.Name)
+		}
+	}
+	for _, name := range d.addedPackageFiles() {
+		if strings.HasSuffix(name, ".rels") {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// relsSourcePart returns the name of the source part of the relationship part, see relsPartName.
+func relsSourcePart(relsPart string) string {
+	dir, file := path.Split(relsPart)
+	return strings.TrimSuffix(dir, "_rels/") + strings.TrimSuffix(file, ".rels")
+}
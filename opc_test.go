@@ -0,0 +1,65 @@
+package docx
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRelationshipTargets(t *testing.T) {
+	tests := []struct {
+		source string
+		target string
+		part   string
+	}{
+		{"", "word/document.xml", "word/document.xml"},
+		{"word/document.xml", "media/image1.jpg", "word/media/image1.jpg"},
+		{"word/document.xml", "../customXml/item1.xml", "customXml/item1.xml"},
+		{"customXml/item1.xml", "itemProps1.xml", "customXml/itemProps1.xml"},
+	}
+	for _, test := range tests {
+		if part := resolveTarget(test.source, test.target); part != test.part {
+			t.Errorf("resolveTarget(%s, %s) = %s, expected %s", test.source, test.target, part, test.part)
+		}
+		if target := relativeTarget(test.source, test.part); target != test.target {
+			t.Errorf("relativeTarget(%s, %s) = %s, expected %s", test.source, test.part, target, test.target)
+		}
+	}
+
+	if name := relsPartName(""); name != PackageRelsXml {
+		t.Errorf("expected %s, got %s", PackageRelsXml, name)
+	}
+	if name := relsPartName(DocumentXml); name != DocumentRelsXml {
+		t.Errorf("expected %s, got %s", DocumentRelsXml, name)
+	}
+	if source := relsSourcePart(DocumentRelsXml); source != DocumentXml {
+		t.Errorf("expected %s, got %s", DocumentXml, source)
+	}
+}
+
+func TestDocument_RemovePart(t *testing.T) {
+	doc, err := Open("./test/template.docx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.removePart("word/footer1.xml"); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := doc.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if archiveContains(t, buf.Bytes(), "word/footer1.xml") {
+		t.Error("removed part was written")
+	}
+	out, err := OpenBytes(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(out.packageFiles[ContentTypesXml], []byte("footer1.xml")) {
+		t.Error("content type of the removed part was not removed")
+	}
+	if bytes.Contains(out.packageFiles[DocumentRelsXml], []byte("footer1.xml")) {
+		t.Error("relationship to the removed part was not removed")
+	}
+}
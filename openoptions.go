@@ -0,0 +1,178 @@
+package docx
+
+import (
+	"archive/zip"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Parts selects the XML parts of a document which are processed, see OpenOptions.
+type Parts int
+
+const (
+	// PartBody is the main document part, word/document.xml. It is always required.
+	PartBody Parts = 1 << iota
+	// PartHeaders are the headers of all sections.
+	PartHeaders
+	// PartFooters are the footers of all sections.
+	PartFooters
+	// PartFootnotes are the footnotes, including their separators, word/footnotes.xml.
+	PartFootnotes
+	// PartEndnotes are the endnotes, including their separators, word/endnotes.xml.
+	PartEndnotes
+	// PartComments are the comments, word/comments.xml.
+	PartComments
+
+	// AllParts selects all stories: the body, the headers, the footers, the footnotes, the endnotes and the
+	// comments. Text boxes are processed as part of the story they are anchored in.
+	AllParts = PartBody | PartHeaders | PartFooters | PartFootnotes | PartEndnotes | PartComments
+)
+
+// OpenOptions controls how a document is opened, see OpenWithOptions. The zero value opens the document just
+// like Open.
+type OpenOptions struct {
+	// Parts selects the parts which are processed by templates and placeholder replacement, 0 selects AllParts.
+	// Parts which are left out are written unchanged. The body must always be selected.
+	Parts Parts
+	// AcceptRevisions accepts all tracked changes of the processed parts when the document is opened: inserted
+	// and moved content is kept, deleted content and the recorded formatting changes are removed.
+	AcceptRevisions bool
+	// MaxSize is the maximum total size in bytes of the uncompressed files of the archive, larger documents are
+	// rejected before they are read. 0 means no limit.
+	MaxSize int64
+	// Delimiters are the left and right delimiters of the actions in the document, e.g. {"[[", "]]"} for
+	// templates where the braces are used as text. The actions are translated to the regular delimiters when the
+	// document is opened, so each action must be within a single run, and {{ in the text is kept as it is. The
+	// zero value uses {{ and }}.
+	Delimiters [2]string
+	// Lazy reads the media files of the archive when they are accessed for the first time instead of when the
+	// document is opened. Media files which are never accessed are copied from the archive when it is written,
+	// which saves memory for documents with many images. The file opened by OpenWithOptions must not be
+	// modified as long as the document is used.
+	Lazy bool
+	// FailFast rejects documents with files which cannot be read, e.g. because their data is corrupt. By default
+	// such files are reported by Document.Warnings and the document is processed without them.
+	FailFast bool
+
+	// runs are the runs of the parts taken from a template manifest instead of parsing the parts, see LoadManifest
+	runs map[string]DocumentRuns
+}
+
+// parts returns the selected parts, the zero value selects all parts.
+func (o OpenOptions) parts() Parts {
+	if o.Parts == 0 {
+		return AllParts
+	}
+	return o.Parts
+}
+
+// validate checks the options and the size of the archive.
+func (o OpenOptions) validate(zipFile *zip.Reader) error {
+	if o.parts()&PartBody == 0 {
+		return fmt.Errorf("invalid parts %d, the body must be selected", o.Parts)
+	}
+	if (o.Delimiters[0] == "") != (o.Delimiters[1] == "") {
+		return fmt.Errorf("invalid delimiters %q, both delimiters must be set", o.Delimiters)
+	}
+	if o.MaxSize > 0 {
+		var size uint64
+		for _, file := range zipFile.File {
+			size += file.UncompressedSize64
+		}
+		if size > uint64(o.MaxSize) {
+			return fmt.Errorf("the document exceeds the maximum size of %d bytes", o.MaxSize)
+		}
+	}
+	return nil
+}
+
+// hasFile returns true if the file is processed by the library (see parseArchive), even if it was not read yet.
+func (d *Document) hasFile(fileName string) bool {
+	if _, exists := d.files[fileName]; exists {
+		return true
+	}
+	_, lazy := d.lazyFiles[fileName]
+	return lazy
+}
+
+// loadLazyFile reads the file if it was not read when the document was opened, see OpenOptions.Lazy.
+// Files which cannot be read are reported by Warnings and stay unread, so they are copied when the document is
+// written.
+func (d *Document) loadLazyFile(fileName string) {
+	file, lazy := d.lazyFiles[fileName]
+	if !lazy {
+		return
+	}
+	data, err := readZipFileBytes(file)
+	if err != nil {
+		d.warn(fileName, err)
+		return
+	}
+	delete(d.lazyFiles, fileName)
+	d.files[fileName] = data
+}
+
+// isLoadedFile returns true if the file was read into the document, i.e. it is written from memory.
+func (d *Document) isLoadedFile(fileName string) bool {
+	if _, exists := d.files[fileName]; exists {
+		return true
+	}
+	_, exists := d.packageFiles[fileName]
+	return exists
+}
+
+// translateDelimiters replaces the actions with the given delimiters in all XML parts by regular actions. Regular
+// delimiters in the text are escaped, so they are kept as text.
+func (d *Document) translateDelimiters(left, right string) error {
+	if left == "{{" && right == "}}" {
+		return nil
+	}
+	for _, fileName := range d.xmlParts() {
+		data := d.GetFile(fileName)
+		runs := d.runParsers[fileName].Runs()
+
+		var replacements []replacement
+		for _, placeholder := range parseDelimitedPlaceholders(runs, data, fileName, left, right) {
+			replacements = append(replacements, replacement{placeholder.Placeholder.StartPos(), placeholder.Placeholder.EndPos(), []byte("{{" + placeholder.Key + "}}")})
+		}
+		for _, run := range runs.WithText() {
+			text := run.GetText(data)
+			for offset := 0; ; offset += 2 {
+				i := strings.Index(text[offset:], "{{")
+				if i < 0 {
+					break
+				}
+				offset += i
+				start := run.Text.OpenTag.End + int64(offset)
+				if !overlapsReplacement(replacements, start, start+2) {
+					replacements = append(replacements, replacement{start, start + 2, []byte("{{`{{`}}")})
+				}
+			}
+		}
+		if len(replacements) == 0 {
+			continue
+		}
+
+		sort.Slice(replacements, func(i, j int) bool {
+			return replacements[i].Start < replacements[j].Start
+		})
+		if err := d.SetFile(fileName, applyReplacements(data, replacements)); err != nil {
+			return err
+		}
+		if err := d.refreshRuns(fileName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// overlapsReplacement returns true if the range overlaps any of the replacements.
+func overlapsReplacement(replacements []replacement, start, end int64) bool {
+	for _, r := range replacements {
+		if start < r.End && r.Start < end {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+package docx
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestOpenWithOptions(t *testing.T) {
+	body := `<w:body>` +
+		`<w:p><w:r><w:t xml:space="preserve">Dear [[.name]], </w:t></w:r>` +
+		`<w:ins w:id="1" w:author="A"><w:r><w:t>accepted </w:t></w:r></w:ins>` +
+		`<w:del w:id="2" w:author="A"><w:r><w:delText>rejected </w:delText></w:r></w:del>` +
+		`<w:r><w:rPr><w:b/><w:rPrChange w:id="3" w:author="A"><w:rPr/></w:rPrChange></w:rPr><w:t>{{literal}}</w:t></w:r></w:p>` +
+		`<w:p><w:pPr><w:rPr><w:del w:id="4" w:author="A"/></w:rPr></w:pPr><w:moveFrom w:id="5" w:author="A"><w:r><w:t>moved away</w:t></w:r></w:moveFrom></w:p>`
+	archive := rewriteArchive(t, readFile(t, "./test/template.docx"), func(name string, data []byte) []byte {
+		switch name {
+		case DocumentXml:
+			return []byte(strings.Replace(string(data), "<w:body>", body, 1))
+		case "word/header1.xml":
+			return bytes.Replace(data, []byte("Header {key}"), []byte("Header [[.name]]"), 1)
+		}
+		return data
+	}, nil)
+
+	doc, err := OpenBytesWithOptions(archive, OpenOptions{
+		Parts:           PartBody | PartFooters,
+		AcceptRevisions: true,
+		Delimiters:      [2]string{"[[", "]]"},
+		Lazy:            true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+
+	if len(doc.headerFiles) != 0 || len(doc.footerFiles) == 0 {
+		t.Errorf("expected only the footers to be processed, got %v %v", doc.headerFiles, doc.footerFiles)
+	}
+	if _, read := doc.files["word/media/image1.jpg"]; read {
+		t.Errorf("expected the media file to be read on demand")
+	}
+	if err := doc.ExecuteTemplate(map[string]interface{}{"name": "Ann", "literal": "x"}); err != nil {
+		t.Fatal(err)
+	}
+	documentXml := string(doc.GetFile(DocumentXml))
+	expected := `<w:p><w:r><w:t xml:space="preserve">Dear Ann, </w:t></w:r><w:r><w:t>accepted </w:t></w:r><w:r><w:rPr><w:b/></w:rPr><w:t>{{literal}}</w:t></w:r></w:p><w:p><w:pPr><w:rPr></w:rPr></w:pPr></w:p>`
+	if !strings.Contains(documentXml, expected) {
+		t.Errorf("expected %s in %s", expected, documentXml[strings.Index(documentXml, "<w:body>"):strings.Index(documentXml, "<w:tbl>")])
+	}
+
+	var buf bytes.Buffer
+	if err := doc.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	written, err := OpenBytes(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header := string(written.GetFile("word/header1.xml")); !strings.Contains(header, "Header [[.name]]") {
+		t.Errorf("expected the header to be written unchanged, got %s", header)
+	}
+	original, _ := OpenBytes(archive)
+	if image := written.GetFile("word/media/image1.jpg"); len(image) == 0 || !bytes.Equal(image, original.GetFile("word/media/image1.jpg")) {
+		t.Errorf("expected the media file to be copied from the archive")
+	}
+	if image := doc.GetFile("word/media/image1.jpg"); !bytes.Equal(image, original.GetFile("word/media/image1.jpg")) {
+		t.Errorf("expected the media file to be read on access")
+	}
+
+	for _, options := range []OpenOptions{
+		{MaxSize: 1000},
+		{Parts: PartHeaders},
+		{Delimiters: [2]string{"[[", ""}},
+	} {
+		if _, err := OpenBytesWithOptions(archive, options); err == nil {
+			t.Errorf("expected an error for the options %+v", options)
+		}
+	}
+	if _, err := OpenBytesWithOptions(archive, OpenOptions{MaxSize: 100 << 20}); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
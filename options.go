@@ -0,0 +1,298 @@
+package docx
+
+import (
+	"fmt"
+	"text/template"
+)
+
+// Option configures a document when it is opened, e.g. Open("template.docx", WithDebug(true)).
+// Options are applied in the given order after the document is parsed.
+type Option func(*Document)
+
+// Logger receives the debug messages of the template processing. *log.Logger implements it.
+type Logger interface {
+	Printf(format string, args ...interface{})
+}
+
+// MissingKeyPolicy controls how placeholders which reference missing values are handled.
+type MissingKeyPolicy int
+
+const (
+	// MissingKeyKeep leaves placeholders which reference missing values unchanged, so the document can be
+	// processed again with more data. This is the default.
+	MissingKeyKeep MissingKeyPolicy = iota
+	// MissingKeyEmpty replaces placeholders which reference missing values with an empty string.
+	MissingKeyEmpty
+	// MissingKeyError stops the template execution with an error at the first placeholder which references a
+	// missing value.
+	MissingKeyError
+)
+
+// PanicPolicy controls how panics of custom template functions and alternative engines are handled. Panics are
+// always recovered, so they cannot crash the process.
+type PanicPolicy int
+
+const (
+	// PanicAbort stops the template execution at the first panic with a TemplateError wrapping a PanicError.
+	// This is the default.
+	PanicAbort PanicPolicy = iota
+	// PanicContinue skips placeholders and blocks whose functions panic: placeholders are left unchanged and
+	// blocks spanning paragraphs are removed. The panics are available from RecoveredPanics. Panics in table rows
+	// and other helpers still stop the execution.
+	PanicContinue
+)
+
+// OverflowPolicy controls how blocks and table rows which exceed the part size limit are handled, see
+// ExecutionLimits.
+type OverflowPolicy int
+
+const (
+	// OverflowError stops the template execution with a TemplateError wrapping ErrPartSizeLimit. This is the
+	// default.
+	OverflowError OverflowPolicy = iota
+	// OverflowTruncate keeps the paragraphs, tables and rows which fit into the part and leaves out the rest. The
+	// truncated actions are available from Truncations.
+	OverflowTruncate
+)
+
+// ExecutionMode controls how ExecuteTemplate executes the placeholders of the parts.
+type ExecutionMode int
+
+const (
+	// ExecutePlaceholders executes each placeholder on its own, control structures must be closed inside their run
+	// or span whole paragraphs. This is the default.
+	ExecutePlaceholders ExecutionMode = iota
+	// ExecuteParts executes the text of each part as a single template, see SetExecutionMode.
+	ExecuteParts
+)
+
+// LengthPolicy controls whether ReplaceAll may change the length of the parts of the document.
+type LengthPolicy int
+
+const (
+	// LengthResize replaces placeholders with values of any length, the parts grow or shrink accordingly. This is
+	// the default.
+	LengthResize LengthPolicy = iota
+	// LengthPad pads values which are shorter than their placeholder with spaces, so every part keeps its length
+	// in bytes, e.g. for workflows which refer to byte offsets of signed regions. Longer values are an error.
+	LengthPad
+	// LengthExact requires every value to have the length of its placeholder in bytes, other values are an error.
+	LengthExact
+)
+
+// InvalidCharPolicy controls how values containing invalid UTF-8 or characters which are not allowed in XML 1.0,
+// e.g. control characters or unpaired surrogates, are handled. Such characters make the document unreadable.
+type InvalidCharPolicy int
+
+const (
+	// InvalidCharReplace replaces each invalid byte and character with the replacement character U+FFFD. This is
+	// the default.
+	InvalidCharReplace InvalidCharPolicy = iota
+	// InvalidCharStrip removes the invalid bytes and characters from the values.
+	InvalidCharStrip
+	// InvalidCharError stops the replacement with an error wrapping ErrInvalidChar at the first invalid value.
+	InvalidCharError
+)
+
+// WithDebug enables or disables debug logging for template processing and string replacement.
+func WithDebug(debug bool) Option {
+	return func(d *Document) {
+		d.SetDebug(debug)
+		d.stringReplacer.SetDebug(debug)
+	}
+}
+
+// WithLogger writes the debug messages to the logger instead of the standard output and enables debug logging.
+func WithLogger(logger Logger) Option {
+	return func(d *Document) {
+		d.SetLogger(logger)
+		WithDebug(true)(d)
+	}
+}
+
+// WithFuncs adds custom functions to the template processor, see AddTemplateFuncs.
+func WithFuncs(funcMap template.FuncMap) Option {
+	return func(d *Document) {
+		if funcMap != nil {
+			d.AddTemplateFuncs(funcMap)
+		}
+	}
+}
+
+// WithLocale sets the locale of the document, see SetLocale.
+func WithLocale(locale string) Option {
+	return func(d *Document) {
+		d.SetLocale(locale)
+	}
+}
+
+// WithDateLayouts sets the layouts of date strings passed to the date helpers, see SetDateLayouts.
+func WithDateLayouts(layouts ...string) Option {
+	return func(d *Document) {
+		d.SetDateLayouts(layouts...)
+	}
+}
+
+// WithMissingKeyPolicy sets how placeholders which reference missing values are handled, see MissingKeyPolicy.
+func WithMissingKeyPolicy(policy MissingKeyPolicy) Option {
+	return func(d *Document) {
+		d.SetMissingKeyPolicy(policy)
+	}
+}
+
+// WithExecutionLimits bounds the execution time and the output size of each placeholder and block, see
+// SetExecutionLimits.
+func WithExecutionLimits(limits ExecutionLimits) Option {
+	return func(d *Document) {
+		d.SetExecutionLimits(limits)
+	}
+}
+
+// WithPanicPolicy sets how panics of template functions are handled, see PanicPolicy.
+func WithPanicPolicy(policy PanicPolicy) Option {
+	return func(d *Document) {
+		d.SetPanicPolicy(policy)
+	}
+}
+
+// WithExecutionMode sets how the placeholders of the parts are executed, see ExecutionMode.
+func WithExecutionMode(mode ExecutionMode) Option {
+	return func(d *Document) {
+		d.SetExecutionMode(mode)
+	}
+}
+
+// WithLengthPolicy sets whether ReplaceAll may change the length of the parts, see LengthPolicy.
+func WithLengthPolicy(policy LengthPolicy) Option {
+	return func(d *Document) {
+		d.SetLengthPolicy(policy)
+	}
+}
+
+// WithInvalidCharPolicy sets how invalid characters in values are handled, see InvalidCharPolicy.
+func WithInvalidCharPolicy(policy InvalidCharPolicy) Option {
+	return func(d *Document) {
+		d.SetInvalidCharPolicy(policy)
+	}
+}
+
+// WithBookmarkValues marks the values inserted by ExecuteTemplate with hidden bookmarks, see SetBookmarkValues.
+func WithBookmarkValues() Option {
+	return func(d *Document) {
+		d.SetBookmarkValues(true)
+	}
+}
+
+// applyOptions applies the options to the document.
+func (d *Document) applyOptions(opts []Option) {
+	for _, opt := range opts {
+		if opt != nil {
+			opt(d)
+		}
+	}
+}
+
+// SetLogger sets the logger which receives the debug messages. Pass nil to print them to the standard output.
+func (d *Document) SetLogger(logger Logger) {
+	d.templateReplacer.logger = logger
+	d.stringReplacer.logger = logger
+}
+
+// SetLocale sets the locale of the document as a BCP 47 language tag, e.g. de-CH. The locale is available in
+// templates as {{locale}}, so localized text can be chosen with {{if eq locale "fr"}}...{{end}}.
+func (d *Document) SetLocale(locale string) {
+	d.templateReplacer.locale = locale
+}
+
+// Locale returns the locale set with SetLocale or WithLocale.
+func (d *Document) Locale() string {
+	return d.templateReplacer.locale
+}
+
+// SetMissingKeyPolicy sets how placeholders which reference missing values are handled, see MissingKeyPolicy.
+func (d *Document) SetMissingKeyPolicy(policy MissingKeyPolicy) {
+	d.templateReplacer.missingKey = policy
+}
+
+// String returns the name of the policy.
+func (p MissingKeyPolicy) String() string {
+	switch p {
+	case MissingKeyKeep:
+		return "keep"
+	case MissingKeyEmpty:
+		return "empty"
+	case MissingKeyError:
+		return "error"
+	}
+	return fmt.Sprintf("MissingKeyPolicy(%d)", int(p))
+}
+
+// String returns the name of the policy.
+func (p PanicPolicy) String() string {
+	switch p {
+	case PanicAbort:
+		return "abort"
+	case PanicContinue:
+		return "continue"
+	}
+	return fmt.Sprintf("PanicPolicy(%d)", int(p))
+}
+
+// String returns the name of the policy.
+func (p OverflowPolicy) String() string {
+	switch p {
+	case OverflowError:
+		return "error"
+	case OverflowTruncate:
+		return "truncate"
+	}
+	return fmt.Sprintf("OverflowPolicy(%d)", int(p))
+}
+
+// String returns the name of the mode.
+func (m ExecutionMode) String() string {
+	switch m {
+	case ExecutePlaceholders:
+		return "placeholders"
+	case ExecuteParts:
+		return "parts"
+	}
+	return fmt.Sprintf("ExecutionMode(%d)", int(m))
+}
+
+// SetLengthPolicy sets whether ReplaceAll may change the length of the parts, see LengthPolicy.
+func (d *Document) SetLengthPolicy(policy LengthPolicy) {
+	d.stringReplacer.length = policy
+}
+
+// String returns the name of the policy.
+func (p LengthPolicy) String() string {
+	switch p {
+	case LengthResize:
+		return "resize"
+	case LengthPad:
+		return "pad"
+	case LengthExact:
+		return "exact"
+	}
+	return fmt.Sprintf("LengthPolicy(%d)", int(p))
+}
+
+// SetInvalidCharPolicy sets how values of ExecuteTemplate and ReplaceAll containing invalid UTF-8 or characters
+// which are not allowed in XML 1.0 are handled, see InvalidCharPolicy.
+func (d *Document) SetInvalidCharPolicy(policy InvalidCharPolicy) {
+	d.invalidChars = policy
+}
+
+// String returns the name of the policy.
+func (p InvalidCharPolicy) String() string {
+	switch p {
+	case InvalidCharReplace:
+		return "replace"
+	case InvalidCharStrip:
+		return "strip"
+	case InvalidCharError:
+		return "error"
+	}
+	return fmt.Sprintf("InvalidCharPolicy(%d)", int(p))
+}
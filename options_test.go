@@ -0,0 +1,70 @@
+package docx
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+// testLogger collects the debug messages.
+type testLogger struct {
+	messages []string
+}
+
+func (l *testLogger) Printf(format string, args ...interface{}) {
+	l.messages = append(l.messages, fmt.Sprintf(format, args...))
+}
+
+func TestOptions(t *testing.T) {
+	body := `<w:body><w:p><w:r><w:t>{{.name | shout}} ({{locale}}) {{.missing}}</w:t></w:r></w:p>`
+	archive := rewriteArchive(t, readFile(t, "./test/template.docx"), func(name string, data []byte) []byte {
+		if name == DocumentXml {
+			return []byte(strings.Replace(string(data), "<w:body>", body, 1))
+		}
+		return data
+	}, nil)
+	funcs := WithFuncs(template.FuncMap{"shout": strings.ToUpper})
+	data := map[string]interface{}{"name": "Anna"}
+
+	tests := []struct {
+		name     string
+		opts     []Option
+		expected string
+	}{
+		{"keep missing values", []Option{funcs, WithLocale("de-CH")}, `<w:t>ANNA (de-CH) {{.missing}}</w:t>`},
+		{"empty missing values", []Option{funcs, WithMissingKeyPolicy(MissingKeyEmpty)}, `<w:t>ANNA () </w:t>`},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			doc, err := OpenBytes(archive, test.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer doc.Close()
+			if err := doc.ExecuteTemplate(data); err != nil {
+				t.Fatal(err)
+			}
+			if documentXml := string(doc.GetFile(DocumentXml)); !strings.Contains(documentXml, test.expected) {
+				t.Errorf("expected %s in %s", test.expected, documentXml[:strings.Index(documentXml, "<w:sectPr")])
+			}
+		})
+	}
+
+	t.Run("missing values are errors", func(t *testing.T) {
+		_, err := CompleteTemplateFromBytesToBytes(archive, data, funcs, WithMissingKeyPolicy(MissingKeyError))
+		if err == nil || !strings.Contains(err.Error(), "missing value") {
+			t.Errorf("expected an error for the missing value, got %v", err)
+		}
+	})
+
+	t.Run("logger", func(t *testing.T) {
+		logger := &testLogger{}
+		if _, err := CompleteTemplateFromBytesToBytesWithFuncs(archive, data, template.FuncMap{"shout": strings.ToUpper}, WithLogger(logger)); err != nil {
+			t.Fatal(err)
+		}
+		if len(logger.messages) == 0 || !strings.HasPrefix(logger.messages[0], "[DEBUG] ") {
+			t.Errorf("unexpected debug messages %v", logger.messages)
+		}
+	})
+}
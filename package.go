@@ -0,0 +1,323 @@
+package docx
+
+import (
+	"archive/zip"
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+const (
+	// ContentTypesXml is the path of the part which declares the content types of all other parts.
+	ContentTypesXml = "[Content_Types].xml"
+	// PackageRelsXml is the path of the package relationships part.
+	PackageRelsXml = "_rels/.rels"
+	// DocumentRelsXml is the path of the relationships part belonging to the main document.
+	DocumentRelsXml = "word/_rels/document.xml.rels"
+	// VbaProjectBin is the path where macro-enabled packages store their VBA project.
+	VbaProjectBin = "word/vbaProject.bin"
+)
+
+const (
+	// DocxContentType is the main part content type of a regular .docx document.
+	DocxContentType = "application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"
+	// DocmContentType is the main part content type of a macro-enabled .docm document.
+	DocmContentType = "application/vnd.ms-word.document.macroEnabled.main+xml"
+	// DotxContentType is the main part content type of a .dotx template.
+	DotxContentType = "application/vnd.openxmlformats-officedocument.wordprocessingml.template.main+xml"
+	// DotmContentType is the main part content type of a macro-enabled .dotm template.
+	DotmContentType = "application/vnd.ms-word.template.macroEnabledTemplate.main+xml"
+
+	// VbaProjectContentType is the content type of the VBA project of macro-enabled packages.
+	VbaProjectContentType = "application/vnd.ms-office.vbaProject"
+	// VbaDataContentType is the content type of the VBA data part of macro-enabled packages.
+	VbaDataContentType = "application/vnd.ms-word.vbaData+xml"
+	// VbaProjectRelationshipType is the relationship type from the main document to the VBA project.
+	VbaProjectRelationshipType = "http://schemas.microsoft.com/office/2006/relationships/vbaProject"
+)
+
+var (
+	// vbaPartRegex matches all parts which make up the VBA project of a macro-enabled package.
+	vbaPartRegex = regexp.MustCompile(`^word/(vbaProject\.bin|vbaData\.xml|_rels/vbaProject\.bin\.rels)$`)
+)
+
+// DocumentType identifies the kind of WordprocessingML package, derived from the content type of the main part.
+type DocumentType int
+
+const (
+	// TypeDocx is a regular document (.docx).
+	TypeDocx DocumentType = iota
+	// TypeDocm is a macro-enabled document (.docm).
+	TypeDocm
+	// TypeDotx is a template (.dotx).
+	TypeDotx
+	// TypeDotm is a macro-enabled template (.dotm).
+	TypeDotm
+)
+
+// String returns the file extension of the type without the leading dot.
+func (t DocumentType) String() string {
+	switch t {
+	case TypeDocm:
+		return "docm"
+	case TypeDotx:
+		return "dotx"
+	case TypeDotm:
+		return "dotm"
+	default:
+		return "docx"
+	}
+}
+
+// Extension returns the file extension, including the leading dot, which is used for this type.
+func (t DocumentType) Extension() string {
+	return "." + t.String()
+}
+
+// ContentType returns the content type of the main document part for this type.
+func (t DocumentType) ContentType() string {
+	switch t {
+	case TypeDocm:
+		return DocmContentType
+	case TypeDotx:
+		return DotxContentType
+	case TypeDotm:
+		return DotmContentType
+	default:
+		return DocxContentType
+	}
+}
+
+// MacroEnabled returns true if packages of this type may carry a VBA project.
+func (t DocumentType) MacroEnabled() bool {
+	return t == TypeDocm || t == TypeDotm
+}
+
+// documentTypeFromContentType maps a main part content type to its DocumentType.
+func documentTypeFromContentType(contentType string) (DocumentType, error) {
+	for _, t := range []DocumentType{TypeDocx, TypeDocm, TypeDotx, TypeDotm} {
+		if t.ContentType() == contentType {
+			return t, nil
+		}
+	}
+	return TypeDocx, fmt.Errorf("unsupported main document content type %s", contentType)
+}
+
+// detectDocumentType reads the content type of the main document part from [Content_Types].xml.
+// Packages without a content type declaration for the main part are treated as regular .docx documents.
+func detectDocumentType(contentTypesXml []byte) (DocumentType, error) {
+	if contentTypesXml == nil {
+		return TypeDocx, nil
+	}
+
+	types, err := parseContentTypes(contentTypesXml)
+	if err != nil {
+		return TypeDocx, err
+	}
+	for _, override := range types.Overrides {
+		if override.PartName == "/"+DocumentXml {
+			return documentTypeFromContentType(override.ContentType)
+		}
+	}
+	return TypeDocx, nil
+}
+
+// Type returns the type of the opened package, e.g. TypeDotx if a template was opened.
+func (d *Document) Type() DocumentType {
+	return d.docType
+}
+
+// OutputType returns the type the package will have when it is written.
+// Unless changed through SetOutputType, this is the same as Type().
+func (d *Document) OutputType() DocumentType {
+	return d.outputType
+}
+
+// SetOutputType changes the type of the package which is written by Write and WriteToFile.
+// This is typically used to produce a .docx from a .dotx or .docm template.
+// If the output type is not macro-enabled, the VBA project of the source is left out of the output.
+// The original package is never modified.
+func (d *Document) SetOutputType(t DocumentType) {
+	d.outputType = t
+}
+
+// isDroppedFile returns true if the given file of the original archive must not be written,
+// either because it was removed or because it cannot be part of the current output type.
+func (d *Document) isDroppedFile(fileName string) bool {
+	if d.removedFiles[fileName] {
+		return true
+	}
+	return d.docType.MacroEnabled() && !d.outputType.MacroEnabled() && vbaPartRegex.MatchString(fileName)
+}
+
+// archiveFile returns the given file of the original archive or nil. The files are indexed on first use, so
+// archives with many entries are handled efficiently.
+func (d *Document) archiveFile(fileName string) *zip.File {
+	if d.archiveIndex == nil {
+		d.archiveIndex = make(map[string]*zip.File, len(d.zipFile.File))
+		for _, file := range d.zipFile.File {
+			if _, exists := d.archiveIndex[file.Name]; !exists {
+				d.archiveIndex[file.Name] = file
+			}
+		}
+	}
+	return d.archiveIndex[fileName]
+}
+
+// hasArchiveFile returns true if the original archive contains the given file.
+func (d *Document) hasArchiveFile(fileName string) bool {
+	return d.archiveFile(fileName) != nil
+}
+
+// addedPackageFiles returns the sorted names of all package files which are not part of the original archive.
+func (d *Document) addedPackageFiles() []string {
+	var names []string
+	for name := range d.packageFiles {
+		if !d.hasArchiveFile(name) && !d.removedFiles[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// addedFiles returns the sorted names of all processed files (see parseArchive) which are not part of the original archive.
+func (d *Document) addedFiles() []string {
+	var names []string
+	for name := range d.files {
+		if !d.hasArchiveFile(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// addFile adds a new file which is processed just like the files read by parseArchive, e.g. a new header.
+// The file is parsed for its runs so that templates and placeholders inside of it are replaced.
+func (d *Document) addFile(fileName string, data []byte) error {
+	parser := NewRunParser(data)
+	if err := parser.Execute(); err != nil {
+		return err
+	}
+	d.files[fileName] = data
+	d.runParsers[fileName] = parser
+	delete(d.removedFiles, fileName)
+
+	if MediaPathRegex.MatchString(fileName) {
+		d.mediaFiles = append(d.mediaFiles, fileName)
+	} else {
+		d.addStoryFile(fileName)
+	}
+	return nil
+}
+
+// addStoryFile registers the part of a story besides the main document, e.g. a header or the footnotes, so it is
+// processed by templates. Other files are ignored.
+func (d *Document) addStoryFile(fileName string) {
+	switch {
+	case HeaderPathRegex.MatchString(fileName):
+		d.headerFiles = append(d.headerFiles, fileName)
+	case FooterPathRegex.MatchString(fileName):
+		d.footerFiles = append(d.footerFiles, fileName)
+	case FootnotesPathRegex.MatchString(fileName), EndnotesPathRegex.MatchString(fileName), CommentsPathRegex.MatchString(fileName):
+		d.noteFiles = append(d.noteFiles, fileName)
+	}
+}
+
+// loadPackageFile returns the given package-level file. Files of the original archive which were not
+// read by parseArchive are loaded on demand, so that they can be modified and are written back by Write.
+func (d *Document) loadPackageFile(fileName string) ([]byte, bool) {
+	if data, exists := d.packageFiles[fileName]; exists {
+		return data, true
+	}
+	if d.removedFiles[fileName] {
+		return nil, false
+	}
+	file := d.archiveFile(fileName)
+	if file == nil {
+		return nil, false
+	}
+	data, err := readZipFileBytes(file)
+	if err != nil {
+		d.warn(fileName, err)
+		return nil, false
+	}
+	d.packageFiles[fileName] = data
+	return data, true
+}
+
+// setPackageFile adds or replaces a package-level file.
+func (d *Document) setPackageFile(fileName string, data []byte) {
+	d.packageFiles[fileName] = data
+	delete(d.removedFiles, fileName)
+}
+
+// removePackageFile removes a file from the package, regardless of whether it was part of the original archive.
+func (d *Document) removePackageFile(fileName string) {
+	delete(d.packageFiles, fileName)
+	d.removedFiles[fileName] = true
+}
+
+// forgetFile removes a removed file from the files which are processed by the library (see parseArchive).
+func (d *Document) forgetFile(fileName string) {
+	if !d.hasFile(fileName) {
+		return
+	}
+	delete(d.files, fileName)
+	delete(d.lazyFiles, fileName)
+	delete(d.runParsers, fileName)
+
+	without := func(files []string) []string {
+		var kept []string
+		for _, file := range files {
+			if file != fileName {
+				kept = append(kept, file)
+			}
+		}
+		return kept
+	}
+	d.headerFiles = without(d.headerFiles)
+	d.footerFiles = without(d.footerFiles)
+	d.noteFiles = without(d.noteFiles)
+	d.mediaFiles = without(d.mediaFiles)
+}
+
+// outputPackageFile returns the contents of a package-level file (see packageFiles) as it must be written
+// for the current output type.
+func (d *Document) outputPackageFile(fileName string) []byte {
+	data := d.packageFiles[fileName]
+	if d.docType == d.outputType {
+		return data
+	}
+
+	dropMacros := d.docType.MacroEnabled() && !d.outputType.MacroEnabled()
+	switch fileName {
+	case ContentTypesXml:
+		types, err := parseContentTypes(data)
+		if err != nil {
+			return data
+		}
+		types.setOverride("/"+DocumentXml, d.outputType.ContentType())
+		if dropMacros {
+			types.removeContentType(VbaProjectContentType)
+			types.removeContentType(VbaDataContentType)
+		}
+		if converted, err := types.marshal(); err == nil {
+			return converted
+		}
+	case DocumentRelsXml:
+		if !dropMacros {
+			return data
+		}
+		rels, err := parseRelationships(data)
+		if err != nil {
+			return data
+		}
+		rels.removeIf(func(rel opcRelationship) bool { return rel.Type == VbaProjectRelationshipType })
+		if converted, err := rels.marshal(); err == nil {
+			return converted
+		}
+	}
+	return data
+}
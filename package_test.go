@@ -0,0 +1,175 @@
+package docx
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+// rewriteArchive copies the given archive, applying modify to each file. Files for which modify returns nil are dropped.
+// All files in extra are appended to the archive.
+func rewriteArchive(t testing.TB, src []byte, modify func(name string, data []byte) []byte, extra map[string][]byte) []byte {
+	zr, err := zip.NewReader(bytes.NewReader(src), int64(len(src)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(rc)
+		_ = rc.Close()
+		if modify != nil {
+			data = modify(f.Name, data)
+		}
+		if data == nil {
+			continue
+		}
+		w, _ := zw.Create(f.Name)
+		_, _ = w.Write(data)
+	}
+	for name, data := range extra {
+		w, _ := zw.Create(name)
+		_, _ = w.Write(data)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// makeDocm turns the test template into a macro-enabled document with a (fake) VBA project.
+func makeDocm(t testing.TB) []byte {
+	modify := func(name string, data []byte) []byte {
+		switch name {
+		case ContentTypesXml:
+			s := strings.Replace(string(data), DocxContentType, DocmContentType, 1)
+			s = strings.Replace(s, "<Default Extension=\"xml\"", "<Default Extension=\"bin\" ContentType=\"application/vnd.ms-office.vbaProject\"/><Default Extension=\"xml\"", 1)
+			return []byte(s)
+		case DocumentRelsXml:
+			return []byte(strings.Replace(string(data), "</Relationships>", "<Relationship Id=\"rId99\" Type=\"http://schemas.microsoft.com/office/2006/relationships/vbaProject\" Target=\"vbaProject.bin\"/></Relationships>", 1))
+		}
+		return data
+	}
+	return rewriteArchive(t, readFile(t, "./test/template.docx"), modify, map[string][]byte{VbaProjectBin: []byte("VBA")})
+}
+
+func TestDocument_Type(t *testing.T) {
+	doc, err := Open("./test/template.docx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Type() != TypeDocx {
+		t.Errorf("expected type docx, got %s", doc.Type())
+	}
+
+	doc, err = OpenBytes(makeDocm(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc.Type() != TypeDocm {
+		t.Errorf("expected type docm, got %s", doc.Type())
+	}
+}
+
+func TestDocument_DocmPreservesVbaProject(t *testing.T) {
+	doc, err := OpenBytes(makeDocm(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := doc.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out, err := OpenBytes(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Type() != TypeDocm {
+		t.Errorf("expected type docm, got %s", out.Type())
+	}
+	if !archiveContains(t, buf.Bytes(), VbaProjectBin) {
+		t.Errorf("%s was not preserved", VbaProjectBin)
+	}
+}
+
+func TestDocument_SetOutputType(t *testing.T) {
+	doc, err := OpenBytes(makeDocm(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc.SetOutputType(TypeDocx)
+
+	var buf bytes.Buffer
+	if err := doc.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out, err := OpenBytes(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Type() != TypeDocx {
+		t.Errorf("expected type docx, got %s", out.Type())
+	}
+	if archiveContains(t, buf.Bytes(), VbaProjectBin) {
+		t.Errorf("%s must be removed when writing a docx", VbaProjectBin)
+	}
+	if bytes.Contains(out.packageFiles[ContentTypesXml], []byte("vbaProject")) {
+		t.Error("vbaProject content type must be removed when writing a docx")
+	}
+	if bytes.Contains(out.packageFiles[DocumentRelsXml], []byte("vbaProject")) {
+		t.Error("vbaProject relationship must be removed when writing a docx")
+	}
+}
+
+func archiveContains(t testing.TB, archive []byte, name string) bool {
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range zr.File {
+		if f.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func TestDocument_GenerateThumbnail(t *testing.T) {
+	doc, err := Open("./test/template.docx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.GenerateThumbnail(TextPreviewRenderer{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.SetThumbnail([]byte("PNG"), ThumbnailPNG); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := doc.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out, err := OpenBytes(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, format, exists := out.Thumbnail()
+	if !exists || format != ThumbnailPNG || string(data) != "PNG" {
+		t.Errorf("expected the png thumbnail, got %v %s", exists, format)
+	}
+	if archiveContains(t, buf.Bytes(), "docProps/thumbnail.jpeg") {
+		t.Error("the replaced jpeg thumbnail must be removed")
+	}
+	if !bytes.Contains(out.packageFiles[ContentTypesXml], []byte(`Extension="png"`)) {
+		t.Error("png content type was not registered")
+	}
+}
@@ -0,0 +1,98 @@
+package docx
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+)
+
+// pagination.go implements the helpers which control how paragraphs break across pages: {{keepnext}},
+// {{keeplines}} and {{widowcontrol}}. They may be placed anywhere in a paragraph, including paragraphs which are
+// repeated by {{range}} or cloned with table rows, and write a marker which is replaced by the property of the
+// paragraph after the template is executed.
+
+var (
+	// paragraphMarkerRegex matches the markers written by the pagination helpers, the element of the paragraph
+	// properties is hex-encoded.
+	paragraphMarkerRegex = regexp.MustCompile(`\[\[docx-paragraph ([0-9a-f]*)\]\]`)
+	// paragraphOpenTagRegex matches the open tag of a paragraph.
+	paragraphOpenTagRegex = regexp.MustCompile(`^<w:p\b[^>]*>`)
+)
+
+// paragraphPropertiesOrder lists the children of the paragraph properties (<w:pPr>) in the order of the schema.
+var paragraphPropertiesOrder = []string{"w:pStyle", "w:keepNext", "w:keepLines", "w:pageBreakBefore", "w:framePr",
+	"w:widowControl", "w:numPr", "w:suppressLineNumbers", "w:pBdr", "w:shd", "w:tabs", "w:suppressAutoHyphens",
+	"w:kinsoku", "w:wordWrap", "w:overflowPunct", "w:topLinePunct", "w:autoSpaceDE", "w:autoSpaceDN", "w:bidi",
+	"w:adjustRightInd", "w:snapToGrid", "w:spacing", "w:ind", "w:contextualSpacing", "w:mirrorIndents",
+	"w:suppressOverlap", "w:jc", "w:textDirection", "w:textAlignment", "w:textboxTightWrap", "w:outlineLvl",
+	"w:divId", "w:cnfStyle", "w:rPr", "w:sectPr", "w:pPrChange"}
+
+// paragraphPropertyHelper returns a helper which switches the on/off property of the paragraph with the given
+// name, e.g. {{keepnext}} or {{keepnext false}} to override the style of the paragraph.
+func paragraphPropertyHelper(helper, name string) func(...bool) (cellMarker, error) {
+	return func(on ...bool) (cellMarker, error) {
+		if len(on) > 1 {
+			return "", fmt.Errorf("%s: expected at most one argument, got %d", helper, len(on))
+		}
+		element := "<" + name + "/>"
+		if len(on) == 1 && !on[0] {
+			element = "<" + name + ` w:val="0"/>`
+		}
+		return cellMarker("[[docx-paragraph " + hex.EncodeToString([]byte(element)) + "]]"), nil
+	}
+}
+
+var (
+	// keepnextHelper implements {{keepnext}}: the paragraph is kept on the same page as the next one, so generated
+	// headings are not left at the bottom of a page.
+	keepnextHelper = paragraphPropertyHelper("keepnext", "w:keepNext")
+	// keeplinesHelper implements {{keeplines}}: all lines of the paragraph are kept on the same page.
+	keeplinesHelper = paragraphPropertyHelper("keeplines", "w:keepLines")
+	// widowcontrolHelper implements {{widowcontrol}}: the first and the last line of the paragraph are not
+	// displayed alone on a page.
+	widowcontrolHelper = paragraphPropertyHelper("widowcontrol", "w:widowControl")
+)
+
+// resolveParagraphProperties replaces the markers of the pagination helpers in all XML parts.
+func (tr *TemplateReplacer) resolveParagraphProperties() error {
+	for _, fileName := range tr.document.xmlParts() {
+		data := tr.document.GetFile(fileName)
+		if !paragraphMarkerRegex.Match(data) {
+			continue
+		}
+		if err := tr.document.SetFile(fileName, resolveParagraphMarkers(data)); err != nil {
+			return err
+		}
+		if err := tr.document.refreshRuns(fileName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveParagraphMarkers removes the markers of the pagination helpers and sets the properties of the
+// paragraphs containing them. Markers outside of paragraphs are removed without effect.
+func resolveParagraphMarkers(data []byte) []byte {
+	// setting a property moves the following markers, so they are resolved one by one
+	for {
+		match := paragraphMarkerRegex.FindSubmatchIndex(data)
+		if match == nil {
+			return data
+		}
+		rest := data[match[1]:]
+		start := -1
+		for _, tag := range []string{"<w:p>", "<w:p "} {
+			if i := bytes.LastIndex(data[:match[0]], []byte(tag)); i > start {
+				start = i
+			}
+		}
+		if start < 0 {
+			data = append(append([]byte{}, data[:match[0]]...), rest...)
+			continue
+		}
+		element, _ := hex.DecodeString(string(data[match[2]:match[3]]))
+		paragraph := setNestedProperty(data[start:match[0]], paragraphOpenTagRegex, "w:pPr", elementName(element), element, paragraphPropertiesOrder)
+		data = append(append(append([]byte{}, data[:start]...), paragraph...), rest...)
+	}
+}
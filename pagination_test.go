@@ -0,0 +1,55 @@
+package docx
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTemplateReplacer_ParagraphProperties(t *testing.T) {
+	body := `<w:body>` +
+		`<w:p><w:r><w:t>{{range .sections}}</w:t></w:r></w:p>` +
+		`<w:p><w:pPr><w:pStyle w:val="Heading2"/><w:jc w:val="center"/></w:pPr><w:r><w:t>{{keepnext}}{{.title}}</w:t></w:r></w:p>` +
+		`<w:p w14:paraId="1A2B3C4D"><w:r><w:t>{{keeplines}}{{widowcontrol false}}{{.text}}</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>{{end}}</w:t></w:r></w:p>` +
+		`<w:p><w:pPr><w:keepNext w:val="0"/></w:pPr><w:r><w:t>Summary{{keepnext}}</w:t></w:r></w:p>`
+
+	doc, err := OpenBytes(rewriteArchive(t, readFile(t, "./test/template.docx"), func(name string, data []byte) []byte {
+		if name == DocumentXml {
+			return []byte(strings.Replace(string(data), "<w:body>", body, 1))
+		}
+		return data
+	}, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+
+	err = doc.ExecuteTemplate(map[string]interface{}{
+		"sections": []map[string]string{{"title": "Scope", "text": "All services."}, {"title": "Term", "text": "One year."}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	documentXml := string(doc.GetFile(DocumentXml))
+	if strings.Contains(documentXml, "[[docx-paragraph") {
+		t.Fatalf("the markers were not resolved")
+	}
+	heading := `<w:p><w:pPr><w:pStyle w:val="Heading2"/><w:keepNext/><w:jc w:val="center"/></w:pPr><w:r><w:t>`
+	text := `<w:p w14:paraId="1A2B3C4D"><w:pPr><w:keepLines/><w:widowControl w:val="0"/></w:pPr><w:r><w:t>`
+	for expected, count := range map[string]int{
+		heading + `Scope</w:t>`:                                    1,
+		heading + `Term</w:t>`:                                     1,
+		text + `All services.</w:t>`:                               1,
+		text + `One year.</w:t>`:                                   1,
+		`<w:p><w:pPr><w:keepNext/></w:pPr><w:r><w:t>Summary</w:t>`: 1,
+	} {
+		if n := strings.Count(documentXml, expected); n != count {
+			t.Errorf("expected %s %d times, got %d in %s", expected, count, n, documentXml[strings.Index(documentXml, "<w:body>"):strings.Index(documentXml, "<w:sectPr")])
+		}
+	}
+
+	if _, err := keepnextHelper(true, false); err == nil {
+		t.Errorf("expected an error for too many arguments")
+	}
+}
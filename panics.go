@@ -0,0 +1,106 @@
+package docx
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"runtime/debug"
+	"text/template"
+)
+
+// panics.go recovers panics of custom template functions and alternative engines, so a failing function cannot
+// crash the process. Panics are returned as TemplateError wrapping a PanicError, or skipped according to the
+// PanicPolicy.
+
+// PanicError is the error of a template function which panicked.
+type PanicError struct {
+	Function string      // The name of the function, empty for panics outside of custom functions
+	Value    interface{} // The value passed to panic
+	Stack    []byte      // The stack trace of the panic
+}
+
+// Error returns the panic value and the name of the function.
+func (e *PanicError) Error() string {
+	if e.Function == "" {
+		return fmt.Sprintf("panic: %v", e.Value)
+	}
+	return fmt.Sprintf("function %s panicked: %v", e.Function, e.Value)
+}
+
+// Unwrap returns the panic value if it is an error.
+func (e *PanicError) Unwrap() error {
+	if err, isError := e.Value.(error); isError {
+		return err
+	}
+	return nil
+}
+
+// SetPanicPolicy sets how panics of template functions are handled, see PanicPolicy.
+func (d *Document) SetPanicPolicy(policy PanicPolicy) {
+	d.templateReplacer.panicPolicy = policy
+}
+
+// RecoveredPanics returns the panics skipped by the last template execution with PanicContinue. The errors are
+// TemplateError wrapping a PanicError, so the failed placeholders can be located.
+func (d *Document) RecoveredPanics() []error {
+	return d.templateReplacer.recovered
+}
+
+// recoverFuncs wraps the functions, so their panics are converted to a PanicError. text/template returns panics
+// as error, the PanicError keeps the name of the function and the stack trace.
+func recoverFuncs(funcMap template.FuncMap) template.FuncMap {
+	wrapped := make(template.FuncMap, len(funcMap))
+	for name, function := range funcMap {
+		fn := reflect.ValueOf(function)
+		if fn.Kind() != reflect.Func {
+			// text/template reports the invalid function
+			wrapped[name] = function
+			continue
+		}
+		wrapped[name] = reflect.MakeFunc(fn.Type(), func(args []reflect.Value) (results []reflect.Value) {
+			defer func() {
+				if r := recover(); r != nil {
+					panic(newPanicError(name, r))
+				}
+			}()
+			if fn.Type().IsVariadic() {
+				return fn.CallSlice(args)
+			}
+			return fn.Call(args)
+		}).Interface()
+	}
+	return wrapped
+}
+
+// newPanicError returns the PanicError of the recovered value. Panics of nested functions keep their error.
+func newPanicError(function string, r interface{}) *PanicError {
+	if panicErr, isPanic := r.(*PanicError); isPanic {
+		return panicErr
+	}
+	return &PanicError{Function: function, Value: r, Stack: debug.Stack()}
+}
+
+// recoverPanic converts a panic into a PanicError returned in err. It must be deferred.
+func recoverPanic(err *error) {
+	if r := recover(); r != nil {
+		*err = newPanicError("", r)
+	}
+}
+
+// skipPanic returns true if the error is a panic which is skipped according to the panic policy. The error is
+// recorded, see RecoveredPanics.
+func (tr *TemplateReplacer) skipPanic(err error) bool {
+	var panicErr *PanicError
+	if tr.panicPolicy != PanicContinue || !errors.As(err, &panicErr) {
+		return false
+	}
+	tr.debugLog("Skipping after panic: %v", err)
+	tr.recovered = append(tr.recovered, err)
+	return true
+}
+
+// executeEngineAction executes the action with the alternative engine, panics are returned as PanicError.
+func (tr *TemplateReplacer) executeEngineAction(action string, data TemplateData) (result string, err error) {
+	defer recoverPanic(&err)
+	return tr.engine.Execute(action, data)
+}
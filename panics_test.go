@@ -0,0 +1,62 @@
+package docx
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+func TestDocument_PanicPolicy(t *testing.T) {
+	funcs := template.FuncMap{
+		"explode": func(s string) string {
+			var m map[string]int
+			m[s] = 1
+			return s
+		},
+		"upper": strings.ToUpper,
+		"join":  func(sep string, values ...string) string { return strings.Join(values, sep) },
+	}
+	build := func(opts ...Option) *Document {
+		doc, err := NewDocument().
+			Paragraph(`Name: {{upper .name}}`).
+			Paragraph(`Code: {{explode .name}}`).
+			Paragraph(`{{range .items}}`).Paragraph(`Item {{explode .}}`).Paragraph(`{{end}}`).
+			Paragraph(`Tags: {{join ", " "a" "b"}}`).
+			Build(append([]Option{WithFuncs(funcs)}, opts...)...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return doc
+	}
+	data := map[string]interface{}{"name": "jane", "items": []string{"x"}}
+
+	doc := build()
+	err := doc.ExecuteTemplate(data)
+	doc.Close()
+	var templateErr *TemplateError
+	var panicErr *PanicError
+	if !errors.As(err, &templateErr) || !errors.As(err, &panicErr) {
+		t.Fatalf("expected a TemplateError wrapping a PanicError, got %v", err)
+	}
+	if templateErr.Action != "{{range .items}}" || panicErr.Function != "explode" || len(panicErr.Stack) == 0 {
+		t.Errorf("unexpected error %v of %s", err, templateErr.Action)
+	}
+
+	doc = build(WithPanicPolicy(PanicContinue))
+	defer doc.Close()
+	if err := doc.ExecuteTemplate(data); err != nil {
+		t.Fatal(err)
+	}
+	texts, err := paragraphTexts(doc.GetFile(DocumentXml))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "Name: JANE|Code: {{explode .name}}|Tags: a, b"
+	if text := strings.Join(texts, "|"); text != expected {
+		t.Errorf("expected %q, got %q", expected, text)
+	}
+	if recovered := doc.RecoveredPanics(); len(recovered) != 2 || !strings.Contains(recovered[1].Error(), "{{explode .name}}") {
+		t.Errorf("unexpected recovered panics %v", recovered)
+	}
+}
@@ -0,0 +1,370 @@
+package docx
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// parts.go implements the public API to add, remove, rename and inspect the parts of the package. The content types
+// and relationships are kept consistent by the OPC layer, see opc.go.
+
+var (
+	// referenceContainerRegex matches the elements which show a related part, e.g. the drawing of an image.
+	referenceContainerRegex = regexp.MustCompile(`(?s)<w:(drawing|object|pict)\b[^>]*>.*?</w:(?:drawing|object|pict)>`)
+	// referenceElementRegex matches the singleton elements referencing a relationship, e.g. <w:footerReference/>.
+	referenceElementRegex = regexp.MustCompile(`<[\w:]+\s[^>]*\br:(?:id|embed|link|pict|dm|lo|qs|cs)="[^"]*"[^>]*/>`)
+)
+
+// partRelationshipTypes lists the relationship types from the main document to new parts by their content type.
+var partRelationshipTypes = map[string]string{
+	HeaderContentType:    HeaderRelationshipType,
+	FooterContentType:    FooterRelationshipType,
+	StylesContentType:    StylesRelationshipType,
+	NumberingContentType: NumberingRelationshipType,
+	fontTableContentType: FontTableRelationshipType,
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.footnotes+xml": "http://schemas.openxmlformats.org/officeDocument/2006/relationships/footnotes",
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.endnotes+xml":  "http://schemas.openxmlformats.org/officeDocument/2006/relationships/endnotes",
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.comments+xml":  "http://schemas.openxmlformats.org/officeDocument/2006/relationships/comments",
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.settings+xml":  "http://schemas.openxmlformats.org/officeDocument/2006/relationships/settings",
+	"application/vnd.openxmlformats-officedocument.theme+xml":                      "http://schemas.openxmlformats.org/officeDocument/2006/relationships/theme",
+	"application/vnd.openxmlformats-officedocument.drawingml.chart+xml":            "http://schemas.openxmlformats.org/officeDocument/2006/relationships/chart",
+}
+
+// AddFile adds a new part to the package, the counterpart to SetFile for parts which do not exist yet. The content
+// type is registered in [Content_Types].xml. Headers, footers, notes, comments, styles, charts, images and other
+// parts with a well-known content type are related to the main document and the id of the relationship is
+// returned, e.g. to reference a new header from the section properties or a chart from a drawing. Other parts,
+// e.g. the relationship part of a new chart, are only added and the returned id is empty.
+// Headers, footers, notes and comments are processed by ExecuteTemplate like the existing ones.
+func (d *Document) AddFile(fileName string, data []byte, contentType string) (string, error) {
+	if fileName == "" || strings.HasPrefix(fileName, "/") || strings.HasSuffix(fileName, "/") {
+		return "", fmt.Errorf("invalid part name %q", fileName)
+	}
+	if contentType == "" {
+		return "", fmt.Errorf("missing content type of %s", fileName)
+	}
+	if _, exists := d.partData(fileName); exists || fileName == ContentTypesXml {
+		return "", fmt.Errorf("file %s already exists, use SetFile to change it", fileName)
+	}
+
+	isStory := false
+	for _, story := range storyTypes {
+		isStory = isStory || story.regex.MatchString(fileName)
+	}
+	switch {
+	case MediaPathRegex.MatchString(fileName):
+		if err := d.ensureContentType(fileName, contentType); err != nil {
+			return "", err
+		}
+		d.setMediaFile(fileName, data)
+	case isStory:
+		// parsed for its runs, so the placeholders are replaced
+		if err := d.ensureContentType(fileName, contentType); err != nil {
+			return "", err
+		}
+		if err := d.addFile(fileName, data); err != nil {
+			return "", err
+		}
+	default:
+		if err := d.addPart(fileName, data, contentType); err != nil {
+			return "", err
+		}
+	}
+
+	relType, exists := partRelationshipTypes[contentType]
+	if !exists && strings.HasPrefix(contentType, "image/") {
+		relType, exists = ImageRelationshipType, true
+	}
+	if !exists {
+		return "", nil
+	}
+	return d.addRelationship(DocumentXml, relType, fileName)
+}
+
+// RemoveFile removes a part from the package, e.g. a footer or an unused chart. Its content type, its
+// relationships and the relationships of other parts targeting it are removed. The elements referencing the
+// removed relationships are removed as well, e.g. the footer references of the sections or the drawings of an
+// image, so the package stays valid. The main document and [Content_Types].xml cannot be removed.
+func (d *Document) RemoveFile(fileName string) error {
+	if fileName == DocumentXml || fileName == ContentTypesXml {
+		return fmt.Errorf("%s cannot be removed", fileName)
+	}
+	if _, exists := d.partData(fileName); !exists {
+		return fmt.Errorf("file %s not found", fileName)
+	}
+
+	// the relationships are collected before they are removed with the part
+	referencing := make(map[string]map[string]bool)
+	for _, relsPart := range d.relsParts() {
+		source := relsSourcePart(relsPart)
+		if relsPart == relsPartName(fileName) || source == fileName {
+			continue
+		}
+		rels, err := d.packageRelationships(source)
+		if err != nil {
+			return err
+		}
+		for _, rel := range rels.Relationships {
+			if rel.TargetMode != TargetModeExternal && resolveTarget(source, rel.Target) == fileName {
+				if referencing[source] == nil {
+					referencing[source] = make(map[string]bool)
+				}
+				referencing[source][rel.ID] = true
+			}
+		}
+	}
+	if err := d.removePart(fileName); err != nil {
+		return err
+	}
+
+	for source, ids := range referencing {
+		if err := d.removeReferences(source, ids); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeReferences removes the elements of the source part which reference the relationships with the given ids.
+func (d *Document) removeReferences(source string, ids map[string]bool) error {
+	data, exists := d.partData(source)
+	if !exists || !strings.HasSuffix(source, ".xml") {
+		return nil
+	}
+	references := func(element []byte) bool {
+		for _, match := range relationshipAttrRegex.FindAllSubmatch(element, -1) {
+			if ids[string(match[2])] {
+				return true
+			}
+		}
+		return false
+	}
+	result := referenceContainerRegex.ReplaceAllFunc(data, func(container []byte) []byte {
+		if references(container) {
+			return nil
+		}
+		return container
+	})
+	result = referenceElementRegex.ReplaceAllFunc(result, func(element []byte) []byte {
+		if references(element) {
+			return nil
+		}
+		return element
+	})
+	if len(result) == len(data) {
+		return nil
+	}
+
+	if !d.hasFile(source) {
+		d.setPackageFile(source, result)
+		return nil
+	}
+	if err := d.SetFile(source, result); err != nil {
+		return err
+	}
+	if _, parsed := d.runParsers[source]; parsed {
+		return d.refreshRuns(source)
+	}
+	return nil
+}
+
+// RenamePart renames a part of the package, e.g. word/media/image1.png to word/media/logo.png. The relationships
+// targeting the part, its own relationships and its content type are updated, so all references stay valid.
+// Headers, footers and notes are only processed by templates if the new name follows their naming, e.g.
+// word/header3.xml. The main document, [Content_Types].xml and relationship parts cannot be renamed.
+func (d *Document) RenamePart(oldName, newName string) error {
+	for _, name := range []string{oldName, newName} {
+		if name == DocumentXml || name == ContentTypesXml || strings.HasSuffix(name, ".rels") {
+			return fmt.Errorf("%s cannot be renamed", name)
+		}
+	}
+	if newName == "" || strings.HasPrefix(newName, "/") || strings.HasSuffix(newName, "/") {
+		return fmt.Errorf("invalid part name %q", newName)
+	}
+	data, exists := d.partData(oldName)
+	if !exists {
+		return fmt.Errorf("file %s not found", oldName)
+	}
+	if _, exists := d.partData(newName); exists {
+		return fmt.Errorf("file %s already exists", newName)
+	}
+	types, err := d.packageContentTypes()
+	if err != nil {
+		return err
+	}
+	contentType := types.lookup("/" + oldName)
+
+	// the relationships of other parts targeting the part
+	updated := make(map[string]*opcRelationships)
+	for _, relsPart := range d.relsParts() {
+		source := relsSourcePart(relsPart)
+		if source == oldName {
+			continue
+		}
+		rels, err := d.packageRelationships(source)
+		if err != nil {
+			return err
+		}
+		changed := false
+		for i, rel := range rels.Relationships {
+			if rel.TargetMode != TargetModeExternal && resolveTarget(source, rel.Target) == oldName {
+				rels.Relationships[i].Target = renamedTarget(source, rel.Target, newName)
+				changed = true
+			}
+		}
+		if changed {
+			updated[source] = rels
+		}
+	}
+
+	// the own relationships, whose targets are relative to the new name
+	_, hasRels := d.loadPackageFile(relsPartName(oldName))
+	ownRels, err := d.packageRelationships(oldName)
+	if err != nil {
+		return err
+	}
+	for i, rel := range ownRels.Relationships {
+		if rel.TargetMode != TargetModeExternal && !strings.HasPrefix(rel.Target, "/") {
+			target := resolveTarget(oldName, rel.Target)
+			if target == oldName {
+				target = newName
+			}
+			ownRels.Relationships[i].Target = relativeTarget(newName, target)
+		}
+	}
+
+	wasFile := d.hasFile(oldName)
+	types.removeOverride("/" + oldName)
+	if err := d.storeContentTypes(types); err != nil {
+		return err
+	}
+	d.removePackageFile(relsPartName(oldName))
+	d.removePackageFile(oldName)
+	d.forgetFile(oldName)
+
+	if contentType != "" {
+		if err := d.ensureContentType(newName, contentType); err != nil {
+			return err
+		}
+	}
+	switch {
+	case wasFile && MediaPathRegex.MatchString(newName):
+		d.setMediaFile(newName, data)
+	case wasFile:
+		if err := d.addFile(newName, data); err != nil {
+			return err
+		}
+	default:
+		d.setPackageFile(newName, data)
+	}
+	if hasRels {
+		if err := d.storeRelationships(newName, ownRels); err != nil {
+			return err
+		}
+	}
+	for source, rels := range updated {
+		if err := d.storeRelationships(source, rels); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renamedTarget returns the target of a relationship of the source part to the renamed part. Absolute targets
+// stay absolute.
+func renamedTarget(source, target, newName string) string {
+	if strings.HasPrefix(target, "/") {
+		return "/" + newName
+	}
+	return relativeTarget(source, newName)
+}
+
+// Relationship is a relationship from a part to another part or to an external resource, see Relationships.
+type Relationship struct {
+	ID         string // The id referenced by the source part, e.g. rId5
+	Type       string // The relationship type, e.g. ImageRelationshipType
+	Target     string // The target as written in the relationship part, e.g. media/image1.png or a URL
+	TargetMode string // TargetModeExternal for external targets such as hyperlinks, empty otherwise
+	Part       string // The part name of internal targets, e.g. word/media/image1.png, empty for external targets
+}
+
+// External returns true if the target is not a part of the package, e.g. the URL of a hyperlink.
+func (r Relationship) External() bool {
+	return r.TargetMode == TargetModeExternal
+}
+
+// Relationships returns the relationships of the part in the order of its relationship part, e.g. the images,
+// hyperlinks and headers of word/document.xml. The empty part name returns the package relationships
+// (_rels/.rels). Parts without relationships return none.
+func (d *Document) Relationships(part string) ([]Relationship, error) {
+	if _, exists := d.partData(part); part != "" && !exists {
+		return nil, fmt.Errorf("file %s not found", part)
+	}
+	rels, err := d.packageRelationships(part)
+	if err != nil {
+		return nil, err
+	}
+	relationships := make([]Relationship, 0, len(rels.Relationships))
+	for _, rel := range rels.Relationships {
+		relationship := Relationship{ID: rel.ID, Type: rel.Type, Target: rel.Target, TargetMode: rel.TargetMode}
+		if !relationship.External() {
+			relationship.Part = resolveTarget(part, rel.Target)
+		}
+		relationships = append(relationships, relationship)
+	}
+	return relationships, nil
+}
+
+// PartFilter selects the files listed by Files.
+type PartFilter int
+
+const (
+	// MainDocumentFile selects the main document, word/document.xml.
+	MainDocumentFile PartFilter = iota
+	// HeaderFiles selects the headers of all sections, e.g. word/header1.xml.
+	HeaderFiles
+	// FooterFiles selects the footers of all sections, e.g. word/footer1.xml.
+	FooterFiles
+	// NoteFiles selects the footnotes, the endnotes and the comments.
+	NoteFiles
+	// MediaFiles selects the images and other media, e.g. word/media/image1.png, including images stored outside
+	// of word/media.
+	MediaFiles
+)
+
+// Files returns the sorted names of the files the document loaded, i.e. the files which can be read with GetFile
+// and replaced with SetFile, e.g. to find the name of an image to replace. Filters restrict the list to the
+// files selected by any of them, without filters all files are returned. Media files of lazy documents are listed
+// although they are read on first access, see OpenOptions.Lazy.
+func (d *Document) Files(filter ...PartFilter) []string {
+	selected := make(map[PartFilter]bool, len(filter))
+	for _, f := range filter {
+		selected[f] = true
+	}
+	groups := []struct {
+		filter PartFilter
+		files  []string
+	}{
+		{MainDocumentFile, []string{DocumentXml}},
+		{HeaderFiles, d.headerFiles},
+		{FooterFiles, d.footerFiles},
+		{NoteFiles, d.noteFiles},
+		{MediaFiles, d.mediaFiles},
+	}
+
+	var names []string
+	for _, group := range groups {
+		if len(filter) > 0 && !selected[group.filter] {
+			continue
+		}
+		for _, name := range group.files {
+			if d.hasFile(name) {
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,203 @@
+package docx
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDocument_AddFile(t *testing.T) {
+	doc, err := Open("./test/template.docx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := []byte(xmlDeclaration + `<w:hdr ` + wordprocessingNamespaces + `><w:p><w:r><w:t>Offer for {{.name}}</w:t></w:r></w:p></w:hdr>`)
+	id, err := doc.AddFile("word/header9.xml", header, HeaderContentType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id == "" {
+		t.Fatal("expected the id of the relationship to the header")
+	}
+	if id, err := doc.AddFile("word/charts/_rels/chart1.xml.rels", []byte(xmlDeclaration+`<Relationships xmlns="`+relationshipsNamespace+`"/>`),
+		"application/vnd.openxmlformats-package.relationships+xml"); err != nil || id != "" {
+		t.Fatalf("unexpected result %q, %v", id, err)
+	}
+	if _, err := doc.AddFile("word/header9.xml", header, HeaderContentType); err == nil {
+		t.Error("expected an error for an existing part")
+	}
+	if _, err := doc.AddFile("word/unknown.bin", nil, ""); err == nil {
+		t.Error("expected an error for a missing content type")
+	}
+	if err := doc.ExecuteTemplate(map[string]interface{}{"name": "ACME"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := doc.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	doc.Close()
+	out, err := OpenBytes(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	if !strings.Contains(string(out.GetFile("word/header9.xml")), "Offer for ACME") {
+		t.Errorf("header was not processed: %s", out.GetFile("word/header9.xml"))
+	}
+	if types, _ := out.packageContentTypes(); types.lookup("/word/header9.xml") != HeaderContentType {
+		t.Error("missing content type of the header")
+	}
+	if targets := out.relationshipTargets(DocumentXml, HeaderRelationshipType); !strings.Contains(strings.Join(targets, " "), "word/header9.xml") {
+		t.Errorf("missing relationship to the header, got %v", targets)
+	}
+	if !archiveContains(t, buf.Bytes(), "word/charts/_rels/chart1.xml.rels") {
+		t.Error("missing relationship part of the chart")
+	}
+}
+
+func TestDocument_RemoveFile(t *testing.T) {
+	doc, err := Open("./test/template.docx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, part := range []string{"word/footer1.xml", "word/media/image1.jpg"} {
+		if err := doc.RemoveFile(part); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := doc.RemoveFile("word/footer1.xml"); err == nil {
+		t.Error("expected an error for a removed part")
+	}
+	if err := doc.RemoveFile(DocumentXml); err == nil {
+		t.Error("expected an error for the main document")
+	}
+	if err := doc.ExecuteTemplate(map[string]interface{}{}); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := doc.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	doc.Close()
+	for _, part := range []string{"word/footer1.xml", "word/media/image1.jpg"} {
+		if archiveContains(t, buf.Bytes(), part) {
+			t.Errorf("removed part %s was written", part)
+		}
+	}
+	out, err := OpenBytes(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	documentXml := string(out.GetFile(DocumentXml))
+	if strings.Contains(documentXml, "<w:footerReference") || strings.Contains(documentXml, `r:embed="rId6"`) {
+		t.Error("references to the removed parts were not removed")
+	}
+	if !strings.Contains(documentXml, "<w:headerReference") {
+		t.Error("the header reference was removed")
+	}
+}
+
+func TestDocument_RenamePart(t *testing.T) {
+	doc, err := Open("./test/template.docx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.RenamePart("word/media/image1.jpg", "media/logo.jpg"); err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.RenamePart("word/header1.xml", "word/header5.xml"); err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.RenamePart("word/footer1.xml", "word/header5.xml"); err == nil {
+		t.Error("expected an error for an existing part")
+	}
+	if err := doc.RenamePart(DocumentXml, "word/main.xml"); err == nil {
+		t.Error("expected an error for the main document")
+	}
+	if doc.GetFile("word/header5.xml") == nil {
+		t.Error("the renamed header is not processed")
+	}
+
+	var buf bytes.Buffer
+	if err := doc.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	doc.Close()
+	if archiveContains(t, buf.Bytes(), "word/media/image1.jpg") || !archiveContains(t, buf.Bytes(), "media/logo.jpg") {
+		t.Error("the image was not renamed")
+	}
+	out, err := OpenBytes(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	if targets := out.relationshipTargets(DocumentXml, ImageRelationshipType); len(targets) != 1 || targets[0] != "media/logo.jpg" {
+		t.Errorf("unexpected image targets %v", targets)
+	}
+	if !strings.Contains(string(out.packageFiles[DocumentRelsXml]), `Target="../media/logo.jpg"`) {
+		t.Errorf("unexpected relationships %s", out.packageFiles[DocumentRelsXml])
+	}
+	if types, _ := out.packageContentTypes(); types.lookup("/word/header5.xml") != HeaderContentType || types.lookup("/word/header1.xml") == HeaderContentType {
+		t.Error("the content type of the header was not renamed")
+	}
+}
+
+func TestDocument_Relationships(t *testing.T) {
+	doc, err := Open("./test/template.docx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+	relationships, err := doc.Relationships(DocumentXml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(relationships) != 10 {
+		t.Fatalf("expected 10 relationships, got %d", len(relationships))
+	}
+	expected := Relationship{ID: "rId6", Type: ImageRelationshipType, Target: "media/image1.jpg", Part: "word/media/image1.jpg"}
+	if relationships[5] != expected || relationships[5].External() {
+		t.Errorf("expected %+v, got %+v", expected, relationships[5])
+	}
+
+	if relationships, err := doc.Relationships(""); err != nil || len(relationships) == 0 || relationships[0].Part == "" {
+		t.Errorf("unexpected package relationships %+v, %v", relationships, err)
+	}
+	if relationships, err := doc.Relationships("word/header1.xml"); err != nil || len(relationships) != 0 {
+		t.Errorf("expected no relationships, got %+v, %v", relationships, err)
+	}
+	if _, err := doc.Relationships("word/missing.xml"); err == nil {
+		t.Error("expected an error for a missing part")
+	}
+}
+
+func TestDocument_Files(t *testing.T) {
+	doc, err := OpenWithOptions("./test/template.docx", OpenOptions{Lazy: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+
+	if files := doc.Files(); strings.Join(files, ",") != "word/document.xml,word/endnotes.xml,word/footer1.xml,"+
+		"word/footnotes.xml,word/header1.xml,word/media/image1.jpg" {
+		t.Errorf("unexpected files %q", files)
+	}
+	if files := doc.Files(HeaderFiles, FooterFiles); strings.Join(files, ",") != "word/footer1.xml,word/header1.xml" {
+		t.Errorf("unexpected headers and footers %q", files)
+	}
+	media := doc.Files(MediaFiles)
+	if len(media) != 1 || doc.GetFile(media[0]) == nil {
+		t.Fatalf("expected the lazy image to be listed and readable, got %q", media)
+	}
+
+	if err := doc.RemoveFile("word/footer1.xml"); err != nil {
+		t.Fatal(err)
+	}
+	if files := doc.Files(FooterFiles); len(files) != 0 {
+		t.Errorf("expected the removed footer not to be listed, got %q", files)
+	}
+}
@@ -0,0 +1,56 @@
+package docx
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDocument_ExecuteTemplatePasses(t *testing.T) {
+	archive := rewriteArchive(t, readFile(t, "./test/template.docx"), func(name string, data []byte) []byte {
+		if name == DocumentXml {
+			return []byte(strings.Replace(string(data), "<w:body>", `<w:body><w:p><w:r><w:t>{{.greeting}}</w:t></w:r></w:p>`, 1))
+		}
+		return data
+	}, nil)
+	data := map[string]interface{}{
+		"greeting": `Dear {{.name}}, {{if eq .status "open"}}please pay{{end}}`,
+		"name":     "Anna",
+		"status":   "open",
+	}
+
+	tests := []struct {
+		passes   int
+		expected string
+	}{
+		{1, `<w:t>Dear {{.name}}, {{if eq .status "open"}}please pay{{end}}</w:t>`},
+		{2, `<w:t>Dear Anna, please pay</w:t>`},
+		{5, `<w:t>Dear Anna, please pay</w:t>`},
+	}
+	for _, test := range tests {
+		logger := &testLogger{}
+		doc, err := OpenBytes(archive, WithLogger(logger))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := doc.ExecuteTemplatePasses(data, test.passes); err != nil {
+			t.Fatal(err)
+		}
+		if documentXml := string(doc.GetFile(DocumentXml)); !strings.Contains(documentXml, test.expected) {
+			t.Errorf("%d passes: expected %s", test.passes, test.expected)
+		}
+		stopped := strings.Contains(strings.Join(logger.messages, "\n"), "Pass 3 left the document unchanged")
+		if stopped != (test.passes == 5) {
+			t.Errorf("%d passes: unexpected early stop %t", test.passes, stopped)
+		}
+		doc.Close()
+	}
+
+	doc, err := OpenBytes(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+	if err := doc.ExecuteTemplatePasses(data, 0); err == nil {
+		t.Errorf("expected an error for 0 passes")
+	}
+}
@@ -0,0 +1,210 @@
+package docx
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"image"
+	"image/gif"
+	"image/png"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// pdfa.go implements CheckPDFA, which reports the features of a document that break its conversion to PDF/A, so
+// archival pipelines fail before the converter silently substitutes fonts or drops content.
+
+// ThemeRelationshipType is the relationship type of the theme of the main document part (word/theme/theme1.xml).
+const ThemeRelationshipType = "http://schemas.openxmlformats.org/officeDocument/2006/relationships/theme"
+
+var (
+	// runFontsRegex matches the fonts of run properties.
+	runFontsRegex = regexp.MustCompile(`<w:rFonts\b[^>]*/>`)
+	// symbolFontRegex matches the font of a symbol character.
+	symbolFontRegex = regexp.MustCompile(`<w:sym\b[^>]*\sw:font="([^"]*)"`)
+	// fontTableEntryRegex matches a font of the font table, the first group is its name.
+	fontTableEntryRegex = regexp.MustCompile(`(?s)<w:font\s+w:name="([^"]*)"\s*(?:/>|>.*?</w:font>)`)
+	// embeddedFontRegex matches a font embedded into the font table.
+	embeddedFontRegex = regexp.MustCompile(`<w:embed(?:Regular|Bold|Italic|BoldItalic)\b`)
+	// themeFontRegex matches the Latin font of the major or minor fonts of a theme.
+	themeFontRegex = regexp.MustCompile(`(?s)<a:(major|minor)Font>\s*<a:latin\s+typeface="([^"]*)"`)
+	// alphaRegex matches the transparency effects of DrawingML.
+	alphaRegex = regexp.MustCompile(`<a:(?:alphaModFix|alpha)\b`)
+)
+
+// PDFAPolicy controls the checks of CheckPDFA.
+type PDFAPolicy struct {
+	// AvailableFonts are the fonts installed on the converter; all other fonts must be embedded, see EmbedFont.
+	AvailableFonts []string
+	// AllowTransparency permits transparent images, which PDF/A-2 and PDF/A-3 support but PDF/A-1 does not.
+	AllowTransparency bool
+	// AllowExternalHyperlinks permits hyperlinks to web sites and other documents.
+	AllowExternalHyperlinks bool
+	// RequireMetadata requires a title and a language, e.g. for the accessible levels PDF/A-2a and PDF/A-3a.
+	RequireMetadata bool
+}
+
+// PDFAIssue is a feature of the document which breaks its conversion to PDF/A.
+type PDFAIssue struct {
+	Part    string // The part containing the feature, e.g. word/document.xml or word/media/image1.png
+	Message string
+}
+
+// String returns the issue with its part, e.g. word/media/image1.png: the image is transparent
+func (i PDFAIssue) String() string {
+	return i.Part + ": " + i.Message
+}
+
+// PDFAError is returned by CheckPDFA if the document is not suitable for PDF/A.
+type PDFAError struct {
+	Issues []PDFAIssue
+}
+
+// Error implements the error interface.
+func (e *PDFAError) Error() string {
+	messages := make([]string, len(e.Issues))
+	for i, issue := range e.Issues {
+		messages[i] = issue.String()
+	}
+	return "not suitable for PDF/A: " + strings.Join(messages, "; ")
+}
+
+// CheckPDFA checks the document before it is handed to a PDF converter for archiving and returns a *PDFAError
+// listing every feature which breaks the conversion to PDF/A:
+//   - fonts which are neither embedded into the document nor available to the converter,
+//   - transparent images and drawings, unless the policy allows transparency,
+//   - content which is linked instead of embedded, e.g. linked images,
+//   - hyperlinks to external targets, unless the policy allows them, and
+//   - a missing title or language, if the policy requires metadata.
+//
+// The fonts of the body, headers, footers, notes, styles and numbering definitions are checked, including the
+// fonts of the theme they refer to.
+func (d *Document) CheckPDFA(policy PDFAPolicy) error {
+	var issues []PDFAIssue
+	issues = append(issues, d.pdfaFontIssues(policy.AvailableFonts)...)
+	issues = append(issues, d.pdfaContentIssues(policy)...)
+	if policy.RequireMetadata {
+		if strings.TrimSpace(d.documentProperties()["title"]) == "" {
+			issues = append(issues, PDFAIssue{Part: "docProps/core.xml", Message: "the document has no title"})
+		}
+		if d.Language() == "" {
+			issues = append(issues, PDFAIssue{Part: StylesXml, Message: "the document has no language, see SetLanguage"})
+		}
+	}
+	if len(issues) > 0 {
+		sort.SliceStable(issues, func(i, j int) bool { return issues[i].Part < issues[j].Part })
+		return &PDFAError{Issues: issues}
+	}
+	return nil
+}
+
+// pdfaFontIssues reports the fonts used by the document which are neither embedded nor available.
+func (d *Document) pdfaFontIssues(available []string) []PDFAIssue {
+	provided := make(map[string]bool)
+	for _, font := range available {
+		provided[strings.ToLower(font)] = true
+	}
+	for _, fontTable := range d.relationshipTargets(DocumentXml, FontTableRelationshipType) {
+		fonts, _ := d.partData(fontTable)
+		for _, match := range fontTableEntryRegex.FindAllSubmatch(fonts, -1) {
+			if embeddedFontRegex.Match(match[0]) {
+				provided[strings.ToLower(html.UnescapeString(string(match[1])))] = true
+			}
+		}
+	}
+
+	themeFonts := make(map[string]string) // major or minor => typeface
+	for _, theme := range d.relationshipTargets(DocumentXml, ThemeRelationshipType) {
+		data, _ := d.partData(theme)
+		for _, match := range themeFontRegex.FindAllSubmatch(data, -1) {
+			themeFonts[string(match[1])] = html.UnescapeString(string(match[2]))
+		}
+	}
+
+	parts := append(d.xmlParts(), StylesXml)
+	parts = append(parts, d.relationshipTargets(DocumentXml, NumberingRelationshipType)...)
+	var issues []PDFAIssue
+	reported := make(map[string]bool)
+	for _, part := range parts {
+		data, _ := d.partData(part)
+		var fonts []string
+		for _, tag := range runFontsRegex.FindAll(data, -1) {
+			for _, attr := range []string{"w:ascii", "w:hAnsi", "w:eastAsia", "w:cs"} {
+				fonts = append(fonts, attrOf(tag, attr))
+			}
+			for _, attr := range []string{"w:asciiTheme", "w:hAnsiTheme"} {
+				if theme := attrOf(tag, attr); strings.HasPrefix(theme, "major") {
+					fonts = append(fonts, themeFonts["major"])
+				} else if strings.HasPrefix(theme, "minor") {
+					fonts = append(fonts, themeFonts["minor"])
+				}
+			}
+		}
+		for _, match := range symbolFontRegex.FindAllSubmatch(data, -1) {
+			fonts = append(fonts, html.UnescapeString(string(match[1])))
+		}
+		for _, font := range fonts {
+			if font == "" || provided[strings.ToLower(font)] || reported[strings.ToLower(font)] {
+				continue
+			}
+			reported[strings.ToLower(font)] = true
+			issues = append(issues, PDFAIssue{Part: part, Message: fmt.Sprintf("the font %q is neither embedded nor available to the converter", font)})
+		}
+	}
+	return issues
+}
+
+// pdfaContentIssues reports transparent images and drawings, linked content and external hyperlinks.
+func (d *Document) pdfaContentIssues(policy PDFAPolicy) []PDFAIssue {
+	var issues []PDFAIssue
+	checked := make(map[string]bool)
+	for _, part := range d.xmlParts() {
+		if !policy.AllowTransparency && alphaRegex.Match(d.GetFile(part)) {
+			issues = append(issues, PDFAIssue{Part: part, Message: "a drawing is transparent"})
+		}
+		rels, err := d.packageRelationships(part)
+		if err != nil {
+			continue
+		}
+		for _, rel := range rels.Relationships {
+			switch {
+			case rel.TargetMode == TargetModeExternal && rel.Type == HyperlinkRelationshipType:
+				if !policy.AllowExternalHyperlinks {
+					issues = append(issues, PDFAIssue{Part: part, Message: fmt.Sprintf("the hyperlink to %s is external", rel.Target)})
+				}
+			case rel.TargetMode == TargetModeExternal:
+				issues = append(issues, PDFAIssue{Part: part, Message: fmt.Sprintf("the content %s is linked instead of embedded", rel.Target)})
+			case rel.Type == ImageRelationshipType && !policy.AllowTransparency:
+				target := resolveTarget(part, rel.Target)
+				if checked[target] {
+					continue
+				}
+				checked[target] = true
+				if data, _ := d.partData(target); transparentImage(data) {
+					issues = append(issues, PDFAIssue{Part: target, Message: "the image is transparent"})
+				}
+			}
+		}
+	}
+	return issues
+}
+
+// transparentImage reports whether the PNG or GIF image has transparent pixels. Other formats are opaque.
+func transparentImage(data []byte) bool {
+	var img image.Image
+	var err error
+	switch {
+	case bytes.HasPrefix(data, []byte("\x89PNG")):
+		img, err = png.Decode(bytes.NewReader(data))
+	case bytes.HasPrefix(data, []byte("GIF8")):
+		img, err = gif.Decode(bytes.NewReader(data))
+	default:
+		return false
+	}
+	if err != nil {
+		return false
+	}
+	opaque, ok := img.(interface{ Opaque() bool })
+	return ok && !opaque.Opaque()
+}
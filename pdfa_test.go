@@ -0,0 +1,75 @@
+package docx
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func TestDocument_CheckPDFA(t *testing.T) {
+	doc, err := Open("./test/template.docx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+
+	policy := PDFAPolicy{AvailableFonts: []string{"Times New Roman", "arial", "Courier-PS"}, RequireMetadata: true}
+	var pdfaErr *PDFAError
+	if err := doc.CheckPDFA(policy); !errors.As(err, &pdfaErr) || len(pdfaErr.Issues) != 2 ||
+		pdfaErr.Issues[0].String() != "docProps/core.xml: the document has no title" ||
+		pdfaErr.Issues[1].String() != `word/document.xml: the font "Lato" is neither embedded nor available to the converter` {
+		t.Fatalf("unexpected result %v", err)
+	}
+
+	// embedded fonts need not be available
+	if err := doc.EmbedFont(testFont(0, 0), "Lato"); err != nil {
+		t.Fatal(err)
+	}
+	policy.RequireMetadata = false
+	if err := doc.CheckPDFA(policy); err != nil {
+		t.Errorf("unexpected issues %v", err)
+	}
+}
+
+func TestDocument_CheckPDFAContent(t *testing.T) {
+	transparent := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	transparent.SetNRGBA(1, 1, color.NRGBA{R: 0xFF, A: 0x80})
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, transparent); err != nil {
+		t.Fatal(err)
+	}
+	doc, err := NewDocument().Image(Image{Data: buf.Bytes(), Width: 4, Height: 4}).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+	rels, err := doc.packageRelationships(DocumentXml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rels.add(HyperlinkRelationshipType, "https://example.com", TargetModeExternal)
+	rels.add(ImageRelationshipType, "file:///C:/logo.png", TargetModeExternal)
+	if err := doc.storeRelationships(DocumentXml, rels); err != nil {
+		t.Fatal(err)
+	}
+
+	policy := PDFAPolicy{AvailableFonts: []string{"Calibri"}}
+	err = doc.CheckPDFA(policy)
+	for _, expected := range []string{"word/document.xml: the hyperlink to https://example.com is external",
+		"word/document.xml: the content file:///C:/logo.png is linked instead of embedded",
+		"word/media/image1.png: the image is transparent"} {
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected the issue %q, got %v", expected, err)
+		}
+	}
+
+	policy.AllowExternalHyperlinks, policy.AllowTransparency = true, true
+	if err := doc.CheckPDFA(policy); err == nil || strings.Contains(err.Error(), "hyperlink") || strings.Contains(err.Error(), "transparent") ||
+		!strings.Contains(err.Error(), "linked instead of embedded") {
+		t.Errorf("linked content must be reported regardless of the policy, got %v", err)
+	}
+}
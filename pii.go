@@ -0,0 +1,138 @@
+package docx
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// pii.go implements ScanPII, which finds personal data in the text of a rendered document, so it can be reviewed
+// before the document is distributed.
+
+// Detector finds one kind of personal data in the text of paragraphs.
+type Detector struct {
+	Name    string         // The name reported with the findings, e.g. email
+	Pattern *regexp.Regexp // Matches the candidates
+	// Validate rejects candidates which match the pattern but are no personal data, e.g. because the check
+	// digits of an IBAN are wrong. All candidates are reported if Validate is nil.
+	Validate func(match string) bool
+}
+
+var (
+	// EmailDetector finds e-mail addresses.
+	EmailDetector = Detector{
+		Name:    "email",
+		Pattern: regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}\b`),
+	}
+	// IBANDetector finds international bank account numbers with or without spaces, validating their check digits.
+	IBANDetector = Detector{
+		Name:     "iban",
+		Pattern:  regexp.MustCompile(`\b[A-Z]{2}\d{2}(?: ?[A-Z0-9]{4}){2,7}(?: ?[A-Z0-9]{1,3})?\b`),
+		Validate: validIBAN,
+	}
+	// SSNDetector finds US social security numbers like 123-45-6789, skipping numbers which are never issued.
+	SSNDetector = Detector{
+		Name:     "ssn",
+		Pattern:  regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`),
+		Validate: validSSN,
+	}
+	// CreditCardDetector finds payment card numbers of 13 to 19 digits, validating their Luhn check digit.
+	CreditCardDetector = Detector{
+		Name:     "credit-card",
+		Pattern:  regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`),
+		Validate: validLuhn,
+	}
+)
+
+// Finding is personal data found by ScanPII.
+type Finding struct {
+	Detector  string // The name of the detector which found the data
+	Part      string // The part containing the data, e.g. word/document.xml or word/footer1.xml
+	Paragraph int    // The number of the paragraph inside the part, starting with 1
+	Match     string // The text which was found
+}
+
+// String returns the finding with its location, e.g. word/document.xml, paragraph 3: email "anna@example.com"
+func (f Finding) String() string {
+	return fmt.Sprintf("%s, paragraph %d: %s %q", f.Part, f.Paragraph, f.Detector, f.Match)
+}
+
+// ScanPII finds personal data in the text of the body, headers, footers, notes and comments, e.g. to review a
+// rendered document before it is distributed. Without detectors, e-mail addresses, IBANs, social security numbers
+// and credit card numbers are found. The findings are sorted by part and paragraph.
+func (d *Document) ScanPII(detectors ...Detector) []Finding {
+	if len(detectors) == 0 {
+		detectors = []Detector{EmailDetector, IBANDetector, SSNDetector, CreditCardDetector}
+	}
+	var findings []Finding
+	for _, part := range d.xmlParts() {
+		data, exists := d.partData(part)
+		if !exists {
+			continue
+		}
+		texts, err := paragraphTexts(data)
+		if err != nil {
+			continue
+		}
+		for i, text := range texts {
+			for _, detector := range detectors {
+				for _, match := range detector.Pattern.FindAllString(text, -1) {
+					if detector.Validate != nil && !detector.Validate(match) {
+						continue
+					}
+					findings = append(findings, Finding{Detector: detector.Name, Part: part, Paragraph: i + 1, Match: match})
+				}
+			}
+		}
+	}
+	sort.SliceStable(findings, func(i, j int) bool { return findings[i].Part < findings[j].Part })
+	return findings
+}
+
+// validIBAN checks the length and the check digits (ISO 7064 MOD 97-10) of an IBAN.
+func validIBAN(match string) bool {
+	iban := strings.ReplaceAll(match, " ", "")
+	if len(iban) < 15 || len(iban) > 34 {
+		return false
+	}
+	// the country code and the check digits are moved to the end, letters count as 10 to 35
+	var digits strings.Builder
+	for _, r := range iban[4:] + iban[:4] {
+		if r >= 'A' && r <= 'Z' {
+			digits.WriteString(fmt.Sprint(r - 'A' + 10))
+		} else {
+			digits.WriteRune(r)
+		}
+	}
+	number, ok := new(big.Int).SetString(digits.String(), 10)
+	return ok && new(big.Int).Mod(number, big.NewInt(97)).Int64() == 1
+}
+
+// validSSN rejects social security numbers with an area, group or serial number which is never issued.
+func validSSN(match string) bool {
+	area, group, serial := match[0:3], match[4:6], match[7:11]
+	return area != "000" && area != "666" && area[0] != '9' && group != "00" && serial != "0000"
+}
+
+// validLuhn checks the Luhn check digit of a card number, ignoring spaces and dashes.
+func validLuhn(match string) bool {
+	sum := 0
+	double := false
+	for i := len(match) - 1; i >= 0; i-- {
+		if match[i] < '0' || match[i] > '9' {
+			continue
+		}
+		digit := int(match[i] - '0')
+		if double {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+		double = !double
+	}
+	return sum%10 == 0
+}
@@ -0,0 +1,44 @@
+package docx
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestDocument_ScanPII(t *testing.T) {
+	doc, err := OpenBytes(rewriteArchive(t, readFile(t, "./test/template.docx"), func(name string, data []byte) []byte {
+		if name == DocumentXml {
+			return []byte(strings.Replace(string(data), "<w:body>", `<w:body>`+
+				`<w:p><w:r><w:t>Contact anna.meier@example.com or pay to </w:t></w:r><w:r><w:t>DE89 3704 0044 0532 0130 00</w:t></w:r></w:p>`+
+				`<w:p><w:r><w:t>Not an IBAN: DE00 3704 0044 0532 0130 00, SSN 123-45-6789, invalid SSN 666-12-3456</w:t></w:r></w:p>`+
+				`<w:p><w:r><w:t>Card 4111 1111 1111 1111, order 1234567890123, employee E-12345</w:t></w:r></w:p>`, 1))
+		}
+		return data
+	}, map[string][]byte{CommentsXml: []byte(`<w:comments xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">` +
+		`<w:comment w:id="0"><w:p><w:r><w:t>Ask bob@example.org</w:t></w:r></w:p></w:comment></w:comments>`)}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+
+	findings := doc.ScanPII()
+	expected := []string{`word/comments.xml, paragraph 1: email "bob@example.org"`,
+		`word/document.xml, paragraph 1: email "anna.meier@example.com"`,
+		`word/document.xml, paragraph 1: iban "DE89 3704 0044 0532 0130 00"`,
+		`word/document.xml, paragraph 2: ssn "123-45-6789"`,
+		`word/document.xml, paragraph 3: credit-card "4111 1111 1111 1111"`}
+	if len(findings) != len(expected) {
+		t.Fatalf("expected %d findings, got %v", len(expected), findings)
+	}
+	for i, finding := range findings {
+		if finding.String() != expected[i] {
+			t.Errorf("expected %s, got %s", expected[i], finding)
+		}
+	}
+
+	employee := Detector{Name: "employee-id", Pattern: regexp.MustCompile(`\bE-\d{5}\b`)}
+	if findings := doc.ScanPII(employee); len(findings) != 1 || findings[0].Match != "E-12345" || findings[0].Paragraph != 3 {
+		t.Errorf("unexpected findings of a custom detector %v", findings)
+	}
+}
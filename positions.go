@@ -0,0 +1,124 @@
+package docx
+
+import (
+	"sort"
+	"strings"
+)
+
+// positions.go implements the position map of the placeholders, which relates the fields of the data to the
+// paragraphs and the estimated pages they appear on and back, e.g. for preview UIs highlighting the location of a
+// field while it is edited.
+
+// PlaceholderPosition locates a placeholder of the template or a value rendered from it, see PlaceholderPositions.
+type PlaceholderPosition struct {
+	Part        string `json:"part"`               // The part containing the placeholder, e.g. word/document.xml
+	Placeholder string `json:"placeholder"`        // The placeholder, e.g. {{.customer.name}}
+	Field       string `json:"field,omitempty"`    // The field the placeholder references, e.g. customer.name
+	Type        string `json:"type,omitempty"`     // The type declared by an annotation or by SetFieldTypes
+	Bookmark    string `json:"bookmark,omitempty"` // The bookmark of a rendered value, see SetBookmarkValues
+	// Paragraph is the number of the paragraph inside the part in document order, starting with 1. Paragraphs
+	// nested in tables and text boxes are counted where they start.
+	Paragraph int `json:"paragraph"`
+	// Page is the page of the body the paragraph is estimated to appear on, counting the page breaks and the
+	// sections starting on a new page before it. It is 0 for headers, footers, notes and comments.
+	Page int    `json:"page"`
+	Text string `json:"text"` // The text of the paragraph, so the placeholder can be found in a preview
+}
+
+// PositionMap is the position map of the placeholders of a document in document order, see PlaceholderPositions.
+// It is encoded as a JSON array, e.g. for preview UIs.
+type PositionMap []PlaceholderPosition
+
+// PlaceholderPositions returns the positions of the placeholders of all parts and of the values rendered with
+// SetBookmarkValues, so external previews can highlight where a field lands in the document. The pages are
+// estimates, since the layout is only known to Word.
+func (d *Document) PlaceholderPositions() (PositionMap, error) {
+	// located is a position with the offset of its placeholder or bookmark in the part
+	type located struct {
+		offset   int64
+		position PlaceholderPosition
+	}
+	var positions PositionMap
+	for _, part := range d.xmlParts() {
+		data := d.GetFile(part)
+		paragraphs, err := findBlockParagraphs(data)
+		if err != nil {
+			return nil, err
+		}
+		// locate returns the position of the innermost paragraph containing the offset
+		locate := func(info PlaceholderInfo, offset int64) PlaceholderPosition {
+			position := PlaceholderPosition{Part: part, Placeholder: info.Placeholder, Field: info.Field, Type: info.Type}
+			for i, paragraph := range paragraphs {
+				if paragraph.Start <= offset && offset < paragraph.End {
+					position.Paragraph = i + 1
+					position.Text = strings.TrimSpace(paragraph.Text)
+				}
+			}
+			if part == DocumentXml {
+				position.Page = 1 + countPageBreaks(data[:offset])
+			}
+			return position
+		}
+
+		var partPositions []located
+		placeholders, err := ParseTemplatePlaceholders(d.runParsers[part].Runs(), data, part)
+		if err != nil {
+			return nil, err
+		}
+		for _, placeholder := range placeholders {
+			offset := placeholder.Placeholder.StartPos()
+			info := d.templateReplacer.placeholderInfo(part, placeholder.TemplateContent)
+			partPositions = append(partPositions, located{offset, locate(info, offset)})
+		}
+		for _, loc := range bookmarkStartRegex.FindAllIndex(data, -1) {
+			name := attrOf(data[loc[0]:loc[1]], "w:name")
+			action, exists := d.templateReplacer.renderedValues[name]
+			if !exists {
+				continue
+			}
+			position := locate(d.templateReplacer.placeholderInfo(part, action), int64(loc[0]))
+			position.Bookmark = name
+			partPositions = append(partPositions, located{int64(loc[0]), position})
+		}
+
+		sort.SliceStable(partPositions, func(i, j int) bool { return partPositions[i].offset < partPositions[j].offset })
+		for _, partPosition := range partPositions {
+			positions = append(positions, partPosition.position)
+		}
+	}
+	return positions, nil
+}
+
+// Field returns the positions of the placeholders referencing the field, e.g. customer.name.
+func (m PositionMap) Field(field string) []PlaceholderPosition {
+	var positions []PlaceholderPosition
+	for _, position := range m {
+		if position.Field == field {
+			positions = append(positions, position)
+		}
+	}
+	return positions
+}
+
+// Paragraph returns the positions of the placeholders inside the paragraph of the part, e.g. to show the fields of
+// the paragraph a user selected in a preview.
+func (m PositionMap) Paragraph(part string, paragraph int) []PlaceholderPosition {
+	var positions []PlaceholderPosition
+	for _, position := range m {
+		if position.Part == part && position.Paragraph == paragraph {
+			positions = append(positions, position)
+		}
+	}
+	return positions
+}
+
+// Page returns the positions of the placeholders of the body estimated to appear on the page.
+func (m PositionMap) Page(page int) []PlaceholderPosition {
+	var positions []PlaceholderPosition
+	for _, position := range m {
+		if position.Page == page {
+			positions = append(positions, position)
+		}
+	}
+	return positions
+}
@@ -0,0 +1,70 @@
+package docx
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestDocument_PlaceholderPositions(t *testing.T) {
+	body := `<w:body>` +
+		`<w:p><w:r><w:t>Dear {{.customer.name}},</w:t></w:r></w:p>` +
+		`<w:tbl><w:tr><w:tc><w:p><w:r><w:t>Total: {{.total /*type:money*/}}</w:t></w:r></w:p></w:tc></w:tr></w:tbl>` +
+		`<w:p><w:r><w:br w:type="page"/></w:r></w:p>` +
+		`<w:p><w:r><w:t>Signed by {{.customer.name}}</w:t></w:r></w:p>`
+	template := rewriteArchive(t, readFile(t, "./test/template.docx"), func(name string, data []byte) []byte {
+		if name == DocumentXml {
+			return []byte(strings.Replace(string(data), "<w:body>", body, 1))
+		}
+		return data
+	}, nil)
+	doc, err := OpenBytes(template, WithBookmarkValues())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+
+	positions, err := doc.PlaceholderPositions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []PlaceholderPosition{
+		{Part: DocumentXml, Placeholder: "{{.customer.name}}", Field: "customer.name", Paragraph: 1, Page: 1, Text: "Dear {{.customer.name}},"},
+		{Part: DocumentXml, Placeholder: "{{.total /*type:money*/}}", Field: "total", Type: "money", Paragraph: 2, Page: 1, Text: "Total: {{.total /*type:money*/}}"},
+		{Part: DocumentXml, Placeholder: "{{.customer.name}}", Field: "customer.name", Paragraph: 4, Page: 2, Text: "Signed by {{.customer.name}}"},
+	}
+	if len(positions) < len(expected) {
+		t.Fatalf("expected at least %d positions, got %v", len(expected), positions)
+	}
+	for i, position := range expected {
+		if positions[i] != position {
+			t.Errorf("expected the position %+v, got %+v", position, positions[i])
+		}
+	}
+	if names := positions.Field("customer.name"); len(names) != 2 || names[1].Page != 2 {
+		t.Errorf("expected the field on pages 1 and 2, got %v", names)
+	}
+	if fields := positions.Paragraph(DocumentXml, 2); len(fields) != 1 || fields[0].Field != "total" {
+		t.Errorf("expected the total in paragraph 2, got %v", fields)
+	}
+	if onPage := positions.Page(2); len(onPage) != 1 || onPage[0].Placeholder != "{{.customer.name}}" {
+		t.Errorf("expected the signature on page 2, got %v", onPage)
+	}
+	encoded, err := json.Marshal(positions[:1])
+	if err != nil || !strings.Contains(string(encoded), `"field":"customer.name","paragraph":1,"page":1`) {
+		t.Errorf("unexpected JSON %s (%v)", encoded, err)
+	}
+
+	// the values rendered with bookmarks are located by their bookmarks
+	if err := doc.ExecuteTemplate(map[string]interface{}{"customer": map[string]interface{}{"name": "Anna"}, "total": "120.00"}); err != nil {
+		t.Fatal(err)
+	}
+	positions, err = doc.PlaceholderPositions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := positions.Field("customer.name")
+	if len(names) != 2 || names[0].Bookmark == "" || names[0].Text != "Dear Anna," || names[1].Page != 2 || names[1].Text != "Signed by Anna" {
+		t.Errorf("expected the rendered names in paragraphs 1 and 4, got %+v", names)
+	}
+}
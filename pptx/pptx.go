@@ -0,0 +1,141 @@
+// Package pptx fills PowerPoint presentations (.pptx and .potx files) with the placeholder syntax of the docx
+// package, so one templating codebase serves documents, presentations and spreadsheets.
+//
+// The placeholders of the slides and of their speaker notes are evaluated like those of a docx document: with
+// text/template by default or with an alternative templating.Engine. Placeholders may be split across runs with
+// different formatting, the result takes the formatting of the run the placeholder starts in. Control structures
+// like {{if}} are supported within a paragraph; repeating slides, paragraphs or table rows is not.
+package pptx
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/template"
+
+	"github.com/izetmolla/docx"
+	"github.com/izetmolla/docx/internal/zipxml"
+	"github.com/izetmolla/docx/templating"
+)
+
+const (
+	// PresentationXml is the main part of a presentation, which lists its slides.
+	PresentationXml = "ppt/presentation.xml"
+	// SlidesPrefix is the common prefix of the slide parts, e.g. ppt/slides/slide1.xml.
+	SlidesPrefix = "ppt/slides/slide"
+	// NotesSlidesPrefix is the common prefix of the speaker notes parts, e.g. ppt/notesSlides/notesSlide1.xml.
+	NotesSlidesPrefix = "ppt/notesSlides/notesSlide"
+)
+
+var _ docx.TemplateDocument = (*Presentation)(nil)
+
+// syntax describes the paragraphs of DrawingML text, whose text is held by the a:t elements of their runs.
+var syntax = zipxml.Syntax{
+	Paragraphs: []string{"a:p"},
+	Text:       "a:t",
+	Breaks:     map[string]bool{"a:br": true, "a:fld": true},
+}
+
+// Presentation is a PowerPoint presentation which is filled like a docx document.
+type Presentation struct {
+	path     string
+	pkg      *zipxml.Package
+	template zipxml.Template
+}
+
+// Open opens the presentation or presentation template at the path.
+func Open(path string) (*Presentation, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	presentation, err := OpenBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	presentation.path = path
+	return presentation, nil
+}
+
+// OpenBytes opens the presentation or presentation template in memory.
+func OpenBytes(data []byte) (*Presentation, error) {
+	pkg, err := zipxml.ReadPackage(data)
+	if err != nil {
+		return nil, err
+	}
+	if pkg.GetFile(PresentationXml) == nil {
+		return nil, fmt.Errorf("not a presentation: %s is missing", PresentationXml)
+	}
+	return &Presentation{pkg: pkg}, nil
+}
+
+// Slides returns the names of the slide parts, e.g. ppt/slides/slide1.xml.
+func (p *Presentation) Slides() []string {
+	slides := p.pkg.Names(SlidesPrefix, ".xml")
+	sort.Slice(slides, func(i, j int) bool {
+		return len(slides[i]) < len(slides[j]) || len(slides[i]) == len(slides[j]) && slides[i] < slides[j]
+	})
+	return slides
+}
+
+// parts returns the parts whose placeholders are evaluated.
+func (p *Presentation) parts() []string {
+	return append(p.Slides(), p.pkg.Names(NotesSlidesPrefix, ".xml")...)
+}
+
+// GetFile returns the content of the file of the package, or nil if it does not exist.
+func (p *Presentation) GetFile(name string) []byte {
+	return p.pkg.GetFile(name)
+}
+
+// SetFile replaces the content of an existing file or adds a new file to the package.
+func (p *Presentation) SetFile(name string, data []byte) {
+	p.pkg.SetFile(name, data)
+}
+
+// SetTemplateEngine sets an alternative engine which evaluates the placeholders, e.g. a moustache-style engine.
+// Pass nil to use text/template again.
+func (p *Presentation) SetTemplateEngine(engine templating.Engine) {
+	p.template.Engine = engine
+}
+
+// AddTemplateFuncs adds custom functions to the text/template placeholders.
+func (p *Presentation) AddTemplateFuncs(funcMap template.FuncMap) {
+	p.template.AddFuncs(funcMap)
+}
+
+// SetMissingKeyPolicy sets the handling of placeholders which reference missing values. By default they are
+// left unchanged.
+func (p *Presentation) SetMissingKeyPolicy(policy docx.MissingKeyPolicy) {
+	p.template.MissingKey = policy
+}
+
+// ExecuteTemplate evaluates the placeholders of the slides and speaker notes with the data.
+func (p *Presentation) ExecuteTemplate(data docx.TemplateData) error {
+	return p.template.Execute(p.pkg, p.parts(), syntax, data)
+}
+
+// ReplaceAll replaces the string-based placeholders delimited with { and } with the values of the map.
+// Placeholders which are not in the map are left unchanged.
+func (p *Presentation) ReplaceAll(replaceMap docx.PlaceholderMap) error {
+	return p.template.ReplaceAll(p.pkg, p.parts(), syntax, replaceMap)
+}
+
+// Write writes the presentation to the writer.
+func (p *Presentation) Write(writer io.Writer) error {
+	return p.pkg.Write(writer, "")
+}
+
+// WriteToFile writes the presentation to a new file. The directories of the path are created if necessary.
+func (p *Presentation) WriteToFile(file string) error {
+	if file == p.path {
+		return fmt.Errorf("WriteToFile cannot write into the original template")
+	}
+	return p.pkg.WriteToFile(file, "")
+}
+
+// Close releases the content of the presentation.
+func (p *Presentation) Close() {
+	p.pkg = nil
+}
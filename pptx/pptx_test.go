@@ -0,0 +1,85 @@
+package pptx
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/izetmolla/docx"
+)
+
+// testPresentation returns a presentation with the shape trees of the slides.
+func testPresentation(t *testing.T, slides ...string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+	files := map[string]string{PresentationXml: `<p:presentation/>`}
+	for i, slide := range slides {
+		files[SlidesPrefix+string(rune('1'+i))+".xml"] = `<p:sld><p:cSld><p:spTree><p:sp><p:txBody>` + slide +
+			`</p:txBody></p:sp></p:spTree></p:cSld></p:sld>`
+	}
+	for name, content := range files {
+		entry, err := writer.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := entry.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestPresentation_ExecuteTemplate(t *testing.T) {
+	presentation, err := OpenBytes(testPresentation(t,
+		`<a:p><a:r><a:rPr b="1"/><a:t>{{.Ti</a:t></a:r><a:r><a:rPr/><a:t>tle}} &amp; more</a:t></a:r></a:p>`,
+		`<a:p><a:r><a:t>{{if .Draft}}DRAFT{{end}}</a:t></a:r><a:br/><a:r><a:t>{{.Missing}}</a:t></a:r></a:p>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer presentation.Close()
+
+	var template docx.TemplateDocument = presentation
+	if err := template.ExecuteTemplate(map[string]interface{}{"Title": "Q3 <Results>", "Draft": false}); err != nil {
+		t.Fatal(err)
+	}
+	if slides := presentation.Slides(); len(slides) != 2 || slides[0] != "ppt/slides/slide1.xml" {
+		t.Fatalf("unexpected slides %v", slides)
+	}
+	if slide := string(presentation.GetFile("ppt/slides/slide1.xml")); !strings.Contains(slide,
+		`<a:r><a:rPr b="1"/><a:t>Q3 &lt;Results&gt;</a:t></a:r><a:r><a:rPr/><a:t> &amp; more</a:t></a:r>`) {
+		t.Errorf("unexpected slide %s", slide)
+	}
+	if slide := string(presentation.GetFile("ppt/slides/slide2.xml")); !strings.Contains(slide,
+		`<a:r><a:t></a:t></a:r><a:br/><a:r><a:t>{{.Missing}}</a:t></a:r>`) {
+		t.Errorf("unexpected slide %s", slide)
+	}
+
+	if err := template.ReplaceAll(docx.PlaceholderMap{"unused": "value"}); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := template.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenBytes(buf.Bytes()); err != nil {
+		t.Errorf("the written presentation cannot be opened: %v", err)
+	}
+}
+
+func TestOpenBytes_NoPresentation(t *testing.T) {
+	if _, err := OpenBytes(testPresentation(t)[:10]); err == nil {
+		t.Errorf("expected an error for invalid data")
+	}
+	var buf bytes.Buffer
+	if err := zip.NewWriter(&buf).Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenBytes(buf.Bytes()); err == nil {
+		t.Errorf("expected an error for a package without presentation")
+	}
+}
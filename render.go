@@ -0,0 +1,211 @@
+package docx
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"text/template"
+)
+
+// Template is a parsed template which can be rendered any number of times, see Render.
+// Each rendering works on a fresh copy of the document, so a Template may be used concurrently.
+type Template struct {
+	data     []byte
+	funcs    template.FuncMap
+	schema   *Schema
+	opts     []Option
+	manifest *TemplateManifest
+}
+
+// NewTemplate creates a template from the content of a .docx file. The options are applied to the document of
+// each rendering.
+func NewTemplate(data []byte, opts ...Option) (*Template, error) {
+	doc, err := OpenBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	doc.Close()
+	return &Template{data: data, opts: opts}, nil
+}
+
+// LoadTemplate reads a template from a .docx file.
+func LoadTemplate(path string, opts ...Option) (*Template, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read template: %w", err)
+	}
+	return NewTemplate(data, opts...)
+}
+
+// Funcs adds the functions to the functions available in the template and returns the template.
+func (t *Template) Funcs(funcMap template.FuncMap) *Template {
+	if t.funcs == nil {
+		t.funcs = make(template.FuncMap)
+	}
+	for name, fn := range funcMap {
+		t.funcs[name] = fn
+	}
+	return t
+}
+
+// SetSchema sets a schema the data is validated with in addition to the one derived from its type.
+func (t *Template) SetSchema(schema *Schema) {
+	t.schema = schema
+}
+
+// Render executes the template with the data and returns the resulting document. Fields of structs tagged with
+// docx:"name" are available under that name, e.g. {{.customer_name}} for
+//
+//	type Offer struct {
+//		CustomerName string `docx:"customer_name,required"`
+//		Internal     string `docx:"-"`
+//	}
+//
+// Fields tagged as required must not be missing, see SchemaFor. Fields without tag keep their Go name.
+func Render[T any](tpl *Template, data T) (*Document, error) {
+	return tpl.render(SchemaFor(data), bindData(reflect.ValueOf(data)), nil, nil)
+}
+
+// render validates the bound data and executes the template on a fresh copy of the document. The functions and
+// options are added to those of the template, see Renderer.
+func (t *Template) render(schema *Schema, bound TemplateData, funcs template.FuncMap, opts []Option) (*Document, error) {
+	if err := schema.Validate(bound); err != nil {
+		return nil, err
+	}
+	if t.schema != nil {
+		if err := t.schema.Validate(bound); err != nil {
+			return nil, err
+		}
+	}
+
+	doc, err := OpenBytesWithOptions(t.data, OpenOptions{runs: t.manifestRuns()}, append(append([]Option{}, t.opts...), opts...)...)
+	if err != nil {
+		return nil, err
+	}
+	version := schema.Version
+	if version == "" && t.schema != nil {
+		version = t.schema.Version
+	}
+	if err := doc.checkVersions(version); err != nil {
+		doc.Close()
+		return nil, err
+	}
+	for _, funcMap := range []template.FuncMap{funcs, t.funcs} {
+		if funcMap != nil {
+			doc.templateReplacer.AddFuncs(funcMap)
+		}
+	}
+	if err := doc.ExecuteTemplate(bound); err != nil {
+		doc.Close()
+		return nil, err
+	}
+	return doc, nil
+}
+
+// fieldName returns the name of the struct field in templates and whether it is required.
+// Fields tagged with docx:"-" are skipped, their name is empty.
+func fieldName(field reflect.StructField) (string, bool) {
+	tag := field.Tag.Get("docx")
+	if tag == "-" {
+		return "", false
+	}
+	name, options, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = field.Name
+	}
+	required := false
+	for _, option := range strings.Split(options, ",") {
+		required = required || option == "required"
+	}
+	return name, required
+}
+
+// hasDocxTags reports whether any field of the struct type has a docx tag.
+func hasDocxTags(t reflect.Type) bool {
+	for _, field := range reflect.VisibleFields(t) {
+		if _, tagged := field.Tag.Lookup("docx"); tagged && field.IsExported() {
+			return true
+		}
+	}
+	return false
+}
+
+// bindData converts structs with docx tags into maps using the names of the tags, so templates can use them.
+// Structs without tags are converted as well if their fields contain tagged structs, other structs are kept, so
+// their methods stay available; slices and maps are converted if their values are.
+func bindData(value reflect.Value) interface{} {
+	for value.Kind() == reflect.Interface || value.Kind() == reflect.Pointer {
+		if value.IsNil() {
+			return nil
+		}
+		if value.Kind() == reflect.Pointer && value.Elem().Kind() == reflect.Struct && !needsBinding(value.Elem().Type(), map[reflect.Type]bool{}) {
+			return value.Interface()
+		}
+		value = value.Elem()
+	}
+	if !value.IsValid() {
+		return nil
+	}
+
+	switch value.Kind() {
+	case reflect.Struct:
+		if !needsBinding(value.Type(), map[reflect.Type]bool{}) {
+			return value.Interface()
+		}
+		fields := make(map[string]interface{})
+		for _, field := range reflect.VisibleFields(value.Type()) {
+			if !field.IsExported() || field.Anonymous {
+				continue
+			}
+			if name, _ := fieldName(field); name != "" {
+				fields[name] = bindData(value.FieldByIndex(field.Index))
+			}
+		}
+		return fields
+	case reflect.Slice, reflect.Array:
+		if value.Kind() == reflect.Slice && value.IsNil() || !needsBinding(value.Type().Elem(), map[reflect.Type]bool{}) {
+			return value.Interface()
+		}
+		items := make([]interface{}, value.Len())
+		for i := range items {
+			items[i] = bindData(value.Index(i))
+		}
+		return items
+	case reflect.Map:
+		if value.Type().Key().Kind() != reflect.String || !needsBinding(value.Type().Elem(), map[reflect.Type]bool{}) {
+			return value.Interface()
+		}
+		entries := make(map[string]interface{}, value.Len())
+		for iter := value.MapRange(); iter.Next(); {
+			entries[iter.Key().String()] = bindData(iter.Value())
+		}
+		return entries
+	}
+	return value.Interface()
+}
+
+// needsBinding reports whether values of the type might contain structs with docx tags. Fields of structs are
+// followed by their declared type, so a struct whose interface fields hold tagged structs is kept.
+func needsBinding(t reflect.Type, seen map[reflect.Type]bool) bool {
+	if seen[t] {
+		return false
+	}
+	seen[t] = true
+	switch t.Kind() {
+	case reflect.Interface:
+		return true
+	case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+		return needsBinding(t.Elem(), seen)
+	case reflect.Struct:
+		if hasDocxTags(t) {
+			return true
+		}
+		for _, field := range reflect.VisibleFields(t) {
+			if field.IsExported() && !field.Anonymous && field.Type.Kind() != reflect.Interface && needsBinding(field.Type, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package docx
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+)
+
+type renderItem struct {
+	Label string `docx:"label"`
+}
+
+type renderOffer struct {
+	CustomerName string       `docx:"customer_name,required"`
+	Items        []renderItem `docx:"items"`
+	Date         time.Time    `docx:"date"`
+	Internal     string       `docx:"-"`
+}
+
+func TestRender(t *testing.T) {
+	data := rewriteArchive(t, readFile(t, "./test/template.docx"), func(name string, data []byte) []byte {
+		if name == DocumentXml {
+			return []byte(strings.Replace(string(data), "<w:body>", "<w:body>"+
+				`<w:p><w:r><w:t>Offer for {{shout .customer_name}} of {{print (.date.Format "2006-01-02")}}</w:t></w:r></w:p>`+
+				`<w:p><w:r><w:t>{{range .items}}</w:t></w:r></w:p><w:p><w:r><w:t>Item {{.label}}</w:t></w:r></w:p><w:p><w:r><w:t>{{end}}</w:t></w:r></w:p>`, 1))
+		}
+		return data
+	}, nil)
+	tpl, err := NewTemplate(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tpl.Funcs(template.FuncMap{"shout": strings.ToUpper})
+
+	offer := renderOffer{
+		CustomerName: "acme",
+		Items:        []renderItem{{"Pen"}, {"Paper"}},
+		Date:         time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		Internal:     "secret",
+	}
+	for i := 0; i < 2; i++ {
+		doc, err := Render(tpl, &offer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		documentXml := string(doc.GetFile(DocumentXml))
+		if !strings.Contains(documentXml, "Offer for ACME of 2024-03-01") || !strings.Contains(documentXml, "Item Pen") || !strings.Contains(documentXml, "Item Paper") {
+			t.Errorf("the template was not rendered with the tagged names")
+		}
+		doc.Close()
+	}
+
+	if _, err := Render(tpl, renderOffer{}); err == nil || !strings.Contains(err.Error(), "customer_name: must be at least 1 characters long") {
+		t.Errorf("expected an error for the missing customer name, got %v", err)
+	}
+
+	bound := bindData(reflect.ValueOf(offer)).(map[string]interface{})
+	if _, exists := bound["Internal"]; exists {
+		t.Errorf("fields tagged with docx:\"-\" must be skipped")
+	}
+	if _, isTime := bound["date"].(time.Time); !isTime {
+		t.Errorf("structs without docx tags must be kept")
+	}
+}
+
+type renderCustomer struct {
+	Name string `docx:"customer_name,required"`
+}
+
+type renderNestedOffer struct {
+	Customer renderCustomer
+	Note     string
+}
+
+func TestRender_NestedTags(t *testing.T) {
+	data := rewriteArchive(t, readFile(t, "./test/template.docx"), func(name string, data []byte) []byte {
+		if name == DocumentXml {
+			return []byte(strings.Replace(string(data), "<w:body>", "<w:body>"+
+				`<w:p><w:r><w:t>Offer for {{.Customer.customer_name}} ({{.Note}})</w:t></w:r></w:p>`, 1))
+		}
+		return data
+	}, nil)
+	tpl, err := NewTemplate(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := Render(tpl, renderNestedOffer{Customer: renderCustomer{Name: "ACME"}, Note: "urgent"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+	if !strings.Contains(string(doc.GetFile(DocumentXml)), "Offer for ACME (urgent)") {
+		t.Errorf("the nested struct was not rendered with its tagged names")
+	}
+
+	if _, err := Render(tpl, renderNestedOffer{}); err == nil || !strings.Contains(err.Error(), "Customer.customer_name") {
+		t.Errorf("expected an error for the missing customer name, got %v", err)
+	}
+}
@@ -0,0 +1,67 @@
+package docx
+
+import (
+	"reflect"
+	"text/template"
+)
+
+// Renderer renders templates with functions, default data and options which are configured once, e.g. per
+// tenant: the company details and the legal footer text are merged into the data of every rendering, and the
+// policies are set with options such as WithMissingKeyPolicy. A configured Renderer may be used concurrently.
+type Renderer struct {
+	funcs    template.FuncMap
+	defaults map[string]interface{}
+	opts     []Option
+}
+
+// NewRenderer creates a renderer whose options are applied to every rendered document, after the options of the
+// template.
+func NewRenderer(opts ...Option) *Renderer {
+	return &Renderer{opts: opts}
+}
+
+// Funcs adds functions available in every rendered template and returns the renderer. Functions of the template
+// with the same name take precedence.
+func (r *Renderer) Funcs(funcMap template.FuncMap) *Renderer {
+	if r.funcs == nil {
+		r.funcs = make(template.FuncMap)
+	}
+	for name, fn := range funcMap {
+		r.funcs[name] = fn
+	}
+	return r
+}
+
+// Defaults adds data available in every rendered template, e.g. {"company": company, "legalFooter": footer},
+// and returns the renderer. Keys of the data of a rendering replace the defaults.
+func (r *Renderer) Defaults(data map[string]interface{}) *Renderer {
+	if r.defaults == nil {
+		r.defaults = make(map[string]interface{})
+	}
+	for key, value := range data {
+		r.defaults[key] = bindData(reflect.ValueOf(value))
+	}
+	return r
+}
+
+// Render executes the template with the data merged into the defaults and returns the resulting document, see
+// Render. With defaults, the data must be a map or a struct with docx tags.
+func (r *Renderer) Render(tpl *Template, data TemplateData) (*Document, error) {
+	bound := bindData(reflect.ValueOf(data))
+	if len(r.defaults) > 0 {
+		changed := make(map[string]interface{})
+		if bound != nil {
+			merged, err := mergeData(bound, nil)
+			if err != nil {
+				return nil, err
+			}
+			changed = merged.(map[string]interface{})
+		}
+		merged, err := mergeData(r.defaults, changed)
+		if err != nil {
+			return nil, err
+		}
+		bound = merged
+	}
+	return tpl.render(SchemaFor(data), bound, r.funcs, r.opts)
+}
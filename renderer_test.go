@@ -0,0 +1,54 @@
+package docx
+
+import (
+	"strings"
+	"testing"
+	"text/template"
+)
+
+func TestRenderer_Render(t *testing.T) {
+	data := rewriteArchive(t, readFile(t, "./test/template.docx"), func(name string, data []byte) []byte {
+		if name == DocumentXml {
+			return []byte(strings.Replace(string(data), "<w:body>", "<w:body>"+
+				`<w:p><w:r><w:t>Offer for {{shout .customer_name}} by {{.company.name}}</w:t></w:r></w:p>`+
+				`<w:p><w:r><w:t>{{.legal}}{{.missing}}</w:t></w:r></w:p>`, 1))
+		}
+		return data
+	}, nil)
+	tpl, err := NewTemplate(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	renderer := NewRenderer(WithMissingKeyPolicy(MissingKeyEmpty)).
+		Funcs(template.FuncMap{"shout": strings.ToUpper}).
+		Defaults(map[string]interface{}{"company": map[string]string{"name": "ACME"}, "legal": "Terms apply.", "customer_name": "nobody"})
+
+	for _, test := range []struct {
+		data     TemplateData
+		expected string
+	}{
+		{renderOffer{CustomerName: "jane"}, "Offer for JANE by ACME|Terms apply."},
+		{map[string]interface{}{"customer_name": "john", "legal": "No terms."}, "Offer for JOHN by ACME|No terms."},
+		{nil, "Offer for NOBODY by ACME|Terms apply."},
+	} {
+		doc, err := renderer.Render(tpl, test.data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		texts, err := paragraphTexts(doc.GetFile(DocumentXml))
+		doc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if text := strings.Join(texts[:2], "|"); text != test.expected {
+			t.Errorf("expected %q, got %q", test.expected, text)
+		}
+	}
+
+	if _, err := renderer.Render(tpl, renderOffer{}); err == nil {
+		t.Error("expected an error for a missing required field")
+	}
+	if _, err := renderer.Render(tpl, 42); err == nil {
+		t.Error("expected an error for data which cannot be merged")
+	}
+}
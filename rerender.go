@@ -0,0 +1,182 @@
+package docx
+
+import (
+	"fmt"
+	"html"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template/parse"
+)
+
+// Rerender updates the values of a rendered document which depend on the changed data, without processing the
+// template again. The document must have been rendered with SetBookmarkValues, so the values can be found: each
+// value whose action references a changed key is evaluated again with the data of the last rendering merged with
+// the changed keys, all other parts of the document are kept. The merged data is used by later calls.
+//
+// Only values outside of {{range}} and {{with}} are rendered again, and conditions and loops are not evaluated
+// again, so the structure of the document stays as it is. An error is returned if a changed key is not used by
+// any such value, e.g. a list rendered by {{range}}; render the template again in this case.
+func (d *Document) Rerender(changed map[string]interface{}) error {
+	return d.templateReplacer.Rerender(changed)
+}
+
+// Rerender updates the values which depend on the changed data, see Document.Rerender.
+func (tr *TemplateReplacer) Rerender(changed map[string]interface{}) error {
+	if len(tr.renderedValues) == 0 {
+		return fmt.Errorf("the document has no rendered values, render it with SetBookmarkValues first")
+	}
+	data, err := mergeData(tr.data, changed)
+	if err != nil {
+		return err
+	}
+	if err := tr.ValidateData(data); err != nil {
+		return err
+	}
+
+	// the actions which reference a changed key
+	affected := make(map[string]bool)
+	unused := make(map[string]bool, len(changed))
+	for key := range changed {
+		unused[key] = true
+	}
+	for _, action := range tr.renderedValues {
+		keys, all := tr.actionKeys(action)
+		for key := range changed {
+			if all || keys[key] {
+				affected[action] = true
+				delete(unused, key)
+			}
+		}
+	}
+	if len(unused) > 0 {
+		keys := make([]string, 0, len(unused))
+		for key := range unused {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		return fmt.Errorf("%s not used by any rendered value outside of loops, render the template again", strings.Join(keys, ", "))
+	}
+
+	results := make(map[string]string, len(affected))
+	if _, err := tr.document.updateBookmarks(func(part, bookmark string) (string, bool, error) {
+		action, exists := tr.renderedValues[bookmark]
+		if !exists || !affected[action] {
+			return "", false, nil
+		}
+		result, evaluated := results[action]
+		if !evaluated {
+			var err error
+			if result, err = tr.evaluateAction(action, data); err != nil {
+				return "", false, fmt.Errorf("failed to render %s again: %w", action, err)
+			}
+			results[action] = result
+		}
+		return tr.document.applyReplaceHooks(part, action, result), true, nil
+	}); err != nil {
+		return err
+	}
+	tr.data = data
+	tr.debugLog("Rendered %d changed values again", len(results))
+	return nil
+}
+
+// mergeData returns the data as map with the changed keys replaced. Structs with docx tags are converted like
+// Render does, other data cannot be merged.
+func mergeData(data TemplateData, changed map[string]interface{}) (TemplateData, error) {
+	value := reflect.ValueOf(bindData(reflect.ValueOf(data)))
+	if value.Kind() != reflect.Map || value.Type().Key().Kind() != reflect.String {
+		return nil, fmt.Errorf("the data is a %T, only maps and structs with docx tags can be merged", data)
+	}
+	merged := make(map[string]interface{}, value.Len()+len(changed))
+	for iter := value.MapRange(); iter.Next(); {
+		merged[iter.Key().String()] = iter.Value().Interface()
+	}
+	for key, change := range changed {
+		merged[key] = bindData(reflect.ValueOf(change))
+	}
+	return merged, nil
+}
+
+// evaluateAction executes the action of a rendered value with the data. Missing values are empty.
+func (tr *TemplateReplacer) evaluateAction(action string, data TemplateData) (string, error) {
+	if tr.engine != nil {
+		return tr.executeEngineAction(html.UnescapeString(action), data)
+	}
+	tmpl, err := tr.tmpl.Clone()
+	if err != nil {
+		return "", err
+	}
+	if tmpl, err = tmpl.Parse(stripAnnotations(action)); err != nil {
+		return "", err
+	}
+	nilSafeFields(tmpl.Tree.Root)
+	limitLoops(tmpl.Tree.Root)
+	buf := tr.newLimitedWriter()
+	if err := tmpl.Execute(buf, data); err != nil {
+		return "", err
+	}
+	return strings.ReplaceAll(buf.String(), "<no value>", ""), nil
+}
+
+// actionKeys returns the keys of the data the action of a rendered value references. The result is true if the
+// action may reference any key, e.g. {{index . "vat-id"}}.
+func (tr *TemplateReplacer) actionKeys(action string) (map[string]bool, bool) {
+	keys := make(map[string]bool)
+	if tr.engine != nil {
+		keys[strings.Split(strings.TrimSpace(html.UnescapeString(action)), ".")[0]] = true
+		return keys, false
+	}
+	tmpl, err := tr.tmpl.Clone()
+	if err == nil {
+		tmpl, err = tmpl.Parse(stripAnnotations(action))
+	}
+	if err != nil {
+		return keys, true
+	}
+	return keys, collectKeys(tmpl.Tree.Root, keys)
+}
+
+// collectKeys adds the keys of the data referenced by the node to keys. The result is true if the node may
+// reference any key, which is the case for the dot itself and for {{range}} and {{with}}.
+func collectKeys(node parse.Node, keys map[string]bool) bool {
+	all := false
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n != nil {
+			for _, child := range n.Nodes {
+				all = collectKeys(child, keys) || all
+			}
+		}
+	case *parse.ActionNode:
+		all = collectKeys(n.Pipe, keys)
+	case *parse.IfNode:
+		all = collectKeys(n.Pipe, keys)
+		all = collectKeys(n.List, keys) || all
+		all = collectKeys(n.ElseList, keys) || all
+	case *parse.RangeNode, *parse.WithNode, *parse.DotNode:
+		all = true
+	case *parse.PipeNode:
+		if n != nil {
+			for _, command := range n.Cmds {
+				all = collectKeys(command, keys) || all
+			}
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			all = collectKeys(arg, keys) || all
+		}
+	case *parse.ChainNode:
+		all = collectKeys(n.Node, keys)
+	case *parse.FieldNode:
+		keys[n.Ident[0]] = true
+	case *parse.VariableNode:
+		if n.Ident[0] == "$" {
+			if len(n.Ident) == 1 {
+				return true
+			}
+			keys[n.Ident[1]] = true
+		}
+	}
+	return all
+}
@@ -0,0 +1,71 @@
+package docx
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDocument_Rerender(t *testing.T) {
+	invoice, err := NewDocument().
+		Paragraph("Invoice for {{.customer.name}}").
+		Paragraph("{{range .lines}}").
+		Paragraph("{{.item}}: {{.amount}}").
+		Paragraph("{{end}}").
+		Paragraph("{{if .paid}}").
+		Paragraph("Paid: {{printf \"%s %.2f\" .currency .total}}").
+		Paragraph("{{end}}").
+		Paragraph("Due: {{.total}}").
+		Build(WithBookmarkValues())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer invoice.Close()
+
+	data := map[string]interface{}{
+		"customer": map[string]interface{}{"name": "Anna"},
+		"lines":    []map[string]interface{}{{"item": "Desk", "amount": 250}},
+		"paid":     true,
+		"currency": "CHF",
+		"total":    250.0,
+	}
+	if err := invoice.ExecuteTemplate(data); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(invoice.GetFile(DocumentXml)), `w:name="_customer_name"`) {
+		t.Errorf("expected a bookmark for the nested field")
+	}
+
+	if err := invoice.Rerender(map[string]interface{}{"total": 330.5, "customer": map[string]interface{}{"name": "Ben"}}); err != nil {
+		t.Fatal(err)
+	}
+	texts, err := paragraphTexts(invoice.GetFile(DocumentXml))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := strings.Join(texts, "|"); text != "Invoice for Ben|Desk: 250|Paid: CHF 330.50|Due: 330.5" {
+		t.Errorf("unexpected paragraphs %q", text)
+	}
+
+	if err := invoice.Rerender(map[string]interface{}{"currency": "EUR"}); err != nil {
+		t.Fatal(err)
+	}
+	if texts, _ := paragraphTexts(invoice.GetFile(DocumentXml)); texts[2] != "Paid: EUR 330.50" {
+		t.Errorf("expected the merged data to be kept, got %q", texts[2])
+	}
+
+	if err := invoice.Rerender(map[string]interface{}{"lines": nil, "paid": false}); err == nil || !strings.Contains(err.Error(), "lines, paid not used") {
+		t.Errorf("expected an error for keys used by loops and conditions, got %v", err)
+	}
+
+	doc, err := NewDocument().Paragraph("{{.total}}").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+	if err := doc.ExecuteTemplate(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.Rerender(map[string]interface{}{"total": 1}); err == nil {
+		t.Errorf("expected an error for a document rendered without bookmarks")
+	}
+}
@@ -0,0 +1,42 @@
+package docx
+
+import "regexp"
+
+var (
+	// revisionMarkRegex matches the empty elements which mark inserted or deleted content, e.g. the paragraph
+	// mark inside the run properties of a paragraph or the insertion of a table row, and the ranges of moves.
+	revisionMarkRegex = regexp.MustCompile(`<w:(?:ins|del|moveFrom|moveTo|moveFromRangeStart|moveFromRangeEnd|moveToRangeStart|moveToRangeEnd)\b[^>]*/>`)
+	// insertedContentRegex matches the open and close tags around inserted and moved content.
+	insertedContentRegex = regexp.MustCompile(`</?w:(?:ins|moveTo)\b[^>]*>`)
+	// deletedContentRegexes match deleted and moved away content including its tags.
+	deletedContentRegexes = []*regexp.Regexp{
+		regexp.MustCompile(`(?s)<w:del\b[^>]*>.*?</w:del>`),
+		regexp.MustCompile(`(?s)<w:moveFrom\b[^>]*>.*?</w:moveFrom>`),
+	}
+	// propertyChangeRegexes match the recorded formatting changes, which contain the former properties.
+	propertyChangeRegexes = []*regexp.Regexp{
+		regexp.MustCompile(`(?s)<w:rPrChange\b[^>]*>.*?</w:rPrChange>`),
+		regexp.MustCompile(`(?s)<w:pPrChange\b[^>]*>.*?</w:pPrChange>`),
+		regexp.MustCompile(`(?s)<w:sectPrChange\b[^>]*>.*?</w:sectPrChange>`),
+		regexp.MustCompile(`(?s)<w:tblPrChange\b[^>]*>.*?</w:tblPrChange>`),
+		regexp.MustCompile(`(?s)<w:tblPrExChange\b[^>]*>.*?</w:tblPrExChange>`),
+		regexp.MustCompile(`(?s)<w:trPrChange\b[^>]*>.*?</w:trPrChange>`),
+		regexp.MustCompile(`(?s)<w:tcPrChange\b[^>]*>.*?</w:tcPrChange>`),
+		regexp.MustCompile(`(?s)<w:tblGridChange\b[^>]*>.*?</w:tblGridChange>`),
+		regexp.MustCompile(`(?s)<w:numberingChange\b[^>]*/>`),
+	}
+)
+
+// acceptRevisions accepts all tracked changes of the part: inserted and moved content is kept without its
+// revision marks, deleted and moved away content as well as the recorded formatting changes are removed.
+// Deleted paragraph marks are only unmarked, so the paragraphs are not joined.
+func acceptRevisions(data []byte) []byte {
+	for _, re := range propertyChangeRegexes {
+		data = re.ReplaceAll(data, nil)
+	}
+	data = revisionMarkRegex.ReplaceAll(data, nil)
+	for _, re := range deletedContentRegexes {
+		data = re.ReplaceAll(data, nil)
+	}
+	return insertedContentRegex.ReplaceAll(data, nil)
+}
@@ -0,0 +1,153 @@
+package docx
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf16"
+)
+
+const (
+	// rtfTextWidth is the usable page width in twips which is distributed across table columns.
+	rtfTextWidth = 9000
+)
+
+// ExportRTF converts the main body of the document into RTF and writes it to the given writer.
+// The conversion is fidelity-limited: only text, bold/italic/underline/strike formatting, paragraph alignment,
+// tabs, line breaks and tables are converted. Headers, footers, images and styles are not exported.
+// Nested tables are flattened into the cell which contains them.
+func (d *Document) ExportRTF(writer io.Writer) error {
+	blocks, err := parseBody(d.GetFile(DocumentXml))
+	if err != nil {
+		return err
+	}
+
+	var sb strings.Builder
+	sb.WriteString("{\\rtf1\\ansi\\ansicpg1252\\deff0{\\fonttbl{\\f0\\fswiss Calibri;}}\\uc1\\pard\\plain\\f0\\fs22\n")
+	for _, block := range blocks {
+		if block.Paragraph != nil {
+			writeRTFParagraph(&sb, block.Paragraph, false)
+			sb.WriteString("\\par\n")
+			continue
+		}
+		writeRTFTable(&sb, block.Table)
+	}
+	sb.WriteString("}")
+
+	_, err = io.WriteString(writer, sb.String())
+	return err
+}
+
+// writeRTFParagraph writes the paragraph properties and runs. The closing \par or \cell is up to the caller.
+func writeRTFParagraph(sb *strings.Builder, paragraph *bodyParagraph, inTable bool) {
+	sb.WriteString("\\pard")
+	if inTable {
+		sb.WriteString("\\intbl")
+	}
+	switch paragraph.Alignment {
+	case "center":
+		sb.WriteString("\\qc")
+	case "right", "end":
+		sb.WriteString("\\qr")
+	case "both", "distribute":
+		sb.WriteString("\\qj")
+	default:
+		sb.WriteString("\\ql")
+	}
+	sb.WriteString(" ")
+
+	for _, run := range paragraph.Runs {
+		if run.Text == "" {
+			continue
+		}
+		sb.WriteString("{")
+		if run.Bold {
+			sb.WriteString("\\b")
+		}
+		if run.Italic {
+			sb.WriteString("\\i")
+		}
+		if run.Underline {
+			sb.WriteString("\\ul")
+		}
+		if run.Strike {
+			sb.WriteString("\\strike")
+		}
+		if run.Bold || run.Italic || run.Underline || run.Strike {
+			sb.WriteString(" ")
+		}
+		sb.WriteString(escapeRTF(run.Text))
+		sb.WriteString("}")
+	}
+}
+
+// writeRTFTable writes the table row by row. All columns of a row have the same width.
+func writeRTFTable(sb *strings.Builder, table *bodyTable) {
+	for _, row := range table.Rows {
+		if len(row.Cells) == 0 {
+			continue
+		}
+		sb.WriteString("\\trowd\\trgaph108")
+		cellWidth := rtfTextWidth / len(row.Cells)
+		for i := range row.Cells {
+			fmt.Fprintf(sb, "\\clbrdrt\\brdrs\\clbrdrl\\brdrs\\clbrdrb\\brdrs\\clbrdrr\\brdrs\\cellx%d", (i+1)*cellWidth)
+		}
+		sb.WriteString("\n")
+
+		for _, cell := range row.Cells {
+			paragraphs := flattenParagraphs(cell.Blocks)
+			if len(paragraphs) == 0 {
+				sb.WriteString("\\pard\\intbl ")
+			}
+			for i, paragraph := range paragraphs {
+				if i > 0 {
+					sb.WriteString("\\par\n")
+				}
+				writeRTFParagraph(sb, paragraph, true)
+			}
+			sb.WriteString("\\cell\n")
+		}
+		sb.WriteString("\\row\n")
+	}
+	sb.WriteString("\\pard\n")
+}
+
+// flattenParagraphs returns all paragraphs of the blocks, including those of nested tables.
+func flattenParagraphs(blocks []bodyBlock) []*bodyParagraph {
+	var paragraphs []*bodyParagraph
+	for _, block := range blocks {
+		if block.Paragraph != nil {
+			paragraphs = append(paragraphs, block.Paragraph)
+			continue
+		}
+		for _, row := range block.Table.Rows {
+			for _, cell := range row.Cells {
+				paragraphs = append(paragraphs, flattenParagraphs(cell.Blocks)...)
+			}
+		}
+	}
+	return paragraphs
+}
+
+// escapeRTF escapes RTF control characters. Non-ASCII characters are written as \uN? with UTF-16 code units.
+func escapeRTF(text string) string {
+	var sb strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '\\' || r == '{' || r == '}':
+			sb.WriteRune('\\')
+			sb.WriteRune(r)
+		case r == '\t':
+			sb.WriteString("\\tab ")
+		case r == '\n':
+			sb.WriteString("\\line ")
+		case r < 0x80:
+			sb.WriteRune(r)
+		default:
+			for _, unit := range utf16.Encode([]rune{r}) {
+				fmt.Fprintf(&sb, "\\u%d?", int16(unit))
+			}
+		}
+	}
+	return sb.String()
+}
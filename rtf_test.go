@@ -0,0 +1,134 @@
+package docx
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// bodyContentRegex matches the content of the body in front of the section properties.
+var bodyContentRegex = regexp.MustCompile(`(?s)<w:body>.*?(<w:sectPr)`)
+
+// openBody opens the template with the content of the body replaced by the given XML.
+func openBody(t testing.TB, body string) *Document {
+	modify := func(name string, data []byte) []byte {
+		if name == DocumentXml {
+			return bodyContentRegex.ReplaceAllLiteral(data, []byte("<w:body>"+body+"<w:sectPr"))
+		}
+		return data
+	}
+	doc, err := OpenBytes(rewriteArchive(t, readFile(t, "./test/template.docx"), modify, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func TestDocument_ExportRTF(t *testing.T) {
+	run := func(properties, text string) string {
+		return `<w:r><w:rPr>` + properties + `</w:rPr><w:t xml:space="preserve">` + text + `</w:t></w:r>`
+	}
+	paragraph := func(alignment, content string) string {
+		if alignment == "" {
+			return `<w:p>` + content + `</w:p>`
+		}
+		return `<w:p><w:pPr><w:jc w:val="` + alignment + `"/></w:pPr>` + content + `</w:p>`
+	}
+	cell := func(content string) string {
+		return `<w:tc>` + content + `</w:tc>`
+	}
+	nested := `<w:tbl><w:tr>` + cell(paragraph("", run("", "Inner A"))) + cell(paragraph("", run("", "Inner B"))) + `</w:tr></w:tbl>`
+
+	tests := []struct {
+		name       string
+		body       string
+		rows       int
+		expected   []string
+		unexpected []string
+	}{
+		{"plain", paragraph("", run("", "Plain")), 0, []string{`\pard\ql {Plain}\par`}, nil},
+		{"bold", paragraph("", run("<w:b/>", "Bold")), 0, []string{`{\b Bold}`}, nil},
+		{"italic", paragraph("", run("<w:i/>", "Italic")), 0, []string{`{\i Italic}`}, nil},
+		{"underline", paragraph("", run(`<w:u w:val="single"/>`, "Underlined")), 0, []string{`{\ul Underlined}`}, nil},
+		{"no underline", paragraph("", run(`<w:u w:val="none"/>`, "Plain")), 0, []string{`{Plain}`}, []string{`\ul`}},
+		{"combined", paragraph("", run(`<w:b/><w:i/><w:u w:val="single"/>`, "All")), 0, []string{`{\b\i\ul All}`}, nil},
+		{"bold off", paragraph("", run(`<w:b w:val="0"/>`, "Plain")), 0, []string{`{Plain}`}, []string{`\b`}},
+		{"center", paragraph("center", run("", "Title")), 0, []string{`\pard\qc {Title}`}, nil},
+		{"right", paragraph("right", run("", "Date")), 0, []string{`\pard\qr {Date}`}, nil},
+		{"end", paragraph("end", run("", "Date")), 0, []string{`\pard\qr {Date}`}, nil},
+		{"justified", paragraph("both", run("", "Text")), 0, []string{`\pard\qj {Text}`}, nil},
+		{"left", paragraph("left", run("", "Text")), 0, []string{`\pard\ql {Text}`}, nil},
+		{
+			"table",
+			`<w:tbl><w:tr>` + cell(paragraph("", run("", "A1"))) + cell(paragraph("", run("", "B1"))) + `</w:tr>` +
+				`<w:tr>` + cell(paragraph("", run("", "A2"))) + cell(`<w:p/>`) + `</w:tr></w:tbl>`,
+			2,
+			[]string{
+				`\trowd\trgaph108`, `\cellx4500`, `\cellx9000`,
+				`\pard\intbl\ql {A1}\cell` + "\n" + `\pard\intbl\ql {B1}\cell` + "\n" + `\row`,
+				`\pard\intbl\ql {A2}\cell` + "\n" + `\pard\intbl\ql \cell` + "\n" + `\row`,
+			},
+			nil,
+		},
+		{
+			"nested table",
+			`<w:tbl><w:tr>` + cell(paragraph("", run("", "Outer"))+nested+`<w:p/>`) + `</w:tr></w:tbl>`,
+			1,
+			[]string{`\pard\intbl\ql {Outer}\par` + "\n" + `\pard\intbl\ql {Inner A}\par` + "\n" + `\pard\intbl\ql {Inner B}\par`, `\cellx9000`},
+			[]string{`\cellx4500`},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			doc := openBody(t, test.body)
+			defer doc.Close()
+
+			var buf bytes.Buffer
+			if err := doc.ExportRTF(&buf); err != nil {
+				t.Fatal(err)
+			}
+			rtf := buf.String()
+			if !strings.HasPrefix(rtf, `{\rtf1\ansi`) || !strings.HasSuffix(rtf, "}") {
+				t.Errorf("invalid RTF document %q", rtf)
+			}
+			for _, expected := range test.expected {
+				if !strings.Contains(rtf, expected) {
+					t.Errorf("expected %q in %q", expected, rtf)
+				}
+			}
+			for _, unexpected := range test.unexpected {
+				if strings.Contains(rtf, unexpected) {
+					t.Errorf("unexpected %q in %q", unexpected, rtf)
+				}
+			}
+			if rows := strings.Count(rtf, `\trowd`); rows != test.rows {
+				t.Errorf("expected %d table rows, got %d in %q", test.rows, rows, rtf)
+			}
+		})
+	}
+}
+
+func TestEscapeRTF(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{"plain text", "plain text"},
+		{`C:\path`, `C:\\path`},
+		{"{braces}", `\{braces\}`},
+		{"a\tb", `a\tab b`},
+		{"a\nb", `a\line b`},
+		{"café", `caf\u233?`},
+		{"5 €", `5 \u8364?`},
+		{"\uFFFD", `\u-3?`},
+		// characters outside of the BMP are written as surrogate pair, RTF expects signed 16-bit values
+		{"😀", `\u-10179?\u-8704?`},
+		{`{\u}😀`, `\{\\u\}\u-10179?\u-8704?`},
+	}
+	for _, test := range tests {
+		if escaped := escapeRTF(test.text); escaped != test.expected {
+			t.Errorf("escapeRTF(%q) = %q, expected %q", test.text, escaped, test.expected)
+		}
+	}
+}
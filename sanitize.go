@@ -0,0 +1,110 @@
+package docx
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// sanitize.go implements the handling of values which contain invalid UTF-8 or characters which are not allowed
+// in XML 1.0, see InvalidCharPolicy.
+
+// ErrInvalidChar is returned with InvalidCharError if a value contains invalid UTF-8 or a character which is not
+// allowed in XML 1.0.
+var ErrInvalidChar = errors.New("invalid character")
+
+// validXMLChar reports whether the character is allowed in XML 1.0: tab, line feed, carriage return and the
+// characters outside of the control characters, the surrogates and U+FFFE and U+FFFF.
+func validXMLChar(r rune) bool {
+	return r == '\t' || r == '\n' || r == '\r' ||
+		r >= 0x20 && r <= 0xD7FF ||
+		r >= 0xE000 && r <= 0xFFFD ||
+		r >= 0x10000 && r <= utf8.MaxRune
+}
+
+// invalidCharAt returns the size of the character at the position of the value and whether it is invalid UTF-8
+// or not allowed in XML 1.0.
+func invalidCharAt(value string, i int) (int, bool) {
+	r, size := utf8.DecodeRuneInString(value[i:])
+	return size, r == utf8.RuneError && size == 1 || !validXMLChar(r)
+}
+
+// sanitizeValue returns the value with its invalid bytes and characters handled according to the policy.
+func sanitizeValue(value string, policy InvalidCharPolicy) (string, error) {
+	first := 0
+	for first < len(value) {
+		size, invalid := invalidCharAt(value, first)
+		if invalid {
+			break
+		}
+		first += size
+	}
+	if first == len(value) {
+		return value, nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString(value[:first])
+	for i := first; i < len(value); {
+		size, invalid := invalidCharAt(value, i)
+		switch {
+		case !invalid:
+			sb.WriteString(value[i : i+size])
+		case policy == InvalidCharError:
+			return "", fmt.Errorf("%w %q at byte %d of %q", ErrInvalidChar, value[i:i+size], i, value)
+		case policy == InvalidCharReplace:
+			sb.WriteRune(utf8.RuneError)
+		}
+		i += size
+	}
+	return sb.String(), nil
+}
+
+// markerPrefix starts the markers which helpers write for later passes, e.g. [[docx-hiderow]] for {{rowif}}.
+const markerPrefix = "[[docx-"
+
+// escapedMarkerPrefix replaces markerPrefix in values, so the template data can never be read as a marker. A word
+// joiner is inserted, which no marker pattern matches even after the text is decoded, e.g. for the text of cells.
+const escapedMarkerPrefix = "[[docx\u2060-"
+
+// escapeMarkers returns the value with all marker prefixes escaped, see unescapeMarkers.
+func escapeMarkers(value string) string {
+	if !strings.Contains(value, markerPrefix) {
+		return value
+	}
+	return strings.ReplaceAll(value, markerPrefix, escapedMarkerPrefix)
+}
+
+// markerSafeValue is the docxMarkers function of placeholders, whose values are not escaped for XML. Values which
+// print with a marker prefix are escaped by escapeMarkers, the output of the helpers and all other values are
+// returned unchanged, so they are printed as before.
+func markerSafeValue(_ string, value interface{}) interface{} {
+	switch value.(type) {
+	case nil, cellMarker, rawXML:
+		return value
+	}
+	if text := fmt.Sprint(value); strings.Contains(text, markerPrefix) {
+		return escapeMarkers(text)
+	}
+	return value
+}
+
+// unescapeMarkers turns the marker prefixes escaped by escapeMarkers back into their text in all XML parts. It is
+// called after all markers are resolved.
+func (d *Document) unescapeMarkers() error {
+	for _, fileName := range d.xmlParts() {
+		data := d.GetFile(fileName)
+		if !bytes.Contains(data, []byte(escapedMarkerPrefix)) {
+			continue
+		}
+		if err := d.SetFile(fileName, bytes.ReplaceAll(data, []byte(escapedMarkerPrefix), []byte(markerPrefix))); err != nil {
+			return err
+		}
+		if err := d.refreshRuns(fileName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
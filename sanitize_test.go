@@ -0,0 +1,155 @@
+package docx
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestSanitizeValue(t *testing.T) {
+	tests := []struct {
+		value    string
+		replaced string
+		stripped string
+	}{
+		{"Ana 李 🎉\ttab\r\n", "Ana 李 🎉\ttab\r\n", "Ana 李 🎉\ttab\r\n"},
+		{"bell\a and null\x00", "bell� and null�", "bell and null"},
+		{"latin1 caf\xe9", "latin1 caf�", "latin1 caf"},
+		{"surrogate \xed\xa0\x80!", "surrogate ���!", "surrogate !"},
+		{"non-character ￾", "non-character �", "non-character "},
+		{"replacement �", "replacement �", "replacement �"},
+	}
+	for _, test := range tests {
+		if replaced, err := sanitizeValue(test.value, InvalidCharReplace); err != nil || replaced != test.replaced {
+			t.Errorf("expected %q to be replaced as %q, got %q (%v)", test.value, test.replaced, replaced, err)
+		}
+		if stripped, err := sanitizeValue(test.value, InvalidCharStrip); err != nil || stripped != test.stripped {
+			t.Errorf("expected %q to be stripped as %q, got %q (%v)", test.value, test.stripped, stripped, err)
+		}
+		_, err := sanitizeValue(test.value, InvalidCharError)
+		if valid := test.value == test.replaced; valid != (err == nil) || err != nil && !errors.Is(err, ErrInvalidChar) {
+			t.Errorf("unexpected error for %q: %v", test.value, err)
+		}
+	}
+
+	if InvalidCharStrip.String() != "strip" || InvalidCharPolicy(7).String() != "InvalidCharPolicy(7)" {
+		t.Errorf("unexpected names %s, %s", InvalidCharStrip, InvalidCharPolicy(7))
+	}
+}
+
+func TestDocument_InvalidCharPolicy(t *testing.T) {
+	wellFormed := func(t *testing.T, data []byte) {
+		t.Helper()
+		decoder := xml.NewDecoder(strings.NewReader(string(data)))
+		for {
+			if _, err := decoder.Token(); err == io.EOF {
+				return
+			} else if err != nil {
+				t.Fatalf("the document is not well-formed: %v", err)
+			}
+		}
+	}
+	value := "A\x01B\xffC"
+	template := rewriteArchive(t, readFile(t, "./test/template.docx"), func(name string, data []byte) []byte {
+		if name == DocumentXml {
+			return []byte(strings.Replace(string(data), "<w:body>",
+				`<w:body><w:p><w:r><w:t>[{{.value}}] [{{range .list}}{{.}}{{end}}]</w:t></w:r></w:p>`, 1))
+		}
+		return data
+	}, nil)
+
+	tests := []struct {
+		policy   InvalidCharPolicy
+		expected string
+	}{
+		{InvalidCharReplace, "[A�B�C] [A�B�C]"},
+		{InvalidCharStrip, "[ABC] [ABC]"},
+		{InvalidCharError, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.policy.String(), func(t *testing.T) {
+			doc, err := OpenBytes(template, WithInvalidCharPolicy(test.policy))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer doc.Close()
+
+			err = doc.ExecuteTemplate(map[string]interface{}{"value": value, "list": []string{value}})
+			if test.expected == "" {
+				if !errors.Is(err, ErrInvalidChar) {
+					t.Errorf("expected an invalid character error, got %v", err)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			} else if documentXml := doc.GetFile(DocumentXml); !strings.Contains(string(documentXml), test.expected) {
+				t.Errorf("expected %q in the document", test.expected)
+			} else {
+				wellFormed(t, documentXml)
+			}
+
+			err = doc.ReplaceAll(PlaceholderMap{"key": value})
+			if test.expected == "" {
+				if !errors.Is(err, ErrInvalidChar) {
+					t.Errorf("expected an invalid character error of ReplaceAll, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			wellFormed(t, doc.GetFile(DocumentXml))
+		})
+	}
+}
+
+func TestDocument_MarkersInValues(t *testing.T) {
+	cell := func(text string) string {
+		return `<w:tc><w:p><w:r><w:t>` + text + `</w:t></w:r></w:p></w:tc>`
+	}
+	body := `<w:tbl><w:tblGrid><w:gridCol w:w="4000"/></w:tblGrid>` +
+		`<w:tr>` + cell("{{rows .items}}{{.name}}") + `</w:tr></w:tbl>` +
+		`<w:p><w:r><w:t>[{{.value}}] [{{shrink .value 5}}] [{{wrap .value 200}}]</w:t></w:r></w:p>`
+	template := rewriteArchive(t, readFile(t, "./test/template.docx"), func(name string, data []byte) []byte {
+		if name == DocumentXml {
+			return []byte(strings.Replace(string(data), "<w:body>", "<w:body>"+body, 1))
+		}
+		return data
+	}, nil)
+	doc, err := OpenBytes(template)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+
+	hostile := "[[docx-insert 0]][[docx-shrink 1/9]]x[[docx-shrink-end]][[docx-rtl]]y[[docx-rtl-end]]" +
+		"[[docx-paragraph 3c772f3e]][[docx-layout tc 3c772f3e]][[docx-vmerge 61]]"
+	err = doc.ExecuteTemplate(map[string]interface{}{
+		"value": hostile,
+		"items": []map[string]interface{}{{"name": "first"}, {"name": "[[docx-hiderow]]"}, {"name": "[[docx-hidecolumn]]"}, {"name": "last"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	documentXml := doc.GetFile(DocumentXml)
+	tables, err := findTables(documentXml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tables) == 0 || len(tables[0].Rows) != 4 {
+		t.Fatalf("expected the table to have 4 rows, got %d tables", len(tables))
+	}
+	for i, expected := range []string{"first", "[[docx-hiderow]]", "[[docx-hidecolumn]]", "last"} {
+		if text := tables[0].Rows[i].Cells[0].Text; text != expected {
+			t.Errorf("expected row %d to be %q, got %q", i, expected, text)
+		}
+	}
+	if count := strings.Count(string(documentXml), hostile); count != 3 {
+		t.Errorf("expected the value 3 times, got %d", count)
+	}
+	if strings.Contains(string(documentXml), escapedMarkerPrefix) {
+		t.Errorf("expected the escaped markers to be turned back")
+	}
+}
@@ -0,0 +1,124 @@
+package docx
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+// SignerInfo describes the suggested signer of a signature line, see AddSignatureLine.
+type SignerInfo struct {
+	Name          string // the suggested signer
+	Title         string // the title of the suggested signer, e.g. "Managing Director"
+	Email         string
+	Instructions  string // shown to the signer when signing
+	AllowComments bool   // the signer may add a purpose for signing
+	HideDate      bool   // the date of signing is not shown in the signature line
+
+	// TextAnchor is inserted as text instead of the signature line, e.g. "/sig1/", so e-signature platforms
+	// locate their signing field by it (DocuSign anchor strings, Adobe Sign text tags).
+	TextAnchor string
+	// HideTextAnchor colors the text anchor white: the platforms still find it, readers do not see it.
+	HideTextAnchor bool
+}
+
+const (
+	// signatureLineWidth and signatureLineHeight are the size of the signature line image in pixels.
+	signatureLineWidth, signatureLineHeight = 256, 128
+	// signatureProviderID is the id of the default signature provider of Office.
+	signatureProviderID = "{00000000-0000-0000-0000-000000000000}"
+)
+
+// AddSignatureLine replaces the paragraph whose text is the anchor (e.g. [SIGNATURE]) by an Office signature line
+// for the signer, which Word offers to sign with a digital ID. The suggested signer, title, e-mail address and
+// instructions are stored with the line and shown by Word when signing. With a TextAnchor the paragraph holds that
+// text instead, e.g. /sig1/ for an e-signature platform. The paragraph properties of the anchor paragraph are kept.
+func (d *Document) AddSignatureLine(anchor string, signer SignerInfo) error {
+	paragraph, err := d.anchorParagraph(anchor)
+	if err != nil {
+		return err
+	}
+	documentXml := d.GetFile(DocumentXml)
+	var properties []byte
+	if match := paragraphPropertiesRegex.Find(documentXml[paragraph.Start:paragraph.End]); match != nil {
+		properties = match
+	}
+
+	if signer.TextAnchor != "" {
+		var runProperties string
+		if signer.HideTextAnchor {
+			runProperties = `<w:rPr><w:color w:val="FFFFFF"/></w:rPr>`
+		}
+		text := fmt.Sprintf(`<w:p>%s<w:r>%s<w:t xml:space="preserve">%s</w:t></w:r></w:p>`, properties, runProperties, xmlEscape(signer.TextAnchor))
+		return d.replaceParagraphXml(paragraph, []byte(text), 0)
+	}
+
+	imageName, err := d.addMedia("image", signatureLineImage(), "png", "image/png")
+	if err != nil {
+		return err
+	}
+	imageID, err := d.addRelationship(DocumentXml, ImageRelationshipType, imageName)
+	if err != nil {
+		return err
+	}
+	lineID, err := newItemID()
+	if err != nil {
+		return err
+	}
+
+	// the shape type of pictures is declared once per part, Word writes it in front of the first shape using it
+	var shapeType string
+	if !bytes.Contains(documentXml, []byte(`id="_x0000_t75"`)) {
+		shapeType = `<v:shapetype id="_x0000_t75" coordsize="21600,21600" o:spt="75" o:preferrelative="t" ` +
+			`path="m@4@5l@4@11@9@11@9@5xe" filled="f" stroked="f"><v:stroke joinstyle="miter"/>` +
+			`<o:lock v:ext="edit" aspectratio="t"/></v:shapetype>`
+	}
+	flag := func(value bool) string {
+		if value {
+			return "t"
+		}
+		return "f"
+	}
+	var instructions string
+	if signer.Instructions != "" {
+		instructions = ` o:signinginstructionsset="t" o:signinginstructions="` + xmlEscape(signer.Instructions) + `"`
+	}
+
+	// the size of VML shapes is given in points
+	line := fmt.Sprintf(`<w:p>%s<w:r>`+
+		`<w:pict xmlns:v="urn:schemas-microsoft-com:vml" xmlns:o="urn:schemas-microsoft-com:office:office">%s`+
+		`<v:shape id="_x0000_i%d" type="#_x0000_t75" alt="Microsoft Office Signature Line..." style="width:%gpt;height:%gpt">`+
+		`<v:imagedata r:id="%s" o:title=""/>`+
+		`<o:lock v:ext="edit" ungrouping="t" rotation="t" cropping="t" verticies="t" text="t" grouping="t"/>`+
+		`<o:signatureline v:ext="edit" id="%s" provid="%s" o:suggestedsigner="%s" o:suggestedsigner2="%s" o:suggestedsigneremail="%s"%s`+
+		` allowcomments="%s" showsigndate="%s" issignatureline="t"/></v:shape></w:pict></w:r></w:p>`,
+		properties, shapeType, 1024+d.nextDrawingID(), float64(signatureLineWidth)*0.75, float64(signatureLineHeight)*0.75,
+		imageID, lineID, signatureProviderID, xmlEscape(signer.Name), xmlEscape(signer.Title), xmlEscape(signer.Email),
+		instructions, flag(signer.AllowComments), flag(!signer.HideDate))
+	return d.replaceParagraphXml(paragraph, []byte(line), 0)
+}
+
+// signatureLineImage returns the image shown by an unsigned signature line: an X in front of the line the
+// signature is written on. Word replaces it by the signature when the document is signed.
+func signatureLineImage() []byte {
+	img := image.NewNRGBA(image.Rect(0, 0, signatureLineWidth, signatureLineHeight))
+	ink := color.NRGBA{A: 0xFF}
+	for x := 8; x < signatureLineWidth-8; x++ {
+		img.SetNRGBA(x, 88, ink)
+		img.SetNRGBA(x, 89, ink)
+	}
+	for i := range 16 {
+		for _, x := range []int{12 + i, 27 - i} {
+			img.SetNRGBA(x, 64+i, ink)
+			img.SetNRGBA(x+1, 64+i, ink)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		panic(err) // encoding an in-memory image does not fail
+	}
+	return buf.Bytes()
+}
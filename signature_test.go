@@ -0,0 +1,53 @@
+package docx
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDocument_AddSignatureLine(t *testing.T) {
+	doc, err := OpenBytes(rewriteArchive(t, readFile(t, "./test/template.docx"), func(name string, data []byte) []byte {
+		if name == DocumentXml {
+			return []byte(strings.Replace(string(data), "<w:body>", `<w:body><w:p><w:pPr><w:jc w:val="right"/></w:pPr>`+
+				`<w:r><w:t>[SIGNATURE]</w:t></w:r></w:p><w:p><w:r><w:t>[COUNTERSIGNATURE]</w:t></w:r></w:p>`, 1))
+		}
+		return data
+	}, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+
+	err = doc.AddSignatureLine("[SIGNATURE]", SignerInfo{Name: "Anna Meier", Title: "CEO & Founder", Email: "anna@example.com",
+		Instructions: "Sign to accept the offer", HideDate: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.AddSignatureLine("[COUNTERSIGNATURE]", SignerInfo{TextAnchor: "/sig2/", HideTextAnchor: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.AddSignatureLine("[MISSING]", SignerInfo{}); err == nil {
+		t.Errorf("expected an error for a missing anchor")
+	}
+
+	documentXml := string(doc.GetFile(DocumentXml))
+	if strings.Contains(documentXml, "[SIGNATURE]") || strings.Contains(documentXml, "[COUNTERSIGNATURE]") {
+		t.Errorf("the anchors were not replaced")
+	}
+	if !strings.Contains(documentXml, `<w:p><w:pPr><w:jc w:val="right"/></w:pPr><w:r><w:pict `) {
+		t.Errorf("the paragraph properties were not kept")
+	}
+	for _, attr := range []string{`o:suggestedsigner="Anna Meier"`, `o:suggestedsigner2="CEO &amp; Founder"`,
+		`o:suggestedsigneremail="anna@example.com"`, `o:signinginstructions="Sign to accept the offer"`,
+		`showsigndate="f"`, `allowcomments="f"`, `issignatureline="t"`} {
+		if !strings.Contains(documentXml, attr) {
+			t.Errorf("the signature line does not contain %s", attr)
+		}
+	}
+	if !strings.Contains(documentXml, `<w:color w:val="FFFFFF"/></w:rPr><w:t xml:space="preserve">/sig2/</w:t>`) {
+		t.Errorf("expected the hidden text anchor")
+	}
+	if images := doc.relationshipTargets(DocumentXml, ImageRelationshipType); len(images) != 2 || images[1] != "word/media/image1.png" {
+		t.Errorf("expected the image of the signature line, got %v", images)
+	}
+}
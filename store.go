@@ -0,0 +1,239 @@
+package docx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TemplateSource is a location the templates of a TemplateStore are read from, e.g. a directory or the prefix of
+// a storage bucket.
+type TemplateSource interface {
+	// List returns the templates of the source. The revision changes whenever the content of a template changes,
+	// e.g. its modification time or the ETag of an object.
+	List(ctx context.Context) ([]TemplateEntry, error)
+	// Read returns the content of the template with the given name.
+	Read(ctx context.Context, name string) ([]byte, error)
+}
+
+// TemplateEntry is a template listed by a TemplateSource.
+type TemplateEntry struct {
+	Name     string // The name the template is available under, e.g. invoices/offer
+	Revision string // Changes whenever the content changes
+}
+
+// TemplateVersion describes a loaded version of a template of a TemplateStore.
+type TemplateVersion struct {
+	Name    string
+	Version int       // Counts the loaded versions of the template, starting at 1
+	Hash    string    // The SHA-256 hash of the template file
+	Loaded  time.Time // When the version was loaded
+}
+
+// storedTemplate is a template of a TemplateStore with its current version.
+type storedTemplate struct {
+	template *Template
+	version  TemplateVersion
+	revision string
+}
+
+// TemplateStore keeps the templates of a source parsed and up to date, so services can render them by name.
+// Templates are reloaded by Reload or periodically by Watch; a template which fails to load keeps its previous
+// version. A TemplateStore may be used concurrently.
+type TemplateStore struct {
+	source TemplateSource
+	opts   []Option
+
+	mu        sync.RWMutex
+	templates map[string]*storedTemplate
+}
+
+// NewTemplateStore creates a store for the templates of the source and loads them. The options are applied to
+// the documents of all renderings. The store is returned together with the errors of the templates which could
+// not be loaded.
+func NewTemplateStore(ctx context.Context, source TemplateSource, opts ...Option) (*TemplateStore, error) {
+	store := &TemplateStore{source: source, opts: opts, templates: make(map[string]*storedTemplate)}
+	return store, store.Reload(ctx)
+}
+
+// Reload loads the templates which were added or changed since the last reload and removes the templates which
+// no longer exist. Templates whose content did not change keep their version.
+func (s *TemplateStore) Reload(ctx context.Context) error {
+	entries, err := s.source.List(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to list templates: %w", err)
+	}
+
+	s.mu.RLock()
+	current := make(map[string]*storedTemplate, len(s.templates))
+	for name, stored := range s.templates {
+		current[name] = stored
+	}
+	s.mu.RUnlock()
+
+	var errs []error
+	templates := make(map[string]*storedTemplate, len(entries))
+	for _, entry := range entries {
+		previous := current[entry.Name]
+		if previous != nil && previous.revision == entry.Revision {
+			templates[entry.Name] = previous
+			continue
+		}
+		stored, err := s.load(ctx, entry, previous)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("unable to load template %s: %w", entry.Name, err))
+			if previous != nil {
+				templates[entry.Name] = previous
+			}
+			continue
+		}
+		templates[entry.Name] = stored
+	}
+
+	s.mu.Lock()
+	s.templates = templates
+	s.mu.Unlock()
+	return errors.Join(errs...)
+}
+
+// load reads and parses the template of the entry. The version is only incremented if the content changed.
+func (s *TemplateStore) load(ctx context.Context, entry TemplateEntry, previous *storedTemplate) (*storedTemplate, error) {
+	data, err := s.source.Read(ctx, entry.Name)
+	if err != nil {
+		return nil, err
+	}
+	hash := contentHash(data)
+	if previous != nil && previous.version.Hash == hash {
+		return &storedTemplate{template: previous.template, version: previous.version, revision: entry.Revision}, nil
+	}
+	tpl, err := NewTemplate(data, s.opts...)
+	if err != nil {
+		return nil, err
+	}
+	version := TemplateVersion{Name: entry.Name, Version: 1, Hash: hash, Loaded: time.Now()}
+	if previous != nil {
+		version.Version = previous.version.Version + 1
+	}
+	return &storedTemplate{template: tpl, version: version, revision: entry.Revision}, nil
+}
+
+// Watch reloads the templates in the given interval until the context is canceled. Errors of the reloads are
+// passed to onError if it is not nil.
+func (s *TemplateStore) Watch(ctx context.Context, interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Reload(ctx); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+// Template returns the current version of the template with the given name.
+func (s *TemplateStore) Template(name string) (*Template, TemplateVersion, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	stored, exists := s.templates[name]
+	if !exists {
+		return nil, TemplateVersion{}, false
+	}
+	return stored.template, stored.version, true
+}
+
+// Versions returns the current versions of all templates, sorted by name.
+func (s *TemplateStore) Versions() []TemplateVersion {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	versions := make([]TemplateVersion, 0, len(s.templates))
+	for _, stored := range s.templates {
+		versions = append(versions, stored.version)
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].Name < versions[j].Name
+	})
+	return versions
+}
+
+// Render renders the current version of the template with the given name, see Render.
+func (s *TemplateStore) Render(name string, data interface{}) (*Document, error) {
+	tpl, _, exists := s.Template(name)
+	if !exists {
+		return nil, fmt.Errorf("template %s not found", name)
+	}
+	return Render(tpl, data)
+}
+
+// templateExtensions are the extensions of the files read by DirSource.
+var templateExtensions = []string{".docx", ".docm", ".dotx", ".dotm"}
+
+// dirSource is a TemplateSource reading the templates of a directory.
+type dirSource struct {
+	dir string
+}
+
+// DirSource returns a source for the .docx, .docm, .dotx and .dotm files of the directory and its
+// subdirectories. The templates are named by their path relative to the directory without the extension, using
+// forward slashes, e.g. invoices/offer for invoices/offer.docx.
+func DirSource(dir string) TemplateSource {
+	return dirSource{dir: dir}
+}
+
+// List implements TemplateSource. The revision is the modification time and the size of the file.
+func (s dirSource) List(ctx context.Context) ([]TemplateEntry, error) {
+	var entries []TemplateEntry
+	err := filepath.WalkDir(s.dir, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		// files starting with ~$ are created by Word while a document is open
+		if entry.IsDir() || !slices.Contains(templateExtensions, strings.ToLower(filepath.Ext(file))) || strings.HasPrefix(entry.Name(), "~$") {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		relative, err := filepath.Rel(s.dir, file)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(relative)
+		entries = append(entries, TemplateEntry{
+			Name:     strings.TrimSuffix(name, path.Ext(name)),
+			Revision: fmt.Sprintf("%d-%d", info.ModTime().UnixNano(), info.Size()),
+		})
+		return nil
+	})
+	return entries, err
+}
+
+// Read implements TemplateSource.
+func (s dirSource) Read(ctx context.Context, name string) ([]byte, error) {
+	if !fs.ValidPath(name) {
+		return nil, fmt.Errorf("invalid template name %s", name)
+	}
+	for _, ext := range templateExtensions {
+		data, err := os.ReadFile(filepath.Join(s.dir, filepath.FromSlash(name)+ext))
+		if !errors.Is(err, fs.ErrNotExist) {
+			return data, err
+		}
+	}
+	return nil, fmt.Errorf("template %s not found", name)
+}
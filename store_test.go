@@ -0,0 +1,90 @@
+package docx
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTemplateStore(t *testing.T) {
+	dir := t.TempDir()
+	original := readFile(t, "./test/template.docx")
+	changed := rewriteArchive(t, original, func(name string, data []byte) []byte {
+		if name == DocumentXml {
+			return []byte(strings.Replace(string(data), "<w:body>", `<w:body><w:p><w:r><w:t>Version 2 for {{.name}}</w:t></w:r></w:p>`, 1))
+		}
+		return data
+	}, nil)
+
+	write := func(name string, data []byte, modified time.Time) {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(file, modified, modified); err != nil {
+			t.Fatal(err)
+		}
+	}
+	start := time.Now().Add(-time.Hour)
+	write("letters/offer.docx", original, start)
+	write("notes.txt", []byte("no template"), start)
+
+	ctx := context.Background()
+	store, err := NewTemplateStore(ctx, DirSource(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	versions := store.Versions()
+	if len(versions) != 1 || versions[0].Name != "letters/offer" || versions[0].Version != 1 {
+		t.Fatalf("unexpected templates %+v", versions)
+	}
+
+	// touching the file keeps the version, changing the content loads a new version
+	write("letters/offer.docx", original, start.Add(time.Minute))
+	if err := store.Reload(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, version, _ := store.Template("letters/offer"); version.Version != 1 {
+		t.Errorf("expected version 1 of the unchanged template, got %d", version.Version)
+	}
+	write("letters/offer.docx", changed, start.Add(2*time.Minute))
+	if err := store.Reload(ctx); err != nil {
+		t.Fatal(err)
+	}
+	doc, err := store.Render("letters/offer", map[string]interface{}{"name": "Anna"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(doc.GetFile(DocumentXml)), "Version 2 for Anna") {
+		t.Errorf("the changed template was not rendered")
+	}
+	doc.Close()
+	if _, version, _ := store.Template("letters/offer"); version.Version != 2 || version.Hash != contentHash(changed) {
+		t.Errorf("unexpected version %+v", version)
+	}
+
+	// a broken template keeps its previous version
+	write("letters/offer.docx", []byte("broken"), start.Add(3*time.Minute))
+	if err := store.Reload(ctx); err == nil || !strings.Contains(err.Error(), "letters/offer") {
+		t.Errorf("expected an error for the broken template, got %v", err)
+	}
+	if _, version, exists := store.Template("letters/offer"); !exists || version.Version != 2 {
+		t.Errorf("the previous version was not kept")
+	}
+
+	if err := os.Remove(filepath.Join(dir, "letters", "offer.docx")); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Reload(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Render("letters/offer", nil); err == nil {
+		t.Errorf("expected an error for the removed template")
+	}
+}
@@ -0,0 +1,86 @@
+package docx
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// storiesDocument returns the test template with placeholders in every story type.
+func storiesDocument(t *testing.T) []byte {
+	note := func(element, text string) string {
+		return `<w:` + element + ` w:id="5"><w:p><w:r><w:t>` + text + `</w:t></w:r></w:p></w:` + element + `>`
+	}
+	textBox := `<w:p><w:r><mc:AlternateContent><mc:Choice Requires="wps"><w:drawing><wp:anchor><a:graphic><a:graphicData><wps:wsp><wps:txbx><w:txbxContent>` +
+		`<w:p><w:r><w:t>Box {{.box}}</w:t></w:r></w:p></w:txbxContent></wps:txbx></wps:wsp></a:graphicData></a:graphic></wp:anchor></w:drawing></mc:Choice>` +
+		`<mc:Fallback><w:pict><v:shape><v:textbox><w:txbxContent><w:p><w:r><w:t>Box {{.box}}</w:t></w:r></w:p></w:txbxContent></v:textbox></v:shape></w:pict></mc:Fallback></mc:AlternateContent></w:r></w:p>`
+	return rewriteArchive(t, readFile(t, "./test/template.docx"), func(name string, data []byte) []byte {
+		switch name {
+		case FootnotesXml:
+			return []byte(strings.Replace(string(data), "</w:footnotes>", note("footnote", "Footnote {{.note}}")+"</w:footnotes>", 1))
+		case EndnotesXml:
+			return []byte(strings.Replace(string(data), "</w:endnotes>", note("endnote", "Endnote {{.note}}")+"</w:endnotes>", 1))
+		case "word/header1.xml":
+			return []byte(strings.Replace(string(data), "</w:hdr>", textBox+"</w:hdr>", 1))
+		}
+		return data
+	}, map[string][]byte{
+		CommentsXml: []byte(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+			`<w:comments xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main">` + note("comment", "Comment {{.note}}") + `</w:comments>`),
+	})
+}
+
+func TestDocument_ExecuteTemplate_Stories(t *testing.T) {
+	doc, err := OpenBytes(storiesDocument(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+	if err := doc.ExecuteTemplate(map[string]interface{}{"note": "rendered", "box": "rendered"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := doc.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	written, err := OpenBytes(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer written.Close()
+	expected := map[string]string{
+		FootnotesXml:       "Footnote rendered",
+		EndnotesXml:        "Endnote rendered",
+		CommentsXml:        "Comment rendered",
+		"word/header1.xml": "Box rendered",
+	}
+	for part, text := range expected {
+		data := string(written.GetFile(part))
+		if !strings.Contains(data, text) || strings.Contains(data, "{{") {
+			t.Errorf("the placeholders of %s were not rendered", part)
+		}
+	}
+	if strings.Count(string(written.GetFile("word/header1.xml")), "Box rendered") != 2 {
+		t.Errorf("both branches of the text box in the header must be rendered")
+	}
+}
+
+func TestDocument_ExecuteTemplate_StoriesNotSelected(t *testing.T) {
+	doc, err := OpenBytesWithOptions(storiesDocument(t), OpenOptions{Parts: PartBody | PartFootnotes})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+	if err := doc.ExecuteTemplate(map[string]interface{}{"note": "rendered", "box": "rendered"}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(doc.GetFile(FootnotesXml)), "Footnote rendered") {
+		t.Errorf("the footnotes were not rendered")
+	}
+	for _, part := range []string{EndnotesXml, CommentsXml} {
+		if doc.GetFile(part) != nil {
+			t.Errorf("%s is not selected and must not be processed", part)
+		}
+	}
+}
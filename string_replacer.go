@@ -9,7 +9,9 @@ import (
 // StringReplacer provides string-based placeholder replacement functionality
 type StringReplacer struct {
 	document *Document
-	debug    bool // Enable debug logging
+	logger   Logger // The logger of the debug messages, the standard output is used if nil
+	debug    bool   // Enable debug logging
+	length   LengthPolicy
 }
 
 // NewStringReplacer creates a new string replacer for the given document
@@ -26,9 +28,14 @@ func (sr *StringReplacer) SetDebug(debug bool) {
 
 // debugLog logs a message if debug mode is enabled
 func (sr *StringReplacer) debugLog(format string, args ...interface{}) {
-	if sr.debug {
-		fmt.Printf("[DEBUG] "+format+"\n", args...)
+	if !sr.debug {
+		return
 	}
+	if sr.logger != nil {
+		sr.logger.Printf("[DEBUG] "+format, args...)
+		return
+	}
+	fmt.Printf("[DEBUG] "+format+"\n", args...)
 }
 
 // ReplaceAll replaces all string-based placeholders in the document using the provided PlaceholderMap.
@@ -39,7 +46,9 @@ func (sr *StringReplacer) ReplaceAll(replaceMap PlaceholderMap) error {
 	sr.debugLog("Starting string-based placeholder replacement...")
 	sr.debugLog("Found %d placeholders to replace", len(replaceMap))
 
-	// Process each file in the document
+	// all parts are replaced before any is updated, so a value which doesn't fit its placeholder (see
+	// LengthPolicy) leaves the document unchanged
+	updates := make(map[string]string)
 	for fileName := range sr.document.files {
 		sr.debugLog("Processing file: %s", fileName)
 
@@ -50,24 +59,48 @@ func (sr *StringReplacer) ReplaceAll(replaceMap PlaceholderMap) error {
 		}
 
 		// Replace placeholders in this file
-		newContent, err := sr.replacePlaceholdersInFile(string(fileContent), replaceMap)
+		newContent, err := sr.replacePlaceholdersInFile(fileName, string(fileContent), replaceMap)
 		if err != nil {
 			return fmt.Errorf("failed to replace placeholders in file %s: %w", fileName, err)
 		}
+		updates[fileName] = newContent
+	}
 
-		// Update the file content
-		err = sr.document.SetFile(fileName, []byte(newContent))
+	// the text of SmartArt diagrams is stored in separate parts
+	diagramUpdates := make(map[string]string)
+	for _, fileName := range sr.document.diagramParts() {
+		fileContent, _ := sr.document.loadPackageFile(fileName)
+		newContent, err := sr.replacePlaceholdersInFile(fileName, string(fileContent), replaceMap)
 		if err != nil {
+			return fmt.Errorf("failed to replace placeholders in file %s: %w", fileName, err)
+		}
+		if newContent != string(fileContent) {
+			diagramUpdates[fileName] = newContent
+		}
+	}
+
+	for fileName, newContent := range updates {
+		if err := sr.document.SetFile(fileName, []byte(newContent)); err != nil {
 			return fmt.Errorf("failed to update file %s: %w", fileName, err)
 		}
 	}
+	for fileName, newContent := range diagramUpdates {
+		sr.document.setPackageFile(fileName, []byte(newContent))
+	}
+
+	// the fallback of a text box may differ from its choice, so it is only synchronized if the parts may be resized
+	if sr.length == LengthResize {
+		if err := sr.document.syncTextBoxes(); err != nil {
+			return err
+		}
+	}
 
 	sr.debugLog("String-based placeholder replacement completed successfully")
 	return nil
 }
 
 // replacePlaceholdersInFile replaces all placeholders in a single file's content
-func (sr *StringReplacer) replacePlaceholdersInFile(content string, replaceMap PlaceholderMap) (string, error) {
+func (sr *StringReplacer) replacePlaceholdersInFile(fileName, content string, replaceMap PlaceholderMap) (string, error) {
 	result := content
 
 	// Process each placeholder in the replace map
@@ -81,7 +114,16 @@ func (sr *StringReplacer) replacePlaceholdersInFile(content string, replaceMap P
 		count := strings.Count(result, fullPlaceholder)
 		if count > 0 {
 			sr.debugLog("Found %d occurrences of {%s}", count, placeholder)
-			result = strings.ReplaceAll(result, fullPlaceholder, replacement)
+			replacement = sr.document.applyReplaceHooks(fileName, fullPlaceholder, replacement)
+			sanitized, err := sanitizeValue(replacement, sr.document.invalidChars)
+			if err != nil {
+				return "", fmt.Errorf("the value of %s: %w", fullPlaceholder, err)
+			}
+			fitted, err := fitLength(fullPlaceholder, sanitized, sr.length)
+			if err != nil {
+				return "", err
+			}
+			result = strings.ReplaceAll(result, fullPlaceholder, fitted)
 		} else {
 			sr.debugLog("No occurrences found for {%s}", placeholder)
 		}
@@ -131,3 +173,16 @@ func (sr *StringReplacer) ValidatePlaceholders(replaceMap PlaceholderMap) ([]str
 
 	return missingPlaceholders, nil
 }
+
+// fitLength returns the replacement of the placeholder according to the length policy: the replacement itself,
+// padded with spaces to the length of the placeholder or an error if it doesn't fit.
+func fitLength(placeholder, replacement string, policy LengthPolicy) (string, error) {
+	switch {
+	case policy == LengthResize || len(replacement) == len(placeholder):
+		return replacement, nil
+	case policy == LengthPad && len(replacement) < len(placeholder):
+		return replacement + strings.Repeat(" ", len(placeholder)-len(replacement)), nil
+	}
+	return "", fmt.Errorf("the value of %s has %d bytes, the placeholder %d bytes (length policy %s)",
+		placeholder, len(replacement), len(placeholder), policy)
+}
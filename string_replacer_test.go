@@ -0,0 +1,70 @@
+package docx
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDocument_ReplaceAll_LengthPolicy(t *testing.T) {
+	partSizes := func(doc *Document) map[string]int {
+		sizes := make(map[string]int)
+		for _, part := range doc.xmlParts() {
+			sizes[part] = len(doc.GetFile(part))
+		}
+		return sizes
+	}
+
+	tests := []struct {
+		name    string
+		policy  LengthPolicy
+		value   string
+		padded  string // the expected text of the replaced placeholder, empty if an error is expected
+		resized bool
+	}{
+		{"resize", LengthResize, "a longer value", "a longer value", true},
+		{"pad a shorter value", LengthPad, "ab", "ab   ", false},
+		{"pad a value of the same length", LengthPad, "abcde", "abcde", false},
+		{"pad a longer value", LengthPad, "abcdef", "", false},
+		{"exact length", LengthExact, "abcde", "abcde", false},
+		{"exact a shorter value", LengthExact, "ab", "", false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			doc, err := Open("./test/template.docx", WithLengthPolicy(test.policy))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer doc.Close()
+			before := partSizes(doc)
+			original := string(doc.GetFile(DocumentXml))
+
+			err = doc.ReplaceAll(PlaceholderMap{"key": test.value})
+			if test.padded == "" {
+				if err == nil || !strings.Contains(err.Error(), "the value of {key} has") {
+					t.Errorf("expected an error for the length of the value, got %v", err)
+				}
+				if string(doc.GetFile(DocumentXml)) != original {
+					t.Errorf("the document must not be modified if a value doesn't fit")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			documentXml := string(doc.GetFile(DocumentXml))
+			if !strings.Contains(documentXml, ">"+test.padded+"<") || strings.Contains(documentXml, "{key}") {
+				t.Errorf("expected {key} to be replaced with %q", test.padded)
+			}
+			after := partSizes(doc)
+			for part, size := range before {
+				if resized := after[part] != size; resized && !test.resized {
+					t.Errorf("expected %s to keep its length of %d bytes, got %d", part, size, after[part])
+				}
+			}
+		})
+	}
+
+	if LengthPad.String() != "pad" || LengthPolicy(7).String() != "LengthPolicy(7)" {
+		t.Errorf("unexpected names %s, %s", LengthPad, LengthPolicy(7))
+	}
+}
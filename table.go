@@ -0,0 +1,638 @@
+package docx
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+var (
+	// rowsActionRegex matches the {{rows PIPELINE}} action which marks a table row to be repeated.
+	rowsActionRegex = regexp.MustCompile(`\{\{-?\s*rows\s+([^<]*?)(?:\s+-)?\s*\}\}`)
+	// columnsActionRegex matches the {{columns PIPELINE}} action which marks a table column to be repeated.
+	columnsActionRegex = regexp.MustCompile(`\{\{-?\s*columns\s+([^<]*?)(?:\s+-)?\s*\}\}`)
+	// rawActionRegex matches a template action which is not split across multiple runs.
+	rawActionRegex = regexp.MustCompile(`\{\{[^<]*?\}\}`)
+)
+
+// tableLayout describes the structure of a <w:tbl> element by the offsets of its elements.
+type tableLayout struct {
+	Start    int64
+	End      int64
+	GridCols []elementRange // all <w:gridCol> elements of the table grid
+	Rows     []tableRowLayout
+}
+
+// tableRowLayout describes a single <w:tr> of a tableLayout.
+type tableRowLayout struct {
+	Start      int64
+	End        int64
+	GridBefore int // number of grid columns skipped before the first cell
+	Cells      []tableCellLayout
+}
+
+// tableCellLayout describes a single <w:tc> of a tableRowLayout.
+type tableCellLayout struct {
+	Start    int64
+	End      int64
+	GridSpan int    // number of grid columns the cell spans
+	Text     string // the text of the cell, excluding nested tables
+}
+
+// replacement replaces the range of a part with new content.
+type replacement struct {
+	Start   int64
+	End     int64
+	Content []byte
+}
+
+// applyReplacements applies the replacements, which must be sorted by their position and must not overlap.
+func applyReplacements(data []byte, replacements []replacement) []byte {
+	// replace from the back, so the earlier positions stay valid
+	for i := len(replacements) - 1; i >= 0; i-- {
+		r := replacements[i]
+		data = append(append(append([]byte{}, data[:r.Start]...), r.Content...), data[r.End:]...)
+	}
+	return data
+}
+
+// elementRange is the range of an element from its open tag to the end of its close tag.
+type elementRange struct {
+	Start int64
+	End   int64
+}
+
+// cellAt returns the index of the cell which covers the given grid column, or -1.
+func (r *tableRowLayout) cellAt(gridColumn int) int {
+	column := r.GridBefore
+	for i, cell := range r.Cells {
+		if gridColumn >= column && gridColumn < column+cell.GridSpan {
+			return i
+		}
+		column += cell.GridSpan
+	}
+	return -1
+}
+
+// gridColumn returns the first grid column covered by the given cell.
+func (r *tableRowLayout) gridColumn(cell int) int {
+	column := r.GridBefore
+	for _, c := range r.Cells[:cell] {
+		column += c.GridSpan
+	}
+	return column
+}
+
+// findTables returns the layout of all tables of the part in document order, including nested tables.
+func findTables(data []byte) ([]*tableLayout, error) {
+	docReader := NewReader(string(data))
+	decoder := xml.NewDecoder(docReader)
+
+	var tables []*tableLayout
+	var open []*tableLayout // the currently open tables, the innermost last
+	inText := false
+	for {
+		start := docReader.Pos()
+		tok, err := decoder.Token()
+		if tok == nil || err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error getting token: %s", err)
+		}
+
+		var table *tableLayout
+		if len(open) > 0 {
+			table = open[len(open)-1]
+		}
+
+		switch elem := tok.(type) {
+		case xml.StartElement:
+			switch elem.Name.Local {
+			case TableElementName:
+				table = &tableLayout{Start: start}
+				tables = append(tables, table)
+				open = append(open, table)
+			case "gridCol":
+				if table != nil {
+					table.GridCols = append(table.GridCols, elementRange{Start: start})
+				}
+			case TableRowElementName:
+				if table != nil {
+					table.Rows = append(table.Rows, tableRowLayout{Start: start})
+				}
+			case TableCellElementName:
+				if row := table.lastRow(); row != nil {
+					row.Cells = append(row.Cells, tableCellLayout{Start: start, GridSpan: 1})
+				}
+			case "gridBefore":
+				if row := table.lastRow(); row != nil {
+					row.GridBefore, _ = strconv.Atoi(attrValue(elem, "val"))
+				}
+			case "gridSpan":
+				if cell := table.lastCell(); cell != nil {
+					if span, err := strconv.Atoi(attrValue(elem, "val")); err == nil && span > 0 {
+						cell.GridSpan = span
+					}
+				}
+			case "t":
+				inText = true
+			}
+		case xml.EndElement:
+			switch elem.Name.Local {
+			case TableElementName:
+				if table != nil {
+					table.End = docReader.Pos()
+					open = open[:len(open)-1]
+				}
+			case "gridCol":
+				if table != nil && len(table.GridCols) > 0 {
+					table.GridCols[len(table.GridCols)-1].End = docReader.Pos()
+				}
+			case TableRowElementName:
+				if row := table.lastRow(); row != nil {
+					row.End = docReader.Pos()
+				}
+			case TableCellElementName:
+				if cell := table.lastCell(); cell != nil {
+					cell.End = docReader.Pos()
+				}
+			case "t":
+				inText = false
+			}
+		case xml.CharData:
+			if cell := table.lastCell(); inText && cell != nil {
+				cell.Text += string(elem)
+			}
+		}
+	}
+	return tables, nil
+}
+
+// lastRow returns the row which was found last, or nil.
+func (t *tableLayout) lastRow() *tableRowLayout {
+	if t == nil || len(t.Rows) == 0 {
+		return nil
+	}
+	return &t.Rows[len(t.Rows)-1]
+}
+
+// lastCell returns the cell of the last row which was found last, or nil.
+func (t *tableLayout) lastCell() *tableCellLayout {
+	row := t.lastRow()
+	if row == nil || len(row.Cells) == 0 {
+		return nil
+	}
+	return &row.Cells[len(row.Cells)-1]
+}
+
+// executeTables repeats all table rows marked with {{rows PIPELINE}} and all table columns marked with
+// {{columns PIPELINE}} once per item of the pipeline, just like {{range PIPELINE}}. The action may be placed
+// in any cell of the row or column, usually the first one or the header. Inside the cloned cells the dot refers
+// to the item, the data is still available as $. Afterwards the rows and columns hidden by {{rowif}} or {{columnif}}
+// are removed, the cells marked with {{vmerge}} or {{hmerge}} are merged and the layout helpers are applied.
+func (tr *TemplateReplacer) executeTables() error {
+	for _, fileName := range tr.document.xmlParts() {
+		tr.part = fileName
+		modified := false
+		for {
+			data := tr.document.GetFile(fileName)
+			tables, err := findTables(data)
+			if err != nil {
+				return fmt.Errorf("failed to parse tables in %s: %w", fileName, err)
+			}
+
+			data, found, err := tr.expandNextRange(data, tables)
+			if err != nil {
+				return err
+			}
+			if !found {
+				break
+			}
+			if err := tr.document.SetFile(fileName, data); err != nil {
+				return err
+			}
+			modified = true
+		}
+
+		data := tr.document.GetFile(fileName)
+		processed, err := tr.executeTableConditions(data)
+		if err != nil {
+			return err
+		}
+		if processed, err = hideMarkedCells(processed); err != nil {
+			return fmt.Errorf("failed to hide cells in %s: %w", fileName, err)
+		}
+		if processed, err = mergeCells(processed); err != nil {
+			return fmt.Errorf("failed to merge cells in %s: %w", fileName, err)
+		}
+		if processed, err = applyTableLayout(processed); err != nil {
+			return fmt.Errorf("failed to lay out tables in %s: %w", fileName, err)
+		}
+		if !modified && bytes.Equal(processed, data) {
+			continue
+		}
+		if err := tr.document.SetFile(fileName, processed); err != nil {
+			return err
+		}
+		if err := tr.document.refreshRuns(fileName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// expandNextRange expands the first row or column marked with {{rows}} or {{columns}}.
+// Since all offsets change, only one range is expanded per call. Errors are reported as TemplateError.
+func (tr *TemplateReplacer) expandNextRange(data []byte, tables []*tableLayout) ([]byte, bool, error) {
+	for _, table := range tables {
+		for r, row := range table.Rows {
+			for c, cell := range row.Cells {
+				for _, re := range []*regexp.Regexp{rowsActionRegex, columnsActionRegex} {
+					match := re.FindStringSubmatch(cell.Text)
+					if match == nil {
+						continue
+					}
+					if !re.Match(data[cell.Start:cell.End]) {
+						err := errors.New("the action must not be split across multiple runs")
+						return nil, false, newTemplateError(data, tr.part, cell.Start, match[0], err)
+					}
+
+					var expanded []byte
+					var err error
+					if re == rowsActionRegex {
+						tr.debugLog("Expanding table row %s", match[0])
+						expanded, err = tr.expandRow(data, table.Rows[r], match[0], match[1])
+					} else {
+						if cell.GridSpan != 1 {
+							err := errors.New("the column must not span multiple grid columns")
+							return nil, false, newTemplateError(data, tr.part, cell.Start, match[0], err)
+						}
+						tr.debugLog("Expanding table column %s", match[0])
+						expanded, err = tr.expandColumn(data, table, row.gridColumn(c), match[1])
+					}
+					if err != nil {
+						return nil, false, newTemplateError(data, tr.part, cell.Start, match[0], err)
+					}
+					return expanded, true, nil
+				}
+			}
+		}
+	}
+	return data, false, nil
+}
+
+// expandRow replaces the row by one row per item of the pipeline. The rows exceeding the part size limit are
+// truncated according to the overflow policy.
+func (tr *TemplateReplacer) expandRow(data []byte, row tableRowLayout, action, pipeline string) ([]byte, error) {
+	rowXml := rowsActionRegex.ReplaceAll(data[row.Start:row.End], nil)
+	rows, err := tr.executeFragment("{{range "+pipeline+"}}"+string(rowXml)+"{{end}}", tr.data)
+	if err != nil {
+		return nil, err
+	}
+	rest := len(data) - int(row.End-row.Start)
+	if err := tr.partSizeError(rows, rest); err != nil {
+		if !tr.truncate(newTemplateError(data, tr.part, row.Start, action, err)) {
+			return nil, err
+		}
+		rows = truncateElements(rows, tr.limits.MaxPartSize-rest)
+	}
+	return applyReplacements(data, []replacement{{row.Start, row.End, rows}}), nil
+}
+
+// expandColumn replaces the given grid column of the table by one column per item of the pipeline.
+// The widths of the grid column and its cells are divided among the new columns, so the table keeps its width.
+// Cells spanning the column are widened (or narrowed if there are no items) instead. Without items, rows which
+// have no other cells are removed, and the table is removed if no row remains, like columns hidden by {{columnif}}.
+func (tr *TemplateReplacer) expandColumn(data []byte, table *tableLayout, gridColumn int, pipeline string) ([]byte, error) {
+	count, err := tr.countItems(pipeline)
+	if err != nil {
+		return nil, err
+	}
+
+	var replacements []replacement
+	emptied := 0 // the rows without any cell left
+
+	if gridColumn < len(table.GridCols) {
+		gridCol := table.GridCols[gridColumn]
+		tag := divideWidth(data[gridCol.Start:gridCol.End], "w:w", count)
+		replacements = append(replacements, replacement{gridCol.Start, gridCol.End, bytes.Repeat(tag, count)})
+	}
+
+	for _, row := range table.Rows {
+		c := row.cellAt(gridColumn)
+		if c < 0 {
+			continue
+		}
+		cell := row.Cells[c]
+		cellXml := data[cell.Start:cell.End]
+
+		if count == 0 && cell.GridSpan == 1 && len(row.Cells) == 1 {
+			// a row without cells is invalid
+			replacements = append(replacements, replacement{row.Start, row.End, nil})
+			emptied++
+			continue
+		}
+		if cell.GridSpan > 1 {
+			// the cell spans the column as well as others, only its span changes
+			span := []byte(fmt.Sprintf(`<w:gridSpan w:val="%d"/>`, cell.GridSpan+count-1))
+			cellXml = setCellProperty(cellXml, "w:gridSpan", span)
+			replacements = append(replacements, replacement{cell.Start, cell.End, cellXml})
+			continue
+		}
+
+		cellXml = columnsActionRegex.ReplaceAll(cellXml, nil)
+		if tcW := cellProperty(cellXml, "w:tcW"); tcW != nil {
+			cellXml = setCellProperty(cellXml, "w:tcW", divideWidth(tcW, "w:w", count))
+		}
+		cells, err := tr.executeFragment("{{range "+pipeline+"}}"+string(cellXml)+"{{end}}", tr.data)
+		if err != nil {
+			return nil, err
+		}
+		replacements = append(replacements, replacement{cell.Start, cell.End, cells})
+	}
+
+	if emptied == len(table.Rows) {
+		return removeTable(data, table), nil
+	}
+	return applyReplacements(data, replacements), nil
+}
+
+// inlineTableRanges rewrites the rows and columns of the fragment marked with {{rows}} or {{columns}} to {{range}}
+// actions, so they are repeated with the dot of the fragment when it is executed as a whole, e.g. the item of a
+// {{range}} block spanning multiple paragraphs. The widths and spans of the columns are divided like expandColumn
+// does, but by the functions docxColumnWidth and docxColumnSpan when the fragment is executed.
+func inlineTableRanges(fragment []byte) ([]byte, error) {
+	for n := 1; ; n++ {
+		tables, err := findTables(fragment)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse tables: %w", err)
+		}
+		inlined, found, err := inlineNextRange(fragment, tables, fmt.Sprintf("$docxItems%d", n))
+		if err != nil || !found {
+			return fragment, err
+		}
+		fragment = inlined
+	}
+}
+
+// inlineNextRange rewrites the first row or column marked with {{rows}} or {{columns}}, see inlineTableRanges.
+// The items of a column are assigned to the variable, since its grid column and all its cells refer to them.
+func inlineNextRange(data []byte, tables []*tableLayout, variable string) ([]byte, bool, error) {
+	for _, table := range tables {
+		for _, row := range table.Rows {
+			for c, cell := range row.Cells {
+				for _, re := range []*regexp.Regexp{rowsActionRegex, columnsActionRegex} {
+					match := re.FindStringSubmatch(cell.Text)
+					if match == nil {
+						continue
+					}
+					if !re.Match(data[cell.Start:cell.End]) {
+						return nil, false, fmt.Errorf("%s: the action must not be split across multiple runs", match[0])
+					}
+					if re == rowsActionRegex {
+						rowXml := rowsActionRegex.ReplaceAll(data[row.Start:row.End], nil)
+						rows := "{{range " + match[1] + "}}" + string(rowXml) + "{{end}}"
+						return applyReplacements(data, []replacement{{row.Start, row.End, []byte(rows)}}), true, nil
+					}
+					if cell.GridSpan != 1 {
+						return nil, false, fmt.Errorf("%s: the column must not span multiple grid columns", match[0])
+					}
+					return inlineColumn(data, table, row.gridColumn(c), match[1], variable), true, nil
+				}
+			}
+		}
+	}
+	return data, false, nil
+}
+
+// inlineColumn rewrites the grid column of the table to a {{range}} of its items, see expandColumn.
+func inlineColumn(data []byte, table *tableLayout, gridColumn int, pipeline, variable string) []byte {
+	var replacements []replacement
+	emptied := 0 // the rows without any cell left if there are no items
+
+	if gridColumn < len(table.GridCols) {
+		gridCol := table.GridCols[gridColumn]
+		tag := inlineWidth(data[gridCol.Start:gridCol.End], "w:w", variable)
+		replacements = append(replacements, replacement{gridCol.Start, gridCol.End, []byte("{{range " + variable + "}}" + string(tag) + "{{end}}")})
+	}
+
+	for _, row := range table.Rows {
+		c := row.cellAt(gridColumn)
+		if c < 0 {
+			continue
+		}
+		cell := row.Cells[c]
+		cellXml := data[cell.Start:cell.End]
+
+		if cell.GridSpan > 1 {
+			// the cell spans the column as well as others, only its span changes
+			span := []byte(fmt.Sprintf(`<w:gridSpan w:val="{{docxColumnSpan %d %s}}"/>`, cell.GridSpan, variable))
+			replacements = append(replacements, replacement{cell.Start, cell.End, setCellProperty(cellXml, "w:gridSpan", span)})
+			continue
+		}
+
+		cellXml = columnsActionRegex.ReplaceAll(cellXml, nil)
+		if tcW := cellProperty(cellXml, "w:tcW"); tcW != nil {
+			cellXml = setCellProperty(cellXml, "w:tcW", inlineWidth(tcW, "w:w", variable))
+		}
+		cells := "{{range " + variable + "}}" + string(cellXml) + "{{end}}"
+		if len(row.Cells) == 1 {
+			// a row without cells is invalid
+			rowXml := string(data[row.Start:cell.Start]) + cells + string(data[cell.End:row.End])
+			replacements = append(replacements, replacement{row.Start, row.End, []byte("{{if docxHasItems " + variable + "}}" + rowXml + "{{end}}")})
+			emptied++
+			continue
+		}
+		replacements = append(replacements, replacement{cell.Start, cell.End, []byte(cells)})
+	}
+
+	inlined := applyReplacements(data, replacements)
+	tableEnd := table.End + int64(len(inlined)-len(data))
+	prefix, suffix := "{{"+variable+" := "+pipeline+"}}", ""
+	if emptied == len(table.Rows) {
+		// like removeTable, an empty paragraph is kept if the table was the last element of a cell
+		prefix += "{{if docxHasItems " + variable + "}}"
+		suffix = "{{end}}"
+		if bytes.HasPrefix(bytes.TrimLeft(inlined[tableEnd:], " \t\r\n"), []byte("</w:tc>")) {
+			suffix = "{{else}}<w:p/>{{end}}"
+		}
+	}
+	return applyReplacements(inlined, []replacement{{table.Start, table.Start, []byte(prefix)}, {tableEnd, tableEnd, []byte(suffix)}})
+}
+
+// inlineWidth replaces the width of the attribute by the call of docxColumnWidth, see divideWidth.
+func inlineWidth(tag []byte, attr, variable string) []byte {
+	value, exists := getTagAttr(tag, attr)
+	width, err := strconv.Atoi(strings.TrimSpace(value))
+	if !exists || err != nil {
+		return tag
+	}
+	return setTagAttr(tag, attr, fmt.Sprintf("{{docxColumnWidth %d %s}}", width, variable))
+}
+
+// columnWidthHelper implements docxColumnWidth, which divides the width of a column among its items.
+func columnWidthHelper(width int, items interface{}) (rawXML, error) {
+	count, err := rangeCount(items)
+	if err != nil || count <= 1 {
+		return rawXML(strconv.Itoa(width)), err
+	}
+	return rawXML(strconv.Itoa(width / count)), nil
+}
+
+// columnSpanHelper implements docxColumnSpan, which widens a cell spanning a column by its items.
+func columnSpanHelper(span int, items interface{}) (rawXML, error) {
+	count, err := rangeCount(items)
+	return rawXML(strconv.Itoa(span + count - 1)), err
+}
+
+// hasItemsHelper implements docxHasItems, which reports whether {{range}} iterates over the items.
+func hasItemsHelper(items interface{}) (bool, error) {
+	count, err := rangeCount(items)
+	return count > 0, err
+}
+
+// rangeCount returns the number of iterations of {{range}} over the value.
+func rangeCount(value interface{}) (int, error) {
+	v := indirect(reflect.ValueOf(value))
+	switch v.Kind() {
+	case reflect.Invalid:
+		return 0, nil
+	case reflect.Array, reflect.Slice, reflect.Map:
+		return v.Len(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return max(int(v.Int()), 0), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return int(v.Uint()), nil
+	}
+	if isNil(value) {
+		return 0, nil
+	}
+	return 0, fmt.Errorf("range can't iterate over %v", value)
+}
+
+// countItems returns the number of iterations of {{range PIPELINE}}.
+func (tr *TemplateReplacer) countItems(pipeline string) (int, error) {
+	result, err := tr.executeFragment("{{range "+pipeline+"}}.{{end}}", tr.data)
+	if err != nil {
+		return 0, err
+	}
+	return len(result), nil
+}
+
+// fragmentFuncs are the functions which are only available inside fragments, see executeFragment.
+var fragmentFuncs = template.FuncMap{
+	"docxEscape":  func(_ string, value interface{}) string { return escapeTemplateValue(value) },
+	"docxScoped":  func(_ string, value interface{}) string { return escapeTemplateValue(value) },
+	"vmerge":      vmergeHelper,
+	"hmerge":      hmergeHelper,
+	"rowif":       rowifHelper,
+	"columnif":    columnifHelper,
+	"tablelayout": tablelayoutHelper,
+	"tablewidth":  tablewidthHelper,
+	"cellwidth":   cellwidthHelper,
+	"cantsplit":   cantsplitHelper,
+	"headerrow":   headerrowHelper,
+	"shade":       shadeHelper,
+	"shaderow":    shaderowHelper,
+	"band":        bandHelper,
+
+	"docxColumnWidth": columnWidthHelper,
+	"docxColumnSpan":  columnSpanHelper,
+	"docxHasItems":    hasItemsHelper,
+}
+
+// executeFragment executes the given XML fragment as template. Unlike regular placeholders, the fragment may
+// contain complete control structures. The results of all actions are XML-escaped and missing values are empty.
+func (tr *TemplateReplacer) executeFragment(fragment string, data TemplateData) (result []byte, err error) {
+	defer recoverPanic(&err)
+	// the actions are part of the XML text, so their quotes etc. are escaped
+	fragment = stripAnnotations(rawActionRegex.ReplaceAllStringFunc(fragment, html.UnescapeString))
+
+	tmpl, err := tr.tmpl.Clone()
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err = tmpl.Funcs(fragmentFuncs).Funcs(template.FuncMap{"docxEscape": tr.escapeValue, "docxScoped": tr.escapeScopedValue}).Parse(fragment)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+	// the actions are escaped first, so the original actions are passed to docxEscape
+	escapeActions(tmpl.Tree.Root, "docxEscape", "docxScoped")
+	nilSafeFields(tmpl.Tree.Root)
+	limitLoops(tmpl.Tree.Root)
+
+	buf := tr.newLimitedWriter()
+	if err := tmpl.Execute(buf, data); err != nil {
+		return nil, fmt.Errorf("failed to execute template: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// escapeActions appends the function, e.g. docxEscape, to all actions which print a value. The action itself is
+// passed to the function, e.g. {{.name | docxEscape "{{.name}}"}}, so the value can be related to its placeholder.
+// Actions inside {{range}} and {{with}}, whose dot is not the data, get the scoped function instead.
+func escapeActions(node parse.Node, function, scoped string) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			escapeActions(child, function, scoped)
+		}
+	case *parse.ActionNode:
+		if len(n.Pipe.Decl) == 0 {
+			action := n.String()
+			n.Pipe.Cmds = append(n.Pipe.Cmds, &parse.CommandNode{
+				NodeType: parse.NodeCommand,
+				Args: []parse.Node{
+					parse.NewIdentifier(function),
+					&parse.StringNode{NodeType: parse.NodeString, Quoted: strconv.Quote(action), Text: action},
+				},
+			})
+		}
+	case *parse.IfNode:
+		escapeActions(n.List, function, scoped)
+		escapeActions(n.ElseList, function, scoped)
+	case *parse.RangeNode:
+		escapeActions(n.List, scoped, scoped)
+		escapeActions(n.ElseList, function, scoped)
+	case *parse.WithNode:
+		escapeActions(n.List, scoped, scoped)
+		escapeActions(n.ElseList, function, scoped)
+	}
+}
+
+// escapeTemplateValue formats the value of an action and escapes it for XML. Missing values are empty,
+// the markers of the merge helpers and the XML written by other helpers are kept as they are.
+func escapeTemplateValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case cellMarker:
+		return string(v)
+	case rawXML:
+		return string(v)
+	}
+	return escapeMarkers(xmlEscape(fmt.Sprint(value)))
+}
+
+// divideWidth divides the numeric value of the attribute of the tag by count.
+func divideWidth(tag []byte, attr string, count int) []byte {
+	value, exists := getTagAttr(tag, attr)
+	width, err := strconv.Atoi(strings.TrimSpace(value))
+	if !exists || err != nil || count <= 1 {
+		return tag
+	}
+	return setTagAttr(tag, attr, strconv.Itoa(width/count))
+}
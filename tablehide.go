@@ -0,0 +1,158 @@
+package docx
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"text/template"
+)
+
+// tablehide.go implements the {{rowif CONDITION}} and {{columnif CONDITION}} helpers, which remove the row or
+// the column of their cell unless the condition is true (e.g. to omit a "Discount" column if no line has a discount).
+// Inside rows and columns cloned by {{rows}} and {{columns}}, the condition is evaluated for each item.
+
+var (
+	// tableConditionActionRegex matches a {{rowif}} or {{columnif}} action or an action of a layout or shading helper
+	// (see tablelayout.go and tableshade.go) which is not part of a cloned row or column.
+	tableConditionActionRegex = regexp.MustCompile(`\{\{-?\s*(?:(?:rowif|columnif|tablelayout|tablewidth|cellwidth|shade|shaderow|band)\s+[^<]*?|(?:cantsplit|headerrow)\s*)\}\}`)
+	// hideMarkerRegex matches the markers written by the rowif and columnif helpers.
+	hideMarkerRegex = regexp.MustCompile(`\[\[docx-hide(row|column)\]\]`)
+)
+
+// rowifHelper implements {{rowif CONDITION}}.
+func rowifHelper(condition interface{}) cellMarker {
+	if isTrue(condition) {
+		return ""
+	}
+	return "[[docx-hiderow]]"
+}
+
+// columnifHelper implements {{columnif CONDITION}}.
+func columnifHelper(condition interface{}) cellMarker {
+	if isTrue(condition) {
+		return ""
+	}
+	return "[[docx-hidecolumn]]"
+}
+
+// isTrue reports whether the value is true in the sense of {{if}}: false, 0, nil and empty values are false.
+func isTrue(value interface{}) bool {
+	truth, _ := template.IsTrue(value)
+	return truth
+}
+
+// executeTableConditions evaluates all {{rowif}}, {{columnif}} and layout actions of the part with the template data.
+func (tr *TemplateReplacer) executeTableConditions(data []byte) ([]byte, error) {
+	var replacements []replacement
+	for _, loc := range tableConditionActionRegex.FindAllIndex(data, -1) {
+		action := string(data[loc[0]:loc[1]])
+		result, err := tr.executeFragment(action, tr.data)
+		if err != nil {
+			return nil, newTemplateError(data, tr.part, int64(loc[0]), action, err)
+		}
+		replacements = append(replacements, replacement{int64(loc[0]), int64(loc[1]), result})
+	}
+	return applyReplacements(data, replacements), nil
+}
+
+// hideMarkedCells removes all rows and columns marked by the rowif and columnif helpers.
+// Tables without any remaining rows or columns are removed completely.
+func hideMarkedCells(data []byte) ([]byte, error) {
+	for {
+		if !hideMarkerRegex.Match(data) {
+			return data, nil
+		}
+		tables, err := findTables(data)
+		if err != nil {
+			return nil, err
+		}
+
+		// nested tables follow their parent, so the last marked table does not contain other marked tables
+		var marked *tableLayout
+		for _, table := range tables {
+			for _, row := range table.Rows {
+				for _, cell := range row.Cells {
+					if hideMarkerRegex.MatchString(cell.Text) {
+						marked = table
+					}
+				}
+			}
+		}
+		if marked == nil {
+			// markers outside of tables have no effect
+			return hideMarkerRegex.ReplaceAll(data, nil), nil
+		}
+		data = hideTableCells(data, marked)
+	}
+}
+
+// hideTableCells removes the marked rows and columns of a single table.
+func hideTableCells(data []byte, table *tableLayout) []byte {
+	hiddenRows := make(map[int]bool)
+	hiddenColumns := make(map[int]bool)
+	for r, row := range table.Rows {
+		for c, cell := range row.Cells {
+			for _, match := range hideMarkerRegex.FindAllStringSubmatch(cell.Text, -1) {
+				if match[1] == "row" {
+					hiddenRows[r] = true
+				} else {
+					hiddenColumns[row.gridColumn(c)] = true
+				}
+			}
+		}
+	}
+
+	gridColumns := len(table.GridCols)
+	for _, row := range table.Rows {
+		if columns := row.gridColumn(len(row.Cells)); columns > gridColumns {
+			gridColumns = columns
+		}
+	}
+	if len(hiddenRows) == len(table.Rows) || len(hiddenColumns) >= gridColumns {
+		return removeTable(data, table)
+	}
+
+	var replacements []replacement
+	for i, gridCol := range table.GridCols {
+		if hiddenColumns[i] {
+			replacements = append(replacements, replacement{gridCol.Start, gridCol.End, nil})
+		}
+	}
+	for r, row := range table.Rows {
+		if hiddenRows[r] {
+			replacements = append(replacements, replacement{row.Start, row.End, nil})
+			continue
+		}
+		for c, cell := range row.Cells {
+			first := row.gridColumn(c)
+			hidden := 0
+			for column := first; column < first+cell.GridSpan; column++ {
+				if hiddenColumns[column] {
+					hidden++
+				}
+			}
+
+			switch {
+			case hidden == cell.GridSpan:
+				replacements = append(replacements, replacement{cell.Start, cell.End, nil})
+			case hidden > 0:
+				cellXml := hideMarkerRegex.ReplaceAll(data[cell.Start:cell.End], nil)
+				span := []byte(fmt.Sprintf(`<w:gridSpan w:val="%d"/>`, cell.GridSpan-hidden))
+				replacements = append(replacements, replacement{cell.Start, cell.End, setCellProperty(cellXml, "w:gridSpan", span)})
+			case hideMarkerRegex.MatchString(cell.Text):
+				replacements = append(replacements, replacement{cell.Start, cell.End, hideMarkerRegex.ReplaceAll(data[cell.Start:cell.End], nil)})
+			}
+		}
+	}
+	return applyReplacements(data, replacements)
+}
+
+// removeTable removes the table from the part. Since table cells must end with a paragraph,
+// an empty paragraph is kept if the table was the last element of a cell.
+func removeTable(data []byte, table *tableLayout) []byte {
+	var content []byte
+	if bytes.HasPrefix(bytes.TrimLeft(data[table.End:], " \t\r\n"), []byte("</w:tc>")) {
+		content = []byte("<w:p/>")
+	}
+	return applyReplacements(data, []replacement{{table.Start, table.End, content}})
+}
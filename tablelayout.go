@@ -0,0 +1,256 @@
+package docx
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// tablelayout.go implements the helpers which set the layout of tables, their rows and cells, so long generated
+// tables paginate cleanly: {{tablelayout}}, {{tablewidth}}, {{cellwidth}}, {{cantsplit}} and {{headerrow}}. Like
+// the merge helpers, they write a marker into the cell, which is replaced by the matching properties after all
+// rows and columns of the part are expanded.
+
+var (
+	// layoutMarkerRegex matches the markers written by the layout helpers: the element of the table (tbl), row
+	// (tr) or cell (tc) properties is hex-encoded. Elements of the cell properties may also be set for all cells of
+	// the row (row) or, cycling through the elements separated by newlines, for every row of a band (band).
+	layoutMarkerRegex = regexp.MustCompile(`\[\[docx-layout (tbl|tr|tc|row|band) ([0-9a-f]*)\]\]`)
+	// tableRowOpenTagRegex matches the open tag of a table row and the table property exceptions which follow it.
+	tableRowOpenTagRegex = regexp.MustCompile(`(?s)^<w:tr\b[^>]*>\s*(?:<w:tblPrEx>.*?</w:tblPrEx>\s*|<w:tblPrEx/>\s*)?`)
+	// tableOpenTagRegex matches the open tag of a table including the following whitespace.
+	tableOpenTagRegex = regexp.MustCompile(`^<w:tbl\b[^>]*>\s*`)
+)
+
+// rowPropertiesOrder lists the children of the table row properties (<w:trPr>) in the order of the schema.
+var rowPropertiesOrder = []string{"w:cnfStyle", "w:divId", "w:gridBefore", "w:gridAfter", "w:wBefore", "w:wAfter",
+	"w:cantSplit", "w:trHeight", "w:tblHeader", "w:tblCellSpacing", "w:jc", "w:hidden", "w:ins", "w:del", "w:trPrChange"}
+
+// tablePropertiesOrder lists the children of the table properties (<w:tblPr>) in the order of the schema.
+var tablePropertiesOrder = []string{"w:tblStyle", "w:tblpPr", "w:tblOverlap", "w:bidiVisual", "w:tblStyleRowBandSize",
+	"w:tblStyleColBandSize", "w:tblW", "w:jc", "w:tblCellSpacing", "w:tblInd", "w:tblBorders", "w:shd", "w:tblLayout",
+	"w:tblCellMar", "w:tblLook", "w:tblCaption", "w:tblDescription", "w:tblPrChange"}
+
+// layoutMarker returns the marker which sets the element of the table, row or cell properties.
+func layoutMarker(scope, element string) cellMarker {
+	return cellMarker("[[docx-layout " + scope + " " + hex.EncodeToString([]byte(element)) + "]]")
+}
+
+// tablelayoutHelper implements {{tablelayout "fixed"}} and {{tablelayout "autofit"}}. Fixed tables keep the
+// widths of their columns instead of fitting them to the content, which makes long tables much faster to lay out.
+func tablelayoutHelper(layout string) (cellMarker, error) {
+	if layout != "fixed" && layout != "autofit" {
+		return "", fmt.Errorf("tablelayout: invalid layout %q, expected fixed or autofit", layout)
+	}
+	return layoutMarker("tbl", `<w:tblLayout w:type="`+layout+`"/>`), nil
+}
+
+// tablewidthHelper implements {{tablewidth WIDTH}}, which sets the preferred width of the table, see tableWidth.
+func tablewidthHelper(width interface{}) (cellMarker, error) {
+	w, unit, err := tableWidth(width)
+	if err != nil {
+		return "", fmt.Errorf("tablewidth: %w", err)
+	}
+	return layoutMarker("tbl", fmt.Sprintf(`<w:tblW w:w="%d" w:type="%s"/>`, w, unit)), nil
+}
+
+// cellwidthHelper implements {{cellwidth WIDTH}}, which sets the preferred width of the cell, see tableWidth.
+func cellwidthHelper(width interface{}) (cellMarker, error) {
+	w, unit, err := tableWidth(width)
+	if err != nil {
+		return "", fmt.Errorf("cellwidth: %w", err)
+	}
+	return layoutMarker("tc", fmt.Sprintf(`<w:tcW w:w="%d" w:type="%s"/>`, w, unit)), nil
+}
+
+// cantsplitHelper implements {{cantsplit}}: the row of the cell is not split across pages.
+func cantsplitHelper() cellMarker {
+	return layoutMarker("tr", `<w:cantSplit/>`)
+}
+
+// headerrowHelper implements {{headerrow}}: the row of the cell is repeated at the top of each page.
+func headerrowHelper() cellMarker {
+	return layoutMarker("tr", `<w:tblHeader/>`)
+}
+
+// tableWidth converts a width to the value and the unit of a WordprocessingML width. Numbers are twentieths of
+// a point (dxa), strings may have the units %, pt, cm, mm or in (e.g. "50%" or "2.5cm"), "auto" lets Word decide.
+func tableWidth(width interface{}) (int, string, error) {
+	var text string
+	switch v := width.(type) {
+	case int:
+		return v, "dxa", nil
+	case int64:
+		return int(v), "dxa", nil
+	case float64:
+		return int(math.Round(v)), "dxa", nil
+	case string:
+		text = strings.TrimSpace(v)
+	default:
+		return 0, "", fmt.Errorf("unsupported width of type %T", width)
+	}
+	if text == "auto" {
+		return 0, "auto", nil
+	}
+
+	// the factors convert to dxa, percentages are given in fiftieths of a percent
+	units := []struct {
+		suffix string
+		factor float64
+		unit   string
+	}{{"%", 50, "pct"}, {"pt", 20, "dxa"}, {"cm", 1440 / 2.54, "dxa"}, {"mm", 144 / 2.54, "dxa"}, {"in", 1440, "dxa"}, {"", 1, "dxa"}}
+	for _, u := range units {
+		if number, found := strings.CutSuffix(text, u.suffix); found {
+			value, err := strconv.ParseFloat(strings.TrimSpace(number), 64)
+			if err != nil || value < 0 {
+				return 0, "", fmt.Errorf("invalid width %q", width)
+			}
+			return int(math.Round(value * u.factor)), u.unit, nil
+		}
+	}
+	return 0, "", fmt.Errorf("invalid width %q", width)
+}
+
+// applyTableLayout replaces all markers written by the layout helpers by the matching table, row and cell properties.
+func applyTableLayout(data []byte) ([]byte, error) {
+	for {
+		if !layoutMarkerRegex.Match(data) {
+			return data, nil
+		}
+		tables, err := findTables(data)
+		if err != nil {
+			return nil, err
+		}
+
+		// nested tables follow their parent, so the last marked table does not contain other marked tables
+		var marked *tableLayout
+		for _, table := range tables {
+			for _, row := range table.Rows {
+				for _, cell := range row.Cells {
+					if layoutMarkerRegex.MatchString(cell.Text) {
+						marked = table
+					}
+				}
+			}
+		}
+		if marked == nil {
+			// markers outside of tables have no effect
+			return layoutMarkerRegex.ReplaceAll(data, nil), nil
+		}
+		data = applyReplacements(data, []replacement{{marked.Start, marked.End, layoutTable(data, marked)}})
+	}
+}
+
+// layoutTable returns the table with the properties set by the markers of its cells.
+func layoutTable(data []byte, table *tableLayout) []byte {
+	var tableProperties [][]byte
+	var rows []replacement
+	band := 0
+	for _, row := range table.Rows {
+		var rowProperties, allCellProperties [][]byte
+		cellProperties := make([][][]byte, len(row.Cells))
+		marked := false
+		for c, cell := range row.Cells {
+			for _, match := range layoutMarkerRegex.FindAllStringSubmatch(cell.Text, -1) {
+				marked = true
+				element, _ := hex.DecodeString(match[2])
+				switch match[1] {
+				case "tbl":
+					tableProperties = append(tableProperties, element)
+				case "tr":
+					rowProperties = append(rowProperties, element)
+				case "tc":
+					cellProperties[c] = append(cellProperties[c], element)
+				case "row":
+					allCellProperties = append(allCellProperties, element)
+				case "band":
+					elements := bytes.Split(element, []byte("\n"))
+					allCellProperties = append(allCellProperties, elements[band%len(elements)])
+					band++
+				}
+			}
+		}
+		if !marked {
+			continue
+		}
+
+		// the properties of a cell take precedence over the properties of the whole row
+		var cells []replacement
+		for c, cell := range row.Cells {
+			properties := append(append([][]byte{}, allCellProperties...), cellProperties[c]...)
+			if len(properties) == 0 && !layoutMarkerRegex.MatchString(cell.Text) {
+				continue
+			}
+			cellXml := layoutMarkerRegex.ReplaceAll(data[cell.Start:cell.End], nil)
+			for _, element := range properties {
+				cellXml = setCellProperty(cellXml, elementName(element), element)
+			}
+			cells = append(cells, replacement{cell.Start - row.Start, cell.End - row.Start, cellXml})
+		}
+
+		rowXml := applyReplacements(data[row.Start:row.End], cells)
+		for _, element := range rowProperties {
+			rowXml = setNestedProperty(rowXml, tableRowOpenTagRegex, "w:trPr", elementName(element), element, rowPropertiesOrder)
+		}
+		rows = append(rows, replacement{row.Start - table.Start, row.End - table.Start, rowXml})
+	}
+
+	sort.Slice(rows, func(i, j int) bool {
+		return rows[i].Start < rows[j].Start
+	})
+	tableXml := applyReplacements(data[table.Start:table.End], rows)
+	for _, element := range tableProperties {
+		tableXml = setNestedProperty(tableXml, tableOpenTagRegex, "w:tblPr", elementName(element), element, tablePropertiesOrder)
+	}
+	return tableXml
+}
+
+// setNestedProperty sets the element with the given name in the properties (e.g. w:trPr) which follow the part of
+// the element matched by the prefix regex. An existing element is replaced, otherwise the element is inserted in
+// the order of the schema. Missing properties are added.
+func setNestedProperty(elementXml []byte, prefix *regexp.Regexp, properties, name string, element []byte, order []string) []byte {
+	start := prefix.Find(elementXml)
+	if start == nil {
+		return elementXml
+	}
+	rest := elementXml[len(start):]
+	var content []byte
+	switch {
+	case bytes.HasPrefix(rest, []byte("<"+properties+"/>")):
+		rest = rest[len("<"+properties+"/>"):]
+	case bytes.HasPrefix(rest, []byte("<"+properties+">")):
+		if end := bytes.Index(rest, []byte("</"+properties+">")); end >= 0 {
+			content = rest[len("<"+properties+">"):end]
+			rest = rest[end+len("</"+properties+">"):]
+		}
+	}
+	content = regexp.MustCompile(`<`+regexp.QuoteMeta(name)+`\b[^>]*/>`).ReplaceAll(content, nil)
+
+	var followers []string
+	for i, property := range order {
+		if property == name {
+			followers = order[i+1:]
+		}
+	}
+	content = insertChild(content, element, followers)
+
+	out := append([]byte{}, start...)
+	out = append(out, "<"+properties+">"...)
+	out = append(out, content...)
+	out = append(out, "</"+properties+">"...)
+	return append(out, rest...)
+}
+
+// elementName returns the name of the element, e.g. w:tcW for <w:tcW w:w="100" w:type="dxa"/>.
+func elementName(element []byte) string {
+	end := bytes.IndexAny(element, " />")
+	if end < 0 {
+		return ""
+	}
+	return string(element[1:end])
+}
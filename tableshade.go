@@ -0,0 +1,65 @@
+package docx
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// tableshade.go implements the helpers which shade table cells: {{shade}} for a single cell, {{shaderow}} for all
+// cells of the row and {{band}} for alternating row colors. They use the markers of the layout helpers, so inside
+// cloned rows the colors may depend on the item, e.g. {{if lt .amount 0.0}}{{shaderow "FFCCCC"}}{{end}}.
+
+// shadingColorRegex matches a color in hexadecimal RRGGBB notation.
+var shadingColorRegex = regexp.MustCompile(`^[0-9A-Fa-f]{6}$`)
+
+// shadeHelper implements {{shade COLOR}}, which fills the cell with the color, e.g. {{shade "FFEEEE"}}.
+func shadeHelper(color string) (cellMarker, error) {
+	shading, err := cellShading(color)
+	if err != nil {
+		return "", fmt.Errorf("shade: %w", err)
+	}
+	return layoutMarker("tc", shading), nil
+}
+
+// shaderowHelper implements {{shaderow COLOR}}, which fills all cells of the row with the color. Cells shaded with
+// {{shade}} keep their own color.
+func shaderowHelper(color string) (cellMarker, error) {
+	shading, err := cellShading(color)
+	if err != nil {
+		return "", fmt.Errorf("shaderow: %w", err)
+	}
+	return layoutMarker("row", shading), nil
+}
+
+// bandHelper implements {{band COLOR...}}: the rows of the table marked with {{band}} are filled with the colors
+// in turn, e.g. {{band "FFFFFF" "F2F2F2"}}. A single color fills every second row.
+func bandHelper(colors ...string) (cellMarker, error) {
+	if len(colors) == 0 {
+		return "", fmt.Errorf("band: no colors given")
+	}
+	if len(colors) == 1 {
+		colors = []string{"auto", colors[0]}
+	}
+	shadings := make([]string, len(colors))
+	for i, color := range colors {
+		shading, err := cellShading(color)
+		if err != nil {
+			return "", fmt.Errorf("band: %w", err)
+		}
+		shadings[i] = shading
+	}
+	return layoutMarker("band", strings.Join(shadings, "\n")), nil
+}
+
+// cellShading returns the shading element of the cell properties which fills the cell with the color.
+// The color is given as RRGGBB or "auto" for no fill.
+func cellShading(color string) (string, error) {
+	if color != "auto" {
+		if !shadingColorRegex.MatchString(color) {
+			return "", fmt.Errorf("invalid color %q, expected RRGGBB or auto", color)
+		}
+		color = strings.ToUpper(color)
+	}
+	return `<w:shd w:val="clear" w:color="auto" w:fill="` + color + `"/>`, nil
+}
@@ -2,29 +2,101 @@ package docx
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
-	"regexp"
+	"html"
+	"io"
 	"strings"
 	"text/template"
+	"time"
+
+	"github.com/izetmolla/docx/templating"
 )
 
 // TemplateData represents the data structure that can be used in templates
 type TemplateData interface{}
 
+// TemplateDocument is a template of any supported format: a *Document, an OpenDocument text of the odt package,
+// a presentation of the pptx package or a workbook of the xlsx package. Applications fill templates through it
+// without knowing the format of the file.
+type TemplateDocument interface {
+	// ExecuteTemplate evaluates the placeholders of the template with the data.
+	ExecuteTemplate(data TemplateData) error
+	// ReplaceAll replaces the string-based placeholders delimited with { and }.
+	ReplaceAll(replaceMap PlaceholderMap) error
+	// SetTemplateEngine sets an alternative engine which evaluates the placeholders, nil selects text/template.
+	SetTemplateEngine(engine templating.Engine)
+	// Write writes the filled document to the writer.
+	Write(writer io.Writer) error
+	// WriteToFile writes the filled document to a new file.
+	WriteToFile(file string) error
+	// Close releases the resources of the template.
+	Close()
+}
+
+var _ TemplateDocument = (*Document)(nil)
+
 // TemplateReplacer provides template-based replacement functionality
 type TemplateReplacer struct {
-	document *Document
-	tmpl     *template.Template
-	data     TemplateData
-	debug    bool // Enable debug logging
+	document       *Document
+	tmpl           *template.Template
+	data           TemplateData
+	engine         templating.Engine  // Alternative engine, text/template is used if nil
+	part           string             // The part which is currently processed, e.g. word/document.xml
+	docxtemplater  bool               // Understand docxtemplater tags, see SetDocxtemplaterSyntax
+	clauses        *ClauseLibrary     // The clauses inserted by {{clause}}, see SetClauseLibrary
+	insertions     []*Document        // The documents inserted by {{clause}} and {{embed}}, see insertionMarker
+	embedDir       string             // The directory of the paths embedded by {{embed}}, see SetEmbedDir
+	schema         *Schema            // The schema of the data, see SetSchema
+	rightToLeft    RightToLeftOptions // The handling of right-to-left values, see SetRightToLeft
+	locale         string             // The locale of the document, see SetLocale
+	language       string             // The language of the localized variants, see SetLanguage
+	dateLayouts    []string           // The layouts of date strings, see SetDateLayouts
+	limits         ExecutionLimits    // The limits of each placeholder and block, see SetExecutionLimits
+	deadline       time.Time          // The deadline of the current execution, see newLimitedWriter
+	bookmarkValues bool               // Mark the inserted values with bookmarks, see SetBookmarkValues
+	valueActions   []string           // The actions of the marked values which can be rendered again, see markValue
+	renderedValues map[string]string  // The actions of the bookmarks which can be rendered again, see Rerender
+	missingKey     MissingKeyPolicy   // The handling of missing values, see SetMissingKeyPolicy
+	mode           ExecutionMode      // Execute placeholders or whole parts, see SetExecutionMode
+	panicPolicy    PanicPolicy        // The handling of panics, see SetPanicPolicy
+	recovered      []error            // The panics skipped by the last execution, see RecoveredPanics
+	truncated      []error            // The content truncated by the last execution, see Truncations
+	fieldTypes     map[string]string  // The types of fields without annotation, see SetFieldTypes
+	definitions    map[string]bool    // The templates defined in the document, true for definitions spanning paragraphs
+	logger         Logger             // The logger of the debug messages, the standard output is used if nil
+	debug          bool               // Enable debug logging
 }
 
 // NewTemplateReplacer creates a new template replacer for the given document
 func NewTemplateReplacer(doc *Document) *TemplateReplacer {
-	return &TemplateReplacer{
-		document: doc,
-		tmpl:     template.New("docx-template"),
-	}
+	tr := &TemplateReplacer{document: doc}
+	tr.tmpl = template.New("docx-template").Funcs(template.FuncMap{
+		"image":         tr.imageHelper,
+		"clause":        tr.clauseHelper,
+		"docxField":     fieldHelper,
+		"embed":         tr.embedHelper,
+		"keepnext":      keepnextHelper,
+		"keeplines":     keeplinesHelper,
+		"widowcontrol":  widowcontrolHelper,
+		"locale":        func() string { return tr.locale },
+		"money":         tr.moneyHelper,
+		"amountInWords": tr.amountInWordsHelper,
+		"numToWords":    tr.numToWordsHelper,
+		"ordinal":       tr.ordinalHelper,
+		"roman":         romanHelper,
+		"addDays":       tr.addDaysHelper,
+		"addMonths":     tr.addMonthsHelper,
+		"endOfMonth":    tr.endOfMonthHelper,
+		"weekday":       tr.weekdayHelper,
+		"between":       tr.betweenHelper,
+		"fit":           fitHelper,
+		"wrap":          tr.wrapHelper,
+		"shrink":        tr.shrinkHelper,
+		"docxMarkers":   markerSafeValue,
+		"docxDeadline":  tr.deadlineHelper,
+	})
+	return tr
 }
 
 // SetData sets the data to be used for template execution
@@ -39,14 +111,25 @@ func (tr *TemplateReplacer) SetDebug(debug bool) {
 
 // debugLog logs a message if debug mode is enabled
 func (tr *TemplateReplacer) debugLog(format string, args ...interface{}) {
-	if tr.debug {
-		fmt.Printf("[DEBUG] "+format+"\n", args...)
+	if !tr.debug {
+		return
 	}
+	if tr.logger != nil {
+		tr.logger.Printf("[DEBUG] "+format, args...)
+		return
+	}
+	fmt.Printf("[DEBUG] "+format+"\n", args...)
+}
+
+// SetEngine sets an alternative template engine. Pass nil to use text/template again.
+// Blocks spanning multiple paragraphs and the table helpers are only available with text/template.
+func (tr *TemplateReplacer) SetEngine(engine templating.Engine) {
+	tr.engine = engine
 }
 
-// AddFuncs adds custom functions to the template
+// AddFuncs adds custom functions to the template. Panics of the functions are returned as PanicError.
 func (tr *TemplateReplacer) AddFuncs(funcMap template.FuncMap) {
-	tr.tmpl = tr.tmpl.Funcs(funcMap)
+	tr.tmpl = tr.tmpl.Funcs(recoverFuncs(funcMap))
 }
 
 // ExecuteTemplate replaces all template placeholders in the document
@@ -55,9 +138,104 @@ func (tr *TemplateReplacer) ExecuteTemplate() error {
 	if tr.data == nil {
 		return fmt.Errorf("template data not set, call SetData() first")
 	}
+	if err := tr.ValidateData(tr.data); err != nil {
+		return err
+	}
 
 	tr.debugLog("Starting template execution...")
+	tr.recovered = nil
+	tr.truncated = nil
+
+	if err := tr.selectLanguage(); err != nil {
+		return err
+	}
+
+	if tr.engine != nil {
+		return tr.executeEngine()
+	}
+
+	// Inserted documents may contain actions themselves, so the document is processed again after inserting them
+	tr.insertions = nil
+	for inserted := 0; ; {
+		if tr.docxtemplater {
+			if err := tr.translateDocxtemplaterTags(); err != nil {
+				return err
+			}
+		}
+
+		// Definitions are collected first, so they can be called from any part
+		if err := tr.collectDefinitions(); err != nil {
+			return err
+		}
 
+		// Blocks spanning multiple paragraphs are resolved first, so only the kept content is processed below
+		if err := tr.executeBlocks(); err != nil {
+			return err
+		}
+		if err := tr.executeTables(); err != nil {
+			return err
+		}
+		if tr.docxtemplater {
+			if err := tr.executeInlineSections(); err != nil {
+				return err
+			}
+		}
+
+		count, err := tr.insertDocuments(maxInsertions - inserted)
+		if err != nil {
+			return err
+		}
+		if count == 0 {
+			break
+		}
+		inserted += count
+	}
+
+	if err := tr.trimParagraphs(); err != nil {
+		return err
+	}
+
+	if err := tr.executeDiagrams(); err != nil {
+		return err
+	}
+
+	// The parts are executed as a whole or placeholder by placeholder
+	execute := tr.executePlaceholders
+	if tr.mode == ExecuteParts {
+		execute = tr.executeParts
+	}
+	if err := execute(); err != nil {
+		return err
+	}
+
+	if err := tr.resolveValueMarkers(); err != nil {
+		return err
+	}
+	if err := tr.resolveRightToLeft(); err != nil {
+		return err
+	}
+	if err := tr.resolveFontSizes(); err != nil {
+		return err
+	}
+	if err := tr.document.syncTextBoxes(); err != nil {
+		return err
+	}
+	if err := tr.resolveParagraphProperties(); err != nil {
+		return err
+	}
+	if err := tr.checkPartSizes(); err != nil {
+		return err
+	}
+	if err := tr.document.unescapeMarkers(); err != nil {
+		return err
+	}
+
+	tr.debugLog("Template execution completed successfully")
+	return nil
+}
+
+// executePlaceholders executes the placeholders of all parts one by one.
+func (tr *TemplateReplacer) executePlaceholders() error {
 	// Extract all template placeholders from the document
 	templatePlaceholders, err := tr.extractTemplatePlaceholders()
 	if err != nil {
@@ -73,8 +251,90 @@ func (tr *TemplateReplacer) ExecuteTemplate() error {
 		tr.debugLog("Processing placeholder: %s", placeholder.TemplateContent)
 		err := tr.processTemplatePlaceholder(placeholder)
 		if err != nil {
-			return fmt.Errorf("failed to process template placeholder %s: %w", placeholder.TemplateContent, err)
+			templateErr := placeholder.error(tr.document.GetFile(placeholder.FileName), err)
+			if tr.skipPanic(templateErr) {
+				continue
+			}
+			return templateErr
+		}
+	}
+
+	// The replacements moved the runs, parse them again so the document can be processed once more
+	refreshed := make(map[string]bool)
+	for _, placeholder := range templatePlaceholders {
+		if !refreshed[placeholder.FileName] {
+			if err := tr.document.refreshRuns(placeholder.FileName); err != nil {
+				return err
+			}
+			refreshed[placeholder.FileName] = true
+		}
+	}
+	return nil
+}
+
+// executeEngine replaces all placeholders of the alternative engine in the document.
+// The results are XML-escaped, placeholders referencing missing values are left unchanged. Placeholders split
+// across runs are moved into their first run before, so they take its formatting.
+func (tr *TemplateReplacer) executeEngine() error {
+	left, right := tr.engine.Delimiters()
+	for _, fileName := range tr.document.xmlParts() {
+		parser, exists := tr.document.runParsers[fileName]
+		if !exists {
+			continue
+		}
+		if moved, changed := moveActions(tr.document.GetFile(fileName), parser.Runs().WithText(), findDelimitedActions(left, right), keepAction); changed {
+			if err := tr.document.SetFile(fileName, moved); err != nil {
+				return err
+			}
+			if err := tr.document.refreshRuns(fileName); err != nil {
+				return err
+			}
+		}
+		placeholders := parseDelimitedPlaceholders(tr.document.runParsers[fileName].Runs(), tr.document.GetFile(fileName), fileName, left, right)
+		tr.debugLog("Found %d placeholders in %s", len(placeholders), fileName)
+
+		// Process in reverse order, so that earlier positions remain valid after replacements
+		for i := len(placeholders) - 1; i >= 0; i-- {
+			placeholder := placeholders[i]
+			result, err := tr.executeEngineAction(html.UnescapeString(placeholder.Key), tr.data)
+			if errors.Is(err, templating.ErrMissingValue) {
+				if err := tr.missingValue(placeholder, "missing value"); err != nil {
+					return placeholder.error(tr.document.GetFile(fileName), err)
+				}
+				continue
+			}
+			if err != nil {
+				templateErr := placeholder.error(tr.document.GetFile(fileName), err)
+				if tr.skipPanic(templateErr) {
+					continue
+				}
+				return templateErr
+			}
+			result = tr.document.applyReplaceHooks(fileName, placeholder.TemplateContent, result)
+			if result, err = sanitizeValue(result, tr.document.invalidChars); err != nil {
+				return placeholder.error(tr.document.GetFile(fileName), err)
+			}
+			if err := tr.replacePlaceholder(placeholder, tr.markValue(bookmarkName(placeholder.Key), placeholder.Key, tr.markRightToLeft(escapeMarkers(xmlEscape(result))))); err != nil {
+				return fmt.Errorf("failed to replace placeholder: %w", err)
+			}
 		}
+		if len(placeholders) > 0 {
+			if err := tr.document.refreshRuns(fileName); err != nil {
+				return err
+			}
+		}
+	}
+	if err := tr.resolveValueMarkers(); err != nil {
+		return err
+	}
+	if err := tr.resolveRightToLeft(); err != nil {
+		return err
+	}
+	if err := tr.document.syncTextBoxes(); err != nil {
+		return err
+	}
+	if err := tr.document.unescapeMarkers(); err != nil {
+		return err
 	}
 
 	tr.debugLog("Template execution completed successfully")
@@ -85,7 +345,7 @@ func (tr *TemplateReplacer) ExecuteTemplate() error {
 func (tr *TemplateReplacer) extractTemplatePlaceholders() ([]*TemplatePlaceholder, error) {
 	var templatePlaceholders []*TemplatePlaceholder
 
-	for fileName := range tr.document.files {
+	for _, fileName := range tr.document.xmlParts() {
 		placeholders, err := ParseTemplatePlaceholders(tr.document.runParsers[fileName].Runs(), tr.document.GetFile(fileName), fileName)
 		if err != nil {
 			return nil, err
@@ -97,30 +357,33 @@ func (tr *TemplateReplacer) extractTemplatePlaceholders() ([]*TemplatePlaceholde
 }
 
 // processTemplatePlaceholder processes a single template placeholder
-func (tr *TemplateReplacer) processTemplatePlaceholder(placeholder *TemplatePlaceholder) error {
+func (tr *TemplateReplacer) processTemplatePlaceholder(placeholder *TemplatePlaceholder) (err error) {
+	defer recoverPanic(&err)
+	tr.part = placeholder.FileName
+
 	// Check if the template references missing fields BEFORE executing
-	if tr.hasMissingFields(placeholder.TemplateContent) {
-		tr.debugLog("Skipping placeholder %s - missing fields detected", placeholder.TemplateContent)
-		// Skip this placeholder - leave it unchanged in the document
-		return nil
+	content := stripAnnotations(placeholder.TemplateContent)
+	if tr.hasMissingFields(content) {
+		return tr.missingValue(placeholder, "missing fields detected")
 	}
 
 	// Parse the template content
-	tmpl, err := tr.tmpl.Parse(placeholder.TemplateContent)
+	tmpl, err := tr.tmpl.Parse(content)
 	if err != nil {
 		return fmt.Errorf("failed to parse template: %w", err)
 	}
+	escapeActions(tmpl.Tree.Root, "docxMarkers", "docxMarkers")
+	nilSafeFields(tmpl.Tree.Root)
+	limitLoops(tmpl.Tree.Root)
 
 	// Execute the template with the provided data
-	var buf bytes.Buffer
-	err = tmpl.Execute(&buf, tr.data)
+	buf := tr.newLimitedWriter()
+	err = tmpl.Execute(buf, tr.data)
 	if err != nil {
 		// Check if the error is due to missing field/property
 		// If so, skip this placeholder instead of failing
 		if tr.isMissingFieldError(err) {
-			tr.debugLog("Skipping placeholder %s - execution error indicates missing field: %v", placeholder.TemplateContent, err)
-			// Skip this placeholder - leave it unchanged in the document
-			return nil
+			return tr.missingValue(placeholder, fmt.Sprintf("execution error indicates missing field: %v", err))
 		}
 		return fmt.Errorf("failed to execute template: %w", err)
 	}
@@ -128,15 +391,20 @@ func (tr *TemplateReplacer) processTemplatePlaceholder(placeholder *TemplatePlac
 	// Check if the result contains "<no value>" which indicates missing fields
 	result := buf.String()
 	if strings.Contains(result, "<no value>") {
-		tr.debugLog("Skipping placeholder %s - result contains '<no value>'", placeholder.TemplateContent)
-		// Skip this placeholder - leave it unchanged in the document
-		return nil
+		return tr.missingValue(placeholder, "result contains '<no value>'")
 	}
 
+	// XML written by helpers such as {{image}} is not passed to the hooks
+	if !strings.Contains(result, "<") {
+		result = tr.document.applyReplaceHooks(placeholder.FileName, placeholder.TemplateContent, result)
+	}
+	if result, err = sanitizeValue(result, tr.document.invalidChars); err != nil {
+		return err
+	}
 	tr.debugLog("Replacing placeholder %s with result: %s", placeholder.TemplateContent, result)
 
 	// Replace the placeholder with the executed result
-	err = tr.replacePlaceholder(placeholder, result)
+	err = tr.replacePlaceholder(placeholder, tr.markValue(valueName(placeholder.TemplateContent), placeholder.TemplateContent, tr.markRightToLeft(result)))
 	if err != nil {
 		return fmt.Errorf("failed to replace placeholder: %w", err)
 	}
@@ -144,9 +412,29 @@ func (tr *TemplateReplacer) processTemplatePlaceholder(placeholder *TemplatePlac
 	return nil
 }
 
+// missingValue handles a placeholder which references a missing value according to the missing key policy:
+// the placeholder is left unchanged, replaced with an empty string or an error is returned.
+func (tr *TemplateReplacer) missingValue(placeholder *TemplatePlaceholder, reason string) error {
+	switch tr.missingKey {
+	case MissingKeyEmpty:
+		tr.debugLog("Removing placeholder %s - %s", placeholder.TemplateContent, reason)
+		if err := tr.replacePlaceholder(placeholder, ""); err != nil {
+			return fmt.Errorf("failed to replace placeholder: %w", err)
+		}
+		return nil
+	case MissingKeyError:
+		return fmt.Errorf("missing value: %s", reason)
+	}
+	tr.debugLog("Skipping placeholder %s - %s", placeholder.TemplateContent, reason)
+	// Skip this placeholder - leave it unchanged in the document
+	return nil
+}
+
 // isMissingFieldError checks if the error is due to a missing field/property in the data structure
 func (tr *TemplateReplacer) isMissingFieldError(err error) bool {
-	if err == nil {
+	// e.g. a nil pointer dereference inside a function is not a missing field
+	var panicErr *PanicError
+	if err == nil || errors.As(err, &panicErr) {
 		return false
 	}
 
@@ -179,10 +467,8 @@ func (tr *TemplateReplacer) hasMissingFields(templateContent string) bool {
 		return true
 	}
 
-	// Extract field names from template content like {{.fieldName}}
-	// This is a simple regex to find field references
-	fieldPattern := `\{\{\.([^}]+)\}\}`
-	matches := regexp.MustCompile(fieldPattern).FindAllStringSubmatch(templateContent, -1)
+	// Extract the field chains the actions start with, like {{.fieldName}} or {{.user.name | upper}}
+	matches := fieldReferenceRegex.FindAllStringSubmatch(templateContent, -1)
 
 	for _, match := range matches {
 		if len(match) > 1 {
@@ -196,48 +482,18 @@ func (tr *TemplateReplacer) hasMissingFields(templateContent string) bool {
 	return false
 }
 
-// fieldExists checks if a field exists in the data structure
+// fieldExists checks if a field exists in the data structure. Fields behind nil values exist, they are empty.
 func (tr *TemplateReplacer) fieldExists(fieldName string) bool {
 	if tr.data == nil {
 		tr.debugLog("Field %s: data is nil", fieldName)
 		return false
 	}
 
-	// Handle map[string]interface{}
-	if dataMap, ok := tr.data.(map[string]interface{}); ok {
-		_, exists := dataMap[fieldName]
-		tr.debugLog("Field %s: exists in map = %v", fieldName, exists)
-		return exists
-	}
-
-	// Handle structs - use reflection to check if field exists
-	// This is a simplified check - for complex nested fields, we'd need more sophisticated logic
-	exists := tr.checkStructField(fieldName)
-	tr.debugLog("Field %s: exists in struct = %v", fieldName, exists)
+	exists := pathExists(tr.data, fieldName)
+	tr.debugLog("Field %s: exists = %v", fieldName, exists)
 	return exists
 }
 
-// checkStructField checks if a field exists in a struct using reflection
-func (tr *TemplateReplacer) checkStructField(fieldName string) bool {
-	// For now, we'll use a simple approach - try to execute a minimal template
-	// and see if it fails with a missing field error
-	testTemplate := fmt.Sprintf("{{.%s}}", fieldName)
-	tmpl, err := template.New("test").Parse(testTemplate)
-	if err != nil {
-		return false
-	}
-
-	var buf bytes.Buffer
-	err = tmpl.Execute(&buf, tr.data)
-	if err != nil {
-		return tr.isMissingFieldError(err)
-	}
-
-	// If execution succeeds and doesn't produce "<no value>", the field exists
-	result := buf.String()
-	return !strings.Contains(result, "<no value>")
-}
-
 // replacePlaceholder replaces a template placeholder with the executed result
 func (tr *TemplateReplacer) replacePlaceholder(placeholder *TemplatePlaceholder, result string) error {
 	// Get the document bytes for the file
@@ -268,6 +524,47 @@ type TemplatePlaceholder struct {
 	Key             string
 }
 
+// error returns a TemplateError locating the placeholder inside the given part data.
+func (p *TemplatePlaceholder) error(data []byte, err error) error {
+	return newTemplateError(data, p.FileName, p.Placeholder.StartPos(), p.TemplateContent, err)
+}
+
+// TemplateError is returned if an action of the template fails. Besides the action it names the part and the
+// paragraph containing it, so the action can be found in long documents.
+type TemplateError struct {
+	Part      string // The part containing the action, e.g. word/document.xml or word/header1.xml
+	Paragraph string // The text of the paragraph containing the action
+	Action    string // The failed action, e.g. {{.total | money}}
+	Err       error
+}
+
+// Error returns the error with its location, e.g.
+// word/header1.xml: failed to execute {{.total | money}} in paragraph "Total: {{.total | money}}": ...
+func (e *TemplateError) Error() string {
+	if e.Paragraph == "" {
+		return fmt.Sprintf("%s: failed to execute %s: %v", e.Part, e.Action, e.Err)
+	}
+	return fmt.Sprintf("%s: failed to execute %s in paragraph %q: %v", e.Part, e.Action, e.Paragraph, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *TemplateError) Unwrap() error {
+	return e.Err
+}
+
+// newTemplateError returns the error of the action at the given offset of the part data. The paragraph is the
+// innermost paragraph containing the offset.
+func newTemplateError(data []byte, part string, offset int64, action string, err error) *TemplateError {
+	templateErr := &TemplateError{Part: part, Action: html.UnescapeString(action), Err: err}
+	paragraphs, _ := findBlockParagraphs(data)
+	for _, paragraph := range paragraphs {
+		if paragraph.Start <= offset && offset < paragraph.End {
+			templateErr.Paragraph = strings.TrimSpace(paragraph.Text)
+		}
+	}
+	return templateErr
+}
+
 // Placeholder represents a parsed placeholder from the docx-archive.
 type Placeholder struct {
 	Fragments []*PlaceholderFragment
@@ -290,7 +587,9 @@ type PlaceholderFragment struct {
 	Run      *Run
 }
 
-// ParseTemplatePlaceholders extracts Go template syntax placeholders from document runs
+// ParseTemplatePlaceholders extracts Go template syntax placeholders from document runs.
+// Control structures which start and end inside the same run, like {{if .x}}A{{else}}B{{end}},
+// are returned as a single placeholder.
 func ParseTemplatePlaceholders(runs DocumentRuns, docBytes []byte, fileName string) ([]*TemplatePlaceholder, error) {
 	var templatePlaceholders []*TemplatePlaceholder
 
@@ -298,76 +597,173 @@ func ParseTemplatePlaceholders(runs DocumentRuns, docBytes []byte, fileName stri
 		runText := run.GetText(docBytes)
 
 		// Find template placeholders using Go template syntax
-		templateStarts := findTemplateStarts(runText)
-		templateEnds := findTemplateEnds(runText)
-
-		// Match template starts with ends
-		for i, start := range templateStarts {
-			if i < len(templateEnds) {
-				end := templateEnds[i]
-				templateContent := runText[start : end+2] // +2 to include }}
-
-				// Create placeholder fragment
-				fragment := &PlaceholderFragment{
-					Position: Position{int64(start), int64(end + 2)},
-					Run:      run,
-				}
-				placeholder := &Placeholder{Fragments: []*PlaceholderFragment{fragment}}
-
-				// Extract the key (content between {{ and }})
-				key := templateContent[2 : len(templateContent)-2] // Remove {{ and }}
+		for _, action := range groupTemplateActions(runText, findTemplateActions(runText)) {
+			start, end := action.Start, action.End
+			templateContent := runText[start:end]
+
+			// Create placeholder fragment, including the white space removed by trim markers
+			trimStart, trimEnd := trimPosition(runText, start, end)
+			fragment := &PlaceholderFragment{
+				Position: Position{int64(trimStart), int64(trimEnd)},
+				Run:      run,
+			}
+			placeholder := &Placeholder{Fragments: []*PlaceholderFragment{fragment}}
 
-				templatePlaceholder := &TemplatePlaceholder{
-					Placeholder:     placeholder,
-					FileName:        fileName,
-					TemplateContent: templateContent,
-					Key:             key,
-				}
+			// Extract the key (content between {{ and }})
+			key := runText[start+action.Left : end-action.Right]
 
-				templatePlaceholders = append(templatePlaceholders, templatePlaceholder)
+			templatePlaceholder := &TemplatePlaceholder{
+				Placeholder:     placeholder,
+				FileName:        fileName,
+				TemplateContent: templateContent,
+				Key:             key,
 			}
+
+			templatePlaceholders = append(templatePlaceholders, templatePlaceholder)
 		}
 	}
 
 	return templatePlaceholders, nil
 }
 
-// findTemplateStarts finds all positions of "{{" in the text
-// Handles both regular braces and Unicode variants that might be introduced by copy-paste
-func findTemplateStarts(text string) []int {
-	var starts []int
-	runes := []rune(text)
+// parseDelimitedPlaceholders extracts the placeholders enclosed by the given delimiters from the document runs.
+// Unlike ParseTemplatePlaceholders, positions are byte offsets and each left delimiter is paired with the next
+// right delimiter. The Key of the returned placeholders is the raw expression between the delimiters.
+func parseDelimitedPlaceholders(runs DocumentRuns, docBytes []byte, fileName, left, right string) []*TemplatePlaceholder {
+	var templatePlaceholders []*TemplatePlaceholder
+	find := findDelimitedActions(left, right)
+	for _, run := range runs.WithText() {
+		runText := run.GetText(docBytes)
+		for _, action := range find(runText) {
+			fragment := &PlaceholderFragment{
+				Position: Position{int64(action.Start), int64(action.End)},
+				Run:      run,
+			}
+			templatePlaceholders = append(templatePlaceholders, &TemplatePlaceholder{
+				Placeholder:     &Placeholder{Fragments: []*PlaceholderFragment{fragment}},
+				FileName:        fileName,
+				TemplateContent: runText[action.Start:action.End],
+				Key:             runText[action.Start+action.Left : action.End-action.Right],
+			})
+		}
+	}
+	return templatePlaceholders
+}
+
+// templateAction is the position of an action, or of a complete control structure, inside the text of a run.
+// All positions are byte offsets.
+type templateAction struct {
+	Start int // position of the opening delimiter
+	End   int // position behind the closing delimiter
+	Left  int // length of the opening delimiter
+	Right int // length of the closing delimiter
+}
+
+const (
+	// smartLeftDelimiter and smartRightDelimiter are the delimiters after Word replaced the braces by typographic quotes.
+	smartLeftDelimiter  = "\u201C\u201C"
+	smartRightDelimiter = "\u201D\u201D"
+)
 
-	for i := 0; i < len(runes)-1; i++ {
-		// Check for regular braces
-		if runes[i] == '{' && runes[i+1] == '{' {
-			starts = append(starts, i)
+// findTemplateActions finds all actions in the text. Each opening delimiter is paired with the closing
+// delimiter which ends the action, delimiters inside strings and comments of the action are skipped.
+// Handles both regular braces and Unicode variants that might be introduced by copy-paste.
+func findTemplateActions(text string) []templateAction {
+	var actions []templateAction
+	for i := 0; i < len(text)-1; i++ {
+		left := delimiterAt(text, i, "{{", smartLeftDelimiter)
+		if left == 0 {
+			continue
 		}
-		// Check for Unicode left double quotation mark variants (U+201C)
-		if runes[i] == '\u201C' && runes[i+1] == '\u201C' {
-			starts = append(starts, i)
+		end, right := findActionEnd(text, i+left)
+		if end < 0 {
+			break
 		}
+		actions = append(actions, templateAction{Start: i, End: end, Left: left, Right: right})
+		i = end - 1
+	}
+	return actions
+}
+
+// findActionEnd returns the position behind the closing delimiter of the action starting at pos
+// and the length of the delimiter, or -1 if the action is not closed.
+func findActionEnd(text string, pos int) (int, int) {
+	var quote byte
+	for i := pos; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == '\\' && quote != '`' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '`' || c == '\'':
+			quote = c
+		case strings.HasPrefix(text[i:], "/*"):
+			// comments end with */ followed by the delimiter
+			if end := strings.Index(text[i+2:], "*/"); end >= 0 {
+				i += end + 3
+			} else {
+				i = len(text)
+			}
+		default:
+			if right := delimiterAt(text, i, "}}", smartRightDelimiter); right > 0 {
+				return i + right, right
+			}
+		}
+	}
+	return -1, 0
+}
+
+// delimiterAt returns the length of the delimiter or its typographic variant at position i of the text, or 0.
+func delimiterAt(text string, i int, delimiter, smart string) int {
+	if strings.HasPrefix(text[i:], delimiter) {
+		return len(delimiter)
+	}
+	if strings.HasPrefix(text[i:], smart) {
+		return len(smart)
 	}
-	return starts
+	return 0
 }
 
-// findTemplateEnds finds all positions of "}}" in the text
-// Handles both regular braces and Unicode variants that might be introduced by copy-paste
-func findTemplateEnds(text string) []int {
-	var ends []int
-	runes := []rune(text)
+// groupTemplateActions combines each action opening a control structure ({{if}}, {{range}}, {{with}}, ...) with
+// all actions up to the matching {{end}}, so the structure is executed as a whole. Actions without a matching
+// counterpart in the text are returned as they are.
+func groupTemplateActions(text string, actions []templateAction) []templateAction {
+	kind := func(action templateAction) string {
+		k, _ := blockActionKind(text[action.Start+action.Left : action.End-action.Right])
+		return k
+	}
+
+	var grouped []templateAction
+	for i := 0; i < len(actions); i++ {
+		switch kind(actions[i]) {
+		case "", "else", "end":
+			grouped = append(grouped, actions[i])
+			continue
+		}
 
-	for i := 0; i < len(runes)-1; i++ {
-		// Check for regular braces
-		if runes[i] == '}' && runes[i+1] == '}' {
-			ends = append(ends, i)
+		depth, end := 0, -1
+		for j := i; j < len(actions) && end < 0; j++ {
+			switch kind(actions[j]) {
+			case "", "else":
+			case "end":
+				if depth--; depth == 0 {
+					end = j
+				}
+			default:
+				depth++
+			}
 		}
-		// Check for Unicode right double quotation mark variants (U+201D)
-		if runes[i] == '\u201D' && runes[i+1] == '\u201D' {
-			ends = append(ends, i)
+		if end < 0 {
+			grouped = append(grouped, actions[i])
+			continue
 		}
+		grouped = append(grouped, templateAction{Start: actions[i].Start, End: actions[end].End, Left: actions[i].Left, Right: actions[end].Right})
+		i = end
 	}
-	return ends
+	return grouped
 }
 
 // ExecuteTemplateWithData is a convenience method that combines SetData and ExecuteTemplate
@@ -381,3 +777,35 @@ func (tr *TemplateReplacer) ExecuteTemplateWithFuncs(data TemplateData, funcMap
 	tr.AddFuncs(funcMap)
 	return tr.ExecuteTemplateWithData(data)
 }
+
+// ExecuteTemplatePasses executes the template with the data up to the given number of passes, so values inserted
+// in one pass may contain actions which are executed in the next one, e.g. a clause text referencing {{.name}}.
+// The passes stop early once a pass leaves the document unchanged.
+func (tr *TemplateReplacer) ExecuteTemplatePasses(data TemplateData, passes int) error {
+	if passes < 1 {
+		return fmt.Errorf("invalid number of passes %d, expected at least 1", passes)
+	}
+	tr.SetData(data)
+	for pass := 1; pass <= passes; pass++ {
+		before := make(map[string][]byte)
+		for _, part := range tr.document.xmlParts() {
+			before[part] = tr.document.GetFile(part)
+		}
+		if err := tr.ExecuteTemplate(); err != nil {
+			if pass == 1 {
+				return err
+			}
+			return fmt.Errorf("pass %d: %w", pass, err)
+		}
+
+		changed := false
+		for _, part := range tr.document.xmlParts() {
+			changed = changed || !bytes.Equal(before[part], tr.document.GetFile(part))
+		}
+		if !changed {
+			tr.debugLog("Pass %d left the document unchanged", pass)
+			break
+		}
+	}
+	return nil
+}
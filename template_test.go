@@ -2,10 +2,16 @@ package docx
 
 import (
 	"bytes"
+	"errors"
+	"image"
+	"image/png"
+	"regexp"
 	"strings"
 	"testing"
 	"text/template"
 	"time"
+
+	"github.com/izetmolla/docx/templating"
 )
 
 func TestTemplateReplacer_ExecuteTemplate(t *testing.T) {
@@ -96,6 +102,97 @@ func TestParseTemplatePlaceholders(t *testing.T) {
 	}
 }
 
+func TestFindTemplateActions(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected []string
+	}{
+		{"{{end}} text {{if .x}}", []string{"{{end}}", "{{if .x}}"}},
+		{"}} stray {{.a}} and {{.b}}", []string{"{{.a}}", "{{.b}}"}},
+		{`{{printf "%s}}" .a}} {{/* }} */}}`, []string{`{{printf "%s}}" .a}}`, "{{/* }} */}}"}},
+		{"{{if .x}}A{{else}}B{{end}} {{.y}}", []string{"{{if .x}}A{{else}}B{{end}}", "{{.y}}"}},
+		{"{{range .items}}{{if .ok}}x{{end}}{{end}}{{end}}", []string{"{{range .items}}{{if .ok}}x{{end}}{{end}}", "{{end}}"}},
+		{"{{if .x}}A {{.y}}", []string{"{{if .x}}", "{{.y}}"}},
+		{"{{.unclosed", nil},
+	}
+	for _, test := range tests {
+		var found []string
+		for _, action := range groupTemplateActions(test.text, findTemplateActions(test.text)) {
+			found = append(found, test.text[action.Start:action.End])
+		}
+		if strings.Join(found, "|") != strings.Join(test.expected, "|") {
+			t.Errorf("%s: expected %q, got %q", test.text, test.expected, found)
+		}
+	}
+}
+
+func TestTemplateReplacer_InlineControlStructures(t *testing.T) {
+	doc, err := OpenBytes(rewriteArchive(t, readFile(t, "./test/template.docx"), func(name string, data []byte) []byte {
+		if name == DocumentXml {
+			return []byte(strings.Replace(string(data), "<w:body>", "<w:body>"+
+				`<w:p><w:r><w:t xml:space="preserve">{{if .vip}}VIP {{else}}Guest {{end}}{{.name}}: {{range .items}}[{{.}}]{{end}}</w:t></w:r></w:p>`, 1))
+		}
+		return data
+	}, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+
+	if err := doc.ExecuteTemplate(map[string]interface{}{"vip": true, "name": "Bob", "items": []string{"a", "b"}}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(doc.GetFile(DocumentXml)), "VIP Bob: [a][b]") {
+		t.Errorf("the control structures were not executed")
+	}
+}
+
+func TestTemplateReplacer_NonASCII(t *testing.T) {
+	texts := []string{
+		`Здравствуйте, {{.name}}! Заказ № {{.order}}`,
+		`尊敬的{{.name}}，您的订单{{.order}}已发货`,
+		`🎉🎉 {{.name}} 👋 {{if .vip}}⭐{{end}} {{.order}} ✅`,
+		`“Quoted” {{- .name -}} ” {{.order}}`,
+	}
+	var paragraphs string
+	for _, text := range texts {
+		paragraphs += `<w:p><w:r><w:t xml:space="preserve">` + text + `</w:t></w:r></w:p>`
+	}
+	doc, err := OpenBytes(rewriteArchive(t, readFile(t, "./test/template.docx"), func(name string, data []byte) []byte {
+		if name == DocumentXml {
+			return []byte(strings.Replace(string(data), "<w:body>", "<w:body>"+paragraphs, 1))
+		}
+		return data
+	}, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+
+	if err := doc.ExecuteTemplate(map[string]interface{}{"name": "Ана 李", "order": "A-42", "vip": true}); err != nil {
+		t.Fatal(err)
+	}
+	documentXml := string(doc.GetFile(DocumentXml))
+	for _, expected := range []string{
+		`Здравствуйте, Ана 李! Заказ № A-42`,
+		`尊敬的Ана 李，您的订单A-42已发货`,
+		`🎉🎉 Ана 李 👋 ⭐ A-42 ✅`,
+		`“Quoted”Ана 李” A-42`,
+	} {
+		if !strings.Contains(documentXml, expected) {
+			t.Errorf("expected %q in the document", expected)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := doc.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenBytes(buf.Bytes()); err != nil {
+		t.Errorf("invalid output: %s", err)
+	}
+}
+
 func TestTemplatePlaceholderProcessing(t *testing.T) {
 	// Test template content processing
 	templateContent := "{{.name | upper}}"
@@ -188,3 +285,839 @@ func TestTemplateReplacer_MissingFields(t *testing.T) {
 
 	t.Log("Successfully handled missing fields without corruption")
 }
+
+func TestTemplateReplacer_ErrorContext(t *testing.T) {
+	errFailed := errors.New("failed on purpose")
+	funcs := template.FuncMap{"fail": func(interface{}) (string, error) { return "", errFailed }}
+
+	tests := []struct {
+		part      string
+		old, new  string // the replaced content of the part
+		paragraph string
+		action    string
+	}{
+		{"word/header1.xml", "Header {key}", "Total: {{fail .total}} EUR", "Total: {{fail .total}} EUR", "{{fail .total}}"},
+		{DocumentXml, "<w:body>", `<w:body><w:p><w:r><w:t>{{if fail .vip}}</w:t></w:r></w:p><w:p><w:r><w:t>VIP</w:t></w:r></w:p><w:p><w:r><w:t>{{end}}</w:t></w:r></w:p>`, "{{if fail .vip}}", "{{if fail .vip}}"},
+	}
+	for _, test := range tests {
+		doc, err := OpenBytes(rewriteArchive(t, readFile(t, "./test/template.docx"), func(name string, data []byte) []byte {
+			if name == test.part {
+				return []byte(strings.Replace(string(data), test.old, test.new, 1))
+			}
+			return data
+		}, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = doc.ExecuteTemplateWithFuncs(map[string]interface{}{"total": 1, "vip": true}, funcs)
+		var templateErr *TemplateError
+		if !errors.As(err, &templateErr) {
+			t.Fatalf("expected a TemplateError, got %v", err)
+		}
+		if templateErr.Part != test.part || templateErr.Paragraph != test.paragraph || templateErr.Action != test.action {
+			t.Errorf("unexpected location %s, %q, %s", templateErr.Part, templateErr.Paragraph, templateErr.Action)
+		}
+		if !errors.Is(err, errFailed) {
+			t.Errorf("expected the error of the function, got %v", err)
+		}
+		if prefix := test.part + ": failed to execute " + test.action + " in paragraph"; !strings.HasPrefix(err.Error(), prefix) {
+			t.Errorf("expected the error to start with %q, got %q", prefix, err.Error())
+		}
+		doc.Close()
+	}
+}
+
+func TestTemplateReplacer_ParagraphBlocks(t *testing.T) {
+	p := func(text string) string {
+		return `<w:p><w:r><w:t>` + text + `</w:t></w:r></w:p>`
+	}
+	body := p("{{if .showClause}}") +
+		p("Clause for {{.name}}") +
+		`<w:tbl><w:tr><w:tc>` + p("Clause table") + `</w:tc></w:tr></w:tbl>` +
+		p("{{if .nested}}") + p("Nested clause") + p("{{end}}") +
+		p("{{else}}") +
+		p("No clause") +
+		p("{{end}}") +
+		// the action is split across runs
+		`<w:p><w:r><w:t>{{if .</w:t></w:r><w:r><w:t>missing}}</w:t></w:r></w:p>` +
+		p("Missing") +
+		p("{{else if eq .name &quot;ACME&quot;}}") +
+		p("Else if") +
+		p("{{end}}") +
+		p("{{range .items}}") + p("Item {{.}} of {{$.name}}") + p("{{if eq . 2}}") + p("Second") + p("{{end}}") + p("{{end}}")
+
+	modify := func(name string, data []byte) []byte {
+		if name == DocumentXml {
+			return []byte(strings.Replace(string(data), "<w:body>", "<w:body>"+body, 1))
+		}
+		return data
+	}
+	doc, err := OpenBytes(rewriteArchive(t, readFile(t, "./test/template.docx"), modify, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+
+	err = doc.ExecuteTemplate(map[string]interface{}{"showClause": true, "nested": false, "name": "ACME", "items": []int{1, 2}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	documentXml := string(doc.GetFile(DocumentXml))
+	for _, expected := range []string{"Clause for ACME", "Clause table", "Else if", "Item 1 of ACME", "Item 2 of ACME<", "Second"} {
+		if !strings.Contains(documentXml, expected) {
+			t.Errorf("expected %q in the document", expected)
+		}
+	}
+	for _, unexpected := range []string{"{{", "Nested clause", "No clause", "Missing<"} {
+		if strings.Contains(documentXml, unexpected) {
+			t.Errorf("unexpected %q in the document", unexpected)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := doc.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenBytes(buf.Bytes()); err != nil {
+		t.Errorf("invalid output: %s", err)
+	}
+}
+
+func TestTemplateReplacer_TablesInBlocks(t *testing.T) {
+	p := func(text string) string {
+		return `<w:p><w:r><w:t>` + text + `</w:t></w:r></w:p>`
+	}
+	cell := func(text string) string {
+		return `<w:tc><w:tcPr><w:tcW w:w="3000" w:type="dxa"/></w:tcPr>` + p(text) + `</w:tc>`
+	}
+	body := p("{{range .Groups}}") +
+		p("Group {{.Name}}") +
+		`<w:tbl><w:tblGrid><w:gridCol w:w="3000"/><w:gridCol w:w="3000"/></w:tblGrid>` +
+		`<w:tr>` + cell("Item") + cell("Currency") + `</w:tr>` +
+		`<w:tr>` + cell("{{rows .Lines}}{{.Item}} of {{$.Owner}}") + cell("{{$.Currency}}") + `</w:tr></w:tbl>` +
+		`<w:tbl><w:tblGrid><w:gridCol w:w="3000"/></w:tblGrid>` +
+		`<w:tr>` + cell("{{columns .Lines}}[{{.Item}}]") + `</w:tr></w:tbl>` +
+		p("{{end}}")
+
+	modify := func(name string, data []byte) []byte {
+		if name == DocumentXml {
+			return []byte(strings.Replace(string(data), "<w:body>", "<w:body>"+body, 1))
+		}
+		return data
+	}
+	doc, err := OpenBytes(rewriteArchive(t, readFile(t, "./test/template.docx"), modify, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+	tablesBefore, err := findTables(doc.GetFile(DocumentXml))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type line struct{ Item string }
+	type group struct {
+		Name  string
+		Lines []line
+	}
+	err = doc.ExecuteTemplate(map[string]interface{}{
+		"Owner":    "ACME",
+		"Currency": "EUR",
+		"Groups": []group{
+			{Name: "Books", Lines: []line{{"Novel"}, {"Atlas"}}},
+			{Name: "Pens", Lines: []line{{"Fountain pen"}}},
+			{Name: "Empty"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	documentXml := doc.GetFile(DocumentXml)
+	for _, expected := range []string{"Group Books", "Novel of ACME", "Atlas of ACME", "Group Pens", "Fountain pen of ACME",
+		"[Novel]", "[Atlas]", `<w:gridCol w:w="1500"/><w:gridCol w:w="1500"/>`, `<w:tcW w:w="1500" w:type="dxa"/>`, "Group Empty"} {
+		if !strings.Contains(string(documentXml), expected) {
+			t.Errorf("expected %q in the document", expected)
+		}
+	}
+	if strings.Contains(string(documentXml), "{{") {
+		t.Errorf("unexpected actions in the document")
+	}
+
+	// both tables are repeated per group, except the column table of the group without lines
+	tables, err := findTables(documentXml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tables) != len(tablesBefore)+3 {
+		t.Fatalf("expected 5 tables for the groups, got %d", len(tables)-len(tablesBefore)+2)
+	}
+	for i, rows := range []int{3, 2, 1} {
+		if len(tables[2*i].Rows) != rows {
+			t.Errorf("expected %d rows in the table of group %d, got %d", rows, i, len(tables[2*i].Rows))
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := doc.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenBytes(buf.Bytes()); err != nil {
+		t.Errorf("invalid output: %s", err)
+	}
+}
+
+func TestParseTemplateBlocks_Errors(t *testing.T) {
+	p := func(text string) string {
+		return `<w:p><w:r><w:t>` + text + `</w:t></w:r></w:p>`
+	}
+	tests := []string{
+		p("{{if .a}}") + p("text"),
+		p("text") + p("{{end}}"),
+		p("{{if .a}} and text") + p("{{end}}"),
+		`<w:tbl><w:tr><w:tc>` + p("{{if .a}}") + `</w:tc></w:tr></w:tbl>` + p("{{end}}"),
+	}
+	for _, test := range tests {
+		if _, err := parseTemplateBlocks([]byte(`<w:body>` + test + `</w:body>`)); err == nil {
+			t.Errorf("expected an error for %s", test)
+		}
+	}
+}
+
+func TestTemplateReplacer_TableColumns(t *testing.T) {
+	cell := func(text string, props string) string {
+		return `<w:tc><w:tcPr><w:tcW w:w="3000" w:type="dxa"/>` + props + `</w:tcPr><w:p><w:r><w:t>` + text + `</w:t></w:r></w:p></w:tc>`
+	}
+	table := `<w:tbl><w:tblGrid><w:gridCol w:w="3000"/><w:gridCol w:w="3000"/></w:tblGrid>` +
+		`<w:tr>` + cell("Variant", "") + cell("{{columns .variants}}{{.name}}", "") + `</w:tr>` +
+		`<w:tr>` + cell("Price", "") + cell("{{.price}} {{$.currency}}", "") + `</w:tr>` +
+		`<w:tr>` + cell("Spanning", `<w:gridSpan w:val="2"/>`) + `</w:tr>` +
+		`</w:tbl>`
+
+	modify := func(name string, data []byte) []byte {
+		if name == DocumentXml {
+			return []byte(strings.Replace(string(data), "<w:body>", "<w:body>"+table, 1))
+		}
+		return data
+	}
+	doc, err := OpenBytes(rewriteArchive(t, readFile(t, "./test/template.docx"), modify, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+
+	err = doc.ExecuteTemplate(map[string]interface{}{
+		"currency": "EUR",
+		"variants": []map[string]interface{}{
+			{"name": "Basic", "price": 10},
+			{"name": "Pro & Co", "price": 20},
+			{"name": "Enterprise", "price": 30},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tables, err := findTables(doc.GetFile(DocumentXml))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := tables[0]
+	if len(result.GridCols) != 4 {
+		t.Fatalf("expected 4 grid columns, got %d", len(result.GridCols))
+	}
+	var texts []string
+	for _, row := range result.Rows {
+		for _, c := range row.Cells {
+			texts = append(texts, c.Text)
+		}
+	}
+	expected := []string{"Variant", "Basic", "Pro & Co", "Enterprise", "Price", "10 EUR", "20 EUR", "30 EUR", "Spanning"}
+	if strings.Join(texts, "|") != strings.Join(expected, "|") {
+		t.Errorf("expected cells %v, got %v", expected, texts)
+	}
+	if span := result.Rows[2].Cells[0].GridSpan; span != 4 {
+		t.Errorf("expected the spanning cell to span 4 columns, got %d", span)
+	}
+	if !strings.Contains(string(doc.GetFile(DocumentXml)), `<w:gridCol w:w="1000"/>`) {
+		t.Errorf("expected the width of the column to be divided")
+	}
+}
+
+func TestTemplateReplacer_EmptyTableColumns(t *testing.T) {
+	cell := func(text string) string {
+		return `<w:tc><w:p><w:r><w:t>` + text + `</w:t></w:r></w:p></w:tc>`
+	}
+	tables := `<w:tbl><w:tblGrid><w:gridCol w:w="3000"/></w:tblGrid>` +
+		`<w:tr>` + cell("{{columns .years}}{{.}}") + `</w:tr><w:tr>` + cell("Removed") + `</w:tr></w:tbl>` +
+		`<w:tbl><w:tblGrid><w:gridCol w:w="3000"/><w:gridCol w:w="3000"/></w:tblGrid>` +
+		`<w:tr>` + cell("Year") + cell("{{columns .years}}{{.}}") + `</w:tr><w:tr>` + cell("Revenue") + cell("x") + `</w:tr></w:tbl>`
+
+	modify := func(name string, data []byte) []byte {
+		if name == DocumentXml {
+			return []byte(strings.Replace(string(data), "<w:body>", "<w:body>"+tables, 1))
+		}
+		return data
+	}
+	doc, err := OpenBytes(rewriteArchive(t, readFile(t, "./test/template.docx"), modify, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+	if err := doc.ExecuteTemplate(map[string]interface{}{"years": []int{}}); err != nil {
+		t.Fatal(err)
+	}
+
+	documentXml := string(doc.GetFile(DocumentXml))
+	if strings.Contains(documentXml, "Removed") || strings.Contains(documentXml, "<w:tr></w:tr>") {
+		t.Errorf("expected the table without cells to be removed")
+	}
+	if !strings.Contains(documentXml, `<w:tblGrid><w:gridCol w:w="3000"/></w:tblGrid><w:tr>`+cell("Year")+`</w:tr><w:tr>`+cell("Revenue")+`</w:tr>`) {
+		t.Errorf("expected the other table to keep its first column")
+	}
+}
+
+func TestTemplateReplacer_TableRowsAndMerges(t *testing.T) {
+	cell := func(text string) string {
+		return `<w:tc><w:tcPr><w:tcW w:w="2000" w:type="dxa"/></w:tcPr><w:p><w:r><w:t>` + text + `</w:t></w:r></w:p></w:tc>`
+	}
+	table := `<w:tbl><w:tblGrid><w:gridCol w:w="2000"/><w:gridCol w:w="2000"/><w:gridCol w:w="2000"/></w:tblGrid>` +
+		`<w:tr>` + cell("{{rows .lines}}{{vmerge .group}}{{.group}}") + cell("{{.item}}") + cell("{{.amount}}") + `</w:tr>` +
+		`<w:tr>` + cell("{{rows .totals}}{{hmerge 2}}Total") + cell("removed") + cell("{{.}}") + `</w:tr>` +
+		`</w:tbl>`
+
+	modify := func(name string, data []byte) []byte {
+		if name == DocumentXml {
+			return []byte(strings.Replace(string(data), "<w:body>", "<w:body>"+table, 1))
+		}
+		return data
+	}
+	doc, err := OpenBytes(rewriteArchive(t, readFile(t, "./test/template.docx"), modify, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+
+	err = doc.ExecuteTemplate(map[string]interface{}{
+		"lines": []map[string]interface{}{
+			{"group": "Hardware", "item": "Laptop", "amount": 1000},
+			{"group": "Hardware", "item": "Monitor", "amount": 300},
+			{"group": "Software", "item": "License", "amount": 200},
+		},
+		"totals": []int{1500},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	documentXml := doc.GetFile(DocumentXml)
+	tables, err := findTables(documentXml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := tables[0].Rows
+	if len(rows) != 4 {
+		t.Fatalf("expected 4 rows, got %d", len(rows))
+	}
+
+	vMerges := []string{`<w:vMerge w:val="restart"/>`, `<w:vMerge/>`, `<w:vMerge w:val="restart"/>`}
+	for i, expected := range vMerges {
+		first := documentXml[rows[i].Cells[0].Start:rows[i].Cells[0].End]
+		if merge := cellProperty(first, "w:vMerge"); string(merge) != expected {
+			t.Errorf("row %d: expected %s, got %s", i, expected, merge)
+		}
+	}
+	if text := rows[1].Cells[0].Text; text != "" {
+		t.Errorf("expected the merged cell to be empty, got %q", text)
+	}
+
+	total := rows[3]
+	if len(total.Cells) != 2 || total.Cells[0].GridSpan != 2 || total.Cells[0].Text != "Total" || total.Cells[1].Text != "1500" {
+		t.Errorf("unexpected total row %+v", total.Cells)
+	}
+	if width := cellWidth(documentXml[total.Cells[0].Start:total.Cells[0].End]); width != 4000 {
+		t.Errorf("expected the merged cell to be 4000 wide, got %d", width)
+	}
+}
+
+func TestTemplateReplacer_TableConditions(t *testing.T) {
+	cell := func(text string) string {
+		return `<w:tc><w:p><w:r><w:t>` + text + `</w:t></w:r></w:p></w:tc>`
+	}
+	table := `<w:tbl><w:tblGrid><w:gridCol w:w="2000"/><w:gridCol w:w="2000"/><w:gridCol w:w="2000"/></w:tblGrid>` +
+		`<w:tr>` + cell("Item") + cell("{{columnif .hasDiscount}}Discount") + cell("Amount") + `</w:tr>` +
+		`<w:tr>` + cell("{{rows .lines}}{{rowif .amount}}{{.item}}") + cell("{{.discount}}") + cell("{{.amount}}") + `</w:tr>` +
+		`</w:tbl>` +
+		`<w:tbl><w:tr>` + cell("{{rowif .hasDiscount}}Removed table") + `</w:tr></w:tbl>`
+
+	modify := func(name string, data []byte) []byte {
+		if name == DocumentXml {
+			return []byte(strings.Replace(string(data), "<w:body>", "<w:body>"+table, 1))
+		}
+		return data
+	}
+	doc, err := OpenBytes(rewriteArchive(t, readFile(t, "./test/template.docx"), modify, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+
+	err = doc.ExecuteTemplate(map[string]interface{}{
+		"hasDiscount": false,
+		"lines": []map[string]interface{}{
+			{"item": "Laptop", "amount": 1000},
+			{"item": "Free sample", "amount": 0},
+			{"item": "Monitor", "amount": 300},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	documentXml := doc.GetFile(DocumentXml)
+	tables, err := findTables(documentXml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(documentXml), "Removed table") {
+		t.Errorf("expected the second table to be removed")
+	}
+	if len(tables[0].GridCols) != 2 {
+		t.Errorf("expected 2 grid columns, got %d", len(tables[0].GridCols))
+	}
+	var texts []string
+	for _, row := range tables[0].Rows {
+		for _, c := range row.Cells {
+			texts = append(texts, c.Text)
+		}
+	}
+	expected := []string{"Item", "Amount", "Laptop", "1000", "Monitor", "300"}
+	if strings.Join(texts, "|") != strings.Join(expected, "|") {
+		t.Errorf("expected cells %v, got %v", expected, texts)
+	}
+}
+
+func TestTemplateReplacer_TableLayout(t *testing.T) {
+	cell := func(properties, text string) string {
+		return `<w:tc>` + properties + `<w:p><w:r><w:t>` + text + `</w:t></w:r></w:p></w:tc>`
+	}
+	table := `<w:tbl><w:tblPr><w:tblStyle w:val="TableGrid"/><w:tblLook w:val="04A0"/></w:tblPr>` +
+		`<w:tblGrid><w:gridCol w:w="2000"/><w:gridCol w:w="2000"/></w:tblGrid>` +
+		`<w:tr>` + cell("", `{{tablelayout "fixed"}}{{tablewidth "100%"}}{{headerrow}}Item`) + cell("", "Amount") + `</w:tr>` +
+		`<w:tr>` + cell(`<w:tcPr><w:tcW w:w="2000" w:type="dxa"/><w:vAlign w:val="center"/></w:tcPr>`, `{{rows .lines}}{{cantsplit}}{{cellwidth "25%"}}{{.item}}`) +
+		cell("", `{{cellwidth "1in"}}{{.amount}}`) + `</w:tr>` +
+		`</w:tbl>` +
+		`<w:p><w:r><w:t>{{cantsplit}}Outside</w:t></w:r></w:p>`
+
+	modify := func(name string, data []byte) []byte {
+		if name == DocumentXml {
+			return []byte(strings.Replace(string(data), "<w:body>", "<w:body>"+table, 1))
+		}
+		return data
+	}
+	doc, err := OpenBytes(rewriteArchive(t, readFile(t, "./test/template.docx"), modify, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+
+	err = doc.ExecuteTemplate(map[string]interface{}{
+		"lines": []map[string]interface{}{{"item": "Laptop", "amount": 1000}, {"item": "Monitor", "amount": 300}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	documentXml := string(doc.GetFile(DocumentXml))
+	if strings.Contains(documentXml, "[[docx-layout") {
+		t.Fatalf("the layout markers were not resolved")
+	}
+	for _, expected := range []string{
+		`<w:tblPr><w:tblStyle w:val="TableGrid"/><w:tblW w:w="5000" w:type="pct"/><w:tblLayout w:type="fixed"/><w:tblLook w:val="04A0"/></w:tblPr>`,
+		`<w:tr><w:trPr><w:tblHeader/></w:trPr><w:tc><w:p><w:r><w:t>Item</w:t>`,
+		`<w:tcPr><w:tcW w:w="1440" w:type="dxa"/></w:tcPr><w:p><w:r><w:t>1000</w:t>`,
+		`<w:t>Outside</w:t>`,
+	} {
+		if !strings.Contains(documentXml, expected) {
+			t.Errorf("expected %s in %s", expected, documentXml)
+		}
+	}
+	row := `<w:tr><w:trPr><w:cantSplit/></w:trPr><w:tc><w:tcPr><w:tcW w:w="1250" w:type="pct"/><w:vAlign w:val="center"/></w:tcPr>`
+	if count := strings.Count(documentXml, row); count != 2 {
+		t.Errorf("expected 2 rows which can not split, got %d", count)
+	}
+
+	invalid, err := OpenBytes(rewriteArchive(t, readFile(t, "./test/template.docx"), func(name string, data []byte) []byte {
+		if name == DocumentXml {
+			return []byte(strings.Replace(string(data), "<w:body>", `<w:body><w:tbl><w:tr>`+cell("", `{{tablelayout "grid"}}`)+`</w:tr></w:tbl>`, 1))
+		}
+		return data
+	}, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer invalid.Close()
+	if err := invalid.ExecuteTemplate(map[string]interface{}{}); err == nil || !strings.Contains(err.Error(), "invalid layout") {
+		t.Errorf("expected an error for an invalid layout, got %v", err)
+	}
+}
+
+func TestTemplateReplacer_TableShading(t *testing.T) {
+	cell := func(text string) string {
+		return `<w:tc><w:tcPr><w:tcW w:w="2000" w:type="dxa"/></w:tcPr><w:p><w:r><w:t>` + text + `</w:t></w:r></w:p></w:tc>`
+	}
+	table := `<w:tbl><w:tblGrid><w:gridCol w:w="2000"/><w:gridCol w:w="2000"/></w:tblGrid>` +
+		`<w:tr>` + cell(`{{shade "d9d9d9"}}Item`) + cell("Amount") + `</w:tr>` +
+		`<w:tr>` + cell(`{{rows .lines}}{{band "FFFFFF" "F2F2F2"}}{{.item}}`) +
+		cell(`{{if lt .amount 0}}{{shaderow "FFEEEE"}}{{shade "FF0000"}}{{end}}{{.amount}}`) + `</w:tr>` +
+		`</w:tbl>`
+
+	modify := func(name string, data []byte) []byte {
+		if name == DocumentXml {
+			return []byte(strings.Replace(string(data), "<w:body>", "<w:body>"+table, 1))
+		}
+		return data
+	}
+	doc, err := OpenBytes(rewriteArchive(t, readFile(t, "./test/template.docx"), modify, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+
+	err = doc.ExecuteTemplate(map[string]interface{}{
+		"lines": []map[string]interface{}{{"item": "Laptop", "amount": 1000}, {"item": "Refund", "amount": -50}, {"item": "Monitor", "amount": 300}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	documentXml := doc.GetFile(DocumentXml)
+	tables, err := findTables(documentXml)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shadingRegex := regexp.MustCompile(`<w:tcPr><w:tcW w:w="2000" w:type="dxa"/><w:shd w:val="clear" w:color="auto" w:fill="([0-9A-F]{6})"/></w:tcPr>`)
+	var fills []string
+	for _, row := range tables[0].Rows {
+		for _, c := range row.Cells {
+			fill := "none"
+			if match := shadingRegex.FindSubmatch(documentXml[c.Start:c.End]); match != nil {
+				fill = string(match[1])
+			}
+			fills = append(fills, fill)
+		}
+	}
+	expected := []string{"D9D9D9", "none", "FFFFFF", "FFFFFF", "FFEEEE", "FF0000", "FFFFFF", "FFFFFF"}
+	if strings.Join(fills, "|") != strings.Join(expected, "|") {
+		t.Errorf("expected fills %v, got %v", expected, fills)
+	}
+	if strings.Contains(string(documentXml), "[[docx-layout") {
+		t.Errorf("the markers were not resolved")
+	}
+
+	for _, helper := range []func() (cellMarker, error){
+		func() (cellMarker, error) { return shadeHelper("red") },
+		func() (cellMarker, error) { return shaderowHelper("#FFEEEE") },
+		func() (cellMarker, error) { return bandHelper() },
+	} {
+		if _, err := helper(); err == nil {
+			t.Errorf("expected an error for an invalid color")
+		}
+	}
+}
+
+func TestTableWidth(t *testing.T) {
+	tests := []struct {
+		width interface{}
+		value int
+		unit  string
+	}{
+		{1440, 1440, "dxa"},
+		{2000.4, 2000, "dxa"},
+		{"auto", 0, "auto"},
+		{"50%", 2500, "pct"},
+		{"12pt", 240, "dxa"},
+		{"2.54cm", 1440, "dxa"},
+		{"10mm", 567, "dxa"},
+		{"0.5in", 720, "dxa"},
+		{" 3000 ", 3000, "dxa"},
+	}
+	for _, test := range tests {
+		value, unit, err := tableWidth(test.width)
+		if err != nil || value != test.value || unit != test.unit {
+			t.Errorf("tableWidth(%v) = %d %s %v, expected %d %s", test.width, value, unit, err, test.value, test.unit)
+		}
+	}
+	for _, width := range []interface{}{"wide", "-5%", true} {
+		if _, _, err := tableWidth(width); err == nil {
+			t.Errorf("expected an error for the width %v", width)
+		}
+	}
+}
+
+func TestDocument_SetTemplateEngine(t *testing.T) {
+	paragraph := `<w:p><w:r><w:t>Dear {{ customer.name }}, {{unknown}} &lt;[[upper city]]&gt;</w:t></w:r></w:p>`
+	split := `<w:p><w:r><w:t>Split {{ custo</w:t></w:r><w:r><w:rPr><w:b/></w:rPr><w:t>mer.name }} [[upper </w:t></w:r><w:r><w:t>city]].</w:t></w:r></w:p>`
+	modify := func(name string, data []byte) []byte {
+		if name == DocumentXml {
+			return []byte(strings.Replace(string(data), "<w:body>", "<w:body>"+paragraph+split, 1))
+		}
+		return data
+	}
+	doc, err := OpenBytes(rewriteArchive(t, readFile(t, "./test/template.docx"), modify, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+
+	data := map[string]interface{}{
+		"customer": map[string]interface{}{"name": "Smith & Sons"},
+		"city":     "berlin",
+	}
+	doc.SetTemplateEngine(templating.Mustache{})
+	if err := doc.ExecuteTemplate(data); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(doc.GetFile(DocumentXml)), "Dear Smith &amp; Sons, {{unknown}} ") {
+		t.Errorf("mustache placeholders were not replaced")
+	}
+	if text := string(doc.GetFile(DocumentXml)); strings.Contains(text, "custo") || !strings.Contains(text, "Smith &amp; Sons") {
+		t.Errorf("mustache placeholders split across runs were not replaced")
+	}
+
+	// engines with other delimiters
+	doc.SetTemplateEngine(templating.EngineFunc{Left: "[[", Right: "]]", Func: func(expression string, data interface{}) (string, error) {
+		fields := strings.Fields(expression)
+		value, _ := templating.Lookup(data, fields[1])
+		return strings.ToUpper(value.(string)), nil
+	}})
+	if err := doc.ExecuteTemplate(data); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(doc.GetFile(DocumentXml)), "&lt;BERLIN&gt;") {
+		t.Errorf("custom placeholders were not replaced")
+	}
+	if !strings.Contains(string(doc.GetFile(DocumentXml)), "<w:t xml:space=\"preserve\"> BERLIN</w:t>") {
+		t.Errorf("custom placeholders split across runs were not replaced")
+	}
+}
+
+func TestDocument_ImageHelper(t *testing.T) {
+	paragraph := `<w:p><w:r><w:rPr><w:b/></w:rPr><w:t>Logo: {{image .logo}} and {{image .missing}}done</w:t></w:r></w:p>`
+	modify := func(name string, data []byte) []byte {
+		if name == DocumentXml {
+			return []byte(strings.Replace(string(data), "<w:body>", "<w:body>"+paragraph, 1))
+		}
+		return data
+	}
+	doc, err := OpenBytes(rewriteArchive(t, readFile(t, "./test/template.docx"), modify, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+
+	var logo bytes.Buffer
+	if err := png.Encode(&logo, image.NewRGBA(image.Rect(0, 0, 40, 20))); err != nil {
+		t.Fatal(err)
+	}
+	err = doc.ExecuteTemplate(map[string]interface{}{"logo": Image{Data: logo.Bytes(), Height: 10, Name: "Logo", AltText: "Company logo"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	documentXml := string(doc.GetFile(DocumentXml))
+	for _, expected := range []string{"Logo: </w:t>", `<wp:extent cx="190500" cy="95250"/>`, `name="Logo"`, `descr="Company logo"`, "and done"} {
+		if !strings.Contains(documentXml, expected) {
+			t.Errorf("expected %q in the document", expected)
+		}
+	}
+	targets := doc.relationshipTargets(DocumentXml, ImageRelationshipType)
+	if len(targets) == 0 || !bytes.Equal(doc.GetFile(targets[len(targets)-1]), logo.Bytes()) {
+		t.Errorf("the image part was not added, targets: %v", targets)
+	}
+
+	invalid, err := OpenBytes(rewriteArchive(t, readFile(t, "./test/template.docx"), modify, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer invalid.Close()
+	if err := invalid.ExecuteTemplate(map[string]interface{}{"logo": 42}); err == nil {
+		t.Errorf("expected an error for an unsupported image value")
+	}
+}
+
+func TestDocument_DocxtemplaterSyntax(t *testing.T) {
+	paragraph := func(text string) string {
+		return `<w:p><w:r><w:t xml:space="preserve">` + text + `</w:t></w:r></w:p>`
+	}
+	cell := func(text string) string {
+		return `<w:tc><w:p><w:r><w:t xml:space="preserve">` + text + `</w:t></w:r></w:p></w:tc>`
+	}
+	body := paragraph("Dear {name},") +
+		paragraph("{#items}") + paragraph("- {title} for {name}") + paragraph("{/items}") +
+		paragraph("{^discount}No discount{/discount}{#vip} VIP{/vip}") +
+		`<w:tbl><w:tblGrid><w:gridCol w:w="2000"/><w:gridCol w:w="2000"/></w:tblGrid>` +
+		`<w:tr>` + cell("{#items}[{title}]") + cell("{price}{/items}") + `</w:tr></w:tbl>` +
+		paragraph("{%logo}")
+
+	modify := func(name string, data []byte) []byte {
+		if name == DocumentXml {
+			return []byte(strings.Replace(string(data), "<w:body>", "<w:body>"+body, 1))
+		}
+		return data
+	}
+	doc, err := OpenBytes(rewriteArchive(t, readFile(t, "./test/template.docx"), modify, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+
+	var logo bytes.Buffer
+	if err := png.Encode(&logo, image.NewRGBA(image.Rect(0, 0, 40, 20))); err != nil {
+		t.Fatal(err)
+	}
+
+	doc.SetDocxtemplaterSyntax(true)
+	err = doc.ExecuteTemplate(map[string]interface{}{
+		"name": "Smith & Sons",
+		"items": []map[string]interface{}{
+			{"title": "Book", "price": 12},
+			{"title": "Pen", "price": 3},
+		},
+		"discount": 0,
+		"vip":      true,
+		"logo":     Image{Data: logo.Bytes(), Width: 80, AltText: "Logo"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	documentXml := string(doc.GetFile(DocumentXml))
+	for _, expected := range []string{
+		"Dear Smith &amp; Sons,",
+		"- Book for Smith &amp; Sons", "- Pen for Smith &amp; Sons",
+		"No discount VIP",
+		"[Book]", "12", "[Pen]", "3",
+		`<wp:extent cx="762000" cy="381000"/>`, `descr="Logo"`,
+	} {
+		if !strings.Contains(documentXml, expected) {
+			t.Errorf("expected %q in the document", expected)
+		}
+	}
+	if strings.Contains(documentXml, "{#items}") || strings.Contains(documentXml, "{/items}") {
+		t.Errorf("section tags were not removed")
+	}
+
+	tables, err := findTables([]byte(documentXml))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows := len(tables[0].Rows); rows != 2 {
+		t.Errorf("expected 2 table rows, got %d", rows)
+	}
+
+	drawings := doc.Drawings()
+	if len(drawings) == 0 || drawings[0].AltText != "Logo" {
+		t.Fatalf("the image was not inserted, drawings: %v", drawings)
+	}
+	targets := doc.relationshipTargets(DocumentXml, ImageRelationshipType)
+	if len(targets) == 0 || len(doc.GetFile(targets[len(targets)-1])) != logo.Len() {
+		t.Errorf("the image part was not added, targets: %v", targets)
+	}
+
+	var out bytes.Buffer
+	if err := doc.Write(&out); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenBytes(out.Bytes()); err != nil {
+		t.Errorf("the written document cannot be opened: %v", err)
+	}
+}
+
+func TestDocument_DocxtemplaterSplitTags(t *testing.T) {
+	runs := func(texts ...string) string {
+		var sb strings.Builder
+		sb.WriteString("<w:p>")
+		for i, text := range texts {
+			// every second run is bold, so Word would keep the runs apart
+			properties := ""
+			if i%2 == 1 {
+				properties = "<w:rPr><w:b/></w:rPr>"
+			}
+			sb.WriteString(`<w:r>` + properties + `<w:t xml:space="preserve">` + text + `</w:t></w:r>`)
+		}
+		sb.WriteString("</w:p>")
+		return sb.String()
+	}
+	open := func(body string) *Document {
+		t.Helper()
+		modify := func(name string, data []byte) []byte {
+			if name == DocumentXml {
+				return []byte(strings.Replace(string(data), "<w:body>", "<w:body>"+body, 1))
+			}
+			return data
+		}
+		doc, err := OpenBytes(rewriteArchive(t, readFile(t, "./test/template.docx"), modify, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		doc.SetDocxtemplaterSyntax(true)
+		return doc
+	}
+	data := map[string]interface{}{
+		"name":  "ACME",
+		"items": []map[string]interface{}{{"title": "Book"}, {"title": "Pen"}},
+		"vip":   true,
+	}
+
+	tests := []struct {
+		name     string
+		body     string
+		expected []string
+	}{
+		{"inline", runs("Dear {na", "me},"), []string{"Dear ACME,"}},
+		{"inline section", runs("{#v", "ip}VIP{/", "vip} {name}"), []string{"VIP", "ACME"}},
+		{"loop open", runs("{#it", "ems}") + runs("- {title}") + runs("{/items}"), []string{"- Book", "- Pen"}},
+		{"loop close", runs("{#items}") + runs("- {ti", "tle}") + runs("{/it", "ems}"), []string{"- Book", "- Pen"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			doc := open(test.body)
+			defer doc.Close()
+			if err := doc.ExecuteTemplate(data); err != nil {
+				t.Fatal(err)
+			}
+			texts, err := paragraphTexts(doc.GetFile(DocumentXml))
+			if err != nil {
+				t.Fatal(err)
+			}
+			// the paragraphs of the template following the inserted ones contain braces of their own
+			text, _, _ := strings.Cut(strings.Join(texts, "|"), "|---")
+			for _, expected := range test.expected {
+				if !strings.Contains(text, expected) {
+					t.Errorf("expected %q in %q", expected, text)
+				}
+			}
+			if strings.ContainsAny(text, "{}") {
+				t.Errorf("unexpected tags in %q", text)
+			}
+		})
+	}
+
+	// tags which do not match are reported with the tag instead of a failing {{end}}
+	for _, body := range []string{
+		runs("{/items}"),
+		runs("{#items}") + runs("text"),
+		runs("{#items}") + runs("{/vip}"),
+	} {
+		doc := open(body)
+		err := doc.ExecuteTemplate(data)
+		var templateErr *TemplateError
+		if !errors.As(err, &templateErr) || !strings.HasPrefix(templateErr.Action, "{") {
+			t.Errorf("expected a template error naming the tag for %s, got %v", body, err)
+		}
+		doc.Close()
+	}
+}
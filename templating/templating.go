@@ -0,0 +1,132 @@
+// Package templating defines the interface of template engines which evaluate the placeholders of a docx document.
+//
+// The docx package finds the placeholders inside the document, passes their expressions to the engine
+// and replaces them with the XML-escaped results. By default, placeholders are evaluated with text/template.
+package templating
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ErrMissingValue is returned by engines if the expression references a value which does not exist in the data.
+// Such placeholders are left unchanged in the document.
+var ErrMissingValue = errors.New("missing value")
+
+// Engine evaluates the placeholders of a document.
+type Engine interface {
+	// Delimiters returns the delimiters which enclose a placeholder, e.g. "{{" and "}}".
+	Delimiters() (left, right string)
+	// Execute evaluates the expression of a single placeholder (without its delimiters) with the given data.
+	Execute(expression string, data interface{}) (string, error)
+}
+
+// EngineFunc adapts an ordinary function to the Engine interface.
+type EngineFunc struct {
+	Left, Right string
+	Func        func(expression string, data interface{}) (string, error)
+}
+
+// Delimiters implements the Engine interface.
+func (e EngineFunc) Delimiters() (string, string) {
+	return e.Left, e.Right
+}
+
+// Execute implements the Engine interface.
+func (e EngineFunc) Execute(expression string, data interface{}) (string, error) {
+	return e.Func(expression, data)
+}
+
+// Mustache is a minimal engine for moustache-style variables like {{name}} or {{customer.address.city}}.
+// Sections and partials are not supported.
+type Mustache struct{}
+
+// Delimiters implements the Engine interface.
+func (Mustache) Delimiters() (string, string) {
+	return "{{", "}}"
+}
+
+// Execute implements the Engine interface.
+func (Mustache) Execute(expression string, data interface{}) (string, error) {
+	value, exists := Lookup(data, strings.TrimSpace(expression))
+	if !exists {
+		return "", ErrMissingValue
+	}
+	if value == nil {
+		return "", nil
+	}
+	return fmt.Sprint(value), nil
+}
+
+// Lookup resolves a dotted path like customer.address.city in the data. Each element of the path is
+// either a key of a map, an exported field or method of a struct or an index of a slice or array.
+// Pointers and interfaces are followed. The path "." or an empty path returns the data itself.
+func Lookup(data interface{}, path string) (interface{}, bool) {
+	value := reflect.ValueOf(data)
+	if path == "" || path == "." {
+		return data, true
+	}
+
+	for _, name := range strings.Split(strings.TrimPrefix(path, "."), ".") {
+		for value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface {
+			if value.IsNil() {
+				return nil, false
+			}
+			value = value.Elem()
+		}
+
+		if method := methodByName(value, name); method.IsValid() {
+			value = method.Call(nil)[0]
+			continue
+		}
+
+		switch value.Kind() {
+		case reflect.Map:
+			if value.Type().Key().Kind() != reflect.String {
+				return nil, false
+			}
+			value = value.MapIndex(reflect.ValueOf(name).Convert(value.Type().Key()))
+		case reflect.Struct:
+			field, exists := value.Type().FieldByName(name)
+			if !exists || !field.IsExported() {
+				return nil, false
+			}
+			// promoted fields behind a nil embedded pointer do not exist
+			promoted, err := value.FieldByIndexErr(field.Index)
+			if err != nil {
+				return nil, false
+			}
+			value = promoted
+		case reflect.Slice, reflect.Array:
+			index, err := strconv.Atoi(name)
+			if err != nil || index < 0 || index >= value.Len() {
+				return nil, false
+			}
+			value = value.Index(index)
+		default:
+			return nil, false
+		}
+		if !value.IsValid() {
+			return nil, false
+		}
+	}
+	return value.Interface(), true
+}
+
+// methodByName returns the exported method without arguments and with a single result, or an invalid value.
+func methodByName(value reflect.Value, name string) reflect.Value {
+	if !value.IsValid() {
+		return reflect.Value{}
+	}
+	method := value.MethodByName(name)
+	if !method.IsValid() && value.CanAddr() {
+		method = value.Addr().MethodByName(name)
+	}
+	if !method.IsValid() || method.Type().NumIn() != 0 || method.Type().NumOut() != 1 {
+		return reflect.Value{}
+	}
+	return method
+}
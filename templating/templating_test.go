@@ -0,0 +1,67 @@
+package templating
+
+import (
+	"errors"
+	"testing"
+)
+
+type address struct {
+	City string
+}
+
+type customer struct {
+	Name    string
+	Address *address
+	tags    []string
+}
+
+type branch struct {
+	*address
+	Name string
+}
+
+func (c customer) Greeting() string {
+	return "Hello " + c.Name
+}
+
+func TestLookup(t *testing.T) {
+	data := map[string]interface{}{
+		"customer": customer{Name: "ACME", Address: &address{City: "Berlin"}},
+		"items":    []string{"a", "b"},
+		"missing":  nil,
+		"branch":   branch{Name: "HQ"},
+		"office":   branch{address: &address{City: "Paris"}},
+	}
+	tests := []struct {
+		path   string
+		value  interface{}
+		exists bool
+	}{
+		{"customer.Name", "ACME", true},
+		{"customer.Address.City", "Berlin", true},
+		{"customer.Greeting", "Hello ACME", true},
+		{"items.1", "b", true},
+		{"items.2", nil, false},
+		{"customer.tags", nil, false},
+		{"missing.value", nil, false},
+		{"unknown", nil, false},
+		{"branch.City", nil, false},
+		{"office.City", "Paris", true},
+	}
+	for _, test := range tests {
+		value, exists := Lookup(data, test.path)
+		if exists != test.exists || (exists && value != test.value) {
+			t.Errorf("Lookup(%s) = %v, %v, expected %v, %v", test.path, value, exists, test.value, test.exists)
+		}
+	}
+}
+
+func TestMustache(t *testing.T) {
+	result, err := Mustache{}.Execute(" name ", map[string]string{"name": "ACME"})
+	if err != nil || result != "ACME" {
+		t.Errorf("expected ACME, got %s (%v)", result, err)
+	}
+	if _, err := (Mustache{}).Execute("unknown", map[string]string{}); !errors.Is(err, ErrMissingValue) {
+		t.Errorf("expected ErrMissingValue, got %v", err)
+	}
+}
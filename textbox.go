@@ -0,0 +1,157 @@
+package docx
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// textbox.go keeps the two copies of the content of text boxes consistent. Word writes shapes like text boxes
+// and callouts as <mc:AlternateContent>, with a DrawingML shape in <mc:Choice> and a VML shape in <mc:Fallback>
+// for older readers. Both contain the same <w:txbxContent>, so the placeholders exist twice and are rendered
+// separately; functions with state (e.g. counters) or runs which Word split differently in the fallback lead to
+// different texts. After rendering, the content of the choice is therefore copied to the fallback.
+
+// alternateContent is an <mc:AlternateContent> element found by syncTextBoxes.
+type alternateContent struct {
+	depth    int        // the nesting level of the element
+	branch   string     // the local name of the current child, Choice or Fallback
+	choice   []Position // the content of the text boxes of the first choice
+	fallback []Position // the content of the text boxes of the fallback
+	choices  int        // the number of choices seen so far
+}
+
+// syncTextBoxes replaces the content of the text boxes in the fallback of all shapes with the content of the text
+// boxes in the choice, see textbox.go. Shapes whose choice and fallback contain a different number of text boxes
+// are left unchanged. Modified parts are parsed again.
+func (d *Document) syncTextBoxes() error {
+	for _, part := range d.xmlParts() {
+		data := d.GetFile(part)
+		if !bytes.Contains(data, []byte("txbxContent")) || !bytes.Contains(data, []byte("AlternateContent")) {
+			continue
+		}
+		synced, changed, err := syncTextBoxes(data)
+		if err != nil {
+			return fmt.Errorf("failed to parse text boxes in %s: %w", part, err)
+		}
+		if !changed {
+			continue
+		}
+		if err := d.SetFile(part, synced); err != nil {
+			return err
+		}
+		if err := d.refreshRuns(part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// syncTextBoxes returns the data with the content of the fallback text boxes replaced by the content of the
+// choice text boxes. Text boxes nested in other text boxes are copied together with their parent.
+func syncTextBoxes(data []byte) ([]byte, bool, error) {
+	docReader := NewReader(string(data))
+	decoder := xml.NewDecoder(docReader)
+
+	var replacements [][2]Position // the fallback content and the choice content replacing it
+	var stack []*alternateContent
+	depth := 0
+	textBoxDepth := 0      // the number of open text boxes
+	var contentStart int64 // offset behind the open tag of the outermost open text box
+	for {
+		tok, err := decoder.Token()
+		if tok == nil || err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, false, fmt.Errorf("error getting token: %s", err)
+		}
+
+		switch elem := tok.(type) {
+		case xml.StartElement:
+			depth++
+			if elem.Name.Local == "txbxContent" {
+				textBoxDepth++
+				if textBoxDepth == 1 {
+					contentStart = docReader.Pos()
+				}
+			}
+			if textBoxDepth > 0 {
+				continue
+			}
+			switch elem.Name.Local {
+			case "AlternateContent":
+				stack = append(stack, &alternateContent{depth: depth})
+			case "Choice", "Fallback":
+				if len(stack) > 0 && stack[len(stack)-1].depth == depth-1 {
+					current := stack[len(stack)-1]
+					current.branch = elem.Name.Local
+					if current.branch == "Choice" {
+						current.choices++
+					}
+				}
+			}
+		case xml.EndElement:
+			depth--
+			switch elem.Name.Local {
+			case "txbxContent":
+				textBoxDepth--
+				if textBoxDepth > 0 || len(stack) == 0 {
+					break
+				}
+				// the decoder reads ahead after character data, so the close tag is searched from its end
+				content := Position{Start: contentStart, End: int64(bytes.LastIndexByte(data[:docReader.Pos()], '<'))}
+				if docReader.Pos() == contentStart {
+					// a singleton tag, <w:txbxContent/>, which has no content to replace
+					content = Position{Start: -1, End: -1}
+				}
+				switch current := stack[len(stack)-1]; {
+				case current.branch == "Choice" && current.choices == 1:
+					current.choice = append(current.choice, content)
+				case current.branch == "Fallback":
+					current.fallback = append(current.fallback, content)
+				}
+			case "AlternateContent":
+				if textBoxDepth > 0 || len(stack) == 0 {
+					break
+				}
+				current := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				if len(current.choice) == 0 || len(current.choice) != len(current.fallback) {
+					break
+				}
+				for i, choice := range current.choice {
+					if choice.Start >= 0 && current.fallback[i].Start >= 0 {
+						replacements = append(replacements, [2]Position{current.fallback[i], choice})
+					}
+				}
+			}
+		}
+	}
+
+	// shapes nested in the fallback of another shape are found first
+	sort.Slice(replacements, func(i, j int) bool {
+		return replacements[i][0].Start < replacements[j][0].Start
+	})
+
+	var out bytes.Buffer
+	changed := false
+	pos := int64(0)
+	for _, replacement := range replacements {
+		fallback, choice := replacement[0], replacement[1]
+		if bytes.Equal(data[fallback.Start:fallback.End], data[choice.Start:choice.End]) {
+			continue
+		}
+		changed = true
+		out.Write(data[pos:fallback.Start])
+		out.Write(data[choice.Start:choice.End])
+		pos = fallback.End
+	}
+	if !changed {
+		return data, false, nil
+	}
+	out.Write(data[pos:])
+	return out.Bytes(), true, nil
+}
@@ -0,0 +1,124 @@
+package docx
+
+import (
+	"strings"
+	"testing"
+)
+
+// textBoxDocument returns the test template with a text box at the beginning of the body, the fallback
+// contains the given paragraphs instead of those of the choice.
+func textBoxDocument(t *testing.T, choice, fallback string) []byte {
+	shape := `<w:p><w:r><w:t>Before</w:t></w:r><w:r><mc:AlternateContent><mc:Choice Requires="wps"><w:drawing><wp:anchor><a:graphic><a:graphicData><wps:wsp><wps:txbx><w:txbxContent>` + choice +
+		`</w:txbxContent></wps:txbx></wps:wsp></a:graphicData></a:graphic></wp:anchor></w:drawing></mc:Choice><mc:Fallback><w:pict><v:shape><v:textbox><w:txbxContent>` + fallback +
+		`</w:txbxContent></v:textbox></v:shape></w:pict></mc:Fallback></mc:AlternateContent></w:r><w:r><w:t>After</w:t></w:r></w:p>`
+	return rewriteArchive(t, readFile(t, "./test/template.docx"), func(name string, data []byte) []byte {
+		if name == DocumentXml {
+			return []byte(strings.Replace(string(data), "<w:body>", "<w:body>"+shape, 1))
+		}
+		return data
+	}, nil)
+}
+
+// textBoxContents returns the content of all text boxes of the part.
+func textBoxContents(data string) []string {
+	var contents []string
+	for _, part := range strings.Split(data, "<w:txbxContent>")[1:] {
+		contents = append(contents, part[:strings.Index(part, "</w:txbxContent>")])
+	}
+	return contents
+}
+
+func TestDocument_ExecuteTemplate_TextBox(t *testing.T) {
+	choice := `<w:p><w:r><w:t>{{.name}} {{next}}</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>{{range .items}}</w:t></w:r></w:p><w:p><w:r><w:t>Item {{.}}</w:t></w:r></w:p><w:p><w:r><w:t>{{end}}</w:t></w:r></w:p>`
+	// Word split the placeholder of the fallback into two runs
+	fallback := `<w:p><w:r><w:t>{{.na</w:t></w:r><w:r><w:t>me}} {{next}}</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>{{range .items}}</w:t></w:r></w:p><w:p><w:r><w:t>Item {{.}}</w:t></w:r></w:p><w:p><w:r><w:t>{{end}}</w:t></w:r></w:p>`
+	doc, err := OpenBytes(textBoxDocument(t, choice, fallback))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+
+	counter := 0
+	doc.AddTemplateFuncs(map[string]interface{}{"next": func() int {
+		counter++
+		return counter
+	}})
+	if err := doc.ExecuteTemplate(map[string]interface{}{"name": "Anna", "items": []string{"a", "b"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the text box of the template follows the inserted one
+	contents := textBoxContents(string(doc.GetFile(DocumentXml)))
+	if len(contents) < 2 {
+		t.Fatalf("expected 2 text boxes, got %d", len(contents))
+	}
+	if !strings.Contains(contents[0], "Anna ") || !strings.Contains(contents[0], "Item b") {
+		t.Errorf("the text box was not rendered: %s", contents[0])
+	}
+	if contents[1] != contents[0] {
+		t.Errorf("the fallback differs from the choice: %s", contents[1])
+	}
+}
+
+func TestDocument_ReplaceAll_TextBox(t *testing.T) {
+	doc, err := OpenBytes(textBoxDocument(t, `<w:p><w:r><w:t>{key}</w:t></w:r></w:p>`, `<w:p><w:r><w:t>{ke</w:t></w:r><w:r><w:t>y}</w:t></w:r></w:p>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+	if err := doc.ReplaceAll(PlaceholderMap{"key": "value"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, content := range textBoxContents(string(doc.GetFile(DocumentXml)))[:2] {
+		if content != `<w:p><w:r><w:t>value</w:t></w:r></w:p>` {
+			t.Errorf("unexpected text box content %s", content)
+		}
+	}
+}
+
+func TestSyncTextBoxes(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected string
+	}{
+		{
+			name:     "without fallback",
+			data:     `<r><AlternateContent><Choice><txbxContent>a</txbxContent></Choice></AlternateContent></r>`,
+			expected: `<r><AlternateContent><Choice><txbxContent>a</txbxContent></Choice></AlternateContent></r>`,
+		},
+		{
+			name:     "different number of text boxes",
+			data:     `<AlternateContent><Choice><txbxContent>a</txbxContent><txbxContent>b</txbxContent></Choice><Fallback><txbxContent>c</txbxContent></Fallback></AlternateContent>`,
+			expected: `<AlternateContent><Choice><txbxContent>a</txbxContent><txbxContent>b</txbxContent></Choice><Fallback><txbxContent>c</txbxContent></Fallback></AlternateContent>`,
+		},
+		{
+			name:     "group of text boxes",
+			data:     `<AlternateContent><Choice><txbxContent>a</txbxContent><txbxContent>b</txbxContent></Choice><Fallback><txbxContent>c</txbxContent><txbxContent>d</txbxContent></Fallback></AlternateContent>`,
+			expected: `<AlternateContent><Choice><txbxContent>a</txbxContent><txbxContent>b</txbxContent></Choice><Fallback><txbxContent>a</txbxContent><txbxContent>b</txbxContent></Fallback></AlternateContent>`,
+		},
+		{
+			name:     "nested text box",
+			data:     `<AlternateContent><Choice><txbxContent>a<AlternateContent><Choice><txbxContent>b</txbxContent></Choice><Fallback><txbxContent>c</txbxContent></Fallback></AlternateContent></txbxContent></Choice><Fallback><txbxContent>d</txbxContent></Fallback></AlternateContent>`,
+			expected: `<AlternateContent><Choice><txbxContent>a<AlternateContent><Choice><txbxContent>b</txbxContent></Choice><Fallback><txbxContent>c</txbxContent></Fallback></AlternateContent></txbxContent></Choice><Fallback><txbxContent>a<AlternateContent><Choice><txbxContent>b</txbxContent></Choice><Fallback><txbxContent>c</txbxContent></Fallback></AlternateContent></txbxContent></Fallback></AlternateContent>`,
+		},
+		{
+			name:     "empty fallback",
+			data:     `<AlternateContent><Choice><txbxContent>a</txbxContent></Choice><Fallback><txbxContent/></Fallback></AlternateContent>`,
+			expected: `<AlternateContent><Choice><txbxContent>a</txbxContent></Choice><Fallback><txbxContent/></Fallback></AlternateContent>`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			synced, _, err := syncTextBoxes([]byte(tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(synced) != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, synced)
+			}
+		})
+	}
+}
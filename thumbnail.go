@@ -0,0 +1,177 @@
+package docx
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"path"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// ThumbnailRelationshipType is the package relationship type pointing to the thumbnail part.
+	ThumbnailRelationshipType = "http://schemas.openxmlformats.org/package/2006/relationships/metadata/thumbnail"
+)
+
+// ThumbnailFormat is the image format of a thumbnail.
+type ThumbnailFormat string
+
+const (
+	// ThumbnailJPEG stores the thumbnail as docProps/thumbnail.jpeg.
+	ThumbnailJPEG ThumbnailFormat = "jpeg"
+	// ThumbnailPNG stores the thumbnail as docProps/thumbnail.png.
+	ThumbnailPNG ThumbnailFormat = "png"
+	// ThumbnailWMF stores the thumbnail as docProps/thumbnail.wmf.
+	ThumbnailWMF ThumbnailFormat = "wmf"
+	// ThumbnailEMF stores the thumbnail as docProps/thumbnail.emf.
+	ThumbnailEMF ThumbnailFormat = "emf"
+)
+
+// ContentType returns the content type used in [Content_Types].xml for this format.
+func (f ThumbnailFormat) ContentType() string {
+	switch f {
+	case ThumbnailPNG:
+		return "image/png"
+	case ThumbnailWMF:
+		return "image/x-wmf"
+	case ThumbnailEMF:
+		return "image/x-emf"
+	default:
+		return "image/jpeg"
+	}
+}
+
+// ThumbnailRenderer renders a preview image of the first page of a document.
+// Renderers are typically backed by an external tool (e.g. LibreOffice) or service.
+type ThumbnailRenderer interface {
+	RenderThumbnail(doc *Document) ([]byte, ThumbnailFormat, error)
+}
+
+// ThumbnailRendererFunc allows the use of ordinary functions as ThumbnailRenderer.
+type ThumbnailRendererFunc func(doc *Document) ([]byte, ThumbnailFormat, error)
+
+// RenderThumbnail calls f(doc).
+func (f ThumbnailRendererFunc) RenderThumbnail(doc *Document) ([]byte, ThumbnailFormat, error) {
+	return f(doc)
+}
+
+// GenerateThumbnail renders a thumbnail using the given renderer and stores it in the document, see SetThumbnail.
+// It should be called after the template was executed, so the preview shows the rendered content.
+func (d *Document) GenerateThumbnail(renderer ThumbnailRenderer) error {
+	data, format, err := renderer.RenderThumbnail(d)
+	if err != nil {
+		return fmt.Errorf("unable to render thumbnail: %w", err)
+	}
+	return d.SetThumbnail(data, format)
+}
+
+// SetThumbnail adds or replaces the thumbnail of the document (docProps/thumbnail.*).
+// The package relationship and content type are updated accordingly. A previous thumbnail
+// of a different format is removed.
+func (d *Document) SetThumbnail(data []byte, format ThumbnailFormat) error {
+	if len(data) == 0 {
+		return fmt.Errorf("thumbnail is empty")
+	}
+	if _, exists := d.packageFiles[PackageRelsXml]; !exists {
+		return fmt.Errorf("invalid docx archive, %s is missing", PackageRelsXml)
+	}
+
+	fileName := "docProps/thumbnail." + string(format)
+	for _, oldFileName := range d.relationshipTargets("", ThumbnailRelationshipType) {
+		if oldFileName != fileName {
+			if err := d.removePart(oldFileName); err != nil {
+				return err
+			}
+		}
+	}
+	if err := d.removeRelationships("", ThumbnailRelationshipType); err != nil {
+		return err
+	}
+
+	if err := d.addPart(fileName, data, format.ContentType()); err != nil {
+		return err
+	}
+	_, err := d.addRelationship("", ThumbnailRelationshipType, fileName)
+	return err
+}
+
+// Thumbnail returns the current thumbnail of the document and its format, if any.
+func (d *Document) Thumbnail() ([]byte, ThumbnailFormat, bool) {
+	targets := d.relationshipTargets("", ThumbnailRelationshipType)
+	if len(targets) == 0 {
+		return nil, "", false
+	}
+	fileName := targets[0]
+	format := ThumbnailFormat(strings.TrimPrefix(path.Ext(fileName), "."))
+
+	data, exists := d.loadPackageFile(fileName)
+	if !exists {
+		return nil, "", false
+	}
+	return data, format, true
+}
+
+// TextPreviewRenderer is a dependency-free ThumbnailRenderer which draws a schematic preview of the first
+// page: every line of text is drawn as a grey bar of the appropriate length. It is meant as fallback
+// where no real renderer is available, so that file explorers show at least the layout of the page.
+type TextPreviewRenderer struct {
+	Width  int // width of the thumbnail in pixels, defaults to 192
+	Height int // height of the thumbnail in pixels, defaults to 256
+}
+
+// RenderThumbnail implements the ThumbnailRenderer interface.
+func (r TextPreviewRenderer) RenderThumbnail(doc *Document) ([]byte, ThumbnailFormat, error) {
+	width, height := r.Width, r.Height
+	if width <= 0 {
+		width = 192
+	}
+	if height <= 0 {
+		height = 256
+	}
+
+	blocks, err := parseBody(doc.GetFile(DocumentXml))
+	if err != nil {
+		return nil, "", err
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	bar := image.NewUniform(color.RGBA{R: 0x9e, G: 0x9e, B: 0x9e, A: 0xff})
+
+	margin := width / 10
+	lineHeight := height / 48
+	if lineHeight < 2 {
+		lineHeight = 2
+	}
+	textWidth := width - 2*margin
+	charsPerLine := 90
+
+	y := margin
+	for _, line := range strings.Split(blocksText(blocks), "\n") {
+		chars := utf8.RuneCountInString(strings.TrimSpace(line))
+		for chars > 0 && y+lineHeight < height-margin {
+			length := chars
+			if length > charsPerLine {
+				length = charsPerLine
+			}
+			barWidth := textWidth * length / charsPerLine
+			draw.Draw(img, image.Rect(margin, y, margin+barWidth, y+lineHeight*2/3), bar, image.Point{}, draw.Src)
+			y += lineHeight
+			chars -= length
+		}
+		y += lineHeight / 2
+		if y+lineHeight >= height-margin {
+			break
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 80}); err != nil {
+		return nil, "", fmt.Errorf("unable to encode thumbnail: %s", err)
+	}
+	return buf.Bytes(), ThumbnailJPEG, nil
+}
@@ -0,0 +1,396 @@
+package docx
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// toc.go implements UpdateTableOfContents, which regenerates the entries of the table of contents from the
+// headings of the document, and keeps the outline levels of the headings inserted by clauses and embedded
+// documents, see keepOutlineLevels.
+
+var (
+	// paragraphStartRegex matches the open tag and the properties of a paragraph, the group is the properties.
+	paragraphStartRegex = regexp.MustCompile(`(?s)^<w:p\b[^>]*>\s*(<w:pPr>.*?</w:pPr>)?`)
+	// paragraphStyleRegex matches the style of a paragraph, the group is the style id.
+	paragraphStyleRegex = regexp.MustCompile(`<w:pStyle\s+w:val="([^"]*)"`)
+	// outlineLevelRegex matches the outline level of a paragraph or a style, from 0 to 9.
+	outlineLevelRegex = regexp.MustCompile(`<w:outlineLvl\s+w:val="([0-9])"`)
+	// basedOnRegex matches the style a style is based on.
+	basedOnRegex = regexp.MustCompile(`<w:basedOn\s+w:val="([^"]*)"`)
+	// tocLevelsRegex matches the outline levels included by a TOC field, e.g. \o "1-3".
+	tocLevelsRegex = regexp.MustCompile(`\\o\s+"?([1-9])-([1-9])"?`)
+	// pageBreakBeforeRegex matches the property of paragraphs which start on a new page.
+	pageBreakBeforeRegex = regexp.MustCompile(`<w:pageBreakBefore(?:\s+w:val="(?:1|true|on)")?\s*/>`)
+	// pageBreakRegex matches the elements which start a new page: page breaks, paragraphs starting on a new page
+	// and the properties of sections. Sections start on a new page unless they are continuous.
+	pageBreakRegex = regexp.MustCompile(`(?s)<w:br\b[^>]*w:type="page"[^>]*/>|` + pageBreakBeforeRegex.String() +
+		`|<w:sectPr\b[^>]*>.*?</w:sectPr>`)
+)
+
+// bodyOutlineLevel is the outline level of paragraphs which are not headings.
+const bodyOutlineLevel = 9
+
+// outlineLevels are the outline levels of the paragraph styles of a document, see styleOutlineLevels.
+type outlineLevels struct {
+	levels       map[string]int
+	defaultStyle string
+}
+
+// styleOutlineLevels returns the outline levels of the paragraph styles, including the levels inherited from
+// the styles they are based on.
+func styleOutlineLevels(styles []byte) outlineLevels {
+	basedOn := make(map[string]string)
+	direct := make(map[string]int)
+	result := outlineLevels{levels: make(map[string]int)}
+	for _, style := range styleRegex.FindAll(styles, -1) {
+		tag := style[:bytes.IndexByte(style, '>')+1]
+		id, exists := getTagAttr(tag, "w:styleId")
+		if styleType, _ := getTagAttr(tag, "w:type"); !exists || styleType != "paragraph" {
+			continue
+		}
+		if isDefault, _ := getTagAttr(tag, "w:default"); isDefault == "1" || isDefault == "true" {
+			result.defaultStyle = id
+		}
+		if match := basedOnRegex.FindSubmatch(style); match != nil {
+			basedOn[id] = string(match[1])
+		}
+		if match := outlineLevelRegex.FindSubmatch(style); match != nil {
+			direct[id], _ = strconv.Atoi(string(match[1]))
+		}
+	}
+
+	for id := range basedOn {
+		result.levels[id] = bodyOutlineLevel
+	}
+	for id := range direct {
+		result.levels[id] = bodyOutlineLevel
+	}
+	for id := range result.levels {
+		// the depth is limited, so cyclic definitions end
+		for style, depth := id, 0; style != "" && depth < 20; style, depth = basedOn[style], depth+1 {
+			if level, exists := direct[style]; exists {
+				result.levels[id] = level
+				break
+			}
+		}
+	}
+	return result
+}
+
+// paragraphOutlineLevel returns the outline level of the paragraph from 0 to 8, or bodyOutlineLevel if the
+// paragraph is not a heading. A level set by the paragraph properties takes precedence over its style.
+func (l outlineLevels) paragraphOutlineLevel(paragraph []byte) int {
+	properties := paragraphStartRegex.FindSubmatch(paragraph)[1]
+	if match := outlineLevelRegex.FindSubmatch(properties); match != nil {
+		level, _ := strconv.Atoi(string(match[1]))
+		return level
+	}
+	style := l.defaultStyle
+	if match := paragraphStyleRegex.FindSubmatch(properties); match != nil {
+		style = string(match[1])
+	}
+	if level, exists := l.levels[style]; exists {
+		return level
+	}
+	return bodyOutlineLevel
+}
+
+// keepOutlineLevels returns the body imported from the source document with the outline levels of its styles.
+// Headings whose style is defined differently by this document, e.g. clause titles with a style which is a
+// heading in the clause but body text in the document, get their outline level as paragraph property, so they
+// appear in the table of contents like in the source document.
+func (d *Document) keepOutlineLevels(source *Document, body []byte) ([]byte, error) {
+	sourceStyles, exists := source.loadPackageFile(StylesXml)
+	if !exists {
+		return body, nil
+	}
+	styles, exists := d.loadPackageFile(StylesXml)
+	if !exists {
+		return body, nil
+	}
+	sourceLevels, levels := styleOutlineLevels(sourceStyles), styleOutlineLevels(styles)
+
+	paragraphs, err := findBlockParagraphs(body)
+	if err != nil {
+		return nil, err
+	}
+	// the properties are changed from the back, so the offsets of the preceding paragraphs stay valid
+	for i := len(paragraphs) - 1; i >= 0; i-- {
+		paragraph := body[paragraphs[i].Start:paragraphs[i].End]
+		properties := paragraphStartRegex.FindSubmatch(paragraph)[1]
+		match := paragraphStyleRegex.FindSubmatch(properties)
+		if match == nil || outlineLevelRegex.Match(properties) {
+			continue
+		}
+		style := string(match[1])
+		if _, defined := levels.levels[style]; !defined {
+			// the style is imported from the source document
+			continue
+		}
+		level := sourceLevels.paragraphOutlineLevel(paragraph)
+		if level == levels.paragraphOutlineLevel(paragraph) {
+			continue
+		}
+		element := []byte(`<w:outlineLvl w:val="` + strconv.Itoa(level) + `"/>`)
+		rest := setNestedProperty(body[paragraphs[i].Start:], paragraphOpenTagRegex, "w:pPr", "w:outlineLvl", element, paragraphPropertiesOrder)
+		body = append(append([]byte{}, body[:paragraphs[i].Start]...), rest...)
+	}
+	return body, nil
+}
+
+// tocField is the position of the TOC field of the main document.
+type tocField struct {
+	paragraph   int    // offset of the paragraph containing the start of the field
+	begin       int    // offset of the run starting the field
+	separate    int    // offset behind the run separating the instruction from the result, -1 if there is none
+	end         int    // offset of the run ending the field
+	endRun      int    // offset behind the run ending the field
+	instruction string // the instruction, e.g. TOC \o "1-3" \h \z \u
+}
+
+// tocHeading is a heading listed by the table of contents.
+type tocHeading struct {
+	level    int    // the level of the entry, from 1 to 9
+	text     string // the text of the heading
+	bookmark string // the name of the bookmark the entry refers to
+	page     int    // the estimated page of the heading
+}
+
+// UpdateTableOfContents regenerates the cached entries of the table of contents (the TOC field of the main
+// document) from the headings, so the printed table matches the content after clauses or other headings were
+// inserted, without requiring Word to update the fields. The outline levels selected by the field (\o "1-3")
+// are listed, taking the levels of the paragraph properties and of the styles into account; entries are linked
+// to their headings (\h) and show page numbers unless the field omits them (\n). The headings receive bookmarks
+// (_Toc...) for the links and the PAGEREF fields of the page numbers if they do not have one yet.
+//
+// Page numbers depend on the layout, which is only known to Word: they are estimated from the page breaks and
+// the sections starting on a new page. Set WriteOptions.UpdateFieldsOnOpen to have Word correct them. Documents
+// without a table of contents are left unchanged.
+func (d *Document) UpdateTableOfContents() error {
+	data := d.GetFile(DocumentXml)
+	field, found := findTableOfContents(data)
+	if !found {
+		return nil
+	}
+	styles, _ := d.loadPackageFile(StylesXml)
+	levels := styleOutlineLevels(styles)
+
+	minLevel, maxLevel := 1, 9
+	if match := tocLevelsRegex.FindStringSubmatch(field.instruction); match != nil {
+		minLevel, _ = strconv.Atoi(match[1])
+		maxLevel, _ = strconv.Atoi(match[2])
+	}
+	paragraphs, err := findBlockParagraphs(data)
+	if err != nil {
+		return err
+	}
+
+	names, nextID := d.bookmarks()
+	var headings []tocHeading
+	var replacements []replacement
+	previousEnd := int64(-1) // paragraphs inside of the previous paragraph, e.g. in text boxes, are skipped
+	for _, paragraph := range paragraphs {
+		if paragraph.Start < previousEnd || paragraph.End > int64(field.paragraph) && paragraph.Start < int64(field.endRun) {
+			continue
+		}
+		previousEnd = paragraph.End
+		content := data[paragraph.Start:paragraph.End]
+		level := levels.paragraphOutlineLevel(content) + 1
+		text := strings.TrimSpace(paragraph.Text)
+		if level < minLevel || level > maxLevel || text == "" {
+			continue
+		}
+
+		heading := tocHeading{level: level, text: text, page: 1 + countPageBreaks(data[:paragraph.Start])}
+		if pageBreakBeforeRegex.Match(paragraphStartRegex.Find(content)) {
+			heading.page++
+		}
+		for _, tag := range bookmarkStartRegex.FindAll(content, -1) {
+			if name := attrOf(tag, "w:name"); strings.HasPrefix(name, "_Toc") {
+				heading.bookmark = name
+				break
+			}
+		}
+		if heading.bookmark == "" {
+			for n := len(names); heading.bookmark == "" || names[heading.bookmark]; n++ {
+				heading.bookmark = fmt.Sprintf("_Toc%09d", n)
+			}
+			names[heading.bookmark] = true
+			id := strconv.Itoa(nextID)
+			nextID++
+			start := paragraph.Start + int64(len(paragraphStartRegex.Find(content)))
+			end := paragraph.End - int64(len("</w:p>"))
+			replacements = append(replacements,
+				replacement{start, start, []byte(`<w:bookmarkStart w:id="` + id + `" w:name="` + heading.bookmark + `"/>`)},
+				replacement{end, end, []byte(`<w:bookmarkEnd w:id="` + id + `"/>`)})
+		}
+		headings = append(headings, heading)
+	}
+
+	entries := tocEntries(data, field, headings, textWidth(data))
+	replacements = append(replacements, replacement{int64(field.paragraph), int64(field.endRun), entries})
+	sort.Slice(replacements, func(i, j int) bool {
+		return replacements[i].Start < replacements[j].Start
+	})
+	if err := d.SetFile(DocumentXml, applyReplacements(data, replacements)); err != nil {
+		return err
+	}
+	if err := d.addTocStyles(headings); err != nil {
+		return err
+	}
+	return d.refreshRuns(DocumentXml)
+}
+
+// findTableOfContents returns the position of the first TOC field of the part.
+func findTableOfContents(data []byte) (tocField, bool) {
+	type openField struct {
+		begin, separate int
+		instruction     strings.Builder
+	}
+	var open []*openField
+	for _, loc := range complexFieldRegex.FindAllSubmatchIndex(data, -1) {
+		if loc[2] >= 0 {
+			if len(open) > 0 {
+				open[len(open)-1].instruction.Write(data[loc[2]:loc[3]])
+			}
+			continue
+		}
+		fieldType, _ := getTagAttr(data[loc[0]:loc[1]], "w:fldCharType")
+		switch fieldType {
+		case "begin":
+			open = append(open, &openField{begin: runStart(data, loc[0]), separate: -1})
+		case "separate":
+			if len(open) > 0 && open[len(open)-1].separate < 0 {
+				open[len(open)-1].separate = runEnd(data, loc[1])
+			}
+		case "end":
+			if len(open) == 0 {
+				continue
+			}
+			field := open[len(open)-1]
+			open = open[:len(open)-1]
+			instruction := strings.TrimSpace(html.UnescapeString(field.instruction.String()))
+			if !strings.HasPrefix(strings.ToUpper(instruction), "TOC") || field.begin < 0 {
+				continue
+			}
+			paragraph := -1
+			for _, tag := range []string{"<w:p>", "<w:p "} {
+				paragraph = max(paragraph, bytes.LastIndex(data[:field.begin], []byte(tag)))
+			}
+			end, endRun := runStart(data, loc[0]), runEnd(data, loc[1])
+			if paragraph < 0 || end < 0 || endRun < 0 {
+				continue
+			}
+			return tocField{paragraph: paragraph, begin: field.begin, separate: field.separate, end: end, endRun: endRun, instruction: instruction}, true
+		}
+	}
+	return tocField{}, false
+}
+
+// tocEntries returns the paragraphs replacing the table of contents from the paragraph containing the start of
+// the field to the end of the field. The first entry starts the field, the field ends in a paragraph of its own,
+// which continues with the content following the field.
+func tocEntries(data []byte, field tocField, headings []tocHeading, width int) []byte {
+	start := data[field.begin:field.end]
+	if field.separate >= 0 {
+		start = data[field.begin:field.separate]
+	} else {
+		start = append(append([]byte{}, start...), `<w:r><w:fldChar w:fldCharType="separate"/></w:r>`...)
+	}
+	hyperlinks := regexp.MustCompile(`\\h\b`).MatchString(field.instruction)
+	pageNumbers := !regexp.MustCompile(`\\n\b`).MatchString(field.instruction)
+
+	var entries bytes.Buffer
+	if len(headings) == 0 {
+		entries.WriteString(`<w:p>`)
+		entries.Write(start)
+		entries.WriteString(`<w:r><w:t>No table of contents entries found.</w:t></w:r></w:p>`)
+	}
+	for i, heading := range headings {
+		fmt.Fprintf(&entries, `<w:p><w:pPr><w:pStyle w:val="TOC%d"/><w:tabs><w:tab w:val="right" w:leader="dot" w:pos="%d"/></w:tabs></w:pPr>`, heading.level, width)
+		if i == 0 {
+			entries.Write(start)
+		}
+		if hyperlinks {
+			entries.WriteString(`<w:hyperlink w:anchor="` + heading.bookmark + `" w:history="1">`)
+		}
+		entries.WriteString(`<w:r><w:t xml:space="preserve">` + xmlEscape(heading.text) + `</w:t></w:r>`)
+		if pageNumbers {
+			fmt.Fprintf(&entries, `<w:r><w:tab/></w:r><w:r><w:fldChar w:fldCharType="begin"/></w:r>`+
+				`<w:r><w:instrText xml:space="preserve"> PAGEREF %s \h </w:instrText></w:r><w:r><w:fldChar w:fldCharType="separate"/></w:r>`+
+				`<w:r><w:t>%d</w:t></w:r><w:r><w:fldChar w:fldCharType="end"/></w:r>`, heading.bookmark, heading.page)
+		}
+		if hyperlinks {
+			entries.WriteString(`</w:hyperlink>`)
+		}
+		entries.WriteString(`</w:p>`)
+	}
+	entries.WriteString(`<w:p>`)
+	entries.Write(data[field.end:field.endRun])
+	return entries.Bytes()
+}
+
+// countPageBreaks returns the number of page breaks in the data, see pageBreakRegex.
+func countPageBreaks(data []byte) int {
+	count := 0
+	for _, element := range pageBreakRegex.FindAll(data, -1) {
+		if !bytes.Contains(element, []byte(`<w:type w:val="continuous"/>`)) {
+			count++
+		}
+	}
+	return count
+}
+
+// textWidth returns the width of the text area of the last section in twips.
+func textWidth(data []byte) int {
+	sections := regexp.MustCompile(`(?s)<w:sectPr\b[^>]*>.*?</w:sectPr>`).FindAll(data, -1)
+	if len(sections) == 0 {
+		return builderTextWidth
+	}
+	section := sections[len(sections)-1]
+	twips := func(element, attr string) (int, bool) {
+		tag := regexp.MustCompile(`<` + element + `\b[^>]*>`).Find(section)
+		value, err := strconv.Atoi(attrOf(tag, attr))
+		return value, tag != nil && err == nil
+	}
+	page, ok := twips("w:pgSz", "w:w")
+	left, okLeft := twips("w:pgMar", "w:left")
+	right, okRight := twips("w:pgMar", "w:right")
+	if !ok || !okLeft || !okRight || page-left-right <= 0 {
+		return builderTextWidth
+	}
+	return page - left - right
+}
+
+// addTocStyles adds the styles of the entries (TOC1 to TOC9) which the document does not define yet. Each level
+// is indented by 11 points.
+func (d *Document) addTocStyles(headings []tocHeading) error {
+	styles, exists := d.loadPackageFile(StylesXml)
+	if !exists {
+		return nil
+	}
+	defined := make(map[string]bool)
+	for _, style := range styleRegex.FindAll(styles, -1) {
+		if id, ok := getTagAttr(style, "w:styleId"); ok {
+			defined[id] = true
+		}
+	}
+	var definitions []byte
+	for _, heading := range headings {
+		id := "TOC" + strconv.Itoa(heading.level)
+		if defined[id] {
+			continue
+		}
+		defined[id] = true
+		definitions = fmt.Appendf(definitions, `<w:style w:type="paragraph" w:styleId="%s"><w:name w:val="toc %d"/>`+
+			`<w:basedOn w:val="Normal"/><w:next w:val="Normal"/><w:autoRedefine/><w:uiPriority w:val="39"/><w:unhideWhenUsed/>`+
+			`<w:pPr><w:spacing w:after="100"/><w:ind w:left="%d"/></w:pPr></w:style>`, id, heading.level, 220*(heading.level-1))
+	}
+	return d.appendStyles(definitions)
+}
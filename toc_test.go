@@ -0,0 +1,96 @@
+package docx
+
+import (
+	"strings"
+	"testing"
+)
+
+// tocDocument returns a built document with the body.
+func tocDocument(t *testing.T, body string) *Document {
+	doc, err := NewDocument().Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	documentXml := strings.Replace(string(doc.GetFile(DocumentXml)), "<w:body>", "<w:body>"+body, 1)
+	if err := doc.SetFile(DocumentXml, []byte(documentXml)); err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.refreshRuns(DocumentXml); err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func TestDocument_UpdateTableOfContents(t *testing.T) {
+	heading := func(style, text string) string {
+		return `<w:p><w:pPr><w:pStyle w:val="` + style + `"/></w:pPr><w:r><w:t>` + text + `</w:t></w:r></w:p>`
+	}
+	toc := `<w:p><w:pPr><w:pStyle w:val="TOC1"/></w:pPr><w:r><w:fldChar w:fldCharType="begin"/></w:r>` +
+		`<w:r><w:instrText xml:space="preserve"> TOC \o "1-3" \h \z \u </w:instrText></w:r><w:r><w:fldChar w:fldCharType="separate"/></w:r>` +
+		`<w:hyperlink w:anchor="_Toc1"><w:r><w:t>Old entry</w:t></w:r><w:r><w:tab/></w:r><w:r><w:fldChar w:fldCharType="begin"/></w:r>` +
+		`<w:r><w:instrText xml:space="preserve"> PAGEREF _Toc1 \h </w:instrText></w:r><w:r><w:fldChar w:fldCharType="separate"/></w:r>` +
+		`<w:r><w:t>1</w:t></w:r><w:r><w:fldChar w:fldCharType="end"/></w:r></w:hyperlink></w:p>` +
+		`<w:p><w:r><w:fldChar w:fldCharType="end"/></w:r></w:p>`
+	doc := tocDocument(t, toc+heading("Heading1", "Introduction")+`<w:p><w:r><w:br w:type="page"/></w:r></w:p>`+
+		heading("Heading2", "Scope &amp; Terms")+heading("Heading4", "Details")+
+		`<w:p><w:pPr><w:outlineLvl w:val="0"/></w:pPr><w:r><w:t>Appendix</w:t></w:r></w:p>`)
+	defer doc.Close()
+
+	for range 2 {
+		// the entries are regenerated the same way, the bookmarks of the headings are kept
+		if err := doc.UpdateTableOfContents(); err != nil {
+			t.Fatal(err)
+		}
+		texts, err := paragraphTexts(doc.GetFile(DocumentXml))
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := []string{"Introduction1", "Scope & Terms2", "Appendix2", "", "Introduction", "", "Scope & Terms", "Details", "Appendix"}
+		if strings.Join(texts[:min(len(texts), len(expected))], "|") != strings.Join(expected, "|") {
+			t.Fatalf("unexpected paragraphs %q", texts)
+		}
+	}
+
+	documentXml := string(doc.GetFile(DocumentXml))
+	for _, expected := range []string{
+		`<w:p><w:pPr><w:pStyle w:val="TOC2"/><w:tabs><w:tab w:val="right" w:leader="dot" w:pos="9360"/></w:tabs></w:pPr><w:hyperlink w:anchor="_Toc000000001"`,
+		` PAGEREF _Toc000000001 \h `,
+		`<w:bookmarkStart w:id="1" w:name="_Toc000000001"/><w:r><w:t>Scope &amp; Terms</w:t></w:r><w:bookmarkEnd w:id="1"/>`,
+		` TOC \o "1-3" \h \z \u `,
+	} {
+		if !strings.Contains(documentXml, expected) {
+			t.Errorf("expected %s in the document", expected)
+		}
+	}
+	if strings.Count(documentXml, "<w:bookmarkStart") != 3 {
+		t.Errorf("expected a bookmark per heading, got %s", documentXml)
+	}
+	styles, _ := doc.loadPackageFile(StylesXml)
+	if !strings.Contains(string(styles), `w:styleId="TOC1"`) || !strings.Contains(string(styles), `w:styleId="TOC2"`) {
+		t.Error("expected the styles of the entries to be added")
+	}
+}
+
+func TestDocument_InsertClauseOutlineLevels(t *testing.T) {
+	doc := tocDocument(t, `<w:p><w:r><w:t>[CLAUSE]</w:t></w:r></w:p>`)
+	defer doc.Close()
+	// the document defines Heading1 as body text
+	styles, _ := doc.loadPackageFile(StylesXml)
+	doc.setPackageFile(StylesXml, []byte(strings.Replace(string(styles), `<w:outlineLvl w:val="0"/>`, "", 1)))
+
+	clause, err := NewDocument().Heading(1, "Confidentiality").Heading(2, "Scope").Paragraph("Text").Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clause.Close()
+	if err := doc.InsertClause("[CLAUSE]", clause); err != nil {
+		t.Fatal(err)
+	}
+	documentXml := string(doc.GetFile(DocumentXml))
+	if !strings.Contains(documentXml, `<w:pPr><w:pStyle w:val="Heading1"/><w:outlineLvl w:val="0"/></w:pPr><w:r><w:t xml:space="preserve">Confidentiality</w:t>`) {
+		t.Errorf("expected the clause title to keep its outline level, got %s", documentXml)
+	}
+	if !strings.Contains(documentXml, `<w:pPr><w:pStyle w:val="Heading2"/></w:pPr>`) {
+		t.Errorf("headings with the same outline level must not change, got %s", documentXml)
+	}
+}
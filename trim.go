@@ -0,0 +1,98 @@
+package docx
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// trim.go implements the trim markers of actions, e.g. {{- .name -}}. Inside a run, the white space next to
+// the marker is removed together with the action. A paragraph which consists of actions only, starts with
+// {{- and ends with -}} is removed completely if the actions produce no output, so comments and other
+// actions without output do not leave empty lines.
+
+// trimmedParagraphRegex matches the text of a paragraph which starts and ends with a trim marker.
+var trimmedParagraphRegex = regexp.MustCompile(`(?s)^\{\{-\s.*\s-\}\}$`)
+
+// withoutTrimMarkers removes the trim markers of the action content, e.g. "- if .x -" becomes " if .x ".
+func withoutTrimMarkers(content string) string {
+	if strings.HasPrefix(content, "-") && len(content) > 1 && isTrimSpace(content[1]) {
+		content = content[1:]
+	}
+	if strings.HasSuffix(content, "-") && len(content) > 1 && isTrimSpace(content[len(content)-2]) {
+		content = content[:len(content)-1]
+	}
+	return content
+}
+
+// isTrimSpace reports whether the byte is white space removed by trim markers.
+func isTrimSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n'
+}
+
+// trimPosition extends the positions of the action in the text of a run by the white space removed by its
+// trim markers.
+func trimPosition(text string, start, end int) (int, int) {
+	if !strings.HasPrefix(text[start:], "{{") || !strings.HasSuffix(text[:end], "}}") {
+		return start, end
+	}
+	content := text[start+2 : end-2]
+	if strings.HasPrefix(content, "- ") || strings.HasPrefix(content, "-\t") || strings.HasPrefix(content, "-\n") || strings.HasPrefix(content, "-\r") {
+		for start > 0 && isTrimSpace(text[start-1]) {
+			start--
+		}
+	}
+	if len(content) > 1 && content[len(content)-1] == '-' && isTrimSpace(content[len(content)-2]) {
+		for end < len(text) && isTrimSpace(text[end]) {
+			end++
+		}
+	}
+	return start, end
+}
+
+// trimParagraphs removes all paragraphs which consist of actions with trim markers at the boundaries
+// and whose actions produce no output.
+func (tr *TemplateReplacer) trimParagraphs() error {
+	for _, fileName := range tr.document.xmlParts() {
+		tr.part = fileName
+		data := tr.document.GetFile(fileName)
+		if !bytes.Contains(data, []byte("{{-")) {
+			continue
+		}
+		paragraphs, err := findBlockParagraphs(data)
+		if err != nil {
+			return fmt.Errorf("failed to parse paragraphs in %s: %w", fileName, err)
+		}
+
+		var replacements []replacement
+		end := int64(0)
+		for _, paragraph := range paragraphs {
+			text := strings.TrimSpace(paragraph.Text)
+			if paragraph.Start < end || paragraph.Embedded || !trimmedParagraphRegex.MatchString(text) ||
+				strings.TrimSpace(blockActionRegex.ReplaceAllString(text, "")) != "" ||
+				paragraph.Parent >= 0 && bytes.HasPrefix(data[paragraph.Parent:], []byte("<w:tc")) {
+				// table cells must keep their paragraphs
+				continue
+			}
+			result, err := tr.executeFragment(text, tr.data)
+			if err != nil || strings.TrimSpace(string(result)) != "" {
+				// the actions are processed like any other placeholder
+				continue
+			}
+			tr.debugLog("Removing paragraph %s", text)
+			replacements = append(replacements, replacement{paragraph.Start, paragraph.End, nil})
+			end = paragraph.End
+		}
+		if len(replacements) == 0 {
+			continue
+		}
+		if err := tr.document.SetFile(fileName, applyReplacements(data, replacements)); err != nil {
+			return err
+		}
+		if err := tr.document.refreshRuns(fileName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
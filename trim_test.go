@@ -0,0 +1,49 @@
+package docx
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDocument_TrimMarkers(t *testing.T) {
+	doc, err := OpenBytes(rewriteArchive(t, readFile(t, "./test/template.docx"), func(name string, data []byte) []byte {
+		if name == DocumentXml {
+			return []byte(strings.Replace(string(data), "<w:body>", "<w:body>"+
+				`<w:p><w:r><w:t xml:space="preserve">Dear   {{- .name -}}  , welcome</w:t></w:r></w:p>`+
+				`<w:p><w:r><w:t>{{- /* internal note */ -}}</w:t></w:r></w:p>`+
+				`<w:p><w:r><w:t>{{- if .vip -}}</w:t></w:r></w:p>`+
+				`<w:p><w:r><w:t>VIP content</w:t></w:r></w:p>`+
+				`<w:p><w:r><w:t>{{- else -}}</w:t></w:r></w:p>`+
+				`<w:p><w:r><w:t>Regular content</w:t></w:r></w:p>`+
+				`<w:p><w:r><w:t>{{- end -}}</w:t></w:r></w:p>`+
+				`<w:p><w:r><w:t>{{- range .items }}</w:t></w:r></w:p>`+
+				`<w:p><w:r><w:t>Item {{.}}</w:t></w:r></w:p>`+
+				`<w:p><w:r><w:t>{{ end -}}</w:t></w:r></w:p>`+
+				`<w:p><w:r><w:t>{{- .name -}}</w:t></w:r></w:p>`, 1))
+		}
+		return data
+	}, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+
+	paragraphs := strings.Count(string(doc.GetFile(DocumentXml)), "<w:p>")
+	if err := doc.ExecuteTemplate(map[string]interface{}{"name": "Bob", "vip": false, "items": []string{"A", "B"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	documentXml := string(doc.GetFile(DocumentXml))
+	for _, expected := range []string{"DearBob, welcome", "Regular content", "Item A", "Item B", "<w:t>Bob</w:t>"} {
+		if !strings.Contains(documentXml, expected) {
+			t.Errorf("expected %q in the document", expected)
+		}
+	}
+	if strings.Contains(documentXml, "VIP content") || strings.Contains(documentXml, "internal note") {
+		t.Errorf("unexpected content in the document")
+	}
+	// the comment, the if and range blocks (6 paragraphs) are removed, one paragraph of the range is repeated
+	if count := strings.Count(documentXml, "<w:p>"); count != paragraphs-6 {
+		t.Errorf("expected %d paragraphs, got %d", paragraphs-6, count)
+	}
+}
@@ -0,0 +1,36 @@
+package docx
+
+import (
+	"bytes"
+	"regexp"
+)
+
+// stringPlaceholderRegex matches the placeholders replaced by ReplaceAll, e.g. {customer}.
+var stringPlaceholderRegex = regexp.MustCompile(`\{[^{}]+\}`)
+
+// HasUnrenderedPlaceholders reports whether the body, headers or footers still contain template actions like
+// {{.name}} or placeholders of ReplaceAll like {name}, e.g. to verify that a stored document was actually
+// processed. Placeholders split across runs are found as well. Parts without braces are skipped without parsing
+// them, so the check is fast for rendered documents.
+func (d *Document) HasUnrenderedPlaceholders() bool {
+	for _, part := range d.xmlParts() {
+		data := d.GetFile(part)
+		if bytes.IndexByte(data, '{') < 0 && !bytes.Contains(data, []byte(smartLeftDelimiter)) {
+			continue
+		}
+		paragraphs, err := findBlockParagraphs(data)
+		if err != nil {
+			// the text cannot be read, so the part is checked as a whole
+			if stringPlaceholderRegex.Match(data) {
+				return true
+			}
+			continue
+		}
+		for _, paragraph := range paragraphs {
+			if len(findTemplateActions(paragraph.Text)) > 0 || stringPlaceholderRegex.MatchString(paragraph.Text) {
+				return true
+			}
+		}
+	}
+	return false
+}
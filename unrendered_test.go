@@ -0,0 +1,52 @@
+package docx
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDocument_HasUnrenderedPlaceholders(t *testing.T) {
+	withBody := func(body string) *Document {
+		t.Helper()
+		doc, err := OpenBytes(rewriteArchive(t, readFile(t, "./test/template.docx"), func(name string, data []byte) []byte {
+			if name == DocumentXml {
+				start, end := strings.Index(string(data), "<w:body>"), strings.Index(string(data), "<w:sectPr")
+				return []byte(string(data[:start]) + "<w:body>" + body + string(data[end:]))
+			}
+			// the header and footer of the template contain {key}
+			return []byte(strings.ReplaceAll(string(data), "{key}", "key"))
+		}, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(doc.Close)
+		return doc
+	}
+
+	tests := []struct {
+		name     string
+		body     string
+		expected bool
+	}{
+		{"rendered", `<w:p><w:r><w:t>Dear Anna</w:t></w:r></w:p>`, false},
+		{"template action", `<w:p><w:r><w:t>Dear {{.name}}</w:t></w:r></w:p>`, true},
+		{"split action", `<w:p><w:r><w:t>Dear {{.</w:t></w:r><w:r><w:rPr><w:b/></w:rPr><w:t>name}}</w:t></w:r></w:p>`, true},
+		{"string placeholder", `<w:p><w:r><w:t>Dear {</w:t></w:r><w:r><w:t>name}</w:t></w:r></w:p>`, true},
+		{"braces in different paragraphs", `<w:p><w:r><w:t>Set {</w:t></w:r></w:p><w:p><w:r><w:t>}</w:t></w:r></w:p>`, false},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if found := withBody(test.body).HasUnrenderedPlaceholders(); found != test.expected {
+				t.Errorf("expected %t, got %t", test.expected, found)
+			}
+		})
+	}
+
+	doc := withBody(`<w:p><w:r><w:t>Dear {{.name}}</w:t></w:r></w:p>`)
+	if err := doc.ExecuteTemplate(map[string]interface{}{"name": "Anna"}); err != nil {
+		t.Fatal(err)
+	}
+	if doc.HasUnrenderedPlaceholders() {
+		t.Errorf("expected the rendered document to have no placeholders")
+	}
+}
@@ -0,0 +1,402 @@
+package docx
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/izetmolla/docx/templating"
+)
+
+// Schema describes the data expected by a template. It supports the following subset of JSON Schema:
+// type, properties, required, items, enum, minimum, maximum, minLength, maxLength, pattern, minItems and maxItems.
+// The version of a schema is checked against the version of the template by Render, see SetVersionPolicy.
+type Schema struct {
+	Version    string             `json:"version,omitempty"`
+	Type       schemaTypes        `json:"type,omitempty"`
+	Properties map[string]*Schema `json:"properties,omitempty"`
+	Required   []string           `json:"required,omitempty"`
+	Items      *Schema            `json:"items,omitempty"`
+	Enum       []interface{}      `json:"enum,omitempty"`
+	Minimum    *float64           `json:"minimum,omitempty"`
+	Maximum    *float64           `json:"maximum,omitempty"`
+	MinLength  *int               `json:"minLength,omitempty"`
+	MaxLength  *int               `json:"maxLength,omitempty"`
+	Pattern    string             `json:"pattern,omitempty"`
+	MinItems   *int               `json:"minItems,omitempty"`
+	MaxItems   *int               `json:"maxItems,omitempty"`
+
+	pattern *regexp.Regexp
+	order   []string // the properties in the order of the fields of the struct, see SchemaFor
+}
+
+// schemaTypes are the allowed types of a value, e.g. "string" or ["string", "null"].
+type schemaTypes []string
+
+// UnmarshalJSON accepts a single type as well as a list of types.
+func (t *schemaTypes) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*t = schemaTypes{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("type must be a string or a list of strings")
+	}
+	*t = list
+	return nil
+}
+
+// MarshalJSON writes a single type as string.
+func (t schemaTypes) MarshalJSON() ([]byte, error) {
+	if len(t) == 1 {
+		return json.Marshal(t[0])
+	}
+	return json.Marshal([]string(t))
+}
+
+// ParseSchema parses a JSON Schema, see Schema for the supported keywords.
+func ParseSchema(data []byte) (*Schema, error) {
+	var schema Schema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	if err := schema.compile(""); err != nil {
+		return nil, err
+	}
+	return &schema, nil
+}
+
+// LoadSchema reads and parses a JSON Schema file, usually shipped next to the template.
+func LoadSchema(path string) (*Schema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseSchema(data)
+}
+
+// compile compiles the patterns of the schema and all nested schemas.
+func (s *Schema) compile(path string) error {
+	if s.Pattern != "" && s.pattern == nil {
+		pattern, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid pattern of %s: %w", schemaPath(path), err)
+		}
+		s.pattern = pattern
+	}
+	for name, property := range s.Properties {
+		if err := property.compile(joinPath(path, name)); err != nil {
+			return err
+		}
+	}
+	if s.Items != nil {
+		return s.Items.compile(path + "[]")
+	}
+	return nil
+}
+
+// SchemaFor derives a schema from the type of the value, usually a struct. Structs become objects whose
+// properties are the exported fields, slices and arrays become arrays. Fields are named like their docx tag
+// (see Render). Fields tagged as required must neither be missing nor nil nor an empty string, e.g.
+//
+//	type Offer struct {
+//		Customer string `docx:"customer,required"`
+//		Items    []Item
+//	}
+//
+// Values implementing SchemaVersioner set the version of the schema.
+func SchemaFor(value interface{}) *Schema {
+	schema := schemaForType(reflect.TypeOf(value), map[reflect.Type]bool{})
+	if versioner, ok := value.(SchemaVersioner); ok {
+		schema.Version = versioner.SchemaVersion()
+	}
+	return schema
+}
+
+// schemaForType derives the schema of the type. Recursive types are not followed.
+func schemaForType(t reflect.Type, seen map[reflect.Type]bool) *Schema {
+	if t == nil {
+		return &Schema{}
+	}
+	nullable := false
+	for t.Kind() == reflect.Pointer {
+		t, nullable = t.Elem(), true
+	}
+
+	schema := &Schema{}
+	switch t.Kind() {
+	case reflect.String:
+		schema.Type = schemaTypes{"string"}
+	case reflect.Bool:
+		schema.Type = schemaTypes{"boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		schema.Type = schemaTypes{"integer"}
+	case reflect.Float32, reflect.Float64:
+		schema.Type = schemaTypes{"number"}
+	case reflect.Slice, reflect.Array:
+		schema.Type = schemaTypes{"array"}
+		schema.Items = schemaForType(t.Elem(), seen)
+		nullable = nullable || t.Kind() == reflect.Slice
+	case reflect.Map:
+		schema.Type = schemaTypes{"object"}
+		nullable = true
+	case reflect.Struct:
+		schema.Type = schemaTypes{"object"}
+		if seen[t] {
+			break
+		}
+		seen[t] = true
+		defer delete(seen, t)
+		schema.Properties = make(map[string]*Schema)
+		for _, field := range reflect.VisibleFields(t) {
+			if !field.IsExported() || field.Anonymous {
+				continue
+			}
+			name, required := fieldName(field)
+			if name == "" {
+				continue
+			}
+			property := schemaForType(field.Type, seen)
+			if required {
+				// the zero values of required fields count as missing
+				property.Type = slices.DeleteFunc(property.Type, func(t string) bool { return t == "null" })
+				if slices.Contains(property.Type, "string") && property.MinLength == nil {
+					minLength := 1
+					property.MinLength = &minLength
+				}
+				schema.Required = append(schema.Required, name)
+			}
+			schema.Properties[name] = property
+			schema.order = append(schema.order, name)
+		}
+	default:
+		return schema
+	}
+	if nullable && len(schema.Type) > 0 {
+		schema.Type = append(schema.Type, "null")
+	}
+	return schema
+}
+
+// ValidationIssue is a single violation of the schema.
+type ValidationIssue struct {
+	Path    string // The path of the value, e.g. customer.address.city or items[2].price
+	Message string
+}
+
+// ValidationError is returned if the data does not match the schema of the template.
+type ValidationError struct {
+	Issues []ValidationIssue
+}
+
+// Error implements the error interface.
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Issues))
+	for i, issue := range e.Issues {
+		messages[i] = issue.Path + ": " + issue.Message
+	}
+	return "invalid template data: " + strings.Join(messages, "; ")
+}
+
+// Validate checks the data against the schema and returns a *ValidationError listing all violations.
+func (s *Schema) Validate(data interface{}) error {
+	if err := s.compile(""); err != nil {
+		return err
+	}
+	var issues []ValidationIssue
+	s.validate("", data, &issues)
+	if len(issues) > 0 {
+		return &ValidationError{Issues: issues}
+	}
+	return nil
+}
+
+// validate appends the violations of the value at the path to the issues.
+func (s *Schema) validate(path string, data interface{}, issues *[]ValidationIssue) {
+	report := func(format string, args ...interface{}) {
+		*issues = append(*issues, ValidationIssue{Path: schemaPath(path), Message: fmt.Sprintf(format, args...)})
+	}
+
+	value := reflect.ValueOf(data)
+	for value.Kind() == reflect.Pointer || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			value = reflect.Value{}
+			break
+		}
+		value = value.Elem()
+	}
+	kind := schemaKind(value)
+	if len(s.Type) > 0 && !slices.Contains(s.Type, kind) && !(kind == "integer" && slices.Contains(s.Type, "number")) {
+		report("expected %s, got %s", strings.Join(s.Type, " or "), kind)
+		return
+	}
+
+	if len(s.Enum) > 0 && !schemaEnumContains(s.Enum, value) {
+		report("must be one of %v", s.Enum)
+	}
+
+	switch kind {
+	case "string":
+		text := value.String()
+		if s.MinLength != nil && utf8.RuneCountInString(text) < *s.MinLength {
+			report("must be at least %d characters long", *s.MinLength)
+		}
+		if s.MaxLength != nil && utf8.RuneCountInString(text) > *s.MaxLength {
+			report("must be at most %d characters long", *s.MaxLength)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(text) {
+			report("must match %s", s.Pattern)
+		}
+	case "integer", "number":
+		number := schemaNumber(value)
+		if s.Minimum != nil && number < *s.Minimum {
+			report("must be at least %v", *s.Minimum)
+		}
+		if s.Maximum != nil && number > *s.Maximum {
+			report("must be at most %v", *s.Maximum)
+		}
+	case "array":
+		if s.MinItems != nil && value.Len() < *s.MinItems {
+			report("must have at least %d items", *s.MinItems)
+		}
+		if s.MaxItems != nil && value.Len() > *s.MaxItems {
+			report("must have at most %d items", *s.MaxItems)
+		}
+		if s.Items != nil {
+			for i := 0; i < value.Len(); i++ {
+				s.Items.validate(path+"["+strconv.Itoa(i)+"]", value.Index(i).Interface(), issues)
+			}
+		}
+	case "object":
+		for _, name := range s.Required {
+			if _, exists := templating.Lookup(value.Interface(), name); !exists {
+				*issues = append(*issues, ValidationIssue{Path: schemaPath(joinPath(path, name)), Message: "is required"})
+			}
+		}
+		names := make([]string, 0, len(s.Properties))
+		for name := range s.Properties {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if property, exists := templating.Lookup(value.Interface(), name); exists {
+				s.Properties[name].validate(joinPath(path, name), property, issues)
+			}
+		}
+	}
+}
+
+// schemaKind returns the JSON Schema type of the value.
+func schemaKind(value reflect.Value) string {
+	switch value.Kind() {
+	case reflect.Invalid:
+		return "null"
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		if f := value.Float(); f == float64(int64(f)) {
+			return "integer"
+		}
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	}
+	return value.Kind().String()
+}
+
+// schemaNumber returns the value of a number as float64.
+func schemaNumber(value reflect.Value) float64 {
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(value.Uint())
+	}
+	return value.Float()
+}
+
+// schemaEnumContains reports whether the value equals one of the allowed values. Numbers are compared by value.
+func schemaEnumContains(enum []interface{}, value reflect.Value) bool {
+	for _, allowed := range enum {
+		a := reflect.ValueOf(allowed)
+		kind := schemaKind(value)
+		if (kind == "integer" || kind == "number") && a.CanFloat() {
+			if schemaNumber(value) == a.Float() {
+				return true
+			}
+			continue
+		}
+		if value.IsValid() && reflect.DeepEqual(value.Interface(), allowed) || !value.IsValid() && allowed == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// joinPath appends the property name to the path.
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// schemaPath returns the path for messages, the data itself is shown as "(root)".
+func schemaPath(path string) string {
+	if path == "" {
+		return "(root)"
+	}
+	return path
+}
+
+// SetSchema sets the schema of the template data. ExecuteTemplate validates the data before the document is
+// changed and returns a *ValidationError if the data does not match.
+func (tr *TemplateReplacer) SetSchema(schema *Schema) {
+	tr.schema = schema
+}
+
+// ValidateData checks the data against the schema set by SetSchema. It returns a *ValidationError listing the
+// paths of all missing and invalid values, or nil if no schema is set.
+func (tr *TemplateReplacer) ValidateData(data TemplateData) error {
+	if tr.schema == nil {
+		return nil
+	}
+	return tr.schema.Validate(data)
+}
+
+// SetSchema sets the schema of the template data, see ValidateData.
+func (d *Document) SetSchema(schema *Schema) {
+	d.templateReplacer.SetSchema(schema)
+}
+
+// ValidateData checks the data against the schema set by SetSchema before rendering, e.g. to answer requests
+// with invalid data with a 400 error:
+//
+//	if err := doc.ValidateData(data); err != nil {
+//		var invalid *docx.ValidationError
+//		if errors.As(err, &invalid) {
+//			for _, issue := range invalid.Issues {
+//				// issue.Path, issue.Message
+//			}
+//		}
+//	}
+func (d *Document) ValidateData(data TemplateData) error {
+	return d.templateReplacer.ValidateData(data)
+}
@@ -0,0 +1,118 @@
+package docx
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSchema_Validate(t *testing.T) {
+	schema, err := ParseSchema([]byte(`{
+		"type": "object",
+		"required": ["customer", "items"],
+		"properties": {
+			"customer": {
+				"type": "object",
+				"required": ["name"],
+				"properties": {
+					"name": {"type": "string", "minLength": 1},
+					"email": {"type": ["string", "null"], "pattern": "^[^@]+@[^@]+$"}
+				}
+			},
+			"status": {"enum": ["draft", "final"]},
+			"items": {
+				"type": "array",
+				"minItems": 1,
+				"items": {"type": "object", "properties": {"price": {"type": "number", "minimum": 0}, "quantity": {"type": "integer"}}}
+			}
+		}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	valid := map[string]interface{}{
+		"customer": map[string]interface{}{"name": "ACME", "email": nil},
+		"status":   "final",
+		"items":    []map[string]interface{}{{"price": 9.5, "quantity": 2}},
+	}
+	if err := schema.Validate(valid); err != nil {
+		t.Errorf("unexpected error %s", err)
+	}
+
+	invalid := map[string]interface{}{
+		"customer": map[string]interface{}{"email": "acme.com"},
+		"status":   "sent",
+		"items":    []map[string]interface{}{{"price": -1, "quantity": 1.5}},
+	}
+	err = schema.Validate(invalid)
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("expected a ValidationError, got %v", err)
+	}
+	expected := []ValidationIssue{
+		{"customer.name", "is required"},
+		{"customer.email", "must match ^[^@]+@[^@]+$"},
+		{"items[0].price", "must be at least 0"},
+		{"items[0].quantity", "expected integer, got number"},
+		{"status", "must be one of [draft final]"},
+	}
+	if !reflect.DeepEqual(validationErr.Issues, expected) {
+		t.Errorf("expected %v, got %v", expected, validationErr.Issues)
+	}
+
+	if _, err := ParseSchema([]byte(`{"pattern": "("}`)); err == nil {
+		t.Errorf("expected an error for an invalid pattern")
+	}
+}
+
+func TestSchemaFor(t *testing.T) {
+	type Item struct {
+		Name  string `docx:",required"`
+		Price float64
+	}
+	type Offer struct {
+		Customer string `docx:",required"`
+		Items    []Item
+		Discount *int
+	}
+	schema := SchemaFor(Offer{})
+
+	if err := schema.Validate(Offer{Customer: "ACME", Items: []Item{{Name: "Pen"}}}); err != nil {
+		t.Errorf("unexpected error %s", err)
+	}
+	err := schema.Validate(map[string]interface{}{"Items": []interface{}{map[string]interface{}{"Price": "free"}}, "Discount": nil})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	for _, expected := range []string{"Customer: is required", "Items[0].Name: is required", "Items[0].Price: expected number, got string"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected %q in %q", expected, err)
+		}
+	}
+}
+
+func TestDocument_ValidateData(t *testing.T) {
+	doc, err := Open("./test/template.docx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+
+	schema, err := ParseSchema([]byte(`{"type": "object", "required": ["name"]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc.SetSchema(schema)
+	before := string(doc.GetFile(DocumentXml))
+	if err := doc.ExecuteTemplate(map[string]interface{}{"company": "ACME"}); err == nil || !strings.Contains(err.Error(), "name: is required") {
+		t.Errorf("expected a validation error, got %v", err)
+	}
+	if string(doc.GetFile(DocumentXml)) != before {
+		t.Errorf("the document must not be changed by invalid data")
+	}
+	if err := doc.ValidateData(map[string]interface{}{"name": "John"}); err != nil {
+		t.Errorf("unexpected error %s", err)
+	}
+}
@@ -0,0 +1,261 @@
+package docx
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// values.go marks the values inserted by the template actions with hidden bookmarks, so they can be found and
+// updated later without rendering the document again. Like right-to-left values (see bidi.go), the values are
+// wrapped into markers when they are inserted and the markers are resolved after all actions are executed.
+
+const (
+	// maxBookmarkName is the maximum length of bookmark names accepted by Word.
+	maxBookmarkName = 40
+)
+
+var (
+	// valueMarkerRegex matches a value wrapped by markValue. The groups are the name of its bookmark, the index of
+	// its action in valueActions, if it can be rendered again, and the value.
+	valueMarkerRegex = regexp.MustCompile(`(?s)\[\[docx-value ([A-Za-z0-9_]+)(?:#([0-9]+))?\]\](.*?)\[\[docx-value-end\]\]`)
+	// bookmarkStartRegex matches the start of a bookmark.
+	bookmarkStartRegex = regexp.MustCompile(`<w:bookmarkStart\b[^>]*>`)
+	// bookmarkNameCharsRegex matches the characters which are not allowed in bookmark names.
+	bookmarkNameCharsRegex = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+)
+
+// SetBookmarkValues enables or disables marking the values inserted by the template actions with bookmarks.
+func (tr *TemplateReplacer) SetBookmarkValues(enabled bool) {
+	tr.bookmarkValues = enabled
+}
+
+// SetBookmarkValues enables or disables marking the values inserted by ExecuteTemplate with hidden bookmarks, so
+// downstream tools can find them and UpdateRenderedValue can change them later. The bookmark of a value is named
+// after its field with a leading underscore, which hides it in Word: {{.invoice_total}} becomes _invoice_total,
+// {{.customer.name}} _customer_name. Further values of the same field get a number, e.g. _invoice_total_2.
+// Values of other pipelines are named after their first field, values without field _value.
+func (d *Document) SetBookmarkValues(enabled bool) {
+	d.templateReplacer.SetBookmarkValues(enabled)
+}
+
+// markValue wraps the escaped value into markers if values are marked with bookmarks, name is the name of its
+// bookmark without the leading underscore. The action is remembered for Rerender unless it is empty, which
+// marks values that depend on their scope. XML written by helpers such as {{image}} and values in SmartArt
+// diagrams, which cannot hold bookmarks, are not marked.
+func (tr *TemplateReplacer) markValue(name, action, value string) string {
+	if !tr.bookmarkValues || strings.Contains(value, "<") ||
+		DiagramDataPathRegex.MatchString(tr.part) || DiagramDrawingPathRegex.MatchString(tr.part) {
+		return value
+	}
+	if action != "" {
+		name += "#" + strconv.Itoa(len(tr.valueActions))
+		tr.valueActions = append(tr.valueActions, action)
+	}
+	return "[[docx-value " + name + "]]" + value + "[[docx-value-end]]"
+}
+
+// valueName returns the name of the bookmark of the action without the leading underscore, see SetBookmarkValues.
+func valueName(action string) string {
+	return bookmarkName(strings.Join(actionField(action), "_"))
+}
+
+// actionField returns the names of the first field referenced by the action, e.g. [customer name] for
+// {{.customer.name | upper}}, or nil if the action references no field.
+func actionField(action string) []string {
+	content := strings.TrimSpace(withoutTrimMarkers(strings.TrimSuffix(strings.TrimPrefix(action, "{{"), "}}")))
+	for _, command := range strings.Split(content, "|") {
+		for _, arg := range strings.Fields(command) {
+			if path := fieldPath(arg); len(path) > 0 {
+				return path
+			}
+		}
+	}
+	return nil
+}
+
+// bookmarkName replaces the characters which are not allowed in bookmark names, "value" is used if none is left.
+func bookmarkName(name string) string {
+	name = strings.Trim(bookmarkNameCharsRegex.ReplaceAllString(name, "_"), "_")
+	if name == "" {
+		name = "value"
+	}
+	return name
+}
+
+// resolveValueMarkers replaces the markers written by markValue in all XML parts with bookmarks and remembers
+// the actions of the bookmarks for Rerender.
+func (tr *TemplateReplacer) resolveValueMarkers() error {
+	defer func() { tr.valueActions = nil }()
+	if tr.renderedValues == nil {
+		tr.renderedValues = make(map[string]string)
+	}
+	used, nextID := tr.document.bookmarks()
+	for _, fileName := range tr.document.xmlParts() {
+		data := tr.document.GetFile(fileName)
+		if !valueMarkerRegex.Match(data) {
+			continue
+		}
+		if err := tr.document.SetFile(fileName, resolveValueMarkers(data, used, &nextID, tr.valueActions, tr.renderedValues)); err != nil {
+			return err
+		}
+		if err := tr.document.refreshRuns(fileName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bookmarks returns the names of the bookmarks of the document and an id which is not used by any of them yet.
+func (d *Document) bookmarks() (map[string]bool, int) {
+	names := make(map[string]bool)
+	nextID := 0
+	for _, fileName := range d.xmlParts() {
+		for _, tag := range bookmarkStartRegex.FindAll(d.GetFile(fileName), -1) {
+			if name, exists := getTagAttr(tag, "w:name"); exists {
+				names[name] = true
+			}
+			if id, err := strconv.Atoi(attrOf(tag, "w:id")); err == nil && id >= nextID {
+				nextID = id + 1
+			}
+		}
+	}
+	return names, nextID
+}
+
+// attrOf returns the value of the attribute of the tag, empty if it is missing.
+func attrOf(tag []byte, name string) string {
+	value, _ := getTagAttr(tag, name)
+	return value
+}
+
+// resolveValueMarkers puts each marked value into a run of its own, which is enclosed by a bookmark. The run
+// keeps the formatting of the placeholder. Values outside of runs are unmarked. The actions of the bookmarks
+// are added to rendered.
+func resolveValueMarkers(data []byte, used map[string]bool, nextID *int, actions []string, rendered map[string]string) []byte {
+	for offset := 0; ; {
+		loc := valueMarkerRegex.FindSubmatchIndex(data[offset:])
+		if loc == nil {
+			return data
+		}
+		for i := range loc {
+			loc[i] += offset
+		}
+		value := data[loc[6]:loc[7]]
+
+		run := runStart(data, loc[0])
+		text := textStart(data, loc[0])
+		if run < 0 || text < run || bytes.Contains(data[text:loc[0]], []byte("</w:t>")) {
+			data = applyReplacements(data, []replacement{{int64(loc[0]), int64(loc[1]), value}})
+			offset = loc[0] + len(value)
+			continue
+		}
+
+		name := uniqueBookmarkName(string(data[loc[2]:loc[3]]), used)
+		if loc[4] >= 0 {
+			if index, err := strconv.Atoi(string(data[loc[4]:loc[5]])); err == nil && index < len(actions) {
+				rendered[name] = actions[index]
+			}
+		}
+		id := strconv.Itoa(*nextID)
+		*nextID++
+
+		// the text element is split, the value gets a run of its own between the parts of the original run
+		properties := runPropertiesRegex.Find(data[run:text])
+		textEnd := text + bytes.IndexByte(data[text:], '>') + 1
+		textTag := setTagAttr(data[text:textEnd], "xml:space", "preserve")
+
+		var split []byte
+		split = append(split, textTag...)
+		split = append(split, data[textEnd:loc[0]]...)
+		split = append(split, "</w:t></w:r>"...)
+		split = append(split, `<w:bookmarkStart w:id="`+id+`" w:name="`+name+`"/><w:r>`...)
+		split = append(split, properties...)
+		split = append(split, `<w:t xml:space="preserve">`...)
+		split = append(split, value...)
+		split = append(split, `</w:t></w:r><w:bookmarkEnd w:id="`+id+`"/>`...)
+		offset = text + len(split)
+		split = append(split, "<w:r>"...)
+		split = append(split, properties...)
+		split = append(split, textTag...)
+		data = applyReplacements(data, []replacement{{int64(text), int64(loc[1]), split}})
+	}
+}
+
+// uniqueBookmarkName returns the hidden bookmark name for the value name which is not used yet and marks it used.
+func uniqueBookmarkName(name string, used map[string]bool) string {
+	base := "_" + name
+	if len(base) > maxBookmarkName {
+		base = base[:maxBookmarkName]
+	}
+	candidate := base
+	for n := 2; used[candidate]; n++ {
+		suffix := "_" + strconv.Itoa(n)
+		candidate = base[:min(len(base), maxBookmarkName-len(suffix))] + suffix
+	}
+	used[candidate] = true
+	return candidate
+}
+
+// UpdateRenderedValue sets the text of the values which were marked with bookmarks when the document was rendered,
+// see SetBookmarkValues: all bookmarks of the name are updated, e.g. "invoice_total" updates _invoice_total,
+// _invoice_total_2 and so on. The text keeps the formatting of the first run of the value. An error is returned if
+// there is no such value.
+func (d *Document) UpdateRenderedValue(name, value string) error {
+	pattern := regexp.MustCompile(`^_` + regexp.QuoteMeta(name) + `(?:_[0-9]+)?$`)
+	updated, err := d.updateBookmarks(func(_, bookmark string) (string, bool, error) {
+		return value, pattern.MatchString(bookmark), nil
+	})
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return fmt.Errorf("rendered value %s not found", name)
+	}
+	return nil
+}
+
+// updateBookmarks replaces the content of the bookmarks for which the function returns a text and true with a
+// run of the text. The run keeps the formatting of the first run of the bookmark. The function is called with
+// the part and the name of each bookmark, the number of updated bookmarks is returned.
+func (d *Document) updateBookmarks(text func(part, bookmark string) (string, bool, error)) (int, error) {
+	updated := 0
+	for _, fileName := range d.xmlParts() {
+		data := d.GetFile(fileName)
+		var replacements []replacement
+		for _, loc := range bookmarkStartRegex.FindAllIndex(data, -1) {
+			tag := data[loc[0]:loc[1]]
+			name := attrOf(tag, "w:name")
+			value, update, err := text(fileName, name)
+			if err != nil {
+				return updated, err
+			}
+			if !update {
+				continue
+			}
+			end := bytes.Index(data[loc[1]:], []byte(`<w:bookmarkEnd w:id="`+attrOf(tag, "w:id")+`"/>`))
+			if end < 0 {
+				return updated, fmt.Errorf("the bookmark %s in %s has no end", name, fileName)
+			}
+			content := data[loc[1] : loc[1]+end]
+			if bytes.Contains(content, []byte("</w:p>")) {
+				return updated, fmt.Errorf("the bookmark %s in %s spans multiple paragraphs", name, fileName)
+			}
+			run := []byte(`<w:r>` + string(runPropertiesRegex.Find(content)) + `<w:t xml:space="preserve">` + xmlEscape(value) + `</w:t></w:r>`)
+			replacements = append(replacements, replacement{int64(loc[1]), int64(loc[1] + end), run})
+		}
+		if len(replacements) == 0 {
+			continue
+		}
+		updated += len(replacements)
+		if err := d.SetFile(fileName, applyReplacements(data, replacements)); err != nil {
+			return updated, err
+		}
+		if err := d.refreshRuns(fileName); err != nil {
+			return updated, err
+		}
+	}
+	return updated, nil
+}
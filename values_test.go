@@ -0,0 +1,61 @@
+package docx
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDocument_UpdateRenderedValue(t *testing.T) {
+	body := `<w:body><w:bookmarkStart w:id="7" w:name="Intro"/><w:bookmarkEnd w:id="7"/>` +
+		`<w:p><w:r><w:rPr><w:b/></w:rPr><w:t>Total: {{.invoice_total}} CHF</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>{{range .lines}}</w:t></w:r></w:p><w:p><w:r><w:t>{{.item}}: {{.amount | printf "%.2f"}}</w:t></w:r></w:p><w:p><w:r><w:t>{{end}}</w:t></w:r></w:p>` +
+		`<w:p><w:r><w:t>Due: {{.invoice_total}}</w:t></w:r></w:p>`
+	doc, err := OpenBytes(rewriteArchive(t, readFile(t, "./test/template.docx"), func(name string, data []byte) []byte {
+		if name == DocumentXml {
+			return []byte(strings.Replace(string(data), "<w:body>", body, 1))
+		}
+		return data
+	}, nil), WithBookmarkValues())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+
+	data := map[string]interface{}{
+		"invoice_total": "330.00",
+		"lines":         []map[string]interface{}{{"item": "Desk", "amount": 250.0}, {"item": "Chair", "amount": 80.0}},
+	}
+	if err := doc.ExecuteTemplate(data); err != nil {
+		t.Fatal(err)
+	}
+	documentXml := string(doc.GetFile(DocumentXml))
+	for _, expected := range []string{
+		`<w:t xml:space="preserve">Total: </w:t></w:r><w:bookmarkStart w:id="8" w:name="_invoice_total"/>` +
+			`<w:r><w:rPr><w:b/></w:rPr><w:t xml:space="preserve">330.00</w:t></w:r><w:bookmarkEnd w:id="8"/>` +
+			`<w:r><w:rPr><w:b/></w:rPr><w:t xml:space="preserve"> CHF</w:t>`,
+		`w:name="_item"/><w:r><w:t xml:space="preserve">Desk</w:t></w:r>`,
+		`w:name="_amount_2"/><w:r><w:t xml:space="preserve">80.00</w:t></w:r>`,
+		`w:name="_invoice_total_2"/><w:r><w:t xml:space="preserve">330.00</w:t></w:r>`,
+	} {
+		if !strings.Contains(documentXml, expected) {
+			t.Errorf("expected %s in %s", expected, documentXml[:strings.Index(documentXml, "<w:sectPr")])
+		}
+	}
+	if strings.Contains(documentXml, "[[docx-value") {
+		t.Errorf("the markers were not resolved")
+	}
+
+	if err := doc.UpdateRenderedValue("invoice_total", "<360.00>"); err != nil {
+		t.Fatal(err)
+	}
+	texts, err := paragraphTexts(doc.GetFile(DocumentXml))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := strings.Join(texts, "|"); !strings.Contains(text, "Total: <360.00> CHF|") || !strings.Contains(text, "|Due: <360.00>") {
+		t.Errorf("expected the totals to be updated, got %q", text)
+	}
+	if err := doc.UpdateRenderedValue("customer", "Anna"); err == nil {
+		t.Errorf("expected an error for a value which was not rendered")
+	}
+}
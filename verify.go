@@ -0,0 +1,84 @@
+package docx
+
+import "fmt"
+
+// Deviation is a paragraph whose fixed text differs between a template and a document, see MatchesTemplate.
+// Paragraphs are numbered in document order, starting at 0. Paragraphs inside of tables are included.
+type Deviation struct {
+	// Type is ChangeRemoved for paragraphs of the template which are missing in the document, ChangeAdded for
+	// paragraphs of the document which the template doesn't have and ChangeModified for paragraphs whose fixed
+	// text was changed.
+	Type     ChangeType
+	Index    int    // The index of the paragraph of the document, -1 for removed paragraphs
+	Template string // The paragraph of the template including its placeholders, empty for added paragraphs
+	Text     string // The paragraph of the document, empty for removed paragraphs
+}
+
+// String returns a human-readable representation of the deviation.
+func (d Deviation) String() string {
+	switch d.Type {
+	case ChangeAdded:
+		return fmt.Sprintf("+ [%d] %s", d.Index, d.Text)
+	case ChangeRemoved:
+		return fmt.Sprintf("- %s", d.Template)
+	default:
+		return fmt.Sprintf("~ [%d] %s → %s", d.Index, d.Template, d.Text)
+	}
+}
+
+// MatchesTemplate verifies that the document was generated from the template and its fixed text was not edited
+// afterwards, e.g. to detect unauthorized changes to generated contracts. The paragraphs of the body are matched
+// like by Extract: placeholders match any text, blocks of paragraphs may be repeated or left out. Every paragraph
+// of the template which is missing or changed and every paragraph which was added is reported as deviation. A
+// document or template whose body cannot be read does not match.
+func MatchesTemplate(tpl *Template, doc *Document) (bool, []Deviation) {
+	template, err := OpenBytes(tpl.data)
+	if err != nil {
+		return false, nil
+	}
+	defer template.Close()
+	templateBlocks, err := parseBody(template.GetFile(DocumentXml))
+	if err != nil {
+		return false, nil
+	}
+	paragraphs, err := documentParagraphs(doc)
+	if err != nil {
+		return false, nil
+	}
+
+	parser := &extractParser{}
+	parser.blocks(templateBlocks)
+	matcher := newExtractMatcher(paragraphs)
+	matcher.match(parser.root, 0, make(map[string]interface{}), nil)
+	matcher.skip(len(paragraphs))
+
+	var deviations []Deviation
+	var removed []*extractNode
+	var added []int
+	// flush turns the paragraphs between two matched paragraphs into deviations, like Diff
+	flush := func() {
+		for i := 0; i < len(removed) || i < len(added); i++ {
+			switch {
+			case i < len(removed) && i < len(added):
+				deviations = append(deviations, Deviation{Type: ChangeModified, Index: added[i], Template: removed[i].text, Text: matcher.paragraphs[added[i]]})
+			case i < len(removed):
+				deviations = append(deviations, Deviation{Type: ChangeRemoved, Index: -1, Template: removed[i].text})
+			default:
+				deviations = append(deviations, Deviation{Type: ChangeAdded, Index: added[i], Text: matcher.paragraphs[added[i]]})
+			}
+		}
+		removed, added = nil, nil
+	}
+	for _, event := range matcher.events {
+		switch {
+		case event.matched:
+			flush()
+		case event.node != nil:
+			removed = append(removed, event.node)
+		default:
+			added = append(added, event.index)
+		}
+	}
+	flush()
+	return len(deviations) == 0, deviations
+}
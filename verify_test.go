@@ -0,0 +1,91 @@
+package docx
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMatchesTemplate(t *testing.T) {
+	template, err := NewDocument().
+		Heading(1, "Service Agreement").
+		Paragraph("This agreement is made between {{.provider}} and {{.customer}}.").
+		Paragraph("{{range .services}}").
+		Paragraph("Service: {{.}}").
+		Paragraph("{{end}}").
+		Paragraph("The liability of the provider is limited to the fees paid.").
+		Paragraph("Signed in {{.city}}").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := template.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	tpl, err := NewTemplate(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	render := func(edit func(documentXml string) string) *Document {
+		t.Helper()
+		doc, err := Render(tpl, map[string]interface{}{"provider": "ACME", "customer": "Anna", "services": []string{"Hosting", "Support"}, "city": "Zurich"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if edit != nil {
+			if err := doc.SetFile(DocumentXml, []byte(edit(string(doc.GetFile(DocumentXml))))); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return doc
+	}
+
+	if matches, deviations := MatchesTemplate(tpl, render(nil)); !matches || len(deviations) > 0 {
+		t.Errorf("expected the rendered document to match, got %v", deviations)
+	}
+
+	tests := []struct {
+		name     string
+		edit     func(documentXml string) string
+		expected []Deviation
+	}{
+		{
+			"modified fixed text",
+			func(documentXml string) string {
+				return strings.Replace(documentXml, "limited to the fees paid", "unlimited", 1)
+			},
+			[]Deviation{{Type: ChangeModified, Index: 4, Template: "The liability of the provider is limited to the fees paid.", Text: "The liability of the provider is unlimited."}},
+		},
+		{
+			"removed paragraph",
+			func(documentXml string) string {
+				start := strings.Index(documentXml, "<w:p><w:r><w:t xml:space=\"preserve\">The liability")
+				end := start + strings.Index(documentXml[start:], "</w:p>") + len("</w:p>")
+				return documentXml[:start] + documentXml[end:]
+			},
+			[]Deviation{{Type: ChangeRemoved, Index: -1, Template: "The liability of the provider is limited to the fees paid."}},
+		},
+		{
+			"added paragraph",
+			func(documentXml string) string {
+				return strings.Replace(documentXml, "<w:sectPr>", `<w:p><w:r><w:t>Side letter</w:t></w:r></w:p><w:sectPr>`, 1)
+			},
+			[]Deviation{{Type: ChangeAdded, Index: 6, Text: "Side letter"}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			matches, deviations := MatchesTemplate(tpl, render(test.edit))
+			if matches || !reflect.DeepEqual(deviations, test.expected) {
+				t.Errorf("expected the deviations %v, got %v", test.expected, deviations)
+			}
+		})
+	}
+
+	if s := (Deviation{Type: ChangeModified, Index: 4, Template: "a {{.b}}", Text: "c"}).String(); s != "~ [4] a {{.b}} → c" {
+		t.Errorf("unexpected string %s", s)
+	}
+}
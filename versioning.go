@@ -0,0 +1,145 @@
+package docx
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// versioning.go implements the versions declared by templates, so rendering fails or warns before a template is
+// filled with data it was not written for.
+
+const (
+	// TemplateVersionProperty is the custom document property declaring the version of a template, e.g. 2.1.
+	TemplateVersionProperty = "TemplateVersion"
+	// RequiredEngineVersionProperty is the custom document property declaring the minimum version of this package
+	// a template needs, e.g. v1.8.0.
+	RequiredEngineVersionProperty = "RequiredEngineVersion"
+)
+
+// ErrVersionMismatch is returned by Render with VersionError if the template is incompatible with the data or
+// with this package.
+var ErrVersionMismatch = errors.New("version mismatch")
+
+// VersionPolicy controls how Render handles templates which are incompatible with the data or this package.
+type VersionPolicy int
+
+const (
+	// VersionWarn logs the mismatch to the logger of the document (see WithLogger) and renders the template.
+	// Without a logger the mismatch is not reported. This is the default.
+	VersionWarn VersionPolicy = iota
+	// VersionError stops rendering with an error wrapping ErrVersionMismatch.
+	VersionError
+	// VersionIgnore renders the template without checking the versions.
+	VersionIgnore
+)
+
+// SchemaVersioner is implemented by data types which declare the version of their schema, see SchemaFor.
+type SchemaVersioner interface {
+	SchemaVersion() string
+}
+
+// TemplateVersions are the versions declared by a template in its custom document properties, which authors set
+// in Word under File > Info > Properties > Advanced Properties > Custom.
+type TemplateVersions struct {
+	Template       string // The TemplateVersion property
+	RequiredEngine string // The RequiredEngineVersion property
+}
+
+// WithVersionPolicy sets the handling of version mismatches when the document is rendered, see SetVersionPolicy.
+func WithVersionPolicy(policy VersionPolicy) Option {
+	return func(d *Document) {
+		d.SetVersionPolicy(policy)
+	}
+}
+
+// SetVersionPolicy sets the handling of templates whose major version differs from the version of the data
+// schema, or which require a newer version of this package, when the template is rendered by Render.
+func (d *Document) SetVersionPolicy(policy VersionPolicy) {
+	d.versionPolicy = policy
+}
+
+// TemplateVersions returns the versions declared by the template, empty if it declares none.
+func (d *Document) TemplateVersions() TemplateVersions {
+	var versions TemplateVersions
+	if data, exists := d.loadPackageFile(d.customPropertiesPart()); exists {
+		properties := customProperties(data)
+		versions.Template = properties[TemplateVersionProperty]
+		versions.RequiredEngine = properties[RequiredEngineVersionProperty]
+	}
+	return versions
+}
+
+// checkVersions checks the versions declared by the template against the version of the data schema and the
+// version of this package. The data schema and the template are compatible if their major versions are equal.
+// Unknown versions are not checked.
+func (d *Document) checkVersions(schemaVersion string) error {
+	if d.versionPolicy == VersionIgnore {
+		return nil
+	}
+	versions := d.TemplateVersions()
+	var mismatches []string
+	if versions.Template != "" && schemaVersion != "" && majorVersion(versions.Template) != majorVersion(schemaVersion) {
+		mismatches = append(mismatches, fmt.Sprintf("the template version %s is incompatible with the data schema version %s", versions.Template, schemaVersion))
+	}
+	if engine := rendererVersion(); versions.RequiredEngine != "" && engine != "(devel)" && compareVersions(engine, versions.RequiredEngine) < 0 {
+		mismatches = append(mismatches, fmt.Sprintf("the template requires version %s of the engine, this is version %s", versions.RequiredEngine, engine))
+	}
+	mismatch := strings.Join(mismatches, "; ")
+	switch {
+	case mismatch == "":
+		return nil
+	case d.versionPolicy == VersionError:
+		return fmt.Errorf("%w: %s", ErrVersionMismatch, mismatch)
+	case d.templateReplacer.logger != nil:
+		d.templateReplacer.logger.Printf("[WARN] %s", mismatch)
+	}
+	return nil
+}
+
+// versionNumbers returns the numbers of a version like v1.8.0 or 2.1, a pre-release or build suffix is ignored.
+func versionNumbers(version string) []int {
+	version = strings.TrimPrefix(strings.TrimSpace(version), "v")
+	if end := strings.IndexAny(version, "-+ "); end >= 0 {
+		version = version[:end]
+	}
+	var numbers []int
+	for _, part := range strings.Split(version, ".") {
+		number, err := strconv.Atoi(part)
+		if err != nil {
+			break
+		}
+		numbers = append(numbers, number)
+	}
+	return numbers
+}
+
+// majorVersion returns the major version, the version itself if it is not numeric.
+func majorVersion(version string) string {
+	if numbers := versionNumbers(version); len(numbers) > 0 {
+		return strconv.Itoa(numbers[0])
+	}
+	return strings.TrimSpace(version)
+}
+
+// compareVersions compares the numeric versions, missing numbers count as 0.
+func compareVersions(a, b string) int {
+	x, y := versionNumbers(a), versionNumbers(b)
+	for i := 0; i < len(x) || i < len(y); i++ {
+		var m, n int
+		if i < len(x) {
+			m = x[i]
+		}
+		if i < len(y) {
+			n = y[i]
+		}
+		if m != n {
+			if m < n {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
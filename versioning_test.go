@@ -0,0 +1,116 @@
+package docx
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"testing"
+)
+
+type offerV2 struct {
+	Name string `docx:"name"`
+}
+
+func (offerV2) SchemaVersion() string { return "2.4" }
+
+type offerV3 struct {
+	Name string `docx:"name"`
+}
+
+func (offerV3) SchemaVersion() string { return "v3.0.0" }
+
+// recordingLogger keeps the messages it receives.
+type recordingLogger struct {
+	messages []string
+}
+
+func (l *recordingLogger) Printf(format string, args ...interface{}) {
+	l.messages = append(l.messages, fmt.Sprintf(format, args...))
+}
+
+func TestTemplate_Versions(t *testing.T) {
+	custom := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` +
+		`<Properties xmlns="http://schemas.openxmlformats.org/officeDocument/2006/custom-properties" xmlns:vt="http://schemas.openxmlformats.org/officeDocument/2006/docPropsVTypes">` +
+		`<property fmtid="{D5CDD505-2E9C-101B-9397-08002B2CF9AE}" pid="2" name="TemplateVersion"><vt:lpwstr>2.1</vt:lpwstr></property>` +
+		`<property fmtid="{D5CDD505-2E9C-101B-9397-08002B2CF9AE}" pid="3" name="RequiredEngineVersion"><vt:lpwstr>v1.8.0</vt:lpwstr></property></Properties>`
+	data := rewriteArchive(t, readFile(t, "./test/template.docx"), func(name string, data []byte) []byte { return data },
+		map[string][]byte{"docProps/custom.xml": []byte(custom)})
+
+	doc, err := OpenBytes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if versions := doc.TemplateVersions(); versions != (TemplateVersions{Template: "2.1", RequiredEngine: "v1.8.0"}) {
+		t.Errorf("unexpected versions %+v", versions)
+	}
+	doc.Close()
+
+	strict, err := NewTemplate(data, WithVersionPolicy(VersionError))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if doc, err := Render(strict, offerV2{Name: "ACME"}); err != nil {
+		t.Errorf("the minor versions may differ, got %v", err)
+	} else {
+		doc.Close()
+	}
+	if _, err := Render(strict, offerV3{Name: "ACME"}); !errors.Is(err, ErrVersionMismatch) ||
+		!strings.Contains(err.Error(), "the template version 2.1 is incompatible with the data schema version v3.0.0") {
+		t.Errorf("expected a version mismatch, got %v", err)
+	}
+
+	logger := &recordingLogger{}
+	lenient, err := NewTemplate(data, WithLogger(logger))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err = Render(lenient, offerV3{Name: "ACME"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc.Close()
+	warned := false
+	for _, message := range logger.messages {
+		warned = warned || strings.HasPrefix(message, "[WARN] the template version 2.1")
+	}
+	if !warned {
+		t.Errorf("expected a warning, got %v", logger.messages)
+	}
+
+	// without a logger the mismatch must not be written to the standard logger of the application
+	var output bytes.Buffer
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(&output)
+	silent, err := NewTemplate(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err = Render(silent, offerV3{Name: "ACME"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc.Close()
+	if output.Len() > 0 {
+		t.Errorf("expected no output of the standard logger, got %s", output.String())
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"v1.8.0", "v1.8.0", 0},
+		{"v1.8", "1.8.0", 0},
+		{"v1.10.0", "v1.9.2", 1},
+		{"v1.7.9-rc1", "v1.8.0", -1},
+		{"2", "10", -1},
+	}
+	for _, test := range tests {
+		if result := compareVersions(test.a, test.b); result != test.expected {
+			t.Errorf("compareVersions(%s, %s) = %d, expected %d", test.a, test.b, result, test.expected)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package docx
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Warning is a file of the archive which could not be read, e.g. because its data is corrupt. The document is
+// processed without the file, see Document.Warnings.
+type Warning struct {
+	Part string // The file of the archive, e.g. word/header1.xml
+	Err  error
+}
+
+// String returns the file with the reason it could not be read.
+func (w Warning) String() string {
+	return fmt.Sprintf("unable to read %s: %s", w.Part, w.Err)
+}
+
+// Warnings returns the files of the archive which could not be read so far. Documents are processed on a
+// best-effort basis: headers, footers and other parts which cannot be read are neither processed nor modified
+// and are copied unchanged when the document is written, images which cannot be read are left out of the image
+// functions. Set OpenOptions.FailFast to reject such documents instead. The main document part must always be
+// readable.
+func (d *Document) Warnings() []Warning {
+	return append([]Warning(nil), d.warnings...)
+}
+
+// warn records that the file of the archive could not be read. Each file is reported once.
+func (d *Document) warn(fileName string, err error) {
+	if d.unreadableFiles == nil {
+		d.unreadableFiles = make(map[string]bool)
+	}
+	if d.unreadableFiles[fileName] {
+		return
+	}
+	d.unreadableFiles[fileName] = true
+	d.warnings = append(d.warnings, Warning{Part: fileName, Err: err})
+}
+
+// warningsError returns the warnings as a single error, nil if there are none.
+func (d *Document) warningsError() error {
+	var errs []error
+	for _, warning := range d.warnings {
+		errs = append(errs, fmt.Errorf("unable to read %s: %w", warning.Part, warning.Err))
+	}
+	return errors.Join(errs...)
+}
+
+// copyRawZipFile copies the compressed data of the file to the archive without reading it, so files which
+// cannot be read are written just like they were found.
+func copyRawZipFile(zipWriter *zip.Writer, file *zip.File) error {
+	header := file.FileHeader
+	header.Extra = withoutZip64Extra(header.Extra)
+	writer, err := zipWriter.CreateRaw(&header)
+	if err != nil {
+		return fmt.Errorf("unable to create writer: %s", err)
+	}
+	reader, err := file.OpenRaw()
+	if err != nil {
+		return fmt.Errorf("unable to writeFile %s: %s", file.Name, err)
+	}
+	if _, err := io.Copy(writer, reader); err != nil {
+		return fmt.Errorf("unable to writeFile %s: %s", file.Name, err)
+	}
+	return nil
+}
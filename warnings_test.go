@@ -0,0 +1,94 @@
+package docx
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// corruptArchive returns the archive with the data of the given file damaged, so it fails its checksum.
+func corruptArchive(t *testing.T, src []byte, fileName string) []byte {
+	reader, err := zip.NewReader(bytes.NewReader(src), int64(len(src)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	corrupted := append([]byte(nil), src...)
+	for _, file := range reader.File {
+		if file.Name != fileName {
+			continue
+		}
+		offset, err := file.DataOffset()
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := offset + int64(file.CompressedSize64)/2; i < offset+int64(file.CompressedSize64)/2+8; i++ {
+			corrupted[i] ^= 0xff
+		}
+		return corrupted
+	}
+	t.Fatalf("file %s not found", fileName)
+	return nil
+}
+
+func TestDocument_Warnings(t *testing.T) {
+	corrupted := corruptArchive(t, readFile(t, "./test/template.docx"), "word/header1.xml")
+	doc, err := OpenBytes(corrupted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+
+	warnings := doc.Warnings()
+	if len(warnings) != 1 || warnings[0].Part != "word/header1.xml" {
+		t.Fatalf("unexpected warnings %v", warnings)
+	}
+	if !strings.HasPrefix(warnings[0].String(), "unable to read word/header1.xml: ") {
+		t.Errorf("unexpected warning %s", warnings[0])
+	}
+
+	// the other parts are processed, the damaged header is copied unchanged
+	if err := doc.ReplaceAll(PlaceholderMap{"key": "value"}); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := doc.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	written, err := OpenBytes(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer written.Close()
+	if !strings.Contains(string(written.GetFile("word/footer1.xml")), "value") {
+		t.Errorf("the footer was not processed")
+	}
+	if warnings := written.Warnings(); len(warnings) != 1 || warnings[0].Part != "word/header1.xml" {
+		t.Errorf("the damaged header was not copied unchanged, got warnings %v", warnings)
+	}
+
+	if _, err := OpenBytesWithOptions(corrupted, OpenOptions{FailFast: true}); err == nil || !strings.Contains(err.Error(), "word/header1.xml") {
+		t.Errorf("expected an error for the damaged header, got %v", err)
+	}
+	if _, err := OpenBytes(corruptArchive(t, readFile(t, "./test/template.docx"), DocumentXml)); err == nil {
+		t.Errorf("expected an error for the damaged main document")
+	}
+}
+
+func TestDocument_Warnings_LazyMedia(t *testing.T) {
+	corrupted := corruptArchive(t, readFile(t, "./test/template.docx"), "word/media/image1.jpg")
+	doc, err := OpenBytesWithOptions(corrupted, OpenOptions{Lazy: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+	if data := doc.GetFile("word/media/image1.jpg"); data != nil {
+		t.Errorf("expected no data for the damaged image")
+	}
+	if warnings := doc.Warnings(); len(warnings) != 1 || warnings[0].Part != "word/media/image1.jpg" {
+		t.Errorf("unexpected warnings %v", warnings)
+	}
+	if err := doc.Write(&bytes.Buffer{}); err != nil {
+		t.Errorf("the damaged image must be copied: %v", err)
+	}
+}
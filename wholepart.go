@@ -0,0 +1,120 @@
+package docx
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// wholepart.go implements the ExecuteParts mode, which executes the text of each part as a single template
+// instead of placeholder by placeholder. The texts of the runs are joined with markers naming their run, so the
+// output can be written back into the runs it came from.
+
+// runMarkerRegex matches the marker in front of the text of a run in the template of a part.
+var runMarkerRegex = regexp.MustCompile(`\[\[docx-run (\d+)\]\]`)
+
+// SetExecutionMode sets how the placeholders of the parts are executed. With ExecuteParts the texts of all runs of
+// a part are stitched together and executed once as a single template, so control structures may span runs with
+// different formatting and paragraphs, e.g. {{if .vip}} in one paragraph and {{end}} in a later one, and documents
+// with many placeholders are rendered faster. The output is written back into the runs: each output goes into the
+// last run whose beginning was executed, so the text keeps its formatting, and runs skipped by a condition become
+// empty. Text repeated by {{range}} must start and end inside the same run. Like inside blocks, the values are
+// escaped and missing values are empty. Blocks spanning whole paragraphs are resolved first, unless a control
+// structure of the part spans paragraphs otherwise.
+func (d *Document) SetExecutionMode(mode ExecutionMode) {
+	d.templateReplacer.mode = mode
+}
+
+// executeParts executes the text of each part as a single template.
+func (tr *TemplateReplacer) executeParts() error {
+	for _, fileName := range tr.document.xmlParts() {
+		if err := tr.executePart(fileName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// executePart executes the text of the part as a single template and writes the output back into the runs.
+func (tr *TemplateReplacer) executePart(fileName string) error {
+	parser, exists := tr.document.runParsers[fileName]
+	if !exists {
+		return nil
+	}
+	tr.part = fileName
+
+	// placeholders split across runs are moved into their first run, so the markers are not inside actions
+	data := tr.document.GetFile(fileName)
+	if normalized, changed := normalizeRuns(data, parser.Runs().WithText()); changed {
+		if err := tr.document.SetFile(fileName, normalized); err != nil {
+			return err
+		}
+		if err := tr.document.refreshRuns(fileName); err != nil {
+			return err
+		}
+		data = normalized
+	}
+
+	runs := tr.document.runParsers[fileName].Runs().WithText()
+	var source strings.Builder
+	hasActions := false
+	for i, run := range runs {
+		text := run.GetText(data)
+		hasActions = hasActions || strings.Contains(text, "{{")
+		fmt.Fprintf(&source, "[[docx-run %d]]%s", i, text)
+	}
+	if !hasActions {
+		return nil
+	}
+
+	tr.debugLog("Executing %s as a single template", fileName)
+	result, err := tr.executeFragment(source.String(), tr.data)
+	if err != nil {
+		templateErr := newTemplateError(data, fileName, 0, "the template of the part", err)
+		if tr.skipPanic(templateErr) {
+			return nil
+		}
+		return templateErr
+	}
+
+	// the output following the marker of a run is its new text, runs without marker are left out
+	texts := make([]string, len(runs))
+	seen := make([]bool, len(runs))
+	matches := runMarkerRegex.FindAllSubmatchIndex(result, -1)
+	for m, match := range matches {
+		index, err := strconv.Atoi(string(result[match[2]:match[3]]))
+		if err != nil || index >= len(runs) {
+			return fmt.Errorf("unknown run marker %s in %s", result[match[0]:match[1]], fileName)
+		}
+		if seen[index] {
+			text := runs[index].GetText(data)
+			return newTemplateError(data, fileName, runs[index].OpenTag.Start, text,
+				fmt.Errorf("the text of the run is repeated, {{range}} must start and end inside the same run"))
+		}
+		end := len(result)
+		if m+1 < len(matches) {
+			end = matches[m+1][0]
+		}
+		texts[index] = string(result[match[1]:end])
+		seen[index] = true
+	}
+
+	// Replace in reverse order, so that earlier positions remain valid
+	out := data
+	for i := len(runs) - 1; i >= 0; i-- {
+		run := runs[i]
+		start, end := run.Text.OpenTag.End, run.Text.CloseTag.Start
+		if texts[i] == string(data[start:end]) {
+			continue
+		}
+		replaced := make([]byte, 0, len(out)-int(end-start)+len(texts[i]))
+		replaced = append(replaced, out[:start]...)
+		replaced = append(replaced, texts[i]...)
+		out = append(replaced, out[end:]...)
+	}
+	if err := tr.document.SetFile(fileName, out); err != nil {
+		return err
+	}
+	return tr.document.refreshRuns(fileName)
+}
@@ -0,0 +1,76 @@
+package docx
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTemplateReplacer_ExecuteParts(t *testing.T) {
+	build := func() *Document {
+		doc, err := NewDocument().
+			Paragraph(`{{if .vip}}Dear valued customer |BOLD|{{.name}}|END|,`).
+			Paragraph(`thank you for your loyalty.{{else}}Dear {{.name}},{{end}}`).
+			Paragraph(`{{range .items}}{{.}}; {{end}}`).
+			Build(WithExecutionMode(ExecuteParts))
+		if err != nil {
+			t.Fatal(err)
+		}
+		data := strings.NewReplacer(
+			"|BOLD|", `</w:t></w:r><w:r><w:rPr><w:b/></w:rPr><w:t xml:space="preserve">`,
+			"|END|", `</w:t></w:r><w:r><w:t xml:space="preserve">`,
+		).Replace(string(doc.GetFile(DocumentXml)))
+		if err := doc.SetFile(DocumentXml, []byte(data)); err != nil {
+			t.Fatal(err)
+		}
+		if err := doc.refreshRuns(DocumentXml); err != nil {
+			t.Fatal(err)
+		}
+		return doc
+	}
+
+	doc := build()
+	defer doc.Close()
+	if err := doc.ExecuteTemplate(map[string]interface{}{"vip": true, "name": "Jane & Co", "items": []string{"a", "b"}}); err != nil {
+		t.Fatal(err)
+	}
+	data := string(doc.GetFile(DocumentXml))
+	if !strings.Contains(data, `<w:b/></w:rPr><w:t xml:space="preserve">Jane &amp; Co</w:t>`) {
+		t.Errorf("expected the name to keep its formatting, got %s", data)
+	}
+	texts, err := paragraphTexts(doc.GetFile(DocumentXml))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := strings.Join(texts, "|"); text != "Dear valued customer Jane & Co,|thank you for your loyalty.|a; b; " {
+		t.Errorf("unexpected text %q", text)
+	}
+
+	other := build()
+	defer other.Close()
+	if err := other.ExecuteTemplate(map[string]interface{}{"name": "Joe"}); err != nil {
+		t.Fatal(err)
+	}
+	texts, err = paragraphTexts(other.GetFile(DocumentXml))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := strings.Join(texts, "|"); text != "Dear Joe,||" {
+		t.Errorf("unexpected text %q", text)
+	}
+
+	repeated, err := NewDocument().Paragraph(`{{range .items}}|BOLD|{{.}}{{end}}`).Build(WithExecutionMode(ExecuteParts))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repeated.Close()
+	if err := repeated.SetFile(DocumentXml, []byte(strings.Replace(string(repeated.GetFile(DocumentXml)), "|BOLD|",
+		`</w:t></w:r><w:r><w:rPr><w:b/></w:rPr><w:t xml:space="preserve">`, 1))); err != nil {
+		t.Fatal(err)
+	}
+	if err := repeated.refreshRuns(DocumentXml); err != nil {
+		t.Fatal(err)
+	}
+	if err := repeated.ExecuteTemplate(map[string]interface{}{"items": []int{1, 2}}); err == nil || !strings.Contains(err.Error(), "repeated") {
+		t.Errorf("expected an error for the repeated run, got %v", err)
+	}
+}
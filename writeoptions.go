@@ -0,0 +1,215 @@
+package docx
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	// proofErrRegex matches the marks of spelling and grammar errors, which Word writes around misspelled words.
+	proofErrRegex = regexp.MustCompile(`<w:proofErr\b[^>]*/>`)
+	// rsidAttrRegex matches the revision save IDs of paragraphs, runs, rows and sections, e.g. w:rsidR="00164916".
+	rsidAttrRegex = regexp.MustCompile(`\s+w:rsid[A-Za-z]*="[0-9A-Fa-f]*"`)
+	// updateFieldsRegex matches the setting which makes Word update the fields when it opens the document.
+	updateFieldsRegex = regexp.MustCompile(`<w:updateFields\b[^>]*/>`)
+)
+
+// deterministicModTime is the modification time of all files written in deterministic mode, the earliest
+// time of the zip format.
+var deterministicModTime = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// updateFieldsFollowers lists the children of the settings which follow <w:updateFields> according to the schema.
+var updateFieldsFollowers = []string{"w:hdrShapeDefaults", "w:footnotePr", "w:endnotePr", "w:compat", "w:docVars",
+	"w:rsids", "m:mathPr", "w:attachedSchema", "w:themeFontLang", "w:clrSchemeMapping", "w:doNotIncludeSubdocsInStats",
+	"w:doNotAutoCompressPictures", "w:forceUpgrade", "w:captions", "w:readModeInkLockDown", "w:smartTagType",
+	"sl:schemaLibrary", "w:shapeDefaults", "w:doNotEmbedSmartTags", "w:decimalSymbol", "w:listSeparator"}
+
+// WriteOptions controls how the document is written, see WriteWithOptions. The zero value writes the document
+// just like Write. The options only affect the output, the document itself is not modified.
+type WriteOptions struct {
+	// CompressionLevel is the level of the Deflate compression of the archive, from flate.BestSpeed (1) to
+	// flate.BestCompression (9) or flate.HuffmanOnly. 0 uses the default compression.
+	CompressionLevel int
+	// Deterministic writes the files in the order of their names (after [Content_Types].xml) with a fixed
+	// modification time, so the output only depends on the content of the document, e.g. for caching or
+	// comparing generated documents.
+	Deterministic bool
+	// StripProofing removes the marks of spelling and grammar errors (<w:proofErr>) and the revision save IDs
+	// (w:rsidR etc.) from the document body, headers and footers. The marks refer to the text of the template and
+	// are stale after it is modified, Word checks the text again when the document is opened. The output is also
+	// considerably smaller.
+	StripProofing bool
+	// PruneMedia leaves out the media files (e.g. word/media/image1.png) which are not referenced by any
+	// relationship, such as images of the template which were removed by conditions or replaced.
+	PruneMedia bool
+	// UpdateFieldsOnOpen makes Word update all fields (e.g. the table of contents and page references) when the
+	// document is opened. Word asks the user before it updates the fields.
+	UpdateFieldsOnOpen bool
+}
+
+// outputFile returns the data of the modified or added file as it is written with the options.
+func (d *Document) outputFile(fileName string, options WriteOptions) []byte {
+	data := d.files[fileName]
+	if options.StripProofing && d.isXmlPart(fileName) {
+		data = proofErrRegex.ReplaceAll(data, nil)
+		data = rsidAttrRegex.ReplaceAll(data, nil)
+	}
+	return data
+}
+
+// outputPackageFileWithOptions returns the package file as it is written (see outputPackageFile). The content
+// types and relationships of pruned files are removed and the settings are changed according to the options.
+func (d *Document) outputPackageFileWithOptions(fileName string, options WriteOptions, pruned map[string]bool) []byte {
+	data := d.outputPackageFile(fileName)
+	switch {
+	case fileName == ContentTypesXml && len(pruned) > 0:
+		types, err := parseContentTypes(data)
+		if err != nil {
+			return data
+		}
+		for name := range pruned {
+			types.removeOverride("/" + name)
+		}
+		if converted, err := types.marshal(); err == nil {
+			return converted
+		}
+	case strings.HasSuffix(fileName, ".rels") && len(pruned) > 0:
+		rels, err := parseRelationships(data)
+		if err != nil {
+			return data
+		}
+		sourcePart := relsSourcePart(fileName)
+		removed := rels.removeIf(func(rel opcRelationship) bool {
+			return rel.TargetMode != TargetModeExternal && pruned[resolveTarget(sourcePart, rel.Target)]
+		})
+		if removed == 0 {
+			return data
+		}
+		if converted, err := rels.marshal(); err == nil {
+			return converted
+		}
+	case fileName == SettingsXml && options.UpdateFieldsOnOpen:
+		return enableUpdateFields(data)
+	}
+	return data
+}
+
+// unusedMedia returns the media files of the document which are not referenced. Relationships of the document
+// body, headers and footers only reference their target if the part uses the id of the relationship, other parts
+// are not inspected.
+func (d *Document) unusedMedia() (map[string]bool, error) {
+	referenced := make(map[string]bool)
+	for _, relsPart := range d.relsParts() {
+		sourcePart := relsSourcePart(relsPart)
+		rels, err := d.packageRelationships(sourcePart)
+		if err != nil {
+			return nil, err
+		}
+		isPart := d.isXmlPart(sourcePart)
+		for _, rel := range rels.Relationships {
+			if rel.TargetMode == TargetModeExternal {
+				continue
+			}
+			if isPart && !bytes.Contains(d.files[sourcePart], []byte(`"`+rel.ID+`"`)) {
+				continue
+			}
+			referenced[resolveTarget(sourcePart, rel.Target)] = true
+		}
+	}
+
+	unused := make(map[string]bool)
+	for _, name := range d.mediaFiles {
+		if !referenced[name] {
+			unused[name] = true
+		}
+	}
+	return unused, nil
+}
+
+// enableUpdateFields returns the settings with the setting which makes Word update the fields when it opens the
+// document. Invalid settings are returned unchanged.
+func enableUpdateFields(settings []byte) []byte {
+	settings = updateFieldsRegex.ReplaceAll(settings, nil)
+	open := settingsOpenTagRegex.FindIndex(settings)
+	closeTag := bytes.LastIndex(settings, []byte("</w:settings>"))
+	if open == nil || closeTag < open[1] {
+		return settings
+	}
+	children := insertChild(settings[open[1]:closeTag], []byte(`<w:updateFields w:val="true"/>`), updateFieldsFollowers)
+	return append(append(append([]byte{}, settings[:open[1]]...), children...), settings[closeTag:]...)
+}
+
+// WriteWithHash writes the document like Write and returns the hex-encoded SHA-256 hash of the written bytes,
+// computed while the document is written, e.g. to store an integrity hash or to detect identical documents.
+func (d *Document) WriteWithHash(writer io.Writer) (string, error) {
+	return d.WriteWithOptionsAndHash(writer, WriteOptions{})
+}
+
+// WriteWithOptionsAndHash writes the document like WriteWithOptions and returns the hex-encoded SHA-256 hash of
+// the written bytes. Documents with the same content only have the same hash if they are written with the
+// Deterministic option.
+func (d *Document) WriteWithOptionsAndHash(writer io.Writer, options WriteOptions) (string, error) {
+	hash := sha256.New()
+	if err := d.WriteWithOptions(io.MultiWriter(writer, hash), options); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// copyUnmodifiedFile copies a file of the original archive which was not modified. Its compressed data is copied
+// as it is, so the compression method, the modification time and the extra fields (e.g. extended timestamps) are
+// kept and tools comparing archives see the file unchanged. In deterministic mode or with a compression level
+// the file is compressed again, keeping its compression method, and only the metadata not depending on the
+// original archive is kept.
+func copyUnmodifiedFile(zipWriter *zip.Writer, file *zip.File, options WriteOptions) error {
+	if !options.Deterministic && options.CompressionLevel == 0 {
+		return copyRawZipFile(zipWriter, file)
+	}
+
+	header := &zip.FileHeader{Name: file.Name, Method: file.Method, Comment: file.Comment}
+	if header.Method != zip.Store {
+		// other methods cannot be compressed again by the zip package
+		header.Method = zip.Deflate
+	}
+	if options.Deterministic {
+		header.Modified = deterministicModTime
+	} else {
+		// Modified is left empty, so the original timestamps are used instead of adding another one
+		header.ModifiedTime, header.ModifiedDate = file.ModifiedTime, file.ModifiedDate
+		header.Extra = withoutZip64Extra(file.Extra)
+	}
+	fw, err := zipWriter.CreateHeader(header)
+	if err != nil {
+		return fmt.Errorf("unable to create writer: %s", err)
+	}
+	return copyZipFile(fw, file)
+}
+
+// zip64ExtraID is the id of the extra field holding the sizes and offsets of zip64 archives.
+const zip64ExtraID = 0x0001
+
+// withoutZip64Extra returns the extra fields without the zip64 field, which the zip package writes itself when the
+// sizes or the offset of a file require it.
+func withoutZip64Extra(extra []byte) []byte {
+	var out []byte
+	for len(extra) >= 4 {
+		id, size := binary.LittleEndian.Uint16(extra), int(binary.LittleEndian.Uint16(extra[2:]))
+		if 4+size > len(extra) {
+			// a malformed field, the rest is kept as it is
+			break
+		}
+		if id != zip64ExtraID {
+			out = append(out, extra[:4+size]...)
+		}
+		extra = extra[4+size:]
+	}
+	return append(out, extra...)
+}
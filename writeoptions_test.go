@@ -0,0 +1,243 @@
+package docx
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDocument_WriteWithOptions(t *testing.T) {
+	doc, err := Open("./test/template.docx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+	original := string(doc.GetFile(DocumentXml))
+	if !strings.Contains(original, "<w:proofErr") || !strings.Contains(original, "w:rsidR=") {
+		t.Fatalf("expected the template to contain proofing marks and revision save IDs")
+	}
+
+	var buf bytes.Buffer
+	if err := doc.WriteWithOptions(&buf, WriteOptions{StripProofing: true}); err != nil {
+		t.Fatal(err)
+	}
+	written, err := OpenBytes(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, part := range written.xmlParts() {
+		data := string(written.GetFile(part))
+		if strings.Contains(data, "<w:proofErr") || strings.Contains(data, "w:rsid") {
+			t.Errorf("expected the proofing marks to be removed from %s", part)
+		}
+	}
+	if stripped := string(written.GetFile(DocumentXml)); len(stripped) >= len(original) || !strings.Contains(stripped, "{key-with-dashes}") {
+		t.Errorf("unexpected document %s", stripped)
+	}
+	if string(doc.GetFile(DocumentXml)) != original {
+		t.Errorf("the document itself must not be modified")
+	}
+
+	buf.Reset()
+	if err := doc.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if written, err = OpenBytes(buf.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if string(written.GetFile(DocumentXml)) != original {
+		t.Errorf("expected Write to keep the proofing marks")
+	}
+}
+
+func TestDocument_WriteWithOptions_Archive(t *testing.T) {
+	doc, err := Open("./test/template.docx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+
+	write := func(options WriteOptions) []byte {
+		t.Helper()
+		var buf bytes.Buffer
+		if err := doc.WriteWithOptions(&buf, options); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+
+	fast, best := write(WriteOptions{CompressionLevel: flate.BestSpeed}), write(WriteOptions{CompressionLevel: flate.BestCompression})
+	if len(best) >= len(fast) {
+		t.Errorf("expected the best compression to be smaller than %d bytes, got %d", len(fast), len(best))
+	}
+	if err := doc.WriteWithOptions(&bytes.Buffer{}, WriteOptions{CompressionLevel: 12}); err == nil {
+		t.Errorf("expected an error for an invalid compression level")
+	}
+
+	deterministic := write(WriteOptions{Deterministic: true})
+	if !bytes.Equal(deterministic, write(WriteOptions{Deterministic: true})) {
+		t.Errorf("expected the deterministic output to be the same")
+	}
+	archive, err := zip.NewReader(bytes.NewReader(deterministic), int64(len(deterministic)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, file := range archive.File {
+		names = append(names, file.Name)
+		if !file.Modified.Equal(deterministicModTime) {
+			t.Errorf("unexpected modification time %s of %s", file.Modified, file.Name)
+		}
+	}
+	if names[0] != ContentTypesXml || !sort.StringsAreSorted(names[1:]) {
+		t.Errorf("expected the files to be sorted, got %v", names)
+	}
+
+	updated, err := OpenBytes(write(WriteOptions{UpdateFieldsOnOpen: true}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings, _ := updated.loadPackageFile(SettingsXml)
+	if bytes.Count(settings, []byte(`<w:updateFields w:val="true"/>`)) != 1 {
+		t.Errorf("expected the fields to be updated on open in %s", settings)
+	}
+	if settings, _ := doc.loadPackageFile(SettingsXml); bytes.Contains(settings, []byte("w:updateFields")) {
+		t.Errorf("the settings of the document must not be modified")
+	}
+}
+
+func TestDocument_WriteWithOptions_Metadata(t *testing.T) {
+	src := readFile(t, "./test/template.docx")
+	zr, err := zip.NewReader(bytes.NewReader(src), int64(len(src)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range zr.File {
+		if err := zw.Copy(f); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// a stored file with a modification time and an extra field of some other tool
+	modified := time.Date(2020, 5, 4, 12, 30, 0, 0, time.UTC)
+	toolExtra := []byte{0xfe, 0xca, 0x02, 0x00, 0x01, 0x02}
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: "customXml/item1.xml", Method: zip.Store, Modified: modified, Extra: toolExtra})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = w.Write([]byte(`<?xml version="1.0" encoding="UTF-8" standalone="yes"?><data/>`))
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := OpenBytes(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.ReplaceAll(map[string]string{"key": "value"}); err != nil {
+		t.Fatal(err)
+	}
+	write := func(options WriteOptions) map[string]*zip.File {
+		t.Helper()
+		var out bytes.Buffer
+		if err := doc.WriteWithOptions(&out, options); err != nil {
+			t.Fatal(err)
+		}
+		archive, err := zip.NewReader(bytes.NewReader(out.Bytes()), int64(out.Len()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		files := make(map[string]*zip.File)
+		for _, file := range archive.File {
+			files[file.Name] = file
+		}
+		return files
+	}
+
+	files := write(WriteOptions{})
+	custom := files["customXml/item1.xml"]
+	if custom == nil {
+		t.Fatal("expected customXml/item1.xml to be written")
+	}
+	if custom.Method != zip.Store || !custom.Modified.Equal(modified) || !bytes.Contains(custom.Extra, toolExtra) {
+		t.Errorf("expected the metadata to be kept, got method %d, modified %s, extra %x", custom.Method, custom.Modified, custom.Extra)
+	}
+
+	files = write(WriteOptions{Deterministic: true})
+	custom = files["customXml/item1.xml"]
+	if custom.Method != zip.Store || !custom.Modified.Equal(deterministicModTime) || bytes.Contains(custom.Extra, toolExtra) {
+		t.Errorf("expected the method to be kept and the time to be fixed, got method %d, modified %s, extra %x", custom.Method, custom.Modified, custom.Extra)
+	}
+}
+
+func TestDocument_WriteWithOptions_PruneMedia(t *testing.T) {
+	for _, removed := range []bool{false, true} {
+		doc, err := OpenBytes(rewriteArchive(t, readFile(t, "./test/template.docx"), func(name string, data []byte) []byte {
+			if name == DocumentXml && removed {
+				return bytes.ReplaceAll(data, []byte(` r:embed="rId6"`), nil)
+			}
+			return data
+		}, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer doc.Close()
+
+		var buf bytes.Buffer
+		if err := doc.WriteWithOptions(&buf, WriteOptions{PruneMedia: true}); err != nil {
+			t.Fatal(err)
+		}
+		written, err := OpenBytes(buf.Bytes())
+		if err != nil {
+			t.Fatal(err)
+		}
+		rels, _ := written.loadPackageFile(DocumentRelsXml)
+		if exists := written.GetFile("word/media/image1.jpg") != nil; exists == removed {
+			t.Errorf("expected the image to exist: %t", !removed)
+		}
+		if referenced := bytes.Contains(rels, []byte("media/image1.jpg")); referenced == removed {
+			t.Errorf("expected the image to be referenced: %t", !removed)
+		}
+		if !bytes.Contains(rels, []byte("styles.xml")) {
+			t.Errorf("expected the other relationships to be kept")
+		}
+	}
+}
+
+func TestDocument_WriteWithHash(t *testing.T) {
+	doc, err := Open("./test/template.docx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+
+	var buf bytes.Buffer
+	hash, err := doc.WriteWithHash(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hash != contentHash(buf.Bytes()) {
+		t.Errorf("expected the hash of the written bytes, got %s", hash)
+	}
+
+	var first, second bytes.Buffer
+	firstHash, err := doc.WriteWithOptionsAndHash(&first, WriteOptions{Deterministic: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	secondHash, err := doc.WriteWithOptionsAndHash(&second, WriteOptions{Deterministic: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if firstHash != secondHash || firstHash != contentHash(first.Bytes()) {
+		t.Errorf("expected the same hash for the deterministic output, got %s and %s", firstHash, secondHash)
+	}
+	if _, err := doc.WriteWithOptionsAndHash(&bytes.Buffer{}, WriteOptions{CompressionLevel: 12}); err == nil {
+		t.Errorf("expected an error for an invalid compression level")
+	}
+}
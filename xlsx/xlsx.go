@@ -0,0 +1,132 @@
+// Package xlsx fills Excel workbooks (.xlsx and .xltx files) with the placeholder syntax of the docx package,
+// so one templating codebase serves documents, presentations and spreadsheets.
+//
+// The placeholders of the shared strings and of the inline strings of the worksheets are evaluated like those of
+// a docx document: with text/template by default or with an alternative templating.Engine. Placeholders may be
+// split across rich text runs. The results are text, a cell holding {{.Total}} keeps its string type; formulas
+// and repeating rows are not supported.
+package xlsx
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"text/template"
+
+	"github.com/izetmolla/docx"
+	"github.com/izetmolla/docx/internal/zipxml"
+	"github.com/izetmolla/docx/templating"
+)
+
+const (
+	// WorkbookXml is the main part of a workbook, which lists its worksheets.
+	WorkbookXml = "xl/workbook.xml"
+	// SharedStringsXml holds the strings of the cells of all worksheets.
+	SharedStringsXml = "xl/sharedStrings.xml"
+	// WorksheetsPrefix is the common prefix of the worksheet parts, e.g. xl/worksheets/sheet1.xml.
+	WorksheetsPrefix = "xl/worksheets/sheet"
+)
+
+var _ docx.TemplateDocument = (*Workbook)(nil)
+
+// syntax describes shared and inline strings, whose text is held by their t elements. The phonetic runs of a
+// string interrupt its text.
+var syntax = zipxml.Syntax{
+	Paragraphs: []string{"si", "is"},
+	Text:       "t",
+	Breaks:     map[string]bool{"rPh": true},
+}
+
+// Workbook is an Excel workbook which is filled like a docx document.
+type Workbook struct {
+	path     string
+	pkg      *zipxml.Package
+	template zipxml.Template
+}
+
+// Open opens the workbook or workbook template at the path.
+func Open(path string) (*Workbook, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	workbook, err := OpenBytes(data)
+	if err != nil {
+		return nil, err
+	}
+	workbook.path = path
+	return workbook, nil
+}
+
+// OpenBytes opens the workbook or workbook template in memory.
+func OpenBytes(data []byte) (*Workbook, error) {
+	pkg, err := zipxml.ReadPackage(data)
+	if err != nil {
+		return nil, err
+	}
+	if pkg.GetFile(WorkbookXml) == nil {
+		return nil, fmt.Errorf("not a workbook: %s is missing", WorkbookXml)
+	}
+	return &Workbook{pkg: pkg}, nil
+}
+
+// parts returns the parts whose placeholders are evaluated.
+func (w *Workbook) parts() []string {
+	return append([]string{SharedStringsXml}, w.pkg.Names(WorksheetsPrefix, ".xml")...)
+}
+
+// GetFile returns the content of the file of the package, or nil if it does not exist.
+func (w *Workbook) GetFile(name string) []byte {
+	return w.pkg.GetFile(name)
+}
+
+// SetFile replaces the content of an existing file or adds a new file to the package.
+func (w *Workbook) SetFile(name string, data []byte) {
+	w.pkg.SetFile(name, data)
+}
+
+// SetTemplateEngine sets an alternative engine which evaluates the placeholders, e.g. a moustache-style engine.
+// Pass nil to use text/template again.
+func (w *Workbook) SetTemplateEngine(engine templating.Engine) {
+	w.template.Engine = engine
+}
+
+// AddTemplateFuncs adds custom functions to the text/template placeholders.
+func (w *Workbook) AddTemplateFuncs(funcMap template.FuncMap) {
+	w.template.AddFuncs(funcMap)
+}
+
+// SetMissingKeyPolicy sets the handling of placeholders which reference missing values. By default they are
+// left unchanged.
+func (w *Workbook) SetMissingKeyPolicy(policy docx.MissingKeyPolicy) {
+	w.template.MissingKey = policy
+}
+
+// ExecuteTemplate evaluates the placeholders of the shared and inline strings with the data.
+func (w *Workbook) ExecuteTemplate(data docx.TemplateData) error {
+	return w.template.Execute(w.pkg, w.parts(), syntax, data)
+}
+
+// ReplaceAll replaces the string-based placeholders delimited with { and } with the values of the map.
+// Placeholders which are not in the map are left unchanged.
+func (w *Workbook) ReplaceAll(replaceMap docx.PlaceholderMap) error {
+	return w.template.ReplaceAll(w.pkg, w.parts(), syntax, replaceMap)
+}
+
+// Write writes the workbook to the writer.
+func (w *Workbook) Write(writer io.Writer) error {
+	return w.pkg.Write(writer, "")
+}
+
+// WriteToFile writes the workbook to a new file. The directories of the path are created if necessary.
+func (w *Workbook) WriteToFile(file string) error {
+	if file == w.path {
+		return fmt.Errorf("WriteToFile cannot write into the original template")
+	}
+	return w.pkg.WriteToFile(file, "")
+}
+
+// Close releases the content of the workbook.
+func (w *Workbook) Close() {
+	w.pkg = nil
+}
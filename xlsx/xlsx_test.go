@@ -0,0 +1,73 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/izetmolla/docx"
+)
+
+// testWorkbook returns a workbook with the shared strings and the cells of a worksheet.
+func testWorkbook(t *testing.T, sharedStrings, cells string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+	files := map[string]string{
+		WorkbookXml:                `<workbook/>`,
+		SharedStringsXml:           `<sst count="2" uniqueCount="2">` + sharedStrings + `</sst>`,
+		WorksheetsPrefix + "1.xml": `<worksheet><sheetData><row r="1">` + cells + `</row></sheetData></worksheet>`,
+	}
+	for name, content := range files {
+		entry, err := writer.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := entry.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestWorkbook_ExecuteTemplate(t *testing.T) {
+	workbook, err := OpenBytes(testWorkbook(t,
+		`<si><t>Invoice {{.Number}}</t></si><si><r><rPr><b/></rPr><t>{{.Cust</t></r><r><t>omer}}</t></r><rPh><t>{{.Customer}}</t></rPh></si>`,
+		`<c r="A1" t="s"><v>0</v></c><c r="B1" t="inlineStr"><is><t>{code}</t></is></c>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer workbook.Close()
+
+	if err := workbook.ExecuteTemplate(map[string]interface{}{"Number": 7, "Customer": "Smith & Sons"}); err != nil {
+		t.Fatal(err)
+	}
+	sharedStrings := string(workbook.GetFile(SharedStringsXml))
+	for _, expected := range []string{`<si><t>Invoice 7</t></si>`,
+		`<r><rPr><b/></rPr><t>Smith &amp; Sons</t></r><r><t></t></r><rPh><t>Smith &amp; Sons</t></rPh>`} {
+		if !strings.Contains(sharedStrings, expected) {
+			t.Errorf("expected %s in %s", expected, sharedStrings)
+		}
+	}
+
+	if err := workbook.ReplaceAll(docx.PlaceholderMap{"code": "X-1"}); err != nil {
+		t.Fatal(err)
+	}
+	if sheet := string(workbook.GetFile(WorksheetsPrefix + "1.xml")); !strings.Contains(sheet, `<is><t>X-1</t></is>`) {
+		t.Errorf("the inline string was not replaced: %s", sheet)
+	}
+}
+
+func TestOpenBytes_NoWorkbook(t *testing.T) {
+	var buf bytes.Buffer
+	if err := zip.NewWriter(&buf).Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := OpenBytes(buf.Bytes()); err == nil {
+		t.Errorf("expected an error for a package without workbook")
+	}
+}
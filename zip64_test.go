@@ -0,0 +1,56 @@
+package docx
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// manyEntries is more than the 65535 entries of a classic zip archive.
+const manyEntries = 70000
+
+func TestDocument_Zip64Entries(t *testing.T) {
+	extra := make(map[string][]byte, manyEntries)
+	for i := 0; i < manyEntries; i++ {
+		extra[fmt.Sprintf("word/media/scan%d.png", i)] = []byte{byte(i)}
+	}
+	src := rewriteArchive(t, readFile(t, "./test/template.docx"), nil, extra)
+
+	doc, err := OpenBytes(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer doc.Close()
+	if err := doc.ReplaceAll(PlaceholderMap{"key": "value"}); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := doc.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	// the end of the archive holds the zip64 end of central directory record
+	if !bytes.Contains(buf.Bytes()[buf.Len()-200:], []byte("PK\x06\x06")) {
+		t.Errorf("the archive was not written as zip64")
+	}
+	reader, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reader.File) != manyEntries+16 {
+		t.Errorf("expected %d entries, got %d", manyEntries+16, len(reader.File))
+	}
+	written, err := OpenBytes(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer written.Close()
+	if !strings.Contains(string(written.GetFile(DocumentXml)), "value") {
+		t.Errorf("the document was not processed")
+	}
+	if data := written.GetFile("word/media/scan69999.png"); !bytes.Equal(data, []byte{69999 % 256}) {
+		t.Errorf("unexpected data of the last entry %v", data)
+	}
+}